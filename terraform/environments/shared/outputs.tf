@@ -46,6 +46,11 @@ output "bastion_public_ip" {
   value       = module.bastion.bastion_public_ip
 }
 
+output "bastion_instance_id" {
+  description = "Instance ID of the bastion host, for Session Manager connections"
+  value       = module.bastion.bastion_instance_id
+}
+
 # Route table IDs (needed by prod/dev for VPC peering routes)
 output "db_route_table_id" {
   description = "Route table ID for the DB subnets (for VPC peering routes)"
@@ -0,0 +1,9 @@
+# The smallest deployable configuration: a VPC with public, private and database subnets in two availability
+# zones, an internet gateway and the S3 gateway endpoint. None of these are billed, so the example test applies it.
+
+module "networking" {
+  source = "../../modules/networking"
+
+  prefix     = var.prefix
+  aws_region = var.aws_region
+}
@@ -0,0 +1,9 @@
+output "vpc_id" {
+  description = "ID of the VPC"
+  value       = module.networking.vpc_id
+}
+
+output "private_subnet_ids" {
+  description = "IDs of the private app subnets"
+  value       = module.networking.private_subnet_ids
+}
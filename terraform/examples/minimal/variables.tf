@@ -0,0 +1,9 @@
+variable "prefix" {
+  description = "Prefix to use for resource names"
+  type        = string
+}
+
+variable "aws_region" {
+  description = "The AWS region to deploy to"
+  type        = string
+}
@@ -0,0 +1,17 @@
+# The network and its security groups and WAF, as the shared account deploys them: a VPC, the database and bastion
+# security groups, and the WAF web ACL. Only the bastion's allowed source ranges have no default.
+
+module "networking" {
+  source = "../../modules/networking"
+
+  prefix     = var.prefix
+  aws_region = var.aws_region
+}
+
+module "security" {
+  source = "../../modules/security"
+
+  prefix                = var.prefix
+  vpc_id                = module.networking.vpc_id
+  allowed_bastion_cidrs = var.allowed_bastion_cidrs
+}
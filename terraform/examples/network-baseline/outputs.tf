@@ -0,0 +1,14 @@
+output "vpc_id" {
+  description = "ID of the VPC"
+  value       = module.networking.vpc_id
+}
+
+output "db_security_group_id" {
+  description = "ID of the database security group"
+  value       = module.security.db_security_group_id
+}
+
+output "waf_web_acl_arn" {
+  description = "ARN of the WAF web ACL"
+  value       = module.security.waf_web_acl_arn
+}
@@ -0,0 +1,14 @@
+variable "prefix" {
+  description = "Prefix to use for resource names"
+  type        = string
+}
+
+variable "aws_region" {
+  description = "The AWS region to deploy to"
+  type        = string
+}
+
+variable "allowed_bastion_cidrs" {
+  description = "CIDR blocks allowed to reach the bastion host over SSH"
+  type        = list(string)
+}
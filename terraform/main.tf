@@ -115,8 +115,14 @@ module "database" {
   app_db_username            = var.app_db_username
   use_secrets_manager        = true
   db_backup_retention_period = 14
+  deletion_protection        = !contains(["dev", "test"], var.environment)
   auto_setup_database        = var.auto_setup_database
   prevent_destroy            = var.prevent_destroy
+
+  # Performance Insights at the free 7-day retention, and enhanced monitoring every minute
+  db_performance_insights_enabled          = true
+  db_performance_insights_retention_period = 7
+  db_monitoring_interval                   = 60
 }
 
 # Secrets Module
@@ -140,7 +146,7 @@ module "ses" {
   domain_name          = var.domain_name
   from_email           = var.ses_from_email
   verify_domain        = var.ses_verify_domain
-  route53_zone_id      = var.route53_zone_id
+  route53_zone_id      = var.manage_dns ? var.route53_zone_id : ""
   dmarc_email          = var.ses_notification_email
   notification_email   = var.ses_notification_email
   enable_notifications = var.ses_enable_notifications
@@ -176,6 +182,8 @@ module "storage" {
   static_cache_min_ttl     = var.cloudfront_static_cache_min_ttl
   static_cache_default_ttl = var.cloudfront_static_cache_default_ttl
   static_cache_max_ttl     = var.cloudfront_static_cache_max_ttl
+
+  price_class = var.environment == "prod" ? "PriceClass_All" : "PriceClass_100"
 }
 
 # Serverless Storage Module - Creates S3 bucket for Lambda/serverless deployments
@@ -213,14 +221,16 @@ resource "aws_acm_certificate" "main" {
   }
 }
 
+# With external DNS the validation records in the acm_validation_records output are created by hand,
+# and the validation below waits for them
 resource "aws_route53_record" "cert_validation" {
-  for_each = {
+  for_each = var.manage_dns ? {
     for dvo in aws_acm_certificate.main.domain_validation_options : dvo.domain_name => {
       name   = dvo.resource_record_name
       record = dvo.resource_record_value
       type   = dvo.resource_record_type
     }
-  }
+  } : {}
 
   allow_overwrite = true
   zone_id         = var.route53_zone_id
@@ -232,7 +242,7 @@ resource "aws_route53_record" "cert_validation" {
 
 resource "aws_acm_certificate_validation" "main" {
   certificate_arn         = aws_acm_certificate.main.arn
-  validation_record_fqdns = [for record in aws_route53_record.cert_validation : record.fqdn]
+  validation_record_fqdns = var.manage_dns ? [for record in aws_route53_record.cert_validation : record.fqdn] : null
 }
 
 # API custom domain
@@ -252,6 +262,8 @@ resource "aws_api_gateway_base_path_mapping" "api" {
 }
 
 resource "aws_route53_record" "api" {
+  count = var.manage_dns ? 1 : 0
+
   zone_id = var.route53_zone_id
   name    = "api.${var.domain_name}"
   type    = "A"
@@ -265,6 +277,8 @@ resource "aws_route53_record" "api" {
 
 # DNS - Point domain to Vercel
 resource "aws_route53_record" "apex" {
+  count = var.manage_dns && var.create_frontend_dns_records ? 1 : 0
+
   zone_id = var.route53_zone_id
   name    = var.domain_name
   type    = "A"
@@ -273,9 +287,27 @@ resource "aws_route53_record" "apex" {
 }
 
 resource "aws_route53_record" "www" {
+  count = var.manage_dns && var.create_frontend_dns_records ? 1 : 0
+
   zone_id = var.route53_zone_id
   name    = "www.${var.domain_name}"
   type    = "CNAME"
   ttl     = 300
   records = ["cname.vercel-dns.com"]
 }
+
+# The DNS records became conditional; keep existing records in place
+moved {
+  from = aws_route53_record.api
+  to   = aws_route53_record.api[0]
+}
+
+moved {
+  from = aws_route53_record.apex
+  to   = aws_route53_record.apex[0]
+}
+
+moved {
+  from = aws_route53_record.www
+  to   = aws_route53_record.www[0]
+}
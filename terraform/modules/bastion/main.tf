@@ -31,6 +31,38 @@ resource "aws_key_pair" "bastion" {
   }
 }
 
+# Instance role limited to Session Manager, so the host can be reached without SSH
+resource "aws_iam_role" "bastion" {
+  name = "${var.prefix}-bastion-role"
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Action = "sts:AssumeRole"
+        Effect = "Allow"
+        Principal = {
+          Service = "ec2.amazonaws.com"
+        }
+      }
+    ]
+  })
+
+  tags = {
+    Name = "${var.prefix}-bastion-role"
+  }
+}
+
+resource "aws_iam_role_policy_attachment" "bastion_ssm" {
+  role       = aws_iam_role.bastion.name
+  policy_arn = "arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"
+}
+
+resource "aws_iam_instance_profile" "bastion" {
+  name = "${var.prefix}-bastion-profile"
+  role = aws_iam_role.bastion.name
+}
+
 resource "aws_instance" "bastion" {
   ami           = data.aws_ami.amazon_linux_2.id
   instance_type = "t4g.nano"
@@ -41,6 +73,14 @@ resource "aws_instance" "bastion" {
   vpc_security_group_ids      = [var.bastion_security_group_id]
   subnet_id                   = var.public_subnet_id
   associate_public_ip_address = true
+  iam_instance_profile        = aws_iam_instance_profile.bastion.name
+
+  # Require IMDSv2 session tokens and keep credentials off forwarded hops
+  metadata_options {
+    http_endpoint               = "enabled"
+    http_tokens                 = "required"
+    http_put_response_hop_limit = 1
+  }
 
   root_block_device {
     volume_size = 8
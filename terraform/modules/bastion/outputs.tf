@@ -3,6 +3,21 @@ output "bastion_public_ip" {
   value       = aws_eip.bastion.public_ip
 }
 
+output "bastion_instance_id" {
+  description = "Instance ID of the bastion host, for Session Manager connections"
+  value       = aws_instance.bastion.id
+}
+
+output "bastion_ami_id" {
+  description = "AMI the bastion host was launched from"
+  value       = aws_instance.bastion.ami
+}
+
+output "bastion_iam_role_name" {
+  description = "Name of the IAM role attached to the bastion host"
+  value       = aws_iam_role.bastion.name
+}
+
 output "bastion_key_pair_name" {
   description = "Name of the bastion key pair"
   value       = var.bastion_key_name
@@ -9,6 +9,13 @@ locals {
   parameter_group_name = var.prevent_destroy ? aws_db_parameter_group.postgres[0].name : aws_db_parameter_group.postgres_testing[0].name
 
   db_setup_script_path = "${path.module}/scripts/db_setup.sh"
+
+  # Without an explicit setting, test and dev prefixes can be torn down freely
+  deletion_protection = var.deletion_protection != null ? var.deletion_protection : !can(regex("test|dev", var.prefix))
+
+  # max_connections is pinned in the parameter group rather than left to the instance class's memory, so the
+  # alarm is sized from the same value the database enforces
+  connections_alarm_threshold = floor(var.db_max_connections * var.db_connections_alarm_percent / 100)
 }
 
 # KMS key for RDS encryption
@@ -94,33 +101,110 @@ resource "aws_db_instance" "postgres" {
   password          = local.master_password
   # Use the regular parameter group for basic parameters
   # Note: Static parameters are defined in postgres_static but not associated with the instance
-  parameter_group_name         = local.parameter_group_name
-  db_subnet_group_name         = aws_db_subnet_group.main.name
-  vpc_security_group_ids       = [var.db_security_group_id]
-  skip_final_snapshot          = can(regex("test|dev", var.prefix)) ? true : false
-  final_snapshot_identifier    = can(regex("test|dev", var.prefix)) ? null : "${var.prefix}-final-snapshot"
-  deletion_protection          = can(regex("test|dev", var.prefix)) ? false : true
-  multi_az                     = false
-  backup_retention_period      = var.db_backup_retention_period
-  backup_window                = "03:00-04:00"
-  maintenance_window           = "mon:04:00-mon:05:00"
-  performance_insights_enabled = false
-  storage_encrypted            = true
-  kms_key_id                   = aws_kms_key.rds.arn
-  monitoring_interval          = 0
-  publicly_accessible          = false
+  parameter_group_name      = local.parameter_group_name
+  db_subnet_group_name      = aws_db_subnet_group.main.name
+  vpc_security_group_ids    = [var.db_security_group_id]
+  skip_final_snapshot       = !local.deletion_protection
+  final_snapshot_identifier = local.deletion_protection ? "${var.prefix}-final-snapshot" : null
+  deletion_protection       = local.deletion_protection
+  multi_az                  = false
+  backup_retention_period   = var.db_backup_retention_period
+  backup_window             = "03:00-04:00"
+  maintenance_window        = "mon:04:00-mon:05:00"
+  storage_encrypted         = true
+  kms_key_id                = aws_kms_key.rds.arn
+  publicly_accessible       = false
+
+  # Storage autoscaling (0 disables it)
+  max_allocated_storage = var.db_max_allocated_storage
+
+  # Performance Insights (7 days retention is included in the free tier)
+  performance_insights_enabled          = var.db_performance_insights_enabled
+  performance_insights_kms_key_id       = var.db_performance_insights_enabled ? aws_kms_key.rds.arn : null
+  performance_insights_retention_period = var.db_performance_insights_enabled ? var.db_performance_insights_retention_period : null
+
+  # Enhanced monitoring (0 disables it)
+  monitoring_interval = var.db_monitoring_interval
+  monitoring_role_arn = var.db_monitoring_interval > 0 ? aws_iam_role.rds_monitoring[0].arn : null
 
   tags = {
     Name = "${var.prefix}-db"
   }
 }
 
+# Alarm before connections run out. The API opens a connection per request, so a traffic spike exhausts
+# max_connections long before the instance runs out of CPU.
+resource "aws_cloudwatch_metric_alarm" "db_connections" {
+  alarm_name          = "${var.prefix}-db-connections"
+  alarm_description   = "Database connections are above ${var.db_connections_alarm_percent}% of max_connections (${var.db_max_connections})"
+  namespace           = "AWS/RDS"
+  metric_name         = "DatabaseConnections"
+  statistic           = "Maximum"
+  period              = 60
+  evaluation_periods  = 2
+  threshold           = local.connections_alarm_threshold
+  comparison_operator = "GreaterThanOrEqualToThreshold"
+  treat_missing_data  = "notBreaching"
+  alarm_actions       = var.alarm_actions
+  ok_actions          = var.alarm_actions
+
+  dimensions = {
+    DBInstanceIdentifier = aws_db_instance.postgres.identifier
+  }
+
+  tags = {
+    Name = "${var.prefix}-db-connections"
+  }
+}
+
+# IAM role for RDS enhanced monitoring
+resource "aws_iam_role" "rds_monitoring" {
+  count = var.db_monitoring_interval > 0 ? 1 : 0
+  name  = "${var.prefix}-rds-monitoring"
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Action = "sts:AssumeRole"
+        Effect = "Allow"
+        Principal = {
+          Service = "monitoring.rds.amazonaws.com"
+        }
+      }
+    ]
+  })
+
+  tags = {
+    Name = "${var.prefix}-rds-monitoring"
+  }
+}
+
+resource "aws_iam_role_policy_attachment" "rds_monitoring" {
+  count      = var.db_monitoring_interval > 0 ? 1 : 0
+  role       = aws_iam_role.rds_monitoring[0].name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AmazonRDSEnhancedMonitoringRole"
+}
+
 # PostgreSQL Parameter Group - Production (with prevent_destroy)
 resource "aws_db_parameter_group" "postgres" {
   count  = var.prevent_destroy ? 1 : 0
   name   = "${var.prefix}-pg-${local.pg_version}-prod"
   family = "postgres${local.pg_version}"
 
+  # Reject unencrypted client connections
+  parameter {
+    name  = "rds.force_ssl"
+    value = "1"
+  }
+
+  # Static, so a change applies at the next reboot
+  parameter {
+    name         = "max_connections"
+    value        = var.db_max_connections
+    apply_method = "pending-reboot"
+  }
+
   tags = {
     Name = "${var.prefix}-pg-${local.pg_version}-prod"
   }
@@ -136,6 +220,19 @@ resource "aws_db_parameter_group" "postgres_testing" {
   name   = "${var.prefix}-pg-${local.pg_version}-test"
   family = "postgres${local.pg_version}"
 
+  # Reject unencrypted client connections
+  parameter {
+    name  = "rds.force_ssl"
+    value = "1"
+  }
+
+  # Static, so a change applies at the next reboot
+  parameter {
+    name         = "max_connections"
+    value        = var.db_max_connections
+    apply_method = "pending-reboot"
+  }
+
   tags = {
     Name = "${var.prefix}-pg-${local.pg_version}-test"
   }
@@ -218,7 +315,7 @@ resource "null_resource" "db_setup" {
       
       # ✅ ADDED: Check if script exists
       if [ ! -f "${local.db_setup_script_path}" ]; then
-        echo "❌ ERROR: db_setup.sh script not found in ${path.root}/scripts"
+        echo "❌ ERROR: db_setup.sh script not found in ${path.module}/scripts"
         echo "Please ensure the db_setup.sh script is in the scripts directory"
         echo "Expected location: ${local.db_setup_script_path}"
         exit 1
@@ -301,7 +398,6 @@ resource "null_resource" "db_setup" {
       echo "✅ Database setup completed successfully via Terraform!"
     EOT
 
-    working_dir = path.root
     environment = {
       AWS_DEFAULT_REGION = var.aws_region
       PGCONNECT_TIMEOUT  = "30"
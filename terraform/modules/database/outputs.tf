@@ -13,11 +13,36 @@ output "db_instance_port" {
   value       = aws_db_instance.postgres.port
 }
 
+output "db_instance_id" {
+  description = "The identifier of the database instance"
+  value       = aws_db_instance.postgres.identifier
+}
+
 output "db_instance_name" {
   description = "The name of the database"
   value       = aws_db_instance.postgres.db_name
 }
 
+output "db_max_connections" {
+  description = "The max_connections pinned in the instance's parameter group"
+  value       = var.db_max_connections
+}
+
+output "db_connections_alarm_name" {
+  description = "The name of the alarm on the instance's DatabaseConnections"
+  value       = aws_cloudwatch_metric_alarm.db_connections.alarm_name
+}
+
+output "db_subnet_group_name" {
+  description = "The name of the database subnet group"
+  value       = aws_db_subnet_group.main.name
+}
+
+output "db_parameter_group_name" {
+  description = "The name of the parameter group attached to the database instance"
+  value       = local.parameter_group_name
+}
+
 output "db_kms_key_arn" {
   description = "The ARN of the KMS key used for RDS encryption"
   value       = aws_kms_key.rds.arn
@@ -35,4 +60,9 @@ output "database_connection_info" {
     app_user    = local.app_username
   }
   sensitive = true
-}
\ No newline at end of file
+}
+
+output "db_master_secret_arn" {
+  description = "The ARN of the Secrets Manager secret holding the master credentials (empty when Secrets Manager is disabled)"
+  value       = var.use_secrets_manager ? aws_secretsmanager_secret.db_master[0].arn : ""
+}
@@ -37,6 +37,12 @@ variable "db_allocated_storage" {
   default     = 20
 }
 
+variable "db_max_allocated_storage" {
+  description = "Upper limit in GB for storage autoscaling (0 disables autoscaling)"
+  type        = number
+  default     = 100
+}
+
 variable "db_engine_version" {
   description = "Version of PostgreSQL to use"
   type        = string
@@ -82,8 +88,65 @@ variable "db_backup_retention_period" {
   default     = 14
 }
 
+variable "deletion_protection" {
+  description = "Whether to enable deletion protection and take a final snapshot on delete (null decides from the prefix: off for test and dev prefixes)"
+  type        = bool
+  default     = null
+}
+
 variable "auto_setup_database" {
   description = "Whether to automatically run database setup"
   type        = bool
   default     = false
-}
\ No newline at end of file
+}
+
+variable "db_performance_insights_enabled" {
+  description = "Whether to enable Performance Insights"
+  type        = bool
+  default     = false
+}
+
+variable "db_performance_insights_retention_period" {
+  description = "Performance Insights retention period in days (7 is free tier)"
+  type        = number
+  default     = 7
+}
+
+variable "db_monitoring_interval" {
+  description = "Enhanced monitoring interval in seconds (0 disables enhanced monitoring)"
+  type        = number
+  default     = 0
+
+  validation {
+    condition     = contains([0, 1, 5, 10, 15, 30, 60], var.db_monitoring_interval)
+    error_message = "db_monitoring_interval must be one of 0, 1, 5, 10, 15, 30, 60."
+  }
+}
+
+variable "db_max_connections" {
+  description = "max_connections pinned in the parameter group. It must fit the instance class, whose memory sets the default: about 112 on db.t4g.micro"
+  type        = number
+  default     = 100
+
+  validation {
+    condition     = var.db_max_connections >= 10 && var.db_max_connections <= 5000
+    error_message = "db_max_connections must be between 10 and 5000."
+  }
+}
+
+variable "db_connections_alarm_percent" {
+  description = "Share of max_connections, in percent, at which the database connections alarm fires"
+  type        = number
+  default     = 80
+
+  validation {
+    condition     = var.db_connections_alarm_percent > 0 && var.db_connections_alarm_percent <= 100
+    error_message = "db_connections_alarm_percent must be between 1 and 100."
+  }
+}
+
+variable "alarm_actions" {
+  description = "ARNs notified when a database alarm fires or recovers, such as SNS topics"
+  type        = list(string)
+  default     = []
+}
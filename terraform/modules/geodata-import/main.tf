@@ -4,7 +4,7 @@ resource "aws_ecs_cluster" "geodata_import" {
 
   setting {
     name  = "containerInsights"
-    value = "disabled" # Disable to save costs
+    value = var.enable_container_insights ? "enabled" : "disabled" # Disabled by default to save costs
   }
 
   tags = merge(
@@ -26,6 +26,19 @@ resource "aws_ecs_task_definition" "geodata_import" {
   execution_role_arn       = aws_iam_role.ecs_execution.arn
   task_role_arn            = aws_iam_role.ecs_task.arn
 
+  runtime_platform {
+    operating_system_family = "LINUX"
+    cpu_architecture        = var.cpu_architecture
+  }
+
+  # Fargate includes 20 GiB; only declare storage when an import needs more
+  dynamic "ephemeral_storage" {
+    for_each = var.ephemeral_storage_gib > 20 ? [var.ephemeral_storage_gib] : []
+    content {
+      size_in_gib = ephemeral_storage.value
+    }
+  }
+
   container_definitions = jsonencode([{
     name  = "geodata-import"
     image = "${var.ecr_repository_url}:latest"
@@ -56,6 +69,14 @@ resource "aws_ecs_task_definition" "geodata_import" {
       }
     }
 
+    # Reap the processes started by the "sh -c" command override and forward signals on stop
+    linuxParameters = {
+      initProcessEnabled = true
+    }
+
+    # GDAL and the TIGER downloads write scratch files, so the root filesystem stays writable
+    readonlyRootFilesystem = false
+
     # Command will be overridden at runtime
     command = ["python", "manage.py", "import_tiger_data", "--help"]
   }])
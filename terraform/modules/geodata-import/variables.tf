@@ -32,4 +32,31 @@ variable "django_secret_key_arn" {
 variable "s3_bucket_arn" {
   description = "ARN of the S3 bucket for application data"
   type        = string
-}
\ No newline at end of file
+}
+variable "enable_container_insights" {
+  description = "Enable CloudWatch Container Insights on the cluster (adds per-task metric charges)"
+  type        = bool
+  default     = false
+}
+
+variable "cpu_architecture" {
+  description = "CPU architecture of the task; must match the platform the container image is built for"
+  type        = string
+  default     = "X86_64"
+
+  validation {
+    condition     = contains(["X86_64", "ARM64"], var.cpu_architecture)
+    error_message = "cpu_architecture must be X86_64 or ARM64."
+  }
+}
+
+variable "ephemeral_storage_gib" {
+  description = "Ephemeral storage for the task in GiB (20 is the Fargate default at no extra cost)"
+  type        = number
+  default     = 20
+
+  validation {
+    condition     = var.ephemeral_storage_gib >= 20 && var.ephemeral_storage_gib <= 200
+    error_message = "ephemeral_storage_gib must be between 20 and 200."
+  }
+}
@@ -0,0 +1,32 @@
+# Creates the Lambda and geolambda ECR repositories for a dev environment
+
+terraform {
+  required_version = ">= 1.5.0"
+
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = ">= 5.0"
+    }
+  }
+}
+
+provider "aws" {
+  region = "us-east-1"
+}
+
+module "lambda_ecr" {
+  source = "../.."
+
+  environment = "dev"
+
+  tags = {
+    Project     = "coalition"
+    Environment = "dev"
+  }
+}
+
+output "lambda_repository_url" {
+  description = "URL to push the Lambda image to"
+  value       = module.lambda_ecr.lambda_repository_url
+}
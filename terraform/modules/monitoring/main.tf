@@ -75,6 +75,18 @@ resource "aws_s3_bucket_policy" "alb_logs" {
         },
         Action   = "s3:PutObject",
         Resource = "${aws_s3_bucket.alb_logs.arn}/alb-logs/AWSLogs/${data.aws_caller_identity.current.account_id}/*"
+      },
+      {
+        Sid       = "DenyInsecureTransport",
+        Effect    = "Deny",
+        Principal = "*",
+        Action    = "s3:*",
+        Resource  = [aws_s3_bucket.alb_logs.arn, "${aws_s3_bucket.alb_logs.arn}/*"],
+        Condition = {
+          Bool = {
+            "aws:SecureTransport" = "false"
+          }
+        }
       }
     ]
   })
@@ -148,6 +160,102 @@ resource "aws_flow_log" "vpc_flow_logs" {
   }
 }
 
+# CloudWatch Dashboard
+data "aws_region" "current" {}
+
+locals {
+  dashboard_widgets = [
+    {
+      type   = "metric"
+      x      = 0
+      y      = 0
+      width  = 12
+      height = 6
+      properties = {
+        title  = "Database"
+        region = data.aws_region.current.name
+        stat   = "Average"
+        period = 300
+        metrics = [
+          ["AWS/RDS", "CPUUtilization", "DBInstanceIdentifier", "${var.prefix}-db"],
+          ["AWS/RDS", "DatabaseConnections", "DBInstanceIdentifier", "${var.prefix}-db"],
+          ["AWS/RDS", "FreeStorageSpace", "DBInstanceIdentifier", "${var.prefix}-db", { yAxis = "right" }]
+        ]
+      }
+    },
+    {
+      type   = "metric"
+      x      = 12
+      y      = 0
+      width  = 12
+      height = 6
+      properties = {
+        title  = "VPC Flow Logs"
+        region = data.aws_region.current.name
+        stat   = "Sum"
+        period = 300
+        metrics = [
+          ["AWS/Logs", "IncomingBytes", "LogGroupName", aws_cloudwatch_log_group.vpc_flow_log_group.name]
+        ]
+      }
+    }
+  ]
+
+  # Charted only for deployments fronted by an ALB
+  alb_dashboard_widgets = [for suffix in compact([var.alb_arn_suffix]) : {
+    type   = "metric"
+    x      = 0
+    y      = 6
+    width  = 12
+    height = 6
+    properties = {
+      title  = "Load Balancer"
+      region = data.aws_region.current.name
+      stat   = "Sum"
+      period = 300
+      metrics = [
+        ["AWS/ApplicationELB", "RequestCount", "LoadBalancer", suffix],
+        ["AWS/ApplicationELB", "HTTPCode_Target_5XX_Count", "LoadBalancer", suffix],
+        ["AWS/ApplicationELB", "TargetResponseTime", "LoadBalancer", suffix, { stat = "Average", yAxis = "right" }]
+      ]
+    }
+  }]
+
+  # Charted only for deployments running an ECS service
+  ecs_dashboard_widgets = [for service in compact([var.ecs_service_name]) : {
+    type   = "metric"
+    x      = 12
+    y      = 6
+    width  = 12
+    height = 6
+    properties = {
+      title  = "ECS Service"
+      region = data.aws_region.current.name
+      stat   = "Average"
+      period = 300
+      metrics = [
+        ["AWS/ECS", "CPUUtilization", "ClusterName", var.ecs_cluster_name, "ServiceName", service],
+        ["AWS/ECS", "MemoryUtilization", "ClusterName", var.ecs_cluster_name, "ServiceName", service]
+      ]
+    }
+  }]
+}
+
+resource "aws_cloudwatch_dashboard" "main" {
+  dashboard_name = "${var.prefix}-dashboard"
+
+  dashboard_body = jsonencode({
+    widgets = concat(local.dashboard_widgets, local.alb_dashboard_widgets, local.ecs_dashboard_widgets)
+  })
+
+  lifecycle {
+    precondition {
+      condition     = var.ecs_service_name == "" || var.ecs_cluster_name != ""
+      error_message = "ecs_cluster_name must be set when ecs_service_name is."
+    }
+  }
+}
+
 # SNS Topic for Budget Alerts
 resource "aws_sns_topic" "budget_alerts" {
   name = "${var.prefix}-budget-alerts"
@@ -8,6 +8,11 @@ output "vpc_flow_logs_group" {
   value       = aws_cloudwatch_log_group.vpc_flow_log_group.name
 }
 
+output "dashboard_name" {
+  description = "Name of the CloudWatch dashboard for the deployment"
+  value       = aws_cloudwatch_dashboard.main.dashboard_name
+}
+
 output "budget_info" {
   description = "Budget configuration summary"
   value       = "Monthly budget alert of $${aws_budgets_budget.monthly.limit_amount} set with notifications at 70%, 90%, and forecast thresholds to ${var.alert_email}"
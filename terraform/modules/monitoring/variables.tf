@@ -18,4 +18,21 @@ variable "budget_limit_amount" {
 variable "alert_email" {
   description = "Email address to receive budget and other alerts"
   type        = string
-}
\ No newline at end of file
+}
+variable "alb_arn_suffix" {
+  description = "ARN suffix of the ALB to chart on the dashboard, such as app/name/id (empty for none)"
+  type        = string
+  default     = ""
+}
+
+variable "ecs_cluster_name" {
+  description = "Name of the cluster running ecs_service_name"
+  type        = string
+  default     = ""
+}
+
+variable "ecs_service_name" {
+  description = "Name of the ECS service to chart on the dashboard (empty for none)"
+  type        = string
+  default     = ""
+}
@@ -4,6 +4,12 @@
 locals {
   vpc_id = var.create_vpc ? aws_vpc.main[0].id : var.vpc_id
 
+  # The a and b subnets go in the first and second of these zones
+  availability_zones = length(var.availability_zones) > 0 ? var.availability_zones : [
+    "${var.aws_region}a",
+    "${var.aws_region}b"
+  ]
+
   # Subnet outputs will be either the created subnets or the provided existing ones
   public_subnet_ids = var.create_public_subnets ? [
     aws_subnet.public_a[0].id,
@@ -53,7 +59,7 @@ resource "aws_subnet" "public_a" {
 
   vpc_id                  = local.vpc_id
   cidr_block              = var.public_subnet_a_cidr
-  availability_zone       = "${var.aws_region}a"
+  availability_zone       = local.availability_zones[0]
   map_public_ip_on_launch = true
 
   tags = {
@@ -66,7 +72,7 @@ resource "aws_subnet" "public_b" {
 
   vpc_id                  = local.vpc_id
   cidr_block              = var.public_subnet_b_cidr
-  availability_zone       = "${var.aws_region}b"
+  availability_zone       = local.availability_zones[1]
   map_public_ip_on_launch = true
 
   tags = {
@@ -80,7 +86,7 @@ resource "aws_subnet" "private_a" {
 
   vpc_id                  = local.vpc_id
   cidr_block              = var.private_subnet_a_cidr
-  availability_zone       = "${var.aws_region}a"
+  availability_zone       = local.availability_zones[0]
   map_public_ip_on_launch = false
 
   tags = {
@@ -93,7 +99,7 @@ resource "aws_subnet" "private_b" {
 
   vpc_id                  = local.vpc_id
   cidr_block              = var.private_subnet_b_cidr
-  availability_zone       = "${var.aws_region}b"
+  availability_zone       = local.availability_zones[1]
   map_public_ip_on_launch = false
 
   tags = {
@@ -107,7 +113,7 @@ resource "aws_subnet" "private_db_a" {
 
   vpc_id                  = local.vpc_id
   cidr_block              = var.private_db_subnet_a_cidr
-  availability_zone       = "${var.aws_region}a"
+  availability_zone       = local.availability_zones[0]
   map_public_ip_on_launch = false
 
   tags = {
@@ -120,7 +126,7 @@ resource "aws_subnet" "private_db_b" {
 
   vpc_id                  = local.vpc_id
   cidr_block              = var.private_db_subnet_b_cidr
-  availability_zone       = "${var.aws_region}b"
+  availability_zone       = local.availability_zones[1]
   map_public_ip_on_launch = false
 
   tags = {
@@ -20,12 +20,33 @@ variable "vpc_id" {
   description = "ID of an existing VPC to use (if create_vpc is false)"
   type        = string
   default     = ""
+
+  validation {
+    condition     = var.create_vpc ? var.vpc_id == "" : can(regex("^vpc-[0-9a-f]+$", var.vpc_id))
+    error_message = "vpc_id must be the ID of an existing VPC when create_vpc is false, and empty when create_vpc is true."
+  }
 }
 
 variable "vpc_cidr" {
   description = "CIDR block for the VPC (if create_vpc is true)"
   type        = string
   default     = "10.0.0.0/16"
+
+  validation {
+    condition     = !var.create_vpc || can(cidrhost(var.vpc_cidr, 0))
+    error_message = "vpc_cidr must be a valid CIDR block when create_vpc is true."
+  }
+}
+
+variable "availability_zones" {
+  description = "The two availability zones for the a and b subnets, in order. Defaults to the region's a and b zones."
+  type        = list(string)
+  default     = []
+
+  validation {
+    condition     = length(var.availability_zones) == 0 || length(var.availability_zones) == 2
+    error_message = "availability_zones must be empty or list exactly two availability zones."
+  }
 }
 
 # Variables for public subnets
@@ -39,18 +60,33 @@ variable "public_subnet_ids" {
   description = "IDs of existing public subnets to use (if create_public_subnets is false)"
   type        = list(string)
   default     = []
+
+  validation {
+    condition     = length(var.public_subnet_ids) == 0 || (!var.create_vpc && !var.create_public_subnets)
+    error_message = "public_subnet_ids can only be set when using an existing VPC (create_vpc = false) with existing public subnets (create_public_subnets = false)."
+  }
 }
 
 variable "public_subnet_a_cidr" {
   description = "CIDR block for public subnet in AZ a (if create_public_subnets is true)"
   type        = string
   default     = "10.0.1.0/24"
+
+  validation {
+    condition     = !var.create_public_subnets || can(cidrhost(var.public_subnet_a_cidr, 0))
+    error_message = "public_subnet_a_cidr must be a valid CIDR block when create_public_subnets is true."
+  }
 }
 
 variable "public_subnet_b_cidr" {
   description = "CIDR block for public subnet in AZ b (if create_public_subnets is true)"
   type        = string
   default     = "10.0.2.0/24"
+
+  validation {
+    condition     = !var.create_public_subnets || can(cidrhost(var.public_subnet_b_cidr, 0))
+    error_message = "public_subnet_b_cidr must be a valid CIDR block when create_public_subnets is true."
+  }
 }
 
 # Variables for private app subnets
@@ -64,18 +100,33 @@ variable "private_subnet_ids" {
   description = "IDs of existing private app subnets to use (if create_private_subnets is false)"
   type        = list(string)
   default     = []
+
+  validation {
+    condition     = length(var.private_subnet_ids) == 0 || (!var.create_vpc && !var.create_private_subnets)
+    error_message = "private_subnet_ids can only be set when using an existing VPC (create_vpc = false) with existing private app subnets (create_private_subnets = false)."
+  }
 }
 
 variable "private_subnet_a_cidr" {
   description = "CIDR block for private app subnet in AZ a (if create_private_subnets is true)"
   type        = string
   default     = "10.0.3.0/24"
+
+  validation {
+    condition     = !var.create_private_subnets || can(cidrhost(var.private_subnet_a_cidr, 0))
+    error_message = "private_subnet_a_cidr must be a valid CIDR block when create_private_subnets is true."
+  }
 }
 
 variable "private_subnet_b_cidr" {
   description = "CIDR block for private app subnet in AZ b (if create_private_subnets is true)"
   type        = string
   default     = "10.0.4.0/24"
+
+  validation {
+    condition     = !var.create_private_subnets || can(cidrhost(var.private_subnet_b_cidr, 0))
+    error_message = "private_subnet_b_cidr must be a valid CIDR block when create_private_subnets is true."
+  }
 }
 
 # Variables for private database subnets
@@ -89,23 +140,38 @@ variable "db_subnet_ids" {
   description = "IDs of existing private database subnets to use (if create_db_subnets is false)"
   type        = list(string)
   default     = []
+
+  validation {
+    condition     = length(var.db_subnet_ids) == 0 || (!var.create_vpc && !var.create_db_subnets)
+    error_message = "db_subnet_ids can only be set when using an existing VPC (create_vpc = false) with existing database subnets (create_db_subnets = false)."
+  }
 }
 
 variable "private_db_subnet_a_cidr" {
   description = "CIDR block for private database subnet in AZ a (if create_db_subnets is true)"
   type        = string
   default     = "10.0.5.0/24"
+
+  validation {
+    condition     = !var.create_db_subnets || can(cidrhost(var.private_db_subnet_a_cidr, 0))
+    error_message = "private_db_subnet_a_cidr must be a valid CIDR block when create_db_subnets is true."
+  }
 }
 
 variable "private_db_subnet_b_cidr" {
   description = "CIDR block for private database subnet in AZ b (if create_db_subnets is true)"
   type        = string
   default     = "10.0.6.0/24"
+
+  validation {
+    condition     = !var.create_db_subnets || can(cidrhost(var.private_db_subnet_b_cidr, 0))
+    error_message = "private_db_subnet_b_cidr must be a valid CIDR block when create_db_subnets is true."
+  }
 }
 
 # VPC Endpoints
 variable "create_vpc_endpoints" {
-  description = "Whether to create interface VPC endpoints for AWS services (Secrets Manager, ECR, CloudWatch Logs)"
+  description = "Whether to create interface VPC endpoints for AWS services (Secrets Manager, CloudWatch Logs, Location Places). Without them, private subnets can only reach S3"
   type        = bool
   default     = false
 
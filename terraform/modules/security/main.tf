@@ -111,12 +111,64 @@ resource "aws_security_group" "bastion_sg" {
     description = "SSH access from allowed IPs"
   }
 
-  egress {
-    from_port   = 0
-    to_port     = 0
-    protocol    = "-1"
-    cidr_blocks = ["0.0.0.0/0"]
-    description = "Allow all outbound traffic"
+  dynamic "egress" {
+    for_each = var.restrict_bastion_egress ? [] : [1]
+
+    content {
+      from_port   = 0
+      to_port     = 0
+      protocol    = "-1"
+      cidr_blocks = ["0.0.0.0/0"]
+      description = "Allow all outbound traffic"
+    }
+  }
+
+  # Restricted egress is limited to what the bastion uses: Session Manager and AWS APIs over HTTPS, yum's package
+  # mirrors, and the database it tunnels to
+  dynamic "egress" {
+    for_each = var.restrict_bastion_egress ? {
+      443 = "HTTPS for Session Manager, AWS APIs and package repositories"
+      80  = "HTTP for yum package mirrors"
+    } : {}
+
+    content {
+      from_port   = egress.key
+      to_port     = egress.key
+      protocol    = "tcp"
+      cidr_blocks = ["0.0.0.0/0"]
+      description = egress.value
+    }
+  }
+
+  dynamic "egress" {
+    for_each = var.restrict_bastion_egress && var.create_db_sg ? [aws_security_group.db_sg[0].id] : []
+
+    content {
+      from_port       = 5432
+      to_port         = 5432
+      protocol        = "tcp"
+      security_groups = [egress.value]
+      description     = "PostgreSQL to the database"
+    }
+  }
+
+  dynamic "egress" {
+    for_each = var.restrict_bastion_egress && length(var.bastion_db_egress_cidrs) > 0 ? [var.bastion_db_egress_cidrs] : []
+
+    content {
+      from_port   = 5432
+      to_port     = 5432
+      protocol    = "tcp"
+      cidr_blocks = egress.value
+      description = "PostgreSQL to a database outside this module"
+    }
+  }
+
+  lifecycle {
+    precondition {
+      condition     = !var.restrict_bastion_egress || var.create_db_sg || length(var.bastion_db_egress_cidrs) > 0
+      error_message = "restrict_bastion_egress needs create_db_sg or bastion_db_egress_cidrs, or the bastion could not reach the database."
+    }
   }
 
   tags = {
@@ -136,6 +188,29 @@ resource "aws_wafv2_web_acl" "main" {
     allow {}
   }
 
+  # Blocks an IP address that floods the application before the managed rules are evaluated
+  rule {
+    name     = "RateLimitPerIP"
+    priority = 0
+
+    action {
+      block {}
+    }
+
+    statement {
+      rate_based_statement {
+        limit              = var.waf_rate_limit
+        aggregate_key_type = "IP"
+      }
+    }
+
+    visibility_config {
+      cloudwatch_metrics_enabled = true
+      metric_name                = "RateLimitPerIP"
+      sampled_requests_enabled   = true
+    }
+  }
+
   rule {
     name     = "AWS-AWSManagedRulesSQLiRuleSet"
     priority = 1
@@ -158,6 +233,50 @@ resource "aws_wafv2_web_acl" "main" {
     }
   }
 
+  rule {
+    name     = "AWS-AWSManagedRulesCommonRuleSet"
+    priority = 2
+
+    override_action {
+      none {}
+    }
+
+    statement {
+      managed_rule_group_statement {
+        name        = "AWSManagedRulesCommonRuleSet"
+        vendor_name = "AWS"
+      }
+    }
+
+    visibility_config {
+      cloudwatch_metrics_enabled = true
+      metric_name                = "AWS-AWSManagedRulesCommonRuleSet"
+      sampled_requests_enabled   = true
+    }
+  }
+
+  rule {
+    name     = "AWS-AWSManagedRulesKnownBadInputsRuleSet"
+    priority = 3
+
+    override_action {
+      none {}
+    }
+
+    statement {
+      managed_rule_group_statement {
+        name        = "AWSManagedRulesKnownBadInputsRuleSet"
+        vendor_name = "AWS"
+      }
+    }
+
+    visibility_config {
+      cloudwatch_metrics_enabled = true
+      metric_name                = "AWS-AWSManagedRulesKnownBadInputsRuleSet"
+      sampled_requests_enabled   = true
+    }
+  }
+
   visibility_config {
     cloudwatch_metrics_enabled = true
     metric_name                = "${var.prefix}-waf"
@@ -45,8 +45,31 @@ variable "create_waf" {
   default     = true
 }
 
+variable "waf_rate_limit" {
+  description = "Requests a single IP address may make in any 5-minute window before the WAF blocks it"
+  type        = number
+  default     = 2000
+
+  validation {
+    condition     = var.waf_rate_limit >= 100 && var.waf_rate_limit <= 2000000000
+    error_message = "waf_rate_limit must be between 100 and 2000000000."
+  }
+}
+
 variable "create_bastion_sg" {
   description = "Whether to create the bastion security group (only needed in shared account)"
   type        = bool
   default     = true
 }
+
+variable "restrict_bastion_egress" {
+  description = "Whether to limit the bastion's outbound traffic to HTTPS, HTTP and PostgreSQL to the database instead of allowing all of it"
+  type        = bool
+  default     = false
+}
+
+variable "bastion_db_egress_cidrs" {
+  description = "CIDR blocks of a database this module does not create, which a bastion with restricted egress may reach on PostgreSQL's port"
+  type        = list(string)
+  default     = []
+}
@@ -125,7 +125,19 @@ resource "aws_s3_bucket_policy" "assets" {
             }
           }
         } : {}
-      )
+      ),
+      {
+        Sid       = "DenyInsecureTransport"
+        Effect    = "Deny"
+        Principal = "*"
+        Action    = "s3:*"
+        Resource  = [aws_s3_bucket.assets.arn, "${aws_s3_bucket.assets.arn}/*"]
+        Condition = {
+          Bool = {
+            "aws:SecureTransport" = "false"
+          }
+        }
+      }
     ]
   })
 
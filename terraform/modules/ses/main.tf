@@ -96,7 +96,8 @@ resource "aws_iam_user_policy" "ses_smtp" {
         ]
         Resource = "*"
         Condition = {
-          StringEquals = {
+          # StringLike, so the domain-wide wildcard matches any sender in the domain
+          StringLike = {
             "ses:FromAddress" = var.verify_domain ? ["*@${var.domain_name}", var.from_email] : [var.from_email]
           }
         }
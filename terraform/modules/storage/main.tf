@@ -21,10 +21,11 @@ resource "aws_s3_bucket" "static_assets" {
 resource "aws_s3_bucket_public_access_block" "static_assets" {
   bucket = aws_s3_bucket.static_assets.id
 
+  # The bucket is only public when it serves assets directly (CloudFront disabled)
   block_public_acls       = var.enable_cloudfront
-  block_public_policy     = false
+  block_public_policy     = var.enable_cloudfront
   ignore_public_acls      = var.enable_cloudfront
-  restrict_public_buckets = false
+  restrict_public_buckets = var.enable_cloudfront
 }
 
 # Bucket ownership controls
@@ -118,6 +119,18 @@ resource "aws_s3_bucket_policy" "static_assets_cloudfront" {
         }
         Action   = "s3:GetObject"
         Resource = "${aws_s3_bucket.static_assets.arn}/*"
+      },
+      {
+        Sid       = "DenyInsecureTransport"
+        Effect    = "Deny"
+        Principal = "*"
+        Action    = "s3:*"
+        Resource  = [aws_s3_bucket.static_assets.arn, "${aws_s3_bucket.static_assets.arn}/*"]
+        Condition = {
+          Bool = {
+            "aws:SecureTransport" = "false"
+          }
+        }
       }
     ]
   })
@@ -143,6 +156,18 @@ resource "aws_s3_bucket_policy" "static_assets_public" {
         Principal = "*"
         Action    = "s3:GetObject"
         Resource  = "${aws_s3_bucket.static_assets.arn}/*"
+      },
+      {
+        Sid       = "DenyInsecureTransport"
+        Effect    = "Deny"
+        Principal = "*"
+        Action    = "s3:*"
+        Resource  = [aws_s3_bucket.static_assets.arn, "${aws_s3_bucket.static_assets.arn}/*"]
+        Condition = {
+          Bool = {
+            "aws:SecureTransport" = "false"
+          }
+        }
       }
     ]
   })
@@ -243,8 +268,8 @@ resource "aws_cloudfront_distribution" "static_assets" {
     cloudfront_default_certificate = true
   }
 
-  # Price class (all edge locations for best performance)
-  price_class = "PriceClass_All"
+  # Price class (all edge locations in production, cheapest tier elsewhere)
+  price_class = var.price_class
 
   tags = {
     Name = "${var.prefix}-static-assets-cdn"
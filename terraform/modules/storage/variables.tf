@@ -80,4 +80,15 @@ variable "static_cache_max_ttl" {
   description = "Maximum TTL for Django static files in seconds"
   type        = number
   default     = 86400 # 1 day
-}
\ No newline at end of file
+}
+
+variable "price_class" {
+  description = "CloudFront price class for the static assets distribution"
+  type        = string
+  default     = "PriceClass_All" # All edge locations for best performance
+
+  validation {
+    condition     = contains(["PriceClass_100", "PriceClass_200", "PriceClass_All"], var.price_class)
+    error_message = "price_class must be PriceClass_100, PriceClass_200 or PriceClass_All."
+  }
+}
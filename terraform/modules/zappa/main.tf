@@ -223,6 +223,17 @@ resource "aws_iam_policy" "zappa_deployment" {
           "iam:PassRole"
         ]
         Resource = "arn:aws:iam::*:role/${var.prefix}-*"
+      },
+      {
+        # The prod stage runs with xray_tracing, which sends segments under this role; X-Ray has no resource ARNs
+        Effect = "Allow"
+        Action = [
+          "xray:PutTraceSegments",
+          "xray:PutTelemetryRecords",
+          "xray:GetSamplingRules",
+          "xray:GetSamplingTargets"
+        ]
+        Resource = "*"
       }
     ]
   })
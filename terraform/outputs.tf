@@ -26,6 +26,16 @@ output "bastion_public_ip" {
   description = "Public IP address of the bastion host"
 }
 
+output "bastion_instance_id" {
+  value       = module.bastion.bastion_instance_id
+  description = "Instance ID of the bastion host, for Session Manager connections"
+}
+
+output "ssm_session_command" {
+  value       = "aws ssm start-session --target ${module.bastion.bastion_instance_id}"
+  description = "Command to open a shell on the bastion host through Session Manager, without SSH"
+}
+
 output "ssh_tunnel_command" {
   value       = "ssh -i ${var.bastion_key_name}.pem ec2-user@${module.bastion.bastion_public_ip} -L 5432:${module.database.db_instance_address}:5432"
   description = "Command to create SSH tunnel for database access"
@@ -169,6 +179,31 @@ output "acm_certificate_arn" {
   value       = aws_acm_certificate.main.arn
 }
 
+output "acm_validation_records" {
+  description = "DNS records that validate the ACM certificate; create them by hand when manage_dns is false"
+  value = [
+    for dvo in aws_acm_certificate.main.domain_validation_options : {
+      name  = dvo.resource_record_name
+      type  = dvo.resource_record_type
+      value = dvo.resource_record_value
+    }
+  ]
+}
+
+output "api_domain_target" {
+  description = "Regional API Gateway domain the api record should alias or CNAME to"
+  value       = aws_api_gateway_domain_name.api.regional_domain_name
+}
+
+output "dns_records" {
+  description = "Fully qualified names of the records managed in Route 53 (empty when manage_dns is false)"
+  value = concat(
+    aws_route53_record.api[*].fqdn,
+    aws_route53_record.apex[*].fqdn,
+    aws_route53_record.www[*].fqdn,
+  )
+}
+
 # Zappa Outputs
 output "zappa_deployment_role_name" {
   description = "Name of the IAM role for Zappa Lambda execution"
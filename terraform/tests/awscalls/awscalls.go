@@ -0,0 +1,240 @@
+// Package awscalls records every AWS API call a test makes through the SDK: the service, operation, duration,
+// error and how often the SDK retried it. A summary is logged when the test finishes, and with AWS_CALLS_DIR set
+// each test's calls are also written there, so throttling behind a flaky test can be traced to the calls that
+// caused it and to the tests that make the most of them.
+//
+// The configuration LoadConfig returns also retries throttled calls in adaptive mode and caps the calls in flight
+// to each service, so parallel tests wait for throttle-prone services such as IAM instead of failing.
+package awscalls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// Test is the part of a test the recorder needs. Calls are recorded for any test, but only one that also has
+// Cleanup and Logf, such as *testing.T, gets a summary when it finishes.
+type Test interface {
+	Name() string
+}
+
+// loggingTest is a test the summary can be logged to
+type loggingTest interface {
+	Test
+	Cleanup(func())
+	Logf(format string, args ...interface{})
+}
+
+// Call is one API call, including the SDK's retries of it
+type Call struct {
+	Time      time.Time     `json:"time"`
+	Service   string        `json:"service"`
+	Operation string        `json:"operation"`
+	Duration  time.Duration `json:"duration_ns"`
+	Retries   int           `json:"retries"`
+	Throttles int           `json:"throttles,omitempty"` // Attempts the service throttled
+	Error     string        `json:"error,omitempty"`     // The API error code, or the message of any other error
+}
+
+var (
+	callsMutex sync.Mutex
+	calls      = map[string][]Call{}
+)
+
+// LoadConfig loads the default AWS configuration for the region with the rate limits and the recorder attached, so
+// every client made from it retries throttling, shares the service caps and records its calls for the test. A test
+// that called Mock gets a configuration whose clients answer from its responder instead.
+func LoadConfig(ctx context.Context, t Test, region string) (aws.Config, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region), config.WithRetryer(newRetryer))
+	if err != nil {
+		return cfg, err
+	}
+	limitConcurrency(&cfg)
+	Attach(&cfg, t)
+	attachMock(&cfg, t.Name())
+	return cfg, nil
+}
+
+// Attach adds the recorder to a configuration loaded some other way
+func Attach(cfg *aws.Config, t Test) {
+	name := t.Name()
+	logging, _ := t.(loggingTest)
+
+	cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+		// After the service metadata is registered, and before the retry loop, so every attempt is counted
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("RecordAPICall", func(
+			ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+		) (middleware.InitializeOutput, middleware.Metadata, error) {
+			start := time.Now()
+			out, metadata, err := next.HandleInitialize(ctx, in)
+
+			call := Call{
+				Time:      start.UTC(),
+				Service:   awsmiddleware.GetServiceID(ctx),
+				Operation: awsmiddleware.GetOperationName(ctx),
+				Duration:  time.Since(start),
+			}
+			if attempts, ok := retry.GetAttemptResults(metadata); ok && len(attempts.Results) > 0 {
+				call.Retries = len(attempts.Results) - 1
+				for _, attempt := range attempts.Results {
+					if attempt.Err != nil && retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(attempt.Err).Bool() {
+						call.Throttles++
+					}
+				}
+			}
+			if err != nil {
+				call.Error = errorCode(err)
+			}
+			record(name, logging, call)
+			return out, metadata, err
+		}), middleware.After)
+	})
+}
+
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return err.Error()
+}
+
+func record(name string, t loggingTest, call Call) {
+	callsMutex.Lock()
+	defer callsMutex.Unlock()
+
+	if _, registered := calls[name]; !registered && t != nil {
+		t.Cleanup(func() { flush(t, name) })
+	}
+	calls[name] = append(calls[name], call)
+}
+
+// Calls returns the calls recorded so far for the test with the given name
+func Calls(name string) []Call {
+	callsMutex.Lock()
+	defer callsMutex.Unlock()
+	return append([]Call(nil), calls[name]...)
+}
+
+// OperationSummary totals the calls to one operation
+type OperationSummary struct {
+	Service   string        `json:"service"`
+	Operation string        `json:"operation"`
+	Calls     int           `json:"calls"`
+	Retries   int           `json:"retries"`
+	Errors    int           `json:"errors"`
+	Throttles int           `json:"throttles"`
+	Duration  time.Duration `json:"duration_ns"`
+}
+
+// Summarize totals calls by operation, busiest first
+func Summarize(calls []Call) []OperationSummary {
+	index := map[string]int{}
+	var summaries []OperationSummary
+	for _, call := range calls {
+		key := call.Service + " " + call.Operation
+		i, ok := index[key]
+		if !ok {
+			i = len(summaries)
+			index[key] = i
+			summaries = append(summaries, OperationSummary{Service: call.Service, Operation: call.Operation})
+		}
+		summary := &summaries[i]
+		summary.Calls++
+		summary.Retries += call.Retries
+		summary.Duration += call.Duration
+		if call.Error != "" {
+			summary.Errors++
+		}
+		summary.Throttles += call.Throttles
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Calls+summaries[i].Retries > summaries[j].Calls+summaries[j].Retries
+	})
+	return summaries
+}
+
+// Report renders the summaries as a table for the test log
+func Report(summaries []OperationSummary) string {
+	var total OperationSummary
+	for _, summary := range summaries {
+		total.Calls += summary.Calls
+		total.Retries += summary.Retries
+		total.Throttles += summary.Throttles
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "AWS API calls: %d calls, %d retries, %d throttled", total.Calls, total.Retries, total.Throttles)
+	for _, summary := range summaries {
+		fmt.Fprintf(&report, "\n  %-16s %-36s %5d calls %4d retries %4d errors %4d throttled %8s",
+			summary.Service, summary.Operation, summary.Calls, summary.Retries, summary.Errors, summary.Throttles,
+			summary.Duration.Round(time.Millisecond))
+	}
+	return report.String()
+}
+
+// testArtifact is what is written to AWS_CALLS_DIR for one test
+type testArtifact struct {
+	Test       string             `json:"test"`
+	Operations []OperationSummary `json:"operations"`
+	Calls      []Call             `json:"calls"`
+}
+
+// flush logs the summary of a test's calls and writes them to AWS_CALLS_DIR. Calls made after the flush, such as
+// by cleanups that run after it, are flushed again and added to the same file.
+func flush(t loggingTest, name string) {
+	callsMutex.Lock()
+	recorded := calls[name]
+	delete(calls, name)
+	callsMutex.Unlock()
+
+	if len(recorded) == 0 {
+		return
+	}
+	t.Logf("%s", Report(Summarize(recorded)))
+
+	dir := os.Getenv("AWS_CALLS_DIR")
+	if dir == "" {
+		return
+	}
+	if err := writeArtifact(dir, name, recorded); err != nil {
+		t.Logf("Failed to write AWS API calls to %s: %v", dir, err)
+	}
+}
+
+func writeArtifact(dir, name string, recorded []Call) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, strings.NewReplacer("/", "_", " ", "_").Replace(name)+".json")
+
+	artifact := testArtifact{Test: name}
+	if existing, err := os.ReadFile(path); err == nil {
+		if err = json.Unmarshal(existing, &artifact); err != nil {
+			return err
+		}
+	}
+	artifact.Calls = append(artifact.Calls, recorded...)
+	artifact.Operations = Summarize(artifact.Calls)
+
+	body, err := json.MarshalIndent(artifact, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, body, 0o644)
+}
@@ -0,0 +1,134 @@
+package awscalls
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type namedTest string
+
+func (n namedTest) Name() string { return string(n) }
+
+// throttlingClient answers every request the way STS does when it throttles the caller
+type throttlingClient struct{}
+
+func (throttlingClient) Do(*http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Header:     http.Header{"Content-Type": []string{"text/xml"}},
+		Body: io.NopCloser(strings.NewReader(`<ErrorResponse><Error><Type>Sender</Type><Code>Throttling</Code>` +
+			`<Message>Rate exceeded</Message></Error><RequestId>1</RequestId></ErrorResponse>`)),
+	}, nil
+}
+
+func TestRecorderCountsRetriesAndThrottles(t *testing.T) {
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient:  throttlingClient{},
+		Retryer: func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.MaxAttempts = 3
+				o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+				o.RateLimiter = ratelimit.None
+			})
+		},
+	}
+	test := namedTest(t.Name() + "/sts")
+	Attach(&cfg, test)
+
+	_, err := sts.NewFromConfig(cfg).GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+	require.Error(t, err)
+
+	calls := Calls(test.Name())
+	require.Len(t, calls, 1, "Retries should be part of the call they retry")
+	assert.Equal(t, "STS", calls[0].Service)
+	assert.Equal(t, "GetCallerIdentity", calls[0].Operation)
+	assert.Equal(t, 2, calls[0].Retries)
+	assert.Equal(t, 3, calls[0].Throttles)
+	assert.Equal(t, "Throttling", calls[0].Error)
+}
+
+// slowClient answers GetCallerIdentity after a delay and keeps the highest number of requests it had in flight
+type slowClient struct {
+	inFlight, maxInFlight atomic.Int32
+}
+
+func (c *slowClient) Do(*http.Request) (*http.Response, error) {
+	current := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		highest := c.maxInFlight.Load()
+		if current <= highest || c.maxInFlight.CompareAndSwap(highest, current) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/xml"}},
+		Body: io.NopCloser(strings.NewReader(`<GetCallerIdentityResponse><GetCallerIdentityResult>` +
+			`<Account>123456789012</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`)),
+	}, nil
+}
+
+func TestServiceConcurrencyIsSharedByClients(t *testing.T) {
+	ServiceConcurrency["STS"] = 1
+	t.Cleanup(func() { delete(ServiceConcurrency, "STS") })
+
+	client := &slowClient{}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		cfg := aws.Config{
+			Region:      "us-east-1",
+			Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+			HTTPClient:  client,
+			Retryer:     newRetryer,
+		}
+		limitConcurrency(&cfg)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := sts.NewFromConfig(cfg).GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), client.maxInFlight.Load(), "Clients should share the cap of their service")
+}
+
+func TestSummarize(t *testing.T) {
+	summaries := Summarize([]Call{
+		{Service: "EC2", Operation: "DescribeVpcs", Duration: time.Second},
+		{Service: "S3", Operation: "ListObjectVersions", Duration: time.Second},
+		{Service: "EC2", Operation: "DescribeVpcs", Duration: time.Second, Retries: 2, Throttles: 2, Error: "Throttling"},
+	})
+
+	assert.Equal(t, []OperationSummary{
+		{Service: "EC2", Operation: "DescribeVpcs", Calls: 2, Retries: 2, Errors: 1, Throttles: 2, Duration: 2 * time.Second},
+		{Service: "S3", Operation: "ListObjectVersions", Calls: 1, Duration: time.Second},
+	}, summaries)
+	assert.Equal(t, "AWS API calls: 3 calls, 2 retries, 2 throttled\n"+
+		"  EC2              DescribeVpcs                             2 calls    2 retries"+
+		"    1 errors    2 throttled       2s\n"+
+		"  S3               ListObjectVersions                       1 calls    0 retries"+
+		"    0 errors    0 throttled       1s",
+		Report(summaries))
+}
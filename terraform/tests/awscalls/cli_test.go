@@ -0,0 +1,88 @@
+package awscalls
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCLI puts an aws command on the PATH that runs the script, in place of the AWS CLI
+func fakeCLI(t *testing.T, script string) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "aws"), []byte("#!/bin/sh\n"+script), 0o755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestRunCLIDecodesAndRecords(t *testing.T) {
+	fakeCLI(t, `echo '{"name": "'"$1 $2"'"}'`)
+	test := namedTest(t.Name() + "/cli")
+
+	var result struct {
+		Name string `json:"name"`
+	}
+	require.NoError(t, RunCLI(context.Background(), test, "us-east-1", &result, "backup", "list-backup-plans"))
+	assert.Equal(t, "backup list-backup-plans", result.Name)
+
+	calls := Calls(test.Name())
+	require.Len(t, calls, 1)
+	assert.Equal(t, "backup", calls[0].Service)
+	assert.Equal(t, "list-backup-plans", calls[0].Operation)
+	assert.Empty(t, calls[0].Error)
+}
+
+func TestRunCLIRecordsThrottling(t *testing.T) {
+	fakeCLI(t, `echo "An error occurred (ThrottlingException) when calling the GetTraceSummaries operation" >&2
+exit 254`)
+	test := namedTest(t.Name() + "/cli")
+
+	err := RunCLI(context.Background(), test, "us-east-1", nil, "xray", "get-trace-summaries")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "aws xray get-trace-summaries --region us-east-1")
+	assert.Equal(t, "ThrottlingException", CLIErrorCode(err))
+
+	calls := Calls(test.Name())
+	require.Len(t, calls, 1)
+	assert.Equal(t, "ThrottlingException", calls[0].Error)
+	assert.Equal(t, 1, calls[0].Throttles)
+}
+
+func TestRunCLIErrorWithoutCode(t *testing.T) {
+	fakeCLI(t, `echo "Unknown options: --bogus" >&2
+exit 252`)
+
+	err := RunCLI(context.Background(), namedTest(t.Name()+"/cli"), "us-east-1", nil, "backup", "list-backup-plans")
+	var cliErr *CLIError
+	require.ErrorAs(t, err, &cliErr)
+	assert.Equal(t, "backup", cliErr.Service)
+	assert.Equal(t, "list-backup-plans", cliErr.Operation)
+	assert.Empty(t, cliErr.Code)
+	assert.Equal(t, "Unknown options: --bogus", cliErr.Stderr)
+	assert.Empty(t, CLIErrorCode(err))
+}
+
+func TestRunCLIWaitsForServiceSlot(t *testing.T) {
+	fakeCLI(t, `echo '{}'`)
+	test := namedTest(t.Name() + "/cli")
+
+	// Hold every Cost Explorer slot, as calls from parallel tests would
+	slot := serviceSlots("Cost Explorer")
+	for i := 0; i < cap(slot); i++ {
+		slot <- struct{}{}
+	}
+	defer func() {
+		for len(slot) > 0 {
+			<-slot
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := RunCLI(ctx, test, "us-east-1", nil, "ce", "get-cost-and-usage")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, Calls(test.Name()), "The command should not run without a slot")
+}
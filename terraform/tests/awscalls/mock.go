@@ -0,0 +1,57 @@
+package awscalls
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// Responder answers an API call in place of AWS. It is given the operation name and input, such as
+// "DescribeSecurityGroups" and an *ec2.DescribeSecurityGroupsInput, and returns the operation's output type or an
+// error, such as a smithy.GenericAPIError with the code the service would send.
+type Responder func(operation string, input interface{}) (interface{}, error)
+
+var (
+	mocksMutex sync.Mutex
+	mocks      = map[string]Responder{}
+)
+
+// Mock makes clients made from configurations LoadConfig returns for the test answer from the responder instead
+// of calling AWS, until the test ends. Calls are still recorded, so a helper's calls and its handling of not found
+// and throttling errors can be checked without an account.
+func Mock(t loggingTest, respond Responder) {
+	name := t.Name()
+	mocksMutex.Lock()
+	mocks[name] = respond
+	mocksMutex.Unlock()
+
+	t.Cleanup(func() {
+		mocksMutex.Lock()
+		delete(mocks, name)
+		mocksMutex.Unlock()
+	})
+}
+
+// attachMock adds the test's responder to a configuration, if the test has one
+func attachMock(cfg *aws.Config, name string) {
+	mocksMutex.Lock()
+	respond, ok := mocks[name]
+	mocksMutex.Unlock()
+	if !ok {
+		return
+	}
+
+	cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+		// Last of the initialize step, after input validation and inside the recorder, and before the request is
+		// built, signed or sent
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("MockAPICall", func(
+			ctx context.Context, in middleware.InitializeInput, _ middleware.InitializeHandler,
+		) (middleware.InitializeOutput, middleware.Metadata, error) {
+			out, err := respond(awsmiddleware.GetOperationName(ctx), in.Parameters)
+			return middleware.InitializeOutput{Result: out}, middleware.Metadata{}, err
+		}), middleware.After)
+	})
+}
@@ -0,0 +1,51 @@
+package awscalls
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockAnswersInsteadOfAWS(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+
+	account := "123456789012"
+	Mock(t, func(operation string, _ interface{}) (interface{}, error) {
+		if account == "" {
+			return nil, &smithy.GenericAPIError{Code: "ExpiredToken", Message: "The security token has expired"}
+		}
+		return &sts.GetCallerIdentityOutput{Account: aws.String(account)}, nil
+	})
+
+	cfg, err := LoadConfig(context.Background(), t, "us-east-1")
+	require.NoError(t, err)
+	client := sts.NewFromConfig(cfg)
+
+	identity, err := client.GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+	require.NoError(t, err, "A mocked call should not need credentials")
+	assert.Equal(t, "123456789012", aws.ToString(identity.Account))
+
+	account = ""
+	_, err = client.GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+	var apiErr smithy.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "ExpiredToken", apiErr.ErrorCode())
+
+	calls := Calls(t.Name())
+	require.Len(t, calls, 2)
+	assert.Equal(t, "GetCallerIdentity", calls[0].Operation)
+	assert.Empty(t, calls[0].Error)
+	assert.Equal(t, "ExpiredToken", calls[1].Error)
+}
+
+func TestLoadConfigWithoutMockIsUnchanged(t *testing.T) {
+	cfg, err := LoadConfig(context.Background(), t, "us-east-1")
+	require.NoError(t, err)
+	assert.Len(t, cfg.APIOptions, 2, "Only the concurrency cap and the recorder should be attached")
+}
@@ -0,0 +1,83 @@
+package awscalls
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// Retry settings for the clients LoadConfig makes. Parallel module tests call the same account at once, so calls
+// wait out throttling for longer than the SDK's defaults before failing the test.
+const (
+	MaxAttempts = 8
+	MaxBackoff  = 30 * time.Second
+)
+
+// DefaultConcurrency caps the calls in flight to any one service across all tests in the process
+const DefaultConcurrency = 16
+
+// ServiceConcurrency caps the calls in flight to the services that throttle at low rates, by SDK service ID. IAM
+// and Cost Explorer allow a few requests per second per account, which parallel validation phases exceed.
+var ServiceConcurrency = map[string]int{
+	"IAM":           2,
+	"Cost Explorer": 1,
+}
+
+var (
+	slotsMutex sync.Mutex
+	slots      = map[string]chan struct{}{}
+)
+
+// newRetryer uses adaptive mode, which slows the client's attempts once the service throttles it. The retry
+// quota is off, since once it is spent the SDK fails calls without trying them, which fails the test instead of
+// waiting.
+func newRetryer() aws.Retryer {
+	return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+		o.StandardOptions = append(o.StandardOptions, func(so *retry.StandardOptions) {
+			so.MaxAttempts = MaxAttempts
+			so.MaxBackoff = MaxBackoff
+			so.RateLimiter = ratelimit.None
+		})
+	})
+}
+
+// limitConcurrency makes each call wait for a slot of its service. The adaptive rate limit belongs to one client,
+// while the slots are shared by every client in the process, so tests running in parallel share the cap.
+func limitConcurrency(cfg *aws.Config) {
+	cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+		// After the service metadata is registered, and around the retry loop, so retries keep their slot
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("LimitServiceConcurrency", func(
+			ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+		) (middleware.InitializeOutput, middleware.Metadata, error) {
+			slot := serviceSlots(awsmiddleware.GetServiceID(ctx))
+			select {
+			case slot <- struct{}{}:
+			case <-ctx.Done():
+				return middleware.InitializeOutput{}, middleware.Metadata{}, ctx.Err()
+			}
+			defer func() { <-slot }()
+			return next.HandleInitialize(ctx, in)
+		}), middleware.After)
+	})
+}
+
+func serviceSlots(service string) chan struct{} {
+	slotsMutex.Lock()
+	defer slotsMutex.Unlock()
+
+	if slot, ok := slots[service]; ok {
+		return slot
+	}
+	limit, ok := ServiceConcurrency[service]
+	if !ok || limit < 1 {
+		limit = DefaultConcurrency
+	}
+	slots[service] = make(chan struct{}, limit)
+	return slots[service]
+}
@@ -0,0 +1,45 @@
+package awsval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+)
+
+// AlarmHistoryTimeout is how long an alarm is given to record a state change. Alarms evaluate each minute, and
+// metrics arrive a minute or two late.
+const AlarmHistoryTimeout = 5 * time.Minute
+
+// alarmHistoryPollInterval is how often WaitForAlarmStateE reads an alarm's history
+const alarmHistoryPollInterval = 15 * time.Second
+
+// WaitForAlarmStateE polls an alarm's history for up to AlarmHistoryTimeout until it entered state after since, and
+// returns the summary CloudWatch gave. An alarm that fired and recovered before the poll is still found. CloudWatch
+// is reached through the AWS CLI, as its SDK module is not among the tests' dependencies.
+func WaitForAlarmStateE(t *testing.T, alarmName, state, region string, since time.Time) (string, error) {
+	return retry.DoWithRetryE(t, fmt.Sprintf("Wait for %s to enter %s", alarmName, state),
+		int(AlarmHistoryTimeout/alarmHistoryPollInterval)+1, alarmHistoryPollInterval, func() (string, error) {
+			var history struct {
+				AlarmHistoryItems []struct {
+					HistorySummary string `json:"HistorySummary"`
+				} `json:"AlarmHistoryItems"`
+			}
+			if err := awscalls.RunCLI(context.Background(), t, region, &history, "cloudwatch",
+				"describe-alarm-history", "--alarm-name", alarmName, "--history-item-type", "StateUpdate",
+				"--start-date", since.UTC().Format(time.RFC3339)); err != nil {
+				return "", retry.FatalError{Underlying: err}
+			}
+			for _, item := range history.AlarmHistoryItems {
+				if strings.HasSuffix(item.HistorySummary, " to "+state) {
+					return item.HistorySummary, nil
+				}
+			}
+			return "", fmt.Errorf("%s has not entered %s since %s", alarmName, state, since.Format(time.RFC3339))
+		})
+}
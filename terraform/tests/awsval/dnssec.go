@@ -0,0 +1,69 @@
+package awsval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/report"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+)
+
+// DNSSECKeyRegion is where the KMS key behind a key signing key must be, as Route53 only signs with keys there
+const DNSSECKeyRegion = "us-east-1"
+
+// GetDNSSECE returns a hosted zone's DNSSEC signing status and its key signing keys
+func GetDNSSECE(t *testing.T, zoneID string) (*route53.GetDNSSECOutput, error) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, "us-east-1")
+	if err != nil {
+		return nil, err
+	}
+	return route53.NewFromConfig(cfg).GetDNSSEC(context.Background(),
+		&route53.GetDNSSECInput{HostedZoneId: aws.String(zoneID)})
+}
+
+// CheckDNSSECStatus checks a zone's DNSSEC status, as GetDNSSECE returns it: the zone is signing, each key signing
+// key is active and backed by a KMS key in DNSSECKeyRegion, and registrarDS, the DS record given to the registrar,
+// is one an active key signing key publishes. A DS record the registrar holds for no active key breaks resolution
+// for every validating resolver.
+func CheckDNSSECStatus(zoneID string, status *route53.GetDNSSECOutput, registrarDS string) []report.Finding {
+	var findings []report.Finding
+
+	serving := ""
+	if status.Status != nil {
+		serving = aws.ToString(status.Status.ServeSignature)
+	}
+	findings = append(findings, report.Finding{Control: "DNSSEC-Signing", Resource: zoneID,
+		Passed: serving == "SIGNING", Detail: fmt.Sprintf("zone signing status is %s, expected SIGNING", serving)})
+
+	registrarDSActive := false
+	for _, key := range status.KeySigningKeys {
+		name, keyStatus, kmsARN := aws.ToString(key.Name), aws.ToString(key.Status), aws.ToString(key.KmsArn)
+		inRegion := strings.HasPrefix(kmsARN, "arn:aws:kms:"+DNSSECKeyRegion+":")
+		findings = append(findings, report.Finding{Control: "DNSSEC-KSK", Resource: zoneID + "/" + name,
+			Passed: keyStatus == "ACTIVE" && inRegion,
+			Detail: fmt.Sprintf("key signing key is %s with KMS key %s, expected ACTIVE with a key in %s",
+				keyStatus, kmsARN, DNSSECKeyRegion)})
+		if keyStatus == "ACTIVE" && normalizeDSRecord(aws.ToString(key.DSRecord)) == normalizeDSRecord(registrarDS) {
+			registrarDSActive = true
+		}
+	}
+	if len(status.KeySigningKeys) == 0 {
+		findings = append(findings, report.Finding{Control: "DNSSEC-KSK", Resource: zoneID,
+			Detail: "zone has no key signing key"})
+	}
+
+	findings = append(findings, report.Finding{Control: "DNSSEC-DS-Record", Resource: zoneID,
+		Passed: registrarDSActive,
+		Detail: fmt.Sprintf("DS record %q is not published by an active key signing key", registrarDS)})
+	return findings
+}
+
+// normalizeDSRecord compares DS records regardless of spacing and the case of the digest
+func normalizeDSRecord(record string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(record), " "))
+}
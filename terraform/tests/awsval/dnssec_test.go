@@ -0,0 +1,40 @@
+package awsval
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCheckDNSSECStatus checks the DS record given to the registrar must be published by an active key signing
+// key backed by a KMS key in us-east-1, whatever its spacing
+func TestCheckDNSSECStatus(t *testing.T) {
+	status := &route53.GetDNSSECOutput{
+		Status: &route53types.DNSSECStatus{ServeSignature: aws.String("SIGNING")},
+		KeySigningKeys: []route53types.KeySigningKey{{
+			Name:     aws.String("coalition-ksk"),
+			Status:   aws.String("ACTIVE"),
+			KmsArn:   aws.String("arn:aws:kms:us-east-1:123456789012:key/0123abcd-45ef-67ab-89cd-ef0123456789"),
+			DSRecord: aws.String("12345 13 2 1A2B3C4D5E6F"),
+		}},
+	}
+	failed := func(registrarDS string) []string {
+		var controls []string
+		for _, finding := range CheckDNSSECStatus("Z0123456789", status, registrarDS) {
+			if !finding.Passed {
+				controls = append(controls, finding.Control)
+			}
+		}
+		return controls
+	}
+
+	assert.Empty(t, failed("12345  13 2 1a2b3c4d5e6f"))
+	assert.Equal(t, []string{"DNSSEC-DS-Record"}, failed("54321 13 2 6F5E4D3C2B1A"))
+
+	status.KeySigningKeys[0].KmsArn = aws.String("arn:aws:kms:eu-west-1:123456789012:key/0123abcd")
+	status.Status.ServeSignature = aws.String("NOT_SIGNING")
+	assert.Equal(t, []string{"DNSSEC-Signing", "DNSSEC-KSK"}, failed("12345 13 2 1A2B3C4D5E6F"))
+}
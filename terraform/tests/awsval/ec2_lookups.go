@@ -0,0 +1,169 @@
+// Package awsval looks up and validates deployed resources with the AWS SDK: EC2 lookups that take the one
+// operation they call, so a unit test can pass a fake, and checks of instances, security groups, subnet routes,
+// Route53 health checks, DNSSEC signing, email authentication records, CloudWatch alarm history and WAF web ACLs.
+// SDK calls go through awscalls, so they are recorded for the test and can be mocked; services whose SDK module the
+// tests do not include, such as CloudWatch and WAFv2, are reached through the AWS CLI.
+package awsval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"terraform-tests/awscalls"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/require"
+)
+
+// The lookups below take the one EC2 operation they call rather than a client, so a unit test can pass a fake
+// that answers with an error such as Throttling or InvalidSubnetID.NotFound. *ec2.Client satisfies all of them.
+
+// DescribeSubnetsAPI is the part of the EC2 client GetSubnetE needs
+type DescribeSubnetsAPI interface {
+	DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput,
+		optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error)
+}
+
+// DescribeSecurityGroupsAPI is the part of the EC2 client GetSecurityGroupE needs
+type DescribeSecurityGroupsAPI interface {
+	DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput,
+		optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+}
+
+// DescribeInternetGatewaysAPI is the part of the EC2 client GetInternetGatewaysE needs
+type DescribeInternetGatewaysAPI interface {
+	DescribeInternetGateways(ctx context.Context, params *ec2.DescribeInternetGatewaysInput,
+		optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error)
+}
+
+// DescribeInstancesAPI is the part of the EC2 client GetInstanceE needs
+type DescribeInstancesAPI interface {
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput,
+		optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+}
+
+var (
+	_ DescribeSubnetsAPI          = (*ec2.Client)(nil)
+	_ DescribeSecurityGroupsAPI   = (*ec2.Client)(nil)
+	_ DescribeInternetGatewaysAPI = (*ec2.Client)(nil)
+	_ DescribeInstancesAPI        = (*ec2.Client)(nil)
+)
+
+// NewEC2Client returns an EC2 client for the region whose calls are recorded for the test
+func NewEC2Client(t *testing.T, region string) *ec2.Client {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+	return ec2.NewFromConfig(cfg)
+}
+
+// NotFoundError is returned by a lookup when the resource does not exist, whether EC2 answered with an
+// Invalid<Kind>ID.NotFound error or with no match
+type NotFoundError struct {
+	Kind string // Such as "subnet"
+	ID   string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %s not found", e.Kind, e.ID)
+}
+
+// IsNotFound reports whether a lookup failed because the resource does not exist
+func IsNotFound(err error) bool {
+	var notFound *NotFoundError
+	return errors.As(err, &notFound)
+}
+
+// lookupError wraps an EC2 error, turning a NotFound error code into a NotFoundError so callers need not know
+// each resource's code. Other errors, including throttling the SDK gave up retrying, are returned as they are.
+func lookupError(err error, kind, id string) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && strings.HasSuffix(apiErr.ErrorCode(), ".NotFound") {
+		return &NotFoundError{Kind: kind, ID: id}
+	}
+	return fmt.Errorf("failed to describe %s %s: %w", kind, id, err)
+}
+
+// GetSubnetE looks up a subnet by ID
+func GetSubnetE(ctx context.Context, client DescribeSubnetsAPI, subnetID string) (*types.Subnet, error) {
+	result, err := client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{SubnetIds: []string{subnetID}})
+	if err != nil {
+		return nil, lookupError(err, "subnet", subnetID)
+	}
+	if len(result.Subnets) != 1 {
+		return nil, &NotFoundError{Kind: "subnet", ID: subnetID}
+	}
+	return &result.Subnets[0], nil
+}
+
+// GetSecurityGroupE looks up a security group by ID
+func GetSecurityGroupE(ctx context.Context, client DescribeSecurityGroupsAPI, groupID string) (
+	*types.SecurityGroup, error,
+) {
+	result, err := client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{GroupIds: []string{groupID}})
+	if err != nil {
+		return nil, lookupError(err, "security group", groupID)
+	}
+	if len(result.SecurityGroups) != 1 {
+		return nil, &NotFoundError{Kind: "security group", ID: groupID}
+	}
+	return &result.SecurityGroups[0], nil
+}
+
+// GetInternetGatewaysE returns the internet gateways attached to a VPC, which is none for a private VPC
+func GetInternetGatewaysE(ctx context.Context, client DescribeInternetGatewaysAPI, vpcID string) (
+	[]types.InternetGateway, error,
+) {
+	result, err := client.DescribeInternetGateways(ctx, &ec2.DescribeInternetGatewaysInput{
+		Filters: []types.Filter{{Name: aws.String("attachment.vpc-id"), Values: []string{vpcID}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe internet gateways of %s: %w", vpcID, err)
+	}
+	return result.InternetGateways, nil
+}
+
+// GetInstanceE looks up an EC2 instance by ID
+func GetInstanceE(ctx context.Context, client DescribeInstancesAPI, instanceID string) (*types.Instance, error) {
+	result, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}})
+	if err != nil {
+		return nil, lookupError(err, "instance", instanceID)
+	}
+	if len(result.Reservations) != 1 || len(result.Reservations[0].Instances) != 1 {
+		return nil, &NotFoundError{Kind: "instance", ID: instanceID}
+	}
+	return &result.Reservations[0].Instances[0], nil
+}
+
+// GetSubnet looks up a subnet by ID, failing the test if it cannot
+func GetSubnet(t *testing.T, subnetID, region string) *types.Subnet {
+	subnet, err := GetSubnetE(context.Background(), NewEC2Client(t, region), subnetID)
+	require.NoError(t, err)
+	return subnet
+}
+
+// GetSecurityGroup looks up a security group by ID, failing the test if it cannot
+func GetSecurityGroup(t *testing.T, groupID, region string) *types.SecurityGroup {
+	group, err := GetSecurityGroupE(context.Background(), NewEC2Client(t, region), groupID)
+	require.NoError(t, err)
+	return group
+}
+
+// GetInternetGateways returns the internet gateways attached to a VPC, failing the test if it cannot
+func GetInternetGateways(t *testing.T, vpcID, region string) []types.InternetGateway {
+	gateways, err := GetInternetGatewaysE(context.Background(), NewEC2Client(t, region), vpcID)
+	require.NoError(t, err)
+	return gateways
+}
+
+// GetInstance looks up an EC2 instance by ID, failing the test if it cannot
+func GetInstance(t *testing.T, instanceID, region string) *types.Instance {
+	instance, err := GetInstanceE(context.Background(), NewEC2Client(t, region), instanceID)
+	require.NoError(t, err)
+	return instance
+}
@@ -0,0 +1,92 @@
+package awsval
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEC2 answers the EC2 lookups from canned subnets and security groups, or with err when it is set
+type fakeEC2 struct {
+	subnets []types.Subnet
+	groups  []types.SecurityGroup
+	err     error
+}
+
+func (f *fakeEC2) DescribeSubnets(_ context.Context, params *ec2.DescribeSubnetsInput,
+	_ ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	output := &ec2.DescribeSubnetsOutput{}
+	for _, subnet := range f.subnets {
+		if containsString(params.SubnetIds, aws.ToString(subnet.SubnetId)) {
+			output.Subnets = append(output.Subnets, subnet)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeEC2) DescribeSecurityGroups(_ context.Context, params *ec2.DescribeSecurityGroupsInput,
+	_ ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	output := &ec2.DescribeSecurityGroupsOutput{}
+	for _, group := range f.groups {
+		if containsString(params.GroupIds, aws.ToString(group.GroupId)) {
+			output.SecurityGroups = append(output.SecurityGroups, group)
+		}
+	}
+	return output, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// TestEC2LookupsReportNotFound checks a missing resource is a NotFoundError whether EC2 answers with its NotFound
+// code or an empty result, and other errors such as throttling are passed on as they are
+func TestEC2LookupsReportNotFound(t *testing.T) {
+	ctx := context.Background()
+
+	client := &fakeEC2{
+		subnets: []types.Subnet{{SubnetId: aws.String("subnet-0a1b"), CidrBlock: aws.String("10.0.1.0/24")}},
+		groups:  []types.SecurityGroup{{GroupId: aws.String("sg-0a1b")}},
+	}
+	subnet, err := GetSubnetE(ctx, client, "subnet-0a1b")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.1.0/24", aws.ToString(subnet.CidrBlock))
+
+	group, err := GetSecurityGroupE(ctx, client, "sg-0a1b")
+	require.NoError(t, err)
+	assert.Equal(t, "sg-0a1b", aws.ToString(group.GroupId))
+
+	_, err = GetSubnetE(ctx, client, "subnet-gone")
+	assert.True(t, IsNotFound(err), "An empty result should be not found: %v", err)
+	assert.EqualError(t, err, "subnet subnet-gone not found")
+
+	client.err = &smithy.GenericAPIError{Code: "InvalidGroup.NotFound", Message: "The security group does not exist"}
+	_, err = GetSecurityGroupE(ctx, client, "sg-gone")
+	assert.True(t, IsNotFound(err), "EC2's NotFound code should be not found: %v", err)
+
+	client.err = &smithy.GenericAPIError{Code: "RequestLimitExceeded", Message: "Request limit exceeded."}
+	_, err = GetSubnetE(ctx, client, "subnet-0a1b")
+	require.Error(t, err)
+	assert.False(t, IsNotFound(err), "Throttling should not be mistaken for a missing subnet")
+	var apiErr smithy.APIError
+	require.True(t, errors.As(err, &apiErr), "The API error should be wrapped, not replaced")
+	assert.Equal(t, "RequestLimitExceeded", apiErr.ErrorCode())
+}
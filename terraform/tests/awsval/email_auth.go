@@ -0,0 +1,155 @@
+package awsval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/report"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+)
+
+// SESSPFInclude is the SPF mechanism that authorizes SES to send for a domain
+const SESSPFInclude = "include:amazonses.com"
+
+// SESDKIMTarget is the suffix of the name each SES DKIM CNAME points at
+const SESDKIMTarget = ".dkim.amazonses.com"
+
+// GetZoneRecordsE lists a hosted zone's records, keyed by type and name without the trailing dot, such as
+// "TXT _dmarc.example.com". TXT values are unquoted, and those Route53 holds as several strings are joined.
+func GetZoneRecordsE(t *testing.T, zoneID, region string) (map[string][]string, error) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	if err != nil {
+		return nil, err
+	}
+
+	records := map[string][]string{}
+	paginator := route53.NewListResourceRecordSetsPaginator(route53.NewFromConfig(cfg),
+		&route53.ListResourceRecordSetsInput{HostedZoneId: aws.String(zoneID)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range page.ResourceRecordSets {
+			key := string(record.Type) + " " + strings.TrimSuffix(aws.ToString(record.Name), ".")
+			for _, value := range record.ResourceRecords {
+				records[key] = append(records[key], unquoteRecordValue(aws.ToString(value.Value)))
+			}
+		}
+	}
+	return records, nil
+}
+
+// unquoteRecordValue removes the quotes around a TXT value, joining the strings of one longer than 255 characters
+func unquoteRecordValue(value string) string {
+	return strings.Trim(strings.ReplaceAll(value, `" "`, ""), `"`)
+}
+
+// SPFRecordProblems lists what keeps an SPF record from authorizing SES and rejecting other senders: a missing
+// SESSPFInclude, and an all mechanism other than ~all or -all, since +all and ?all let anyone send as the domain
+func SPFRecordProblems(record string) []string {
+	terms := strings.Fields(record)
+	if len(terms) == 0 || terms[0] != "v=spf1" {
+		return []string{fmt.Sprintf("%q is not an SPF record", record)}
+	}
+
+	var problems []string
+	if !slices.Contains(terms, SESSPFInclude) {
+		problems = append(problems, "does not include amazonses.com")
+	}
+	switch last := terms[len(terms)-1]; last {
+	case "~all", "-all":
+	case "all", "+all", "?all":
+		problems = append(problems, fmt.Sprintf("ends with %s, which authorizes any sender", last))
+	default:
+		problems = append(problems, "does not end with ~all or -all")
+	}
+	return problems
+}
+
+// DMARCRecordProblems lists what keeps a DMARC record from acting on mail that fails SPF and DKIM and reporting
+// it: a policy other than quarantine or reject, and no mailto: address for aggregate reports
+func DMARCRecordProblems(record string) []string {
+	tags := map[string]string{}
+	for i, tag := range strings.Split(record, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(tag), "=")
+		if i == 0 && (name != "v" || value != "DMARC1") {
+			return []string{fmt.Sprintf("%q is not a DMARC record", record)}
+		}
+		tags[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+
+	var problems []string
+	if policy := tags["p"]; policy != "quarantine" && policy != "reject" {
+		problems = append(problems, fmt.Sprintf("policy is %q, expected quarantine or reject", policy))
+	}
+	if !strings.HasPrefix(tags["rua"], "mailto:") {
+		problems = append(problems, "sends aggregate reports to no mailto: address")
+	}
+	return problems
+}
+
+// CheckEmailAuthRecords checks a zone's records, as GetZoneRecordsE lists them, authenticate mail SES sends from a
+// domain: one SPF record that passes SPFRecordProblems, a CNAME for each DKIM token pointing at SES, and a DMARC
+// record that passes DMARCRecordProblems. Receivers fall back to the DMARC record of a parent domain, so one at
+// the zone's apex covers a subdomain.
+func CheckEmailAuthRecords(domain string, records map[string][]string, dkimTokens []string) []report.Finding {
+	var findings []report.Finding
+
+	var spfRecords []string
+	for _, value := range records["TXT "+domain] {
+		if strings.HasPrefix(value, "v=spf1") {
+			spfRecords = append(spfRecords, value)
+		}
+	}
+	spfFinding := report.Finding{Control: "Email-SPF", Resource: domain}
+	switch len(spfRecords) {
+	case 0:
+		spfFinding.Detail = "no SPF record"
+	case 1:
+		problems := SPFRecordProblems(spfRecords[0])
+		spfFinding.Passed = len(problems) == 0
+		spfFinding.Detail = strings.Join(problems, "; ")
+	default:
+		// More than one SPF record is a permanent error, which fails SPF for every message
+		spfFinding.Detail = fmt.Sprintf("%d SPF records, expected one", len(spfRecords))
+	}
+	findings = append(findings, spfFinding)
+
+	if len(dkimTokens) != 3 {
+		findings = append(findings, report.Finding{Control: "Email-DKIM", Resource: domain,
+			Detail: fmt.Sprintf("%d DKIM tokens, expected the three SES issues", len(dkimTokens))})
+	}
+	for _, token := range dkimTokens {
+		name := fmt.Sprintf("%s._domainkey.%s", token, domain)
+		targets := records["CNAME "+name]
+		findings = append(findings, report.Finding{Control: "Email-DKIM", Resource: name,
+			Passed: len(targets) == 1 && strings.TrimSuffix(targets[0], ".") == token+SESDKIMTarget,
+			Detail: fmt.Sprintf("CNAME points at %v, expected %s%s", targets, token, SESDKIMTarget)})
+	}
+
+	findings = append(findings, checkDMARCRecord(domain, records))
+	return findings
+}
+
+// checkDMARCRecord finds the DMARC record a receiver would use for a domain, its own or the nearest parent's in
+// the zone, and checks it
+func checkDMARCRecord(domain string, records map[string][]string) report.Finding {
+	for name := domain; strings.Contains(name, "."); name = name[strings.Index(name, ".")+1:] {
+		for _, value := range records["TXT _dmarc."+name] {
+			if strings.HasPrefix(value, "v=DMARC1") {
+				problems := DMARCRecordProblems(value)
+				return report.Finding{Control: "Email-DMARC", Resource: "_dmarc." + name, Passed: len(problems) == 0,
+					Detail: strings.Join(problems, "; ")}
+			}
+		}
+	}
+	return report.Finding{Control: "Email-DMARC", Resource: "_dmarc." + domain,
+		Detail: "no DMARC record for the domain or a parent domain"}
+}
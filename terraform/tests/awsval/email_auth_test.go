@@ -0,0 +1,61 @@
+package awsval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEmailAuthRecordProblems checks what counts as an SPF or DMARC record that does not protect the domain
+func TestEmailAuthRecordProblems(t *testing.T) {
+	assert.Empty(t, SPFRecordProblems("v=spf1 include:amazonses.com ~all"))
+	assert.Empty(t, SPFRecordProblems("v=spf1 include:_spf.google.com include:amazonses.com -all"))
+	assert.Equal(t, []string{"does not include amazonses.com"},
+		SPFRecordProblems("v=spf1 include:_spf.google.com ~all"))
+	assert.Equal(t, []string{"ends with +all, which authorizes any sender"},
+		SPFRecordProblems("v=spf1 include:amazonses.com +all"))
+	assert.Equal(t, []string{"does not end with ~all or -all"}, SPFRecordProblems("v=spf1 include:amazonses.com"))
+	assert.Len(t, SPFRecordProblems("verification-token"), 1)
+
+	assert.Empty(t, DMARCRecordProblems("v=DMARC1; p=quarantine; rua=mailto:dmarc@example.org"))
+	assert.Empty(t, DMARCRecordProblems("v=DMARC1;p=reject;rua=mailto:dmarc@example.org;"))
+	assert.Equal(t, []string{`policy is "none", expected quarantine or reject`,
+		"sends aggregate reports to no mailto: address"}, DMARCRecordProblems("v=DMARC1; p=none"))
+	assert.Len(t, DMARCRecordProblems("v=spf1 -all"), 1)
+}
+
+// TestCheckEmailAuthRecords checks a zone's records are found for the domain, DKIM CNAMEs must point at SES, and
+// a DMARC record at the zone's apex covers a subdomain
+func TestCheckEmailAuthRecords(t *testing.T) {
+	domain := "mail.example.org"
+	tokens := []string{"token1", "token2", "token3"}
+	records := map[string][]string{
+		"TXT mail.example.org":                     {"v=spf1 include:amazonses.com ~all"},
+		"TXT _amazonses.mail.example.org":          {"verification-token"},
+		"CNAME token1._domainkey.mail.example.org": {"token1.dkim.amazonses.com"},
+		"CNAME token2._domainkey.mail.example.org": {"token2.dkim.amazonses.com."},
+		"CNAME token3._domainkey.mail.example.org": {"token1.dkim.amazonses.com"},
+		"TXT _dmarc.example.org":                   {"v=DMARC1; p=reject; rua=mailto:dmarc@example.org"},
+	}
+
+	failed := map[string]bool{}
+	findings := CheckEmailAuthRecords(domain, records, tokens)
+	for _, finding := range findings {
+		if !finding.Passed {
+			failed[finding.Control+" "+finding.Resource] = true
+		}
+	}
+	assert.Equal(t, map[string]bool{"Email-DKIM token3._domainkey.mail.example.org": true}, failed)
+	assert.Len(t, findings, 5, "Expected SPF, a finding per DKIM token and DMARC")
+
+	records["TXT mail.example.org"] = append(records["TXT mail.example.org"], "v=spf1 -all")
+	delete(records, "TXT _dmarc.example.org")
+	findings = CheckEmailAuthRecords(domain, records, tokens[:2])
+	var failedControls []string
+	for _, finding := range findings {
+		if !finding.Passed {
+			failedControls = append(failedControls, finding.Control)
+		}
+	}
+	assert.Equal(t, []string{"Email-SPF", "Email-DKIM", "Email-DMARC"}, failedControls)
+}
@@ -0,0 +1,66 @@
+package awsval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// healthCheckPollInterval is how often WaitForHealthCheckHealthy asks Route53 for the checkers' observations
+const healthCheckPollInterval = 15 * time.Second
+
+// HealthCheckObservationsE returns the status each Route53 checker last reported for an endpoint health check, such
+// as "Success: HTTP Status Code 200, OK". Calculated health checks have no checkers to ask.
+func HealthCheckObservationsE(t *testing.T, healthCheckID string) ([]string, error) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, "us-east-1")
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := route53.NewFromConfig(cfg).GetHealthCheckStatus(context.Background(),
+		&route53.GetHealthCheckStatusInput{HealthCheckId: aws.String(healthCheckID)})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]string, 0, len(result.HealthCheckObservations))
+	for _, observation := range result.HealthCheckObservations {
+		if observation.StatusReport != nil {
+			statuses = append(statuses, aws.ToString(observation.StatusReport.Status))
+		}
+	}
+	return statuses, nil
+}
+
+// WaitForHealthCheckHealthy polls Route53 until every checker reports the health check healthy, failing the test
+// if they do not within the given time. A new health check takes a few intervals to be checked from every region.
+func WaitForHealthCheckHealthy(t *testing.T, healthCheckID string, within time.Duration) {
+	attempts := int(within/healthCheckPollInterval) + 1
+
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("Wait for health check %s to be healthy", healthCheckID),
+		attempts, healthCheckPollInterval, func() (string, error) {
+			statuses, statusErr := HealthCheckObservationsE(t, healthCheckID)
+			if statusErr != nil {
+				return "", statusErr
+			}
+			if len(statuses) == 0 {
+				return "", fmt.Errorf("health check %s has no observations yet", healthCheckID)
+			}
+			for _, status := range statuses {
+				if !strings.HasPrefix(status, "Success") {
+					return "", fmt.Errorf("health check %s observed %q", healthCheckID, status)
+				}
+			}
+			return "", nil
+		})
+	require.NoError(t, err, "Health check %s should be healthy within %s", healthCheckID, within)
+}
@@ -0,0 +1,126 @@
+package awsval
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ValidateInstanceRequiresIMDSv2 verifies the instance metadata service only accepts session tokens
+func ValidateInstanceRequiresIMDSv2(t *testing.T, instance *types.Instance) {
+	require.NotNil(t, instance.MetadataOptions, "Instance should report metadata options")
+	assert.Equal(t, types.HttpTokensStateRequired, instance.MetadataOptions.HttpTokens,
+		"Instance metadata should require IMDSv2 session tokens")
+}
+
+// ValidateInstanceRootVolumeEncrypted verifies the EBS root volume of the instance is encrypted
+func ValidateInstanceRootVolumeEncrypted(t *testing.T, instance *types.Instance, region string) {
+	var rootVolumeID string
+	for _, mapping := range instance.BlockDeviceMappings {
+		if aws.ToString(mapping.DeviceName) == aws.ToString(instance.RootDeviceName) && mapping.Ebs != nil {
+			rootVolumeID = aws.ToString(mapping.Ebs.VolumeId)
+		}
+	}
+	require.NotEmpty(t, rootVolumeID, "Instance should have an EBS root volume")
+
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	svc := ec2.NewFromConfig(cfg)
+	result, err := svc.DescribeVolumes(context.Background(), &ec2.DescribeVolumesInput{
+		VolumeIds: []string{rootVolumeID},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Volumes, 1)
+	assert.True(t, aws.ToBool(result.Volumes[0].Encrypted), "Root volume %s should be encrypted", rootVolumeID)
+}
+
+// ValidateInstanceRolePolicies verifies the instance role only carries allowed managed policies and no inline policies
+func ValidateInstanceRolePolicies(t *testing.T, instance *types.Instance, region string, allowedPolicies []string) {
+	require.NotNil(t, instance.IamInstanceProfile, "Instance should have an instance profile")
+
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	svc := iam.NewFromConfig(cfg)
+	profileARN := aws.ToString(instance.IamInstanceProfile.Arn)
+	profileName := profileARN[strings.LastIndex(profileARN, "/")+1:]
+
+	profile, err := svc.GetInstanceProfile(context.Background(), &iam.GetInstanceProfileInput{
+		InstanceProfileName: aws.String(profileName),
+	})
+	require.NoError(t, err)
+	require.Len(t, profile.InstanceProfile.Roles, 1, "Instance profile should carry exactly one role")
+	roleName := profile.InstanceProfile.Roles[0].RoleName
+
+	attached, err := svc.ListAttachedRolePolicies(context.Background(), &iam.ListAttachedRolePoliciesInput{
+		RoleName: roleName,
+	})
+	require.NoError(t, err)
+	for _, policy := range attached.AttachedPolicies {
+		assert.Contains(t, allowedPolicies, aws.ToString(policy.PolicyArn),
+			"Role %s has a policy outside the allowed set", aws.ToString(roleName))
+	}
+
+	inline, err := svc.ListRolePolicies(context.Background(), &iam.ListRolePoliciesInput{RoleName: roleName})
+	require.NoError(t, err)
+	assert.Empty(t, inline.PolicyNames, "Role %s should not have inline policies", aws.ToString(roleName))
+}
+
+// ValidateInstanceAMIAge verifies the instance was launched from an AMI published within maxAgeDays
+func ValidateInstanceAMIAge(t *testing.T, instance *types.Instance, region string, maxAgeDays int) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	svc := ec2.NewFromConfig(cfg)
+	result, err := svc.DescribeImages(context.Background(), &ec2.DescribeImagesInput{
+		ImageIds: []string{aws.ToString(instance.ImageId)},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Images, 1, "AMI %s should still be available", aws.ToString(instance.ImageId))
+
+	created, err := time.Parse(time.RFC3339, aws.ToString(result.Images[0].CreationDate))
+	require.NoError(t, err)
+
+	age := time.Since(created)
+	assert.LessOrEqual(t, age, time.Duration(maxAgeDays)*24*time.Hour,
+		"AMI %s is %d days old; rebuild the instance on the latest image", aws.ToString(instance.ImageId), int(age.Hours()/24))
+}
+
+// ValidateInstanceManagedBySSM verifies the SSM agent on the instance is registered and online
+func ValidateInstanceManagedBySSM(t *testing.T, instanceID, region string) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	svc := ssm.NewFromConfig(cfg)
+	result, err := svc.DescribeInstanceInformation(context.Background(), &ssm.DescribeInstanceInformationInput{
+		Filters: []ssmtypes.InstanceInformationStringFilter{
+			{Key: aws.String("InstanceIds"), Values: []string{instanceID}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.InstanceInformationList, 1, "Instance %s should be registered with SSM", instanceID)
+	assert.Equal(t, ssmtypes.PingStatusOnline, result.InstanceInformationList[0].PingStatus,
+		"SSM agent on %s should be online", instanceID)
+}
+
+// GetInstanceSecurityGroups returns the security groups attached to an instance
+func GetInstanceSecurityGroups(t *testing.T, instance *types.Instance, region string) []*types.SecurityGroup {
+	var groups []*types.SecurityGroup
+	for _, group := range instance.SecurityGroups {
+		groups = append(groups, GetSecurityGroup(t, aws.ToString(group.GroupId), region))
+	}
+	return groups
+}
@@ -0,0 +1,57 @@
+package awsval
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+)
+
+// GetMetricMaximumE returns the highest value a CloudWatch metric reached in one-minute periods since the given
+// time, and false when no datapoint has been published since. Dimensions narrow the metric to one resource, such
+// as {"DBInstanceIdentifier": id}. CloudWatch is reached through the AWS CLI, as its SDK module is not among the
+// tests' dependencies.
+func GetMetricMaximumE(
+	t *testing.T,
+	region, namespace, metricName string,
+	dimensions map[string]string,
+	since time.Time,
+) (float64, bool, error) {
+	args := []string{"get-metric-statistics", "--namespace", namespace, "--metric-name", metricName,
+		"--start-time", since.UTC().Format(time.RFC3339), "--end-time", time.Now().UTC().Format(time.RFC3339),
+		"--period", strconv.Itoa(60), "--statistics", "Maximum"}
+	if len(dimensions) > 0 {
+		names := make([]string, 0, len(dimensions))
+		for name := range dimensions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		args = append(args, "--dimensions")
+		for _, name := range names {
+			args = append(args, fmt.Sprintf("Name=%s,Value=%s", name, dimensions[name]))
+		}
+	}
+
+	var statistics struct {
+		Datapoints []struct {
+			Maximum float64 `json:"Maximum"`
+		} `json:"Datapoints"`
+	}
+	if err := awscalls.RunCLI(context.Background(), t, region, &statistics, "cloudwatch", args...); err != nil {
+		return 0, false, err
+	}
+	if len(statistics.Datapoints) == 0 {
+		return 0, false, nil
+	}
+	maximum := statistics.Datapoints[0].Maximum
+	for _, datapoint := range statistics.Datapoints[1:] {
+		if datapoint.Maximum > maximum {
+			maximum = datapoint.Maximum
+		}
+	}
+	return maximum, true, nil
+}
@@ -0,0 +1,39 @@
+package awsval
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetMetricMaximum answers get-metric-statistics from a fake AWS CLI, and checks the highest datapoint is
+// returned for the dimensions asked for and that no datapoints is reported as unpublished
+func TestGetMetricMaximum(t *testing.T) {
+	dir := t.TempDir()
+	script := `#!/bin/sh
+case "$*" in
+*"--dimensions Name=DBInstanceIdentifier,Value=coalition-db-replica "*)
+	echo '{"Label": "ReplicaLag", "Datapoints": [{"Maximum": 2.0}, {"Maximum": 7.5}, {"Maximum": 0.0}]}' ;;
+*)
+	echo '{"Label": "ReplicaLag", "Datapoints": []}' ;;
+esac
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "aws"), []byte(script), 0o755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	since := time.Now().Add(-5 * time.Minute)
+	lag, published, err := GetMetricMaximumE(t, "us-east-1", "AWS/RDS", "ReplicaLag",
+		map[string]string{"DBInstanceIdentifier": "coalition-db-replica"}, since)
+	require.NoError(t, err)
+	assert.True(t, published)
+	assert.Equal(t, 7.5, lag)
+
+	_, published, err = GetMetricMaximumE(t, "us-east-1", "AWS/RDS", "ReplicaLag",
+		map[string]string{"DBInstanceIdentifier": "other-replica"}, since)
+	require.NoError(t, err)
+	assert.False(t, published)
+}
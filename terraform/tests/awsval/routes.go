@@ -0,0 +1,68 @@
+package awsval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// SubnetRouteTable returns the route table explicitly associated with a subnet
+func SubnetRouteTable(t *testing.T, subnetID, region string) types.RouteTable {
+	result, err := NewEC2Client(t, region).DescribeRouteTables(context.Background(), &ec2.DescribeRouteTablesInput{
+		Filters: []types.Filter{{Name: aws.String("association.subnet-id"), Values: []string{subnetID}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.RouteTables, 1, "Expected subnet %s to be associated with one route table", subnetID)
+	return result.RouteTables[0]
+}
+
+// AssertDefaultRouteViaNAT checks that the route table associated with a subnet sends 0.0.0.0/0 to a NAT
+// gateway, and returns the gateway's ID
+func AssertDefaultRouteViaNAT(t *testing.T, subnetID, region string) string {
+	for _, route := range SubnetRouteTable(t, subnetID, region).Routes {
+		if aws.ToString(route.DestinationCidrBlock) == "0.0.0.0/0" {
+			natGatewayID := aws.ToString(route.NatGatewayId)
+			assert.NotEmpty(t, natGatewayID, "Subnet %s should route 0.0.0.0/0 to a NAT gateway", subnetID)
+			return natGatewayID
+		}
+	}
+	assert.Failf(t, "No default route", "Subnet %s has no 0.0.0.0/0 route", subnetID)
+	return ""
+}
+
+// AssertNoInternetRoute checks that the route table associated with a subnet has no IPv4 or IPv6 default route,
+// so nothing in the subnet can reach the internet
+func AssertNoInternetRoute(t *testing.T, subnetID, region string) {
+	for _, route := range SubnetRouteTable(t, subnetID, region).Routes {
+		destination := aws.ToString(route.DestinationCidrBlock)
+		if destination == "" {
+			destination = aws.ToString(route.DestinationIpv6CidrBlock)
+		}
+		assert.NotContains(t, []string{"0.0.0.0/0", "::/0"}, destination,
+			"Subnet %s should have no route to the internet", subnetID)
+	}
+}
+
+// AssertNATGatewayEgressIPs checks that each of a NAT gateway's public addresses is one of egressIPs, the
+// addresses third parties are given to allowlist
+func AssertNATGatewayEgressIPs(t *testing.T, natGatewayID, region string, egressIPs []string) {
+	result, err := NewEC2Client(t, region).DescribeNatGateways(context.Background(), &ec2.DescribeNatGatewaysInput{
+		NatGatewayIds: []string{natGatewayID},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.NatGateways, 1, "Expected NAT gateway %s", natGatewayID)
+
+	addresses := result.NatGateways[0].NatGatewayAddresses
+	require.NotEmpty(t, addresses, "NAT gateway %s has no addresses", natGatewayID)
+	for _, address := range addresses {
+		assert.NotEmpty(t, aws.ToString(address.AllocationId),
+			"NAT gateway %s should use an Elastic IP, which keeps its address", natGatewayID)
+		assert.Contains(t, egressIPs, aws.ToString(address.PublicIp),
+			"NAT gateway %s egresses from an address missing from the allowlisted egress IPs", natGatewayID)
+	}
+}
@@ -0,0 +1,54 @@
+package awsval
+
+import (
+	"net"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// SecurityGroupAllowsIngressFrom reports whether a TCP connection from ip to port is allowed by the group
+func SecurityGroupAllowsIngressFrom(group *types.SecurityGroup, port int32, ip net.IP) bool {
+	return permissionsAllow(group.IpPermissions, port, ip)
+}
+
+// SecurityGroupAllowsAllEgress reports whether the group lets all traffic out to the internet
+func SecurityGroupAllowsAllEgress(group *types.SecurityGroup) bool {
+	for _, permission := range group.IpPermissionsEgress {
+		if aws.ToString(permission.IpProtocol) != "-1" {
+			continue
+		}
+		for _, ipRange := range permission.IpRanges {
+			if aws.ToString(ipRange.CidrIp) == "0.0.0.0/0" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// permissionsAllow reports whether any permission admits TCP traffic on port from or to ip, IPv4 or IPv6
+func permissionsAllow(permissions []types.IpPermission, port int32, ip net.IP) bool {
+	for _, permission := range permissions {
+		protocol := aws.ToString(permission.IpProtocol)
+		if protocol != "-1" && protocol != "tcp" && protocol != "6" {
+			continue
+		}
+		if protocol != "-1" && (aws.ToInt32(permission.FromPort) > port || aws.ToInt32(permission.ToPort) < port) {
+			continue
+		}
+		for _, ipRange := range permission.IpRanges {
+			_, network, err := net.ParseCIDR(aws.ToString(ipRange.CidrIp))
+			if err == nil && network.Contains(ip) {
+				return true
+			}
+		}
+		for _, ipRange := range permission.Ipv6Ranges {
+			_, network, err := net.ParseCIDR(aws.ToString(ipRange.CidrIpv6))
+			if err == nil && network.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package awsval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/report"
+)
+
+// WebACL is the part of a WAFv2 web ACL the checks read, as aws wafv2 get-web-acl prints it
+type WebACL struct {
+	Name             string           `json:"Name"`
+	ARN              string           `json:"ARN"`
+	DefaultAction    WebACLAction     `json:"DefaultAction"`
+	Rules            []WebACLRule     `json:"Rules"`
+	VisibilityConfig VisibilityConfig `json:"VisibilityConfig"`
+}
+
+// WebACLAction is an allow or block action; the one that is set is the action taken
+type WebACLAction struct {
+	Allow *struct{} `json:"Allow"`
+	Block *struct{} `json:"Block"`
+}
+
+// WebACLRule is one rule of a web ACL: a managed rule group or a rate-based rule
+type WebACLRule struct {
+	Name      string `json:"Name"`
+	Priority  int    `json:"Priority"`
+	Statement struct {
+		ManagedRuleGroupStatement *struct {
+			VendorName string `json:"VendorName"`
+			Name       string `json:"Name"`
+		} `json:"ManagedRuleGroupStatement"`
+		RateBasedStatement *struct {
+			Limit            int64  `json:"Limit"`
+			AggregateKeyType string `json:"AggregateKeyType"`
+		} `json:"RateBasedStatement"`
+	} `json:"Statement"`
+	VisibilityConfig VisibilityConfig `json:"VisibilityConfig"`
+}
+
+// VisibilityConfig is whether a web ACL or rule sends metrics and samples requests
+type VisibilityConfig struct {
+	CloudWatchMetricsEnabled bool   `json:"CloudWatchMetricsEnabled"`
+	SampledRequestsEnabled   bool   `json:"SampledRequestsEnabled"`
+	MetricName               string `json:"MetricName"`
+}
+
+// WebACLExpectation is how a web ACL must be configured
+type WebACLExpectation struct {
+	Scope             string   // "REGIONAL" or "CLOUDFRONT"
+	DefaultAction     string   // "allow" or "block"
+	ManagedRuleGroups []string // AWS managed rule groups, such as "AWSManagedRulesSQLiRuleSet"
+	RateLimit         int64    // Requests per IP in 5 minutes of the rate-based rule; 0 when there must be none
+}
+
+// ParseWebACLARN returns the scope, name and ID of a web ACL from its ARN, which get-web-acl takes separately
+func ParseWebACLARN(arn string) (scope, name, id string, err error) {
+	// arn:aws:wafv2:<region>:<account>:<regional|global>/webacl/<name>/<id>
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[2] != "wafv2" {
+		return "", "", "", fmt.Errorf("%q is not a WAFv2 ARN", arn)
+	}
+	resource := strings.Split(parts[5], "/")
+	if len(resource) != 4 || resource[1] != "webacl" {
+		return "", "", "", fmt.Errorf("%q is not a web ACL ARN", arn)
+	}
+	scope = "REGIONAL"
+	if resource[0] == "global" {
+		scope = "CLOUDFRONT"
+	}
+	return scope, resource[2], resource[3], nil
+}
+
+// GetWebACLE looks up a web ACL by ARN
+func GetWebACLE(t *testing.T, arn, region string) (*WebACL, error) {
+	scope, name, id, err := ParseWebACLARN(arn)
+	if err != nil {
+		return nil, err
+	}
+	if scope == "CLOUDFRONT" {
+		region = "us-east-1" // CloudFront web ACLs are only reachable in us-east-1
+	}
+
+	acl, err := runWebACLCommand(t, region, "get-web-acl", "--scope", scope, "--name", name, "--id", id)
+	if err == nil && acl == nil {
+		err = fmt.Errorf("aws wafv2 get-web-acl returned no web ACL for %s", arn)
+	}
+	return acl, err
+}
+
+// GetWebACLForResourceE returns the web ACL associated with a regional resource, such as an application load
+// balancer or an API Gateway stage, or nil when none is. A web ACL protects nothing until it is associated, and the
+// association is a resource of its own that is easily left out.
+func GetWebACLForResourceE(t *testing.T, resourceARN, region string) (*WebACL, error) {
+	return runWebACLCommand(t, region, "get-web-acl-for-resource", "--resource-arn", resourceARN)
+}
+
+// runWebACLCommand runs an aws wafv2 command answering with a web ACL, and returns nil when it answers without one
+func runWebACLCommand(t *testing.T, region string, args ...string) (*WebACL, error) {
+	var output json.RawMessage
+	if err := awscalls.RunCLI(context.Background(), t, region, &output, "wafv2", args...); err != nil {
+		return nil, err
+	}
+	acl, err := parseWebACLResponse(string(output))
+	if err != nil {
+		return nil, fmt.Errorf("unexpected output from aws wafv2 %s: %w", args[0], err)
+	}
+	return acl, nil
+}
+
+// parseWebACLResponse reads the WebACL of a wafv2 response, which is absent when a resource has no web ACL
+func parseWebACLResponse(output string) (*WebACL, error) {
+	if strings.TrimSpace(output) == "" {
+		return nil, nil
+	}
+	var result struct {
+		WebACL *WebACL `json:"WebACL"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, err
+	}
+	return result.WebACL, nil
+}
+
+// CheckWebACLAssociation checks the web ACL associated with a resource, as GetWebACLForResourceE returns it, is
+// the one with webACLARN
+func CheckWebACLAssociation(resource string, associated *WebACL, webACLARN string) report.Finding {
+	finding := report.Finding{Control: "WAF-Association", Resource: resource, ARN: webACLARN}
+	switch {
+	case associated == nil:
+		finding.Detail = "no web ACL is associated"
+	case associated.ARN != webACLARN:
+		finding.Detail = fmt.Sprintf("associated with %s instead", associated.ARN)
+	default:
+		finding.Passed = true
+		finding.Detail = "associated with " + associated.Name
+	}
+	return finding
+}
+
+// CheckWebACL checks a web ACL against an expectation: its scope, taken from its ARN, its default action, that it
+// runs each managed rule group and the rate-based rule with the expected limit, and that the ACL and each of its
+// rules send CloudWatch metrics. Each check is one finding, so a report names every difference.
+func CheckWebACL(acl *WebACL, expected WebACLExpectation) []report.Finding {
+	// A finding about a rule or rule group is named after it as well as the web ACL, so each is its own subtest
+	finding := func(control, resource string, passed bool, detail string, args ...interface{}) report.Finding {
+		return report.Finding{Control: control, Resource: resource, ARN: acl.ARN, Passed: passed,
+			Detail: fmt.Sprintf(detail, args...)}
+	}
+	var findings []report.Finding
+
+	scope, _, _, err := ParseWebACLARN(acl.ARN)
+	findings = append(findings, finding("WAF-Scope", acl.Name, err == nil && scope == expected.Scope,
+		"scope is %s, expected %s", scope, expected.Scope))
+
+	defaultAction := "none"
+	switch {
+	case acl.DefaultAction.Allow != nil:
+		defaultAction = "allow"
+	case acl.DefaultAction.Block != nil:
+		defaultAction = "block"
+	}
+	findings = append(findings, finding("WAF-DefaultAction", acl.Name, defaultAction == expected.DefaultAction,
+		"default action is %s, expected %s", defaultAction, expected.DefaultAction))
+
+	managed := map[string]bool{}
+	var rateLimits []int64
+	for _, rule := range acl.Rules {
+		if group := rule.Statement.ManagedRuleGroupStatement; group != nil && group.VendorName == "AWS" {
+			managed[group.Name] = true
+		}
+		if rate := rule.Statement.RateBasedStatement; rate != nil {
+			rateLimits = append(rateLimits, rate.Limit)
+		}
+		findings = append(findings, finding("WAF-Metrics", acl.Name+"/"+rule.Name,
+			rule.VisibilityConfig.CloudWatchMetricsEnabled, "rule does not send CloudWatch metrics"))
+	}
+	findings = append(findings, finding("WAF-Metrics", acl.Name, acl.VisibilityConfig.CloudWatchMetricsEnabled,
+		"web ACL does not send CloudWatch metrics"))
+
+	for _, group := range expected.ManagedRuleGroups {
+		findings = append(findings, finding("WAF-ManagedRules", acl.Name+"/"+group, managed[group],
+			"rule group is not run"))
+	}
+
+	if expected.RateLimit == 0 {
+		findings = append(findings, finding("WAF-RateLimit", acl.Name, len(rateLimits) == 0,
+			"has rate-based rules with limits %v, expected none", rateLimits))
+	} else {
+		findings = append(findings, finding("WAF-RateLimit", acl.Name,
+			len(rateLimits) == 1 && rateLimits[0] == expected.RateLimit,
+			"rate-based rule limits are %v, expected one rule limiting to %d", rateLimits, expected.RateLimit))
+	}
+	return findings
+}
@@ -0,0 +1,115 @@
+package awsval
+
+import (
+	"encoding/json"
+	"testing"
+
+	"terraform-tests/report"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sampleWebACL is the WebACL of an aws wafv2 get-web-acl response, trimmed to the fields the checks read
+const sampleWebACL = `{
+	"Name": "coalition-test-waf",
+	"ARN": "arn:aws:wafv2:us-east-1:123456789012:regional/webacl/coalition-test-waf/a1b2c3d4",
+	"DefaultAction": {"Allow": {}},
+	"Rules": [
+		{
+			"Name": "RateLimitPerIP",
+			"Priority": 0,
+			"Statement": {"RateBasedStatement": {"Limit": 2000, "AggregateKeyType": "IP"}},
+			"Action": {"Block": {}},
+			"VisibilityConfig": {"CloudWatchMetricsEnabled": true, "SampledRequestsEnabled": true, "MetricName": "rate"}
+		},
+		{
+			"Name": "AWS-AWSManagedRulesSQLiRuleSet",
+			"Priority": 1,
+			"Statement": {"ManagedRuleGroupStatement": {"VendorName": "AWS", "Name": "AWSManagedRulesSQLiRuleSet"}},
+			"OverrideAction": {"None": {}},
+			"VisibilityConfig": {"CloudWatchMetricsEnabled": false, "SampledRequestsEnabled": true, "MetricName": "sqli"}
+		}
+	],
+	"VisibilityConfig": {"CloudWatchMetricsEnabled": true, "SampledRequestsEnabled": true, "MetricName": "waf"}
+}`
+
+// TestParseWebACLARN checks the scope, name and ID are read from regional and CloudFront web ACL ARNs, and other
+// ARNs are refused
+func TestParseWebACLARN(t *testing.T) {
+	scope, name, id, err := ParseWebACLARN("arn:aws:wafv2:us-east-1:123456789012:regional/webacl/test-waf/a1b2c3d4")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"REGIONAL", "test-waf", "a1b2c3d4"}, []string{scope, name, id})
+
+	scope, _, _, err = ParseWebACLARN("arn:aws:wafv2:us-east-1:123456789012:global/webacl/test-waf/a1b2c3d4")
+	require.NoError(t, err)
+	assert.Equal(t, "CLOUDFRONT", scope)
+
+	for _, arn := range []string{
+		"arn:aws:s3:::coalition-test-bucket",
+		"arn:aws:wafv2:us-east-1:123456789012:regional/ipset/test-ips/a1b2c3d4",
+		"test-waf",
+	} {
+		_, _, _, err := ParseWebACLARN(arn)
+		assert.Error(t, err, "%s is not a web ACL ARN", arn)
+	}
+}
+
+// TestCheckWebACL checks each difference from the expectation is its own failed finding, and the rest pass
+func TestCheckWebACL(t *testing.T) {
+	var acl WebACL
+	require.NoError(t, json.Unmarshal([]byte(sampleWebACL), &acl))
+
+	findings := CheckWebACL(&acl, WebACLExpectation{
+		Scope:             "REGIONAL",
+		DefaultAction:     "allow",
+		ManagedRuleGroups: []string{"AWSManagedRulesCommonRuleSet", "AWSManagedRulesSQLiRuleSet"},
+		RateLimit:         2000,
+	})
+
+	failed := map[string]bool{}
+	for _, finding := range findings {
+		if !finding.Passed {
+			failed[finding.Control+" "+finding.Resource] = true
+		}
+	}
+	assert.Equal(t, map[string]bool{
+		"WAF-Metrics coalition-test-waf/AWS-AWSManagedRulesSQLiRuleSet":    true,
+		"WAF-ManagedRules coalition-test-waf/AWSManagedRulesCommonRuleSet": true,
+	}, failed)
+	// Scope, default action, metrics for the two rules and the ACL, the two rule groups and the rate limit
+	assert.Len(t, findings, 8)
+
+	mismatched := CheckWebACL(&acl, WebACLExpectation{Scope: "CLOUDFRONT", DefaultAction: "block", RateLimit: 100})
+	assert.ElementsMatch(t, []string{"WAF-Scope", "WAF-DefaultAction", "WAF-RateLimit"}, failedControls(mismatched))
+}
+
+// failedControls returns the control of each failed finding, leaving out metrics, which every check reports
+func failedControls(findings []report.Finding) []string {
+	var controls []string
+	for _, finding := range findings {
+		if !finding.Passed && finding.Control != "WAF-Metrics" {
+			controls = append(controls, finding.Control)
+		}
+	}
+	return controls
+}
+
+// TestWebACLAssociation checks a resource without a web ACL, as get-web-acl-for-resource answers for it, or with
+// another web ACL fails, and one with the expected web ACL passes
+func TestWebACLAssociation(t *testing.T) {
+	const albARN = "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/coalition-alb/0123456789abcdef"
+
+	unassociated, err := parseWebACLResponse("{}\n")
+	require.NoError(t, err)
+	assert.Nil(t, unassociated)
+
+	associated, err := parseWebACLResponse(`{"WebACL": ` + sampleWebACL + `}`)
+	require.NoError(t, err)
+	require.NotNil(t, associated)
+
+	assert.False(t, CheckWebACLAssociation(albARN, unassociated, associated.ARN).Passed)
+	assert.False(t, CheckWebACLAssociation(albARN, associated,
+		"arn:aws:wafv2:us-east-1:123456789012:regional/webacl/other-waf/e5f6a7b8").Passed)
+	assert.True(t, CheckWebACLAssociation(albARN, associated, associated.ARN).Passed)
+}
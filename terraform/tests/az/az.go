@@ -0,0 +1,144 @@
+// Package az picks the availability zones tests deploy subnets into, so the suite runs in regions whose zones are
+// not simply <region>a and <region>b: regions where a zone is impaired, opt-in, or does not offer the instance types
+// the modules launch.
+package az
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/awsval"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	terratest_testing "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// BastionInstanceType is the instance type the bastion module launches into the networking module's public subnets
+const BastionInstanceType = "t4g.nano"
+
+// picked caches the zones picked for each region and set of instance types, so every test in a package run deploys
+// into the same zones and the zones are only described once
+var picked sync.Map
+
+// PickTwo returns two availability zones in the region that are available, need no opt-in and offer every given
+// instance type, failing the test if the region has fewer than two
+func PickTwo(t *testing.T, region string, instanceTypes ...string) []string {
+	zones, err := PickTwoE(t, region, instanceTypes...)
+	require.NoError(t, err, "Failed to pick availability zones in %s", region)
+	t.Logf("Deploying into availability zones %s", strings.Join(zones, ", "))
+	return zones
+}
+
+// PickTwoE returns two availability zones in the region that are available, need no opt-in and offer every given
+// instance type, in name order so repeated runs pick the same zones
+func PickTwoE(t terratest_testing.TestingT, region string, instanceTypes ...string) ([]string, error) {
+	sortedTypes := append([]string(nil), instanceTypes...)
+	sort.Strings(sortedTypes)
+	key := region + "/" + strings.Join(sortedTypes, ",")
+	if zones, ok := picked.Load(key); ok {
+		return zones.([]string), nil
+	}
+
+	ctx := context.Background()
+	cfg, err := awscalls.LoadConfig(ctx, t, region)
+	if err != nil {
+		return nil, err
+	}
+	client := ec2.NewFromConfig(cfg)
+
+	zones, err := availableZones(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	for _, instanceType := range sortedTypes {
+		offered, offeringsErr := zonesOffering(ctx, client, instanceType)
+		if offeringsErr != nil {
+			return nil, offeringsErr
+		}
+		zones = intersect(zones, offered)
+	}
+
+	if len(zones) < 2 {
+		return nil, fmt.Errorf("region %s has %d usable availability zones offering %v, need 2: %v",
+			region, len(zones), sortedTypes, zones)
+	}
+	zones = zones[:2]
+	picked.Store(key, zones)
+	return zones, nil
+}
+
+// availableZones returns the names of the region's availability zones that are available and opted in, leaving out
+// Local and Wavelength Zones
+func availableZones(ctx context.Context, client *ec2.Client) ([]string, error) {
+	result, err := client.DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{
+		Filters: []types.Filter{
+			{Name: aws.String("state"), Values: []string{"available"}},
+			{Name: aws.String("zone-type"), Values: []string{"availability-zone"}},
+			{Name: aws.String("opt-in-status"), Values: []string{"opt-in-not-required", "opted-in"}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	zones := make([]string, 0, len(result.AvailabilityZones))
+	for _, zone := range result.AvailabilityZones {
+		zones = append(zones, aws.ToString(zone.ZoneName))
+	}
+	sort.Strings(zones)
+	return zones, nil
+}
+
+// zonesOffering returns the names of the availability zones that offer an instance type
+func zonesOffering(ctx context.Context, client *ec2.Client, instanceType string) (map[string]bool, error) {
+	offered := map[string]bool{}
+	paginator := ec2.NewDescribeInstanceTypeOfferingsPaginator(client, &ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: types.LocationTypeAvailabilityZone,
+		Filters: []types.Filter{
+			{Name: aws.String("instance-type"), Values: []string{instanceType}},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, offering := range page.InstanceTypeOfferings {
+			offered[aws.ToString(offering.Location)] = true
+		}
+	}
+	return offered, nil
+}
+
+// intersect returns the zones, in order, that are also in offered
+func intersect(zones []string, offered map[string]bool) []string {
+	var kept []string
+	for _, zone := range zones {
+		if offered[zone] {
+			kept = append(kept, zone)
+		}
+	}
+	return kept
+}
+
+// AssertSubnetsInZones checks that each subnet landed in the zone at the same index, as the networking module
+// places its a and b subnets in the first and second of its availability_zones
+func AssertSubnetsInZones(t *testing.T, region string, subnetIDs, zones []string) {
+	if !assert.Len(t, subnetIDs, len(zones), "Expected one subnet per availability zone") {
+		return
+	}
+	for i, subnetID := range subnetIDs {
+		subnet := awsval.GetSubnet(t, subnetID, region)
+		assert.Equal(t, zones[i], aws.ToString(subnet.AvailabilityZone),
+			"Subnet %s should be in the picked availability zone", subnetID)
+	}
+}
@@ -0,0 +1,201 @@
+// Package benchmarks implements a curated subset of the CIS AWS Foundations Benchmark (v1.5.0)
+// that is relevant to the Coalition Builder stack, evaluated against live post-apply resources.
+package benchmarks
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/require"
+)
+
+// Result is the outcome of one control evaluated against one resource
+type Result struct {
+	ResourceID string
+	ARN        string
+	Passed     bool
+	Skipped    bool
+	Detail     string
+}
+
+// Control is a single benchmark control evaluated against live resources
+type Control struct {
+	ID       string // e.g. "CIS-2.1.1"
+	Title    string
+	Evaluate func(ctx context.Context, clients *Clients, target *Target) ([]Result, error)
+}
+
+// Clients holds the AWS clients the controls use
+type Clients struct {
+	S3   *s3.Client
+	EC2  *ec2.Client
+	IAM  *iam.Client
+	Logs *cloudwatchlogs.Client
+}
+
+// Target lists the deployed resources the controls are evaluated against
+type Target struct {
+	Region              string
+	Prefix              string
+	BucketNames         []string
+	SecurityGroupIDs    []string
+	VPCIDs              []string
+	PolicyARNs          []string
+	LogGroupNames       []string
+	PublicBucketReasons map[string]string // buckets that are public by design, with the reason
+}
+
+// StackLogGroups are log groups the stack creates without the deployment prefix in their name
+var StackLogGroups = []string{"/vpc/flow-logs"}
+
+// PublicBucketPatterns documents buckets, matched by name, that are public by design
+var PublicBucketPatterns = map[*regexp.Regexp]string{
+	regexp.MustCompile(`-(dev|staging|production)-assets(-[0-9a-f]+)?$`): "serverless assets bucket serves public-read static files",
+}
+
+// NewClients creates the AWS clients for the given region
+func NewClients(t *testing.T, region string) *Clients {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	return &Clients{
+		S3:   s3.NewFromConfig(cfg),
+		EC2:  ec2.NewFromConfig(cfg),
+		IAM:  iam.NewFromConfig(cfg),
+		Logs: cloudwatchlogs.NewFromConfig(cfg),
+	}
+}
+
+// SetupBenchmark discovers the deployment named by BENCHMARK_PREFIX, skipping the test when it is unset
+func SetupBenchmark(t *testing.T) (*Clients, *Target) {
+	common.RequireTier(t, common.TierE2E)
+
+	prefix := os.Getenv("BENCHMARK_PREFIX")
+	if prefix == "" {
+		t.Skip("Skipping benchmark - set BENCHMARK_PREFIX to the prefix of a deployed stack")
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	clients := NewClients(t, region)
+	return clients, DiscoverTarget(t, clients, region, prefix)
+}
+
+// DiscoverTarget finds the live resources that belong to a deployment by their name prefix
+func DiscoverTarget(t *testing.T, clients *Clients, region, prefix string) *Target {
+	ctx := context.Background()
+	target := &Target{Region: region, Prefix: prefix, PublicBucketReasons: map[string]string{}}
+
+	buckets, err := clients.S3.ListBuckets(ctx, &s3.ListBucketsInput{})
+	require.NoError(t, err)
+	for _, bucket := range buckets.Buckets {
+		name := aws.ToString(bucket.Name)
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		target.BucketNames = append(target.BucketNames, name)
+		for pattern, reason := range PublicBucketPatterns {
+			if pattern.MatchString(name) {
+				target.PublicBucketReasons[name] = reason
+			}
+		}
+	}
+
+	groups, err := clients.EC2.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []ec2types.Filter{{Name: aws.String("group-name"), Values: []string{prefix + "*"}}},
+	})
+	require.NoError(t, err)
+	for _, group := range groups.SecurityGroups {
+		target.SecurityGroupIDs = append(target.SecurityGroupIDs, aws.ToString(group.GroupId))
+	}
+
+	vpcs, err := clients.EC2.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+		Filters: []ec2types.Filter{{Name: aws.String("tag:Name"), Values: []string{prefix + "*"}}},
+	})
+	require.NoError(t, err)
+	for _, vpc := range vpcs.Vpcs {
+		target.VPCIDs = append(target.VPCIDs, aws.ToString(vpc.VpcId))
+	}
+
+	policies := iam.NewListPoliciesPaginator(clients.IAM, &iam.ListPoliciesInput{Scope: iamtypes.PolicyScopeTypeLocal})
+	for policies.HasMorePages() {
+		page, err := policies.NextPage(ctx)
+		require.NoError(t, err)
+		for _, policy := range page.Policies {
+			if strings.HasPrefix(aws.ToString(policy.PolicyName), prefix) {
+				target.PolicyARNs = append(target.PolicyARNs, aws.ToString(policy.Arn))
+			}
+		}
+	}
+
+	logGroups := cloudwatchlogs.NewDescribeLogGroupsPaginator(clients.Logs, &cloudwatchlogs.DescribeLogGroupsInput{})
+	for logGroups.HasMorePages() {
+		page, err := logGroups.NextPage(ctx)
+		require.NoError(t, err)
+		for _, group := range page.LogGroups {
+			name := aws.ToString(group.LogGroupName)
+			if strings.Contains(name, prefix) || containsString(StackLogGroups, name) {
+				target.LogGroupNames = append(target.LogGroupNames, name)
+			}
+		}
+	}
+
+	t.Logf("Discovered %d buckets, %d security groups, %d VPCs, %d policies and %d log groups for prefix %s",
+		len(target.BucketNames), len(target.SecurityGroupIDs), len(target.VPCIDs),
+		len(target.PolicyARNs), len(target.LogGroupNames), prefix)
+
+	return target
+}
+
+// Run evaluates each control and reports every result as a subtest named "<control ID>/<resource>"
+func Run(t *testing.T, clients *Clients, target *Target, controls ...Control) {
+	for _, control := range controls {
+		t.Run(control.ID, func(t *testing.T) {
+			t.Logf("%s: %s", control.ID, control.Title)
+
+			results, err := control.Evaluate(context.Background(), clients, target)
+			require.NoError(t, err, "Control %s could not be evaluated", control.ID)
+			if len(results) == 0 {
+				t.Skip("No resources in scope for this control")
+			}
+
+			for _, result := range results {
+				common.RecordEvidence(t, control.ID, result.ResourceID, result.ARN, result.Passed, result.Skipped, result.Detail)
+				t.Run(result.ResourceID, func(t *testing.T) {
+					switch {
+					case result.Skipped:
+						t.Skip(result.Detail)
+					case !result.Passed:
+						t.Errorf("%s %s: %s", control.ID, result.ResourceID, result.Detail)
+					}
+				})
+			}
+		})
+	}
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package benchmarks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCISBenchmarkSubset evaluates the curated CIS controls against a deployed stack.
+// Each result is reported as a subtest named "<control ID>/<resource>" so test output doubles as audit evidence.
+func TestCISBenchmarkSubset(t *testing.T) {
+	clients, target := SetupBenchmark(t)
+
+	Run(t, clients, target, Controls()...)
+}
+
+func TestPolicyGrantsFullAdmin(t *testing.T) {
+	testCases := map[string]struct {
+		document string
+		expected bool
+	}{
+		"wildcard action and resource": {
+			document: `{"Statement":[{"Effect":"Allow","Action":"*","Resource":"*"}]}`,
+			expected: true,
+		},
+		"single statement object": {
+			document: `{"Statement":{"Effect":"Allow","Action":["s3:GetObject","*"],"Resource":["*"]}}`,
+			expected: true,
+		},
+		"scoped action": {
+			document: `{"Statement":[{"Effect":"Allow","Action":"s3:*","Resource":"*"}]}`,
+			expected: false,
+		},
+		"deny statement": {
+			document: `{"Statement":[{"Effect":"Deny","Action":"*","Resource":"*"}]}`,
+			expected: false,
+		},
+		"invalid document": {
+			document: `not json`,
+			expected: false,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, PolicyGrantsFullAdmin(testCase.document))
+		})
+	}
+}
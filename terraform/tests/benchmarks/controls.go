@@ -0,0 +1,294 @@
+package benchmarks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// MinimumLogRetentionDays is the shortest retention accepted for stack log groups
+const MinimumLogRetentionDays = 7
+
+// AdminPorts are the remote administration ports CIS 5.2/5.3 forbid opening to the world
+var AdminPorts = []int32{22, 3389}
+
+// Controls returns the CIS subset relevant to this stack
+func Controls() []Control {
+	return []Control{
+		{ID: "CIS-1.16", Title: "IAM policies that allow full \"*:*\" administrative privileges are not attached", Evaluate: EvaluateNoAdminPolicies},
+		{ID: "CIS-2.1.1", Title: "All S3 buckets employ encryption-at-rest", Evaluate: EvaluateS3Encryption},
+		{ID: "CIS-2.1.5", Title: "S3 buckets are configured with Block public access", Evaluate: EvaluateS3PublicAccessBlock},
+		{ID: "CIS-3.9", Title: "VPC flow logging is enabled in all VPCs", Evaluate: EvaluateVPCFlowLogs},
+		{ID: "CIS-5.2", Title: "No security groups allow ingress from 0.0.0.0/0 to remote server administration ports", Evaluate: evaluateAdminIngress("0.0.0.0/0")},
+		{ID: "CIS-5.3", Title: "No security groups allow ingress from ::/0 to remote server administration ports", Evaluate: evaluateAdminIngress("::/0")},
+		{ID: "STACK-LOG-1", Title: fmt.Sprintf("Log groups retain events for a bounded period of at least %d days", MinimumLogRetentionDays), Evaluate: EvaluateLogRetention},
+	}
+}
+
+// EvaluateNoAdminPolicies flags customer-managed policies that allow every action on every resource
+func EvaluateNoAdminPolicies(ctx context.Context, clients *Clients, target *Target) ([]Result, error) {
+	var results []Result
+	for _, policyARN := range target.PolicyARNs {
+		policy, err := clients.IAM.GetPolicy(ctx, &iam.GetPolicyInput{PolicyArn: aws.String(policyARN)})
+		if err != nil {
+			return nil, err
+		}
+		version, err := clients.IAM.GetPolicyVersion(ctx, &iam.GetPolicyVersionInput{
+			PolicyArn: aws.String(policyARN),
+			VersionId: policy.Policy.DefaultVersionId,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		document, err := url.QueryUnescape(aws.ToString(version.PolicyVersion.Document))
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, withARN(result(aws.ToString(policy.Policy.PolicyName), !PolicyGrantsFullAdmin(document),
+			"policy allows Action \"*\" on Resource \"*\""), policyARN))
+	}
+	return results, nil
+}
+
+// PolicyGrantsFullAdmin reports whether a policy document has an Allow statement for "*" actions on "*" resources
+func PolicyGrantsFullAdmin(document string) bool {
+	var policy struct {
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(document), &policy); err != nil {
+		return false
+	}
+
+	// Statement may be a single object or a list
+	var statements []map[string]interface{}
+	if err := json.Unmarshal(policy.Statement, &statements); err != nil {
+		var single map[string]interface{}
+		if err := json.Unmarshal(policy.Statement, &single); err != nil {
+			return false
+		}
+		statements = []map[string]interface{}{single}
+	}
+
+	for _, statement := range statements {
+		if statement["Effect"] == "Allow" && containsWildcard(statement["Action"]) && containsWildcard(statement["Resource"]) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsWildcard reports whether a policy element is "*" or a list containing "*"
+func containsWildcard(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return v == "*"
+	case []interface{}:
+		for _, item := range v {
+			if item == "*" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EvaluateS3Encryption verifies every bucket has a default server-side encryption rule
+func EvaluateS3Encryption(ctx context.Context, clients *Clients, target *Target) ([]Result, error) {
+	var results []Result
+	for _, bucket := range target.BucketNames {
+		output, err := clients.S3.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{Bucket: aws.String(bucket)})
+		if isErrorCode(err, "ServerSideEncryptionConfigurationNotFoundError") {
+			results = append(results, withARN(result(bucket, false, "bucket has no default encryption configuration"), bucketARN(bucket)))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		encrypted := false
+		for _, rule := range output.ServerSideEncryptionConfiguration.Rules {
+			if rule.ApplyServerSideEncryptionByDefault != nil && rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm != "" {
+				encrypted = true
+			}
+		}
+		results = append(results, withARN(result(bucket, encrypted, "bucket has no default encryption algorithm"), bucketARN(bucket)))
+	}
+	return results, nil
+}
+
+// EvaluateS3PublicAccessBlock verifies every bucket blocks public access, except buckets public by design
+func EvaluateS3PublicAccessBlock(ctx context.Context, clients *Clients, target *Target) ([]Result, error) {
+	var results []Result
+	for _, bucket := range target.BucketNames {
+		if reason, ok := target.PublicBucketReasons[bucket]; ok {
+			results = append(results, Result{ResourceID: bucket, ARN: bucketARN(bucket), Skipped: true, Detail: "documented exception: " + reason})
+			continue
+		}
+
+		output, err := clients.S3.GetPublicAccessBlock(ctx, &s3.GetPublicAccessBlockInput{Bucket: aws.String(bucket)})
+		if isErrorCode(err, "NoSuchPublicAccessBlockConfiguration") {
+			results = append(results, withARN(result(bucket, false, "bucket has no public access block"), bucketARN(bucket)))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		block := output.PublicAccessBlockConfiguration
+		var disabled []string
+		for setting, enabled := range map[string]*bool{
+			"BlockPublicAcls":       block.BlockPublicAcls,
+			"BlockPublicPolicy":     block.BlockPublicPolicy,
+			"IgnorePublicAcls":      block.IgnorePublicAcls,
+			"RestrictPublicBuckets": block.RestrictPublicBuckets,
+		} {
+			if !aws.ToBool(enabled) {
+				disabled = append(disabled, setting)
+			}
+		}
+		sort.Strings(disabled)
+		results = append(results, withARN(result(bucket, len(disabled) == 0,
+			fmt.Sprintf("public access block settings disabled: %s", strings.Join(disabled, ", "))), bucketARN(bucket)))
+	}
+	return results, nil
+}
+
+// EvaluateVPCFlowLogs verifies every VPC has at least one active flow log
+func EvaluateVPCFlowLogs(ctx context.Context, clients *Clients, target *Target) ([]Result, error) {
+	var results []Result
+	for _, vpcID := range target.VPCIDs {
+		output, err := clients.EC2.DescribeFlowLogs(ctx, &ec2.DescribeFlowLogsInput{
+			Filter: []ec2types.Filter{{Name: aws.String("resource-id"), Values: []string{vpcID}}},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		active := false
+		for _, flowLog := range output.FlowLogs {
+			if aws.ToString(flowLog.FlowLogStatus) == "ACTIVE" {
+				active = true
+			}
+		}
+		results = append(results, result(vpcID, active, "VPC has no active flow log"))
+	}
+	return results, nil
+}
+
+// evaluateAdminIngress returns a control that flags admin ports open to the given world CIDR
+func evaluateAdminIngress(worldCIDR string) func(context.Context, *Clients, *Target) ([]Result, error) {
+	return func(ctx context.Context, clients *Clients, target *Target) ([]Result, error) {
+		if len(target.SecurityGroupIDs) == 0 {
+			return nil, nil
+		}
+
+		output, err := clients.EC2.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+			GroupIds: target.SecurityGroupIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var results []Result
+		for _, group := range output.SecurityGroups {
+			var open []string
+			for _, permission := range group.IpPermissions {
+				if !permissionOpenTo(permission, worldCIDR) {
+					continue
+				}
+				for _, port := range AdminPorts {
+					if permissionCoversPort(permission, port) {
+						open = append(open, fmt.Sprint(port))
+					}
+				}
+			}
+			results = append(results, result(aws.ToString(group.GroupId), len(open) == 0,
+				fmt.Sprintf("ports %s are open to %s", strings.Join(open, ", "), worldCIDR)))
+		}
+		return results, nil
+	}
+}
+
+// permissionOpenTo reports whether an ingress permission allows the given CIDR
+func permissionOpenTo(permission ec2types.IpPermission, cidr string) bool {
+	for _, ipRange := range permission.IpRanges {
+		if aws.ToString(ipRange.CidrIp) == cidr {
+			return true
+		}
+	}
+	for _, ipRange := range permission.Ipv6Ranges {
+		if aws.ToString(ipRange.CidrIpv6) == cidr {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionCoversPort reports whether an ingress permission includes the given TCP port
+func permissionCoversPort(permission ec2types.IpPermission, port int32) bool {
+	if aws.ToString(permission.IpProtocol) == "-1" {
+		return true
+	}
+	return aws.ToInt32(permission.FromPort) <= port && port <= aws.ToInt32(permission.ToPort)
+}
+
+// EvaluateLogRetention verifies stack log groups expire events after at least the minimum retention
+func EvaluateLogRetention(ctx context.Context, clients *Clients, target *Target) ([]Result, error) {
+	var results []Result
+	for _, name := range target.LogGroupNames {
+		output, err := clients.Logs.DescribeLogGroups(ctx, &cloudwatchlogs.DescribeLogGroupsInput{
+			LogGroupNamePrefix: aws.String(name),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, group := range output.LogGroups {
+			if aws.ToString(group.LogGroupName) != name {
+				continue
+			}
+			retention := aws.ToInt32(group.RetentionInDays)
+			results = append(results, result(name, retention >= MinimumLogRetentionDays,
+				fmt.Sprintf("retention is %d days (0 means never expire)", retention)))
+		}
+	}
+	return results, nil
+}
+
+// result builds a pass or fail result, keeping the detail only for failures
+func result(resourceID string, passed bool, detail string) Result {
+	if passed {
+		detail = ""
+	}
+	return Result{ResourceID: resourceID, Passed: passed, Detail: detail}
+}
+
+// withARN attaches the resource ARN to a result for evidence records
+func withARN(r Result, arn string) Result {
+	r.ARN = arn
+	return r
+}
+
+// bucketARN returns the ARN of an S3 bucket
+func bucketARN(bucket string) string {
+	return "arn:aws:s3:::" + bucket
+}
+
+// isErrorCode reports whether err is an AWS API error with the given code
+func isErrorCode(err error, code string) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == code
+}
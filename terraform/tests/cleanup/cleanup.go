@@ -0,0 +1,158 @@
+// Package cleanup runs the finalizers a test registers for the resources it creates in a fixed order once the
+// test and its subtests finish, and reports which resources were cleaned up and which were not.
+package cleanup
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Phase groups finalizers that must run before or after others
+type Phase int
+
+// Phases run in this order. Within a phase, finalizers run in reverse order of registration, as defers do, so
+// a stack that depends on another is destroyed first when it is registered after it.
+const (
+	BeforeDestroy Phase = iota // Prepares for the destroy, such as emptying buckets or capturing IDs it removes
+	Destroy                    // Destroys terraform configurations
+	AfterDestroy               // Removes or checks for what the destroy should have removed
+)
+
+func (p Phase) String() string {
+	switch p {
+	case BeforeDestroy:
+		return "before destroy"
+	case Destroy:
+		return "destroy"
+	case AfterDestroy:
+		return "after destroy"
+	}
+	return fmt.Sprintf("phase %d", int(p))
+}
+
+// Finalizer cleans up one resource. Run returns an error rather than failing the test, so the finalizers after it
+// still run; it must not call t.FailNow or anything that does, such as require.
+type Finalizer struct {
+	Name  string
+	Phase Phase
+	Run   func(t *testing.T) error
+}
+
+// Result is the outcome of one finalizer
+type Result struct {
+	Name     string
+	Phase    Phase
+	Err      error
+	Duration time.Duration
+}
+
+// registry holds the finalizers registered for one test
+type registry struct {
+	mu         sync.Mutex
+	finalizers []Finalizer
+	running    bool
+}
+
+var (
+	registriesMutex sync.Mutex
+	registries      = map[*testing.T]*registry{}
+)
+
+// Register adds a finalizer to run when the test finishes. All of a test's finalizers run from a single t.Cleanup,
+// after its subtests, including parallel ones, have finished.
+func Register(t *testing.T, finalizer Finalizer) {
+	t.Helper()
+
+	registriesMutex.Lock()
+	r, ok := registries[t]
+	if !ok {
+		r = &registry{}
+		registries[t] = r
+		t.Cleanup(func() { r.run(t) })
+	}
+	registriesMutex.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		t.Errorf("Cleanup %q registered while cleanup is already running; it will not run", finalizer.Name)
+		return
+	}
+	r.finalizers = append(r.finalizers, finalizer)
+}
+
+// Func registers a finalizer from a function
+func Func(t *testing.T, phase Phase, name string, run func(t *testing.T) error) {
+	t.Helper()
+	Register(t, Finalizer{Name: name, Phase: phase, Run: run})
+}
+
+// run runs every finalizer in order, logs a summary and fails the test for each finalizer that failed
+func (r *registry) run(t *testing.T) {
+	r.mu.Lock()
+	r.running = true
+	finalizers := r.finalizers
+	r.mu.Unlock()
+
+	registriesMutex.Lock()
+	delete(registries, t)
+	registriesMutex.Unlock()
+
+	results := make([]Result, 0, len(finalizers))
+	for _, phase := range []Phase{BeforeDestroy, Destroy, AfterDestroy} {
+		for i := len(finalizers) - 1; i >= 0; i-- {
+			if finalizers[i].Phase == phase {
+				results = append(results, runFinalizer(t, finalizers[i]))
+			}
+		}
+	}
+
+	t.Log(Summary(results))
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("Cleanup %q failed: %v", result.Name, result.Err)
+		}
+	}
+}
+
+// runFinalizer runs one finalizer, turning a panic into an error so the remaining finalizers still run
+func runFinalizer(t *testing.T, finalizer Finalizer) (result Result) {
+	start := time.Now()
+	result = Result{Name: finalizer.Name, Phase: finalizer.Phase}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result.Err = fmt.Errorf("panic: %v", recovered)
+		}
+		result.Duration = time.Since(start)
+	}()
+
+	result.Err = finalizer.Run(t)
+	return result
+}
+
+// Summary renders results as a report of what was cleaned up and what failed, in the order they ran
+func Summary(results []Result) string {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "Cleanup: %d cleaned up, %d failed", len(results)-failed, failed)
+	for _, result := range results {
+		status := "ok  "
+		if result.Err != nil {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&report, "\n  %s %-14s %s (%s)", status, result.Phase, result.Name, result.Duration.Round(time.Second))
+		if result.Err != nil {
+			fmt.Fprintf(&report, ": %v", result.Err)
+		}
+	}
+	return report.String()
+}
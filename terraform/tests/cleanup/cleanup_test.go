@@ -0,0 +1,47 @@
+package cleanup
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFinalizersRunByPhaseThenInReverseOrder(t *testing.T) {
+	var order []string
+	record := func(name string) func(*testing.T) error {
+		return func(*testing.T) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	t.Run("Register", func(t *testing.T) {
+		Func(t, AfterDestroy, "leak check", record("leak check"))
+		Func(t, Destroy, "host vpc", record("host vpc"))
+		Func(t, BeforeDestroy, "empty bucket", record("empty bucket"))
+		Func(t, Destroy, "database", record("database"))
+		assert.Empty(t, order, "Finalizers should wait for the test to finish")
+	})
+
+	assert.Equal(t, []string{"empty bucket", "database", "host vpc", "leak check"}, order)
+}
+
+func TestRunFinalizerRecoversPanics(t *testing.T) {
+	result := runFinalizer(t, Finalizer{Name: "panics", Phase: Destroy, Run: func(*testing.T) error {
+		panic("boom")
+	}})
+
+	assert.EqualError(t, result.Err, "panic: boom")
+}
+
+func TestSummary(t *testing.T) {
+	summary := Summary([]Result{
+		{Name: "empty bucket test-static", Phase: BeforeDestroy},
+		{Name: "destroy ../../modules/storage", Phase: Destroy, Err: errors.New("BucketNotEmpty")},
+	})
+
+	assert.Equal(t, "Cleanup: 1 cleaned up, 1 failed\n"+
+		"  ok   before destroy empty bucket test-static (0s)\n"+
+		"  FAIL destroy        destroy ../../modules/storage (0s): BucketNotEmpty", summary)
+}
@@ -0,0 +1,155 @@
+// Command retryflaky runs go test and re-runs, once, each failed top-level test whose output matches a known
+// transient infrastructure failure. Quarantined tests are not retried and do not fail the run, and a test that
+// needs a retry in too many recent runs is added to the quarantine list so it gets fixed rather than retried
+// forever. Arguments after -- are passed to go test:
+//
+//	go run ./cmd/retryflaky -- -v -timeout 10m ./modules/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"terraform-tests/flaky"
+)
+
+func main() {
+	quarantinePath := flag.String("quarantine", "quarantine.json", "quarantine list, updated when a test is quarantined")
+	historyPath := flag.String("history", ".flaky-history.json", "retry history carried between runs")
+	reportPath := flag.String("report", "flaky-report.json", "where to write the report")
+	quarantineAfter := flag.Int("quarantine-after", 3, "quarantine a test retried in this many of the recent runs")
+	historyRuns := flag.Int("history-runs", 20, "number of recent runs the history keeps")
+	flag.Parse()
+
+	if err := run(*quarantinePath, *historyPath, *reportPath, *quarantineAfter, *historyRuns, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "retryflaky:", err)
+		os.Exit(1)
+	}
+}
+
+func run(quarantinePath, historyPath, reportPath string, quarantineAfter, historyRuns int, args []string) error {
+	quarantine, err := flaky.LoadQuarantine(quarantinePath)
+	if err != nil {
+		return err
+	}
+	history, err := flaky.LoadHistory(historyPath)
+	if err != nil {
+		return err
+	}
+
+	runs, err := goTest(args)
+	if err != nil {
+		return err
+	}
+	results, retry := flaky.Triage(runs, quarantine)
+
+	if len(retry) > 0 {
+		names := make([]string, 0, len(retry))
+		for _, failed := range retry {
+			fmt.Printf("=== RETRY %s after a transient failure\n", failed.Key())
+			names = append(names, regexp.QuoteMeta(failed.Test))
+		}
+		// Tests with the same name in other packages run again too, which costs time but not correctness
+		reruns, rerunErr := goTest(append(withoutRunFlag(args), "-count=1", "-run", "^("+strings.Join(names, "|")+")$"))
+		if rerunErr != nil {
+			return rerunErr
+		}
+		flaky.ApplyRetries(results, reruns)
+	}
+
+	now := time.Now()
+	newlyQuarantined := flaky.QuarantineRepeatOffenders(results, history, quarantine, quarantineAfter, historyRuns, now)
+	if err = history.Save(historyPath); err != nil {
+		return err
+	}
+	if len(newlyQuarantined) > 0 {
+		if err = quarantine.Save(quarantinePath); err != nil {
+			return err
+		}
+	}
+
+	report := flaky.NewReport(results, newlyQuarantined, now)
+	if err = writeReport(report, reportPath); err != nil {
+		return err
+	}
+	if report.Failed() {
+		return fmt.Errorf("%d tests failed", report.Counts[flaky.OutcomeFailed])
+	}
+	return nil
+}
+
+// goTest runs go test -json with the arguments, streaming the test output, and returns the top-level test results.
+// Its exit status is ignored, since the results say which tests failed; a package that failed to build is
+// reported as a failure outside any test.
+func goTest(args []string) ([]flaky.Run, error) {
+	cmd := exec.Command("go", append([]string{"test", "-json"}, args...)...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	runs, readErr := flaky.ReadRuns(stdout, os.Stdout)
+	waitErr := cmd.Wait()
+	if readErr != nil {
+		return nil, readErr
+	}
+	if _, exited := waitErr.(*exec.ExitError); waitErr != nil && !exited {
+		return nil, waitErr
+	}
+	if waitErr != nil && len(runs) == 0 {
+		return nil, fmt.Errorf("go test failed without running any tests: %w", waitErr)
+	}
+	return runs, nil
+}
+
+// withoutRunFlag drops -run from the go test arguments, so the retry selects only the tests it re-runs
+func withoutRunFlag(args []string) []string {
+	kept := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-run" || args[i] == "--run" || args[i] == "-test.run":
+			i++
+		case strings.HasPrefix(args[i], "-run=") || strings.HasPrefix(args[i], "--run=") ||
+			strings.HasPrefix(args[i], "-test.run="):
+			// The value is part of the argument
+		default:
+			kept = append(kept, args[i])
+		}
+	}
+	return kept
+}
+
+// writeReport writes the report as JSON and, in GitHub Actions, adds it to the job summary and annotates the
+// flaky and quarantined tests
+func writeReport(report *flaky.Report, path string) error {
+	if err := report.Save(path); err != nil {
+		return err
+	}
+	fmt.Println(report.Markdown())
+
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return nil
+	}
+	report.Annotate(os.Stdout)
+	summaryPath := os.Getenv("GITHUB_STEP_SUMMARY")
+	if summaryPath == "" {
+		return nil
+	}
+	summary, err := os.OpenFile(summaryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer summary.Close()
+	_, err = io.WriteString(summary, report.Markdown()+"\n")
+	return err
+}
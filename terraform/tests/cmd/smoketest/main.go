@@ -0,0 +1,103 @@
+// Command smoketest runs the smoke checks of package smoketest against a deployed environment and writes the
+// results as JSON, exiting non-zero when any check fails. It needs no AWS credentials or Terraform, so CD
+// pipelines can run it straight after a deploy:
+//
+//	go run ./cmd/smoketest -output smoketest.json https://staging.example.org
+//
+// The API is expected on api.<domain> and the frontend on the apex and www, unless -api-host, -api-base-path or
+// -frontend-hosts say otherwise.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"terraform-tests/smoketest"
+)
+
+func main() {
+	apiHost := flag.String("api-host", "", "host serving the API (default api.<domain>)")
+	apiBasePath := flag.String("api-base-path", "", "path the API is served under, such as an execute-api stage's /dev")
+	frontendHosts := flag.String("frontend-hosts", "", "comma-separated hosts serving the frontend "+
+		"(default <domain>,www.<domain>)")
+	outputPath := flag.String("output", "", "where to write the JSON results (default standard output)")
+	minCertDays := flag.Int("min-cert-days", int(smoketest.DefaultMinCertValidity.Hours()/24),
+		"days a certificate must stay valid")
+	timeout := flag.Duration("timeout", 5*time.Minute, "how long all the checks may take")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: smoketest [flags] <environment URL or domain>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	environment, err := parseEnvironment(flag.Arg(0), *apiHost, *apiBasePath, *frontendHosts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "smoketest:", err)
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	checker := smoketest.NewChecker(smoketest.Options{MinCertValidity: time.Duration(*minCertDays) * 24 * time.Hour})
+	report := checker.Run(ctx, environment)
+
+	if err = writeReport(report, *outputPath); err != nil {
+		fmt.Fprintln(os.Stderr, "smoketest:", err)
+		os.Exit(1)
+	}
+	for _, result := range report.Results {
+		outcome := "PASS"
+		if !result.Passed {
+			outcome = "FAIL"
+		}
+		fmt.Fprintf(os.Stderr, "%s %s %s: %s\n", outcome, result.Check, result.Target, result.Detail)
+	}
+	if !report.Passed {
+		fmt.Fprintf(os.Stderr, "smoketest: %d of %d checks failed\n", len(report.Failed()), len(report.Results))
+		os.Exit(1)
+	}
+}
+
+// parseEnvironment takes the domain from an environment URL or bare domain, and applies the host overrides
+func parseEnvironment(target, apiHost, apiBasePath, frontendHosts string) (smoketest.Environment, error) {
+	if !strings.Contains(target, "://") {
+		target = "https://" + target
+	}
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.Host == "" {
+		return smoketest.Environment{}, fmt.Errorf("%q is not an environment URL or domain", target)
+	}
+
+	environment := smoketest.NewEnvironment(parsed.Host)
+	if apiHost != "" {
+		environment.APIHost = apiHost
+	}
+	environment.APIBasePath = strings.TrimSuffix(apiBasePath, "/")
+	if frontendHosts != "" {
+		environment.FrontendHosts = strings.Split(frontendHosts, ",")
+	}
+	return environment, nil
+}
+
+// writeReport writes the report as indented JSON to a file, or to standard output without one
+func writeReport(report *smoketest.Report, path string) error {
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		_, err = fmt.Println(string(body))
+		return err
+	}
+	return os.WriteFile(path, body, 0o644)
+}
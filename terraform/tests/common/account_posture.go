@@ -0,0 +1,168 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"terraform-tests/awscalls"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestResourcePrefix starts the name of every resource tests create, since TestConfig prefixes are "coalition-"
+// followed by a NewUniqueID. Security Hub findings on resources named this way were caused by a test run.
+const TestResourcePrefix = "coalition-test-"
+
+// AccountPostureEnabled reports whether the account posture checks should run. They only read GuardDuty and
+// Security Hub, but need an account where both are expected to be on, so they are opt-in.
+func AccountPostureEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("ACCOUNT_POSTURE"))
+	return enabled
+}
+
+// AccountPosturePrefix is the resource name prefix whose findings the posture checks report: ACCOUNT_POSTURE_PREFIX
+// to narrow them to a single run, otherwise every test run's resources
+func AccountPosturePrefix() string {
+	if prefix := os.Getenv("ACCOUNT_POSTURE_PREFIX"); prefix != "" {
+		return prefix
+	}
+	return TestResourcePrefix
+}
+
+// SecurityHubStandard is a Security Hub standards subscription, such as AWS Foundational Security Best Practices
+type SecurityHubStandard struct {
+	StandardsArn    string `json:"StandardsArn"`
+	StandardsStatus string `json:"StandardsStatus"`
+}
+
+// SecurityFinding is the part of a Security Hub finding the posture checks report
+type SecurityFinding struct {
+	ID          string `json:"Id"`
+	Title       string `json:"Title"`
+	ProductName string `json:"ProductName"`
+	Severity    struct {
+		Label string `json:"Label"`
+	} `json:"Severity"`
+	Compliance struct {
+		Status string `json:"Status"`
+	} `json:"Compliance"`
+	Resources []struct {
+		ID string `json:"Id"`
+	} `json:"Resources"`
+}
+
+// runSecurityCommand runs a GuardDuty or Security Hub command, such as "guardduty" "list-detectors", and decodes its
+// JSON output
+func runSecurityCommand(t *testing.T, region string, out interface{}, service string, args ...string) {
+	require.NoError(t, awscalls.RunCLI(context.Background(), t, region, out, service, args...))
+}
+
+// GetGuardDutyDetectorStatuses returns the status of each GuardDuty detector in a region, keyed by detector ID.
+// An account has at most one detector per region.
+func GetGuardDutyDetectorStatuses(t *testing.T, region string) map[string]string {
+	var detectors struct {
+		DetectorIDs []string `json:"DetectorIds"`
+	}
+	runSecurityCommand(t, region, &detectors, "guardduty", "list-detectors")
+
+	statuses := map[string]string{}
+	for _, id := range detectors.DetectorIDs {
+		var detector struct {
+			Status string `json:"Status"`
+		}
+		runSecurityCommand(t, region, &detector, "guardduty", "get-detector", "--detector-id", id)
+		statuses[id] = detector.Status
+	}
+	return statuses
+}
+
+// AssertGuardDutyEnabled asserts GuardDuty has an enabled detector in the region
+func AssertGuardDutyEnabled(t *testing.T, region string) {
+	statuses := GetGuardDutyDetectorStatuses(t, region)
+	require.NotEmpty(t, statuses, "GuardDuty has no detector in %s", region)
+	for id, status := range statuses {
+		require.Equal(t, "ENABLED", status, "GuardDuty detector %s in %s should be enabled", id, region)
+	}
+}
+
+// GetSecurityHubStandards returns the Security Hub standards the account subscribes to in a region
+func GetSecurityHubStandards(t *testing.T, region string) []SecurityHubStandard {
+	var result struct {
+		StandardsSubscriptions []SecurityHubStandard `json:"StandardsSubscriptions"`
+	}
+	runSecurityCommand(t, region, &result, "securityhub", "get-enabled-standards")
+	return result.StandardsSubscriptions
+}
+
+// AssertSecurityHubStandardsReady asserts Security Hub subscribes to at least one standard in the region and that
+// every subscription is ready
+func AssertSecurityHubStandardsReady(t *testing.T, region string) {
+	standards := GetSecurityHubStandards(t, region)
+	require.NotEmpty(t, standards, "Security Hub subscribes to no standards in %s", region)
+	for _, standard := range standards {
+		require.Equal(t, "READY", standard.StandardsStatus, "Security Hub standard %s in %s should be ready",
+			standard.StandardsArn, region)
+	}
+}
+
+// SecurityHubFindingFilters returns the get-findings filters for active, unresolved findings on resources whose
+// ID contains the prefix. Resource IDs are usually ARNs, so the prefix is matched anywhere in them.
+func SecurityHubFindingFilters(prefix string) string {
+	filter := func(value, comparison string) map[string]string {
+		return map[string]string{"Value": value, "Comparison": comparison}
+	}
+	filters, _ := json.Marshal(map[string][]map[string]string{
+		"ResourceId":     {filter(prefix, "CONTAINS")},
+		"RecordState":    {filter("ACTIVE", "EQUALS")},
+		"WorkflowStatus": {filter("NEW", "EQUALS"), filter("NOTIFIED", "EQUALS")},
+	})
+	return string(filters)
+}
+
+// OpenSecurityFindings drops findings that report a passing control, which Security Hub records alongside the
+// failures, leaving the ones that need attention. GuardDuty findings carry no compliance status and are kept.
+func OpenSecurityFindings(findings []SecurityFinding) []SecurityFinding {
+	var open []SecurityFinding
+	for _, finding := range findings {
+		switch finding.Compliance.Status {
+		case "PASSED", "NOT_AVAILABLE":
+			continue
+		}
+		open = append(open, finding)
+	}
+	return open
+}
+
+// GetOpenSecurityFindings returns the open Security Hub findings on resources whose ID contains the prefix. With
+// the GuardDuty integration on, Security Hub also holds GuardDuty's findings, so one query covers both.
+func GetOpenSecurityFindings(t *testing.T, region, prefix string) []SecurityFinding {
+	var result struct {
+		Findings []SecurityFinding `json:"Findings"`
+	}
+	runSecurityCommand(t, region, &result, "securityhub", "get-findings", "--filters",
+		SecurityHubFindingFilters(prefix))
+	return OpenSecurityFindings(result.Findings)
+}
+
+// AssertNoOpenSecurityFindings asserts no open Security Hub finding concerns a resource whose ID contains the
+// prefix, listing each one otherwise
+func AssertNoOpenSecurityFindings(t *testing.T, region, prefix string) {
+	findings := GetOpenSecurityFindings(t, region, prefix)
+	for _, finding := range findings {
+		var resources []string
+		for _, resource := range finding.Resources {
+			resources = append(resources, resource.ID)
+		}
+		t.Errorf("%s %s finding: %s (%s)", finding.Severity.Label, finding.ProductName, finding.Title,
+			strings.Join(resources, ", "))
+	}
+	if len(findings) > 0 {
+		t.Logf("%d open findings on resources matching %q; see "+
+			"https://%s.console.aws.amazon.com/securityhub/home?region=%s#/findings", len(findings), prefix, region,
+			region)
+	}
+}
@@ -0,0 +1,271 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	terratest_testing "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// ProgressEventKind says why a progress hook was called
+type ProgressEventKind string
+
+const (
+	ProgressTick           ProgressEventKind = "tick"            // Periodic update while the apply runs
+	ProgressResourceFailed ProgressEventKind = "resource_failed" // A resource failed to apply
+	ProgressFinished       ProgressEventKind = "finished"        // The apply exited, successfully or not
+)
+
+// ApplyProgress is a snapshot of a running apply, built from terraform's machine-readable (-json) output
+type ApplyProgress struct {
+	Operation   string
+	Planned     int      // Resource changes in the plan being applied
+	Completed   int      // Resource changes applied so far
+	InFlight    []string // Resources currently being applied, longest running first
+	Failed      []string // Resources that failed to apply
+	Elapsed     time.Duration
+	OnlyCreates bool // Every planned change creates a resource
+}
+
+// String renders the snapshot as, for example, "42/97 resources created, currently aws_db_instance.postgres
+// (8m elapsed)"
+func (p ApplyProgress) String() string {
+	verb := "resource changes applied"
+	if p.OnlyCreates {
+		verb = "resources created"
+	}
+
+	summary := fmt.Sprintf("%d/%d %s", p.Completed, p.Planned, verb)
+	if len(p.InFlight) > 0 {
+		summary += ", currently " + p.InFlight[0]
+		if len(p.InFlight) > 1 {
+			summary += fmt.Sprintf(" and %d more", len(p.InFlight)-1)
+		}
+	}
+	if len(p.Failed) > 0 {
+		summary += fmt.Sprintf(", %d failed", len(p.Failed))
+	}
+	return fmt.Sprintf("%s (%s elapsed)", summary, formatElapsed(p.Elapsed))
+}
+
+// formatElapsed rounds to whole minutes once past the first minute, which is all a long apply needs
+func formatElapsed(elapsed time.Duration) string {
+	if elapsed < time.Minute {
+		return fmt.Sprintf("%ds", int(elapsed.Seconds()))
+	}
+	return fmt.Sprintf("%dm", int(elapsed.Minutes()))
+}
+
+// ProgressHook receives progress snapshots, for example to surface them in CI
+type ProgressHook func(kind ProgressEventKind, progress ApplyProgress)
+
+// terraformEvent is the subset of a terraform -json log line that progress tracking needs
+type terraformEvent struct {
+	Level   string `json:"@level"`
+	Message string `json:"@message"`
+	Type    string `json:"type"`
+	Hook    struct {
+		Resource struct {
+			Addr string `json:"addr"`
+		} `json:"resource"`
+		Action string `json:"action"`
+	} `json:"hook"`
+	Change struct {
+		Resource struct {
+			Addr string `json:"addr"`
+		} `json:"resource"`
+		Action string `json:"action"`
+	} `json:"change"`
+	Changes struct {
+		Add       int    `json:"add"`
+		Change    int    `json:"change"`
+		Remove    int    `json:"remove"`
+		Operation string `json:"operation"`
+	} `json:"changes"`
+}
+
+// applyTracker folds terraform's -json events into an ApplyProgress
+type applyTracker struct {
+	mu          sync.Mutex
+	operation   string
+	start       time.Time
+	planned     int
+	completed   int
+	onlyCreates bool
+	inFlight    map[string]time.Time
+	failed      []string
+	hooks       []ProgressHook
+}
+
+func newApplyTracker(operation string, hooks []ProgressHook) *applyTracker {
+	return &applyTracker{
+		operation:   operation,
+		start:       time.Now(),
+		onlyCreates: true,
+		inFlight:    make(map[string]time.Time),
+		hooks:       hooks,
+	}
+}
+
+// handleLine updates the tracker from one line of terraform output. It returns the text worth logging for the
+// line, or "" for events that are only counted.
+func (a *applyTracker) handleLine(line string) string {
+	var event terraformEvent
+	if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &event) != nil || event.Type == "" {
+		return line // Not a machine-readable event, such as terratest's own "Running command" line
+	}
+
+	// Data sources read during the apply are not part of the planned changes
+	isRead := event.Hook.Action == "read" || event.Change.Action == "read"
+
+	a.mu.Lock()
+	var failure *ApplyProgress
+	switch {
+	case event.Type == "planned_change" && !isRead:
+		a.planned++
+		if event.Change.Action != "create" {
+			a.onlyCreates = false
+		}
+	case event.Type == "change_summary" && event.Changes.Operation == "plan":
+		a.planned = event.Changes.Add + event.Changes.Change + event.Changes.Remove
+	case event.Type == "apply_start" && !isRead:
+		a.inFlight[event.Hook.Resource.Addr] = time.Now()
+	case event.Type == "apply_complete" && !isRead:
+		delete(a.inFlight, event.Hook.Resource.Addr)
+		a.completed++
+	case event.Type == "apply_errored":
+		delete(a.inFlight, event.Hook.Resource.Addr)
+		a.failed = append(a.failed, event.Hook.Resource.Addr)
+		progress := a.snapshotLocked()
+		failure = &progress
+	}
+	a.mu.Unlock()
+
+	if failure != nil {
+		a.notifyWith(ProgressResourceFailed, *failure)
+	}
+
+	switch event.Type {
+	case "planned_change", "apply_start", "apply_progress", "apply_complete", "refresh_start", "refresh_complete":
+		return ""
+	}
+	return event.Message
+}
+
+// snapshot returns the current progress
+func (a *applyTracker) snapshot() ApplyProgress {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.snapshotLocked()
+}
+
+// snapshotLocked returns the current progress; the caller must hold a.mu
+func (a *applyTracker) snapshotLocked() ApplyProgress {
+	inFlight := make([]string, 0, len(a.inFlight))
+	for address := range a.inFlight {
+		inFlight = append(inFlight, address)
+	}
+	sort.Slice(inFlight, func(i, j int) bool {
+		return a.inFlight[inFlight[i]].Before(a.inFlight[inFlight[j]])
+	})
+
+	return ApplyProgress{
+		Operation:   a.operation,
+		Planned:     a.planned,
+		Completed:   a.completed,
+		InFlight:    inFlight,
+		Failed:      append([]string(nil), a.failed...),
+		Elapsed:     time.Since(a.start),
+		OnlyCreates: a.onlyCreates,
+	}
+}
+
+// inFlightDurations returns how long each in-flight resource has been applying
+func (a *applyTracker) inFlightDurations() map[string]time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	durations := make(map[string]time.Duration, len(a.inFlight))
+	for address, started := range a.inFlight {
+		durations[address] = time.Since(started)
+	}
+	return durations
+}
+
+// notify passes the current progress to every hook
+func (a *applyTracker) notify(kind ProgressEventKind) {
+	a.notifyWith(kind, a.snapshot())
+}
+
+// notifyWith passes a progress snapshot to every hook
+func (a *applyTracker) notifyWith(kind ProgressEventKind, progress ApplyProgress) {
+	for _, hook := range a.hooks {
+		hook(kind, progress)
+	}
+}
+
+// progressLogger is a terratest logger that feeds terraform's output to an applyTracker instead of echoing it
+type progressLogger struct {
+	tracker *applyTracker
+}
+
+func (l progressLogger) Logf(t terratest_testing.TestingT, format string, args ...interface{}) {
+	if message := l.tracker.handleLine(fmt.Sprintf(format, args...)); message != "" {
+		logger.Default.Logf(t, "%s", message)
+	}
+}
+
+// RunTerraformWithProgress runs terraform init and apply, logging how many resources have been applied and which are
+// in flight at every interval instead of terraform's full output. Hooks are called on each interval, when a
+// resource fails and when the apply exits. The apply is interrupted if a resource exceeds its limit in
+// DefaultResourceTimeouts; use RunTerraformWithWatchdog for other limits.
+func RunTerraformWithProgress(
+	t *testing.T,
+	terraformOptions *terraform.Options,
+	operationName string,
+	tickerInterval time.Duration,
+	hooks ...ProgressHook,
+) {
+	RunTerraformWithWatchdog(t, terraformOptions, operationName, tickerInterval, DefaultApplyWatchdog(), hooks...)
+}
+
+// GitHubAnnotationHook reports progress as GitHub Actions workflow commands written to w: a notice with the final
+// count and an error for each resource that failed. It does nothing outside GitHub Actions.
+func GitHubAnnotationHook(w io.Writer) ProgressHook {
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return func(ProgressEventKind, ApplyProgress) {}
+	}
+
+	return func(kind ProgressEventKind, progress ApplyProgress) {
+		switch kind {
+		case ProgressResourceFailed:
+			failed := progress.Failed[len(progress.Failed)-1]
+			_, _ = fmt.Fprintf(w, "::error title=%s::%s\n",
+				escapeWorkflowProperty(progress.Operation), escapeWorkflowData(failed+" failed to apply"))
+		case ProgressFinished:
+			_, _ = fmt.Fprintf(w, "::notice title=%s::%s\n",
+				escapeWorkflowProperty(progress.Operation), escapeWorkflowData(progress.String()))
+		case ProgressTick:
+			// Annotations are capped per step, so intermediate progress stays in the test log
+		}
+	}
+}
+
+// escapeWorkflowData escapes a workflow command's message the way the GitHub Actions toolkit does
+func escapeWorkflowData(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(value)
+}
+
+// escapeWorkflowProperty escapes a workflow command property, such as title, which also may not contain : or ,
+func escapeWorkflowProperty(value string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(escapeWorkflowData(value))
+}
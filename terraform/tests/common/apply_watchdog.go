@@ -0,0 +1,217 @@
+package common
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// DefaultResourceTimeouts are how long one resource of each type may take to apply. They sit well above the
+// usual creation times (CloudFront ~15m, RDS ~10m), so a breach means the apply is stuck rather than slow.
+var DefaultResourceTimeouts = map[string]time.Duration{
+	"aws_cloudfront_distribution": 30 * time.Minute,
+	"aws_db_instance":             25 * time.Minute,
+}
+
+// ApplyWatchdog interrupts an apply as soon as a single resource has been in flight for longer than its timeout,
+// so the test fails with its cleanup still able to run instead of hanging until the go test timeout kills it
+type ApplyWatchdog struct {
+	Timeouts       map[string]time.Duration // By resource type, such as aws_db_instance
+	DefaultTimeout time.Duration            // For types not in Timeouts; zero means no limit
+	CheckInterval  time.Duration            // How often in-flight resources are checked; defaults to 30s
+	GracePeriod    time.Duration            // How long terraform gets to stop after an interrupt; defaults to 5m
+}
+
+// DefaultApplyWatchdog limits the resource types in DefaultResourceTimeouts and leaves everything else unlimited
+func DefaultApplyWatchdog() ApplyWatchdog {
+	return ApplyWatchdog{Timeouts: DefaultResourceTimeouts}
+}
+
+// timeoutFor returns the limit for a resource address, or zero if it has none
+func (w ApplyWatchdog) timeoutFor(address string) time.Duration {
+	if timeout, ok := w.Timeouts[resourceTypeFromAddress(address)]; ok {
+		return timeout
+	}
+	return w.DefaultTimeout
+}
+
+// instanceKeyPattern matches count and for_each keys, which may themselves contain dots
+var instanceKeyPattern = regexp.MustCompile(`\[[^\]]*\]`)
+
+// resourceTypeFromAddress returns the type of a resource address, such as aws_db_instance for
+// module.database.aws_db_instance.postgres
+func resourceTypeFromAddress(address string) string {
+	parts := strings.Split(instanceKeyPattern.ReplaceAllString(address, ""), ".")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
+
+// ResourceTimeoutError reports a resource that exceeded its watchdog timeout
+type ResourceTimeoutError struct {
+	Address      string
+	Elapsed      time.Duration
+	Timeout      time.Duration
+	TerraformDir string
+}
+
+func (e *ResourceTimeoutError) Error() string {
+	return fmt.Sprintf("%s was still applying after %s (limit %s), so terraform was interrupted. "+
+		"If the test cleanup cannot destroy it, check its status in the AWS console and remove it with: "+
+		"terraform -chdir=%s destroy -target='%s'",
+		e.Address, e.Elapsed.Round(time.Second), e.Timeout, e.TerraformDir, e.Address)
+}
+
+// RunTerraformWithWatchdog is RunTerraformWithProgress with a custom watchdog
+func RunTerraformWithWatchdog(
+	t *testing.T,
+	terraformOptions *terraform.Options,
+	operationName string,
+	tickerInterval time.Duration,
+	watchdog ApplyWatchdog,
+	hooks ...ProgressHook,
+) {
+	t.Logf("Starting %s at %s", operationName, time.Now().Format("15:04:05"))
+
+	// Use default interval if zero value provided
+	if tickerInterval == 0 {
+		tickerInterval = 2 * time.Minute
+	}
+
+	terraform.Init(t, terraformOptions)
+
+	tracker := newApplyTracker(operationName, hooks)
+
+	// Log the progress periodically
+	done := make(chan bool)
+	go func() {
+		defer RecoverAndCleanup()
+		ticker := time.NewTicker(tickerInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				t.Logf("%s: %s", operationName, tracker.snapshot())
+				tracker.notify(ProgressTick)
+			}
+		}
+	}()
+
+	err := runWatchedApply(t, terraformOptions, tracker, watchdog)
+	close(done)
+
+	tracker.notify(ProgressFinished)
+	t.Logf("%s finished at %s: %s", operationName, time.Now().Format("15:04:05"), tracker.snapshot())
+	require.NoError(t, err, "%s failed", operationName)
+}
+
+// runWatchedApply runs terraform apply -json, feeding its output to the tracker, and interrupts it when the
+// watchdog finds a resource over its timeout. Terratest has no way to stop a running command, so the apply is run
+// directly with the same arguments and environment terratest would use.
+func runWatchedApply(
+	t *testing.T,
+	terraformOptions *terraform.Options,
+	tracker *applyTracker,
+	watchdog ApplyWatchdog,
+) error {
+	if watchdog.CheckInterval == 0 {
+		watchdog.CheckInterval = 30 * time.Second
+	}
+	if watchdog.GracePeriod == 0 {
+		watchdog.GracePeriod = 5 * time.Minute
+	}
+
+	applyOptions, err := terraformOptions.Clone()
+	require.NoError(t, err)
+	applyArgs := append([]string{"apply", "-input=false", "-auto-approve", "-json"}, applyOptions.ExtraArgs.Apply...)
+	applyOptions, args := terraform.GetCommonOptions(applyOptions, terraform.FormatArgs(applyOptions, applyArgs...)...)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	// #nosec G204 -- the binary and arguments come from the test's own terraform options
+	cmd := exec.CommandContext(ctx, applyOptions.TerraformBinary, args...)
+	cmd.Dir = applyOptions.TerraformDir
+	cmd.Env = os.Environ()
+	for name, value := range applyOptions.EnvVars {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	// Interrupt rather than kill, so terraform stops its providers and writes state before exiting
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = watchdog.GracePeriod
+
+	output, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	logged := make(chan struct{})
+	go func() {
+		defer RecoverAndCleanup()
+		defer close(logged)
+		scanner := bufio.NewScanner(output)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		progress := progressLogger{tracker: tracker}
+		for scanner.Scan() {
+			progress.Logf(t, "%s", scanner.Text())
+		}
+		_, _ = io.Copy(io.Discard, output)
+	}()
+
+	t.Logf("Running command %s with args %v", applyOptions.TerraformBinary, args)
+	if startErr := cmd.Start(); startErr != nil {
+		_ = writer.Close()
+		<-logged
+		return startErr
+	}
+
+	watched := make(chan struct{})
+	go func() {
+		defer RecoverAndCleanup()
+		ticker := time.NewTicker(watchdog.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-watched:
+				return
+			case <-ticker.C:
+				for address, elapsed := range tracker.inFlightDurations() {
+					timeout := watchdog.timeoutFor(address)
+					if timeout > 0 && elapsed > timeout {
+						cancel(&ResourceTimeoutError{
+							Address:      address,
+							Elapsed:      elapsed,
+							Timeout:      timeout,
+							TerraformDir: applyOptions.TerraformDir,
+						})
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	err = cmd.Wait()
+	close(watched)
+	_ = writer.Close()
+	<-logged
+
+	var timeoutErr *ResourceTimeoutError
+	if errors.As(context.Cause(ctx), &timeoutErr) {
+		return timeoutErr
+	}
+	return err
+}
@@ -0,0 +1,165 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"terraform-tests/report"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// AuditFinding is the outcome of a single compliance control evaluated against a single resource
+type AuditFinding = report.Finding
+
+// AuditContext is the set of resources an audit runs against, plus which of their values are unknown
+type AuditContext struct {
+	Resources []*tfjson.StateResource
+	unknown   map[string]map[string]interface{}
+}
+
+// AuditCheck evaluates one resource and returns zero or more findings
+type AuditCheck func(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding
+
+// NewPlanAuditContext builds an audit context from planned values, in a stable address order
+func NewPlanAuditContext(plan *terraform.PlanStruct) *AuditContext {
+	audit := &AuditContext{unknown: map[string]map[string]interface{}{}}
+	for address, resource := range plan.ResourcePlannedValuesMap {
+		audit.Resources = append(audit.Resources, resource)
+		if change, exists := plan.ResourceChangesMap[address]; exists && change.Change != nil {
+			if afterUnknown, ok := change.Change.AfterUnknown.(map[string]interface{}); ok {
+				audit.unknown[address] = afterUnknown
+			}
+		}
+	}
+	sort.Slice(audit.Resources, func(i, j int) bool { return audit.Resources[i].Address < audit.Resources[j].Address })
+	return audit
+}
+
+// NewStateAuditContext builds an audit context from the applied state of the given terraform directory
+func NewStateAuditContext(t *testing.T, terraformOptions *terraform.Options) *AuditContext {
+	output := terraform.RunTerraformCommandAndGetStdout(t, terraformOptions, "show", "-json")
+
+	var state tfjson.State
+	require.NoError(t, json.Unmarshal([]byte(output), &state))
+
+	audit := &AuditContext{unknown: map[string]map[string]interface{}{}}
+	if state.Values != nil {
+		audit.Resources = flattenStateModule(state.Values.RootModule)
+	}
+	sort.Slice(audit.Resources, func(i, j int) bool { return audit.Resources[i].Address < audit.Resources[j].Address })
+	return audit
+}
+
+// flattenStateModule returns the resources of a state module and all of its child modules
+func flattenStateModule(module *tfjson.StateModule) []*tfjson.StateResource {
+	if module == nil {
+		return nil
+	}
+
+	resources := append([]*tfjson.StateResource{}, module.Resources...)
+	for _, child := range module.ChildModules {
+		resources = append(resources, flattenStateModule(child)...)
+	}
+	return resources
+}
+
+// IsUnknown reports whether a top-level attribute of a resource is only known after apply
+func (a *AuditContext) IsUnknown(resource *tfjson.StateResource, attribute string) bool {
+	unknown, _ := a.unknown[resource.Address][attribute].(bool)
+	return unknown
+}
+
+// RunAudit applies every check to every resource and collects the findings
+func RunAudit(audit *AuditContext, checks ...AuditCheck) []AuditFinding {
+	var findings []AuditFinding
+	for _, resource := range audit.Resources {
+		arn := GetPlannedStringAttribute(resource, "arn")
+		for _, check := range checks {
+			for _, finding := range check(resource, audit) {
+				finding.ARN = arn
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings
+}
+
+// ReportAuditFindings reports each finding as its own subtest so failures read like a compliance report,
+// and records the findings as audit evidence when evidence collection is enabled
+func ReportAuditFindings(t *testing.T, findings []AuditFinding) {
+	if len(findings) > 0 {
+		RecordAuditEvidence(t, findings)
+	}
+	report.Report(t, findings)
+}
+
+// nestedBlocks returns the nested block values stored under key in resource attributes
+func nestedBlocks(attributes map[string]interface{}, key string) []map[string]interface{} {
+	raw, ok := attributes[key].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	blocks := make([]map[string]interface{}, 0, len(raw))
+	for _, item := range raw {
+		if block, ok := item.(map[string]interface{}); ok {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// passFail builds a finding whose outcome depends on ok
+func passFail(control string, resource *tfjson.StateResource, ok bool, detail string) AuditFinding {
+	return AuditFinding{Control: control, Resource: resource.Address, Passed: ok, Detail: detail}
+}
+
+// unknown builds a skipped finding for values not known until apply
+func unknown(control string, resource *tfjson.StateResource, attribute string) AuditFinding {
+	return AuditFinding{
+		Control:  control,
+		Resource: resource.Address,
+		Skipped:  true,
+		Detail:   fmt.Sprintf("%s is not known until apply", attribute),
+	}
+}
+
+// missingPolicyActions returns the required actions that no Allow statement in the policies grants, directly or
+// through a service or global wildcard
+func missingPolicyActions(policies, required []string) []string {
+	allowed := map[string]bool{}
+	for _, policy := range policies {
+		var document struct {
+			Statement []struct {
+				Effect string          `json:"Effect"`
+				Action json.RawMessage `json:"Action"`
+			} `json:"Statement"`
+		}
+		if err := json.Unmarshal([]byte(policy), &document); err != nil {
+			continue
+		}
+		for _, statement := range document.Statement {
+			if statement.Effect != "Allow" {
+				continue
+			}
+			for _, action := range stringOrList(statement.Action) {
+				allowed[strings.ToLower(action)] = true
+			}
+		}
+	}
+
+	var missing []string
+	for _, action := range required {
+		service, _, _ := strings.Cut(strings.ToLower(action), ":")
+		if !allowed[strings.ToLower(action)] && !allowed[service+":*"] && !allowed["*"] {
+			missing = append(missing, action)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,186 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// What backup plans must provide for the databases they protect
+const (
+	BackupMaxIntervalHours   = 24 // At least daily
+	BackupMinRetentionDays   = 7  // As long as the database module's automated backups
+	BackupMinColdStorageDays = 90 // AWS Backup deletes no earlier than 90 days after moving to cold storage
+)
+
+// BackupSelectionTag is the tag backup selections choose resources by, so a new database is backed up as soon as
+// it carries the tag rather than once someone adds it to a list
+var BackupSelectionTag = struct{ Key, Value string }{Key: "Backup", Value: "true"}
+
+// BackupAuditChecks returns the checks for AWS Backup plans, selections and vaults, and for the databases they
+// must cover
+func BackupAuditChecks() []AuditCheck {
+	return []AuditCheck{
+		CheckBackupPlanRules,
+		CheckBackupSelectionByTag,
+		CheckRDSBackupCoverage,
+		func(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+			if resource.Type != "aws_backup_vault" {
+				return nil
+			}
+			return CheckEncryptionAtRest(resource, audit)
+		},
+	}
+}
+
+// CheckBackupPlanRules verifies a backup plan has a rule running at least daily, and that every rule keeps
+// recovery points for at least BackupMinRetentionDays and in cold storage for AWS Backup's minimum. Less frequent
+// rules, such as a weekly archive, are fine alongside a daily one.
+func CheckBackupPlanRules(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_backup_plan" {
+		return nil
+	}
+
+	rules := nestedBlocks(resource.AttributeValues, "rule")
+	if len(rules) == 0 {
+		return []AuditFinding{passFail("Backup-Plan-Rules", resource, false, "backup plan has no rules")}
+	}
+
+	var findings []AuditFinding
+	var scheduleProblems []string
+	shortest := 0
+	for _, rule := range rules {
+		name, _ := rule["rule_name"].(string)
+		schedule, _ := rule["schedule"].(string)
+		interval, err := BackupScheduleIntervalHours(schedule)
+		if err != nil {
+			scheduleProblems = append(scheduleProblems, fmt.Sprintf("rule %s: %v", name, err))
+		} else if shortest == 0 || interval < shortest {
+			shortest = interval
+		}
+
+		ruleResource := &tfjson.StateResource{Address: fmt.Sprintf("%s/%s", resource.Address, name)}
+		problem := BackupLifecycleProblem(rule)
+		findings = append(findings, passFail("Backup-Rule-Retention", ruleResource, problem == "", problem))
+	}
+
+	detail := fmt.Sprintf("most frequent rule runs every %d hours, expected at most %d", shortest,
+		BackupMaxIntervalHours)
+	if len(scheduleProblems) > 0 {
+		detail = strings.Join(scheduleProblems, "; ")
+	}
+	return append(findings, passFail("Backup-Plan-Frequency", resource,
+		len(scheduleProblems) == 0 && shortest <= BackupMaxIntervalHours, detail))
+}
+
+// BackupLifecycleProblem describes what is wrong with a backup rule's lifecycle, or returns "" if nothing is
+func BackupLifecycleProblem(rule map[string]interface{}) string {
+	lifecycles := nestedBlocks(rule, "lifecycle")
+	if len(lifecycles) == 0 {
+		return "rule keeps recovery points forever; set a lifecycle"
+	}
+
+	deleteAfter, _ := lifecycles[0]["delete_after"].(float64)
+	coldAfter, _ := lifecycles[0]["cold_storage_after"].(float64)
+	switch {
+	case deleteAfter > 0 && deleteAfter < BackupMinRetentionDays:
+		return fmt.Sprintf("deletes after %.0f days, expected at least %d", deleteAfter, BackupMinRetentionDays)
+	case coldAfter > 0 && deleteAfter > 0 && deleteAfter < coldAfter+BackupMinColdStorageDays:
+		return fmt.Sprintf("deletes after %.0f days, less than %d days after moving to cold storage on day %.0f",
+			deleteAfter, BackupMinColdStorageDays, coldAfter)
+	}
+	return ""
+}
+
+// BackupScheduleIntervalHours returns the longest gap, in hours, between runs of an AWS Backup cron schedule such
+// as "cron(0 5 * * ? *)"
+func BackupScheduleIntervalHours(schedule string) (int, error) {
+	expression := strings.TrimSuffix(strings.TrimPrefix(schedule, "cron("), ")")
+	fields := strings.Fields(expression)
+	if expression == schedule || len(fields) != 6 {
+		return 0, fmt.Errorf("schedule %q is not a six-field cron expression", schedule)
+	}
+	hours, dayOfMonth, month, dayOfWeek := fields[1], fields[2], fields[3], fields[4]
+
+	everyDay := func(field string) bool { return field == "*" || field == "?" }
+	switch {
+	case month != "*":
+		return 24 * 366, nil
+	case !everyDay(dayOfMonth):
+		return 24 * 31, nil
+	case !everyDay(dayOfWeek):
+		return 24 * 7, nil
+	case hours == "*":
+		return 1, nil
+	case strings.HasPrefix(hours, "*/") || strings.HasPrefix(hours, "0/"):
+		step, err := strconv.Atoi(hours[2:])
+		if err != nil || step < 1 {
+			return 0, fmt.Errorf("schedule %q has an invalid hour step", schedule)
+		}
+		return step, nil
+	default:
+		// A single hour or a list of hours: the widest gap is at least a day divided by the number of runs
+		return 24 / len(strings.Split(hours, ",")), nil
+	}
+}
+
+// CheckBackupSelectionByTag verifies backup selections choose resources by BackupSelectionTag instead of listing
+// them
+func CheckBackupSelectionByTag(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_backup_selection" {
+		return nil
+	}
+	return []AuditFinding{passFail("Backup-Selection-Tag", resource, selectsByBackupTag(resource),
+		fmt.Sprintf("selection does not choose resources by the %s=%s tag", BackupSelectionTag.Key,
+			BackupSelectionTag.Value))}
+}
+
+// selectsByBackupTag reports whether a backup selection chooses resources by BackupSelectionTag, with the
+// selection_tag block or a string_equals condition
+func selectsByBackupTag(selection *tfjson.StateResource) bool {
+	for _, tag := range nestedBlocks(selection.AttributeValues, "selection_tag") {
+		if tag["type"] == "STRINGEQUALS" && tag["key"] == BackupSelectionTag.Key &&
+			tag["value"] == BackupSelectionTag.Value {
+			return true
+		}
+	}
+	for _, condition := range nestedBlocks(selection.AttributeValues, "condition") {
+		for _, equals := range nestedBlocks(condition, "string_equals") {
+			if equals["key"] == "aws:ResourceTag/"+BackupSelectionTag.Key &&
+				equals["value"] == BackupSelectionTag.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckRDSBackupCoverage verifies every database carries BackupSelectionTag once a backup selection chooses by it.
+// Until a backup plan exists the check is skipped, since the database module's automated backups are then the
+// only ones.
+func CheckRDSBackupCoverage(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if resource.Type != "aws_db_instance" {
+		return nil
+	}
+
+	selected := false
+	for _, candidate := range audit.Resources {
+		selected = selected || (candidate.Type == "aws_backup_selection" && selectsByBackupTag(candidate))
+	}
+	if !selected {
+		return []AuditFinding{{Control: "Backup-RDS-Coverage", Resource: resource.Address, Skipped: true,
+			Detail: "no backup selection chooses resources by tag"}}
+	}
+
+	// Provider default tags only show in tags_all
+	tagged := false
+	for _, attribute := range []string{"tags", "tags_all"} {
+		tags, _ := resource.AttributeValues[attribute].(map[string]interface{})
+		tagged = tagged || tags[BackupSelectionTag.Key] == BackupSelectionTag.Value
+	}
+	return []AuditFinding{passFail("Backup-RDS-Coverage", resource, tagged,
+		fmt.Sprintf("database is not tagged %s=%s, so no backup plan selects it", BackupSelectionTag.Key,
+			BackupSelectionTag.Value))}
+}
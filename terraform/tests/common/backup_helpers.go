@@ -0,0 +1,164 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/tfopts"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// BackupRestoreTimeout bounds how long a restore job may take; restoring a small database takes 15 to 30 minutes
+const BackupRestoreTimeout = 60 * time.Minute
+
+// RecoveryPoint is the part of an AWS Backup recovery point the restore helpers use
+type RecoveryPoint struct {
+	RecoveryPointArn string  `json:"RecoveryPointArn"`
+	Status           string  `json:"Status"`
+	CreationDate     float64 `json:"CreationDate"`
+	BackupVaultName  string  `json:"BackupVaultName"`
+}
+
+// runBackupCommand runs an aws backup command and decodes its JSON output, if out is set
+func runBackupCommand(t *testing.T, region string, out interface{}, args ...string) error {
+	return awscalls.RunCLI(context.Background(), t, region, out, "backup", args...)
+}
+
+// GetLatestRecoveryPoint returns the most recent completed recovery point of a resource in a backup vault
+func GetLatestRecoveryPoint(t *testing.T, resourceARN, vault, region string) *RecoveryPoint {
+	var result struct {
+		RecoveryPoints []RecoveryPoint `json:"RecoveryPoints"`
+	}
+	require.NoError(t, runBackupCommand(t, region, &result, "list-recovery-points-by-resource",
+		"--resource-arn", resourceARN))
+
+	var latest *RecoveryPoint
+	for i, point := range result.RecoveryPoints {
+		if point.Status != "COMPLETED" || (vault != "" && point.BackupVaultName != vault) {
+			continue
+		}
+		if latest == nil || point.CreationDate > latest.CreationDate {
+			latest = &result.RecoveryPoints[i]
+		}
+	}
+	require.NotNil(t, latest, "No completed recovery point of %s in vault %s", resourceARN, vault)
+	return latest
+}
+
+// RestoreLatestRecoveryPoint starts a restore job from the most recent recovery point of a resource, waits for it
+// to complete and returns the ARN of the resource it created. Overrides replace keys of the recovery point's
+// restore metadata, such as the identifier of the restored resource.
+func RestoreLatestRecoveryPoint(t *testing.T, resourceARN, vault, roleARN, region string,
+	overrides map[string]string) string {
+	point := GetLatestRecoveryPoint(t, resourceARN, vault, region)
+
+	var metadata struct {
+		RestoreMetadata map[string]string `json:"RestoreMetadata"`
+	}
+	require.NoError(t, runBackupCommand(t, region, &metadata, "get-recovery-point-restore-metadata",
+		"--backup-vault-name", point.BackupVaultName, "--recovery-point-arn", point.RecoveryPointArn))
+	for key, value := range overrides {
+		metadata.RestoreMetadata[key] = value
+	}
+	encoded, err := json.Marshal(metadata.RestoreMetadata)
+	require.NoError(t, err)
+
+	var job struct {
+		RestoreJobID string `json:"RestoreJobId"`
+	}
+	require.NoError(t, runBackupCommand(t, region, &job, "start-restore-job",
+		"--recovery-point-arn", point.RecoveryPointArn, "--iam-role-arn", roleARN, "--metadata", string(encoded)))
+	t.Logf("Started restore job %s from %s", job.RestoreJobID, point.RecoveryPointArn)
+
+	var createdARN string
+	retry.DoWithRetry(t, fmt.Sprintf("Wait for restore job %s", job.RestoreJobID),
+		int(BackupRestoreTimeout/(30*time.Second)), 30*time.Second, func() (string, error) {
+			var status struct {
+				Status             string `json:"Status"`
+				StatusMessage      string `json:"StatusMessage"`
+				CreatedResourceArn string `json:"CreatedResourceArn"`
+			}
+			if err := runBackupCommand(t, region, &status, "describe-restore-job",
+				"--restore-job-id", job.RestoreJobID); err != nil {
+				return "", err
+			}
+			switch status.Status {
+			case "COMPLETED":
+				createdARN = status.CreatedResourceArn
+				return status.Status, nil
+			case "ABORTED", "FAILED":
+				return "", retry.FatalError{Underlying: fmt.Errorf("restore job %s %s: %s", job.RestoreJobID,
+					strings.ToLower(status.Status), status.StatusMessage)}
+			}
+			return "", fmt.Errorf("restore job %s is %s", job.RestoreJobID, status.Status)
+		})
+	return createdARN
+}
+
+// RestoreLatestRDSRecoveryPoint restores a database from its most recent recovery point into a new instance,
+// which is deleted when the test ends, and returns the restored instance
+func RestoreLatestRDSRecoveryPoint(t *testing.T, instanceARN, vault, roleARN, region string) *rdstypes.DBInstance {
+	// The instance identifier is the last part of the ARN: arn:aws:rds:<region>:<account>:db:<id>
+	parts := strings.Split(instanceARN, ":")
+	require.Len(t, parts, 7, "Unexpected database ARN %q", instanceARN)
+	restoredID := fmt.Sprintf("%s-restore-%s", parts[6], strings.ToLower(tfopts.NewUniqueID()))
+
+	t.Cleanup(func() {
+		cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+		if err != nil {
+			t.Logf("Could not delete restored database %s: %v", restoredID, err)
+			return
+		}
+		_, err = rds.NewFromConfig(cfg).DeleteDBInstance(context.Background(), &rds.DeleteDBInstanceInput{
+			DBInstanceIdentifier:   aws.String(restoredID),
+			SkipFinalSnapshot:      aws.Bool(true),
+			DeleteAutomatedBackups: aws.Bool(true),
+		})
+		if err != nil {
+			t.Logf("Could not delete restored database %s: %v", restoredID, err)
+		}
+	})
+
+	RestoreLatestRecoveryPoint(t, instanceARN, vault, roleARN, region,
+		map[string]string{"DBInstanceIdentifier": restoredID})
+	return GetRDSInstanceById(t, restoredID, region)
+}
+
+// DeployedBackup describes the AWS Backup setup of an already-deployed stack, from E2E_BACKUP_* variables
+type DeployedBackup struct {
+	Region         string
+	Vault          string
+	RestoreRoleARN string
+	DBInstanceARN  string
+}
+
+// GetDeployedBackup loads the deployed backup vault and database, skipping the test when none is configured
+func GetDeployedBackup(t *testing.T) *DeployedBackup {
+	RequireTier(t, TierE2E)
+
+	deployed := &DeployedBackup{
+		Region:         os.Getenv("AWS_REGION"),
+		Vault:          os.Getenv("E2E_BACKUP_VAULT"),
+		RestoreRoleARN: os.Getenv("E2E_BACKUP_RESTORE_ROLE_ARN"),
+		DBInstanceARN:  os.Getenv("E2E_BACKUP_DB_INSTANCE_ARN"),
+	}
+	if deployed.Vault == "" || deployed.RestoreRoleARN == "" || deployed.DBInstanceARN == "" {
+		t.Skip("Skipping end-to-end test - set E2E_BACKUP_VAULT, E2E_BACKUP_RESTORE_ROLE_ARN and " +
+			"E2E_BACKUP_DB_INSTANCE_ARN to a deployed backup vault and the database it protects")
+	}
+	if deployed.Region == "" {
+		deployed.Region = "us-east-1"
+	}
+	return deployed
+}
@@ -0,0 +1,96 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/awsval"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/stretchr/testify/require"
+)
+
+// BastionMaxAMIAgeDays is how old the bastion AMI may be before the host must be rebuilt on a newer image
+const BastionMaxAMIAgeDays = 90
+
+// BastionAllowedPolicies are the only managed policies the bastion instance role may carry
+var BastionAllowedPolicies = []string{
+	"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore",
+}
+
+// GetBastionInstance returns the deployed bastion instance, by instance ID when known and otherwise by public IP
+func (s *DeployedStack) GetBastionInstance(t *testing.T) *types.Instance {
+	if s.BastionInstanceID != "" {
+		return awsval.GetInstance(t, s.BastionInstanceID, s.Region)
+	}
+
+	cfg, err := awscalls.LoadConfig(context.Background(), t, s.Region)
+	require.NoError(t, err)
+
+	svc := ec2.NewFromConfig(cfg)
+	result, err := svc.DescribeInstances(context.Background(), &ec2.DescribeInstancesInput{
+		Filters: []types.Filter{
+			{Name: aws.String("ip-address"), Values: []string{s.BastionHost}},
+			{Name: aws.String("instance-state-name"), Values: []string{"running", "stopped"}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Reservations, 1, "Expected exactly one instance with public IP %s", s.BastionHost)
+	require.Len(t, result.Reservations[0].Instances, 1)
+
+	return &result.Reservations[0].Instances[0]
+}
+
+// RunOnBastionViaSSM runs a shell command on the bastion through SSM Run Command, without SSH,
+// and returns its standard output once the command finishes
+func (s *DeployedStack) RunOnBastionViaSSM(t *testing.T, command string) (string, error) {
+	instanceID := aws.ToString(s.GetBastionInstance(t).InstanceId)
+
+	cfg, err := awscalls.LoadConfig(context.Background(), t, s.Region)
+	require.NoError(t, err)
+
+	svc := ssm.NewFromConfig(cfg)
+	sent, err := svc.SendCommand(context.Background(), &ssm.SendCommandInput{
+		DocumentName:   aws.String("AWS-RunShellScript"),
+		InstanceIds:    []string{instanceID},
+		Parameters:     map[string][]string{"commands": {command}},
+		TimeoutSeconds: aws.Int32(60),
+		Comment:        aws.String("terratest connectivity check"),
+	})
+	require.NoError(t, err, "Failed to send SSM command to %s", instanceID)
+	commandID := aws.ToString(sent.Command.CommandId)
+
+	for attempt := 0; attempt < 30; attempt++ {
+		time.Sleep(2 * time.Second)
+
+		invocation, err := svc.GetCommandInvocation(context.Background(), &ssm.GetCommandInvocationInput{
+			CommandId:  aws.String(commandID),
+			InstanceId: aws.String(instanceID),
+		})
+		if err != nil {
+			// The invocation is not visible immediately after SendCommand
+			continue
+		}
+
+		switch invocation.Status {
+		case ssmtypes.CommandInvocationStatusSuccess:
+			return aws.ToString(invocation.StandardOutputContent), nil
+		case ssmtypes.CommandInvocationStatusFailed, ssmtypes.CommandInvocationStatusCancelled,
+			ssmtypes.CommandInvocationStatusTimedOut:
+			return aws.ToString(invocation.StandardOutputContent), fmt.Errorf("SSM command %s %s: %s",
+				commandID, invocation.Status, aws.ToString(invocation.StandardErrorContent))
+		}
+	}
+
+	return "", fmt.Errorf("SSM command %s did not finish within 60 seconds", commandID)
+}
+
+// BastionUnexpectedEgressPorts are ports the bastion has no reason to reach on the internet when its egress is restricted
+var BastionUnexpectedEgressPorts = []int{25, 6667, 8080}
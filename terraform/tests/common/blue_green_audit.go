@@ -0,0 +1,169 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// BlueGreenAuditChecks returns the checks for ECS services deployed blue/green by CodeDeploy. No module creates
+// ECS services, since the API runs on Lambda, so they report nothing until a compute module adopts the CODE_DEPLOY
+// deployment controller.
+func BlueGreenAuditChecks() []AuditCheck {
+	return []AuditCheck{
+		CheckECSDeploymentController,
+		CheckCodeDeployDeploymentGroup,
+		CheckCodeDeployListeners,
+	}
+}
+
+// ecsDeploymentGroup returns the ecs_service block of a CodeDeploy deployment group, or nil for groups deploying
+// to EC2 or Lambda
+func ecsDeploymentGroup(resource *tfjson.StateResource) map[string]interface{} {
+	if resource.Type != "aws_codedeploy_deployment_group" {
+		return nil
+	}
+	if services := nestedBlocks(resource.AttributeValues, "ecs_service"); len(services) > 0 {
+		return services[0]
+	}
+	return nil
+}
+
+// CheckECSDeploymentController verifies an ECS service that a CodeDeploy deployment group targets uses the
+// CODE_DEPLOY deployment controller, without which ECS rolls out new task definitions itself
+func CheckECSDeploymentController(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if resource.Type != "aws_ecs_service" {
+		return nil
+	}
+
+	name := GetPlannedStringAttribute(resource, "name")
+	for _, candidate := range audit.Resources {
+		if service := ecsDeploymentGroup(candidate); service == nil || service["service_name"] != name {
+			continue
+		}
+		controller := "ECS"
+		if controllers := nestedBlocks(resource.AttributeValues, "deployment_controller"); len(controllers) > 0 {
+			controller, _ = controllers[0]["type"].(string)
+		}
+		return []AuditFinding{passFail("BlueGreen-ECS-Controller", resource, controller == "CODE_DEPLOY",
+			fmt.Sprintf("service is deployed by %s but uses the %s deployment controller", candidate.Address,
+				controller))}
+	}
+	return nil
+}
+
+// CheckCodeDeployDeploymentGroup verifies an ECS deployment group belongs to an ECS CodeDeploy application, shifts
+// traffic blue/green through the load balancer, moves it gradually with a canary or linear configuration, and
+// rolls back automatically when a deployment fails
+func CheckCodeDeployDeploymentGroup(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if ecsDeploymentGroup(resource) == nil {
+		return nil
+	}
+	var findings []AuditFinding
+
+	appName := GetPlannedStringAttribute(resource, "app_name")
+	platform := ""
+	for _, candidate := range audit.Resources {
+		if candidate.Type == "aws_codedeploy_app" && GetPlannedStringAttribute(candidate, "name") == appName {
+			platform = GetPlannedStringAttribute(candidate, "compute_platform")
+		}
+	}
+	findings = append(findings, passFail("BlueGreen-Application", resource, platform == "ECS",
+		fmt.Sprintf("application %q is not an ECS CodeDeploy application in this configuration", appName)))
+
+	option, deploymentType := "", ""
+	if styles := nestedBlocks(resource.AttributeValues, "deployment_style"); len(styles) > 0 {
+		option, _ = styles[0]["deployment_option"].(string)
+		deploymentType, _ = styles[0]["deployment_type"].(string)
+	}
+	findings = append(findings, passFail("BlueGreen-Deployment-Style", resource,
+		option == "WITH_TRAFFIC_CONTROL" && deploymentType == "BLUE_GREEN",
+		fmt.Sprintf("deployment style is %q with %q, expected BLUE_GREEN with WITH_TRAFFIC_CONTROL", deploymentType,
+			option)))
+
+	config := GetPlannedStringAttribute(resource, "deployment_config_name")
+	findings = append(findings, passFail("BlueGreen-Traffic-Shift", resource, shiftsTrafficGradually(config, audit),
+		fmt.Sprintf("deployment config %q moves all traffic at once; use a canary or linear config", config)))
+
+	rollback := false
+	for _, configuration := range nestedBlocks(resource.AttributeValues, "auto_rollback_configuration") {
+		enabled, _ := configuration["enabled"].(bool)
+		events, _ := configuration["events"].([]interface{})
+		for _, event := range events {
+			rollback = rollback || (enabled && event == "DEPLOYMENT_FAILURE")
+		}
+	}
+	findings = append(findings, passFail("BlueGreen-Auto-Rollback", resource, rollback,
+		"deployment group does not roll back automatically on DEPLOYMENT_FAILURE"))
+	return findings
+}
+
+// shiftsTrafficGradually reports whether a deployment config moves traffic in steps: one of the predefined ECS
+// canary or linear configs, or a custom config with time-based routing
+func shiftsTrafficGradually(config string, audit *AuditContext) bool {
+	if strings.HasPrefix(config, "CodeDeployDefault.ECSCanary") ||
+		strings.HasPrefix(config, "CodeDeployDefault.ECSLinear") {
+		return true
+	}
+	for _, candidate := range audit.Resources {
+		if candidate.Type != "aws_codedeploy_deployment_config" ||
+			GetPlannedStringAttribute(candidate, "deployment_config_name") != config {
+			continue
+		}
+		for _, routing := range nestedBlocks(candidate.AttributeValues, "traffic_routing_config") {
+			routingType, _ := routing["type"].(string)
+			return routingType == "TimeBasedCanary" || routingType == "TimeBasedLinear"
+		}
+	}
+	return false
+}
+
+// CheckCodeDeployListeners verifies an ECS deployment group swaps a pair of target groups behind separate
+// production and test listeners, so the replacement tasks can be tested before they take production traffic
+func CheckCodeDeployListeners(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if ecsDeploymentGroup(resource) == nil {
+		return nil
+	}
+	if audit.IsUnknown(resource, "load_balancer_info") {
+		return []AuditFinding{unknown("BlueGreen-Listeners", resource, "load_balancer_info")}
+	}
+
+	var pairs []map[string]interface{}
+	for _, info := range nestedBlocks(resource.AttributeValues, "load_balancer_info") {
+		pairs = append(pairs, nestedBlocks(info, "target_group_pair_info")...)
+	}
+	if len(pairs) == 0 {
+		return []AuditFinding{passFail("BlueGreen-Listeners", resource, false,
+			"deployment group has no target_group_pair_info")}
+	}
+
+	listeners := func(route string) []interface{} {
+		var arns []interface{}
+		for _, block := range nestedBlocks(pairs[0], route) {
+			listed, _ := block["listener_arns"].([]interface{})
+			arns = append(arns, listed...)
+		}
+		return arns
+	}
+	production, test := listeners("prod_traffic_route"), listeners("test_traffic_route")
+
+	var problems []string
+	if groups := nestedBlocks(pairs[0], "target_group"); len(groups) != 2 {
+		problems = append(problems, fmt.Sprintf("%d target groups, expected a blue and a green", len(groups)))
+	}
+	if len(production) == 0 {
+		problems = append(problems, "no production listener")
+	}
+	if len(test) == 0 {
+		problems = append(problems, "no test listener")
+	}
+	for _, arn := range test {
+		for _, productionARN := range production {
+			if arn == productionARN {
+				problems = append(problems, fmt.Sprintf("listener %v serves both production and test traffic", arn))
+			}
+		}
+	}
+	return []AuditFinding{passFail("BlueGreen-Listeners", resource, len(problems) == 0, strings.Join(problems, "; "))}
+}
@@ -0,0 +1,249 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// CloudTrailDeliveryTimeout bounds how long to wait for an API call to appear in CloudTrail event history, which
+// usually takes about five minutes and occasionally fifteen
+const CloudTrailDeliveryTimeout = 20 * time.Minute
+
+// CloudTrailChecksEnabled reports whether tests should check CloudTrail records their infrastructure changes. The
+// checks only read CloudTrail, but need an account with a trail and add minutes of waiting, so they are opt-in.
+func CloudTrailChecksEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("CLOUDTRAIL_CHECKS"))
+	return enabled
+}
+
+// Trail is a CloudTrail trail visible in a region, with its logging status and whether it records management writes
+type Trail struct {
+	Name                     string `json:"Name"`
+	TrailARN                 string `json:"TrailARN"`
+	HomeRegion               string `json:"HomeRegion"`
+	S3BucketName             string `json:"S3BucketName"`
+	KMSKeyID                 string `json:"KmsKeyId"`
+	IsMultiRegionTrail       bool   `json:"IsMultiRegionTrail"`
+	LogFileValidationEnabled bool   `json:"LogFileValidationEnabled"`
+	IsLogging                bool   `json:"-"`
+	LogsManagementWrites     bool   `json:"-"`
+}
+
+// TrailProblems lists why a trail does not audit infrastructure changes in a region, or returns nil if it does
+func TrailProblems(trail Trail, region string) []string {
+	var problems []string
+	if !trail.IsMultiRegionTrail && trail.HomeRegion != region {
+		problems = append(problems, fmt.Sprintf("trail only covers %s", trail.HomeRegion))
+	}
+	if !trail.IsLogging {
+		problems = append(problems, "trail is not logging")
+	}
+	if !trail.LogsManagementWrites {
+		problems = append(problems, "trail does not record management write events")
+	}
+	if !trail.LogFileValidationEnabled {
+		problems = append(problems, "log file validation is disabled")
+	}
+	return problems
+}
+
+// LogsManagementWrites reports whether a trail's get-event-selectors output records management write events,
+// with basic or advanced event selectors
+func LogsManagementWrites(selectors string) bool {
+	var output struct {
+		EventSelectors []struct {
+			ReadWriteType           string `json:"ReadWriteType"`
+			IncludeManagementEvents bool   `json:"IncludeManagementEvents"`
+		} `json:"EventSelectors"`
+		AdvancedEventSelectors []struct {
+			FieldSelectors []struct {
+				Field     string   `json:"Field"`
+				Equals    []string `json:"Equals"`
+				NotEquals []string `json:"NotEquals"`
+			} `json:"FieldSelectors"`
+		} `json:"AdvancedEventSelectors"`
+	}
+	if err := json.Unmarshal([]byte(selectors), &output); err != nil {
+		return false
+	}
+
+	for _, selector := range output.EventSelectors {
+		if selector.IncludeManagementEvents && selector.ReadWriteType != "ReadOnly" {
+			return true
+		}
+	}
+	for _, selector := range output.AdvancedEventSelectors {
+		management, writes := false, true
+		for _, field := range selector.FieldSelectors {
+			switch field.Field {
+			case "eventCategory":
+				management = containsValue(field.Equals, "Management")
+			case "readOnly":
+				writes = !containsValue(field.Equals, "true") && !containsValue(field.NotEquals, "false")
+			}
+		}
+		if management && writes {
+			return true
+		}
+	}
+	return false
+}
+
+// containsValue reports whether a list of selector values holds a value
+func containsValue(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// runCloudTrailCommand runs an aws cloudtrail command and decodes its JSON output
+func runCloudTrailCommand(t *testing.T, region string, out interface{}, args ...string) error {
+	return awscalls.RunCLI(context.Background(), t, region, out, "cloudtrail", args...)
+}
+
+// GetTrails returns the trails that apply to a region, including multi-region trails created in another region,
+// with their logging status and event selectors
+func GetTrails(t *testing.T, region string) []Trail {
+	var described struct {
+		TrailList []Trail `json:"trailList"`
+	}
+	require.NoError(t, runCloudTrailCommand(t, region, &described, "describe-trails", "--include-shadow-trails"))
+
+	for i, trail := range described.TrailList {
+		// Status and selectors are read from the trail's home region
+		var status struct {
+			IsLogging bool `json:"IsLogging"`
+		}
+		require.NoError(t, runCloudTrailCommand(t, trail.HomeRegion, &status, "get-trail-status", "--name",
+			trail.TrailARN))
+		described.TrailList[i].IsLogging = status.IsLogging
+
+		var selectors json.RawMessage
+		require.NoError(t, runCloudTrailCommand(t, trail.HomeRegion, &selectors, "get-event-selectors",
+			"--trail-name", trail.TrailARN))
+		described.TrailList[i].LogsManagementWrites = LogsManagementWrites(string(selectors))
+	}
+	return described.TrailList
+}
+
+// bucketEncrypted reports whether a bucket has a default encryption rule. A trail may log to a bucket in another
+// account, such as an organization's log archive, whose encryption cannot be read; that is reported as an error.
+func bucketEncrypted(t *testing.T, bucket, region string) (bool, error) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	if err != nil {
+		return false, err
+	}
+	output, err := s3.NewFromConfig(cfg).GetBucketEncryption(context.Background(), &s3.GetBucketEncryptionInput{
+		Bucket: aws.String(bucket),
+	})
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ServerSideEncryptionConfigurationNotFoundError" {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, rule := range output.ServerSideEncryptionConfiguration.Rules {
+		if rule.ApplyServerSideEncryptionByDefault != nil && rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AssertRegionAudited asserts at least one trail covers the region, is logging management writes with log file
+// validation, and delivers to an encrypted bucket. Every trail's problems are logged when none qualifies.
+func AssertRegionAudited(t *testing.T, region string) {
+	trails := GetTrails(t, region)
+	require.NotEmpty(t, trails, "No CloudTrail trail covers %s", region)
+
+	for _, trail := range trails {
+		problems := TrailProblems(trail, region)
+		if len(problems) == 0 {
+			encrypted, err := bucketEncrypted(t, trail.S3BucketName, trail.HomeRegion)
+			switch {
+			case err != nil:
+				problems = append(problems, fmt.Sprintf("cannot read encryption of bucket %s: %v",
+					trail.S3BucketName, err))
+			case !encrypted && trail.KMSKeyID == "":
+				problems = append(problems, fmt.Sprintf("bucket %s is not encrypted", trail.S3BucketName))
+			default:
+				t.Logf("CloudTrail trail %s audits %s", trail.Name, region)
+				return
+			}
+		}
+		t.Logf("CloudTrail trail %s does not audit %s: %s", trail.Name, region, strings.Join(problems, "; "))
+	}
+	t.Errorf("No CloudTrail trail audits %s with logging, validation and an encrypted bucket", region)
+}
+
+// CloudTrailEvent is an event from CloudTrail event history, with the full event record as JSON
+type CloudTrailEvent struct {
+	EventID         string `json:"EventId"`
+	EventName       string `json:"EventName"`
+	Username        string `json:"Username"`
+	CloudTrailEvent string `json:"CloudTrailEvent"`
+}
+
+// FindCloudTrailEvent returns the first event with the name whose record contains every one of the substrings
+func FindCloudTrailEvent(events []CloudTrailEvent, eventName string, substrings ...string) *CloudTrailEvent {
+	for i, event := range events {
+		if event.EventName != eventName {
+			continue
+		}
+		matched := true
+		for _, substring := range substrings {
+			matched = matched && strings.Contains(event.CloudTrailEvent, substring)
+		}
+		if matched {
+			return &events[i]
+		}
+	}
+	return nil
+}
+
+// AssertCloudTrailEventRecorded waits up to CloudTrailDeliveryTimeout for CloudTrail event history to show an API
+// call on a resource, such as the CreateVpc that created a VPC, and checks its record contains each substring, such
+// as the test prefix in the resource's tags
+func AssertCloudTrailEventRecorded(t *testing.T, region, eventName, resourceName string, substrings ...string) {
+	var found *CloudTrailEvent
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("Look up %s of %s in CloudTrail", eventName, resourceName),
+		int(CloudTrailDeliveryTimeout/(30*time.Second)), 30*time.Second, func() (string, error) {
+			var result struct {
+				Events []CloudTrailEvent `json:"Events"`
+			}
+			err := runCloudTrailCommand(t, region, &result, "lookup-events", "--lookup-attributes",
+				"AttributeKey=ResourceName,AttributeValue="+resourceName)
+			if err != nil {
+				return "", err
+			}
+			if found = FindCloudTrailEvent(result.Events, eventName, substrings...); found == nil {
+				return "", fmt.Errorf("%s of %s not in event history yet", eventName, resourceName)
+			}
+			return found.EventID, nil
+		})
+	if assert.NoError(t, err, "CloudTrail should record %s of %s within %s", eventName, resourceName,
+		CloudTrailDeliveryTimeout) {
+		t.Logf("CloudTrail recorded %s of %s by %s as event %s", eventName, resourceName, found.Username,
+			found.EventID)
+	}
+}
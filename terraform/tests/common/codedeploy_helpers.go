@@ -0,0 +1,202 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// CodeDeployDeploymentTimeout bounds how long a blue/green deployment may take to finish or roll back. A canary
+// config waits minutes between its two traffic shifts, on top of the replacement tasks starting.
+const CodeDeployDeploymentTimeout = 45 * time.Minute
+
+// CodeDeployDeployment is the part of a CodeDeploy deployment the blue/green helpers use
+type CodeDeployDeployment struct {
+	DeploymentID string `json:"deploymentId"`
+	Status       string `json:"status"` // Succeeded, Failed and Stopped are final
+	ErrorInfo    struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errorInformation"`
+	RollbackInfo struct {
+		RollbackDeploymentID string `json:"rollbackDeploymentId"`
+		RollbackMessage      string `json:"rollbackMessage"`
+	} `json:"rollbackInfo"`
+}
+
+// RolledBack reports whether CodeDeploy started a rollback of the deployment
+func (d CodeDeployDeployment) RolledBack() bool {
+	return d.RollbackInfo.RollbackDeploymentID != ""
+}
+
+// runDeployCommand runs an aws deploy command and decodes its JSON output
+func runDeployCommand(t *testing.T, region string, out interface{}, args ...string) error {
+	return awscalls.RunCLI(context.Background(), t, region, out, "deploy", args...)
+}
+
+// ECSAppSpec returns the AppSpec content of an ECS blue/green deployment of a task definition, with the container
+// and port the load balancer sends traffic to
+func ECSAppSpec(taskDefinitionARN, container string, port int32) string {
+	spec, _ := json.Marshal(map[string]interface{}{
+		"version": 0.0,
+		"Resources": []interface{}{map[string]interface{}{
+			"TargetService": map[string]interface{}{
+				"Type": "AWS::ECS::Service",
+				"Properties": map[string]interface{}{
+					"TaskDefinition":   taskDefinitionARN,
+					"LoadBalancerInfo": map[string]interface{}{"ContainerName": container, "ContainerPort": port},
+				},
+			},
+		}},
+	})
+	return string(spec)
+}
+
+// DeployTaskDefinition starts a CodeDeploy deployment of a task definition to a service's deployment group and
+// waits up to CodeDeployDeploymentTimeout for it to reach a final status, which it returns
+func DeployTaskDefinition(t *testing.T, deployed *DeployedBlueGreen, taskDefinitionARN string) CodeDeployDeployment {
+	service := GetECSService(t, deployed.Cluster, deployed.Service, deployed.Region)
+	require.NotEmpty(t, service.LoadBalancers, "Service %s has no load balancer to shift traffic on",
+		deployed.Service)
+	target := service.LoadBalancers[0]
+
+	revision, err := json.Marshal(map[string]interface{}{
+		"revisionType": "AppSpecContent",
+		"appSpecContent": map[string]string{
+			"content": ECSAppSpec(taskDefinitionARN, aws.ToString(target.ContainerName), aws.ToInt32(target.ContainerPort)),
+		},
+	})
+	require.NoError(t, err)
+
+	var created struct {
+		DeploymentID string `json:"deploymentId"`
+	}
+	require.NoError(t, runDeployCommand(t, deployed.Region, &created, "create-deployment",
+		"--application-name", deployed.Application, "--deployment-group-name", deployed.DeploymentGroup,
+		"--revision", string(revision)))
+	t.Logf("Started CodeDeploy deployment %s of %s", created.DeploymentID, taskDefinitionARN)
+
+	var deployment CodeDeployDeployment
+	_, err = retry.DoWithRetryE(t, "Wait for CodeDeploy deployment "+created.DeploymentID,
+		int(CodeDeployDeploymentTimeout/(30*time.Second)), 30*time.Second, func() (string, error) {
+			var result struct {
+				DeploymentInfo CodeDeployDeployment `json:"deploymentInfo"`
+			}
+			if err := runDeployCommand(t, deployed.Region, &result, "get-deployment",
+				"--deployment-id", created.DeploymentID); err != nil {
+				return "", err
+			}
+			deployment = result.DeploymentInfo
+			switch deployment.Status {
+			case "Succeeded", "Failed", "Stopped":
+				return deployment.Status, nil
+			}
+			return "", fmt.Errorf("deployment is %s", deployment.Status)
+		})
+	require.NoError(t, err)
+	return deployment
+}
+
+// GetECSService returns a service of a cluster
+func GetECSService(t *testing.T, cluster, service, region string) ecstypes.Service {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	result, err := ecs.NewFromConfig(cfg).DescribeServices(context.Background(), &ecs.DescribeServicesInput{
+		Cluster:  aws.String(cluster),
+		Services: []string{service},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Services, 1, "Service %s not found in %s", service, cluster)
+	return result.Services[0]
+}
+
+// RegisterUnhealthyRevision registers a copy of a task definition whose containers fail their health checks, for
+// driving a deployment that must roll back. The revision is deregistered when the test ends.
+func RegisterUnhealthyRevision(t *testing.T, taskDefinitionARN, region string) string {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+	client := ecs.NewFromConfig(cfg)
+
+	described, err := client.DescribeTaskDefinition(context.Background(), &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(taskDefinitionARN),
+	})
+	require.NoError(t, err)
+	current := described.TaskDefinition
+
+	containers := current.ContainerDefinitions
+	for i := range containers {
+		containers[i].HealthCheck = &ecstypes.HealthCheck{
+			Command:     []string{"CMD-SHELL", "exit 1"},
+			Interval:    aws.Int32(10),
+			Retries:     aws.Int32(2),
+			StartPeriod: aws.Int32(0),
+		}
+	}
+	registered, err := client.RegisterTaskDefinition(context.Background(), &ecs.RegisterTaskDefinitionInput{
+		Family:                  current.Family,
+		ContainerDefinitions:    containers,
+		TaskRoleArn:             current.TaskRoleArn,
+		ExecutionRoleArn:        current.ExecutionRoleArn,
+		NetworkMode:             current.NetworkMode,
+		RequiresCompatibilities: current.RequiresCompatibilities,
+		Cpu:                     current.Cpu,
+		Memory:                  current.Memory,
+		RuntimePlatform:         current.RuntimePlatform,
+		Volumes:                 current.Volumes,
+	})
+	require.NoError(t, err)
+
+	arn := aws.ToString(registered.TaskDefinition.TaskDefinitionArn)
+	t.Cleanup(func() {
+		if _, err := client.DeregisterTaskDefinition(context.Background(), &ecs.DeregisterTaskDefinitionInput{
+			TaskDefinition: aws.String(arn),
+		}); err != nil {
+			t.Logf("Failed to deregister %s: %v", arn, err)
+		}
+	})
+	return arn
+}
+
+// DeployedBlueGreen is an ECS service deployed blue/green by CodeDeploy in an already-deployed stack, from
+// E2E_CODEDEPLOY_* and E2E_ECS_* variables
+type DeployedBlueGreen struct {
+	Region          string
+	Application     string
+	DeploymentGroup string
+	Cluster         string
+	Service         string
+}
+
+// GetDeployedBlueGreen loads the blue/green service to deploy to, skipping the test when none is configured
+func GetDeployedBlueGreen(t *testing.T) *DeployedBlueGreen {
+	RequireTier(t, TierE2E)
+
+	deployed := &DeployedBlueGreen{
+		Region:          os.Getenv("AWS_REGION"),
+		Application:     os.Getenv("E2E_CODEDEPLOY_APPLICATION"),
+		DeploymentGroup: os.Getenv("E2E_CODEDEPLOY_DEPLOYMENT_GROUP"),
+		Cluster:         os.Getenv("E2E_ECS_CLUSTER"),
+		Service:         os.Getenv("E2E_ECS_SERVICE"),
+	}
+	if deployed.Application == "" || deployed.DeploymentGroup == "" || deployed.Cluster == "" ||
+		deployed.Service == "" {
+		t.Skip("Skipping end-to-end test - set E2E_CODEDEPLOY_APPLICATION, E2E_CODEDEPLOY_DEPLOYMENT_GROUP, " +
+			"E2E_ECS_CLUSTER and E2E_ECS_SERVICE to a service using the CODE_DEPLOY deployment controller")
+	}
+	if deployed.Region == "" {
+		deployed.Region = "us-east-1"
+	}
+	return deployed
+}
@@ -0,0 +1,263 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// ConfigEvaluationTimeout bounds how long ConfigCompliance waits for AWS Config to record and evaluate new
+// resources, which usually takes a few minutes after the apply
+const ConfigEvaluationTimeout = 15 * time.Minute
+
+// ConfigRule is an AWS managed Config rule and the resource types it evaluates
+type ConfigRule struct {
+	Name             string
+	SourceIdentifier string
+	ResourceTypes    []string
+}
+
+// ConfigRules are the managed Config rules relevant to this stack, under the names the Config console gives them
+var ConfigRules = []ConfigRule{
+	{Name: "encrypted-volumes", SourceIdentifier: "ENCRYPTED_VOLUMES", ResourceTypes: []string{"AWS::EC2::Volume"}},
+	{Name: "restricted-ssh", SourceIdentifier: "INCOMING_SSH_DISABLED",
+		ResourceTypes: []string{"AWS::EC2::SecurityGroup"}},
+	{Name: "s3-bucket-public-read-prohibited", SourceIdentifier: "S3_BUCKET_PUBLIC_READ_PROHIBITED",
+		ResourceTypes: []string{"AWS::S3::Bucket"}},
+}
+
+// Evaluates reports whether the rule evaluates resources of an AWS Config resource type
+func (r ConfigRule) Evaluates(resourceType string) bool {
+	for _, candidate := range r.ResourceTypes {
+		if candidate == resourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// Values of CONFIG_RULES, which opts apply tests into the Config compliance check
+const (
+	ConfigRulesExisting = "existing" // The account already has the rules
+	ConfigRulesDeploy   = "deploy"   // Create any missing rules; they are left in place for later runs
+)
+
+// ConfigRulesMode returns CONFIG_RULES, or "" when the Config compliance check is off. The check needs an account
+// with a Config recorder covering ConfigRules' resource types.
+func ConfigRulesMode() string {
+	return os.Getenv("CONFIG_RULES")
+}
+
+// ConfigEvaluation is AWS Config's latest evaluation of one resource against one rule
+type ConfigEvaluation struct {
+	ResourceType   string
+	ResourceID     string
+	ComplianceType string // COMPLIANT, NON_COMPLIANT or NOT_APPLICABLE
+}
+
+// configResourceTypes maps Terraform resource types to the AWS Config resource types ConfigRules evaluate
+var configResourceTypes = map[string]string{
+	"aws_ebs_volume":     "AWS::EC2::Volume",
+	"aws_security_group": "AWS::EC2::SecurityGroup",
+	"aws_s3_bucket":      "AWS::S3::Bucket",
+}
+
+// ConfigResource is an applied resource AWS Config evaluates, by its Config type and Terraform address
+type ConfigResource struct {
+	Type    string
+	Address string
+}
+
+// ConfigResources returns the applied resources ConfigRules evaluate, keyed by their AWS Config resource IDs.
+// Instances contribute their root and attached EBS volumes.
+func ConfigResources(state *AuditContext) map[string]ConfigResource {
+	resources := map[string]ConfigResource{}
+	for _, resource := range state.Resources {
+		if configType, evaluated := configResourceTypes[resource.Type]; evaluated {
+			if id := GetPlannedStringAttribute(resource, "id"); id != "" {
+				resources[id] = ConfigResource{Type: configType, Address: resource.Address}
+			}
+		}
+		if resource.Type != "aws_instance" {
+			continue
+		}
+		for _, block := range []string{"root_block_device", "ebs_block_device"} {
+			for _, device := range nestedBlocks(resource.AttributeValues, block) {
+				if volumeID, _ := device["volume_id"].(string); volumeID != "" {
+					resources[volumeID] = ConfigResource{Type: "AWS::EC2::Volume",
+						Address: resource.Address + "/" + block}
+				}
+			}
+		}
+	}
+	return resources
+}
+
+// runConfigCommand runs an aws configservice command and decodes its JSON output, if out is set
+func runConfigCommand(t *testing.T, region string, out interface{}, args ...string) error {
+	return awscalls.RunCLI(context.Background(), t, region, out, "configservice", args...)
+}
+
+// configRulesChecked records the regions whose rules EnsureConfigRules has already found or created
+var configRulesChecked sync.Map
+
+// EnsureConfigRules checks the account has ConfigRules in the region, creating the missing ones in deploy mode and
+// failing the test otherwise. Each region is checked once per test run.
+func EnsureConfigRules(t *testing.T, region, mode string) {
+	if _, checked := configRulesChecked.Load(region); checked {
+		return
+	}
+
+	var existing struct {
+		ConfigRules []struct {
+			ConfigRuleName string `json:"ConfigRuleName"`
+		} `json:"ConfigRules"`
+	}
+	require.NoError(t, runConfigCommand(t, region, &existing, "describe-config-rules"))
+	names := map[string]bool{}
+	for _, rule := range existing.ConfigRules {
+		names[rule.ConfigRuleName] = true
+	}
+
+	for _, rule := range ConfigRules {
+		if names[rule.Name] {
+			continue
+		}
+		require.Equal(t, ConfigRulesDeploy, mode, "AWS Config rule %s does not exist in %s; create it or set "+
+			"CONFIG_RULES=deploy", rule.Name, region)
+
+		definition, err := json.Marshal(map[string]interface{}{
+			"ConfigRuleName": rule.Name,
+			"Source":         map[string]string{"Owner": "AWS", "SourceIdentifier": rule.SourceIdentifier},
+			"Scope":          map[string][]string{"ComplianceResourceTypes": rule.ResourceTypes},
+		})
+		require.NoError(t, err)
+		require.NoError(t, runConfigCommand(t, region, nil, "put-config-rule", "--config-rule", string(definition)))
+		t.Logf("Created AWS Config rule %s in %s", rule.Name, region)
+	}
+	configRulesChecked.Store(region, true)
+}
+
+// GetConfigEvaluations returns the latest evaluations of every resource against a Config rule
+func GetConfigEvaluations(t *testing.T, region, ruleName string) ([]ConfigEvaluation, error) {
+	var result struct {
+		EvaluationResults []struct {
+			ComplianceType             string `json:"ComplianceType"`
+			EvaluationResultIdentifier struct {
+				EvaluationResultQualifier struct {
+					ResourceType string `json:"ResourceType"`
+					ResourceID   string `json:"ResourceId"`
+				} `json:"EvaluationResultQualifier"`
+			} `json:"EvaluationResultIdentifier"`
+		} `json:"EvaluationResults"`
+	}
+	if err := runConfigCommand(t, region, &result, "get-compliance-details-by-config-rule",
+		"--config-rule-name", ruleName); err != nil {
+		return nil, err
+	}
+
+	evaluations := make([]ConfigEvaluation, 0, len(result.EvaluationResults))
+	for _, evaluation := range result.EvaluationResults {
+		qualifier := evaluation.EvaluationResultIdentifier.EvaluationResultQualifier
+		evaluations = append(evaluations, ConfigEvaluation{
+			ResourceType:   qualifier.ResourceType,
+			ResourceID:     qualifier.ResourceID,
+			ComplianceType: evaluation.ComplianceType,
+		})
+	}
+	return evaluations, nil
+}
+
+// ConfigComplianceFindings turns a rule's evaluations into findings for the applied resources of the rule's types.
+// Evaluations of other resources are ignored, and applied resources not evaluated yet are reported as skipped.
+func ConfigComplianceFindings(rule ConfigRule, evaluations []ConfigEvaluation,
+	resources map[string]ConfigResource) []AuditFinding {
+	compliance := map[string]string{}
+	for _, evaluation := range evaluations {
+		compliance[evaluation.ResourceID] = evaluation.ComplianceType
+	}
+
+	var findings []AuditFinding
+	for id, resource := range resources {
+		if !rule.Evaluates(resource.Type) {
+			continue
+		}
+		finding := AuditFinding{Control: "Config-" + rule.Name, Resource: resource.Address}
+		if evaluated, ok := compliance[id]; ok {
+			finding.Passed = evaluated != "NON_COMPLIANT"
+			finding.Detail = fmt.Sprintf("%s is %s", id, evaluated)
+		} else {
+			finding.Skipped = true
+			finding.Detail = fmt.Sprintf("%s has not been evaluated", id)
+		}
+		findings = append(findings, finding)
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Resource < findings[j].Resource })
+	return findings
+}
+
+// ConfigCompliance checks that AWS Config evaluates none of the applied resources as NON_COMPLIANT under
+// ConfigRules. It is skipped unless CONFIG_RULES is set, and waits up to ConfigEvaluationTimeout for Config to
+// evaluate the new resources; any still unevaluated are reported as skipped.
+func ConfigCompliance() Validator {
+	return func(t *testing.T, applied *AppliedConfiguration) []AuditFinding {
+		mode := ConfigRulesMode()
+		if mode == "" {
+			return []AuditFinding{{Control: "Config-Rules", Resource: "AWS Config", Skipped: true,
+				Detail: "set CONFIG_RULES=existing or CONFIG_RULES=deploy to check Config compliance"}}
+		}
+		resources := ConfigResources(applied.State)
+		if len(resources) == 0 {
+			return nil
+		}
+		EnsureConfigRules(t, applied.Region, mode)
+
+		ruleNames := make([]string, 0, len(ConfigRules))
+		for _, rule := range ConfigRules {
+			ruleNames = append(ruleNames, rule.Name)
+		}
+		// Config evaluates new resources on its own, so a refused request, such as one made while an evaluation
+		// is already running, only means waiting longer
+		if err := runConfigCommand(t, applied.Region, nil, append([]string{
+			"start-config-rules-evaluation", "--config-rule-names"}, ruleNames...)...); err != nil {
+			t.Logf("Could not start AWS Config evaluations: %v", err)
+		}
+
+		var findings []AuditFinding
+		_, _ = retry.DoWithRetryE(t, "Wait for AWS Config evaluations", int(ConfigEvaluationTimeout/time.Minute),
+			time.Minute, func() (string, error) {
+				findings = nil
+				for _, rule := range ConfigRules {
+					evaluations, err := GetConfigEvaluations(t, applied.Region, rule.Name)
+					if err != nil {
+						return "", err
+					}
+					findings = append(findings, ConfigComplianceFindings(rule, evaluations, resources)...)
+				}
+				for _, finding := range findings {
+					if finding.Skipped {
+						return "", fmt.Errorf("%s: %s", finding.Resource, finding.Detail)
+					}
+				}
+				return "", nil
+			})
+		return findings
+	}
+}
+
+// AssertConfigCompliance runs ConfigCompliance against an applied configuration, for tests that apply without
+// ApplyAndValidate
+func AssertConfigCompliance(t *testing.T, terraformOptions *terraform.Options) {
+	ReportAuditFindings(t, ConfigCompliance()(t, NewAppliedConfiguration(t, terraformOptions)))
+}
@@ -0,0 +1,178 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"terraform-tests/awscalls"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// CostAllocationTagKeys are the default tags every resource carries, which must be activated as cost allocation
+// tags for the budget's Project filter and per-environment Cost Explorer reports to see any spend
+var CostAllocationTagKeys = []string{"Project", "Environment"}
+
+// Threshold expression dimensions Cost Anomaly Detection compares an anomaly's total impact against
+const (
+	AnomalyImpactAbsolute   = "ANOMALY_TOTAL_IMPACT_ABSOLUTE"
+	AnomalyImpactPercentage = "ANOMALY_TOTAL_IMPACT_PERCENTAGE"
+)
+
+// AnomalyThreshold is when an anomaly subscription alerts: once an anomaly's impact reaches AbsoluteUSD dollars,
+// Percentage percent of the expected spend, or, when both are set, either or both of them as Operator says.
+// A zero field is not part of the threshold.
+type AnomalyThreshold struct {
+	AbsoluteUSD float64
+	Percentage  float64
+	Operator    string // And or Or, when both are set
+}
+
+// AnomalyAlertThreshold is the monitoring module's intent: alert on any anomaly with an impact of $5 or more
+var AnomalyAlertThreshold = AnomalyThreshold{AbsoluteUSD: 5}
+
+// Alerts reports whether an anomaly with an impact in dollars over an expected spend in dollars would be sent
+func (a AnomalyThreshold) Alerts(impactUSD, expectedUSD float64) bool {
+	absolute := impactUSD >= a.AbsoluteUSD
+	percentage := expectedUSD > 0 && impactUSD/expectedUSD*100 >= a.Percentage
+	switch {
+	case a.Percentage == 0:
+		return absolute
+	case a.AbsoluteUSD == 0:
+		return percentage
+	case a.Operator == "Or":
+		return absolute || percentage
+	default:
+		return absolute && percentage
+	}
+}
+
+// thresholdDimension is one comparison of a threshold expression
+type thresholdDimension struct {
+	Key          string   `json:"Key"`
+	Values       []string `json:"Values"`
+	MatchOptions []string `json:"MatchOptions"`
+}
+
+// ParseThresholdExpression reads an anomaly subscription's threshold expression, which is a single dimension
+// comparison or an And or Or of an absolute and a percentage one
+func ParseThresholdExpression(expression string) (AnomalyThreshold, error) {
+	type dimensionExpression struct {
+		Dimensions *thresholdDimension `json:"Dimensions"`
+	}
+	var parsed struct {
+		dimensionExpression
+		And []dimensionExpression `json:"And"`
+		Or  []dimensionExpression `json:"Or"`
+	}
+	if err := json.Unmarshal([]byte(expression), &parsed); err != nil {
+		return AnomalyThreshold{}, fmt.Errorf("threshold expression is not valid JSON: %w", err)
+	}
+
+	var threshold AnomalyThreshold
+	dimensions := []dimensionExpression{parsed.dimensionExpression}
+	switch {
+	case len(parsed.And) > 0:
+		threshold.Operator, dimensions = "And", parsed.And
+	case len(parsed.Or) > 0:
+		threshold.Operator, dimensions = "Or", parsed.Or
+	}
+
+	for _, dimension := range dimensions {
+		if dimension.Dimensions == nil {
+			return AnomalyThreshold{}, fmt.Errorf("threshold expression %s has no Dimensions comparison", expression)
+		}
+		comparison := dimension.Dimensions
+		if len(comparison.Values) != 1 || len(comparison.MatchOptions) != 1 ||
+			comparison.MatchOptions[0] != "GREATER_THAN_OR_EQUAL" {
+			return AnomalyThreshold{}, fmt.Errorf("%s should compare GREATER_THAN_OR_EQUAL to one value", comparison.Key)
+		}
+		value, err := strconv.ParseFloat(comparison.Values[0], 64)
+		if err != nil {
+			return AnomalyThreshold{}, fmt.Errorf("%s threshold %q is not a number", comparison.Key, comparison.Values[0])
+		}
+
+		switch comparison.Key {
+		case AnomalyImpactAbsolute:
+			threshold.AbsoluteUSD = value
+		case AnomalyImpactPercentage:
+			threshold.Percentage = value
+		default:
+			return AnomalyThreshold{}, fmt.Errorf("unexpected threshold dimension %s", comparison.Key)
+		}
+	}
+	return threshold, nil
+}
+
+// PlannedAnomalyThreshold returns the threshold of a planned anomaly subscription from its threshold expression,
+// or from the legacy threshold attribute, which is an absolute amount in dollars
+func PlannedAnomalyThreshold(t *testing.T, subscription *tfjson.StateResource) AnomalyThreshold {
+	if expression, _ := subscription.AttributeValues["threshold_expression"].(string); expression != "" {
+		threshold, err := ParseThresholdExpression(expression)
+		require.NoError(t, err, "%s has an unreadable threshold expression", subscription.Address)
+		return threshold
+	}
+
+	amount, ok := subscription.AttributeValues["threshold"].(float64)
+	require.True(t, ok, "%s sets neither threshold_expression nor threshold", subscription.Address)
+	return AnomalyThreshold{AbsoluteUSD: amount}
+}
+
+// runCostExplorerCommand runs an aws ce command and decodes its JSON output. Cost Explorer's endpoint is in
+// us-east-1 whatever region the stack is in.
+func runCostExplorerCommand(t *testing.T, out interface{}, args ...string) error {
+	return awscalls.RunCLI(context.Background(), t, "us-east-1", out, "ce", args...)
+}
+
+// GetCostAllocationTagStatuses returns whether each user-defined tag key is Active or Inactive as a cost
+// allocation tag. Keys Billing has never seen on a resource are left out. Only the management account of an
+// organization can read the statuses, so the test is skipped when access is denied.
+func GetCostAllocationTagStatuses(t *testing.T, keys []string) map[string]string {
+	var result struct {
+		CostAllocationTags []struct {
+			TagKey string `json:"TagKey"`
+			Status string `json:"Status"`
+		} `json:"CostAllocationTags"`
+	}
+	args := append([]string{"list-cost-allocation-tags", "--type", "UserDefined", "--tag-keys"}, keys...)
+	if err := runCostExplorerCommand(t, &result, args...); err != nil {
+		if strings.HasPrefix(awscalls.CLIErrorCode(err), "AccessDenied") {
+			t.Skipf("Skipping cost allocation tag check - only the organization's management account can read "+
+				"them: %v", err)
+		}
+		require.NoError(t, err)
+	}
+
+	statuses := map[string]string{}
+	for _, tag := range result.CostAllocationTags {
+		statuses[tag.TagKey] = tag.Status
+	}
+	return statuses
+}
+
+// GetAnomalySubscriptionThreshold returns the threshold of a deployed anomaly subscription. AWS stores even a
+// legacy threshold as an expression, so the expression is what is read.
+func GetAnomalySubscriptionThreshold(t *testing.T, subscriptionARN string) AnomalyThreshold {
+	var result struct {
+		AnomalySubscriptions []struct {
+			ThresholdExpression json.RawMessage `json:"ThresholdExpression"`
+			Threshold           float64         `json:"Threshold"`
+		} `json:"AnomalySubscriptions"`
+	}
+	require.NoError(t, runCostExplorerCommand(t, &result, "get-anomaly-subscriptions",
+		"--subscription-arn-list", subscriptionARN))
+	require.Len(t, result.AnomalySubscriptions, 1, "Anomaly subscription %s not found", subscriptionARN)
+
+	subscription := result.AnomalySubscriptions[0]
+	if len(subscription.ThresholdExpression) == 0 {
+		return AnomalyThreshold{AbsoluteUSD: subscription.Threshold}
+	}
+	threshold, err := ParseThresholdExpression(string(subscription.ThresholdExpression))
+	require.NoError(t, err)
+	return threshold
+}
@@ -0,0 +1,176 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/tfopts"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunCost is the actual spend attributed to one test run
+type TestRunCost struct {
+	RunID   string  `json:"run_id"`
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// TestRunCostReport is the JSON artifact produced by the cost report, suitable for trend tracking
+type TestRunCostReport struct {
+	GeneratedAt string        `json:"generated_at"`
+	Start       string        `json:"start"`
+	End         string        `json:"end"`
+	Runs        []TestRunCost `json:"runs"`
+	TotalUSD    float64       `json:"total_usd"`
+}
+
+// GetTestRunCosts queries Cost Explorer for spend grouped by the TestRunId tag between start and end (YYYY-MM-DD).
+// It goes through the AWS CLI, which CI images already provide, and reads every page of the response.
+func GetTestRunCosts(t *testing.T, start, end string) *TestRunCostReport {
+	report, err := ParseTestRunCosts(getCostAndUsagePages(t, start, end, tfopts.TestRunIDTag)...)
+	require.NoError(t, err)
+
+	report.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+	report.Start = start
+	report.End = end
+	return report
+}
+
+// ParseTestRunCosts sums the pages of a Cost Explorer get-cost-and-usage response per TestRunId tag value.
+// Untagged spend (an empty tag value) is left out.
+func ParseTestRunCosts(pages ...[]byte) (*TestRunCostReport, error) {
+	costs, err := parseCostsByTag(pages, tfopts.TestRunIDTag)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &TestRunCostReport{}
+	for _, runID := range sortedKeys(costs) {
+		report.Runs = append(report.Runs, TestRunCost{RunID: runID, CostUSD: costs[runID]})
+		report.TotalUSD += costs[runID]
+	}
+	return report, nil
+}
+
+// BranchCost is the actual spend attributed to the tests of one branch
+type BranchCost struct {
+	Branch  string  `json:"branch"`
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// BranchCostReport is the JSON artifact produced by the per-branch cost report
+type BranchCostReport struct {
+	GeneratedAt string       `json:"generated_at"`
+	Start       string       `json:"start"`
+	End         string       `json:"end"`
+	Branches    []BranchCost `json:"branches"`
+	TotalUSD    float64      `json:"total_usd"`
+}
+
+// GetBranchCosts queries Cost Explorer for spend grouped by the GitBranch tag between start and end (YYYY-MM-DD)
+func GetBranchCosts(t *testing.T, start, end string) *BranchCostReport {
+	report, err := ParseBranchCosts(getCostAndUsagePages(t, start, end, tfopts.GitBranchTag)...)
+	require.NoError(t, err)
+
+	report.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+	report.Start = start
+	report.End = end
+	return report
+}
+
+// ParseBranchCosts sums the pages of a Cost Explorer get-cost-and-usage response per GitBranch tag value, most
+// expensive branch first. Untagged spend is left out.
+func ParseBranchCosts(pages ...[]byte) (*BranchCostReport, error) {
+	costs, err := parseCostsByTag(pages, tfopts.GitBranchTag)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BranchCostReport{}
+	for _, branch := range sortedKeys(costs) {
+		report.Branches = append(report.Branches, BranchCost{Branch: branch, CostUSD: costs[branch]})
+		report.TotalUSD += costs[branch]
+	}
+	sort.SliceStable(report.Branches, func(i, j int) bool {
+		return report.Branches[i].CostUSD > report.Branches[j].CostUSD
+	})
+	return report, nil
+}
+
+// getCostAndUsagePages returns every page of daily spend grouped by a tag between start and end. Cost Explorer
+// splits a response that has more groups than fit in a page, such as a month of test runs, and the CLI does not
+// follow NextPageToken for get-cost-and-usage, so each page is asked for in turn.
+func getCostAndUsagePages(t *testing.T, start, end, tag string) [][]byte {
+	var pages [][]byte
+	token := ""
+	for {
+		args := []string{
+			"get-cost-and-usage",
+			"--time-period", fmt.Sprintf("Start=%s,End=%s", start, end),
+			"--granularity", "DAILY",
+			"--metrics", "UnblendedCost",
+			"--group-by", "Type=TAG,Key=" + tag,
+		}
+		if token != "" {
+			args = append(args, "--next-page-token", token)
+		}
+
+		var page json.RawMessage
+		require.NoError(t, runCostExplorerCommand(t, &page, args...), "Failed to query Cost Explorer")
+		pages = append(pages, page)
+
+		var next struct {
+			NextPageToken string `json:"NextPageToken"`
+		}
+		require.NoError(t, json.Unmarshal(page, &next), "Failed to parse Cost Explorer response")
+		if next.NextPageToken == "" {
+			return pages
+		}
+		token = next.NextPageToken
+	}
+}
+
+// parseCostsByTag sums the pages of a get-cost-and-usage response grouped by a tag, per tag value. Untagged spend
+// (an empty tag value) is left out.
+func parseCostsByTag(pages [][]byte, tag string) (map[string]float64, error) {
+	costs := map[string]float64{}
+	for _, page := range pages {
+		var parsed struct {
+			ResultsByTime []struct {
+				Groups []struct {
+					Keys    []string `json:"Keys"`
+					Metrics map[string]struct {
+						Amount string `json:"Amount"`
+					} `json:"Metrics"`
+				} `json:"Groups"`
+			} `json:"ResultsByTime"`
+		}
+		if err := json.Unmarshal(page, &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse Cost Explorer response: %w", err)
+		}
+
+		for _, period := range parsed.ResultsByTime {
+			for _, group := range period.Groups {
+				if len(group.Keys) == 0 {
+					continue
+				}
+				// Tag group keys look like "TestRunId$<value>"
+				value := strings.TrimPrefix(group.Keys[0], tag+"$")
+				if value == "" {
+					continue
+				}
+				amount, err := strconv.ParseFloat(group.Metrics["UnblendedCost"].Amount, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid cost amount for %s: %w", value, err)
+				}
+				costs[value] += amount
+			}
+		}
+	}
+	return costs, nil
+}
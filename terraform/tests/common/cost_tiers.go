@@ -0,0 +1,132 @@
+package common
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"terraform-tests/tfopts"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Cheapest viable tiers that test-sized resources are held to. Raising any of these is a
+// deliberate decision: update the constant together with the module default it guards.
+const (
+	TestDBInstanceClass      = tfopts.TestDBInstanceClass // The test values' database, in tfopts
+	TestDBAllocatedStorage   = tfopts.TestDBAllocatedStorage
+	TestStorageType          = "gp3"
+	TestCloudFrontPriceClass = "PriceClass_100"
+	TestFargateCPU           = 256 // 0.25 vCPU
+	TestFargateMemory        = 512
+	TestAuroraMinACU         = 0.5 // Aurora Serverless v2 capacity range, in ACUs
+	TestAuroraMaxACU         = 1.0
+)
+
+// VariableDefaultTier pins the default of a module input variable to the tier tests expect
+type VariableDefaultTier struct {
+	Module   string // Path relative to the terraform directory; "." is the root configuration
+	Variable string
+	Expected string
+}
+
+// CostTierDefaults are the variable defaults that decide what tier a deployment lands in
+var CostTierDefaults = []VariableDefaultTier{
+	{Module: ".", Variable: "db_instance_class", Expected: TestDBInstanceClass},
+	{Module: ".", Variable: "db_allocated_storage", Expected: fmt.Sprint(TestDBAllocatedStorage)},
+	{Module: "modules/database", Variable: "db_instance_class", Expected: TestDBInstanceClass},
+	{Module: "modules/database", Variable: "db_allocated_storage", Expected: fmt.Sprint(TestDBAllocatedStorage)},
+	{
+		Module:   "modules/geodata-import",
+		Variable: "ephemeral_storage_gib",
+		Expected: fmt.Sprint(FargateDefaultEphemeralStorageGiB),
+	},
+	{Module: "modules/geodata-import", Variable: "enable_container_insights", Expected: "false"},
+}
+
+// GetVariableDefault reads the default of an input variable from a module's variables.tf, rendered as a string.
+// It fails the test if the variable is not declared or has no default.
+func GetVariableDefault(t *testing.T, modulePath, variable string) string {
+	path := filepath.Join(modulePath, "variables.tf")
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	require.False(t, diags.HasErrors(), "Failed to parse %s: %s", path, diags.Error())
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	require.True(t, ok, "Unexpected body type in %s", path)
+
+	for _, block := range body.Blocks {
+		if block.Type != "variable" || len(block.Labels) == 0 || block.Labels[0] != variable {
+			continue
+		}
+
+		attribute, found := block.Body.Attributes["default"]
+		require.True(t, found, "Variable %s in %s has no default", variable, path)
+
+		value, valueDiags := attribute.Expr.Value(nil)
+		require.False(t, valueDiags.HasErrors(),
+			"Default of %s in %s is not a literal: %s", variable, path, valueDiags.Error())
+		return ctyValueString(value)
+	}
+
+	require.Fail(t, "Variable not declared", "%s does not declare variable %s", path, variable)
+	return ""
+}
+
+// GetModuleArgument reads a literal argument a configuration's main.tf passes to one of its module calls, rendered
+// as a string. It returns false if the module call does not set the argument.
+func GetModuleArgument(t *testing.T, configPath, module, argument string) (string, bool) {
+	path := filepath.Join(configPath, "main.tf")
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	require.False(t, diags.HasErrors(), "Failed to parse %s: %s", path, diags.Error())
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	require.True(t, ok, "Unexpected body type in %s", path)
+
+	for _, block := range body.Blocks {
+		if block.Type != "module" || len(block.Labels) == 0 || block.Labels[0] != module {
+			continue
+		}
+
+		attribute, found := block.Body.Attributes[argument]
+		if !found {
+			return "", false
+		}
+		value, valueDiags := attribute.Expr.Value(nil)
+		require.False(t, valueDiags.HasErrors(),
+			"Argument %s of module %s in %s is not a literal: %s", argument, module, path, valueDiags.Error())
+		return ctyValueString(value), true
+	}
+
+	require.Fail(t, "Module not called", "%s does not call module %s", path, module)
+	return "", false
+}
+
+// ctyValueString renders a primitive cty value the way it is written in Terraform
+func ctyValueString(value cty.Value) string {
+	switch {
+	case value.IsNull():
+		return "null"
+	case value.Type() == cty.String:
+		return value.AsString()
+	case value.Type() == cty.Number:
+		return value.AsBigFloat().Text('f', -1)
+	case value.Type() == cty.Bool:
+		return fmt.Sprint(value.True())
+	default:
+		return value.GoString()
+	}
+}
+
+// AssertVariableDefaultTiers fails the test if any tier-deciding variable default has drifted from what tests expect
+func AssertVariableDefaultTiers(t *testing.T, terraformDir string, tiers []VariableDefaultTier) {
+	for _, tier := range tiers {
+		actual := GetVariableDefault(t, filepath.Join(terraformDir, tier.Module), tier.Variable)
+		assert.Equal(t, tier.Expected, actual,
+			"Default of %s in %s changed; if the larger tier is intentional, update the test tier constants as well",
+			tier.Variable, tier.Module)
+	}
+}
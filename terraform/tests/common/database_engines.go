@@ -0,0 +1,229 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"terraform-tests/awscalls"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// DatabaseEngineVariable is the input variable the database module declares once it can deploy Aurora as well as
+// RDS for PostgreSQL. Aurora tests skip until it does.
+const DatabaseEngineVariable = "db_engine"
+
+// Engines the database module can be deployed as, named as RDS reports them
+const (
+	EnginePostgres       = "postgres"
+	EngineAuroraPostgres = "aurora-postgresql"
+)
+
+// auroraServerlessInstanceClass is the instance class of Aurora Serverless v2 cluster members
+const auroraServerlessInstanceClass = "db.serverless"
+
+// DatabaseEngine is one engine family the database module is tested as. Both families run the same tests and
+// shared assertions, with Vars layered over GetDefaultDatabaseTestVars.
+type DatabaseEngine struct {
+	Name   string
+	Engine string
+	Vars   map[string]interface{}
+}
+
+// DatabaseEngines are the engine families the database module is tested as
+var DatabaseEngines = []DatabaseEngine{
+	{Name: "rds-postgres", Engine: EnginePostgres},
+	{
+		Name:   "aurora-serverless-v2",
+		Engine: EngineAuroraPostgres,
+		Vars: map[string]interface{}{
+			DatabaseEngineVariable: EngineAuroraPostgres,
+			"aurora_min_capacity":  TestAuroraMinACU,
+			"aurora_max_capacity":  TestAuroraMaxACU,
+		},
+	},
+}
+
+// IsAurora reports whether the family deploys an Aurora cluster rather than a single instance
+func (e DatabaseEngine) IsAurora() bool {
+	return e.Engine == EngineAuroraPostgres
+}
+
+// SupportedBy reports whether a module can be deployed as the family. RDS for PostgreSQL is the module's only
+// engine until it declares DatabaseEngineVariable.
+func (e DatabaseEngine) SupportedBy(modulePath string) bool {
+	return !e.IsAurora() || moduleDeclaresVariable(modulePath, DatabaseEngineVariable)
+}
+
+// TestVars returns the default database test variables with the family's own on top
+func (e DatabaseEngine) TestVars() map[string]interface{} {
+	vars := GetDefaultDatabaseTestVars()
+	for name, value := range e.Vars {
+		vars[name] = value
+	}
+	return vars
+}
+
+// AssertPlannedDatabase checks the plan holds one database of the family, encrypted and private, and for Aurora
+// that it is Serverless v2 scaling within the test capacity range
+func AssertPlannedDatabase(t *testing.T, plan *terraform.PlanStruct, engine DatabaseEngine) {
+	databaseType, memberType := "aws_db_instance", "aws_db_instance"
+	if engine.IsAurora() {
+		databaseType, memberType = "aws_rds_cluster", "aws_rds_cluster_instance"
+	}
+
+	databases := GetPlannedResourcesByType(plan, databaseType)
+	require.Len(t, databases, 1, "Expected one %s", databaseType)
+	database := databases[0]
+
+	assert.Equal(t, engine.Engine, GetPlannedStringAttribute(database, "engine"))
+	assert.Equal(t, true, database.AttributeValues["storage_encrypted"], "%s should be encrypted", database.Address)
+
+	members := GetPlannedResourcesByType(plan, memberType)
+	require.NotEmpty(t, members, "Expected at least one %s", memberType)
+	for _, member := range members {
+		assert.Equal(t, false, member.AttributeValues["publicly_accessible"],
+			"%s should not be publicly accessible", member.Address)
+	}
+
+	if !engine.IsAurora() {
+		return
+	}
+
+	assert.Equal(t, "provisioned", GetPlannedStringAttribute(database, "engine_mode"),
+		"Serverless v2 runs in provisioned engine mode")
+	scaling := nestedBlocks(database.AttributeValues, "serverlessv2_scaling_configuration")
+	if assert.Len(t, scaling, 1, "%s should configure Serverless v2 scaling", database.Address) {
+		assert.EqualValues(t, TestAuroraMinACU, scaling[0]["min_capacity"])
+		assert.EqualValues(t, TestAuroraMaxACU, scaling[0]["max_capacity"])
+	}
+	for _, member := range members {
+		assert.Equal(t, auroraServerlessInstanceClass, GetPlannedStringAttribute(member, "instance_class"))
+	}
+}
+
+// DatabaseDeployment is what the shared assertions check of a deployed database, whether a single RDS instance or
+// an Aurora cluster and its members
+type DatabaseDeployment struct {
+	Engine                string
+	WriterEndpoint        string
+	ReaderEndpoint        string // Aurora only
+	Port                  int32
+	StorageEncrypted      bool
+	PubliclyAccessible    bool // Whether any member is
+	BackupRetentionPeriod int32
+	SubnetGroup           string
+	SecurityGroupIDs      []string
+
+	// Set for Aurora only
+	Cluster *rdstypes.DBCluster
+	Members []rdstypes.DBInstance
+}
+
+// GetDatabaseDeployment describes the database deployed with an identifier as the family it was deployed as
+func GetDatabaseDeployment(t *testing.T, engine DatabaseEngine, identifier, region string) *DatabaseDeployment {
+	if !engine.IsAurora() {
+		instance := GetRDSInstanceById(t, identifier, region)
+		deployment := &DatabaseDeployment{
+			Engine:                aws.ToString(instance.Engine),
+			StorageEncrypted:      aws.ToBool(instance.StorageEncrypted),
+			PubliclyAccessible:    aws.ToBool(instance.PubliclyAccessible),
+			BackupRetentionPeriod: aws.ToInt32(instance.BackupRetentionPeriod),
+			SecurityGroupIDs:      instanceSecurityGroupIDs(instance.VpcSecurityGroups),
+		}
+		if instance.Endpoint != nil {
+			deployment.WriterEndpoint = aws.ToString(instance.Endpoint.Address)
+			deployment.Port = aws.ToInt32(instance.Endpoint.Port)
+		}
+		if instance.DBSubnetGroup != nil {
+			deployment.SubnetGroup = aws.ToString(instance.DBSubnetGroup.DBSubnetGroupName)
+		}
+		return deployment
+	}
+
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+	svc := rds.NewFromConfig(cfg)
+
+	clusters, err := svc.DescribeDBClusters(context.Background(), &rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(identifier),
+	})
+	require.NoError(t, err)
+	require.Len(t, clusters.DBClusters, 1)
+	cluster := &clusters.DBClusters[0]
+
+	members, err := svc.DescribeDBInstances(context.Background(), &rds.DescribeDBInstancesInput{
+		Filters: []rdstypes.Filter{{Name: aws.String("db-cluster-id"), Values: []string{identifier}}},
+	})
+	require.NoError(t, err)
+
+	deployment := &DatabaseDeployment{
+		Engine:                aws.ToString(cluster.Engine),
+		WriterEndpoint:        aws.ToString(cluster.Endpoint),
+		ReaderEndpoint:        aws.ToString(cluster.ReaderEndpoint),
+		Port:                  aws.ToInt32(cluster.Port),
+		StorageEncrypted:      aws.ToBool(cluster.StorageEncrypted),
+		BackupRetentionPeriod: aws.ToInt32(cluster.BackupRetentionPeriod),
+		SubnetGroup:           aws.ToString(cluster.DBSubnetGroup),
+		Cluster:               cluster,
+		Members:               members.DBInstances,
+	}
+	for _, group := range cluster.VpcSecurityGroups {
+		deployment.SecurityGroupIDs = append(deployment.SecurityGroupIDs, aws.ToString(group.VpcSecurityGroupId))
+	}
+	for _, member := range members.DBInstances {
+		deployment.PubliclyAccessible = deployment.PubliclyAccessible || aws.ToBool(member.PubliclyAccessible)
+	}
+	return deployment
+}
+
+// instanceSecurityGroupIDs returns the IDs of the security groups attached to an instance
+func instanceSecurityGroupIDs(groups []rdstypes.VpcSecurityGroupMembership) []string {
+	ids := make([]string, 0, len(groups))
+	for _, group := range groups {
+		ids = append(ids, aws.ToString(group.VpcSecurityGroupId))
+	}
+	return ids
+}
+
+// ValidateDatabaseDeployment runs the assertions shared by every engine family: engine, PostgreSQL port, a writer
+// endpoint, encryption, private access, backups, and the module's subnet and security groups
+func ValidateDatabaseDeployment(t *testing.T, deployment *DatabaseDeployment, engine DatabaseEngine,
+	prefix, securityGroupID string, backupRetentionDays int32) {
+	assert.Equal(t, engine.Engine, deployment.Engine)
+	assert.Equal(t, int32(5432), deployment.Port)
+	assert.NotEmpty(t, deployment.WriterEndpoint, "The database should have a writer endpoint")
+	assert.True(t, deployment.StorageEncrypted, "Database storage should be encrypted")
+	assert.False(t, deployment.PubliclyAccessible, "The database should not be publicly accessible")
+	assert.Equal(t, backupRetentionDays, deployment.BackupRetentionPeriod)
+	assert.Equal(t, fmt.Sprintf("%s-db-subnet", prefix), deployment.SubnetGroup)
+	assert.Equal(t, []string{securityGroupID}, deployment.SecurityGroupIDs)
+}
+
+// ValidateAuroraServerlessV2 asserts an Aurora deployment scales within the given ACU range, its members are all
+// Serverless v2, and it has a reader endpoint apart from the writer
+func ValidateAuroraServerlessV2(t *testing.T, deployment *DatabaseDeployment, minACU, maxACU float64) {
+	require.NotNil(t, deployment.Cluster, "Expected an Aurora cluster")
+
+	scaling := deployment.Cluster.ServerlessV2ScalingConfiguration
+	if assert.NotNil(t, scaling, "The cluster should configure Serverless v2 scaling") {
+		assert.Equal(t, minACU, aws.ToFloat64(scaling.MinCapacity))
+		assert.Equal(t, maxACU, aws.ToFloat64(scaling.MaxCapacity))
+	}
+
+	require.NotEmpty(t, deployment.Members, "The cluster should have at least one instance")
+	for _, member := range deployment.Members {
+		assert.Equal(t, auroraServerlessInstanceClass, aws.ToString(member.DBInstanceClass),
+			"Cluster instance %s should be Serverless v2", aws.ToString(member.DBInstanceIdentifier))
+	}
+
+	assert.NotEmpty(t, deployment.ReaderEndpoint, "The cluster should have a reader endpoint")
+	assert.NotEqual(t, deployment.WriterEndpoint, deployment.ReaderEndpoint,
+		"The reader endpoint should differ from the writer endpoint")
+}
@@ -0,0 +1,222 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/awsval"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ReadReplicaVariable is the input variable the database module declares once it can create a read replica.
+// Replica tests skip until it does.
+const ReadReplicaVariable = "create_read_replica"
+
+// DatabaseMasterSecret mirrors the JSON document stored in the database master secret
+type DatabaseMasterSecret struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	DBName   string `json:"dbname"`
+}
+
+// GetRDSInstanceById gets an RDS instance by identifier using AWS SDK v2 directly
+func GetRDSInstanceById(t *testing.T, instanceID, region string) *rdstypes.DBInstance {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	svc := rds.NewFromConfig(cfg)
+	result, err := svc.DescribeDBInstances(context.Background(), &rds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(instanceID),
+	})
+	require.NoError(t, err)
+	require.Len(t, result.DBInstances, 1)
+
+	return &result.DBInstances[0]
+}
+
+// GetDatabaseMasterSecret reads and decodes the database master secret using AWS SDK v2 directly
+func GetDatabaseMasterSecret(t *testing.T, secretID, region string) *DatabaseMasterSecret {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	svc := secretsmanager.NewFromConfig(cfg)
+	result, err := svc.GetSecretValue(context.Background(), &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretID),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result.SecretString, "Secret %s should have a string value", secretID)
+
+	var secret DatabaseMasterSecret
+	require.NoError(t, json.Unmarshal([]byte(*result.SecretString), &secret))
+
+	return &secret
+}
+
+// ValidateOutputsDoNotContain asserts that no terraform output, sensitive or not, contains the given value
+func ValidateOutputsDoNotContain(t *testing.T, terraformOptions *terraform.Options, value string) {
+	outputs := terraform.OutputAll(t, terraformOptions)
+	for name, output := range outputs {
+		encoded, err := json.Marshal(output)
+		require.NoError(t, err)
+		assert.NotContains(t, string(encoded), value, fmt.Sprintf("Terraform output '%s' leaks a secret value", name))
+	}
+}
+
+// ValidateRDSStorageAutoscaling asserts storage autoscaling is enabled with the expected ceiling
+func ValidateRDSStorageAutoscaling(t *testing.T, instance *rdstypes.DBInstance, expectedMaxStorage int32) {
+	require.NotNil(t, instance.MaxAllocatedStorage, "Storage autoscaling should be enabled")
+	assert.Equal(t, expectedMaxStorage, *instance.MaxAllocatedStorage)
+	assert.Greater(t, *instance.MaxAllocatedStorage, *instance.AllocatedStorage,
+		"Autoscaling ceiling should be above the allocated storage")
+}
+
+// ValidateRDSPerformanceInsights asserts Performance Insights is enabled with the expected retention
+func ValidateRDSPerformanceInsights(t *testing.T, instance *rdstypes.DBInstance, expectedRetentionDays int32) {
+	require.NotNil(t, instance.PerformanceInsightsEnabled)
+	assert.True(t, *instance.PerformanceInsightsEnabled, "Performance Insights should be enabled")
+	require.NotNil(t, instance.PerformanceInsightsRetentionPeriod)
+	assert.Equal(t, expectedRetentionDays, *instance.PerformanceInsightsRetentionPeriod)
+	assert.NotEmpty(t, aws.ToString(instance.PerformanceInsightsKMSKeyId), "Performance Insights should be encrypted")
+}
+
+// ValidateRDSEnhancedMonitoring asserts enhanced monitoring runs at the expected interval with a role attached
+func ValidateRDSEnhancedMonitoring(t *testing.T, instance *rdstypes.DBInstance, expectedIntervalSeconds int32) {
+	require.NotNil(t, instance.MonitoringInterval)
+	assert.Equal(t, expectedIntervalSeconds, *instance.MonitoringInterval)
+	assert.Contains(t, aws.ToString(instance.MonitoringRoleArn), "-rds-monitoring",
+		"Enhanced monitoring should use the module's monitoring role")
+}
+
+// GetDBParameterValue returns the configured value of a parameter in a DB parameter group, or "" if unset
+func GetDBParameterValue(t *testing.T, parameterGroupName, parameterName, region string) string {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	svc := rds.NewFromConfig(cfg)
+	paginator := rds.NewDescribeDBParametersPaginator(svc, &rds.DescribeDBParametersInput{
+		DBParameterGroupName: aws.String(parameterGroupName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		require.NoError(t, err)
+		for _, parameter := range page.Parameters {
+			if aws.ToString(parameter.ParameterName) == parameterName {
+				return aws.ToString(parameter.ParameterValue)
+			}
+		}
+	}
+
+	return ""
+}
+
+// ValidateRDSParameterGroupAttached asserts the instance uses the named parameter group and it is in sync
+func ValidateRDSParameterGroupAttached(t *testing.T, instance *rdstypes.DBInstance, parameterGroupName string) {
+	for _, group := range instance.DBParameterGroups {
+		if aws.ToString(group.DBParameterGroupName) == parameterGroupName {
+			assert.Equal(t, "in-sync", aws.ToString(group.ParameterApplyStatus),
+				"Parameter group %s should be applied to the instance", parameterGroupName)
+			return
+		}
+	}
+	assert.Fail(t, fmt.Sprintf("Instance should use parameter group %s", parameterGroupName))
+}
+
+// ModuleSupportsReadReplica reports whether a module declares ReadReplicaVariable
+func ModuleSupportsReadReplica(modulePath string) bool {
+	return moduleDeclaresVariable(modulePath, ReadReplicaVariable)
+}
+
+// ValidateRDSReadReplica asserts the instance replicates from the primary, which RDS reports by identifier in the
+// same region and by ARN across regions
+func ValidateRDSReadReplica(t *testing.T, replica *rdstypes.DBInstance, primaryID string) {
+	source := aws.ToString(replica.ReadReplicaSourceDBInstanceIdentifier)
+	assert.True(t, source == primaryID || strings.HasSuffix(source, ":db:"+primaryID),
+		"%s should replicate from %s, not %q", aws.ToString(replica.DBInstanceIdentifier), primaryID, source)
+	assert.True(t, aws.ToBool(replica.StorageEncrypted), "Replicas should be encrypted like their primary")
+}
+
+// MaxReplicaLag is the most a test replica may fall behind its primary once it is replicating. A test database
+// takes no writes, so any lag beyond a few seconds means replication is stalled.
+const MaxReplicaLag = 30 * time.Second
+
+// WaitForRDSReplicationHealthy waits until RDS reports the replica is replicating normally and CloudWatch has
+// published its ReplicaLag, then checks the lag since replication began stayed within maxLag. RDS reports
+// replication errors and a stopped replica in its status, but the lag itself only as the ReplicaLag metric.
+func WaitForRDSReplicationHealthy(t *testing.T, replicaID, region string, maxLag time.Duration) {
+	_, err := retry.DoWithRetryE(t, "Wait for "+replicaID+" to replicate", 30, 20*time.Second,
+		func() (string, error) {
+			replica := GetRDSInstanceById(t, replicaID, region)
+			for _, info := range replica.StatusInfos {
+				if aws.ToString(info.StatusType) != "read replication" {
+					continue
+				}
+				if aws.ToString(info.Status) == "replicating" && aws.ToBool(info.Normal) {
+					return "", nil
+				}
+				return "", fmt.Errorf("replication is %s: %s", aws.ToString(info.Status), aws.ToString(info.Message))
+			}
+			return "", fmt.Errorf("%s reports no replication status yet", replicaID)
+		})
+	require.NoError(t, err, "Replica %s should replicate normally", replicaID)
+
+	// ReplicaLag is published each minute, a minute or two late
+	since := time.Now().Add(-5 * time.Minute)
+	lag, err := retry.DoWithRetryE(t, "Wait for "+replicaID+" to publish ReplicaLag", 20, 15*time.Second,
+		func() (string, error) {
+			seconds, published, err := awsval.GetMetricMaximumE(t, region, "AWS/RDS", "ReplicaLag",
+				map[string]string{"DBInstanceIdentifier": replicaID}, since)
+			if err != nil {
+				return "", retry.FatalError{Underlying: err}
+			}
+			if !published {
+				return "", fmt.Errorf("%s has published no ReplicaLag since %s", replicaID, since.Format(time.RFC3339))
+			}
+			return strconv.FormatFloat(seconds, 'f', -1, 64), nil
+		})
+	require.NoError(t, err)
+	seconds, err := strconv.ParseFloat(lag, 64)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, seconds, maxLag.Seconds(), "Replica %s should lag its primary by at most %s",
+		replicaID, maxLag)
+}
+
+// PromoteRDSReadReplica promotes a replica to a standalone instance and waits until it is available and no longer
+// replicating, returning the promoted instance
+func PromoteRDSReadReplica(t *testing.T, replicaID, region string) *rdstypes.DBInstance {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	_, err = rds.NewFromConfig(cfg).PromoteReadReplica(context.Background(), &rds.PromoteReadReplicaInput{
+		DBInstanceIdentifier: aws.String(replicaID),
+	})
+	require.NoError(t, err, "Failed to promote %s", replicaID)
+
+	var promoted *rdstypes.DBInstance
+	_, err = retry.DoWithRetryE(t, "Wait for "+replicaID+" to be promoted", 60, 20*time.Second,
+		func() (string, error) {
+			promoted = GetRDSInstanceById(t, replicaID, region)
+			status := aws.ToString(promoted.DBInstanceStatus)
+			if status != "available" || promoted.ReadReplicaSourceDBInstanceIdentifier != nil {
+				return "", fmt.Errorf("%s is %s", replicaID, status)
+			}
+			return "", nil
+		})
+	require.NoError(t, err, "Replica %s should be promoted", replicaID)
+	return promoted
+}
@@ -0,0 +1,195 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// DBConnectionsAlarmPercent is the share of max_connections the database connections alarm fires at by default
+const DBConnectionsAlarmPercent = 80
+
+// DBSaturationPercent is the share of max_connections the saturation test opens requests for, unless
+// E2E_DB_SATURATION_CONCURRENCY says otherwise
+const DBSaturationPercent = 95
+
+// PlannedMaxConnections returns the max_connections a database instance's parameter group pins, reporting false
+// when the group is not among the resources or leaves max_connections to the instance class's memory
+func PlannedMaxConnections(instance *tfjson.StateResource, audit *AuditContext) (int, bool) {
+	groupName, _ := instance.AttributeValues["parameter_group_name"].(string)
+	for _, group := range audit.Resources {
+		if group.Type != "aws_db_parameter_group" || group.AttributeValues["name"] != groupName {
+			continue
+		}
+		parameters, _ := group.AttributeValues["parameter"].([]interface{})
+		for _, parameter := range parameters {
+			fields, _ := parameter.(map[string]interface{})
+			if fields["name"] != "max_connections" {
+				continue
+			}
+			maxConnections, err := strconv.Atoi(fmt.Sprint(fields["value"]))
+			return maxConnections, err == nil
+		}
+	}
+	return 0, false
+}
+
+// DatabaseConnectionsAuditChecks returns the check that each database instance has an alarm on its connections
+func DatabaseConnectionsAuditChecks() []AuditCheck {
+	return []AuditCheck{CheckDatabaseConnectionsAlarm}
+}
+
+// CheckDatabaseConnectionsAlarm verifies a database instance pins max_connections in its parameter group, and has an
+// alarm on its DatabaseConnections that fires before connections run out: at no more than
+// DBConnectionsAlarmPercent of that max_connections. Django opens a connection per request, so a traffic spike
+// exhausts connections before anything else.
+func CheckDatabaseConnectionsAlarm(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if resource.Type != "aws_db_instance" {
+		return nil
+	}
+	for _, attribute := range []string{"identifier", "parameter_group_name"} {
+		if audit.IsUnknown(resource, attribute) {
+			return []AuditFinding{unknown("DB-Connections-Alarm", resource, attribute)}
+		}
+	}
+
+	identifier, _ := resource.AttributeValues["identifier"].(string)
+	maxConnections, pinned := PlannedMaxConnections(resource, audit)
+	if !pinned {
+		return []AuditFinding{passFail("DB-Connections-Alarm", resource, false,
+			fmt.Sprintf("parameter group %v does not pin max_connections, so no alarm threshold can be sized from it",
+				resource.AttributeValues["parameter_group_name"]))}
+	}
+	limit := maxConnections * DBConnectionsAlarmPercent / 100
+
+	for _, alarm := range audit.Resources {
+		if alarm.Type != "aws_cloudwatch_metric_alarm" || alarm.AttributeValues["namespace"] != "AWS/RDS" ||
+			alarm.AttributeValues["metric_name"] != "DatabaseConnections" {
+			continue
+		}
+		dimensions, _ := alarm.AttributeValues["dimensions"].(map[string]interface{})
+		if dimensions == nil || dimensions["DBInstanceIdentifier"] != identifier {
+			continue
+		}
+		threshold, _ := alarm.AttributeValues["threshold"].(float64)
+		return []AuditFinding{passFail("DB-Connections-Alarm", resource, threshold > 0 && threshold <= float64(limit),
+			fmt.Sprintf("%s fires at %v connections, expected at most %d of %d", alarm.Address, threshold, limit,
+				maxConnections))}
+	}
+	return []AuditFinding{passFail("DB-Connections-Alarm", resource, false,
+		fmt.Sprintf("no alarm watches DatabaseConnections for %s", identifier))}
+}
+
+// SaturationResult counts the answers an API gave while its database connections were saturated
+type SaturationResult struct {
+	Codes           map[int]int
+	TransportErrors int
+}
+
+// SaturateAPI sends requests to a URL from concurrency clients at once until the duration is up. Each request the
+// API serves holds a database connection, so enough clients push the database towards max_connections.
+func SaturateAPI(url string, concurrency int, duration time.Duration) SaturationResult {
+	result := SaturationResult{Codes: map[int]int{}}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	client := &http.Client{Timeout: LoadRequestTimeout}
+	deadline := time.Now().Add(duration)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				code := 0
+				request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+				if err == nil {
+					var response *http.Response
+					if response, err = client.Do(request); err == nil {
+						code = response.StatusCode
+						response.Body.Close()
+					}
+				}
+				mutex.Lock()
+				if err != nil {
+					result.TransportErrors++
+				} else {
+					result.Codes[code]++
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return result
+}
+
+// CheckSaturationBehavior checks an API degraded gracefully while saturated: every answer was a success, a 429
+// from throttling or a 503 asking the client to retry. A 500 means Django failed to get a connection, and a 502,
+// 504 or an unanswered request means the function crashed or hung.
+func CheckSaturationBehavior(url string, result SaturationResult) AuditFinding {
+	var ungraceful []string
+	requests := result.TransportErrors
+	for code, count := range result.Codes {
+		requests += count
+		if (code < 200 || code >= 400) && code != http.StatusTooManyRequests && code != http.StatusServiceUnavailable {
+			ungraceful = append(ungraceful, fmt.Sprintf("%d x %d", count, code))
+		}
+	}
+	if result.TransportErrors > 0 {
+		ungraceful = append(ungraceful, fmt.Sprintf("%d unanswered", result.TransportErrors))
+	}
+	return AuditFinding{Control: "DB-Saturation", Resource: url, Passed: requests > 0 && len(ungraceful) == 0,
+		Detail: fmt.Sprintf("of %d requests: %s; status codes %v", requests, strings.Join(ungraceful, ", "),
+			result.Codes)}
+}
+
+// DeployedDatabaseSaturation is the API and database whose connections are saturated, from E2E_DOMAIN and
+// E2E_DB_* variables
+type DeployedDatabaseSaturation struct {
+	Region      string
+	Domain      string
+	InstanceID  string
+	AlarmName   string
+	Concurrency int // Clients at once; 0 works it out from the instance's max_connections
+	Duration    time.Duration
+}
+
+// GetDeployedDatabaseSaturation loads the database saturation test to run, skipping the test unless
+// E2E_DB_INSTANCE_ID names the instance to saturate. The test degrades the environment while it runs, so it never
+// runs from E2E_DOMAIN alone.
+func GetDeployedDatabaseSaturation(t *testing.T) *DeployedDatabaseSaturation {
+	RequireTier(t, TierE2E)
+
+	deployed := &DeployedDatabaseSaturation{
+		Region:     os.Getenv("AWS_REGION"),
+		Domain:     os.Getenv("E2E_DOMAIN"),
+		InstanceID: os.Getenv("E2E_DB_INSTANCE_ID"),
+		AlarmName:  os.Getenv("E2E_DB_CONNECTIONS_ALARM"),
+		Duration:   5 * time.Minute,
+	}
+	if deployed.Domain == "" || deployed.InstanceID == "" {
+		t.Skip("Skipping end-to-end test - set E2E_DOMAIN and E2E_DB_INSTANCE_ID to saturate the database " +
+			"connections of a non-production stack")
+	}
+	if deployed.Region == "" {
+		deployed.Region = "us-east-1"
+	}
+	if deployed.AlarmName == "" {
+		deployed.AlarmName = strings.TrimSuffix(deployed.InstanceID, "-db") + "-db-connections"
+	}
+	if concurrency := os.Getenv("E2E_DB_SATURATION_CONCURRENCY"); concurrency != "" {
+		var err error
+		if deployed.Concurrency, err = strconv.Atoi(concurrency); err != nil {
+			t.Fatalf("E2E_DB_SATURATION_CONCURRENCY should be a number of clients: %v", err)
+		}
+	}
+	return deployed
+}
@@ -0,0 +1,115 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"terraform-tests/e2e"
+
+	"github.com/stretchr/testify/require"
+)
+
+// DeployedStack describes an already-deployed environment that end-to-end checks run against.
+// It is populated from E2E_* environment variables so the checks never create infrastructure.
+type DeployedStack struct {
+	Region            string
+	BastionHost       string
+	BastionInstanceID string
+	BastionUser       string
+	BastionPrivateKey string
+	DBEndpoint        string // host:port
+	DBName            string
+	DBUsername        string
+	DBPassword        string
+}
+
+// GetDeployedStack loads the deployed stack description, skipping the test when none is configured
+func GetDeployedStack(t *testing.T) *DeployedStack {
+	RequireTier(t, TierE2E)
+
+	bastionHost := os.Getenv("E2E_BASTION_HOST")
+	if bastionHost == "" {
+		t.Skip("Skipping end-to-end test - set E2E_BASTION_HOST and related E2E_* variables to run against a deployed stack")
+	}
+
+	stack := &DeployedStack{
+		Region:            os.Getenv("AWS_REGION"),
+		BastionHost:       bastionHost,
+		BastionInstanceID: os.Getenv("E2E_BASTION_INSTANCE_ID"),
+		BastionUser:       os.Getenv("E2E_BASTION_USER"),
+		DBEndpoint:        os.Getenv("E2E_DB_ENDPOINT"),
+		DBName:            os.Getenv("E2E_DB_NAME"),
+		DBUsername:        os.Getenv("E2E_DB_USERNAME"),
+		DBPassword:        os.Getenv("E2E_DB_PASSWORD"),
+	}
+	if stack.Region == "" {
+		stack.Region = "us-east-1"
+	}
+	if stack.BastionUser == "" {
+		stack.BastionUser = "ec2-user"
+	}
+
+	if keyPath := os.Getenv("E2E_BASTION_SSH_KEY_PATH"); keyPath != "" {
+		key, err := os.ReadFile(keyPath)
+		require.NoError(t, err, "Failed to read bastion SSH key")
+		stack.BastionPrivateKey = string(key)
+	}
+
+	return stack
+}
+
+// RunOnBastion runs a shell command on the bastion host over SSH and returns its combined output
+func (s *DeployedStack) RunOnBastion(t *testing.T, command string) (string, error) {
+	require.NotEmpty(t, s.BastionPrivateKey, "E2E_BASTION_SSH_KEY_PATH must be set to run commands on the bastion")
+
+	return e2e.RunOverSSH(t, s.BastionHost, s.BastionUser, s.BastionPrivateKey, command)
+}
+
+// PsqlCommand builds a psql invocation against the stack database with the given sslmode
+func (s *DeployedStack) PsqlCommand(sslMode, query string) string {
+	return fmt.Sprintf("PGPASSWORD='%s' PGCONNECT_TIMEOUT=10 psql 'host=%s dbname=%s user=%s sslmode=%s' -tAc '%s'",
+		s.DBPassword, s.dbHost(), s.DBName, s.DBUsername, sslMode, query)
+}
+
+// dbHost strips the port from the database endpoint
+func (s *DeployedStack) dbHost() string {
+	host, _, err := net.SplitHostPort(s.DBEndpoint)
+	if err != nil {
+		return s.DBEndpoint
+	}
+	return host
+}
+
+// DeployedWebACL is a deployed web ACL and the resources it must protect
+type DeployedWebACL struct {
+	Region       string
+	WebACLARN    string
+	ResourceARNs []string // Application load balancers or API Gateway stages
+}
+
+// GetDeployedWebACL loads the web ACL and the resources it must be associated with, skipping the test when none is
+// configured
+func GetDeployedWebACL(t *testing.T) *DeployedWebACL {
+	RequireTier(t, TierE2E)
+
+	deployed := &DeployedWebACL{
+		Region:    os.Getenv("AWS_REGION"),
+		WebACLARN: os.Getenv("E2E_WEB_ACL_ARN"),
+	}
+	for _, arn := range strings.Split(os.Getenv("E2E_WAF_PROTECTED_ARNS"), ",") {
+		if arn = strings.TrimSpace(arn); arn != "" {
+			deployed.ResourceARNs = append(deployed.ResourceARNs, arn)
+		}
+	}
+	if deployed.WebACLARN == "" || len(deployed.ResourceARNs) == 0 {
+		t.Skip("Skipping end-to-end test - set E2E_WEB_ACL_ARN and E2E_WAF_PROTECTED_ARNS to a deployed web ACL " +
+			"and the load balancers it protects")
+	}
+	if deployed.Region == "" {
+		deployed.Region = "us-east-1"
+	}
+	return deployed
+}
@@ -0,0 +1,99 @@
+package common
+
+import (
+	"testing"
+
+	"terraform-tests/awsval"
+	"terraform-tests/tfopts"
+	"terraform-tests/tfout"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// The helpers below moved out of common into packages of their own, and remain here so existing tests keep
+// building. Each forwards to its replacement; new tests should call the replacement.
+
+// Moved to awsval
+
+// GetSubnetById gets a subnet by ID.
+//
+// Deprecated: use awsval.GetSubnet.
+func GetSubnetById(t *testing.T, subnetID, region string) *types.Subnet {
+	return awsval.GetSubnet(t, subnetID, region)
+}
+
+// GetSecurityGroupById gets a security group by ID.
+//
+// Deprecated: use awsval.GetSecurityGroup.
+func GetSecurityGroupById(t *testing.T, sgID, region string) *types.SecurityGroup {
+	return awsval.GetSecurityGroup(t, sgID, region)
+}
+
+// GetInternetGatewaysForVpc gets internet gateways for a VPC.
+//
+// Deprecated: use awsval.GetInternetGateways.
+func GetInternetGatewaysForVpc(t *testing.T, vpcID, region string) []types.InternetGateway {
+	return awsval.GetInternetGateways(t, vpcID, region)
+}
+
+// GetEc2InstanceById gets an EC2 instance by ID.
+//
+// Deprecated: use awsval.GetInstance.
+func GetEc2InstanceById(t *testing.T, instanceID, region string) *types.Instance {
+	return awsval.GetInstance(t, instanceID, region)
+}
+
+// Moved to tfopts
+
+// InitTerraformForPlanOnly initializes terraform without backend for plan-only tests.
+//
+// Deprecated: use tfopts.InitForPlanOnly.
+func InitTerraformForPlanOnly(t *testing.T, terraformOptions *terraform.Options) {
+	tfopts.InitForPlanOnly(t, terraformOptions)
+}
+
+// CleanupTerraformState removes local terraform state to prevent conflicts between tests.
+//
+// Deprecated: use tfopts.CleanupState.
+func CleanupTerraformState(t *testing.T, terraformDir string) {
+	tfopts.CleanupState(t, terraformDir)
+}
+
+// TestConfig holds common configuration for tests.
+//
+// Deprecated: use tfopts.TestConfig.
+type TestConfig = tfopts.TestConfig
+
+// NewTestConfig creates a new test configuration with a unique ID.
+//
+// Deprecated: use tfopts.NewTestConfig.
+func NewTestConfig(terraformDir string) *TestConfig {
+	return tfopts.NewTestConfig(terraformDir)
+}
+
+// Moved to tfout
+
+// ValidateTerraformOutput validates that a terraform output exists and is not empty.
+//
+// Deprecated: use tfout.OutputString(t, terraformOptions, outputName).NotEmpty().Value().
+func ValidateTerraformOutput(t *testing.T, terraformOptions *terraform.Options, outputName string) string {
+	return tfout.OutputString(t, terraformOptions, outputName).NotEmpty().Value()
+}
+
+// ValidateTerraformOutputList validates that a terraform output list exists and has expectedLength values, or any
+// number but none when expectedLength is 0.
+//
+// Deprecated: use tfout.OutputList with HasLen or NotEmpty.
+func ValidateTerraformOutputList(
+	t *testing.T,
+	terraformOptions *terraform.Options,
+	outputName string,
+	expectedLength int,
+) []string {
+	list := tfout.OutputList(t, terraformOptions, outputName)
+	if expectedLength > 0 {
+		return list.HasLen(expectedLength).Values()
+	}
+	return list.NotEmpty().Values()
+}
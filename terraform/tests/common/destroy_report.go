@@ -0,0 +1,158 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Limits the networking module's destroy is held to. Interface endpoints take a few minutes to delete, and the
+// subnets and security group behind them cannot go before their ENIs do.
+const (
+	NetworkingDestroyBudget       = 12 * time.Minute
+	MaxDependencyViolationRetries = 1               // Destroy attempts that may fail on a resource still in use
+	DestroyStallThreshold         = 3 * time.Minute // A resource taking longer than this to destroy has stalled
+)
+
+// DestroyAttempt is one terraform destroy run by DestroyWithReportE
+type DestroyAttempt struct {
+	Duration             time.Duration
+	Err                  error
+	DependencyViolations []string // Subnets, security groups and VPCs the attempt could not delete while in use
+}
+
+// ResourceDestroy is how long terraform spent destroying one resource, over every attempt
+type ResourceDestroy struct {
+	Address   string
+	ID        string
+	Duration  time.Duration // Until it was destroyed, or the last progress terraform reported for it
+	Completed bool
+}
+
+// DestroyReport is what DestroyWithReportE saw of a destroy: each attempt, and each resource's progress
+type DestroyReport struct {
+	Duration  time.Duration // From the first attempt until the last ended, including the backoff between them
+	Attempts  []DestroyAttempt
+	Resources []ResourceDestroy
+}
+
+// DependencyViolationRetries is how many attempts failed because a resource was still in use
+func (r *DestroyReport) DependencyViolationRetries() int {
+	retries := 0
+	for _, attempt := range r.Attempts {
+		if len(attempt.DependencyViolations) > 0 {
+			retries++
+		}
+	}
+	return retries
+}
+
+// Stalled returns the resources that were never destroyed or took longer than the threshold, slowest first
+func (r *DestroyReport) Stalled(threshold time.Duration) []ResourceDestroy {
+	var stalled []ResourceDestroy
+	for _, resource := range r.Resources {
+		if !resource.Completed || resource.Duration > threshold {
+			stalled = append(stalled, resource)
+		}
+	}
+	sort.SliceStable(stalled, func(i, j int) bool { return stalled[i].Duration > stalled[j].Duration })
+	return stalled
+}
+
+// Diagnostics describes the destroy for a test failure: each attempt, what was still in use when it failed, and
+// the resources that stalled
+func (r *DestroyReport) Diagnostics(threshold time.Duration) string {
+	var report strings.Builder
+	fmt.Fprintf(&report, "Destroy took %s over %d attempts\n", r.Duration.Round(time.Second), len(r.Attempts))
+	for i, attempt := range r.Attempts {
+		outcome := "succeeded"
+		if attempt.Err != nil {
+			outcome = "failed"
+		}
+		fmt.Fprintf(&report, "  Attempt %d %s after %s", i+1, outcome, attempt.Duration.Round(time.Second))
+		if len(attempt.DependencyViolations) > 0 {
+			fmt.Fprintf(&report, "; still in use: %s", strings.Join(attempt.DependencyViolations, ", "))
+		}
+		report.WriteString("\n")
+	}
+
+	stalled := r.Stalled(threshold)
+	if len(stalled) == 0 {
+		fmt.Fprintf(&report, "No resource took longer than %s to destroy\n", threshold)
+		return report.String()
+	}
+	fmt.Fprintf(&report, "Stalled (not destroyed, or longer than %s):\n", threshold)
+	for _, resource := range stalled {
+		state := "destroyed after"
+		if !resource.Completed {
+			state = "not destroyed after"
+		}
+		fmt.Fprintf(&report, "  %s (%s) %s %s\n", resource.Address, resource.ID, state, resource.Duration)
+	}
+	return report.String()
+}
+
+// Terraform's progress lines for a resource being destroyed. Elapsed times read "1m10s" or, from Terraform 1.10,
+// "01m10s".
+var (
+	destroyingPattern      = regexp.MustCompile(`^(.+?): Destroying\.\.\. \[id=([^\]]*)\]`)
+	stillDestroyingPattern = regexp.MustCompile(
+		`^(.+?): Still destroying\.\.\. \[(?:id=([^,\]]*), )?([0-9hms.]+) elapsed\]`)
+	destroyedPattern = regexp.MustCompile(`^(.+?): Destruction complete after ([0-9hms.]+)`)
+)
+
+// ParseDestroyProgress reads how long each resource took to destroy from terraform destroy's output, in the order
+// terraform started destroying them
+func ParseDestroyProgress(output string) []ResourceDestroy {
+	var resources []ResourceDestroy
+	index := map[string]int{}
+	resource := func(address string) *ResourceDestroy {
+		if i, seen := index[address]; seen {
+			return &resources[i]
+		}
+		index[address] = len(resources)
+		resources = append(resources, ResourceDestroy{Address: address})
+		return &resources[len(resources)-1]
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if match := destroyingPattern.FindStringSubmatch(line); match != nil {
+			resource(match[1]).ID = match[2]
+		} else if match = stillDestroyingPattern.FindStringSubmatch(line); match != nil {
+			if elapsed, err := time.ParseDuration(match[3]); err == nil {
+				resource(match[1]).Duration = elapsed
+			}
+		} else if match = destroyedPattern.FindStringSubmatch(line); match != nil {
+			destroyed := resource(match[1])
+			destroyed.Completed = true
+			if elapsed, err := time.ParseDuration(match[2]); err == nil {
+				destroyed.Duration = elapsed
+			}
+		}
+	}
+	return resources
+}
+
+// mergeDestroyProgress adds an attempt's progress to the report's, adding up the time each resource took
+func (r *DestroyReport) mergeDestroyProgress(progress []ResourceDestroy) {
+	for _, attempt := range progress {
+		merged := false
+		for i := range r.Resources {
+			if r.Resources[i].Address == attempt.Address {
+				r.Resources[i].Duration += attempt.Duration
+				r.Resources[i].Completed = attempt.Completed
+				if attempt.ID != "" {
+					r.Resources[i].ID = attempt.ID
+				}
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			r.Resources = append(r.Resources, attempt)
+		}
+	}
+}
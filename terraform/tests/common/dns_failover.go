@@ -0,0 +1,78 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// FailoverDetection is how long Route53 may take to notice a changed health check and stop answering with the
+// record it guards: three 30-second checks and a minute for the status to reach every name server
+const FailoverDetection = 150 * time.Second
+
+// dnsAnswerPollInterval is how often WaitForDNSAnswer asks Route53 for the answer
+const dnsAnswerPollInterval = 10 * time.Second
+
+// FailoverBudget is how long after a primary fails resolvers may still get its address: Route53's detection
+// time plus the TTL they cached the last answer for
+func FailoverBudget(ttlSeconds int) time.Duration {
+	return FailoverDetection + time.Duration(ttlSeconds)*time.Second
+}
+
+// DNSAnswerE returns the values Route53's name servers answer for a record, without the trailing dot. The zone
+// need not be delegated, since Route53 answers for it directly.
+func DNSAnswerE(t *testing.T, zoneID, name, recordType string) ([]string, error) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, "us-east-1")
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := route53.NewFromConfig(cfg).TestDNSAnswer(context.Background(), &route53.TestDNSAnswerInput{
+		HostedZoneId: aws.String(zoneID),
+		RecordName:   aws.String(name),
+		RecordType:   route53types.RRType(recordType),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if code := aws.ToString(result.ResponseCode); code != "NOERROR" {
+		return nil, fmt.Errorf("%s %s answered %s", recordType, name, code)
+	}
+
+	values := make([]string, 0, len(result.RecordData))
+	for _, value := range result.RecordData {
+		values = append(values, strings.TrimSuffix(value, "."))
+	}
+	return values, nil
+}
+
+// WaitForDNSAnswer polls Route53 until it answers a record with only the expected value, failing the test if it
+// does not within the given time, and returns how long the answer took to change
+func WaitForDNSAnswer(t *testing.T, zoneID, name, recordType, expected string, within time.Duration) time.Duration {
+	start := time.Now()
+	attempts := int(within/dnsAnswerPollInterval) + 1
+
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("Wait for %s %s to answer %s", recordType, name, expected),
+		attempts, dnsAnswerPollInterval, func() (string, error) {
+			values, answerErr := DNSAnswerE(t, zoneID, name, recordType)
+			if answerErr != nil {
+				return "", answerErr
+			}
+			if len(values) != 1 || values[0] != strings.TrimSuffix(expected, ".") {
+				return "", fmt.Errorf("%s %s answers %v", recordType, name, values)
+			}
+			return "", nil
+		})
+	require.NoError(t, err, "%s %s should answer %s within %s", recordType, name, expected, within)
+	return time.Since(start)
+}
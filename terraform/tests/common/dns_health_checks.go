@@ -0,0 +1,114 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// MaxHealthCheckFailureThreshold is the most consecutive failures a health check may wait for before it reports
+// the app unhealthy. With 30-second checks, three keep failover within FailoverDetection.
+const MaxHealthCheckFailureThreshold = 3
+
+// HealthCheckAuditChecks returns the checks for Route53 health checks on the app's records: that endpoint checks
+// poll the API health path over HTTPS and fail fast enough, that every health check has an alarm, and that a
+// primary failover record is guarded by a health check or its alias target's health. No configuration has a
+// health check on the app record yet, so they report nothing until one does.
+func HealthCheckAuditChecks() []AuditCheck {
+	return []AuditCheck{CheckHealthCheckEndpoint, CheckHealthCheckAlarm, CheckFailoverRecordHealth}
+}
+
+// CheckHealthCheckEndpoint verifies an endpoint health check requests MaintenanceHealthPath over HTTPS and reports
+// a failure within MaxHealthCheckFailureThreshold checks. Calculated and CloudWatch alarm checks have no endpoint.
+func CheckHealthCheckEndpoint(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_route53_health_check" {
+		return nil
+	}
+	checkType, _ := resource.AttributeValues["type"].(string)
+	if checkType == "CALCULATED" || checkType == "CLOUDWATCH_METRIC" || checkType == "RECOVERY_CONTROL" {
+		return nil
+	}
+
+	path, _ := resource.AttributeValues["resource_path"].(string)
+	threshold, _ := resource.AttributeValues["failure_threshold"].(float64)
+
+	var problems []string
+	if !strings.HasPrefix(checkType, "HTTPS") {
+		problems = append(problems, fmt.Sprintf("type is %s, expected HTTPS", checkType))
+	}
+	if path != MaintenanceHealthPath {
+		problems = append(problems, fmt.Sprintf("requests %q, expected %s", path, MaintenanceHealthPath))
+	}
+	if threshold < 1 || threshold > MaxHealthCheckFailureThreshold {
+		problems = append(problems, fmt.Sprintf("failure threshold is %v, expected 1-%d", threshold,
+			MaxHealthCheckFailureThreshold))
+	}
+	return []AuditFinding{passFail("HealthCheck-Endpoint", resource, len(problems) == 0,
+		strings.Join(problems, "; "))}
+}
+
+// CheckHealthCheckAlarm verifies a CloudWatch alarm watches the health check's HealthCheckStatus metric, so a
+// failover is noticed by people as well as by Route53
+func CheckHealthCheckAlarm(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if resource.Type != "aws_route53_health_check" {
+		return nil
+	}
+	if audit.IsUnknown(resource, "id") {
+		// The alarm names the health check by its ID, which is only known once it exists
+		return []AuditFinding{unknown("HealthCheck-Alarm", resource, "id")}
+	}
+
+	healthCheckID, _ := resource.AttributeValues["id"].(string)
+	for _, alarm := range audit.Resources {
+		if alarm.Type != "aws_cloudwatch_metric_alarm" ||
+			alarm.AttributeValues["namespace"] != "AWS/Route53" ||
+			alarm.AttributeValues["metric_name"] != "HealthCheckStatus" {
+			continue
+		}
+		if dimensions, _ := alarm.AttributeValues["dimensions"].(map[string]interface{}); dimensions != nil &&
+			dimensions["HealthCheckId"] == healthCheckID {
+			return []AuditFinding{passFail("HealthCheck-Alarm", resource, true, "alarmed by "+alarm.Address)}
+		}
+	}
+	return []AuditFinding{passFail("HealthCheck-Alarm", resource, false,
+		fmt.Sprintf("no alarm watches HealthCheckStatus for %s", healthCheckID))}
+}
+
+// CheckFailoverRecordHealth verifies a primary failover record has a health check or evaluates its alias target's
+// health. Route53 never fails over from a primary it cannot tell is unhealthy.
+func CheckFailoverRecordHealth(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if resource.Type != "aws_route53_record" {
+		return nil
+	}
+	policies := nestedBlocks(resource.AttributeValues, "failover_routing_policy")
+	if len(policies) == 0 || policies[0]["type"] != "PRIMARY" {
+		return nil
+	}
+
+	if healthCheckID, _ := resource.AttributeValues["health_check_id"].(string); healthCheckID != "" ||
+		audit.IsUnknown(resource, "health_check_id") {
+		return []AuditFinding{passFail("HealthCheck-Failover", resource, true, "guarded by a health check")}
+	}
+	for _, alias := range nestedBlocks(resource.AttributeValues, "alias") {
+		if alias["evaluate_target_health"] == true {
+			return []AuditFinding{passFail("HealthCheck-Failover", resource, true, "evaluates target health")}
+		}
+	}
+	return []AuditFinding{passFail("HealthCheck-Failover", resource, false,
+		"primary record has no health check and does not evaluate target health")}
+}
+
+// GetDeployedHealthCheckID returns the deployed app record's health check, skipping the test when none is
+// configured
+func GetDeployedHealthCheckID(t *testing.T) string {
+	RequireTier(t, TierE2E)
+
+	healthCheckID := os.Getenv("E2E_HEALTH_CHECK_ID")
+	if healthCheckID == "" {
+		t.Skip("Skipping end-to-end test - set E2E_HEALTH_CHECK_ID to the Route53 health check on the app record")
+	}
+	return healthCheckID
+}
@@ -0,0 +1,199 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awsval"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Route53 signs a zone with a key signing key backed by an asymmetric KMS key in us-east-1. The key must be an
+// ECC_NIST_P256 signing key whose policy lets DNSSECSigningPrincipal use it.
+const (
+	DNSSECKeySpec          = "ECC_NIST_P256"
+	DNSSECKeyUsage         = "SIGN_VERIFY"
+	DNSSECKeyRegion        = awsval.DNSSECKeyRegion
+	DNSSECSigningPrincipal = "dnssec-route53.amazonaws.com"
+)
+
+// DefaultDNSSECResolver is the validating resolver the apex's signatures are checked through
+const DefaultDNSSECResolver = "8.8.8.8:53"
+
+// rrsigType is the DNS type of a signature record, which dnsmessage has no name for
+const rrsigType = dnsmessage.Type(46)
+
+// DNSSECAuditChecks returns the checks for a signed hosted zone: the KMS key behind its key signing key, that the
+// key signing key is active and that the zone is signing. The shared environment's zone is not signed yet, so
+// they report nothing until it is.
+func DNSSECAuditChecks() []AuditCheck {
+	return []AuditCheck{CheckDNSSECKMSKey, CheckKeySigningKeyActive, CheckHostedZoneSigning}
+}
+
+// CheckDNSSECKMSKey verifies a KMS key Route53 may sign with is an ECC_NIST_P256 signing key. A key is taken to be
+// a DNSSEC key when its policy names DNSSECSigningPrincipal.
+func CheckDNSSECKMSKey(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_kms_key" {
+		return nil
+	}
+	if policy, _ := resource.AttributeValues["policy"].(string); !strings.Contains(policy, DNSSECSigningPrincipal) {
+		return nil
+	}
+
+	keySpec, _ := resource.AttributeValues["customer_master_key_spec"].(string)
+	keyUsage, _ := resource.AttributeValues["key_usage"].(string)
+	ok := keySpec == DNSSECKeySpec && keyUsage == DNSSECKeyUsage
+	return []AuditFinding{passFail("DNSSEC-KMS-Key", resource, ok, fmt.Sprintf("key is %s for %s, expected %s for %s",
+		keySpec, keyUsage, DNSSECKeySpec, DNSSECKeyUsage))}
+}
+
+// CheckKeySigningKeyActive verifies a key signing key is active, since an inactive one signs nothing
+func CheckKeySigningKeyActive(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_route53_key_signing_key" {
+		return nil
+	}
+	status, _ := resource.AttributeValues["status"].(string)
+	return []AuditFinding{passFail("DNSSEC-KSK", resource, status == "ACTIVE",
+		fmt.Sprintf("key signing key is %s, expected ACTIVE", status))}
+}
+
+// CheckHostedZoneSigning verifies DNSSEC signing is enabled for a hosted zone rather than left NOT_SIGNING
+func CheckHostedZoneSigning(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_route53_hosted_zone_dnssec" {
+		return nil
+	}
+	status, _ := resource.AttributeValues["signing_status"].(string)
+	return []AuditFinding{passFail("DNSSEC-Signing", resource, status == "SIGNING",
+		fmt.Sprintf("zone signing status is %s, expected SIGNING", status))}
+}
+
+// ApexSignaturesE asks a resolver for a domain's SOA record with DNSSEC records requested, and returns how many
+// signatures the answer holds and whether the resolver validated it. A validating resolver only sets the
+// authenticated data flag when the chain from the root, through the registrar's DS record, checks out.
+func ApexSignaturesE(domain, resolver string) (signatures int, validated bool, err error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(domain, ".") + ".")
+	if err != nil {
+		return 0, false, err
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID: uint16(time.Now().UnixNano()), RecursionDesired: true, AuthenticData: true,
+	})
+	var header dnsmessage.ResourceHeader
+	if err := header.SetEDNS0(4096, dnsmessage.RCodeSuccess, true); err != nil {
+		return 0, false, err
+	}
+	if err := builder.StartQuestions(); err != nil {
+		return 0, false, err
+	}
+	if err := builder.Question(dnsmessage.Question{
+		Name: name, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET,
+	}); err != nil {
+		return 0, false, err
+	}
+	if err := builder.StartAdditionals(); err != nil {
+		return 0, false, err
+	}
+	if err := builder.OPTResource(header, dnsmessage.OPTResource{}); err != nil {
+		return 0, false, err
+	}
+	query, err := builder.Finish()
+	if err != nil {
+		return 0, false, err
+	}
+
+	response, err := exchangeDNS(query, resolver)
+	if err != nil {
+		return 0, false, err
+	}
+	return countSignatures(response)
+}
+
+// exchangeDNS sends a DNS query over UDP and returns the response
+func exchangeDNS(query []byte, resolver string) ([]byte, error) {
+	conn, err := net.DialTimeout("udp", resolver, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	response := make([]byte, 4096)
+	n, err := conn.Read(response)
+	if err != nil {
+		return nil, err
+	}
+	return response[:n], nil
+}
+
+// countSignatures returns how many answers of a DNS response are signatures, and whether it was validated
+func countSignatures(response []byte) (int, bool, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(response)
+	if err != nil {
+		return 0, false, err
+	}
+	if header.RCode != dnsmessage.RCodeSuccess {
+		return 0, false, fmt.Errorf("resolver answered %s", header.RCode)
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return 0, false, err
+	}
+
+	signatures := 0
+	for {
+		answer, err := parser.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		if answer.Type == rrsigType {
+			signatures++
+		}
+		if err := parser.SkipAnswer(); err != nil {
+			return 0, false, err
+		}
+	}
+	return signatures, header.AuthenticData, nil
+}
+
+// DeployedDNSSEC is a deployed signed zone and the DS record its registrar holds
+type DeployedDNSSEC struct {
+	ZoneID   string
+	Domain   string
+	DSRecord string // As given to the registrar, such as "12345 13 2 1A2B..."
+	Resolver string // host:port of a validating resolver
+}
+
+// GetDeployedDNSSEC loads the signed zone to check, skipping the test when none is configured
+func GetDeployedDNSSEC(t *testing.T) *DeployedDNSSEC {
+	RequireTier(t, TierE2E)
+
+	deployed := &DeployedDNSSEC{
+		ZoneID:   os.Getenv("E2E_DNSSEC_ZONE_ID"),
+		Domain:   os.Getenv("E2E_DOMAIN"),
+		DSRecord: os.Getenv("E2E_DNSSEC_DS_RECORD"),
+		Resolver: os.Getenv("E2E_DNS_RESOLVER"),
+	}
+	if deployed.ZoneID == "" || deployed.Domain == "" || deployed.DSRecord == "" {
+		t.Skip("Skipping end-to-end test - set E2E_DNSSEC_ZONE_ID, E2E_DOMAIN and E2E_DNSSEC_DS_RECORD to a signed " +
+			"zone and the DS record its registrar holds")
+	}
+	if deployed.Resolver == "" {
+		deployed.Resolver = DefaultDNSSECResolver
+	}
+	return deployed
+}
@@ -0,0 +1,139 @@
+package common
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"terraform-tests/awscalls"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ECSExecVariable is the input variable an ECS module declares once its tasks can be opened with ECS Exec
+const ECSExecVariable = "enable_execute_command"
+
+// ECSExecActions are the Session Manager channel actions the task role needs for ECS Exec to open a session
+var ECSExecActions = []string{
+	"ssmmessages:CreateControlChannel",
+	"ssmmessages:CreateDataChannel",
+	"ssmmessages:OpenControlChannel",
+	"ssmmessages:OpenDataChannel",
+}
+
+// ModuleSupportsECSExec reports whether a module declares ECSExecVariable
+func ModuleSupportsECSExec(modulePath string) bool {
+	return moduleDeclaresVariable(modulePath, ECSExecVariable)
+}
+
+// MissingECSExecActions returns the ECSExecActions none of a role's policies allow
+func MissingECSExecActions(policies []string) []string {
+	return missingPolicyActions(policies, ECSExecActions)
+}
+
+// CheckECSExecCluster verifies a cluster encrypts ECS Exec sessions with a KMS key and logs them, so a debugging
+// session is neither readable in transit beyond TLS nor unaudited
+func CheckECSExecCluster(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if resource.Type != "aws_ecs_cluster" {
+		return nil
+	}
+
+	var problems []string
+	configurations := nestedBlocks(resource.AttributeValues, "configuration")
+	var execute []map[string]interface{}
+	if len(configurations) > 0 {
+		execute = nestedBlocks(configurations[0], "execute_command_configuration")
+	}
+	if len(execute) == 0 {
+		return []AuditFinding{passFail("ECS-Exec-Cluster", resource, false,
+			"cluster has no execute_command_configuration")}
+	}
+
+	if key, _ := execute[0]["kms_key_id"].(string); key == "" && !audit.IsUnknown(resource, "configuration") {
+		problems = append(problems, "sessions are not encrypted with a KMS key")
+	}
+	if logging, _ := execute[0]["logging"].(string); logging == "NONE" {
+		problems = append(problems, "sessions are not logged")
+	}
+	return []AuditFinding{passFail("ECS-Exec-Cluster", resource, len(problems) == 0, strings.Join(problems, "; "))}
+}
+
+// AssertECSExecReady asserts a running task was started with ECS Exec enabled and that the exec agent is running
+// in the container, which it only is once the task role can open Session Manager channels
+func AssertECSExecReady(t *testing.T, cluster, taskARN, container, region string) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	result, err := ecs.NewFromConfig(cfg).DescribeTasks(context.Background(), &ecs.DescribeTasksInput{
+		Cluster: aws.String(cluster),
+		Tasks:   []string{taskARN},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Tasks, 1, "Task %s not found in %s", taskARN, cluster)
+
+	task := result.Tasks[0]
+	require.True(t, task.EnableExecuteCommand, "Task %s should be started with --enable-execute-command", taskARN)
+	for _, candidate := range task.Containers {
+		if aws.ToString(candidate.Name) != container {
+			continue
+		}
+		for _, agent := range candidate.ManagedAgents {
+			if agent.Name == "ExecuteCommandAgent" {
+				assert.Equal(t, "RUNNING", aws.ToString(agent.LastStatus),
+					"The exec agent in %s should be running: %s", container, aws.ToString(agent.Reason))
+				return
+			}
+		}
+		t.Fatalf("Container %s of task %s has no exec agent", container, taskARN)
+	}
+	t.Fatalf("Task %s has no container %s", taskARN, container)
+}
+
+// ExecuteCommand runs a command in a task's container through ECS Exec and returns its output. It needs the
+// Session Manager plugin for the AWS CLI, as operators debugging a task do.
+func ExecuteCommand(t *testing.T, cluster, taskARN, container, region, command string) string {
+	output, err := shell.RunCommandAndGetStdOutE(t, shell.Command{Command: "aws", Args: []string{
+		"ecs", "execute-command", "--cluster", cluster, "--task", taskARN, "--container", container,
+		"--interactive", "--command", command, "--region", region,
+	}})
+	require.NoError(t, err, "ECS Exec of %q in %s failed; is the Session Manager plugin installed?", command,
+		container)
+	return output
+}
+
+// DeployedECSTask is a running ECS task of an already-deployed stack, from E2E_ECS_* variables
+type DeployedECSTask struct {
+	Region    string
+	Cluster   string
+	TaskARN   string
+	Container string
+}
+
+// GetDeployedECSTask loads the running task to open ECS Exec sessions in, skipping the test when none is configured
+func GetDeployedECSTask(t *testing.T) *DeployedECSTask {
+	RequireTier(t, TierE2E)
+
+	deployed := &DeployedECSTask{
+		Region:    os.Getenv("AWS_REGION"),
+		Cluster:   os.Getenv("E2E_ECS_CLUSTER"),
+		TaskARN:   os.Getenv("E2E_ECS_TASK_ARN"),
+		Container: os.Getenv("E2E_ECS_CONTAINER"),
+	}
+	if deployed.Cluster == "" || deployed.TaskARN == "" {
+		t.Skip("Skipping end-to-end test - set E2E_ECS_CLUSTER and E2E_ECS_TASK_ARN to a task started with " +
+			"--enable-execute-command")
+	}
+	if deployed.Container == "" {
+		deployed.Container = "geodata-import"
+	}
+	if deployed.Region == "" {
+		deployed.Region = "us-east-1"
+	}
+	return deployed
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Private subnets reach AWS services through VPC endpoints and nothing else. Calls to third parties, such as
+// geocoding APIs or email providers, would need an egress path: a NAT gateway with Elastic IPs the providers can
+// allowlist. The static egress tests skip modules that do not declare StaticEgressVariable yet.
+const (
+	StaticEgressVariable  = "enable_static_egress"
+	StaticEgressIPsOutput = "egress_public_ips"
+)
+
+// ModuleProvidesStaticEgress reports whether a module declares StaticEgressVariable
+func ModuleProvidesStaticEgress(modulePath string) bool {
+	return moduleDeclaresVariable(modulePath, StaticEgressVariable)
+}
+
+// AssertPlannedStaticEgress checks that a plan creates public NAT gateways, each with an Elastic IP of its own so
+// its address is stable, and that the database route table has no default route
+func AssertPlannedStaticEgress(t *testing.T, plan *terraform.PlanStruct) {
+	natGateways := GetPlannedResourcesByType(plan, "aws_nat_gateway")
+	require.NotEmpty(t, natGateways, "Expected a NAT gateway in the plan")
+	for _, gateway := range natGateways {
+		assert.NotEqual(t, "private", GetPlannedStringAttribute(gateway, "connectivity_type"),
+			"%s should be a public NAT gateway", gateway.Address)
+	}
+
+	assert.GreaterOrEqual(t, len(GetPlannedResourcesByType(plan, "aws_eip")), len(natGateways),
+		"Each NAT gateway should have an Elastic IP")
+
+	if dbRouteTable, exists := plan.ResourcePlannedValuesMap["aws_route_table.private_db[0]"]; exists {
+		routes, _ := dbRouteTable.AttributeValues["route"].([]interface{})
+		for _, route := range routes {
+			routeAttributes, _ := route.(map[string]interface{})
+			assert.NotEqual(t, "0.0.0.0/0", routeAttributes["cidr_block"], "Database subnets should stay offline")
+		}
+	}
+	for _, route := range GetPlannedResourcesByType(plan, "aws_route") {
+		assert.NotContains(t, route.Address, "private_db", "Database subnets should have no routes beyond the VPC")
+	}
+}
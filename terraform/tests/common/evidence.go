@@ -0,0 +1,183 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/tfopts"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/require"
+)
+
+// Evidence results, as written to the artifact
+const (
+	EvidencePass = "PASS"
+	EvidenceFail = "FAIL"
+	EvidenceSkip = "SKIP"
+)
+
+// EvidenceRecord is a single machine-readable audit evidence entry produced by a test run
+type EvidenceRecord struct {
+	RunID       string `json:"run_id"`
+	Timestamp   string `json:"timestamp"`
+	Test        string `json:"test"`
+	ControlID   string `json:"control_id"`
+	Resource    string `json:"resource"`
+	ResourceARN string `json:"resource_arn,omitempty"`
+	Result      string `json:"result"`
+	Detail      string `json:"detail,omitempty"`
+}
+
+// evidenceCSVHeader is the column order of the CSV artifact
+var evidenceCSVHeader = []string{"run_id", "timestamp", "test", "control_id", "resource", "resource_arn", "result", "detail"}
+
+var (
+	evidenceMutex   sync.Mutex
+	evidenceRecords = map[string][]EvidenceRecord{}
+)
+
+// EvidenceEnabled reports whether tests should emit evidence, which is opted into with
+// EVIDENCE_DIR (local JSON/CSV artifacts) and/or EVIDENCE_S3_BUCKET (upload to an evidence bucket)
+func EvidenceEnabled() bool {
+	return os.Getenv("EVIDENCE_DIR") != "" || os.Getenv("EVIDENCE_S3_BUCKET") != ""
+}
+
+// EvidenceRunID identifies the test run in every evidence record: EVIDENCE_RUN_ID when set, otherwise TestRunID
+func EvidenceRunID() string {
+	if runID := os.Getenv("EVIDENCE_RUN_ID"); runID != "" {
+		return runID
+	}
+	return tfopts.TestRunID()
+}
+
+// RecordEvidence records a control result for the current test. Records are written when the
+// test finishes, one artifact per top-level test. It does nothing unless evidence is enabled.
+func RecordEvidence(t *testing.T, controlID, resource, resourceARN string, passed, skipped bool, detail string) {
+	if !EvidenceEnabled() {
+		return
+	}
+
+	result := EvidenceFail
+	switch {
+	case skipped:
+		result = EvidenceSkip
+	case passed:
+		result = EvidencePass
+	}
+
+	record := EvidenceRecord{
+		RunID:       EvidenceRunID(),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Test:        t.Name(),
+		ControlID:   controlID,
+		Resource:    resource,
+		ResourceARN: resourceARN,
+		Result:      result,
+		Detail:      detail,
+	}
+
+	evidenceMutex.Lock()
+	defer evidenceMutex.Unlock()
+
+	name := t.Name()
+	if _, registered := evidenceRecords[name]; !registered {
+		t.Cleanup(func() { flushEvidence(t, name) })
+	}
+	evidenceRecords[name] = append(evidenceRecords[name], record)
+}
+
+// RecordAuditEvidence records every audit finding as evidence for the current test
+func RecordAuditEvidence(t *testing.T, findings []AuditFinding) {
+	for _, finding := range findings {
+		RecordEvidence(t, finding.Control, finding.Resource, finding.ARN, finding.Passed, finding.Skipped, finding.Detail)
+	}
+}
+
+// flushEvidence writes the records collected for a test to the configured destinations
+func flushEvidence(t *testing.T, name string) {
+	evidenceMutex.Lock()
+	records := evidenceRecords[name]
+	delete(evidenceRecords, name)
+	evidenceMutex.Unlock()
+
+	if len(records) == 0 {
+		return
+	}
+
+	jsonBody, err := json.MarshalIndent(records, "", "  ")
+	require.NoError(t, err)
+	csvBody, err := EvidenceCSV(records)
+	require.NoError(t, err)
+
+	baseName := strings.NewReplacer("/", "_", " ", "_").Replace(name)
+	if dir := os.Getenv("EVIDENCE_DIR"); dir != "" {
+		runDir := filepath.Join(dir, EvidenceRunID())
+		require.NoError(t, os.MkdirAll(runDir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(runDir, baseName+".json"), jsonBody, 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(runDir, baseName+".csv"), csvBody, 0o644))
+		t.Logf("Wrote %d evidence records to %s", len(records), runDir)
+	}
+
+	if bucket := os.Getenv("EVIDENCE_S3_BUCKET"); bucket != "" {
+		key := fmt.Sprintf("%s/%s.json", EvidenceRunID(), baseName)
+		if prefix := os.Getenv("EVIDENCE_S3_PREFIX"); prefix != "" {
+			key = strings.TrimSuffix(prefix, "/") + "/" + key
+		}
+		UploadEvidence(t, bucket, key, jsonBody)
+	}
+}
+
+// EvidenceCSV renders evidence records as CSV with a header row
+func EvidenceCSV(records []EvidenceRecord) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	if err := writer.Write(evidenceCSVHeader); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		row := []string{
+			record.RunID, record.Timestamp, record.Test, record.ControlID,
+			record.Resource, record.ResourceARN, record.Result, record.Detail,
+		}
+		if err := writer.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	return buffer.Bytes(), writer.Error()
+}
+
+// UploadEvidence stores an evidence artifact in S3, encrypted at rest with the bucket's default key
+func UploadEvidence(t *testing.T, bucket, key string, body []byte) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	client := s3.NewFromConfig(cfg)
+	_, err = client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	require.NoError(t, err, "Failed to upload evidence to s3://%s/%s", bucket, key)
+	t.Logf("Uploaded evidence to s3://%s/%s", bucket, key)
+}
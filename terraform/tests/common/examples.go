@@ -0,0 +1,45 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ExamplesDir holds the example configurations shown to deployers, one per directory, relative to the test packages
+const ExamplesDir = "../../examples"
+
+// MinimalExample is the example cheap enough to apply. Only it is applied, and only when EXAMPLES_APPLY is set,
+// which the nightly workflow does.
+const MinimalExample = "minimal"
+
+// DiscoverExamples returns every directory under ExamplesDir with a .tf file, in name order
+func DiscoverExamples(t *testing.T) []string {
+	entries, err := os.ReadDir(ExamplesDir)
+	require.NoError(t, err, "Examples belong in terraform/examples/<name>/")
+
+	var examples []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(ExamplesDir, entry.Name(), "*.tf"))
+		require.NoError(t, err)
+		if len(files) > 0 {
+			examples = append(examples, filepath.Join(ExamplesDir, entry.Name()))
+		}
+	}
+	sort.Strings(examples)
+	require.NotEmpty(t, examples, "No example configurations found under %s", ExamplesDir)
+	return examples
+}
+
+// ExamplesApplyEnabled reports whether EXAMPLES_APPLY asks for MinimalExample to be applied
+func ExamplesApplyEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("EXAMPLES_APPLY"))
+	return enabled
+}
@@ -0,0 +1,213 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// PublicIngressAllowedPorts are the ports that may accept traffic from anywhere (load balancer listeners)
+var PublicIngressAllowedPorts = map[float64]bool{80: true, 443: true}
+
+// PublicExposureExceptions documents resources, matched by address substring, that are public by design
+var PublicExposureExceptions = map[string]string{
+	"module.serverless_storage.aws_s3_bucket_public_access_block.assets": "serverless assets bucket serves public-read static files",
+	"module.serverless_storage.aws_s3_bucket_policy.assets":              "serverless assets bucket serves public-read static files",
+	"aws_s3_bucket_policy.static_assets_public":                          "static assets are served directly from S3 when CloudFront is disabled",
+}
+
+// PublicExposureChecks returns the checks that look for anything reachable from the internet that should not be
+func PublicExposureChecks() []AuditCheck {
+	return []AuditCheck{
+		CheckRDSNotPublic,
+		CheckS3PublicAccessBlock,
+		CheckS3PolicyNotPublic,
+		CheckSecurityGroupPublicIngress,
+		CheckInstancePublicIP,
+		CheckCloudFrontOriginAccess,
+	}
+}
+
+// exposureException returns a skipped finding if the resource is public by design
+func exposureException(control string, resource *tfjson.StateResource) []AuditFinding {
+	for pattern, reason := range PublicExposureExceptions {
+		if strings.Contains(resource.Address, pattern) {
+			return []AuditFinding{{
+				Control:  control,
+				Resource: resource.Address,
+				Skipped:  true,
+				Detail:   "documented exception: " + reason,
+			}}
+		}
+	}
+	return nil
+}
+
+// CheckRDSNotPublic verifies databases are not publicly accessible
+func CheckRDSNotPublic(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_db_instance" {
+		return nil
+	}
+
+	public, _ := resource.AttributeValues["publicly_accessible"].(bool)
+	return []AuditFinding{passFail("Exposure-RDS-Public", resource, !public, "database is publicly accessible")}
+}
+
+// CheckS3PublicAccessBlock verifies every public access block setting is enabled
+func CheckS3PublicAccessBlock(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_s3_bucket_public_access_block" {
+		return nil
+	}
+	if exception := exposureException("Exposure-S3-PublicAccessBlock", resource); exception != nil {
+		return exception
+	}
+
+	var disabled []string
+	for _, setting := range []string{"block_public_acls", "block_public_policy", "ignore_public_acls", "restrict_public_buckets"} {
+		if enabled, _ := resource.AttributeValues[setting].(bool); !enabled {
+			disabled = append(disabled, setting)
+		}
+	}
+
+	return []AuditFinding{passFail("Exposure-S3-PublicAccessBlock", resource, len(disabled) == 0,
+		fmt.Sprintf("public access block settings disabled: %s", strings.Join(disabled, ", ")))}
+}
+
+// CheckS3PolicyNotPublic verifies bucket policies do not grant unconditional access to everyone
+func CheckS3PolicyNotPublic(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if resource.Type != "aws_s3_bucket_policy" {
+		return nil
+	}
+	if exception := exposureException("Exposure-S3-Policy", resource); exception != nil {
+		return exception
+	}
+	if audit.IsUnknown(resource, "policy") {
+		return []AuditFinding{unknown("Exposure-S3-Policy", resource, "policy")}
+	}
+
+	policy := GetPlannedStringAttribute(resource, "policy")
+	return []AuditFinding{passFail("Exposure-S3-Policy", resource, !PolicyAllowsAnonymousAccess(policy),
+		"bucket policy allows unconditional access to any principal")}
+}
+
+// PolicyAllowsAnonymousAccess reports whether a policy has an unconditional Allow for the "*" principal
+func PolicyAllowsAnonymousAccess(policy string) bool {
+	var document struct {
+		Statement []struct {
+			Effect    string          `json:"Effect"`
+			Principal json.RawMessage `json:"Principal"`
+			Condition json.RawMessage `json:"Condition"`
+		} `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(policy), &document); err != nil {
+		return false
+	}
+
+	for _, statement := range document.Statement {
+		if statement.Effect != "Allow" || len(statement.Condition) > 0 {
+			continue
+		}
+		principal := strings.ReplaceAll(string(statement.Principal), " ", "")
+		if principal == `"*"` || principal == `{"AWS":"*"}` {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckSecurityGroupPublicIngress verifies only load balancer ports accept traffic from anywhere
+func CheckSecurityGroupPublicIngress(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	var rules []map[string]interface{}
+	switch resource.Type {
+	case "aws_security_group":
+		rules = nestedBlocks(resource.AttributeValues, "ingress")
+	case "aws_security_group_rule":
+		if ruleType, _ := resource.AttributeValues["type"].(string); ruleType == "ingress" {
+			rules = []map[string]interface{}{resource.AttributeValues}
+		}
+	case "aws_vpc_security_group_ingress_rule":
+		rule := map[string]interface{}{
+			"from_port":        resource.AttributeValues["from_port"],
+			"to_port":          resource.AttributeValues["to_port"],
+			"cidr_blocks":      []interface{}{resource.AttributeValues["cidr_ipv4"]},
+			"ipv6_cidr_blocks": []interface{}{resource.AttributeValues["cidr_ipv6"]},
+		}
+		rules = []map[string]interface{}{rule}
+	default:
+		return nil
+	}
+
+	var findings []AuditFinding
+	for _, rule := range rules {
+		if !ruleOpenToWorld(rule) {
+			continue
+		}
+		from, _ := rule["from_port"].(float64)
+		to, _ := rule["to_port"].(float64)
+		findings = append(findings, passFail("Exposure-SG-PublicIngress", resource,
+			from == to && PublicIngressAllowedPorts[from],
+			fmt.Sprintf("ports %v-%v are open to the internet", from, to)))
+	}
+	return findings
+}
+
+// ruleOpenToWorld reports whether an ingress rule allows 0.0.0.0/0 or ::/0
+func ruleOpenToWorld(rule map[string]interface{}) bool {
+	for _, key := range []string{"cidr_blocks", "ipv6_cidr_blocks"} {
+		cidrs, _ := rule[key].([]interface{})
+		for _, cidr := range cidrs {
+			if cidr == "0.0.0.0/0" || cidr == "::/0" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckInstancePublicIP verifies only the bastion host gets a public IP address
+func CheckInstancePublicIP(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_instance" || strings.Contains(resource.Address, "bastion") {
+		return nil
+	}
+
+	public, _ := resource.AttributeValues["associate_public_ip_address"].(bool)
+	if ip, _ := resource.AttributeValues["public_ip"].(string); ip != "" {
+		public = true
+	}
+	return []AuditFinding{passFail("Exposure-EC2-PublicIP", resource, !public,
+		"instance other than the bastion has a public IP address")}
+}
+
+// CheckCloudFrontOriginAccess verifies S3 origins are only reachable through an origin access identity or control
+func CheckCloudFrontOriginAccess(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if resource.Type != "aws_cloudfront_distribution" {
+		return nil
+	}
+	if audit.IsUnknown(resource, "origin") {
+		return []AuditFinding{unknown("Exposure-CloudFront-OriginAccess", resource, "origin")}
+	}
+
+	var findings []AuditFinding
+	for _, origin := range nestedBlocks(resource.AttributeValues, "origin") {
+		if len(nestedBlocks(origin, "custom_origin_config")) > 0 {
+			continue
+		}
+
+		authenticated := false
+		if oac, _ := origin["origin_access_control_id"].(string); oac != "" {
+			authenticated = true
+		}
+		for _, s3Config := range nestedBlocks(origin, "s3_origin_config") {
+			// The identity path is computed, so a present-but-unknown value still counts as configured
+			if _, configured := s3Config["origin_access_identity"]; configured {
+				authenticated = true
+			}
+		}
+
+		findings = append(findings, passFail("Exposure-CloudFront-OriginAccess", resource, authenticated,
+			fmt.Sprintf("origin %v is not protected by an origin access identity or control", origin["origin_id"])))
+	}
+	return findings
+}
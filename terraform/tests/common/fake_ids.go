@@ -0,0 +1,126 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// FakeID is a variable holding a placeholder where a real resource ID, ARN or zone ID belongs
+type FakeID struct {
+	Variable string // Variable name, with the index or key for values inside lists and maps
+	Value    string
+	Reason   string
+}
+
+var (
+	// resourceIDPattern matches EC2-style resource IDs; real ones end in 8 or 17 lowercase hex digits
+	resourceIDPattern = regexp.MustCompile(`^(vpc|subnet|sg|igw|nat|rtb|eni|vpce|eipalloc|i|ami)-(.+)$`)
+	resourceIDSuffix  = regexp.MustCompile(`^([0-9a-f]{8}|[0-9a-f]{17})$`)
+
+	// placeholderAccountPattern matches ARNs in the placeholder accounts tests use
+	placeholderAccountPattern = regexp.MustCompile(`^arn:aws[a-z-]*:[^:]*:[^:]*:(123456789012|123456789|000000000000):`)
+
+	// placeholderZonePattern matches Route53 hosted zone IDs such as Z123456789
+	placeholderZonePattern = regexp.MustCompile(`^Z12345678`)
+
+	// idVariablePattern matches the variables that take IDs or ARNs; names, such as "vpc-flow-logs", are not checked
+	idVariablePattern = regexp.MustCompile(`_(id|ids|arn|arns)$`)
+)
+
+// placeholderSequences are runs that show up in made-up hex IDs but practically never in real ones
+var placeholderSequences = []string{"12345678", "0123456789abcdef", "abcdef0", "fedcba98", "00000000"}
+
+// fakeIDReason returns why a value is a placeholder, or "" if it may be real
+func fakeIDReason(value string) string {
+	if match := resourceIDPattern.FindStringSubmatch(value); match != nil {
+		suffix := match[2]
+		if !resourceIDSuffix.MatchString(suffix) {
+			return fmt.Sprintf("not a valid %s- ID", match[1])
+		}
+		for _, sequence := range placeholderSequences {
+			if strings.Contains(suffix, sequence) {
+				return fmt.Sprintf("placeholder %s- ID", match[1])
+			}
+		}
+	}
+	if placeholderAccountPattern.MatchString(value) {
+		return "ARN in a placeholder account"
+	}
+	if placeholderZonePattern.MatchString(value) {
+		return "placeholder hosted zone ID"
+	}
+	return ""
+}
+
+// FindFakeIDs returns the ID and ARN variables holding placeholders, including inside lists and maps, sorted by
+// variable
+func FindFakeIDs(vars map[string]interface{}) []FakeID {
+	var found []FakeID
+	var walk func(name string, value interface{})
+	walk = func(name string, value interface{}) {
+		switch typed := value.(type) {
+		case string:
+			if reason := fakeIDReason(typed); reason != "" {
+				found = append(found, FakeID{Variable: name, Value: typed, Reason: reason})
+			}
+		case []string:
+			for i, item := range typed {
+				walk(fmt.Sprintf("%s[%d]", name, i), item)
+			}
+		case []interface{}:
+			for i, item := range typed {
+				walk(fmt.Sprintf("%s[%d]", name, i), item)
+			}
+		case map[string]string:
+			for key, item := range typed {
+				walk(name+"."+key, item)
+			}
+		case map[string]interface{}:
+			for key, item := range typed {
+				walk(name+"."+key, item)
+			}
+		}
+	}
+	for name, value := range vars {
+		if idVariablePattern.MatchString(name) {
+			walk(name, value)
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Variable < found[j].Variable })
+	return found
+}
+
+// RejectFakeIDs fails an apply-tier test whose variables hold placeholder IDs such as vpc-12345678. A plan accepts
+// them, but an apply creates what it can and then fails part way through on the resources that reference them,
+// which is slow to diagnose and leaves more to destroy. Tests in other tiers, which only plan, may use them.
+func RejectFakeIDs(t *testing.T, terraformOptions *terraform.Options) {
+	t.Helper()
+
+	if tier, ok := TestTier(t); !ok || tier != TierApply {
+		return
+	}
+	if err := FakeIDsError(terraformOptions); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// FakeIDsError returns an error listing the placeholder IDs in the options' variables, or nil if there are none
+func FakeIDsError(terraformOptions *terraform.Options) error {
+	fakes := FindFakeIDs(terraformOptions.Vars)
+	if len(fakes) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(fakes))
+	for _, fake := range fakes {
+		lines = append(lines, fmt.Sprintf("  %s = %q (%s)", fake.Variable, fake.Value, fake.Reason))
+	}
+	return fmt.Errorf("%s cannot be applied with placeholder IDs; deploy into the fixtures package's resources "+
+		"instead, or plan it in a plan-tier test:\n%s", terraformOptions.TerraformDir, strings.Join(lines, "\n"))
+}
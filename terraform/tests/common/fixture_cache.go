@@ -0,0 +1,171 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"terraform-tests/tfopts"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// Fixture is prerequisite infrastructure applied once per test process and shared by every test that asks for the
+// same module and variables, such as the VPC that several module apply tests deploy into
+type Fixture struct {
+	Key       string
+	Options   *terraform.Options
+	Outputs   map[string]interface{}
+	AppliedBy string // The test that applied it; later tests reuse its outputs
+}
+
+// Output returns one of the fixture's outputs as a string
+func (f *Fixture) Output(name string) string {
+	value, ok := f.Outputs[name]
+	if !ok || value == nil {
+		return ""
+	}
+	if s, isString := value.(string); isString {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+// OutputList returns one of the fixture's list outputs as strings
+func (f *Fixture) OutputList(name string) []string {
+	values, _ := f.Outputs[name].([]interface{})
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		list = append(list, fmt.Sprint(value))
+	}
+	return list
+}
+
+// fixtureEntry is a fixture being applied or already applied; ready is closed once the apply finishes
+type fixtureEntry struct {
+	ready   chan struct{}
+	fixture *Fixture
+	err     error
+}
+
+var (
+	fixturesMutex sync.Mutex
+	fixtures      = map[string]*fixtureEntry{}
+	fixtureStacks []fixtureStack // In apply order, so they are destroyed in reverse
+)
+
+// fixtureStack is a fixture's options and the module path within its working copy
+type fixtureStack struct {
+	options      *terraform.Options
+	relativePath string
+}
+
+// FixtureKey identifies a fixture by its module path and a hash of its variables. Maps are marshalled with sorted
+// keys, so the order the variables were built in does not matter.
+func FixtureKey(modulePath string, vars map[string]interface{}) (string, error) {
+	absolutePath, err := filepath.Abs(modulePath)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(vars)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return fmt.Sprintf("%s@%s", filepath.ToSlash(absolutePath), hex.EncodeToString(sum[:])[:16]), nil
+}
+
+// ApplyFixture applies a module as a fixture, or returns the fixture an earlier test applied with the same module
+// and variables. The variables should not include a prefix or tags, which the fixture's own namespace provides.
+func ApplyFixture(t *testing.T, modulePath string, vars map[string]interface{}) *Fixture {
+	fixture, err := ApplyFixtureE(t, modulePath, vars)
+	require.NoError(t, err, "Failed to apply fixture %s", modulePath)
+	return fixture
+}
+
+// ApplyFixtureE is ApplyFixture returning an error instead of failing the test. Tests asking for a fixture while
+// another test applies it wait for that apply, and a failed apply fails every test that asked for it.
+func ApplyFixtureE(t *testing.T, modulePath string, vars map[string]interface{}) (*Fixture, error) {
+	key, err := FixtureKey(modulePath, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	fixturesMutex.Lock()
+	entry, exists := fixtures[key]
+	if !exists {
+		entry = &fixtureEntry{ready: make(chan struct{})}
+		fixtures[key] = entry
+	}
+	fixturesMutex.Unlock()
+
+	if exists {
+		<-entry.ready
+		if entry.err == nil {
+			t.Logf("Reusing fixture %s applied by %s", key, entry.fixture.AppliedBy)
+		}
+		return entry.fixture, entry.err
+	}
+
+	defer close(entry.ready)
+	entry.fixture, entry.err = applyFixture(t, key, modulePath, vars)
+	return entry.fixture, entry.err
+}
+
+func applyFixture(t *testing.T, key, modulePath string, vars map[string]interface{}) (*Fixture, error) {
+	relativePath, err := filepath.Rel(filepath.Dir(filepath.Dir(modulePath)), modulePath)
+	if err != nil {
+		return nil, err
+	}
+	namespace := NewNamespace(tfopts.NewTestConfig(modulePath), "fixture-"+filepath.Base(modulePath))
+	terraformOptions := namespace.GetModuleTerraformOptions(t, modulePath, vars).Options
+
+	// Outlives the test, so it is destroyed with the other fixtures when the package's tests finish
+	fixturesMutex.Lock()
+	fixtureStacks = append(fixtureStacks, fixtureStack{options: terraformOptions, relativePath: relativePath})
+	fixturesMutex.Unlock()
+
+	t.Logf("Applying fixture %s as %s", key, namespace.Prefix)
+	if _, err = terraform.InitAndApplyE(t, terraformOptions); err != nil {
+		return nil, err
+	}
+	outputs, err := terraform.OutputAllE(t, terraformOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &Fixture{Key: key, Options: terraformOptions, Outputs: outputs, AppliedBy: t.Name()}, nil
+}
+
+// destroyFixtures destroys every fixture the package applied, latest first, since a later fixture may be deployed
+// into an earlier one. Working copies are removed once their fixture is gone.
+func destroyFixtures() error {
+	fixturesMutex.Lock()
+	stacks := fixtureStacks
+	fixtureStacks = nil
+	fixtures = map[string]*fixtureEntry{}
+	fixturesMutex.Unlock()
+
+	var failed []string
+	for i := len(stacks) - 1; i >= 0; i-- {
+		terraformDir := stacks[i].options.TerraformDir
+		fmt.Fprintf(os.Stderr, "Destroying fixture %s\n", terraformDir)
+		err := DestroyWithRetryE(&cleanupT{name: "FixtureCleanup"}, stacks[i].options, DefaultDestroyRetryPolicy())
+		if err == nil {
+			err = removeWorkingCopy(terraformDir, stacks[i].relativePath)
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", terraformDir, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to destroy %d fixtures:\n%s", len(failed), strings.Join(failed, "\n"))
+	}
+	return nil
+}
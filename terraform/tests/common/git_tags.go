@@ -0,0 +1,92 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"terraform-tests/tfopts"
+)
+
+// FindResourcesByTags returns the resources in a region carrying all of the tags. A tag with an empty value matches
+// any value, so {tfopts.GitBranchTag: ""} finds everything any branch's tests created. Like FindResourcesInNamespace, it
+// does not see IAM.
+func FindResourcesByTags(t *testing.T, region string, tags map[string]string) []NamespacedResource {
+	resources, err := FindResourcesByTagsE(t, region, tags)
+	if err != nil {
+		t.Fatalf("Failed to list resources tagged %v in %s: %v", tags, region, err)
+	}
+	return resources
+}
+
+// FindResourcesByTagsE is FindResourcesByTags returning an error instead of failing the test
+func FindResourcesByTagsE(t *testing.T, region string, tags map[string]string) ([]NamespacedResource, error) {
+	filters := make([]string, 0, len(tags))
+	for _, key := range sortedKeys(tags) {
+		if tags[key] == "" {
+			filters = append(filters, "Key="+key)
+		} else {
+			filters = append(filters, fmt.Sprintf("Key=%s,Values=%s", key, tags[key]))
+		}
+	}
+	return getTaggedResourcesE(t, region, filters)
+}
+
+// FindResourcesByBranch returns the resources in a region that tests on a branch created
+func FindResourcesByBranch(t *testing.T, region, branch string) []NamespacedResource {
+	return FindResourcesByTags(t, region, map[string]string{tfopts.GitBranchTag: tfopts.TagValue(branch)})
+}
+
+// ResourceOrigin is the branch, commit, test and run that created a resource, from its tags
+type ResourceOrigin struct {
+	GitBranch string `json:"git_branch"`
+	GitCommit string `json:"git_commit"`
+	TestName  string `json:"test_name,omitempty"`
+	TestRunID string `json:"test_run_id"`
+}
+
+// OriginOf reads a resource's origin from its tags
+func OriginOf(resource NamespacedResource) ResourceOrigin {
+	return ResourceOrigin{
+		GitBranch: resource.Tags[tfopts.GitBranchTag],
+		GitCommit: resource.Tags[tfopts.GitCommitTag],
+		TestName:  resource.Tags[TestNameTag],
+		TestRunID: resource.Tags[tfopts.TestRunIDTag],
+	}
+}
+
+// ResourcesByOrigin is the resources one test run on one commit left behind
+type ResourcesByOrigin struct {
+	ResourceOrigin
+	ARNs []string `json:"arns"`
+}
+
+// GroupByOrigin groups resources by the branch, commit, test and run that created them, sorted by branch, then
+// commit, then test, so a leak report reads as which pull request left what behind
+func GroupByOrigin(resources []NamespacedResource) []ResourcesByOrigin {
+	groups := map[ResourceOrigin][]string{}
+	for _, resource := range resources {
+		origin := OriginOf(resource)
+		groups[origin] = append(groups[origin], resource.ARN)
+	}
+
+	grouped := make([]ResourcesByOrigin, 0, len(groups))
+	for origin, arns := range groups {
+		sort.Strings(arns)
+		grouped = append(grouped, ResourcesByOrigin{ResourceOrigin: origin, ARNs: arns})
+	}
+	sort.Slice(grouped, func(i, j int) bool {
+		a, b := grouped[i].ResourceOrigin, grouped[j].ResourceOrigin
+		if a.GitBranch != b.GitBranch {
+			return a.GitBranch < b.GitBranch
+		}
+		if a.GitCommit != b.GitCommit {
+			return a.GitCommit < b.GitCommit
+		}
+		if a.TestName != b.TestName {
+			return a.TestName < b.TestName
+		}
+		return a.TestRunID < b.TestRunID
+	})
+	return grouped
+}
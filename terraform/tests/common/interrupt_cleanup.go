@@ -0,0 +1,260 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"terraform-tests/tfopts"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// RetainedStack describes a stack that was still deployed when the test run was interrupted, as written to
+// RETAINED_STATE_DIR so a later step can destroy it
+type RetainedStack struct {
+	TerraformDir  string                 `json:"terraform_dir"`
+	StateFile     string                 `json:"state_file,omitempty"`     // Copy of local state, next to the manifest
+	BackendConfig map[string]interface{} `json:"backend_config,omitempty"` // For a stack whose state is in a backend
+	Vars          map[string]interface{} `json:"vars"`
+	Reason        string                 `json:"reason"`
+	RetainedAt    string                 `json:"retained_at"`
+}
+
+// localStateFile is where terraform keeps state for module tests, which run without a backend
+const localStateFile = "terraform.tfstate"
+
+// cleanupOnce keeps a cancelled job that is sent more than one signal from cleaning up twice
+var cleanupOnce sync.Once
+
+// RunWithInterruptCleanup runs the tests in a package and, if the run is interrupted with SIGINT or SIGTERM (as
+// when CI cancels a job), destroys any stack still holding resources before exiting. Fixtures shared by the tests
+// are destroyed once they finish, and a fixture that cannot be destroyed fails the run. Call it from TestMain:
+//
+//	func TestMain(m *testing.M) { os.Exit(common.RunWithInterruptCleanup(m)) }
+func RunWithInterruptCleanup(m *testing.M) int {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	go func() {
+		received := <-signals
+		cleanupTrackedStacks(fmt.Sprintf("received %s", received))
+		os.Exit(1)
+	}()
+
+	defer RecoverAndCleanup()
+	code := m.Run()
+	if err := destroyFixtures(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		code = 1
+	}
+	return code
+}
+
+// RecoverAndCleanup destroys tracked stacks before letting a panic continue. Test goroutines already run their
+// cleanups when they panic, but a panic on any other goroutine ends the process immediately, so defer this at the
+// top of goroutines the harness starts.
+func RecoverAndCleanup() {
+	if recovered := recover(); recovered != nil {
+		cleanupTrackedStacks(fmt.Sprintf("panic: %v", recovered))
+		panic(recovered)
+	}
+}
+
+// cleanupTrackedStacks retains and then destroys every stack tfopts tracked whose state still holds resources. It
+// runs at most once, as a cancelled job may be sent more than one signal.
+func cleanupTrackedStacks(reason string) {
+	cleanupOnce.Do(func() {
+		stacks := tfopts.TrackedStacks()
+
+		fmt.Fprintf(os.Stderr, "Test run interrupted (%s), cleaning up deployed stacks\n", reason)
+
+		// Retain every stack first: it is quick, and CI may kill the process before the destroys finish
+		var deployed []*terraform.Options
+		for _, terraformOptions := range stacks {
+			if !stackHasResources(terraformOptions) {
+				continue
+			}
+			deployed = append(deployed, terraformOptions)
+			if err := retainStack(terraformOptions, reason); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to retain state for %s: %v\n", terraformOptions.TerraformDir, err)
+			}
+		}
+
+		for _, terraformOptions := range deployed {
+			fmt.Fprintf(os.Stderr, "Destroying %s\n", terraformOptions.TerraformDir)
+			err := initBackend(terraformOptions)
+			if err == nil {
+				err = DestroyWithRetryE(&cleanupT{name: "InterruptCleanup"}, terraformOptions, DefaultDestroyRetryPolicy())
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to destroy %s: %v\n", terraformOptions.TerraformDir, err)
+			}
+		}
+	})
+}
+
+// stackHasResources reports whether a tracked stack's state still records resources. Module stacks keep local
+// state; a stack with a backend, such as the root configuration's, is read from the backend. A backend that cannot
+// be read counts as holding resources, since destroying an empty stack does no harm.
+func stackHasResources(terraformOptions *terraform.Options) bool {
+	if len(terraformOptions.BackendConfig) == 0 {
+		return stateHasResources(filepath.Join(terraformOptions.TerraformDir, localStateFile))
+	}
+	if err := initBackend(terraformOptions); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read the state of %s: %v\n", terraformOptions.TerraformDir, err)
+		return true
+	}
+	resources, err := terraform.RunTerraformCommandAndGetStdoutE(&cleanupT{name: "InterruptCleanup"},
+		terraformOptions, "state", "list")
+	return err != nil || strings.TrimSpace(resources) != ""
+}
+
+// initBackend points a stack's directory at its own backend state. Tests share the root directory, each with its
+// own state key, so whichever test initialized it last may have left it pointing at another test's state.
+func initBackend(terraformOptions *terraform.Options) error {
+	if len(terraformOptions.BackendConfig) == 0 {
+		return nil
+	}
+	initOptions, err := terraformOptions.Clone()
+	if err != nil {
+		return err
+	}
+	initOptions.Reconfigure = true
+	_, err = terraform.InitE(&cleanupT{name: "InterruptCleanup"}, initOptions)
+	return err
+}
+
+// stateHasResources reports whether a terraform state file exists and records at least one resource
+func stateHasResources(path string) bool {
+	content, err := os.ReadFile(path) // #nosec G304 -- path is a test's own terraform directory
+	if err != nil {
+		return false
+	}
+	var state struct {
+		Resources []json.RawMessage `json:"resources"`
+	}
+	return json.Unmarshal(content, &state) == nil && len(state.Resources) > 0
+}
+
+// retainedNamePattern matches characters that should not appear in a retained stack's file names
+var retainedNamePattern = regexp.MustCompile(`[^A-Za-z0-9-]+`)
+
+// retainStack writes a manifest to RETAINED_STATE_DIR, if it is set, along with a copy of the stack's local state.
+// A stack with a backend keeps its state there, so the manifest records the backend configuration instead.
+func retainStack(terraformOptions *terraform.Options, reason string) error {
+	dir := os.Getenv("RETAINED_STATE_DIR")
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+
+	absoluteDir, err := filepath.Abs(terraformOptions.TerraformDir)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s", retainedNamePattern.ReplaceAllString(filepath.Base(absoluteDir), "-"), tfopts.NewUniqueID())
+
+	stack := RetainedStack{
+		TerraformDir:  absoluteDir,
+		BackendConfig: terraformOptions.BackendConfig,
+		Vars:          terraformOptions.Vars,
+		Reason:        reason,
+		RetainedAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	if len(stack.BackendConfig) == 0 {
+		state, readErr := os.ReadFile(filepath.Join(absoluteDir, localStateFile))
+		if readErr != nil {
+			return readErr
+		}
+		stack.StateFile = name + ".tfstate"
+		if err = os.WriteFile(filepath.Join(dir, stack.StateFile), state, 0o600); err != nil {
+			return err
+		}
+	}
+
+	manifest, err := json.MarshalIndent(stack, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Retained state for %s in %s\n", absoluteDir, dir)
+	return os.WriteFile(filepath.Join(dir, name+".json"), manifest, 0o600)
+}
+
+// DestroyRetainedStacks destroys every stack recorded in a RETAINED_STATE_DIR, restoring its retained state into
+// the terraform directory when the original is gone. Manifests are removed once their stack is destroyed.
+func DestroyRetainedStacks(t *testing.T, dir string) {
+	manifests, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+
+	for _, manifestPath := range manifests {
+		content, readErr := os.ReadFile(manifestPath) // #nosec G304 -- manifests are written by retainStack
+		require.NoError(t, readErr)
+
+		var stack RetainedStack
+		require.NoError(t, json.Unmarshal(content, &stack), "Invalid manifest %s", manifestPath)
+
+		t.Run(filepath.Base(manifestPath), func(t *testing.T) {
+			statePath := filepath.Join(stack.TerraformDir, localStateFile)
+			if stack.StateFile != "" && !stateHasResources(statePath) {
+				state, stateErr := os.ReadFile(filepath.Join(dir, stack.StateFile)) // #nosec G304
+				require.NoError(t, stateErr)
+				require.NoError(t, os.WriteFile(statePath, state, 0o600))
+			}
+
+			t.Logf("Destroying %s, retained at %s (%s)", stack.TerraformDir, stack.RetainedAt, stack.Reason)
+			terraformOptions := &terraform.Options{
+				TerraformDir:    stack.TerraformDir,
+				TerraformBinary: "terraform",
+				Vars:            stack.Vars,
+				BackendConfig:   stack.BackendConfig,
+				Reconfigure:     len(stack.BackendConfig) > 0,
+			}
+			terraform.Init(t, terraformOptions)
+			CleanupResources(t, terraformOptions)
+			require.NoError(t, os.Remove(manifestPath))
+		})
+	}
+}
+
+// cleanupT lets terratest run outside a test, once the test that owned a stack can no longer be used
+type cleanupT struct {
+	name string
+}
+
+// Failures are only reported: there is no test left to stop, and the remaining stacks should still be destroyed.
+func (c *cleanupT) Fail() {}
+
+func (c *cleanupT) FailNow() {}
+
+func (c *cleanupT) Fatal(args ...interface{}) {
+	c.Error(args...)
+}
+
+func (c *cleanupT) Fatalf(format string, args ...interface{}) {
+	c.Errorf(format, args...)
+}
+
+func (c *cleanupT) Error(args ...interface{}) {
+	fmt.Fprintln(os.Stderr, args...)
+}
+
+func (c *cleanupT) Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+func (c *cleanupT) Name() string {
+	return c.name
+}
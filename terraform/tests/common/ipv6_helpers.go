@@ -0,0 +1,137 @@
+package common
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/awsval"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// IPv6Variable is the input variable a module declares once it can deploy dual-stack. Dual-stack tests skip
+// modules that do not declare it yet.
+const IPv6Variable = "enable_ipv6"
+
+// ModuleSupportsIPv6 reports whether a module declares IPv6Variable
+func ModuleSupportsIPv6(modulePath string) bool {
+	return moduleDeclaresVariable(modulePath, IPv6Variable)
+}
+
+// AssertPlannedDualStack checks that a plan gives the VPC an Amazon-provided IPv6 block, gives every subnet IPv6
+// addresses on creation, and routes private IPv6 egress through an egress-only internet gateway instead of a NAT
+// gateway
+func AssertPlannedDualStack(t *testing.T, plan *terraform.PlanStruct) {
+	vpcs := GetPlannedResourcesByType(plan, "aws_vpc")
+	require.NotEmpty(t, vpcs, "Expected a VPC in the plan")
+	for _, vpc := range vpcs {
+		assert.Equal(t, true, vpc.AttributeValues["assign_generated_ipv6_cidr_block"],
+			"%s should be assigned an IPv6 CIDR block", vpc.Address)
+	}
+
+	for _, subnet := range GetPlannedResourcesByType(plan, "aws_subnet") {
+		assert.Equal(t, true, subnet.AttributeValues["assign_ipv6_address_on_creation"],
+			"%s should assign IPv6 addresses on creation", subnet.Address)
+	}
+
+	assert.NotEmpty(t, GetPlannedResourcesByType(plan, "aws_egress_only_internet_gateway"),
+		"Private subnets should reach the IPv6 internet through an egress-only internet gateway")
+	assert.Empty(t, GetPlannedResourcesByType(plan, "aws_nat_gateway"),
+		"IPv6 egress needs no NAT gateway")
+}
+
+// AssertVPCHasIPv6CIDR fails the test unless the VPC has an associated IPv6 CIDR block, and returns it
+func AssertVPCHasIPv6CIDR(t *testing.T, vpcID, region string) string {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	result, err := ec2.NewFromConfig(cfg).DescribeVpcs(context.Background(), &ec2.DescribeVpcsInput{
+		VpcIds: []string{vpcID},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Vpcs, 1)
+
+	for _, association := range result.Vpcs[0].Ipv6CidrBlockAssociationSet {
+		if association.Ipv6CidrBlockState != nil &&
+			association.Ipv6CidrBlockState.State == types.VpcCidrBlockStateCodeAssociated {
+			return aws.ToString(association.Ipv6CidrBlock)
+		}
+	}
+	require.Failf(t, "VPC has no IPv6 CIDR block", "VPC %s has no associated IPv6 CIDR block", vpcID)
+	return ""
+}
+
+// AssertSubnetHasIPv6CIDR checks that a subnet has an associated IPv6 CIDR block within the VPC's and assigns IPv6
+// addresses on creation, and returns the block
+func AssertSubnetHasIPv6CIDR(t *testing.T, subnetID, region, vpcIPv6CIDR string) string {
+	subnet := awsval.GetSubnet(t, subnetID, region)
+	assert.True(t, aws.ToBool(subnet.AssignIpv6AddressOnCreation),
+		"Subnet %s should assign IPv6 addresses on creation", subnetID)
+
+	_, vpcNetwork, err := net.ParseCIDR(vpcIPv6CIDR)
+	require.NoError(t, err, "VPC IPv6 CIDR block %q", vpcIPv6CIDR)
+
+	for _, association := range subnet.Ipv6CidrBlockAssociationSet {
+		if association.Ipv6CidrBlockState == nil ||
+			association.Ipv6CidrBlockState.State != types.SubnetCidrBlockStateCodeAssociated {
+			continue
+		}
+		cidr := aws.ToString(association.Ipv6CidrBlock)
+		subnetIP, _, parseErr := net.ParseCIDR(cidr)
+		require.NoError(t, parseErr)
+		assert.True(t, vpcNetwork.Contains(subnetIP),
+			"Subnet %s IPv6 block %s should be within the VPC's %s", subnetID, cidr, vpcIPv6CIDR)
+		return cidr
+	}
+	assert.Failf(t, "Subnet has no IPv6 CIDR block", "Subnet %s has no associated IPv6 CIDR block", subnetID)
+	return ""
+}
+
+// AssertEgressOnlyInternetGateway fails the test unless exactly one egress-only internet gateway is attached to
+// the VPC, and returns its ID
+func AssertEgressOnlyInternetGateway(t *testing.T, vpcID, region string) string {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	// Egress-only gateways cannot be filtered by VPC, so list them all and match their attachments
+	var gatewayIDs []string
+	paginator := ec2.NewDescribeEgressOnlyInternetGatewaysPaginator(ec2.NewFromConfig(cfg),
+		&ec2.DescribeEgressOnlyInternetGatewaysInput{})
+	for paginator.HasMorePages() {
+		page, pageErr := paginator.NextPage(context.Background())
+		require.NoError(t, pageErr)
+		for _, gateway := range page.EgressOnlyInternetGateways {
+			for _, attachment := range gateway.Attachments {
+				if aws.ToString(attachment.VpcId) == vpcID && attachment.State == types.AttachmentStatusAttached {
+					gatewayIDs = append(gatewayIDs, aws.ToString(gateway.EgressOnlyInternetGatewayId))
+				}
+			}
+		}
+	}
+	require.Len(t, gatewayIDs, 1, "Expected one egress-only internet gateway attached to VPC %s", vpcID)
+	return gatewayIDs[0]
+}
+
+// AssertIPv6DefaultRoute checks that the route table associated with a subnet sends ::/0 to the gateway, which is
+// an internet gateway for public subnets and an egress-only internet gateway for private ones
+func AssertIPv6DefaultRoute(t *testing.T, subnetID, region, gatewayID string) {
+	for _, route := range awsval.SubnetRouteTable(t, subnetID, region).Routes {
+		if aws.ToString(route.DestinationIpv6CidrBlock) != "::/0" {
+			continue
+		}
+		target := aws.ToString(route.EgressOnlyInternetGatewayId)
+		if target == "" {
+			target = aws.ToString(route.GatewayId)
+		}
+		assert.Equal(t, gatewayID, target, "Subnet %s should route ::/0 to %s", subnetID, gatewayID)
+		return
+	}
+	assert.Failf(t, "No IPv6 default route", "Subnet %s has no ::/0 route", subnetID)
+}
@@ -0,0 +1,164 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/cleanup"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// NetworkLeak is a billable network resource left allocated after a destroy
+type NetworkLeak struct {
+	Kind   string // "eip", "eni", "vpc-endpoint-eni" or "vpc-endpoint"
+	ID     string
+	Detail string
+}
+
+// RegisterNetworkCleanup destroys a networking configuration when the test finishes and then fails the test if any
+// Elastic IPs, detached ENIs or VPC endpoints belonging to the prefix are still allocated
+func RegisterNetworkCleanup(t *testing.T, terraformOptions *terraform.Options, region, prefix string) {
+	// Capture the VPC before it is destroyed so ENIs left inside it can be found afterwards
+	var vpcID string
+	cleanup.Func(t, cleanup.BeforeDestroy, "capture VPC of "+terraformOptions.TerraformDir, func(t *testing.T) error {
+		vpcID, _ = terraform.OutputE(t, terraformOptions, "vpc_id")
+		return nil
+	})
+	RegisterDestroy(t, terraformOptions)
+	cleanup.Func(t, cleanup.AfterDestroy, "check network leaks for "+prefix, func(t *testing.T) error {
+		return CheckNetworkLeaksE(t, region, prefix, vpcID)
+	})
+}
+
+// AssertNoNetworkLeaks fails the test if network resources tied to the prefix or VPC remain allocated
+func AssertNoNetworkLeaks(t *testing.T, region, prefix, vpcID string) {
+	require.NoError(t, CheckNetworkLeaksE(t, region, prefix, vpcID))
+}
+
+// CheckNetworkLeaksE returns an error listing the network resources tied to the prefix or VPC that remain
+// allocated. ENI and endpoint deletion is eventually consistent, so the check retries for a short while first.
+func CheckNetworkLeaksE(t *testing.T, region, prefix, vpcID string) error {
+	var leaks []NetworkLeak
+	_, err := retry.DoWithRetryE(t, "Check for leaked network resources", 6, 10*time.Second, func() (string, error) {
+		var findErr error
+		if leaks, findErr = FindNetworkLeaksE(t, region, prefix, vpcID); findErr != nil {
+			return "", retry.FatalError{Underlying: findErr}
+		}
+		if len(leaks) > 0 {
+			return "", fmt.Errorf("%d network resources still allocated", len(leaks))
+		}
+		return "", nil
+	})
+	if err == nil {
+		t.Logf("No leaked Elastic IPs, ENIs or VPC endpoints for prefix %s", prefix)
+		return nil
+	}
+	if len(leaks) == 0 {
+		return err
+	}
+
+	descriptions := make([]string, 0, len(leaks))
+	for _, leak := range leaks {
+		descriptions = append(descriptions, fmt.Sprintf("%s %s: %s", leak.Kind, leak.ID, leak.Detail))
+	}
+	return fmt.Errorf("leaked after destroy: %s", strings.Join(descriptions, "; "))
+}
+
+// FindNetworkLeaks returns the Elastic IPs, detached ENIs and VPC endpoints still allocated for a prefix or VPC
+func FindNetworkLeaks(t *testing.T, region, prefix, vpcID string) []NetworkLeak {
+	leaks, err := FindNetworkLeaksE(t, region, prefix, vpcID)
+	require.NoError(t, err)
+	return leaks
+}
+
+// FindNetworkLeaksE is FindNetworkLeaks returning an error instead of failing the test
+func FindNetworkLeaksE(t *testing.T, region, prefix, vpcID string) ([]NetworkLeak, error) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := ec2.NewFromConfig(cfg)
+	ctx := context.Background()
+	var leaks []NetworkLeak
+
+	addresses, err := svc.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{
+		Filters: []types.Filter{{Name: aws.String("tag:Name"), Values: []string{prefix + "-*"}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, address := range addresses.Addresses {
+		leaks = append(leaks, NetworkLeak{
+			Kind:   "eip",
+			ID:     aws.ToString(address.AllocationId),
+			Detail: fmt.Sprintf("%s is still allocated", aws.ToString(address.PublicIp)),
+		})
+	}
+
+	// ENIs are not tagged, so look for detached ones in the VPC and ones whose description names the prefix
+	interfaceFilters := [][]types.Filter{
+		{
+			{Name: aws.String("status"), Values: []string{"available"}},
+			{Name: aws.String("description"), Values: []string{"*" + prefix + "-*"}},
+		},
+	}
+	if vpcID != "" {
+		interfaceFilters = append(interfaceFilters, []types.Filter{{Name: aws.String("vpc-id"), Values: []string{vpcID}}})
+	}
+
+	seen := map[string]bool{}
+	for _, filters := range interfaceFilters {
+		interfaces, describeErr := svc.DescribeNetworkInterfaces(ctx, &ec2.DescribeNetworkInterfacesInput{Filters: filters})
+		if describeErr != nil {
+			return nil, describeErr
+		}
+
+		for _, eni := range interfaces.NetworkInterfaces {
+			id := aws.ToString(eni.NetworkInterfaceId)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			kind := "eni"
+			if eni.InterfaceType == types.NetworkInterfaceTypeVpcEndpoint {
+				kind = "vpc-endpoint-eni"
+			}
+			leaks = append(leaks, NetworkLeak{
+				Kind:   kind,
+				ID:     id,
+				Detail: fmt.Sprintf("%s (%s)", aws.ToString(eni.Description), eni.Status),
+			})
+		}
+	}
+
+	endpoints, err := svc.DescribeVpcEndpoints(ctx, &ec2.DescribeVpcEndpointsInput{
+		Filters: []types.Filter{{Name: aws.String("tag:Name"), Values: []string{prefix + "-*"}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, endpoint := range endpoints.VpcEndpoints {
+		if strings.EqualFold(string(endpoint.State), "deleted") {
+			continue
+		}
+		leaks = append(leaks, NetworkLeak{
+			Kind:   "vpc-endpoint",
+			ID:     aws.ToString(endpoint.VpcEndpointId),
+			Detail: fmt.Sprintf("%s is %s", aws.ToString(endpoint.ServiceName), endpoint.State),
+		})
+	}
+
+	return leaks, nil
+}
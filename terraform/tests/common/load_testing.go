@@ -0,0 +1,336 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/tfopts"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/require"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Load tests drive traffic with vegeta (github.com/tsenart/vegeta), which paces requests at a constant rate however
+// slowly the API answers
+const (
+	DefaultLoadRate     = 10 // Requests per second
+	DefaultLoadDuration = time.Minute
+	LoadRequestTimeout  = 30 * time.Second // API Gateway gives up on the Lambda after 29 seconds
+	LoadScaleOutTimeout = 5 * time.Minute  // Target tracking alarms need three one-minute datapoints
+)
+
+// LoadSLO is the latency and error budget an endpoint must meet under load
+type LoadSLO struct {
+	P95          time.Duration
+	MaxErrorRate float64 // Share of requests that time out or get a 5xx, from 0 to 1
+}
+
+// The SLOs a campaign launch is held to. Submissions write the stakeholder and endorsement and queue a
+// verification email, so they get more time than reads of the campaign.
+var (
+	EndorsementSubmitSLO = LoadSLO{P95: 2 * time.Second, MaxErrorRate: 0.01}
+	CampaignReadSLO      = LoadSLO{P95: time.Second, MaxErrorRate: 0.01}
+)
+
+// LoadTarget is one request of an attack
+type LoadTarget struct {
+	Method string
+	URL    string
+	Body   []byte
+	Header http.Header
+}
+
+// LoadResult is the outcome of one request
+type LoadResult struct {
+	Method  string
+	URL     string
+	Code    int // 0 when no response arrived
+	Latency time.Duration
+	Error   string
+}
+
+// LoadSummary is how one endpoint fared under load
+type LoadSummary struct {
+	Requests  int
+	Errors    int // Timeouts, connection failures and 5xx responses
+	Throttled int // 429 responses, from the API's rate limiting
+	P95       time.Duration
+	Codes     map[int]int
+}
+
+// ErrorRate is the share of the endpoint's requests that failed
+func (s LoadSummary) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+// RunLoadE attacks the targets at rate requests per second for the duration, cycling through them in order, and
+// returns the result of each request
+func RunLoadE(t *testing.T, targets []LoadTarget, rate int, duration time.Duration) ([]LoadResult, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("a load test needs at least one target")
+	}
+	attackTargets := make([]vegeta.Target, 0, len(targets))
+	for _, target := range targets {
+		attackTargets = append(attackTargets, vegeta.Target{
+			Method: target.Method,
+			URL:    target.URL,
+			Body:   target.Body,
+			Header: target.Header,
+		})
+	}
+
+	attacker := vegeta.NewAttacker(vegeta.Timeout(LoadRequestTimeout))
+	pacer := vegeta.Rate{Freq: rate, Per: time.Second}
+	var results []LoadResult
+	for result := range attacker.Attack(vegeta.NewStaticTargeter(attackTargets...), pacer, duration, t.Name()) {
+		results = append(results, LoadResult{
+			Method:  result.Method,
+			URL:     result.URL,
+			Code:    int(result.Code),
+			Latency: result.Latency,
+			Error:   result.Error,
+		})
+	}
+	return results, nil
+}
+
+// numericPathSegment matches an ID in a URL path, which LoadEndpoint replaces so every campaign is one endpoint
+var numericPathSegment = regexp.MustCompile(`/[0-9]+/`)
+
+// LoadEndpoint names the endpoint a result was for by its method and path, such as "GET /api/campaigns/{id}/"
+func LoadEndpoint(result LoadResult) string {
+	path := result.URL
+	if parsed, err := url.Parse(result.URL); err == nil {
+		path = parsed.Path
+	}
+	return result.Method + " " + numericPathSegment.ReplaceAllString(path, "/{id}/")
+}
+
+// SummarizeLoad groups results by LoadEndpoint and summarizes each endpoint's latency and errors
+func SummarizeLoad(results []LoadResult) map[string]LoadSummary {
+	latencies := map[string][]time.Duration{}
+	summaries := map[string]LoadSummary{}
+	for _, result := range results {
+		endpoint := LoadEndpoint(result)
+		summary := summaries[endpoint]
+		if summary.Codes == nil {
+			summary.Codes = map[int]int{}
+		}
+		summary.Requests++
+		summary.Codes[result.Code]++
+		switch {
+		case result.Code == 0 || result.Code >= 500:
+			summary.Errors++
+		case result.Code == http.StatusTooManyRequests:
+			summary.Throttled++
+		}
+		summaries[endpoint] = summary
+		latencies[endpoint] = append(latencies[endpoint], result.Latency)
+	}
+
+	for endpoint, summary := range summaries {
+		summary.P95 = percentile(latencies[endpoint], 0.95)
+		summaries[endpoint] = summary
+	}
+	return summaries
+}
+
+// percentile returns the nearest-rank percentile of a set of latencies
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(math.Ceil(p*float64(len(sorted))))-1]
+}
+
+// CheckLoadSLO checks an endpoint's summary against its SLO, with one finding for latency and one for errors
+func CheckLoadSLO(endpoint string, summary LoadSummary, slo LoadSLO) []AuditFinding {
+	if summary.Requests == 0 {
+		return []AuditFinding{{Control: "Load-Requests", Resource: endpoint, Detail: "no requests were made"}}
+	}
+	return []AuditFinding{
+		{Control: "Load-Latency", Resource: endpoint, Passed: summary.P95 <= slo.P95,
+			Detail: fmt.Sprintf("p95 latency is %s, expected at most %s", summary.P95, slo.P95)},
+		{Control: "Load-ErrorRate", Resource: endpoint, Passed: summary.ErrorRate() <= slo.MaxErrorRate,
+			Detail: fmt.Sprintf("%d of %d requests failed (%.2f%%), expected at most %.2f%%; status codes %v",
+				summary.Errors, summary.Requests, 100*summary.ErrorRate(), 100*slo.MaxErrorRate, summary.Codes)},
+	}
+}
+
+// CSRFCredentials are the cookie and header Django checks on an unsafe request
+type CSRFCredentials struct {
+	Cookie string // The csrftoken cookie, as a Cookie header value
+	Token  string // The X-CSRFToken header
+}
+
+// GetCSRFCredentialsE asks the API for a CSRF token, as the frontend does before submitting a form
+func GetCSRFCredentialsE(client *http.Client, apiURL string) (*CSRFCredentials, error) {
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, apiURL+"/api/csrf-token/", nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var body struct {
+		CSRFToken string `json:"csrf_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("unexpected response from %s/api/csrf-token/: %w", apiURL, err)
+	}
+	for _, cookie := range response.Cookies() {
+		if cookie.Name == "csrftoken" {
+			return &CSRFCredentials{Cookie: cookie.Name + "=" + cookie.Value, Token: body.CSRFToken}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s/api/csrf-token/ set no csrftoken cookie", apiURL)
+}
+
+// CampaignLaunchTargets builds the traffic of a campaign launch: reads of the campaign list and the campaign,
+// with every fifth request submitting an endorsement. Each submission is from its own SES mailbox simulator
+// address, so verification emails are accepted without reaching anyone or counting against the account's
+// reputation.
+func CampaignLaunchTargets(t *testing.T, apiURL, origin string, campaignID, requests int,
+	csrf *CSRFCredentials) []LoadTarget {
+	runID := tfopts.NewUniqueID()
+	postHeader := http.Header{}
+	postHeader.Set("Content-Type", "application/json")
+	postHeader.Set("Origin", origin)
+	postHeader.Set("Referer", origin+"/")
+	postHeader.Set("Cookie", csrf.Cookie)
+	postHeader.Set("X-CSRFToken", csrf.Token)
+	formStart := time.Now().UTC().Add(-time.Minute).Format(time.RFC3339)
+
+	targets := make([]LoadTarget, 0, requests)
+	for i := 0; i < requests; i++ {
+		if i%5 != 4 {
+			path := "/api/campaigns/"
+			if i%2 == 1 {
+				path = fmt.Sprintf("/api/campaigns/%d/", campaignID)
+			}
+			targets = append(targets, LoadTarget{Method: http.MethodGet, URL: apiURL + path})
+			continue
+		}
+
+		user, domain, _ := strings.Cut(SESMailboxSimulatorSuccess, "@")
+		body, err := json.Marshal(map[string]interface{}{
+			"campaign_id": campaignID,
+			"stakeholder": map[string]interface{}{
+				"first_name":     "Load",
+				"last_name":      "Test",
+				"email":          fmt.Sprintf("%s+load-%s-%d@%s", user, runID, i, domain),
+				"street_address": "1 Test Street",
+				"city":           "Annapolis",
+				"state":          "MD",
+				"zip_code":       "21401",
+				"type":           "individual",
+			},
+			"statement":      "Submitted by an infrastructure load test",
+			"public_display": false,
+			"terms_accepted": true,
+			"form_metadata":  map[string]interface{}{"form_start_time": formStart},
+		})
+		require.NoError(t, err)
+		targets = append(targets, LoadTarget{Method: http.MethodPost, URL: apiURL + "/api/endorsements/",
+			Body: body, Header: postHeader})
+	}
+	return targets
+}
+
+// GetServiceDesiredCount returns how many tasks an ECS service wants
+func GetServiceDesiredCount(t *testing.T, cluster, service, region string) int {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	result, err := ecs.NewFromConfig(cfg).DescribeServices(context.Background(), &ecs.DescribeServicesInput{
+		Cluster:  aws.String(cluster),
+		Services: []string{service},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Services, 1, "Expected service %s in %s", service, cluster)
+	return int(result.Services[0].DesiredCount)
+}
+
+// WaitForScaleOut polls an ECS service until auto scaling raises its desired count above from, failing the test if
+// it does not within the given time
+func WaitForScaleOut(t *testing.T, cluster, service, region string, from int, within time.Duration) {
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("Wait for %s to scale out from %d tasks", service, from),
+		int(within/(15*time.Second))+1, 15*time.Second, func() (string, error) {
+			if desired := GetServiceDesiredCount(t, cluster, service, region); desired <= from {
+				return "", fmt.Errorf("%s still wants %d tasks", service, desired)
+			}
+			return "", nil
+		})
+	require.NoError(t, err, "Auto scaling should scale %s out within %s of the load", service, within)
+}
+
+// DeployedLoadTest is the environment a campaign launch is rehearsed against and how hard to drive it, from
+// E2E_DOMAIN and E2E_LOAD_* variables. Cluster and Service are set when scale-out should be checked.
+type DeployedLoadTest struct {
+	Region     string
+	Domain     string
+	CampaignID int
+	Rate       int
+	Duration   time.Duration
+	Cluster    string
+	Service    string
+}
+
+// GetDeployedLoadTest loads the load test to run, skipping the test unless E2E_LOAD_CAMPAIGN_ID names a campaign
+// to submit endorsements to. Load tests write to the environment, so they never run from E2E_DOMAIN alone.
+func GetDeployedLoadTest(t *testing.T) *DeployedLoadTest {
+	RequireTier(t, TierE2E)
+
+	domain, campaign := os.Getenv("E2E_DOMAIN"), os.Getenv("E2E_LOAD_CAMPAIGN_ID")
+	if domain == "" || campaign == "" {
+		t.Skip("Skipping end-to-end test - set E2E_DOMAIN and E2E_LOAD_CAMPAIGN_ID to load test a campaign of a " +
+			"non-production stack")
+	}
+	deployed := &DeployedLoadTest{
+		Region:   os.Getenv("AWS_REGION"),
+		Domain:   domain,
+		Rate:     DefaultLoadRate,
+		Duration: DefaultLoadDuration,
+		Cluster:  os.Getenv("E2E_ECS_CLUSTER"),
+		Service:  os.Getenv("E2E_ECS_SERVICE"),
+	}
+	var err error
+	deployed.CampaignID, err = strconv.Atoi(campaign)
+	require.NoError(t, err, "E2E_LOAD_CAMPAIGN_ID should be a campaign ID")
+	if rate := os.Getenv("E2E_LOAD_RATE"); rate != "" {
+		deployed.Rate, err = strconv.Atoi(rate)
+		require.NoError(t, err, "E2E_LOAD_RATE should be requests per second")
+	}
+	if duration := os.Getenv("E2E_LOAD_DURATION"); duration != "" {
+		deployed.Duration, err = time.ParseDuration(duration)
+		require.NoError(t, err, "E2E_LOAD_DURATION should be a duration such as 5m")
+	}
+	if deployed.Region == "" {
+		deployed.Region = "us-east-1"
+	}
+	return deployed
+}
@@ -0,0 +1,207 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// LockFileName is the dependency lock file terraform init writes next to a configuration
+const LockFileName = ".terraform.lock.hcl"
+
+// LockFilePlatforms are the platforms CI and developers run Terraform on. A lock file needs a hash for each, or
+// init fails with a provider checksum mismatch on the platforms it lacks.
+var LockFilePlatforms = []string{"linux_amd64", "darwin_arm64"}
+
+// LockedProvider is a provider as a lock file pins it
+type LockedProvider struct {
+	Source   string   // Such as "registry.terraform.io/hashicorp/aws"
+	Version  string   // The exact version selected
+	H1Hashes []string // Hashes of the unpacked package, one for each platform it was locked on
+	ZHHashes []string // Hashes of the package archives the registry lists, for every platform it publishes
+}
+
+// ParseLockFile reads the providers a dependency lock file pins
+func ParseLockFile(path string) ([]LockedProvider, error) {
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, diags.Error())
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("unexpected body type in %s", path)
+	}
+
+	var providers []LockedProvider
+	for _, block := range body.Blocks {
+		if block.Type != "provider" || len(block.Labels) == 0 {
+			continue
+		}
+		provider := LockedProvider{Source: block.Labels[0]}
+
+		if attribute, found := block.Body.Attributes["version"]; found {
+			value, valueDiags := attribute.Expr.Value(nil)
+			if valueDiags.HasErrors() || !value.Type().Equals(cty.String) {
+				return nil, fmt.Errorf("version of %s in %s is not a string", provider.Source, path)
+			}
+			provider.Version = value.AsString()
+		}
+		if attribute, found := block.Body.Attributes["hashes"]; found {
+			value, valueDiags := attribute.Expr.Value(nil)
+			if valueDiags.HasErrors() || !value.CanIterateElements() {
+				return nil, fmt.Errorf("hashes of %s in %s are not a list", provider.Source, path)
+			}
+			for _, hash := range value.AsValueSlice() {
+				if !hash.Type().Equals(cty.String) || hash.IsNull() {
+					return nil, fmt.Errorf("hashes of %s in %s are not all strings", provider.Source, path)
+				}
+				switch text := hash.AsString(); {
+				case strings.HasPrefix(text, "h1:"):
+					provider.H1Hashes = append(provider.H1Hashes, text)
+				case strings.HasPrefix(text, "zh:"):
+					provider.ZHHashes = append(provider.ZHHashes, text)
+				}
+			}
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// LockFileProblems returns why providers locked in a lock file would fail init anywhere: a provider without a
+// version, or without the h1 or zh hashes init verifies a package against. An h1 hash does not name its platform,
+// so which platforms a lock file covers is not something it says; LockFileCoverageProblems checks that.
+func LockFileProblems(providers []LockedProvider) []string {
+	var problems []string
+	for _, provider := range providers {
+		switch {
+		case provider.Version == "":
+			problems = append(problems, provider.Source+" has no version")
+		case len(provider.H1Hashes) == 0:
+			problems = append(problems, fmt.Sprintf("%s %s has no h1 hashes", provider.Source, provider.Version))
+		case len(provider.ZHHashes) == 0:
+			problems = append(problems, fmt.Sprintf("%s %s has no zh hashes of its registry packages",
+				provider.Source, provider.Version))
+		}
+	}
+	return problems
+}
+
+// LockFileCoverageProblems compares a lock file with the same configuration relocked for the platforms, and returns
+// each provider relocking changed. Relocking adds the h1 hash of every platform the lock file lacks, so a hash
+// only the relocked file has is a platform init would fail on.
+func LockFileCoverageProblems(locked, relocked []LockedProvider, platforms []string) []string {
+	lockedBySource := make(map[string]LockedProvider, len(locked))
+	for _, provider := range locked {
+		lockedBySource[provider.Source] = provider
+	}
+
+	var problems []string
+	for _, provider := range relocked {
+		previous, found := lockedBySource[provider.Source]
+		switch {
+		case !found:
+			problems = append(problems, provider.Source+" is not locked")
+		case previous.Version != provider.Version:
+			problems = append(problems, fmt.Sprintf("%s is locked at %s, but the constraints now select %s",
+				provider.Source, previous.Version, provider.Version))
+		default:
+			missing := 0
+			for _, hash := range provider.H1Hashes {
+				if !slices.Contains(previous.H1Hashes, hash) {
+					missing++
+				}
+			}
+			if missing > 0 {
+				problems = append(problems, fmt.Sprintf("%s %s lacks %d of the h1 hashes for %s",
+					provider.Source, provider.Version, missing, strings.Join(platforms, ", ")))
+			}
+		}
+	}
+	return problems
+}
+
+// LockFileCommand is the command that locks a configuration's providers for every platform in LockFilePlatforms
+func LockFileCommand() string {
+	args := []string{"terraform", "providers", "lock"}
+	for _, platform := range LockFilePlatforms {
+		args = append(args, "-platform="+platform)
+	}
+	return strings.Join(args, " ")
+}
+
+// ConfigurationDirectories returns the configurations under a terraform directory that can hold a lock file: the
+// root, each environment and each module
+func ConfigurationDirectories(terraformRoot string) ([]string, error) {
+	directories := []string{terraformRoot}
+	for _, parent := range []string{"environments", "modules"} {
+		entries, err := os.ReadDir(filepath.Join(terraformRoot, parent))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				directories = append(directories, filepath.Join(terraformRoot, parent, entry.Name()))
+			}
+		}
+	}
+	sort.Strings(directories[1:])
+	return directories, nil
+}
+
+// DeployedConfigurationDirectories returns the configurations that are deployed rather than called as modules: the
+// root and each environment. Terraform reads their lock files on every init, so each must commit one.
+func DeployedConfigurationDirectories(terraformRoot string) ([]string, error) {
+	directories, err := ConfigurationDirectories(terraformRoot)
+	if err != nil {
+		return nil, err
+	}
+	var deployed []string
+	for _, directory := range directories {
+		if directory == terraformRoot || filepath.Base(filepath.Dir(directory)) == "environments" {
+			deployed = append(deployed, directory)
+		}
+	}
+	return deployed, nil
+}
+
+// RelockProviders copies the terraform directory, leaving out the tests, and relocks the configuration at the
+// relative path within it for LockFilePlatforms, returning the providers the relocked lock file pins. The copy
+// keeps the checked-in lock file, so relocking only adds what it lacks; the checked-in file is left as it is.
+func RelockProviders(t *testing.T, terraformRoot, configuration string) []LockedProvider {
+	copyRoot := t.TempDir()
+	tests := filepath.Join(terraformRoot, "tests")
+	require.NoError(t, files.CopyFolderContentsWithFilter(terraformRoot, copyRoot, func(path string) bool {
+		if path == tests || files.PathContainsTerraformStateOrVars(path) {
+			return false
+		}
+		return files.PathIsTerraformLockFile(path) || !files.PathContainsHiddenFileOrFolder(path)
+	}))
+
+	options := &terraform.Options{
+		TerraformDir:    filepath.Join(copyRoot, configuration),
+		TerraformBinary: "terraform",
+		NoColor:         true,
+	}
+	terraform.RunTerraformCommand(t, options, "init", "-backend=false", "-input=false")
+	args := []string{"providers", "lock"}
+	for _, platform := range LockFilePlatforms {
+		args = append(args, "-platform="+platform)
+	}
+	terraform.RunTerraformCommand(t, options, args...)
+
+	providers, err := ParseLockFile(filepath.Join(options.TerraformDir, LockFileName))
+	require.NoError(t, err)
+	return providers
+}
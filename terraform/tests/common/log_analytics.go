@@ -0,0 +1,268 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// WAFLogDestinationPrefix starts the name of every destination WAF can log to, whether a Firehose stream, an S3
+// bucket or a log group
+const WAFLogDestinationPrefix = "aws-waf-logs-"
+
+// Log tables are partitioned by the day their logs were delivered, in the layout Firehose writes its timestamp
+// prefixes in, so Athena's partition projection finds the objects without a crawler. Firehose and Athena both
+// take Java date patterns; logDayLayout is the same layout for Go.
+const (
+	LogDayPartitionKey    = "day"
+	LogDayPartitionFormat = "yyyy/MM/dd"
+	logDayLayout          = "2006/01/02"
+)
+
+// LogAnalyticsAuditChecks returns the checks for the pipeline that makes ALB and WAF logs queryable: WAF logging,
+// the Firehose streams delivering logs to S3 and the Glue tables Athena queries them through. No module creates
+// the pipeline yet, so they report nothing until one does.
+func LogAnalyticsAuditChecks() []AuditCheck {
+	return []AuditCheck{CheckWAFLogging, CheckFirehoseLogDelivery, CheckGlueLogTable}
+}
+
+// CheckWAFLogging verifies a web ACL's logging configuration sends its logs to a destination WAF accepts, which
+// must be named with WAFLogDestinationPrefix
+func CheckWAFLogging(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if resource.Type != "aws_wafv2_web_acl_logging_configuration" {
+		return nil
+	}
+	if audit.IsUnknown(resource, "log_destination_configs") {
+		// The destination's ARN is only known once it exists
+		return []AuditFinding{unknown("WAF-Log-Destination", resource, "log_destination_configs")}
+	}
+
+	destinations, _ := resource.AttributeValues["log_destination_configs"].([]interface{})
+	problem := ""
+	if len(destinations) == 0 {
+		problem = "logging configuration has no destination"
+	}
+	for _, destination := range destinations {
+		arn, _ := destination.(string)
+		if !strings.Contains(arn, WAFLogDestinationPrefix) {
+			problem = fmt.Sprintf("destination %s is not named %s*", arn, WAFLogDestinationPrefix)
+		}
+	}
+	return []AuditFinding{passFail("WAF-Log-Destination", resource, problem == "", problem)}
+}
+
+// CheckFirehoseLogDelivery verifies a Firehose stream delivers to S3 encrypted, compressed and under day prefixes
+// that a Glue table's LogDayPartitionKey projection can find
+func CheckFirehoseLogDelivery(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_kinesis_firehose_delivery_stream" {
+		return nil
+	}
+
+	destination, _ := resource.AttributeValues["destination"].(string)
+	configs := nestedBlocks(resource.AttributeValues, "extended_s3_configuration")
+	if destination != "extended_s3" || len(configs) == 0 {
+		return []AuditFinding{passFail("Firehose-Destination", resource, false,
+			fmt.Sprintf("stream delivers to %q, expected extended_s3", destination))}
+	}
+	config := configs[0]
+
+	prefix, _ := config["prefix"].(string)
+	errorPrefix, _ := config["error_output_prefix"].(string)
+	partitioned := strings.Contains(prefix, "!{timestamp:"+LogDayPartitionFormat+"}")
+	compression, _ := config["compression_format"].(string)
+
+	encrypted := false
+	for _, encryption := range nestedBlocks(resource.AttributeValues, "server_side_encryption") {
+		encrypted, _ = encryption["enabled"].(bool)
+	}
+	if key, _ := config["kms_key_arn"].(string); key != "" {
+		encrypted = true
+	}
+
+	return []AuditFinding{
+		passFail("Firehose-Destination", resource, true, ""),
+		passFail("Firehose-Partitioned-Prefix", resource, partitioned && errorPrefix != "",
+			fmt.Sprintf("prefix %q should contain !{timestamp:%s} and errors need their own prefix, got %q",
+				prefix, LogDayPartitionFormat, errorPrefix)),
+		passFail("Firehose-Compression", resource, compression != "" && compression != "UNCOMPRESSED",
+			fmt.Sprintf("stream writes %q objects, expected compressed ones", compression)),
+		passFail("Firehose-Encryption", resource, encrypted, "stream is not encrypted"),
+	}
+}
+
+// CheckGlueLogTable verifies a Glue table over S3 logs is partitioned by LogDayPartitionKey, with a date
+// projection in LogDayPartitionFormat whose location template places each day where Firehose writes it
+func CheckGlueLogTable(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_glue_catalog_table" {
+		return nil
+	}
+	var location string
+	for _, descriptor := range nestedBlocks(resource.AttributeValues, "storage_descriptor") {
+		location, _ = descriptor["location"].(string)
+	}
+	if !strings.HasPrefix(location, "s3://") {
+		return nil
+	}
+
+	return []AuditFinding{passFail("Glue-Log-Partitioning", resource, GlueLogTableProblem(resource) == "",
+		GlueLogTableProblem(resource))}
+}
+
+// GlueLogTableProblem describes why a log table's partitions cannot be projected by day, or returns "" if they can
+func GlueLogTableProblem(resource *tfjson.StateResource) string {
+	partitioned := false
+	for _, key := range nestedBlocks(resource.AttributeValues, "partition_keys") {
+		partitioned = partitioned || key["name"] == LogDayPartitionKey
+	}
+	if !partitioned {
+		return fmt.Sprintf("table is not partitioned by %s", LogDayPartitionKey)
+	}
+
+	parameters, _ := resource.AttributeValues["parameters"].(map[string]interface{})
+	parameter := func(name string) string {
+		value, _ := parameters[name].(string)
+		return value
+	}
+	switch {
+	case parameter("projection.enabled") != "true":
+		return "partition projection is not enabled, so new days need a crawler or MSCK REPAIR TABLE"
+	case parameter("projection."+LogDayPartitionKey+".type") != "date":
+		return fmt.Sprintf("%s is not projected as a date", LogDayPartitionKey)
+	case parameter("projection."+LogDayPartitionKey+".format") != LogDayPartitionFormat:
+		return fmt.Sprintf("%s is projected in %q, expected %s", LogDayPartitionKey,
+			parameter("projection."+LogDayPartitionKey+".format"), LogDayPartitionFormat)
+	case !strings.Contains(parameter("storage.location.template"), "${"+LogDayPartitionKey+"}"):
+		return fmt.Sprintf("storage.location.template does not place %s in the object path", LogDayPartitionKey)
+	}
+	return ""
+}
+
+// AthenaQueryTimeout bounds how long a smoke query may run
+const AthenaQueryTimeout = 5 * time.Minute
+
+// runAthenaCommand runs an aws athena command and decodes its JSON output
+func runAthenaCommand(t *testing.T, region string, out interface{}, args ...string) error {
+	return awscalls.RunCLI(context.Background(), t, region, out, "athena", args...)
+}
+
+// RunAthenaQuery runs a query in a deployed log database's workgroup, waits up to AthenaQueryTimeout for it to
+// finish and returns its rows without the header
+func RunAthenaQuery(t *testing.T, deployed *DeployedLogAnalytics, query string) [][]string {
+	var started struct {
+		QueryExecutionID string `json:"QueryExecutionId"`
+	}
+	require.NoError(t, runAthenaCommand(t, deployed.Region, &started, "start-query-execution",
+		"--query-string", query, "--work-group", deployed.WorkGroup,
+		"--query-execution-context", "Database="+deployed.Database))
+	t.Logf("Started Athena query %s: %s", started.QueryExecutionID, query)
+
+	_, err := retry.DoWithRetryE(t, "Wait for Athena query "+started.QueryExecutionID,
+		int(AthenaQueryTimeout/(5*time.Second)), 5*time.Second, func() (string, error) {
+			var result struct {
+				QueryExecution struct {
+					Status struct {
+						State             string `json:"State"`
+						StateChangeReason string `json:"StateChangeReason"`
+					} `json:"Status"`
+				} `json:"QueryExecution"`
+			}
+			if err := runAthenaCommand(t, deployed.Region, &result, "get-query-execution",
+				"--query-execution-id", started.QueryExecutionID); err != nil {
+				return "", err
+			}
+			switch status := result.QueryExecution.Status; status.State {
+			case "SUCCEEDED":
+				return status.State, nil
+			case "FAILED", "CANCELLED":
+				return "", retry.FatalError{Underlying: fmt.Errorf("query %s: %s", status.State,
+					status.StateChangeReason)}
+			default:
+				return "", fmt.Errorf("query is %s", status.State)
+			}
+		})
+	require.NoError(t, err)
+
+	var results struct {
+		ResultSet struct {
+			Rows []struct {
+				Data []struct {
+					VarCharValue string `json:"VarCharValue"`
+				} `json:"Data"`
+			} `json:"Rows"`
+		} `json:"ResultSet"`
+	}
+	require.NoError(t, runAthenaCommand(t, deployed.Region, &results, "get-query-results",
+		"--query-execution-id", started.QueryExecutionID))
+
+	var rows [][]string
+	for i, row := range results.ResultSet.Rows {
+		if i == 0 {
+			continue
+		}
+		values := make([]string, 0, len(row.Data))
+		for _, data := range row.Data {
+			values = append(values, data.VarCharValue)
+		}
+		rows = append(rows, values)
+	}
+	return rows
+}
+
+// RecentLogCountQuery returns a query counting the log records a table holds for the last two days, which only
+// reads those days' partitions
+func RecentLogCountQuery(table string, now time.Time) string {
+	since := now.UTC().AddDate(0, 0, -1).Format(logDayLayout)
+	return fmt.Sprintf(`SELECT count(*) FROM "%s" WHERE %s >= '%s'`, table, LogDayPartitionKey, since)
+}
+
+// CountRecentLogs runs RecentLogCountQuery against a deployed log table and returns the count
+func CountRecentLogs(t *testing.T, deployed *DeployedLogAnalytics) int {
+	rows := RunAthenaQuery(t, deployed, RecentLogCountQuery(deployed.Table, time.Now()))
+	require.Len(t, rows, 1, "A count query returns one row")
+	require.Len(t, rows[0], 1, "A count query returns one column")
+	count, err := strconv.Atoi(rows[0][0])
+	require.NoError(t, err)
+	return count
+}
+
+// DeployedLogAnalytics is a Glue table over delivered ALB or WAF logs in an already-deployed stack, from
+// E2E_ATHENA_DATABASE, E2E_ATHENA_TABLE and E2E_ATHENA_WORKGROUP
+type DeployedLogAnalytics struct {
+	Region    string
+	Database  string
+	Table     string
+	WorkGroup string // Defaults to primary; the workgroup must have a query result location
+}
+
+// GetDeployedLogAnalytics loads the log table to query, skipping the test when none is configured
+func GetDeployedLogAnalytics(t *testing.T) *DeployedLogAnalytics {
+	RequireTier(t, TierE2E)
+
+	deployed := &DeployedLogAnalytics{
+		Region:    os.Getenv("AWS_REGION"),
+		Database:  os.Getenv("E2E_ATHENA_DATABASE"),
+		Table:     os.Getenv("E2E_ATHENA_TABLE"),
+		WorkGroup: os.Getenv("E2E_ATHENA_WORKGROUP"),
+	}
+	if deployed.Database == "" || deployed.Table == "" {
+		t.Skip("Skipping end-to-end test - set E2E_ATHENA_DATABASE and E2E_ATHENA_TABLE to a Glue table over " +
+			"delivered ALB or WAF logs")
+	}
+	if deployed.Region == "" {
+		deployed.Region = "us-east-1"
+	}
+	if deployed.WorkGroup == "" {
+		deployed.WorkGroup = "primary"
+	}
+	return deployed
+}
@@ -0,0 +1,157 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+
+	"terraform-tests/e2e"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MaintenanceModeVariable is the input variable a configuration declares once the site can be put into
+// maintenance mode
+const MaintenanceModeVariable = "maintenance_mode"
+
+// MaintenanceHealthPath is the path monitors poll, which keeps reaching the API while the site is in maintenance
+const MaintenanceHealthPath = "/api/health"
+
+// ModuleSupportsMaintenanceMode reports whether a configuration declares MaintenanceModeVariable
+func ModuleSupportsMaintenanceMode(modulePath string) bool {
+	return moduleDeclaresVariable(modulePath, MaintenanceModeVariable)
+}
+
+// CheckMaintenanceMode verifies a maintenance page, served either by a load balancer fixed-response rule or as a
+// CloudFront error page, answers 503 while leaving MaintenanceHealthPath routed to the API
+func CheckMaintenanceMode(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	switch resource.Type {
+	case "aws_lb_listener_rule", "aws_alb_listener_rule":
+		status, maintenance := listenerRuleFixedResponse(resource)
+		if !maintenance {
+			return nil
+		}
+		priority, _ := resource.AttributeValues["priority"].(float64)
+		healthRouted := false
+		for _, candidate := range audit.Resources {
+			if candidate.Type != resource.Type || candidate == resource {
+				continue
+			}
+			candidatePriority, _ := candidate.AttributeValues["priority"].(float64)
+			if _, fixed := listenerRuleFixedResponse(candidate); !fixed && candidatePriority < priority &&
+				listenerRuleMatchesPath(candidate, MaintenanceHealthPath) {
+				healthRouted = true
+			}
+		}
+		return []AuditFinding{
+			passFail("Maintenance-Status", resource, status == "503",
+				fmt.Sprintf("maintenance rule answers %s, expected 503", status)),
+			passFail("Maintenance-Health-Path", resource, healthRouted,
+				fmt.Sprintf("no rule ahead of the maintenance rule forwards %s", MaintenanceHealthPath)),
+		}
+
+	case "aws_cloudfront_distribution":
+		var findings []AuditFinding
+		for _, page := range nestedBlocks(resource.AttributeValues, "custom_error_response") {
+			path, _ := page["response_page_path"].(string)
+			if fmt.Sprint(page["response_code"]) != "503" || path == "" {
+				continue
+			}
+			healthRouted := false
+			for _, behavior := range nestedBlocks(resource.AttributeValues, "ordered_cache_behavior") {
+				pattern, _ := behavior["path_pattern"].(string)
+				healthRouted = healthRouted || pattern == MaintenanceHealthPath || pattern == "/api/*"
+			}
+			findings = append(findings, passFail("Maintenance-Health-Path", resource, healthRouted,
+				fmt.Sprintf("maintenance page %s is served without a cache behavior for %s", path,
+					MaintenanceHealthPath)))
+		}
+		return findings
+	}
+	return nil
+}
+
+// listenerRuleFixedResponse returns the status code of a listener rule's fixed-response action, and whether it
+// has one
+func listenerRuleFixedResponse(resource *tfjson.StateResource) (string, bool) {
+	for _, action := range nestedBlocks(resource.AttributeValues, "action") {
+		if action["type"] != "fixed-response" {
+			continue
+		}
+		for _, response := range nestedBlocks(action, "fixed_response") {
+			status, _ := response["status_code"].(string)
+			return status, true
+		}
+	}
+	return "", false
+}
+
+// listenerRuleMatchesPath reports whether a listener rule has a path pattern condition listing the path
+func listenerRuleMatchesPath(resource *tfjson.StateResource, path string) bool {
+	for _, condition := range nestedBlocks(resource.AttributeValues, "condition") {
+		for _, pattern := range nestedBlocks(condition, "path_pattern") {
+			values, _ := pattern["values"].([]interface{})
+			for _, value := range values {
+				if value == path {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// MaintenanceModeExpected returns whether E2E_MAINTENANCE_MODE says the deployed stack is in maintenance, skipping
+// the test when it is unset. The checks never change the stack, so each state is checked by its own run.
+func MaintenanceModeExpected(t *testing.T) bool {
+	RequireTier(t, TierE2E)
+
+	value := os.Getenv("E2E_MAINTENANCE_MODE")
+	if value == "" {
+		t.Skip("Skipping end-to-end test - set E2E_MAINTENANCE_MODE to true or false to match the deployed stack")
+	}
+	enabled, err := strconv.ParseBool(value)
+	require.NoError(t, err, "E2E_MAINTENANCE_MODE should be true or false")
+	return enabled
+}
+
+// GetWithRetryAfter sends a GET request without following redirects and returns the status and Retry-After header
+func GetWithRetryAfter(t *testing.T, client *http.Client, url string) (int, string) {
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, http.NoBody)
+	require.NoError(t, err)
+
+	response, err := client.Do(request)
+	require.NoError(t, err, "Request to %s failed", url)
+	response.Body.Close()
+	return response.StatusCode, response.Header.Get("Retry-After")
+}
+
+// AssertMaintenanceMode asserts the site on a deployed domain is or is not in maintenance, and that the API
+// health check answers either way. In maintenance, the site answers 503 with a Retry-After of seconds or an HTTP
+// date; otherwise the frontend serves it.
+func AssertMaintenanceMode(t *testing.T, domain string, enabled bool) {
+	client := e2e.NewNonRedirectingClient()
+	url := fmt.Sprintf("https://%s/", domain)
+
+	if enabled {
+		status, retryAfter := GetWithRetryAfter(t, client, url)
+		assert.Equal(t, http.StatusServiceUnavailable, status, "%s should serve the maintenance page", url)
+		_, secondsErr := strconv.Atoi(retryAfter)
+		_, dateErr := http.ParseTime(retryAfter)
+		assert.True(t, secondsErr == nil || dateErr == nil,
+			"The maintenance page should send Retry-After as seconds or an HTTP date, got %q", retryAfter)
+	} else {
+		backend, response := RequestRoute(t, client, Route{Host: domain, Path: "/", Backend: BackendFrontend})
+		assert.NotEqual(t, http.StatusServiceUnavailable, response.StatusCode, "%s should not be in maintenance", url)
+		assert.Equal(t, BackendFrontend, backend, "%s should be served by the frontend again", url)
+	}
+
+	health, status, _ := GetAPIHealth(t, client, domain)
+	assert.Equal(t, http.StatusOK, status, "%s should keep answering monitors", MaintenanceHealthPath)
+	assert.Equal(t, "healthy", health.Status)
+}
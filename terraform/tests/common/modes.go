@@ -0,0 +1,83 @@
+package common
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"terraform-tests/tfopts"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// Mode is what a test does with a configuration: only plan it, or apply it and destroy it afterwards
+type Mode = tfopts.Mode
+
+// Modes
+const (
+	ModePlan  = tfopts.ModePlan
+	ModeApply = tfopts.ModeApply
+)
+
+// ModeOptions are terraform options together with the mode they were built for
+type ModeOptions = tfopts.ModeOptions
+
+// PlanOnly runs terraform init and plan for options built for a plan and returns the parsed plan. The plan file goes
+// to a temporary directory unless the options name one, which is then removed when the test finishes.
+func PlanOnly(t *testing.T, options *ModeOptions) *terraform.PlanStruct {
+	t.Helper()
+
+	plan, err := PlanOnlyE(t, options)
+	require.NoError(t, err)
+	return plan
+}
+
+// PlanOnlyE is PlanOnly returning the plan's error instead of failing the test, for tests expecting a variable's
+// validation to reject the plan
+func PlanOnlyE(t *testing.T, options *ModeOptions) (*terraform.PlanStruct, error) {
+	t.Helper()
+
+	if options.Mode != ModePlan {
+		t.Fatalf("PlanOnly was given options built for %s; plan them in a plan-tier test with PlanMode options "+
+			"or apply them with ApplyAndValidate", options.Mode)
+	}
+	if planFile := options.PlanFilePath; planFile != "" {
+		t.Cleanup(func() {
+			if err := os.Remove(planFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+				t.Logf("Warning: Failed to remove plan file %s: %v", planFile, err)
+			}
+		})
+	}
+	return PlanAndShowE(t, options.Options)
+}
+
+// ApplyAndValidate applies options built for an apply, destroying them when the test finishes, and then runs the
+// validators in order against the applied state and outputs, reporting all of their findings together. The
+// destroy is registered before the apply, so a failed apply is cleaned up too. The apply runs under
+// DefaultApplyWatchdog, so a resource stuck past its limit fails the test instead of hanging it. Options holding
+// placeholder IDs fail the test before anything is applied, whatever its tier.
+func ApplyAndValidate(t *testing.T, options *ModeOptions, validators ...Validator) {
+	t.Helper()
+
+	if options.Mode != ModeApply {
+		t.Fatalf("ApplyAndValidate was given options built for %s, which cannot be applied; build them for "+
+			"an apply, with fixtures for any IDs they reference", options.Mode)
+	}
+	if err := FakeIDsError(options.Options); err != nil {
+		t.Fatal(err)
+	}
+	RegisterDestroy(t, options.Options)
+
+	RunTerraformWithWatchdog(t, options.Options, "apply "+options.TerraformDir, 0, DefaultApplyWatchdog())
+	if len(validators) == 0 {
+		return
+	}
+
+	applied := NewAppliedConfiguration(t, options.Options)
+	var findings []AuditFinding
+	for _, validate := range validators {
+		findings = append(findings, validate(t, applied)...)
+	}
+	ReportAuditFindings(t, findings)
+}
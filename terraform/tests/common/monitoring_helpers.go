@@ -0,0 +1,76 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"terraform-tests/awscalls"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// DashboardBody is the subset of the CloudWatch dashboard body schema used by assertions
+type DashboardBody struct {
+	Widgets []DashboardWidget `json:"widgets"`
+}
+
+// DashboardWidget is a single widget in a CloudWatch dashboard body
+type DashboardWidget struct {
+	Type       string                    `json:"type"`
+	Properties DashboardWidgetProperties `json:"properties"`
+}
+
+// DashboardWidgetProperties holds the widget fields that reference metrics
+type DashboardWidgetProperties struct {
+	Title   string          `json:"title"`
+	Metrics [][]interface{} `json:"metrics"`
+}
+
+// DimensionValues returns every value used for the named dimension across all metric widgets.
+// Metric rows have the form [namespace, metric, dim1, val1, dim2, val2, ..., {options}].
+func (d *DashboardBody) DimensionValues(dimension string) []string {
+	var values []string
+	for _, widget := range d.Widgets {
+		for _, row := range widget.Properties.Metrics {
+			for i := 2; i+1 < len(row); i += 2 {
+				name, nameOK := row[i].(string)
+				value, valueOK := row[i+1].(string)
+				if nameOK && valueOK && name == dimension {
+					values = append(values, value)
+				}
+			}
+		}
+	}
+	return values
+}
+
+// GetDashboardBody parses the planned dashboard_body of the aws_cloudwatch_dashboard at the given address
+func GetDashboardBody(t *testing.T, plan *terraform.PlanStruct, address string) *DashboardBody {
+	resource, exists := plan.ResourcePlannedValuesMap[address]
+	require.True(t, exists, "Plan should contain dashboard %s", address)
+
+	raw := GetPlannedStringAttribute(resource, "dashboard_body")
+	require.NotEmpty(t, raw, "Dashboard %s should have a known dashboard_body at plan time", address)
+
+	var body DashboardBody
+	require.NoError(t, json.Unmarshal([]byte(raw), &body))
+
+	return &body
+}
+
+// GetDeployedDashboardBody fetches and parses the body of a deployed CloudWatch dashboard, as CloudWatch stores it
+func GetDeployedDashboardBody(t *testing.T, region, name string) *DashboardBody {
+	var dashboard struct {
+		DashboardBody string `json:"DashboardBody"`
+	}
+	require.NoError(t, awscalls.RunCLI(context.Background(), t, region, &dashboard, "cloudwatch", "get-dashboard",
+		"--dashboard-name", name))
+	require.NotEmpty(t, dashboard.DashboardBody, "Dashboard %s should have a body", name)
+
+	var body DashboardBody
+	require.NoError(t, json.Unmarshal([]byte(dashboard.DashboardBody), &body))
+
+	return &body
+}
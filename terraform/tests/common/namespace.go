@@ -0,0 +1,225 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/cleanup"
+	"terraform-tests/tfopts"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/stretchr/testify/require"
+)
+
+// Tags that identify the namespace and test that created a resource
+const (
+	TestNamespaceTag = "TestNamespace" // The namespace prefix, which is unique to one namespace
+	TestNameTag      = "TestName"
+)
+
+// Namespace isolates the resources one test creates for one component, so many tests can run in the same account
+// at once. Its prefix is unique to the namespace, and its tags name the namespace, test, run, commit and branch, so
+// the resources can be found for targeted cleanup and a leak can be traced to the test that created it.
+type Namespace struct {
+	Name   string             // Component, such as "networking"
+	Prefix string             // Resource name prefix, such as "networking-test-3f0a1b2c3d4e5f"
+	Config *tfopts.TestConfig // The test configuration, with Prefix and UniqueID replaced by the namespace's
+}
+
+// namespaceSlugPattern matches the characters a namespace name loses in its prefix
+var namespaceSlugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// NewNamespace returns a new namespace of the test configuration for a component. The prefix starts with the
+// component name, shortened so the whole prefix fits MaxPrefixLength.
+func NewNamespace(tc *tfopts.TestConfig, name string) *Namespace {
+	uniqueID := tfopts.NewUniqueID()
+	slug := namespaceSlugPattern.ReplaceAllString(strings.ToLower(name), "-")
+	if maxSlug := MaxPrefixLength - len(uniqueID) - 1; len(slug) > maxSlug {
+		slug = slug[:maxSlug]
+	}
+	prefix := fmt.Sprintf("%s-%s", strings.Trim(slug, "-"), uniqueID)
+
+	config := *tc
+	config.Prefix = prefix
+	config.UniqueID = uniqueID
+	return &Namespace{Name: name, Prefix: prefix, Config: &config}
+}
+
+// Tags returns the tags that mark a resource as created by this namespace in the test, with the run's RunTags
+func (ns *Namespace) Tags(t *testing.T) map[string]string {
+	tags := tfopts.RunTags()
+	tags[TestNamespaceTag] = ns.Prefix
+	tags[TestNameTag] = t.Name()
+	return tags
+}
+
+// namespaceProviderFile is written into a namespace's working copy to tag everything the module creates
+const namespaceProviderFile = "namespace_provider.tf.json"
+
+// GetModuleTerraformOptions returns options that apply the module from a working copy of the terraform directory
+// made for this namespace, so its state and .terraform directory are not shared with other tests. The module uses
+// the namespace's prefix, and a provider file in the copy adds the namespace's tags to every resource through
+// default_tags; modules with a tags variable also receive them there. The options are in ModeApply, so an
+// apply-tier test whose variables hold placeholder IDs fails here, before it applies anything.
+func (ns *Namespace) GetModuleTerraformOptions(
+	t *testing.T,
+	modulePath string,
+	vars map[string]interface{},
+) *ModeOptions {
+	terraformRoot, relativePath, err := terraformRootOf(modulePath)
+	require.NoError(t, err)
+	copiedRoot, err := files.CopyTerraformFolderToTemp(terraformRoot, filepath.Base(t.Name()))
+	require.NoError(t, err)
+	workingCopy := filepath.Join(copiedRoot, relativePath)
+
+	provider, err := json.MarshalIndent(map[string]interface{}{
+		"provider": map[string]interface{}{
+			"aws": map[string]interface{}{
+				"default_tags": map[string]interface{}{"tags": ns.Tags(t)},
+			},
+		},
+	}, "", "  ")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(workingCopy, namespaceProviderFile), provider, 0o600))
+
+	options := ns.Config.GetModuleTerraformOptions(workingCopy, vars)
+	if tfopts.ModuleAcceptsTags(workingCopy) {
+		tfopts.MergeTags(options.Vars, nil, ns.Tags(t))
+	}
+
+	// Keep the copy while it holds state, so an interrupted or failed destroy can still be retried from it
+	cleanup.Func(t, cleanup.AfterDestroy, "remove working copy "+workingCopy, func(t *testing.T) error {
+		if stateHasResources(filepath.Join(workingCopy, localStateFile)) {
+			return nil
+		}
+		return removeWorkingCopy(workingCopy, relativePath)
+	})
+	RejectFakeIDs(t, options.Options)
+	return options
+}
+
+// terraformRootOf returns the terraform directory a module or scenario lives under, and the module's path within it.
+// That is the nearest directory above it holding both modules/ and tests/, which the relative sources of modules
+// and scenarios resolve against.
+func terraformRootOf(modulePath string) (string, string, error) {
+	absolutePath, err := filepath.Abs(modulePath)
+	if err != nil {
+		return "", "", err
+	}
+	for dir := filepath.Dir(absolutePath); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		modules, modulesErr := os.Stat(filepath.Join(dir, "modules"))
+		tests, testsErr := os.Stat(filepath.Join(dir, "tests"))
+		if modulesErr == nil && testsErr == nil && modules.IsDir() && tests.IsDir() {
+			relativePath, err := filepath.Rel(dir, absolutePath)
+			return dir, relativePath, err
+		}
+	}
+	return "", "", fmt.Errorf("%s is not under a terraform directory with modules and tests", modulePath)
+}
+
+// removeWorkingCopy removes a working copy made by GetModuleTerraformOptions. Terratest copies the root into a
+// directory of its own under the system temp directory, so that whole directory goes.
+func removeWorkingCopy(workingCopy, relativePath string) error {
+	copiedRoot := filepath.Clean(strings.TrimSuffix(workingCopy, relativePath))
+	return os.RemoveAll(filepath.Dir(copiedRoot))
+}
+
+// NamespacedResource is a resource found by its namespace tag
+type NamespacedResource struct {
+	ARN  string
+	Tags map[string]string
+}
+
+// FindResourcesInNamespace returns the tagged resources still present in the namespace's region. It uses the
+// Resource Groups Tagging API through the AWS CLI, so it sees regional resources (and CloudFront from us-east-1)
+// but not IAM. Deleted resources can remain listed for a short while.
+func FindResourcesInNamespace(t *testing.T, ns *Namespace) []NamespacedResource {
+	resources, err := FindResourcesInNamespaceE(t, ns)
+	if err != nil {
+		t.Fatalf("Failed to list resources in namespace %s: %v", ns.Prefix, err)
+	}
+	return resources
+}
+
+// FindResourcesInNamespaceE is FindResourcesInNamespace returning an error instead of failing the test
+func FindResourcesInNamespaceE(t *testing.T, ns *Namespace) ([]NamespacedResource, error) {
+	return getTaggedResourcesE(t, ns.Config.AWSRegion,
+		[]string{fmt.Sprintf("Key=%s,Values=%s", TestNamespaceTag, ns.Prefix)})
+}
+
+// getTaggedResourcesE lists the resources in a region matching all of the get-resources tag filters
+func getTaggedResourcesE(t *testing.T, region string, filters []string) ([]NamespacedResource, error) {
+	output, err := shell.RunCommandAndGetStdOutE(t, shell.Command{
+		Command: "aws",
+		Args: append([]string{
+			"resourcegroupstaggingapi", "get-resources",
+			"--region", region,
+			"--output", "json",
+			"--tag-filters",
+		}, filters...),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		ResourceTagMappingList []struct {
+			ResourceARN string `json:"ResourceARN"`
+			Tags        []struct {
+				Key   string `json:"Key"`
+				Value string `json:"Value"`
+			} `json:"Tags"`
+		} `json:"ResourceTagMappingList"`
+	}
+	if err = json.Unmarshal([]byte(output), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse get-resources output: %w", err)
+	}
+
+	resources := make([]NamespacedResource, 0, len(response.ResourceTagMappingList))
+	for _, mapping := range response.ResourceTagMappingList {
+		tags := make(map[string]string, len(mapping.Tags))
+		for _, tag := range mapping.Tags {
+			tags[tag.Key] = tag.Value
+		}
+		resources = append(resources, NamespacedResource{ARN: mapping.ResourceARN, Tags: tags})
+	}
+	return resources, nil
+}
+
+// RegisterNamespaceLeakCheck fails the test, once its configurations are destroyed, if any resource tagged with the
+// namespace remains. The tagging API is eventually consistent, so the check retries for a short while first.
+func RegisterNamespaceLeakCheck(t *testing.T, ns *Namespace) {
+	cleanup.Func(t, cleanup.AfterDestroy, "check leaks in namespace "+ns.Prefix, func(t *testing.T) error {
+		var leaks []NamespacedResource
+		_, err := retry.DoWithRetryE(t, "Check for resources left in "+ns.Prefix, 6, 10*time.Second,
+			func() (string, error) {
+				var findErr error
+				if leaks, findErr = FindResourcesInNamespaceE(t, ns); findErr != nil {
+					return "", retry.FatalError{Underlying: findErr}
+				}
+				if len(leaks) > 0 {
+					return "", fmt.Errorf("%d resources still tagged %s=%s", len(leaks), TestNamespaceTag, ns.Prefix)
+				}
+				return "", nil
+			})
+		if err == nil || len(leaks) == 0 {
+			return err
+		}
+
+		arns := make([]string, 0, len(leaks))
+		for _, leak := range leaks {
+			arns = append(arns, leak.ARN)
+		}
+		origin := OriginOf(leaks[0])
+		return fmt.Errorf("%s on %s (%s) left %d resources in namespace %s: %s", origin.TestName, origin.GitBranch,
+			origin.GitCommit, len(leaks), ns.Prefix, strings.Join(arns, ", "))
+	})
+}
@@ -0,0 +1,261 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// MaxPrefixLength is the longest resource prefix the modules are expected to support
+const MaxPrefixLength = 32
+
+// WorstCasePrefix is a valid prefix of MaxPrefixLength characters
+var WorstCasePrefix = "coalition-" + strings.Repeat("x", MaxPrefixLength-len("coalition-"))
+
+// WorstCaseNameValues are the longest values substituted for references in name templates. Keys are
+// references as written in Terraform; a "*" in place of a resource name matches any resource of that type.
+var WorstCaseNameValues = map[string]string{
+	"var.prefix":          WorstCasePrefix,
+	"var.bucket_prefix":   WorstCasePrefix,
+	"var.project_name":    WorstCasePrefix,
+	"var.environment":     "production",
+	"local.pg_version":    "16",
+	"random_id.*.hex":     "0123abcd", // byte_length = 4
+	"random_id.*.b64_url": "ASNFZw",
+}
+
+// NameConstraint is an AWS limit on the name of a resource type
+type NameConstraint struct {
+	Attribute string
+	MaxLength int
+	Pattern   *regexp.Regexp // Allowed characters and shape; nil when only the length is limited
+}
+
+// Allowed characters and shapes of resource names
+var (
+	hyphenatedName      = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?$`)
+	iamName             = regexp.MustCompile(`^[\w+=,.@-]+$`)
+	lowercaseIdentifier = regexp.MustCompile(`^[a-z](?:-?[a-z0-9])*$`)
+	simpleName          = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	bucketName          = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*[a-z0-9]$`)
+	subnetGroupName     = regexp.MustCompile(`^[a-z0-9 ._-]+$`)
+	repositoryName      = regexp.MustCompile(`^[a-z0-9][a-z0-9._/-]*$`)
+	aliasName           = regexp.MustCompile(`^alias/[A-Za-z0-9/_-]+$`)
+	secretName          = regexp.MustCompile(`^[A-Za-z0-9/_+=.@-]+$`)
+)
+
+// NameConstraints are the AWS naming limits checked for each resource type
+var NameConstraints = map[string]NameConstraint{
+	"aws_lb":                      {Attribute: "name", MaxLength: 32, Pattern: hyphenatedName},
+	"aws_lb_target_group":         {Attribute: "name", MaxLength: 32, Pattern: hyphenatedName},
+	"aws_s3_bucket":               {Attribute: "bucket", MaxLength: 63, Pattern: bucketName},
+	"aws_iam_role":                {Attribute: "name", MaxLength: 64, Pattern: iamName},
+	"aws_iam_user":                {Attribute: "name", MaxLength: 64, Pattern: iamName},
+	"aws_iam_policy":              {Attribute: "name", MaxLength: 128, Pattern: iamName},
+	"aws_iam_instance_profile":    {Attribute: "name", MaxLength: 128, Pattern: iamName},
+	"aws_db_instance":             {Attribute: "identifier", MaxLength: 63, Pattern: lowercaseIdentifier},
+	"aws_db_parameter_group":      {Attribute: "name", MaxLength: 255, Pattern: lowercaseIdentifier},
+	"aws_db_subnet_group":         {Attribute: "name", MaxLength: 255, Pattern: subnetGroupName},
+	"aws_security_group":          {Attribute: "name", MaxLength: 255},
+	"aws_lambda_function":         {Attribute: "function_name", MaxLength: 64, Pattern: simpleName},
+	"aws_ecs_cluster":             {Attribute: "name", MaxLength: 255, Pattern: simpleName},
+	"aws_ecs_task_definition":     {Attribute: "family", MaxLength: 255, Pattern: simpleName},
+	"aws_ecr_repository":          {Attribute: "name", MaxLength: 256, Pattern: repositoryName},
+	"aws_sns_topic":               {Attribute: "name", MaxLength: 256, Pattern: simpleName},
+	"aws_kms_alias":               {Attribute: "name", MaxLength: 256, Pattern: aliasName},
+	"aws_secretsmanager_secret":   {Attribute: "name", MaxLength: 512, Pattern: secretName},
+	"aws_ses_configuration_set":   {Attribute: "name", MaxLength: 64, Pattern: simpleName},
+	"aws_wafv2_web_acl":           {Attribute: "name", MaxLength: 128, Pattern: simpleName},
+	"aws_budgets_budget":          {Attribute: "name", MaxLength: 100},
+	"aws_cloudwatch_metric_alarm": {Attribute: "alarm_name", MaxLength: 255},
+}
+
+// ComposedName is a resource name rendered from a module's naming convention
+type ComposedName struct {
+	Module       string
+	ResourceType string
+	Address      string
+	Attribute    string
+	Name         string
+}
+
+// ComposeResourceNames renders the constrained name attribute of every resource in the modules under modulesDir,
+// substituting references with values. Names built from expressions other than literals, templates, references
+// and conditionals cannot be composed statically and are logged and left out.
+func ComposeResourceNames(t *testing.T, modulesDir string, values map[string]string) []ComposedName {
+	entries, err := os.ReadDir(modulesDir)
+	require.NoError(t, err)
+
+	var names []ComposedName
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		names = append(names, composeModuleNames(t, filepath.Join(modulesDir, entry.Name()), values)...)
+	}
+	return names
+}
+
+// composeModuleNames renders the constrained names declared in a single module
+func composeModuleNames(t *testing.T, moduleDir string, values map[string]string) []ComposedName {
+	files, err := filepath.Glob(filepath.Join(moduleDir, "*.tf"))
+	require.NoError(t, err)
+	sort.Strings(files)
+
+	parser := hclparse.NewParser()
+	var bodies []*hclsyntax.Body
+	for _, path := range files {
+		file, diags := parser.ParseHCLFile(path)
+		require.False(t, diags.HasErrors(), "Failed to parse %s: %s", path, diags.Error())
+		bodies = append(bodies, file.Body.(*hclsyntax.Body))
+	}
+
+	renderer := &nameRenderer{values: values, locals: map[string]hclsyntax.Expression{}}
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "locals" {
+				continue
+			}
+			for name, attribute := range block.Body.Attributes {
+				renderer.locals[name] = attribute.Expr
+			}
+		}
+	}
+
+	module := filepath.Base(moduleDir)
+	var names []ComposedName
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "resource" || len(block.Labels) != 2 {
+				continue
+			}
+			constraint, constrained := NameConstraints[block.Labels[0]]
+			if !constrained {
+				continue
+			}
+			attribute, found := block.Body.Attributes[constraint.Attribute]
+			if !found {
+				// The provider generates a unique name when none is given
+				continue
+			}
+
+			address := block.Labels[0] + "." + block.Labels[1]
+			name, err := renderer.render(attribute.Expr)
+			if err != nil {
+				t.Logf("Cannot compose %s in module %s: %v", address, module, err)
+				continue
+			}
+			names = append(names, ComposedName{
+				Module:       module,
+				ResourceType: block.Labels[0],
+				Address:      address,
+				Attribute:    constraint.Attribute,
+				Name:         name,
+			})
+		}
+	}
+	return names
+}
+
+// nameRenderer renders name expressions to their worst-case string
+type nameRenderer struct {
+	values map[string]string
+	locals map[string]hclsyntax.Expression
+}
+
+func (r *nameRenderer) render(expr hclsyntax.Expression) (string, error) {
+	switch e := expr.(type) {
+	case *hclsyntax.LiteralValueExpr:
+		if e.Val.Type() != cty.String {
+			return "", fmt.Errorf("literal is not a string")
+		}
+		return e.Val.AsString(), nil
+	case *hclsyntax.TemplateWrapExpr:
+		return r.render(e.Wrapped)
+	case *hclsyntax.TemplateExpr:
+		var builder strings.Builder
+		for _, part := range e.Parts {
+			rendered, err := r.render(part)
+			if err != nil {
+				return "", err
+			}
+			builder.WriteString(rendered)
+		}
+		return builder.String(), nil
+	case *hclsyntax.ConditionalExpr:
+		// Either branch may be taken, so the longer one is the worst case
+		trueValue, err := r.render(e.TrueResult)
+		if err != nil {
+			return "", err
+		}
+		falseValue, err := r.render(e.FalseResult)
+		if err != nil {
+			return "", err
+		}
+		if len(falseValue) > len(trueValue) {
+			return falseValue, nil
+		}
+		return trueValue, nil
+	case *hclsyntax.ScopeTraversalExpr:
+		return r.renderReference(e.Traversal)
+	default:
+		return "", fmt.Errorf("unsupported expression %T", expr)
+	}
+}
+
+func (r *nameRenderer) renderReference(traversal hcl.Traversal) (string, error) {
+	var steps []string
+	for _, step := range traversal {
+		switch s := step.(type) {
+		case hcl.TraverseRoot:
+			steps = append(steps, s.Name)
+		case hcl.TraverseAttr:
+			steps = append(steps, s.Name)
+		case hcl.TraverseIndex:
+			// Every instance of a counted resource shares the same worst case
+		default:
+			return "", fmt.Errorf("unsupported reference step %T", step)
+		}
+	}
+	reference := strings.Join(steps, ".")
+
+	if value, found := r.values[reference]; found {
+		return value, nil
+	}
+	if len(steps) == 3 {
+		if value, found := r.values[steps[0]+".*."+steps[2]]; found {
+			return value, nil
+		}
+	}
+	if len(steps) == 2 && steps[0] == "local" {
+		if expr, found := r.locals[steps[1]]; found {
+			return r.render(expr)
+		}
+	}
+	return "", fmt.Errorf("no worst-case value for %s", reference)
+}
+
+// ValidateComposedName returns the ways a composed name breaks the AWS limits for its resource type
+func ValidateComposedName(name ComposedName) []string {
+	constraint := NameConstraints[name.ResourceType]
+
+	var violations []string
+	if len(name.Name) > constraint.MaxLength {
+		violations = append(violations,
+			fmt.Sprintf("is %d characters, over the limit of %d", len(name.Name), constraint.MaxLength))
+	}
+	if constraint.Pattern != nil && !constraint.Pattern.MatchString(name.Name) {
+		violations = append(violations, fmt.Sprintf("does not match %s", constraint.Pattern))
+	}
+	return violations
+}
@@ -0,0 +1,127 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// NamingRule is the naming convention for a resource type, as a regular expression in which <prefix> stands for the
+// deployment's prefix
+type NamingRule string
+
+// Conventions shared by several resource types
+const (
+	// prefixedName is the prefix followed by lowercase words joined by single hyphens, which also keeps bucket
+	// names free of the dots that break virtual-hosted-style TLS
+	prefixedName NamingRule = `^<prefix>-[a-z0-9]+(-[a-z0-9]+)*$`
+
+	// DefaultNamingRule is the convention for the Name tag of resources without a rule of their own. Tags may
+	// hold underscores, such as the geo_places endpoint's.
+	DefaultNamingRule NamingRule = `^<prefix>-[a-z0-9_-]+$`
+)
+
+// NamingRules are the naming conventions for each resource type, checked against the attribute NameConstraints
+// limits, the name or identifier, or the Name tag of resources such as VPCs that have neither
+var NamingRules = map[string]NamingRule{
+	"aws_vpc":                   `^<prefix>-vpc$`,
+	"aws_subnet":                `^<prefix>-(public|private|private-db)-[a-z]$`,
+	"aws_route_table":           `^<prefix>-(public|private-app|private-db)-rt$`,
+	"aws_internet_gateway":      `^<prefix>-igw$`,
+	"aws_security_group":        `^<prefix>-(db|bastion|app|lambda|vpc-endpoints)-sg$`,
+	"aws_db_instance":           `^<prefix>-db(-replica)?$`,
+	"aws_rds_cluster":           `^<prefix>-db$`,
+	"aws_rds_cluster_instance":  `^<prefix>-db-[0-9]+$`,
+	"aws_db_subnet_group":       `^<prefix>-db-subnet$`,
+	"aws_db_parameter_group":    `^<prefix>-pg-[0-9]+(-static)?-(prod|test)$`,
+	"aws_wafv2_web_acl":         `^<prefix>-waf$`,
+	"aws_kms_alias":             `^alias/<prefix>-[a-z0-9]+(-[a-z0-9]+)*$`,
+	"aws_secretsmanager_secret": `^<prefix>/[a-z0-9]+(-[a-z0-9]+)*$`,
+	"aws_s3_bucket":             prefixedName,
+	"aws_lb":                    prefixedName,
+	"aws_lb_target_group":       prefixedName,
+	"aws_iam_role":              prefixedName,
+	"aws_sns_topic":             prefixedName,
+	"aws_ecs_cluster":           prefixedName,
+	"aws_ecs_task_definition":   prefixedName,
+}
+
+// NamingExceptions documents resources, matched by address substring, that knowingly break their naming convention
+var NamingExceptions = map[string]string{
+	"aws_security_group.lambda":              "predates the -sg suffix; renaming replaces the group the functions run in",
+	"aws_iam_role.vpc_flow_log_role":         "predates prefixed names; renaming replaces the role flow logs deliver with",
+	"aws_iam_role.github_actions":            "one per account and environment, shared by every deployment",
+	"aws_iam_openid_connect_provider.github": "one per account, shared by every deployment",
+	"aws_ecr_repository.":                    "repositories are shared by every deployment and named for their image",
+	"aws_vpc_peering_connection":             "named for the two deployments it joins, with its accepter",
+}
+
+// Regexp returns the rule for a prefix
+func (r NamingRule) Regexp(prefix string) *regexp.Regexp {
+	return regexp.MustCompile(strings.ReplaceAll(string(r), "<prefix>", regexp.QuoteMeta(prefix)))
+}
+
+// CheckNamingRule returns the ways a name breaks the naming convention for its resource type, or DefaultNamingRule
+// for types without one, and the AWS limits in NameConstraints
+func CheckNamingRule(resourceType, name, prefix string) []string {
+	rule, ruled := NamingRules[resourceType]
+	if !ruled {
+		rule = DefaultNamingRule
+	}
+
+	var violations []string
+	if pattern := rule.Regexp(prefix); !pattern.MatchString(name) {
+		violations = append(violations, fmt.Sprintf("does not match %s", pattern))
+	}
+	if _, limited := NameConstraints[resourceType]; limited {
+		violations = append(violations, ValidateComposedName(ComposedName{ResourceType: resourceType, Name: name})...)
+	}
+	return violations
+}
+
+// NamingException returns why a resource may break its naming convention, or "" if it may not
+func NamingException(address string) string {
+	for pattern, reason := range NamingExceptions {
+		if strings.Contains(address, pattern) {
+			return reason
+		}
+	}
+	return ""
+}
+
+// CheckNamingConventions returns a check that sweeps every resource: those with a rule in NamingRules by their
+// name, and every other resource with a Name tag by the tag, against DefaultNamingRule
+func CheckNamingConventions(prefix string) AuditCheck {
+	const control = "Naming-Convention"
+
+	return func(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+		var name string
+		if _, ruled := NamingRules[resource.Type]; ruled {
+			name = resourceName(resource)
+			if name == "" && audit.IsUnknown(resource, nameAttribute(resource.Type)) {
+				return []AuditFinding{unknown(control, resource, nameAttribute(resource.Type))}
+			}
+		} else if name = nameTag(resource); name == "" {
+			return nil
+		}
+
+		if reason := NamingException(resource.Address); reason != "" {
+			return []AuditFinding{{
+				Control:  control,
+				Resource: resource.Address,
+				Skipped:  true,
+				Detail:   "documented exception: " + reason,
+			}}
+		}
+		violations := CheckNamingRule(resource.Type, name, prefix)
+		return []AuditFinding{passFail(control, resource, len(violations) == 0,
+			fmt.Sprintf("name %q %s", name, strings.Join(violations, "; ")))}
+	}
+}
+
+// NamingConventions sweeps every resource in the applied state with CheckNamingConventions
+func NamingConventions(prefix string) Validator {
+	return Audit(CheckNamingConventions(prefix))
+}
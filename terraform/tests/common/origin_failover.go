@@ -0,0 +1,209 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// OriginFailoverStatusCodes are the origin responses a CloudFront origin group must fail over on: the errors a
+// load balancer returns when its targets are unhealthy, overloaded or gone
+var OriginFailoverStatusCodes = []int{500, 502, 503, 504}
+
+// StaticAssetPathPatterns are cache behaviors that only serve static files. They may go straight to the app's
+// load balancer, since failing over would not make the app itself available.
+var StaticAssetPathPatterns = []string{"/static/*", "/media/*"}
+
+// CheckCloudFrontOriginFailover verifies cache behaviors that send app requests to a custom origin, such as a
+// load balancer, target an origin group that fails over on OriginFailoverStatusCodes. Behaviors for S3 origins and
+// StaticAssetPathPatterns are not checked, so the storage module's distribution reports nothing until it fronts
+// the app itself.
+func CheckCloudFrontOriginFailover(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_cloudfront_distribution" {
+		return nil
+	}
+
+	customOrigins := map[string]bool{}
+	for _, origin := range nestedBlocks(resource.AttributeValues, "origin") {
+		if len(nestedBlocks(origin, "custom_origin_config")) > 0 {
+			originID, _ := origin["origin_id"].(string)
+			customOrigins[originID] = true
+		}
+	}
+	groups := map[string]map[string]interface{}{}
+	for _, group := range nestedBlocks(resource.AttributeValues, "origin_group") {
+		originID, _ := group["origin_id"].(string)
+		groups[originID] = group
+	}
+
+	var findings []AuditFinding
+	behaviors := append(
+		nestedBlocks(resource.AttributeValues, "default_cache_behavior"),
+		nestedBlocks(resource.AttributeValues, "ordered_cache_behavior")...,
+	)
+	for _, behavior := range behaviors {
+		pattern, _ := behavior["path_pattern"].(string)
+		if pattern == "" {
+			pattern = "default"
+		}
+		target, _ := behavior["target_origin_id"].(string)
+		if containsValue(StaticAssetPathPatterns, pattern) {
+			continue
+		}
+		if group, isGroup := groups[target]; isGroup {
+			problem := OriginGroupProblem(group)
+			findings = append(findings, passFail("CloudFront-Origin-Failover", resource, problem == "",
+				fmt.Sprintf("cache behavior for %s: %s", pattern, problem)))
+		} else if customOrigins[target] {
+			findings = append(findings, passFail("CloudFront-Origin-Failover", resource, false,
+				fmt.Sprintf("cache behavior for %s sends app requests to %s with no failover origin", pattern, target)))
+		}
+	}
+	return findings
+}
+
+// OriginGroupProblem describes why an origin group does not fail over from its primary origin on every one of
+// OriginFailoverStatusCodes, or returns "" if it does
+func OriginGroupProblem(group map[string]interface{}) string {
+	if members := nestedBlocks(group, "member"); len(members) != 2 {
+		return fmt.Sprintf("origin group has %d members, expected a primary and a failover origin", len(members))
+	}
+
+	covered := map[int]bool{}
+	for _, criteria := range nestedBlocks(group, "failover_criteria") {
+		codes, _ := criteria["status_codes"].([]interface{})
+		for _, code := range codes {
+			if number, ok := code.(float64); ok {
+				covered[int(number)] = true
+			}
+		}
+	}
+	var missing []string
+	for _, code := range OriginFailoverStatusCodes {
+		if !covered[code] {
+			missing = append(missing, fmt.Sprint(code))
+		}
+	}
+	if len(missing) > 0 {
+		return "origin group does not fail over on " + strings.Join(missing, ", ")
+	}
+	return ""
+}
+
+// BlockCloudFrontIngress cuts CloudFront off from an origin by revoking the security group's ingress rules that
+// allow CloudFront's origin-facing prefix list, and restores them when the test ends
+func BlockCloudFrontIngress(t *testing.T, groupID, region string) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+	client := ec2.NewFromConfig(cfg)
+
+	rules, err := client.DescribeSecurityGroupRules(context.Background(), &ec2.DescribeSecurityGroupRulesInput{
+		Filters: []ec2types.Filter{{Name: aws.String("group-id"), Values: []string{groupID}}},
+	})
+	require.NoError(t, err)
+
+	var ids []string
+	var permissions []ec2types.IpPermission
+	for _, rule := range rules.SecurityGroupRules {
+		if aws.ToBool(rule.IsEgress) || rule.PrefixListId == nil {
+			continue
+		}
+		ids = append(ids, aws.ToString(rule.SecurityGroupRuleId))
+		permissions = append(permissions, ec2types.IpPermission{
+			IpProtocol: rule.IpProtocol,
+			FromPort:   rule.FromPort,
+			ToPort:     rule.ToPort,
+			PrefixListIds: []ec2types.PrefixListId{
+				{PrefixListId: rule.PrefixListId, Description: rule.Description},
+			},
+		})
+	}
+	require.NotEmpty(t, ids, "Security group %s has no prefix list ingress to revoke; the origin must only admit "+
+		"CloudFront for the test to block it", groupID)
+
+	_, err = client.RevokeSecurityGroupIngress(context.Background(), &ec2.RevokeSecurityGroupIngressInput{
+		GroupId:              aws.String(groupID),
+		SecurityGroupRuleIds: ids,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if _, err := client.AuthorizeSecurityGroupIngress(context.Background(),
+			&ec2.AuthorizeSecurityGroupIngressInput{GroupId: aws.String(groupID), IpPermissions: permissions}); err != nil {
+			t.Errorf("Failed to restore CloudFront ingress to %s, restore it by hand: %v", groupID, err)
+		}
+	})
+	t.Logf("Revoked %d CloudFront ingress rules of %s", len(ids), groupID)
+}
+
+// AssertServedByFallback waits for CloudFront to serve a URL from the failover origin or custom error page, which
+// is recognized by a marker only that content contains. Each request adds a query string so no cached copy from
+// the primary origin is returned.
+func AssertServedByFallback(t *testing.T, url, marker string) {
+	client := &http.Client{Timeout: 60 * time.Second}
+	_, err := retry.DoWithRetryE(t, "Wait for CloudFront to fall back for "+url, 20, 15*time.Second,
+		func() (string, error) {
+			request, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+				fmt.Sprintf("%s?failover=%d", url, time.Now().UnixNano()), http.NoBody)
+			if err != nil {
+				return "", retry.FatalError{Underlying: err}
+			}
+			response, err := client.Do(request)
+			if err != nil {
+				return "", err
+			}
+			defer response.Body.Close()
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				return "", err
+			}
+			if !strings.Contains(string(body), marker) {
+				return "", fmt.Errorf("%s answered %d (%s) without the fallback marker", url, response.StatusCode,
+					response.Header.Get("X-Cache"))
+			}
+			return "", nil
+		})
+	require.NoError(t, err, "CloudFront should serve %s from the failover origin or error page", url)
+}
+
+// DeployedOriginFailover is a CloudFront distribution fronting the app's load balancer in an already-deployed
+// stack, from E2E_CLOUDFRONT_URL, E2E_ORIGIN_SECURITY_GROUP_ID and E2E_FALLBACK_MARKER
+type DeployedOriginFailover struct {
+	Region          string
+	URL             string // An app page served through the distribution
+	SecurityGroupID string // The load balancer's security group, admitting CloudFront's prefix list
+	Marker          string // Text only the failover origin or custom error page contains
+}
+
+// GetDeployedOriginFailover loads the distribution to test failover on, skipping the test when none is configured
+func GetDeployedOriginFailover(t *testing.T) *DeployedOriginFailover {
+	RequireTier(t, TierE2E)
+
+	deployed := &DeployedOriginFailover{
+		Region:          os.Getenv("AWS_REGION"),
+		URL:             os.Getenv("E2E_CLOUDFRONT_URL"),
+		SecurityGroupID: os.Getenv("E2E_ORIGIN_SECURITY_GROUP_ID"),
+		Marker:          os.Getenv("E2E_FALLBACK_MARKER"),
+	}
+	if deployed.URL == "" || deployed.SecurityGroupID == "" || deployed.Marker == "" {
+		t.Skip("Skipping end-to-end test - set E2E_CLOUDFRONT_URL, E2E_ORIGIN_SECURITY_GROUP_ID and " +
+			"E2E_FALLBACK_MARKER for a distribution that fronts the app's load balancer")
+	}
+	if deployed.Region == "" {
+		deployed.Region = "us-east-1"
+	}
+	return deployed
+}
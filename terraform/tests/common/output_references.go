@@ -0,0 +1,247 @@
+package common
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// OutputReference is a Terraform output that a Go test reads by name
+type OutputReference struct {
+	Position string // file:line:column of the call
+	Module   string // Directory name of the module under modules/
+	Output   string
+}
+
+// modulePathPattern matches the module paths tests pass to NewTestConfig and GetModuleTerraformOptions
+var modulePathPattern = regexp.MustCompile(`^\.\./\.\./modules/([a-z0-9-]+)$`)
+
+// outputNameArgs returns the arguments of a pkg.name call that name outputs, or nil if the call reads none.
+// Readers take the output name as their third argument and RegisterEmptyBuckets takes one from the third argument
+// on; the Outputs validator takes them as every argument, SGRules and WebACLRules as their first,
+// WebACLAssociated as its first two and EmailAuthRecords as its second and third.
+func outputNameArgs(pkg, name string, args []ast.Expr) []ast.Expr {
+	switch {
+	case pkg == "terraform" && strings.HasPrefix(name, "Output") && !strings.HasPrefix(name, "OutputAll") &&
+		!strings.HasPrefix(name, "OutputForKeys") && len(args) > 2:
+		return args[2:3]
+	case pkg == "tfout" && strings.HasPrefix(name, "Output") && len(args) > 2:
+		return args[2:3]
+	case pkg == "common" && strings.HasPrefix(name, "ValidateTerraformOutput") && len(args) > 2:
+		return args[2:3]
+	case pkg == "common" && name == "RegisterEmptyBuckets" && len(args) > 2:
+		return args[2:]
+	case pkg == "common" && name == "Outputs":
+		return args
+	case pkg == "common" && (name == "SGRules" || name == "WebACLRules") && len(args) > 0:
+		return args[:1]
+	case pkg == "common" && name == "WebACLAssociated" && len(args) > 1:
+		return args[:2]
+	case pkg == "common" && name == "EmailAuthRecords" && len(args) > 2:
+		return args[1:3]
+	}
+	return nil
+}
+
+// isValidator reports whether pkg.name builds a validator, which reads outputs only once it is run against a module
+func isValidator(pkg, name string) bool {
+	return pkg == "common" &&
+		(name == "Outputs" || name == "SGRules" || name == "WebACLRules" || name == "WebACLAssociated" ||
+			name == "EmailAuthRecords")
+}
+
+// FindOutputReferences parses the _test.go files in a directory and returns every output read through terratest's
+// terraform.Output* functions, the tfout readers, common.ValidateTerraformOutput* or the Outputs, SGRules,
+// WebACLRules, WebACLAssociated and EmailAuthRecords validators. The module is taken from the module path or
+// SetupModuleTest name used in the same test function. Output names are resolved from string literals and from loops
+// over a local []string literal; anything else cannot be checked statically and is skipped.
+func FindOutputReferences(t *testing.T, testDir string) []OutputReference {
+	files, err := filepath.Glob(filepath.Join(testDir, "*_test.go"))
+	require.NoError(t, err)
+
+	fset := token.NewFileSet()
+	var references []OutputReference
+	for _, file := range files {
+		parsed, parseErr := parser.ParseFile(fset, file, nil, 0)
+		require.NoError(t, parseErr, "Failed to parse %s", file)
+
+		for _, decl := range parsed.Decls {
+			function, ok := decl.(*ast.FuncDecl)
+			if !ok || function.Body == nil {
+				continue
+			}
+			references = append(references, functionOutputReferences(t, fset, function)...)
+		}
+	}
+	return references
+}
+
+// functionOutputReferences returns the outputs read in one function, including its closures
+func functionOutputReferences(t *testing.T, fset *token.FileSet, function *ast.FuncDecl) []OutputReference {
+	modules := map[string]bool{}
+	stringLists := map[string][]string{} // Local variables assigned a []string literal
+	loopValues := map[string][]string{}  // Range variables over one of those lists
+	var calls []*ast.CallExpr
+	readsOutputs := false // Whether any call reads outputs itself, rather than building a validator that does
+
+	ast.Inspect(function.Body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.BasicLit:
+			if match := modulePathPattern.FindStringSubmatch(stringLiteral(n)); match != nil {
+				modules[match[1]] = true
+			}
+		case *ast.AssignStmt:
+			if len(n.Lhs) == 1 && len(n.Rhs) == 1 {
+				if ident, ok := n.Lhs[0].(*ast.Ident); ok {
+					if values, ok := stringListLiteral(n.Rhs[0]); ok {
+						stringLists[ident.Name] = values
+					}
+				}
+			}
+		case *ast.RangeStmt:
+			value, valueOK := n.Value.(*ast.Ident)
+			list, listOK := n.X.(*ast.Ident)
+			if valueOK && listOK {
+				if values, ok := stringLists[list.Name]; ok {
+					loopValues[value.Name] = values
+				}
+			}
+		case *ast.CallExpr:
+			pkg, name := selectorName(n.Fun)
+			if pkg == "common" && name == "SetupModuleTest" && len(n.Args) > 1 {
+				if module := stringLiteral(n.Args[1]); module != "" {
+					modules[module] = true
+				}
+			}
+			if len(outputNameArgs(pkg, name, n.Args)) > 0 {
+				calls = append(calls, n)
+				readsOutputs = readsOutputs || !isValidator(pkg, name)
+			}
+		}
+		return true
+	})
+
+	// Validators built without a module, such as in unit tests of the validators themselves, are never run
+	// against one
+	if len(calls) == 0 || (len(modules) == 0 && !readsOutputs) {
+		return nil
+	}
+	require.Len(t, modules, 1, "%s reads outputs but uses %d modules; cannot tell which module declares them",
+		function.Name.Name, len(modules))
+
+	var module string
+	for name := range modules {
+		module = name
+	}
+
+	var references []OutputReference
+	for _, call := range calls {
+		pkg, name := selectorName(call.Fun)
+		var outputs []string
+		for _, expr := range outputNameArgs(pkg, name, call.Args) {
+			switch arg := expr.(type) {
+			case *ast.BasicLit:
+				outputs = append(outputs, stringLiteral(arg))
+			case *ast.Ident:
+				outputs = append(outputs, loopValues[arg.Name]...)
+			}
+		}
+		for _, output := range outputs {
+			references = append(references, OutputReference{
+				Position: fset.Position(call.Pos()).String(),
+				Module:   module,
+				Output:   output,
+			})
+		}
+	}
+	return references
+}
+
+// selectorName splits a pkg.Name or generic pkg.Name[T] call target, returning empty strings for anything else
+func selectorName(expr ast.Expr) (string, string) {
+	switch generic := expr.(type) {
+	case *ast.IndexExpr:
+		expr = generic.X
+	case *ast.IndexListExpr:
+		expr = generic.X
+	}
+	selector, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "", ""
+	}
+	pkg, ok := selector.X.(*ast.Ident)
+	if !ok {
+		return "", ""
+	}
+	return pkg.Name, selector.Sel.Name
+}
+
+// stringLiteral returns the value of a string literal, or "" if the expression is not one
+func stringLiteral(expr ast.Expr) string {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+	value, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// stringListLiteral returns the values of a []string literal made up only of string literals
+func stringListLiteral(expr ast.Expr) ([]string, bool) {
+	composite, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil, false
+	}
+	values := make([]string, 0, len(composite.Elts))
+	for _, element := range composite.Elts {
+		value := stringLiteral(element)
+		if value == "" {
+			return nil, false
+		}
+		values = append(values, value)
+	}
+	return values, true
+}
+
+// GetModuleOutputs returns the names of the outputs declared in a module's .tf files
+func GetModuleOutputs(t *testing.T, modulePath string) map[string]bool {
+	files, err := filepath.Glob(filepath.Join(modulePath, "*.tf"))
+	require.NoError(t, err)
+
+	outputs := make(map[string]bool)
+	for _, file := range files {
+		for _, block := range parseTerraformFile(t, file).Blocks {
+			if block.Type == "output" && len(block.Labels) > 0 {
+				outputs[block.Labels[0]] = true
+			}
+		}
+	}
+	return outputs
+}
+
+// AssertOutputReferencesDeclared fails the test for every output the tests in testDir read that the module does
+// not declare, so a renamed or removed output is caught without waiting for an apply to reach the read
+func AssertOutputReferencesDeclared(t *testing.T, testDir, modulesDir string) {
+	references := FindOutputReferences(t, testDir)
+	require.NotEmpty(t, references, "No output references found in %s", testDir)
+
+	declared := map[string]map[string]bool{}
+	for _, reference := range references {
+		if _, ok := declared[reference.Module]; !ok {
+			declared[reference.Module] = GetModuleOutputs(t, filepath.Join(modulesDir, reference.Module))
+		}
+		assert.True(t, declared[reference.Module][reference.Output],
+			"%s reads output %s, which modules/%s does not declare", reference.Position, reference.Output, reference.Module)
+	}
+}
@@ -0,0 +1,178 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"terraform-tests/awscalls"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// DatabasePeerPorts are the only ports a peered VPC may reach the database subnets' security groups on
+var DatabasePeerPorts = []int32{5432}
+
+// GetVpcPeeringConnection gets a VPC peering connection by ID using AWS SDK v2 directly
+func GetVpcPeeringConnection(t *testing.T, peeringID, region string) *types.VpcPeeringConnection {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	result, err := ec2.NewFromConfig(cfg).DescribeVpcPeeringConnections(context.Background(),
+		&ec2.DescribeVpcPeeringConnectionsInput{VpcPeeringConnectionIds: []string{peeringID}})
+	require.NoError(t, err)
+	require.Len(t, result.VpcPeeringConnections, 1)
+
+	return &result.VpcPeeringConnections[0]
+}
+
+// AssertPeeringActive checks that a peering connection was accepted and joins the two VPCs
+func AssertPeeringActive(t *testing.T, peering *types.VpcPeeringConnection, requesterVpcID, accepterVpcID string) {
+	require.NotNil(t, peering.Status)
+	assert.Equal(t, types.VpcPeeringConnectionStateReasonCodeActive, peering.Status.Code,
+		"Peering %s should be active: %s", aws.ToString(peering.VpcPeeringConnectionId),
+		aws.ToString(peering.Status.Message))
+	assert.Equal(t, requesterVpcID, aws.ToString(peering.RequesterVpcInfo.VpcId))
+	assert.Equal(t, accepterVpcID, aws.ToString(peering.AccepterVpcInfo.VpcId))
+}
+
+// PeeringDNSResolution reports whether each side of a peering connection resolves the other side's private DNS
+// hostnames to private addresses. Options that were never set resolve them to public addresses.
+func PeeringDNSResolution(peering *types.VpcPeeringConnection) (requester, accepter bool) {
+	if info := peering.RequesterVpcInfo; info != nil && info.PeeringOptions != nil {
+		requester = aws.ToBool(info.PeeringOptions.AllowDnsResolutionFromRemoteVpc)
+	}
+	if info := peering.AccepterVpcInfo; info != nil && info.PeeringOptions != nil {
+		accepter = aws.ToBool(info.PeeringOptions.AllowDnsResolutionFromRemoteVpc)
+	}
+	return requester, accepter
+}
+
+// AssertVPCDNSEnabled checks that a VPC resolves DNS and gives instances hostnames, which private hostnames
+// resolved across a peering connection need on both sides
+func AssertVPCDNSEnabled(t *testing.T, vpcID, region string) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+	client := ec2.NewFromConfig(cfg)
+
+	support, err := client.DescribeVpcAttribute(context.Background(), &ec2.DescribeVpcAttributeInput{
+		VpcId:     aws.String(vpcID),
+		Attribute: types.VpcAttributeNameEnableDnsSupport,
+	})
+	require.NoError(t, err)
+	assert.True(t, aws.ToBool(support.EnableDnsSupport.Value), "VPC %s should have DNS support", vpcID)
+
+	hostnames, err := client.DescribeVpcAttribute(context.Background(), &ec2.DescribeVpcAttributeInput{
+		VpcId:     aws.String(vpcID),
+		Attribute: types.VpcAttributeNameEnableDnsHostnames,
+	})
+	require.NoError(t, err)
+	assert.True(t, aws.ToBool(hostnames.EnableDnsHostnames.Value), "VPC %s should have DNS hostnames", vpcID)
+}
+
+// AssertRouteToPeer checks that a route table sends the peer's CIDR block through the peering connection
+func AssertRouteToPeer(t *testing.T, routeTableID, region, peerCIDR, peeringID string) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	result, err := ec2.NewFromConfig(cfg).DescribeRouteTables(context.Background(), &ec2.DescribeRouteTablesInput{
+		RouteTableIds: []string{routeTableID},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.RouteTables, 1)
+
+	for _, route := range result.RouteTables[0].Routes {
+		if aws.ToString(route.DestinationCidrBlock) != peerCIDR {
+			continue
+		}
+		assert.Equal(t, peeringID, aws.ToString(route.VpcPeeringConnectionId),
+			"Route table %s should send %s through peering %s", routeTableID, peerCIDR, peeringID)
+		assert.Equal(t, types.RouteStateActive, route.State, "Route to %s in %s should be active", peerCIDR, routeTableID)
+		return
+	}
+	assert.Failf(t, "No route to peer", "Route table %s has no route to %s", routeTableID, peerCIDR)
+}
+
+// UnapprovedPeerIngress returns the group's ingress rules that admit the peer CIDR block, or a group reached
+// through a peering connection, on anything other than the approved TCP ports
+func UnapprovedPeerIngress(group *types.SecurityGroup, peerCIDR string, approvedPorts []int32) []string {
+	_, peerNetwork, err := net.ParseCIDR(peerCIDR)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid peer CIDR block %q", peerCIDR)}
+	}
+
+	var problems []string
+	for _, permission := range group.IpPermissions {
+		var sources []string
+		for _, ipRange := range permission.IpRanges {
+			if _, network, parseErr := net.ParseCIDR(aws.ToString(ipRange.CidrIp)); parseErr == nil &&
+				(network.Contains(peerNetwork.IP) || peerNetwork.Contains(network.IP)) {
+				sources = append(sources, aws.ToString(ipRange.CidrIp))
+			}
+		}
+		for _, pair := range permission.UserIdGroupPairs {
+			if aws.ToString(pair.VpcPeeringConnectionId) != "" {
+				sources = append(sources, aws.ToString(pair.GroupId)+" via "+aws.ToString(pair.VpcPeeringConnectionId))
+			}
+		}
+		if len(sources) == 0 || permissionOnApprovedPort(permission, approvedPorts) {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("%s admits %s on %s",
+			aws.ToString(group.GroupId), strings.Join(sources, ", "), describePorts(permission)))
+	}
+	return problems
+}
+
+// permissionOnApprovedPort reports whether a permission opens exactly one approved TCP port
+func permissionOnApprovedPort(permission types.IpPermission, approvedPorts []int32) bool {
+	protocol := aws.ToString(permission.IpProtocol)
+	if protocol != "tcp" && protocol != "6" {
+		return false
+	}
+	for _, port := range approvedPorts {
+		if aws.ToInt32(permission.FromPort) == port && aws.ToInt32(permission.ToPort) == port {
+			return true
+		}
+	}
+	return false
+}
+
+// describePorts returns a permission's protocol and port range for messages
+func describePorts(permission types.IpPermission) string {
+	protocol := aws.ToString(permission.IpProtocol)
+	if protocol == "-1" {
+		return "all traffic"
+	}
+	from, to := aws.ToInt32(permission.FromPort), aws.ToInt32(permission.ToPort)
+	if from == to {
+		return fmt.Sprintf("%s/%d", protocol, from)
+	}
+	return fmt.Sprintf("%s/%d-%d", protocol, from, to)
+}
+
+// AssertPeerReachesOnlyApprovedPorts checks every security group in a VPC, so a group added later that opens the
+// database subnets to the peer is caught, not only the database security group
+func AssertPeerReachesOnlyApprovedPorts(t *testing.T, vpcID, region, peerCIDR string, approvedPorts []int32) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	paginator := ec2.NewDescribeSecurityGroupsPaginator(ec2.NewFromConfig(cfg), &ec2.DescribeSecurityGroupsInput{
+		Filters: []types.Filter{{Name: aws.String("vpc-id"), Values: []string{vpcID}}},
+	})
+	for paginator.HasMorePages() {
+		page, pageErr := paginator.NextPage(context.Background())
+		require.NoError(t, pageErr)
+		for i := range page.SecurityGroups {
+			for _, problem := range UnapprovedPeerIngress(&page.SecurityGroups[i], peerCIDR, approvedPorts) {
+				t.Errorf("Peer %s should only reach ports %v: %s", peerCIDR, approvedPorts, problem)
+			}
+		}
+	}
+}
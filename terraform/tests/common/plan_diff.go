@@ -0,0 +1,230 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// knownAfterApply is how unknown planned values are rendered in a plan diff
+const knownAfterApply = "(known after apply)"
+
+// AttributeChange is a single attribute whose planned value differs between two plans
+type AttributeChange struct {
+	Path   string
+	Before string
+	After  string
+}
+
+// ResourceDiff lists the attribute changes of a resource planned on both sides
+type ResourceDiff struct {
+	Address    string
+	Attributes []AttributeChange
+}
+
+// PlanDiff is the semantic difference between a base plan and a head plan
+type PlanDiff struct {
+	Added   []string
+	Removed []string
+	Changed []ResourceDiff
+}
+
+// IsEmpty reports whether both plans describe the same resources with the same values
+func (d *PlanDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffPlans compares the planned values of two plans resource by resource and attribute by attribute
+func DiffPlans(base, head *terraform.PlanStruct) *PlanDiff {
+	baseValues := NormalizePlan(base)
+	headValues := NormalizePlan(head)
+	diff := &PlanDiff{}
+
+	for _, address := range sortedKeys(headValues) {
+		baseAttributes, exists := baseValues[address]
+		if !exists {
+			diff.Added = append(diff.Added, address)
+			continue
+		}
+
+		headAttributes := headValues[address]
+		var changes []AttributeChange
+		for _, path := range sortedKeys(mergeKeys(baseAttributes, headAttributes)) {
+			before, after := baseAttributes[path], headAttributes[path]
+			if before != after {
+				changes = append(changes, AttributeChange{Path: path, Before: before, After: after})
+			}
+		}
+		if len(changes) > 0 {
+			diff.Changed = append(diff.Changed, ResourceDiff{Address: address, Attributes: changes})
+		}
+	}
+
+	for _, address := range sortedKeys(baseValues) {
+		if _, exists := headValues[address]; !exists {
+			diff.Removed = append(diff.Removed, address)
+		}
+	}
+
+	return diff
+}
+
+// NormalizePlan flattens every planned resource into attribute path -> rendered value,
+// with values that are only known after apply rendered as a placeholder
+func NormalizePlan(plan *terraform.PlanStruct) map[string]map[string]string {
+	normalized := map[string]map[string]string{}
+	for address, resource := range plan.ResourcePlannedValuesMap {
+		attributes := map[string]string{}
+		flattenValue("", resource.AttributeValues, attributes)
+
+		if change, exists := plan.ResourceChangesMap[address]; exists && change.Change != nil {
+			unknown := map[string]string{}
+			flattenValue("", change.Change.AfterUnknown, unknown)
+			for path, value := range unknown {
+				if value == "true" {
+					attributes[path] = knownAfterApply
+				}
+			}
+		}
+
+		normalized[address] = attributes
+	}
+	return normalized
+}
+
+// flattenValue writes nested maps and lists into dotted attribute paths
+func flattenValue(path string, value interface{}, into map[string]string) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range typed {
+			flattenValue(joinPath(path, key), nested, into)
+		}
+	case []interface{}:
+		for index, nested := range typed {
+			flattenValue(joinPath(path, fmt.Sprint(index)), nested, into)
+		}
+	case nil:
+		// Unset attributes are equivalent to absent ones
+	default:
+		rendered, err := json.Marshal(typed)
+		if err != nil {
+			rendered = []byte(fmt.Sprint(typed))
+		}
+		into[path] = string(rendered)
+	}
+}
+
+// joinPath appends a key to a dotted attribute path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Markdown renders the diff as a report for pull request reviewers
+func (d *PlanDiff) Markdown(baseRef, headRef string) string {
+	var report strings.Builder
+	fmt.Fprintf(&report, "## Terraform plan diff: `%s` → `%s`\n\n", baseRef, headRef)
+
+	if d.IsEmpty() {
+		report.WriteString("No differences in planned resources.\n")
+		return report.String()
+	}
+
+	fmt.Fprintf(&report, "| Added | Removed | Changed |\n| --- | --- | --- |\n| %d | %d | %d |\n\n",
+		len(d.Added), len(d.Removed), len(d.Changed))
+
+	writeAddresses := func(title string, addresses []string) {
+		if len(addresses) == 0 {
+			return
+		}
+		fmt.Fprintf(&report, "### %s\n\n", title)
+		for _, address := range addresses {
+			fmt.Fprintf(&report, "- `%s`\n", address)
+		}
+		report.WriteString("\n")
+	}
+	writeAddresses("Added", d.Added)
+	writeAddresses("Removed", d.Removed)
+
+	if len(d.Changed) > 0 {
+		report.WriteString("### Changed\n\n")
+		for _, resource := range d.Changed {
+			fmt.Fprintf(&report, "#### `%s`\n\n| Attribute | Before | After |\n| --- | --- | --- |\n", resource.Address)
+			for _, change := range resource.Attributes {
+				fmt.Fprintf(&report, "| `%s` | %s | %s |\n",
+					change.Path, markdownCell(change.Before), markdownCell(change.After))
+			}
+			report.WriteString("\n")
+		}
+	}
+
+	return report.String()
+}
+
+// markdownCell renders a value safely inside a markdown table cell
+func markdownCell(value string) string {
+	if value == "" {
+		return "_(unset)_"
+	}
+	value = strings.ReplaceAll(value, "|", "\\|")
+	value = strings.ReplaceAll(value, "\n", " ")
+	return "`" + value + "`"
+}
+
+// CheckoutGitRef checks out a git ref into a temporary worktree and returns the directory of terraformDir
+// within it. The worktree is removed when the test finishes.
+func CheckoutGitRef(t *testing.T, terraformDir, ref string) string {
+	absDir, err := filepath.Abs(terraformDir)
+	require.NoError(t, err)
+
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = absDir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, "git %s failed: %s", strings.Join(args, " "), output)
+		return strings.TrimSpace(string(output))
+	}
+
+	// Path of the terraform directory relative to the repository root, e.g. "terraform/"
+	relativeDir := git("rev-parse", "--show-prefix")
+	worktree := filepath.Join(t.TempDir(), "worktree")
+
+	git("worktree", "add", "--detach", worktree, ref)
+	t.Cleanup(func() {
+		git("worktree", "remove", "--force", worktree)
+	})
+
+	t.Logf("Checked out %s into %s", ref, worktree)
+	return filepath.Join(worktree, relativeDir)
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mergeKeys returns the union of the keys of two attribute maps
+func mergeKeys(a, b map[string]string) map[string]string {
+	merged := make(map[string]string, len(a)+len(b))
+	for key := range a {
+		merged[key] = ""
+	}
+	for key := range b {
+		merged[key] = ""
+	}
+	return merged
+}
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// PlanAndShow runs terraform init and plan and returns the parsed plan JSON. Module tests plan through PlanOnly,
+// which checks the options were built for a plan; PlanAndShow is for planning a change to a configuration the test
+// has already applied.
+func PlanAndShow(t *testing.T, terraformOptions *terraform.Options) *terraform.PlanStruct {
+	plan, err := PlanAndShowE(t, terraformOptions)
+	require.NoError(t, err)
+	return plan
+}
+
+// PlanAndShowE is PlanAndShow returning the plan's error instead of failing the test
+func PlanAndShowE(t *testing.T, terraformOptions *terraform.Options) (*terraform.PlanStruct, error) {
+	// Terratest needs a plan file to render the plan as JSON
+	if terraformOptions.PlanFilePath == "" {
+		terraformOptions.PlanFilePath = filepath.Join(t.TempDir(), "plan.out")
+	}
+
+	t.Logf("Planning %s", terraformOptions.TerraformDir)
+	return terraform.InitAndPlanAndShowWithStructE(t, terraformOptions)
+}
+
+// GetPlannedResourcesByType returns the planned values of every resource of the given type
+func GetPlannedResourcesByType(plan *terraform.PlanStruct, resourceType string) []*tfjson.StateResource {
+	var resources []*tfjson.StateResource
+	for _, resource := range plan.ResourcePlannedValuesMap {
+		if resource.Type == resourceType {
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// GetPlannedStringAttribute returns a string attribute from a planned resource, or "" if unknown or unset
+func GetPlannedStringAttribute(resource *tfjson.StateResource, attribute string) string {
+	value, ok := resource.AttributeValues[attribute].(string)
+	if !ok {
+		return ""
+	}
+	return value
+}
+
+// GetPlannedOutput returns the planned value of a root module output, or nil if it is unknown or not declared
+func GetPlannedOutput(plan *terraform.PlanStruct, name string) interface{} {
+	if plan.RawPlan.PlannedValues == nil {
+		return nil
+	}
+	output, exists := plan.RawPlan.PlannedValues.Outputs[name]
+	if !exists {
+		return nil
+	}
+	return output.Value
+}
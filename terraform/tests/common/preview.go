@@ -0,0 +1,198 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"terraform-tests/az"
+	"terraform-tests/s3util"
+	"terraform-tests/smoketest"
+	"terraform-tests/tfopts"
+
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// PreviewScenario is the minimal stack a pull request's preview environment runs on, relative to a test package
+const PreviewScenario = "../scenarios/preview"
+
+// SharedPreviewDatabase is an existing database instance previews get a database on instead of an instance of their
+// own, with the VPC and subnets it is reached from
+type SharedPreviewDatabase struct {
+	Endpoint         string
+	VPCID            string
+	PrivateSubnetIDs []string
+	DBSubnetCIDRs    []string
+}
+
+// PreviewDatabaseName is the database a pull request's preview uses, on its own instance or the shared one
+func PreviewDatabaseName(pullRequest int) string {
+	return fmt.Sprintf("coalition_pr_%d", pullRequest)
+}
+
+// PreviewVars returns the preview scenario's variables for a pull request, on a shared database when one is given
+func PreviewVars(pullRequest int, shared *SharedPreviewDatabase) map[string]interface{} {
+	vars := map[string]interface{}{
+		"db_name":         PreviewDatabaseName(pullRequest),
+		"app_db_username": "app_user",
+		"db_password":     tfopts.NewUniqueID() + "-Db1",
+		"app_db_password": tfopts.NewUniqueID() + "-App1",
+	}
+	if shared != nil {
+		vars["shared_database"] = map[string]interface{}{
+			"endpoint":           shared.Endpoint,
+			"vpc_id":             shared.VPCID,
+			"private_subnet_ids": shared.PrivateSubnetIDs,
+			"db_subnet_cidrs":    shared.DBSubnetCIDRs,
+		}
+	}
+	return vars
+}
+
+// PreviewEnvironment is a pull request's preview environment: the preview scenario applied in a namespace of its
+// own, so previews of different pull requests, and the tests, share an account without colliding
+type PreviewEnvironment struct {
+	PullRequest int
+	Namespace   *Namespace
+	Options     *terraform.Options
+}
+
+// NewPreviewEnvironment returns the preview environment of a pull request, ready to create. Like any apply test's
+// configuration, it is destroyed when the test ends, its Zappa bucket emptied first, and fails the test if it leaves
+// anything tagged with its namespace behind.
+func NewPreviewEnvironment(t *testing.T, pullRequest int, shared *SharedPreviewDatabase) *PreviewEnvironment {
+	namespace := NewNamespace(tfopts.NewTestConfig(PreviewScenario), fmt.Sprintf("pr-%d", pullRequest))
+	vars := PreviewVars(pullRequest, shared)
+	if shared == nil {
+		vars["availability_zones"] = az.PickTwo(t, namespace.Config.AWSRegion)
+	}
+	terraformOptions := namespace.GetModuleTerraformOptions(t, PreviewScenario, vars).Options
+
+	RegisterDestroy(t, terraformOptions)
+	RegisterEmptyBuckets(t, terraformOptions, "zappa_bucket_name")
+	RegisterNamespaceLeakCheck(t, namespace)
+	return &PreviewEnvironment{PullRequest: pullRequest, Namespace: namespace, Options: terraformOptions}
+}
+
+// Create applies the preview's infrastructure
+func (p *PreviewEnvironment) Create(t *testing.T) {
+	terraform.InitAndApply(t, p.Options)
+}
+
+// DeployEnvironment returns what the deploy command needs to know about the preview's infrastructure, as PREVIEW_*
+// environment variables
+func (p *PreviewEnvironment) DeployEnvironment(t *testing.T) map[string]string {
+	outputs := terraform.OutputAll(t, p.Options)
+	subnets := make([]string, 0)
+	for _, subnet := range outputs["private_subnet_ids"].([]interface{}) {
+		subnets = append(subnets, fmt.Sprint(subnet))
+	}
+	return map[string]string{
+		"PREVIEW_PREFIX":                   p.Namespace.Prefix,
+		"PREVIEW_STAGE":                    fmt.Sprintf("pr%d", p.PullRequest),
+		"PREVIEW_AWS_REGION":               p.Namespace.Config.AWSRegion,
+		"PREVIEW_ZAPPA_BUCKET":             fmt.Sprint(outputs["zappa_bucket_name"]),
+		"PREVIEW_ZAPPA_ROLE_ARN":           fmt.Sprint(outputs["zappa_role_arn"]),
+		"PREVIEW_LAMBDA_SECURITY_GROUP_ID": fmt.Sprint(outputs["lambda_security_group_id"]),
+		"PREVIEW_SUBNET_IDS":               strings.Join(subnets, ","),
+		"PREVIEW_DATABASE_SECRET_ARN":      fmt.Sprint(outputs["db_url_secret_arn"]),
+		"PREVIEW_SECRET_KEY_SECRET_ARN":    fmt.Sprint(outputs["secret_key_secret_arn"]),
+		"PREVIEW_DATABASE_NAME":            fmt.Sprint(outputs["db_name"]),
+	}
+}
+
+// deployedURLPattern matches the URL a deploy command prints for the deployed API, as Zappa does
+var deployedURLPattern = regexp.MustCompile(`https://[a-z0-9]+\.execute-api\.[a-z0-9-]+\.amazonaws\.com/[A-Za-z0-9_-]+`)
+
+// DeployedAPIURL returns the last execute-api URL in a deploy command's output, which is the stage Zappa deployed
+// or updated
+func DeployedAPIURL(output string) (string, bool) {
+	matches := deployedURLPattern.FindAllString(output, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+	return matches[len(matches)-1], true
+}
+
+// Deploy runs the command that deploys the application into the preview, such as a Zappa deploy, with
+// DeployEnvironment's variables set, and returns the API URL it printed
+func (p *PreviewEnvironment) Deploy(t *testing.T, command string) string {
+	output, err := shell.RunCommandAndGetOutputE(t, shell.Command{
+		Command: "bash",
+		Args:    []string{"-c", command},
+		Env:     p.DeployEnvironment(t),
+	})
+	require.NoError(t, err, "Deploy command failed")
+
+	apiURL, found := DeployedAPIURL(output)
+	require.True(t, found, "The deploy command should print the execute-api URL of the preview's API")
+	return apiURL
+}
+
+// Smoke runs the smoke checks against the preview's API, and its frontend when given the frontend's preview URL
+func (p *PreviewEnvironment) Smoke(t *testing.T, apiURL string, frontendURLs ...string) *smoketest.Report {
+	environment, err := smoketest.EnvironmentFromURLs(apiURL, frontendURLs...)
+	require.NoError(t, err)
+	return smoketest.NewChecker(smoketest.Options{}).Run(context.Background(), environment)
+}
+
+// Destroy empties the Zappa bucket and destroys the preview now, rather than when the test ends
+func (p *PreviewEnvironment) Destroy(t *testing.T) {
+	require.NoError(t, p.DestroyE(t), "Failed to destroy the preview of pull request #%d", p.PullRequest)
+}
+
+// DestroyE is Destroy returning the destroy's error instead of failing the test
+func (p *PreviewEnvironment) DestroyE(t *testing.T) error {
+	bucket, err := terraform.OutputE(t, p.Options, "zappa_bucket_name")
+	if err == nil && bucket != "" {
+		if _, err = s3util.EmptyBucketE(t, bucket); err != nil {
+			return err
+		}
+	}
+	return DestroyWithRetryE(t, p.Options, DefaultDestroyRetryPolicy())
+}
+
+// PreviewRequest is the preview a CI job asked for, from PREVIEW_* variables
+type PreviewRequest struct {
+	PullRequest    int
+	DeployCommand  string
+	FrontendURL    string
+	SharedDatabase *SharedPreviewDatabase
+}
+
+// GetPreviewRequest loads the preview to create, skipping the test unless PREVIEW_PULL_REQUEST names the pull
+// request. PREVIEW_DEPLOY_COMMAND deploys the application into it, and without one only the infrastructure is
+// created and destroyed. PREVIEW_SHARED_DB_ENDPOINT, with PREVIEW_SHARED_VPC_ID, PREVIEW_SHARED_SUBNET_IDS and
+// PREVIEW_SHARED_DB_SUBNET_CIDRS, puts the preview's database on an existing instance.
+func GetPreviewRequest(t *testing.T) *PreviewRequest {
+	RequireTier(t, TierApply)
+
+	pullRequest := os.Getenv("PREVIEW_PULL_REQUEST")
+	if pullRequest == "" {
+		t.Skip("Skipping preview environment - set PREVIEW_PULL_REQUEST to the pull request to preview")
+	}
+	number, err := strconv.Atoi(pullRequest)
+	require.NoError(t, err, "PREVIEW_PULL_REQUEST should be a pull request number")
+
+	request := &PreviewRequest{
+		PullRequest:   number,
+		DeployCommand: os.Getenv("PREVIEW_DEPLOY_COMMAND"),
+		FrontendURL:   os.Getenv("PREVIEW_FRONTEND_URL"),
+	}
+	if endpoint := os.Getenv("PREVIEW_SHARED_DB_ENDPOINT"); endpoint != "" {
+		request.SharedDatabase = &SharedPreviewDatabase{
+			Endpoint:         endpoint,
+			VPCID:            os.Getenv("PREVIEW_SHARED_VPC_ID"),
+			PrivateSubnetIDs: strings.Split(os.Getenv("PREVIEW_SHARED_SUBNET_IDS"), ","),
+			DBSubnetCIDRs:    strings.Split(os.Getenv("PREVIEW_SHARED_DB_SUBNET_CIDRS"), ","),
+		}
+		require.NotEmpty(t, request.SharedDatabase.VPCID, "Set PREVIEW_SHARED_VPC_ID with PREVIEW_SHARED_DB_ENDPOINT")
+	}
+	return request
+}
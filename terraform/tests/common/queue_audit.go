@@ -0,0 +1,181 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// DeadLetterQueueSuffix ends the name of every dead-letter queue, which is how the audit tells them from the
+// queues workers consume
+const DeadLetterQueueSuffix = "-dlq"
+
+// Redrive limits for worker queues: a message is retried at least once and at most MaxReceiveCountLimit times
+// before it moves to the dead-letter queue, which keeps it for SQS's maximum of 14 days
+const (
+	MaxReceiveCountLimit       = 10
+	DeadLetterRetentionSeconds = 1209600
+)
+
+// QueueWorkerRuntimes is the longest a worker may take to process one message from each queue, keyed by the
+// queue's Terraform resource name. Visibility timeouts must be at least this long, or a message still being
+// processed is handed to a second worker. For Lambda consumers, record six times the function timeout, as AWS
+// recommends. No module creates queues yet, so it is empty.
+var QueueWorkerRuntimes = map[string]time.Duration{}
+
+// QueueAuditChecks returns the checks for SQS queues and the IAM policies granting access to them
+func QueueAuditChecks() []AuditCheck {
+	return []AuditCheck{
+		CheckSQSRedrive,
+		CheckSQSVisibilityTimeout,
+		CheckSQSAccessScoped,
+		func(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+			if resource.Type != "aws_sqs_queue" {
+				return nil
+			}
+			return CheckEncryptionAtRest(resource, audit)
+		},
+	}
+}
+
+// isDeadLetterQueue reports whether a planned queue is a dead-letter queue, by its name
+func isDeadLetterQueue(resource *tfjson.StateResource) bool {
+	name := strings.TrimSuffix(GetPlannedStringAttribute(resource, "name"), ".fifo")
+	return strings.HasSuffix(name, DeadLetterQueueSuffix)
+}
+
+// CheckSQSRedrive verifies worker queues hand failing messages to a dead-letter queue after a bounded number of
+// receives, and that dead-letter queues keep them long enough to investigate
+func CheckSQSRedrive(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if resource.Type != "aws_sqs_queue" {
+		return nil
+	}
+
+	if isDeadLetterQueue(resource) {
+		retention, _ := resource.AttributeValues["message_retention_seconds"].(float64)
+		return []AuditFinding{passFail("SQS-DLQ-Retention", resource, retention == DeadLetterRetentionSeconds,
+			fmt.Sprintf("dead-letter queue keeps messages for %.0f seconds, expected %d", retention,
+				DeadLetterRetentionSeconds))}
+	}
+
+	if audit.IsUnknown(resource, "redrive_policy") {
+		// The policy names the dead-letter queue's ARN, which is only known once it exists
+		return []AuditFinding{unknown("SQS-Redrive", resource, "redrive_policy")}
+	}
+	policy := GetPlannedStringAttribute(resource, "redrive_policy")
+	return []AuditFinding{passFail("SQS-Redrive", resource, RedrivePolicyProblem(policy) == "",
+		RedrivePolicyProblem(policy))}
+}
+
+// RedrivePolicyProblem describes what is wrong with a queue's redrive policy, or returns "" if nothing is
+func RedrivePolicyProblem(policy string) string {
+	if policy == "" {
+		return "queue has no dead-letter queue"
+	}
+
+	var redrive struct {
+		DeadLetterTargetArn string      `json:"deadLetterTargetArn"`
+		MaxReceiveCount     json.Number `json:"maxReceiveCount"`
+	}
+	if err := json.Unmarshal([]byte(policy), &redrive); err != nil {
+		return fmt.Sprintf("redrive policy is not valid JSON: %v", err)
+	}
+	if redrive.DeadLetterTargetArn == "" {
+		return "redrive policy names no dead-letter queue"
+	}
+	count, err := redrive.MaxReceiveCount.Int64()
+	if err != nil || count < 1 || count > MaxReceiveCountLimit {
+		return fmt.Sprintf("maxReceiveCount is %q, expected 1 to %d", redrive.MaxReceiveCount, MaxReceiveCountLimit)
+	}
+	return ""
+}
+
+// CheckSQSVisibilityTimeout verifies a worker queue hides a received message for at least as long as its worker
+// may take to process it
+func CheckSQSVisibilityTimeout(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_sqs_queue" || isDeadLetterQueue(resource) {
+		return nil
+	}
+
+	runtime, known := QueueWorkerRuntimes[resource.Name]
+	if !known {
+		return []AuditFinding{passFail("SQS-Visibility-Timeout", resource, false,
+			fmt.Sprintf("no worker runtime recorded; add %q to common.QueueWorkerRuntimes", resource.Name))}
+	}
+
+	seconds, _ := resource.AttributeValues["visibility_timeout_seconds"].(float64)
+	visibility := time.Duration(seconds) * time.Second
+	return []AuditFinding{passFail("SQS-Visibility-Timeout", resource, visibility >= runtime,
+		fmt.Sprintf("visibility timeout is %s, shorter than the worker runtime of %s", visibility, runtime))}
+}
+
+// CheckSQSAccessScoped verifies IAM policies grant SQS actions on specific queues rather than every queue, and
+// never sqs:* itself
+func CheckSQSAccessScoped(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	switch resource.Type {
+	case "aws_iam_policy", "aws_iam_role_policy", "aws_iam_user_policy":
+	default:
+		return nil
+	}
+
+	if audit.IsUnknown(resource, "policy") {
+		return []AuditFinding{unknown("SQS-Access-Scoped", resource, "policy")}
+	}
+	problems := SQSPolicyProblems(GetPlannedStringAttribute(resource, "policy"))
+	if problems == nil {
+		// Grants nothing on SQS
+		return nil
+	}
+	return []AuditFinding{passFail("SQS-Access-Scoped", resource, len(problems) == 0, strings.Join(problems, "; "))}
+}
+
+// SQSPolicyProblems lists the ways an IAM policy grants SQS access beyond specific queues. It returns nil if the
+// policy allows no SQS actions, and an empty list if it allows them only on specific queues.
+func SQSPolicyProblems(policy string) []string {
+	var document struct {
+		Statement []struct {
+			Effect   string          `json:"Effect"`
+			Action   json.RawMessage `json:"Action"`
+			Resource json.RawMessage `json:"Resource"`
+		} `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(policy), &document); err != nil {
+		return []string{fmt.Sprintf("policy is not valid JSON: %v", err)}
+	}
+
+	var problems []string
+	for i, statement := range document.Statement {
+		if statement.Effect != "Allow" {
+			continue
+		}
+
+		var sqsActions []string
+		for _, action := range stringOrList(statement.Action) {
+			if action == "*" || strings.HasPrefix(strings.ToLower(action), "sqs:") {
+				sqsActions = append(sqsActions, action)
+			}
+		}
+		if len(sqsActions) == 0 {
+			continue
+		}
+		if problems == nil {
+			problems = []string{}
+		}
+
+		for _, action := range sqsActions {
+			if action == "*" || strings.EqualFold(action, "sqs:*") {
+				problems = append(problems, fmt.Sprintf("statement %d allows %s", i, action))
+			}
+		}
+		for _, queue := range stringOrList(statement.Resource) {
+			if !strings.HasPrefix(queue, "arn:aws:sqs:") || strings.Contains(queue, "*") {
+				problems = append(problems, fmt.Sprintf("statement %d grants %s on %s", i,
+					strings.Join(sqsActions, ", "), queue))
+			}
+		}
+	}
+	return problems
+}
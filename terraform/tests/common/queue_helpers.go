@@ -0,0 +1,142 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/tfopts"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// GetQueueAttributes returns every attribute of an SQS queue
+func GetQueueAttributes(t *testing.T, queueURL, region string) map[string]string {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	result, err := sqs.NewFromConfig(cfg).GetQueueAttributes(context.Background(), &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameAll},
+	})
+	require.NoError(t, err)
+	return result.Attributes
+}
+
+// ValidateWorkerQueue asserts a deployed worker queue is encrypted, redrives to a dead-letter queue within
+// MaxReceiveCountLimit receives, and hides messages for at least the worker's runtime
+func ValidateWorkerQueue(t *testing.T, queueURL, region string, workerRuntime time.Duration) {
+	attributes := GetQueueAttributes(t, queueURL, region)
+
+	encrypted := attributes[string(sqstypes.QueueAttributeNameSqsManagedSseEnabled)] == "true" ||
+		attributes[string(sqstypes.QueueAttributeNameKmsMasterKeyId)] != ""
+	assert.True(t, encrypted, "Queue %s should be encrypted at rest", queueURL)
+
+	assert.Empty(t, RedrivePolicyProblem(attributes[string(sqstypes.QueueAttributeNameRedrivePolicy)]),
+		"Queue %s should redrive to a dead-letter queue", queueURL)
+
+	visibility, err := strconv.Atoi(attributes[string(sqstypes.QueueAttributeNameVisibilityTimeout)])
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Duration(visibility)*time.Second, workerRuntime,
+		"Queue %s should hide messages for at least the worker runtime", queueURL)
+}
+
+// GetDeadLetterQueueURL returns the URL of the dead-letter queue a queue's redrive policy names
+func GetDeadLetterQueueURL(t *testing.T, queueURL, region string) string {
+	var redrive struct {
+		DeadLetterTargetArn string `json:"deadLetterTargetArn"`
+	}
+	policy := GetQueueAttributes(t, queueURL, region)[string(sqstypes.QueueAttributeNameRedrivePolicy)]
+	require.NoError(t, json.Unmarshal([]byte(policy), &redrive), "Queue %s has no redrive policy", queueURL)
+
+	// The queue name is the last part of the ARN: arn:aws:sqs:<region>:<account>:<name>
+	parts := strings.Split(redrive.DeadLetterTargetArn, ":")
+	require.Len(t, parts, 6, "Unexpected dead-letter queue ARN %q", redrive.DeadLetterTargetArn)
+
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+	result, err := sqs.NewFromConfig(cfg).GetQueueUrl(context.Background(), &sqs.GetQueueUrlInput{
+		QueueName:              aws.String(parts[5]),
+		QueueOwnerAWSAccountId: aws.String(parts[4]),
+	})
+	require.NoError(t, err)
+	return aws.ToString(result.QueueUrl)
+}
+
+// AssertQueueRoundTrip sends a uniquely identifiable message to a queue, receives it back and deletes it.
+// Run it against queues no worker is consuming, or the worker may take the message first.
+func AssertQueueRoundTrip(t *testing.T, queueURL, region string) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+	svc := sqs.NewFromConfig(cfg)
+
+	body := fmt.Sprintf(`{"test":%q,"id":%q}`, t.Name(), tfopts.NewUniqueID())
+	input := &sqs.SendMessageInput{QueueUrl: aws.String(queueURL), MessageBody: aws.String(body)}
+	if strings.HasSuffix(queueURL, ".fifo") {
+		input.MessageGroupId = aws.String("terratest")
+		input.MessageDeduplicationId = aws.String(tfopts.NewUniqueID())
+	}
+	_, err = svc.SendMessage(context.Background(), input)
+	require.NoError(t, err)
+
+	retry.DoWithRetry(t, fmt.Sprintf("Receive test message from %s", queueURL), 6, time.Second,
+		func() (string, error) {
+			result, receiveErr := svc.ReceiveMessage(context.Background(), &sqs.ReceiveMessageInput{
+				QueueUrl:            aws.String(queueURL),
+				MaxNumberOfMessages: 10,
+				WaitTimeSeconds:     10,
+			})
+			if receiveErr != nil {
+				return "", receiveErr
+			}
+			for _, message := range result.Messages {
+				if aws.ToString(message.Body) != body {
+					continue
+				}
+				_, deleteErr := svc.DeleteMessage(context.Background(), &sqs.DeleteMessageInput{
+					QueueUrl:      aws.String(queueURL),
+					ReceiptHandle: message.ReceiptHandle,
+				})
+				return "", deleteErr
+			}
+			return "", fmt.Errorf("test message not received yet")
+		})
+}
+
+// DeployedWorkerQueue describes a worker queue of an already-deployed stack, from E2E_WORKER_* variables
+type DeployedWorkerQueue struct {
+	Region     string
+	QueueURL   string
+	MaxRuntime time.Duration
+}
+
+// GetDeployedWorkerQueue loads the deployed worker queue, skipping the test when none is configured
+func GetDeployedWorkerQueue(t *testing.T) *DeployedWorkerQueue {
+	RequireTier(t, TierE2E)
+
+	queueURL := os.Getenv("E2E_WORKER_QUEUE_URL")
+	if queueURL == "" {
+		t.Skip("Skipping end-to-end test - set E2E_WORKER_QUEUE_URL and E2E_WORKER_MAX_RUNTIME to a deployed " +
+			"worker queue")
+	}
+
+	maxRuntime, err := time.ParseDuration(os.Getenv("E2E_WORKER_MAX_RUNTIME"))
+	require.NoError(t, err, "E2E_WORKER_MAX_RUNTIME must be a duration such as 5m")
+
+	deployed := &DeployedWorkerQueue{Region: os.Getenv("AWS_REGION"), QueueURL: queueURL, MaxRuntime: maxRuntime}
+	if deployed.Region == "" {
+		deployed.Region = "us-east-1"
+	}
+	return deployed
+}
@@ -0,0 +1,242 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"terraform-tests/tfopts"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ProviderRequirement is one entry of a module's required_providers block
+type ProviderRequirement struct {
+	Name    string // The local name, such as aws
+	Source  string
+	Version string // The version constraint
+}
+
+// GetProviderRequirements parses the required_providers of a module's terraform blocks, in any of its .tf files
+func GetProviderRequirements(t *testing.T, modulePath string) []ProviderRequirement {
+	var requirements []ProviderRequirement
+	for _, body := range parseModuleFiles(t, modulePath) {
+		for _, block := range body.Blocks {
+			if block.Type != "terraform" {
+				continue
+			}
+			for _, providers := range block.Body.Blocks {
+				if providers.Type != "required_providers" {
+					continue
+				}
+				for name, attribute := range providers.Body.Attributes {
+					requirements = append(requirements, providerRequirement(name, attribute.Expr))
+				}
+			}
+		}
+	}
+	sort.Slice(requirements, func(i, j int) bool { return requirements[i].Name < requirements[j].Name })
+	return requirements
+}
+
+// providerRequirement reads a required_providers entry. Entries are objects whose configuration_aliases hold
+// references, so only the source and version items are evaluated.
+func providerRequirement(name string, expr hclsyntax.Expression) ProviderRequirement {
+	requirement := ProviderRequirement{Name: name}
+	object, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return requirement
+	}
+	for _, item := range object.Items {
+		key, diags := item.KeyExpr.Value(nil)
+		if diags.HasErrors() || key.Type() != cty.String {
+			continue
+		}
+		value, diags := item.ValueExpr.Value(nil)
+		if diags.HasErrors() || value.Type() != cty.String {
+			continue
+		}
+		switch key.AsString() {
+		case "source":
+			requirement.Source = value.AsString()
+		case "version":
+			requirement.Version = value.AsString()
+		}
+	}
+	return requirement
+}
+
+// parseModuleFiles parses every .tf file directly in a module directory, keyed by file name
+func parseModuleFiles(t *testing.T, modulePath string) map[string]*hclsyntax.Body {
+	files, err := filepath.Glob(filepath.Join(modulePath, "*.tf"))
+	require.NoError(t, err)
+
+	bodies := make(map[string]*hclsyntax.Body, len(files))
+	for _, file := range files {
+		bodies[filepath.Base(file)] = parseTerraformFile(t, file)
+	}
+	return bodies
+}
+
+// ProviderConstraintProblem describes why a version constraint cannot be published, or returns "" if it can. A
+// module must give each provider a lower bound, so consumers get a version the module was written against.
+func ProviderConstraintProblem(constraint string) string {
+	if strings.TrimSpace(constraint) == "" {
+		return "no version constraint"
+	}
+	constraints, err := version.NewConstraint(constraint)
+	if err != nil {
+		return fmt.Sprintf("invalid version constraint %q: %v", constraint, err)
+	}
+	for _, part := range constraints {
+		switch operator, _ := splitConstraint(part); operator {
+		case ">=", ">", "~>", "=", "":
+			return ""
+		}
+	}
+	return fmt.Sprintf("version constraint %q has no lower bound", constraint)
+}
+
+// splitConstraint returns the operator of one part of a version constraint, and the version it compares to
+func splitConstraint(part *version.Constraint) (string, *version.Version) {
+	text := strings.TrimSpace(part.String())
+	operator := text[:len(text)-len(strings.TrimLeft(text, "<>=~!"))]
+	bound, err := version.NewVersion(strings.TrimSpace(text[len(operator):]))
+	if err != nil {
+		return operator, nil
+	}
+	return operator, bound
+}
+
+// CompatibleProviderVersion returns the lowest version every constraint admits, of the versions their lower bounds
+// name, or "" if none does. Constraints are taken from every module a provider is shared between, since Terraform
+// installs one version of each provider for the whole configuration.
+func CompatibleProviderVersion(constraints []string) string {
+	var parsed []version.Constraints
+	var candidates []*version.Version
+	for _, constraint := range constraints {
+		parts, err := version.NewConstraint(constraint)
+		if err != nil {
+			return ""
+		}
+		parsed = append(parsed, parts)
+		for _, part := range parts {
+			if _, bound := splitConstraint(part); bound != nil {
+				candidates = append(candidates, bound)
+			}
+		}
+	}
+	sort.Sort(version.Collection(candidates))
+
+	for _, candidate := range candidates {
+		admitted := true
+		for _, constraint := range parsed {
+			admitted = admitted && constraint.Check(candidate)
+		}
+		if admitted {
+			return candidate.String()
+		}
+	}
+	return ""
+}
+
+// ModulePathEscapes returns the places a module refers to files outside its own directory: relative paths
+// climbing out of it, and path.root or path.cwd, which resolve to the caller's directory once the module is
+// published. Paths built on path.module stay inside it.
+func ModulePathEscapes(t *testing.T, modulePath string) []string {
+	var escapes []string
+	for file, body := range parseModuleFiles(t, modulePath) {
+		_ = hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+			switch expr := node.(type) {
+			case *hclsyntax.LiteralValueExpr:
+				if expr.Val.Type() == cty.String && !expr.Val.IsNull() &&
+					strings.Contains(expr.Val.AsString(), "../") {
+					escapes = append(escapes, fmt.Sprintf("%s:%d: %q", file, expr.SrcRange.Start.Line,
+						expr.Val.AsString()))
+				}
+			case *hclsyntax.ScopeTraversalExpr:
+				if expr.Traversal.RootName() != "path" || len(expr.Traversal) < 2 {
+					return nil
+				}
+				if attribute, ok := expr.Traversal[1].(hcl.TraverseAttr); ok &&
+					(attribute.Name == "root" || attribute.Name == "cwd") {
+					escapes = append(escapes, fmt.Sprintf("%s:%d: path.%s", file, expr.SrcRange.Start.Line,
+						attribute.Name))
+				}
+			}
+			return nil
+		})
+	}
+	sort.Strings(escapes)
+	return escapes
+}
+
+// ModuleProviderBlocks returns where a module configures providers itself. Only the root may, since a module with
+// provider blocks cannot be used with count, for_each or depends_on, nor be given its caller's providers.
+func ModuleProviderBlocks(t *testing.T, modulePath string) []string {
+	var blocks []string
+	for file, body := range parseModuleFiles(t, modulePath) {
+		for _, block := range body.Blocks {
+			if block.Type == "provider" && len(block.Labels) > 0 {
+				blocks = append(blocks, fmt.Sprintf("%s:%d: provider %q", file, block.TypeRange.Start.Line,
+					block.Labels[0]))
+			}
+		}
+	}
+	sort.Strings(blocks)
+	return blocks
+}
+
+// AssertModulePublishable fails the test for everything that keeps a module from being published to a registry
+// on its own: path escapes, provider blocks, and providers without a source and a lower version bound
+func AssertModulePublishable(t *testing.T, modulePath string) {
+	for _, escape := range ModulePathEscapes(t, modulePath) {
+		assert.Fail(t, "Module refers outside its directory", "%s in %s", escape, modulePath)
+	}
+	for _, block := range ModuleProviderBlocks(t, modulePath) {
+		assert.Fail(t, "Module configures a provider", "%s in %s; pass providers in from the root instead",
+			block, modulePath)
+	}
+
+	requirements := GetProviderRequirements(t, modulePath)
+	assert.NotEmpty(t, requirements, "%s declares no required_providers", modulePath)
+	for _, requirement := range requirements {
+		assert.NotEmpty(t, requirement.Source, "%s in %s has no source", requirement.Name, modulePath)
+		assert.Empty(t, ProviderConstraintProblem(requirement.Version), "%s in %s", requirement.Name, modulePath)
+	}
+}
+
+// ModuleExamplePaths returns the configurations under a module's examples directory
+func ModuleExamplePaths(t *testing.T, modulePath string) []string {
+	examples, err := filepath.Glob(filepath.Join(modulePath, "examples", "*", "main.tf"))
+	require.NoError(t, err)
+
+	paths := make([]string, 0, len(examples))
+	for _, example := range examples {
+		paths = append(paths, filepath.Dir(example))
+	}
+	return paths
+}
+
+// ValidateExample runs terraform init without a backend and terraform validate in an example configuration,
+// removing the .terraform directory and lock file init creates when the test ends
+func ValidateExample(t *testing.T, examplePath string) {
+	RequireTier(t, TierPlan)
+
+	terraformOptions := &terraform.Options{TerraformDir: examplePath, NoColor: true}
+	t.Cleanup(func() {
+		tfopts.CleanupState(t, examplePath)
+		_ = os.Remove(filepath.Join(examplePath, ".terraform.lock.hcl"))
+	})
+	tfopts.InitForPlanOnly(t, terraformOptions)
+	terraform.Validate(t, terraformOptions)
+}
@@ -0,0 +1,153 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/smoketest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Backends that requests to the stack's domain are routed to
+const (
+	BackendAPI      = smoketest.BackendAPI
+	BackendFrontend = smoketest.BackendFrontend
+)
+
+// VercelApexIP and VercelCNAME are the DNS targets Vercel documents for apex and subdomain records
+const (
+	VercelApexIP = "76.76.21.21"
+	VercelCNAME  = "cname.vercel-dns.com"
+)
+
+// ServerRenderingResourceTypes are the resources a self-hosted SSR frontend would need: a load balancer with
+// a target group and listener rules in front of an ECS service. The frontend is rendered on Vercel, so none of
+// them should appear in a plan.
+var ServerRenderingResourceTypes = []string{
+	"aws_lb",
+	"aws_alb",
+	"aws_lb_listener",
+	"aws_lb_listener_rule",
+	"aws_lb_target_group",
+	"aws_ecs_service",
+}
+
+// Route is a request the stack must send to a particular backend
+type Route = smoketest.Route
+
+// ExpectedRoutes lists the requests that pin down how traffic is split: the API domain serves both the
+// Django API and admin, and the apex and www domains serve the frontend
+func ExpectedRoutes(domain string) []Route {
+	return smoketest.NewEnvironment(domain).Routes()
+}
+
+// GetDeployedDomain returns the domain of a deployed stack from E2E_DOMAIN, skipping the test when unset
+func GetDeployedDomain(t *testing.T) string {
+	RequireTier(t, TierE2E)
+
+	domain := os.Getenv("E2E_DOMAIN")
+	if domain == "" {
+		t.Skip("Skipping end-to-end test - set E2E_DOMAIN to the domain of a deployed stack")
+	}
+	return domain
+}
+
+// IdentifyBackend names the backend that served a response from the headers each platform adds
+func IdentifyBackend(response *http.Response) string {
+	return smoketest.IdentifyBackend(response)
+}
+
+// RequestRoute sends a GET request for a route over HTTPS and returns the backend that answered
+func RequestRoute(t *testing.T, client *http.Client, route Route) (string, *http.Response) {
+	url := fmt.Sprintf("https://%s%s", route.Host, route.Path)
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, http.NoBody)
+	require.NoError(t, err)
+
+	response, err := client.Do(request)
+	require.NoError(t, err, "Request to %s failed", url)
+	response.Body.Close()
+
+	return IdentifyBackend(response), response
+}
+
+// Health check budgets for the API, as the smoke test holds it to
+const (
+	APIGatewayIntegrationTimeout = smoketest.APIGatewayIntegrationTimeout
+	APIWarmHealthCheckBudget     = smoketest.APIWarmHealthCheckBudget
+)
+
+// APIHealth is the part of the Django health check response the tests rely on
+type APIHealth = smoketest.APIHealth
+
+// GetAPIHealth requests the API health check on the deployed domain and returns the parsed body, the HTTP
+// status and how long the request took
+func GetAPIHealth(t *testing.T, client *http.Client, domain string) (*APIHealth, int, time.Duration) {
+	health, status, elapsed, err := smoketest.GetAPIHealth(context.Background(), client, "https://api."+domain)
+	require.NoError(t, err)
+	return health, status, elapsed
+}
+
+// CSRF rejection reasons, as explained on Django's 403 CSRF failure page
+const (
+	CSRFRejectedNoReferer = "referer"
+	CSRFRejectedNoCookie  = "cookie"
+)
+
+// PostWithoutCSRFCredentials posts a form with no Referer or CSRF cookie, and with the given Origin unless it
+// is empty, and returns the status and the reason Django gave for rejecting it. Django only insists on a
+// Referer when it sees the request as HTTPS and no Origin is sent, so the reason shows whether
+// X-Forwarded-Proto made it through the proxies. A trusted Origin gets as far as the cookie check, while an
+// untrusted one is rejected without either reason.
+func PostWithoutCSRFCredentials(t *testing.T, client *http.Client, url, origin string) (int, string) {
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url,
+		strings.NewReader("username=terratest&password=terratest"))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if origin != "" {
+		request.Header.Set("Origin", origin)
+	}
+
+	response, err := client.Do(request)
+	require.NoError(t, err, "Request to %s failed", url)
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	switch {
+	case strings.Contains(string(body), "Referer header"):
+		return response.StatusCode, CSRFRejectedNoReferer
+	case strings.Contains(string(body), "CSRF cookie"):
+		return response.StatusCode, CSRFRejectedNoCookie
+	default:
+		return response.StatusCode, ""
+	}
+}
+
+// GetExecuteAPIURL returns the raw execute-api URL of the deployed API Gateway stage from E2E_API_GATEWAY_ID and
+// E2E_API_GATEWAY_STAGE (default "prod"), skipping the test when the API ID is unset
+func GetExecuteAPIURL(t *testing.T) string {
+	RequireTier(t, TierE2E)
+
+	apiID := os.Getenv("E2E_API_GATEWAY_ID")
+	if apiID == "" {
+		t.Skip("Skipping end-to-end test - set E2E_API_GATEWAY_ID to the Zappa API Gateway ID")
+	}
+
+	stage := os.Getenv("E2E_API_GATEWAY_STAGE")
+	if stage == "" {
+		stage = "prod"
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+	return fmt.Sprintf("https://%s.execute-api.%s.amazonaws.com/%s", apiID, region, stage)
+}
@@ -0,0 +1,268 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/e2e"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// ScaleToZeroSchedule is when non-production ECS services are scaled to zero and back by Application Auto Scaling
+// scheduled actions. Schedules are evaluated in Timezone.
+type ScaleToZeroSchedule struct {
+	ScaleDown string // Sets minimum and maximum capacity to zero
+	ScaleUp   string // Restores a minimum capacity of at least one
+	Timezone  string
+}
+
+// NonProductionScaleToZero scales services down on weekday evenings and up on weekday mornings, so they also stay
+// down over weekends
+var NonProductionScaleToZero = ScaleToZeroSchedule{
+	ScaleDown: "cron(0 20 ? * MON-FRI *)",
+	ScaleUp:   "cron(0 7 ? * MON-FRI *)",
+	Timezone:  "America/New_York",
+}
+
+// ScaleToZeroDrainTimeout bounds how long a scaled-down service may take to stop its tasks, including the load
+// balancer's deregistration delay
+const ScaleToZeroDrainTimeout = 10 * time.Minute
+
+// scheduledCapacity reads min_capacity or max_capacity of a scalable_target_action, which the provider stores as
+// a string
+func scheduledCapacity(action map[string]interface{}, key string) (int, bool) {
+	switch value := action[key].(type) {
+	case string:
+		capacity, err := strconv.Atoi(value)
+		return capacity, err == nil
+	case float64:
+		return int(value), true
+	}
+	return 0, false
+}
+
+// CheckScheduledScaleToZero returns a check that ECS scalable targets scale to zero on NonProductionScaleToZero
+// outside production, and are never scheduled to zero in prod. No module creates ECS services yet, so it reports
+// nothing until one does.
+func CheckScheduledScaleToZero(environment string) AuditCheck {
+	return func(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+		if resource.Type != "aws_appautoscaling_target" ||
+			GetPlannedStringAttribute(resource, "service_namespace") != "ecs" {
+			return nil
+		}
+		resourceID := GetPlannedStringAttribute(resource, "resource_id")
+		if resourceID == "" {
+			return []AuditFinding{unknown("Scheduled-Scale-To-Zero", resource, "resource_id")}
+		}
+
+		zeroed, scaleDown, scaleUp := false, false, false
+		var problems []string
+		for _, candidate := range audit.Resources {
+			if candidate.Type != "aws_appautoscaling_scheduled_action" ||
+				GetPlannedStringAttribute(candidate, "resource_id") != resourceID {
+				continue
+			}
+			schedule := GetPlannedStringAttribute(candidate, "schedule")
+			for _, action := range nestedBlocks(candidate.AttributeValues, "scalable_target_action") {
+				minimum, hasMinimum := scheduledCapacity(action, "min_capacity")
+				maximum, hasMaximum := scheduledCapacity(action, "max_capacity")
+				switch {
+				case hasMaximum && maximum == 0:
+					zeroed = true
+					scaleDown = scaleDown || (schedule == NonProductionScaleToZero.ScaleDown &&
+						hasMinimum && minimum == 0)
+					if schedule != NonProductionScaleToZero.ScaleDown {
+						problems = append(problems, fmt.Sprintf("%s scales to zero on %s, expected %s",
+							candidate.Address, schedule, NonProductionScaleToZero.ScaleDown))
+					}
+				case hasMinimum && minimum >= 1:
+					scaleUp = scaleUp || schedule == NonProductionScaleToZero.ScaleUp
+					if schedule != NonProductionScaleToZero.ScaleUp {
+						problems = append(problems, fmt.Sprintf("%s scales up on %s, expected %s",
+							candidate.Address, schedule, NonProductionScaleToZero.ScaleUp))
+					}
+				}
+			}
+			timezone := GetPlannedStringAttribute(candidate, "timezone")
+			if timezone != NonProductionScaleToZero.Timezone {
+				problems = append(problems, fmt.Sprintf("%s is scheduled in %q, expected %s", candidate.Address,
+					timezone, NonProductionScaleToZero.Timezone))
+			}
+		}
+
+		if environment == "prod" {
+			return []AuditFinding{passFail("Scheduled-Scale-To-Zero", resource, !zeroed,
+				"production services must not be scheduled to scale to zero")}
+		}
+		if !scaleDown {
+			problems = append(problems, "no scheduled action scales the service to zero")
+		}
+		if !scaleUp {
+			problems = append(problems, "no scheduled action scales the service back up")
+		}
+		return []AuditFinding{passFail("Scheduled-Scale-To-Zero", resource, len(problems) == 0,
+			strings.Join(problems, "; "))}
+	}
+}
+
+// ScheduledAction is an Application Auto Scaling scheduled action of an ECS service
+type ScheduledAction struct {
+	ScheduledActionName  string `json:"ScheduledActionName"`
+	Schedule             string `json:"Schedule"`
+	Timezone             string `json:"Timezone"`
+	ScalableTargetAction struct {
+		MinCapacity *int `json:"MinCapacity"`
+		MaxCapacity *int `json:"MaxCapacity"`
+	} `json:"ScalableTargetAction"`
+}
+
+// runAutoScalingCommand runs an aws application-autoscaling command against ECS services and decodes its JSON
+// output, if out is set
+func runAutoScalingCommand(t *testing.T, region string, out interface{}, args ...string) error {
+	args = append(args, "--service-namespace", "ecs")
+	return awscalls.RunCLI(context.Background(), t, region, out, "application-autoscaling", args...)
+}
+
+// ECSServiceResourceID is the Application Auto Scaling resource ID of an ECS service
+func ECSServiceResourceID(cluster, service string) string {
+	return fmt.Sprintf("service/%s/%s", cluster, service)
+}
+
+// GetScheduledActions returns the scheduled actions of an ECS service
+func GetScheduledActions(t *testing.T, cluster, service, region string) []ScheduledAction {
+	var result struct {
+		ScheduledActions []ScheduledAction `json:"ScheduledActions"`
+	}
+	require.NoError(t, runAutoScalingCommand(t, region, &result, "describe-scheduled-actions",
+		"--resource-id", ECSServiceResourceID(cluster, service)))
+	return result.ScheduledActions
+}
+
+// ScaleToZeroNow fast-forwards a service's scale-to-zero action: it schedules a copy of the action a minute from
+// now and waits up to ScaleToZeroDrainTimeout for the service's tasks to stop. The copy is deleted and the
+// service's capacity restored when the test ends.
+func ScaleToZeroNow(t *testing.T, cluster, service, region string) {
+	resourceID := ECSServiceResourceID(cluster, service)
+	var targets struct {
+		ScalableTargets []struct {
+			MinCapacity int `json:"MinCapacity"`
+			MaxCapacity int `json:"MaxCapacity"`
+		} `json:"ScalableTargets"`
+	}
+	require.NoError(t, runAutoScalingCommand(t, region, &targets, "describe-scalable-targets",
+		"--resource-ids", resourceID))
+	require.Len(t, targets.ScalableTargets, 1, "Service %s is not an Application Auto Scaling target", service)
+	original := targets.ScalableTargets[0]
+
+	var scaleDown *ScheduledAction
+	actions := GetScheduledActions(t, cluster, service, region)
+	for i, action := range actions {
+		if action.Schedule == NonProductionScaleToZero.ScaleDown {
+			scaleDown = &actions[i]
+		}
+	}
+	require.NotNil(t, scaleDown, "Service %s has no action scheduled on %s", service,
+		NonProductionScaleToZero.ScaleDown)
+
+	name := scaleDown.ScheduledActionName + "-now"
+	at := time.Now().UTC().Add(time.Minute).Format("2006-01-02T15:04:05")
+	target := fmt.Sprintf("MinCapacity=%d,MaxCapacity=%d", aws.ToInt(scaleDown.ScalableTargetAction.MinCapacity),
+		aws.ToInt(scaleDown.ScalableTargetAction.MaxCapacity))
+	require.NoError(t, runAutoScalingCommand(t, region, nil, "put-scheduled-action", "--scheduled-action-name", name,
+		"--resource-id", resourceID, "--scalable-dimension", "ecs:service:DesiredCount",
+		"--schedule", fmt.Sprintf("at(%s)", at), "--scalable-target-action", target))
+	t.Cleanup(func() {
+		if err := runAutoScalingCommand(t, region, nil, "delete-scheduled-action", "--scheduled-action-name", name,
+			"--resource-id", resourceID, "--scalable-dimension", "ecs:service:DesiredCount"); err != nil {
+			t.Logf("Failed to delete scheduled action %s: %v", name, err)
+		}
+		if err := runAutoScalingCommand(t, region, nil, "register-scalable-target", "--resource-id", resourceID,
+			"--scalable-dimension", "ecs:service:DesiredCount",
+			"--min-capacity", strconv.Itoa(original.MinCapacity),
+			"--max-capacity", strconv.Itoa(original.MaxCapacity)); err != nil {
+			t.Logf("Failed to restore the capacity of %s: %v", service, err)
+		}
+	})
+	t.Logf("Scheduled %s to scale %s to zero at %s UTC", name, service, at)
+
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+	client := ecs.NewFromConfig(cfg)
+	_, err = retry.DoWithRetryE(t, fmt.Sprintf("Wait for %s to drain", service),
+		int(ScaleToZeroDrainTimeout/(15*time.Second)), 15*time.Second, func() (string, error) {
+			result, err := client.DescribeServices(context.Background(), &ecs.DescribeServicesInput{
+				Cluster:  aws.String(cluster),
+				Services: []string{service},
+			})
+			if err != nil {
+				return "", err
+			}
+			if len(result.Services) != 1 {
+				return "", retry.FatalError{Underlying: fmt.Errorf("service %s not found in %s", service, cluster)}
+			}
+			current := result.Services[0]
+			if current.DesiredCount != 0 || current.RunningCount != 0 {
+				return "", fmt.Errorf("%s wants %d tasks and runs %d", service, current.DesiredCount,
+					current.RunningCount)
+			}
+			return "", nil
+		})
+	require.NoError(t, err, "Service %s should drain within %s", service, ScaleToZeroDrainTimeout)
+}
+
+// AssertMaintenanceResponse asserts a URL answers 503 Service Unavailable, as a load balancer with no healthy
+// targets does, retrying while targets finish deregistering
+func AssertMaintenanceResponse(t *testing.T, url string) {
+	client := e2e.NewNonRedirectingClient()
+	status := 0
+	_, err := retry.DoWithRetryE(t, "Wait for a maintenance response from "+url, 20, 15*time.Second,
+		func() (string, error) {
+			if status = e2e.GetStatus(t, client, url); status != http.StatusServiceUnavailable {
+				return "", fmt.Errorf("%s answered %d", url, status)
+			}
+			return "", nil
+		})
+	require.NoError(t, err, "%s should serve a maintenance response once its service is scaled to zero", url)
+}
+
+// DeployedScheduledService is a non-production ECS service with scheduled scale-to-zero in an already-deployed
+// stack, and the URL its load balancer serves it on, from E2E_ECS_* and E2E_MAINTENANCE_URL variables
+type DeployedScheduledService struct {
+	Region  string
+	Cluster string
+	Service string
+	URL     string
+}
+
+// GetDeployedScheduledService loads the service to fast-forward scale-to-zero on, skipping the test when none is
+// configured
+func GetDeployedScheduledService(t *testing.T) *DeployedScheduledService {
+	RequireTier(t, TierE2E)
+
+	deployed := &DeployedScheduledService{
+		Region:  os.Getenv("AWS_REGION"),
+		Cluster: os.Getenv("E2E_ECS_CLUSTER"),
+		Service: os.Getenv("E2E_ECS_SERVICE"),
+		URL:     os.Getenv("E2E_MAINTENANCE_URL"),
+	}
+	if deployed.Cluster == "" || deployed.Service == "" || deployed.URL == "" {
+		t.Skip("Skipping end-to-end test - set E2E_ECS_CLUSTER, E2E_ECS_SERVICE and E2E_MAINTENANCE_URL to a " +
+			"non-production service with scheduled scale-to-zero")
+	}
+	if deployed.Region == "" {
+		deployed.Region = "us-east-1"
+	}
+	return deployed
+}
@@ -0,0 +1,313 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/awsval"
+	"terraform-tests/tfopts"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	sesv2types "github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// SESSendActions are the only actions the SES sending policy may allow
+var SESSendActions = []string{"ses:SendEmail", "ses:SendRawEmail"}
+
+// SESEventTypes are the events the configuration set must publish, as Terraform names them
+var SESEventTypes = []string{"bounce", "complaint", "delivery", "reject"}
+
+// SESMailboxSimulatorSuccess is the SES mailbox simulator address that accepts every message. A sandboxed
+// account may send to it without verifying it, and sending does not count against the quota.
+const SESMailboxSimulatorSuccess = "success@simulator.amazonses.com"
+
+// SESIdentity is the part of an SES email identity tests check
+type SESIdentity struct {
+	VerifiedForSendingStatus bool
+	DkimAttributes           struct {
+		SigningEnabled bool
+		Status         string
+		Tokens         []string
+	}
+	ConfigurationSetName string
+}
+
+// DeployedSES describes the email sending of an already-deployed stack, from E2E_SES_* environment variables
+type DeployedSES struct {
+	Region               string
+	FromEmail            string
+	ConfigurationSet     string
+	NotificationTopicARN string
+}
+
+// GetDeployedSES loads the deployed stack's email settings, skipping the test when none are configured
+func GetDeployedSES(t *testing.T) *DeployedSES {
+	RequireTier(t, TierE2E)
+
+	deployed := &DeployedSES{
+		Region:               os.Getenv("AWS_REGION"),
+		FromEmail:            os.Getenv("E2E_SES_FROM_EMAIL"),
+		ConfigurationSet:     os.Getenv("E2E_SES_CONFIGURATION_SET"),
+		NotificationTopicARN: os.Getenv("E2E_SES_NOTIFICATION_TOPIC_ARN"),
+	}
+	if deployed.FromEmail == "" || deployed.ConfigurationSet == "" || deployed.NotificationTopicARN == "" {
+		t.Skip("Skipping end-to-end test - set E2E_SES_FROM_EMAIL, E2E_SES_CONFIGURATION_SET and " +
+			"E2E_SES_NOTIFICATION_TOPIC_ARN to the ses module outputs of a deployed stack")
+	}
+	if deployed.Region == "" {
+		deployed.Region = "us-east-1"
+	}
+	return deployed
+}
+
+// SESSendingPolicyProblems lists what lets a sending policy do more than send as the approved from addresses:
+// actions other than SESSendActions, and sends without an ses:FromAddress condition limited to those addresses
+func SESSendingPolicyProblems(policy string, fromAddresses []string) []string {
+	var document struct {
+		Statement []struct {
+			Effect    string                            `json:"Effect"`
+			Action    json.RawMessage                   `json:"Action"`
+			Condition map[string]map[string]interface{} `json:"Condition"`
+		} `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(policy), &document); err != nil {
+		return []string{fmt.Sprintf("policy is not valid JSON: %v", err)}
+	}
+
+	var problems []string
+	for i, statement := range document.Statement {
+		if statement.Effect != "Allow" {
+			continue
+		}
+		for _, action := range stringOrList(statement.Action) {
+			if !slices.Contains(SESSendActions, action) {
+				problems = append(problems, fmt.Sprintf("statement %d allows %s", i, action))
+			}
+		}
+
+		operator, value := conditionValue(statement.Condition, "ses:FromAddress")
+		senders := stringOrList(value)
+		if len(senders) == 0 {
+			problems = append(problems, fmt.Sprintf("statement %d does not limit ses:FromAddress", i))
+		}
+		for _, sender := range senders {
+			if !slices.Contains(fromAddresses, sender) {
+				problems = append(problems, fmt.Sprintf("statement %d allows sending as %s", i, sender))
+			}
+			// StringEquals compares literally, so a wildcard under it matches no sender at all
+			if operator == "StringEquals" && strings.ContainsAny(sender, "*?") {
+				problems = append(problems, fmt.Sprintf("statement %d matches %s with StringEquals", i, sender))
+			}
+		}
+	}
+	return problems
+}
+
+// conditionValue returns the operator and raw value of a condition key under StringEquals or StringLike
+func conditionValue(condition map[string]map[string]interface{}, key string) (string, json.RawMessage) {
+	for _, operator := range []string{"StringEquals", "StringLike"} {
+		if value, ok := condition[operator][key]; ok {
+			raw, _ := json.Marshal(value)
+			return operator, raw
+		}
+	}
+	return "", nil
+}
+
+// stringOrList decodes a policy element that may be a single string or a list of them
+func stringOrList(raw json.RawMessage) []string {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}
+	}
+	var list []string
+	_ = json.Unmarshal(raw, &list)
+	return list
+}
+
+// GetSESIdentity describes an SES email identity, which is a domain or an address
+func GetSESIdentity(t *testing.T, identity, region string) *SESIdentity {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	result, err := sesv2.NewFromConfig(cfg).GetEmailIdentity(context.Background(), &sesv2.GetEmailIdentityInput{
+		EmailIdentity: aws.String(identity),
+	})
+	require.NoError(t, err)
+
+	described := &SESIdentity{
+		VerifiedForSendingStatus: result.VerifiedForSendingStatus,
+		ConfigurationSetName:     aws.ToString(result.ConfigurationSetName),
+	}
+	if dkim := result.DkimAttributes; dkim != nil {
+		described.DkimAttributes.SigningEnabled = dkim.SigningEnabled
+		described.DkimAttributes.Status = string(dkim.Status)
+		described.DkimAttributes.Tokens = dkim.Tokens
+	}
+	return described
+}
+
+// GetHostedZoneName returns the domain name of a Route53 hosted zone, without the trailing dot
+func GetHostedZoneName(t *testing.T, zoneID, region string) string {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	zone, err := route53.NewFromConfig(cfg).GetHostedZone(context.Background(), &route53.GetHostedZoneInput{
+		Id: aws.String(zoneID),
+	})
+	require.NoError(t, err)
+	return strings.TrimSuffix(aws.ToString(zone.HostedZone.Name), ".")
+}
+
+// AssertSESDNSRecords asserts the zone holds the records SES verifies a domain and signs its mail with: the
+// _amazonses TXT record with the verification token and a _domainkey CNAME for each DKIM token
+func AssertSESDNSRecords(t *testing.T, zoneID, domain, region, verificationToken string, dkimTokens []string) {
+	records, err := awsval.GetZoneRecordsE(t, zoneID, region)
+	require.NoError(t, err)
+
+	assertRecordValue := func(recordType, name, expected string) {
+		values, found := records[recordType+" "+name]
+		if assert.True(t, found, "Zone %s should have a %s record for %s", zoneID, recordType, name) {
+			assert.Equal(t, []string{expected}, values, "%s %s", recordType, name)
+		}
+	}
+
+	assertRecordValue("TXT", "_amazonses."+domain, verificationToken)
+	require.Len(t, dkimTokens, 3, "SES issues three DKIM tokens")
+	for _, token := range dkimTokens {
+		assertRecordValue("CNAME", fmt.Sprintf("%s._domainkey.%s", token, domain), token+".dkim.amazonses.com")
+	}
+}
+
+// SendSESTestEmail sends a message through a configuration set and returns its SES message ID
+func SendSESTestEmail(t *testing.T, region, from, to, configurationSet string) string {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+
+	result, err := sesv2.NewFromConfig(cfg).SendEmail(context.Background(), &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(from),
+		Destination:      &sesv2types.Destination{ToAddresses: []string{to}},
+		Content: &sesv2types.EmailContent{
+			Simple: &sesv2types.Message{
+				Subject: &sesv2types.Content{
+					Data: aws.String("Infrastructure test " + time.Now().UTC().Format(time.RFC3339)),
+				},
+				Body: &sesv2types.Body{Text: &sesv2types.Content{Data: aws.String("Sent by " + t.Name())}},
+			},
+		},
+		ConfigurationSetName: aws.String(configurationSet),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, aws.ToString(result.MessageId), "SES should return a message ID")
+	return aws.ToString(result.MessageId)
+}
+
+// SubscribeTestQueue subscribes a new SQS queue to an SNS topic with raw message delivery, so a test can read
+// what the topic publishes. The queue and subscription are removed when the test finishes.
+func SubscribeTestQueue(t *testing.T, topicARN, region string) string {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+	sqsClient, snsClient := sqs.NewFromConfig(cfg), sns.NewFromConfig(cfg)
+
+	queue, err := sqsClient.CreateQueue(context.Background(), &sqs.CreateQueueInput{
+		QueueName: aws.String(fmt.Sprintf("terratest-%s", tfopts.NewUniqueID())),
+	})
+	require.NoError(t, err)
+	queueURL := aws.ToString(queue.QueueUrl)
+	t.Cleanup(func() {
+		_, _ = sqsClient.DeleteQueue(context.Background(), &sqs.DeleteQueueInput{QueueUrl: aws.String(queueURL)})
+	})
+
+	attributes, err := sqsClient.GetQueueAttributes(context.Background(), &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameQueueArn},
+	})
+	require.NoError(t, err)
+	queueARN := attributes.Attributes[string(sqstypes.QueueAttributeNameQueueArn)]
+
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Effect":    "Allow",
+			"Principal": map[string]string{"Service": "sns.amazonaws.com"},
+			"Action":    "sqs:SendMessage",
+			"Resource":  queueARN,
+			"Condition": map[string]map[string]string{"ArnEquals": {"aws:SourceArn": topicARN}},
+		}},
+	})
+	require.NoError(t, err)
+	_, err = sqsClient.SetQueueAttributes(context.Background(), &sqs.SetQueueAttributesInput{
+		QueueUrl:   aws.String(queueURL),
+		Attributes: map[string]string{string(sqstypes.QueueAttributeNamePolicy): string(policy)},
+	})
+	require.NoError(t, err)
+
+	subscription, err := snsClient.Subscribe(context.Background(), &sns.SubscribeInput{
+		TopicArn:              aws.String(topicARN),
+		Protocol:              aws.String("sqs"),
+		Endpoint:              aws.String(queueARN),
+		Attributes:            map[string]string{"RawMessageDelivery": "true"},
+		ReturnSubscriptionArn: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = snsClient.Unsubscribe(context.Background(), &sns.UnsubscribeInput{
+			SubscriptionArn: subscription.SubscriptionArn,
+		})
+	})
+
+	return queueURL
+}
+
+// WaitForSESEvent polls a queue subscribed with SubscribeTestQueue until SES publishes an event of the given type,
+// such as "Delivery", for a message
+func WaitForSESEvent(t *testing.T, queueURL, region, messageID, eventType string) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+	svc := sqs.NewFromConfig(cfg)
+
+	retry.DoWithRetry(t, fmt.Sprintf("Wait for %s event for message %s", eventType, messageID), 20, 15*time.Second,
+		func() (string, error) {
+			result, receiveErr := svc.ReceiveMessage(context.Background(), &sqs.ReceiveMessageInput{
+				QueueUrl:            aws.String(queueURL),
+				MaxNumberOfMessages: 10,
+				WaitTimeSeconds:     10,
+			})
+			if receiveErr != nil {
+				return "", receiveErr
+			}
+
+			var seen []string
+			for _, message := range result.Messages {
+				var event struct {
+					EventType string `json:"eventType"`
+					Mail      struct {
+						MessageID string `json:"messageId"`
+					} `json:"mail"`
+				}
+				if json.Unmarshal([]byte(aws.ToString(message.Body)), &event) != nil || event.Mail.MessageID != messageID {
+					continue
+				}
+				if event.EventType == eventType {
+					return "", nil
+				}
+				seen = append(seen, event.EventType)
+			}
+			return "", fmt.Errorf("no %s event yet for message %s (seen %v)", eventType, messageID, seen)
+		})
+}
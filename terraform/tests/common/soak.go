@@ -0,0 +1,306 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/stretchr/testify/require"
+)
+
+// A soak holds an environment to these over its whole duration: health checks that answer 200, API requests that
+// rarely end in a 5xx, and no task stopped except by a deployment or scaling
+const (
+	DefaultSoakInterval   = time.Minute
+	SoakAvailabilitySLO   = 0.999 // Share of health checks that must succeed
+	SoakMaxServerErrorPct = 0.1   // Percent of API requests that may end in a 5xx
+)
+
+// SoakEvent is something that went wrong during a soak, and when
+type SoakEvent struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Detail string    `json:"detail"`
+}
+
+// SoakReport is the stability report of a soak
+type SoakReport struct {
+	Start             time.Time   `json:"start"`
+	End               time.Time   `json:"end"`
+	HealthChecks      int         `json:"health_checks"`
+	HealthFailures    []SoakEvent `json:"health_failures"`
+	SlowestHealthMS   int64       `json:"slowest_health_ms"`
+	APIRequests       float64     `json:"api_requests"`
+	API5xx            float64     `json:"api_5xx"`
+	TaskStops         []SoakEvent `json:"task_stops"`
+	OutOfMemoryKills  int         `json:"out_of_memory_kills"`
+	ServerErrorsKnown bool        `json:"server_errors_known"`
+}
+
+// Findings checks the report against the soak's budgets: health check availability, the API's 5xx rate when
+// API Gateway metrics were scraped, and that no task restarted or was killed for running out of memory
+func (r *SoakReport) Findings() []AuditFinding {
+	availability := 0.0
+	if r.HealthChecks > 0 {
+		availability = 1 - float64(len(r.HealthFailures))/float64(r.HealthChecks)
+	}
+	findings := []AuditFinding{
+		{Control: "Soak-Health", Resource: "health checks", Passed: availability >= SoakAvailabilitySLO,
+			Detail: fmt.Sprintf("%d of %d health checks failed, expected availability of at least %.1f%%",
+				len(r.HealthFailures), r.HealthChecks, 100*SoakAvailabilitySLO)},
+		{Control: "Soak-Restarts", Resource: "ECS tasks", Passed: len(r.TaskStops) == 0,
+			Detail: fmt.Sprintf("%d tasks stopped unexpectedly", len(r.TaskStops))},
+		{Control: "Soak-OOM", Resource: "ECS tasks", Passed: r.OutOfMemoryKills == 0,
+			Detail: fmt.Sprintf("%d containers were killed for running out of memory", r.OutOfMemoryKills)},
+	}
+
+	serverErrors := AuditFinding{Control: "Soak-5xx", Resource: "API Gateway", Skipped: !r.ServerErrorsKnown,
+		Detail: "API Gateway metrics were not scraped"}
+	if r.ServerErrorsKnown {
+		percent := 0.0
+		if r.APIRequests > 0 {
+			percent = 100 * r.API5xx / r.APIRequests
+		}
+		serverErrors.Passed = percent <= SoakMaxServerErrorPct
+		serverErrors.Detail = fmt.Sprintf("%.0f of %.0f requests ended in a 5xx (%.3f%%), expected at most %.1f%%",
+			r.API5xx, r.APIRequests, percent, SoakMaxServerErrorPct)
+	}
+	return append(findings, serverErrors)
+}
+
+// ClassifyStoppedTask reports whether an ECS task stopped unexpectedly, and whether one of its containers was
+// killed for running out of memory. Tasks the scheduler stops for a deployment or scale-in, or that someone stops,
+// are expected; a task that fails its health checks, exits or fails to start is not.
+func ClassifyStoppedTask(task ecstypes.Task) (unexpected, outOfMemory bool) {
+	for _, container := range task.Containers {
+		if strings.Contains(aws.ToString(container.Reason), "OutOfMemory") {
+			outOfMemory = true
+		}
+	}
+	reason := aws.ToString(task.StoppedReason)
+	switch {
+	case outOfMemory:
+		unexpected = true
+	case task.StopCode == ecstypes.TaskStopCodeUserInitiated:
+	case task.StopCode == ecstypes.TaskStopCodeServiceSchedulerInitiated &&
+		strings.HasPrefix(reason, "Scaling activity initiated by"):
+	default:
+		unexpected = true
+	}
+	return unexpected, outOfMemory
+}
+
+// DeployedSoak is the environment to soak and for how long, from E2E_DOMAIN and E2E_SOAK_* variables. The API
+// Gateway and ECS service are optional; without them their checks are skipped.
+type DeployedSoak struct {
+	Region       string
+	Domain       string
+	Duration     time.Duration
+	Interval     time.Duration
+	APIGatewayID string
+	Stage        string
+	Cluster      string
+	Service      string
+}
+
+// GetDeployedSoak loads the soak to run, skipping the test unless E2E_SOAK_DURATION says how long to soak for.
+// The test must be given a go test -timeout longer than the soak.
+func GetDeployedSoak(t *testing.T) *DeployedSoak {
+	RequireTier(t, TierE2E)
+
+	domain, duration := os.Getenv("E2E_DOMAIN"), os.Getenv("E2E_SOAK_DURATION")
+	if domain == "" || duration == "" {
+		t.Skip("Skipping end-to-end test - set E2E_DOMAIN and E2E_SOAK_DURATION, such as 8h, to soak a deployed stack")
+	}
+
+	deployed := &DeployedSoak{
+		Region:       os.Getenv("AWS_REGION"),
+		Domain:       domain,
+		Interval:     DefaultSoakInterval,
+		APIGatewayID: os.Getenv("E2E_API_GATEWAY_ID"),
+		Stage:        os.Getenv("E2E_API_GATEWAY_STAGE"),
+		Cluster:      os.Getenv("E2E_ECS_CLUSTER"),
+		Service:      os.Getenv("E2E_ECS_SERVICE"),
+	}
+	var err error
+	deployed.Duration, err = time.ParseDuration(duration)
+	require.NoError(t, err, "E2E_SOAK_DURATION should be a duration such as 8h")
+	if interval := os.Getenv("E2E_SOAK_INTERVAL"); interval != "" {
+		deployed.Interval, err = time.ParseDuration(interval)
+		require.NoError(t, err, "E2E_SOAK_INTERVAL should be a duration such as 30s")
+	}
+	if deadline, ok := t.Deadline(); ok {
+		require.True(t, time.Until(deadline) > deployed.Duration+10*time.Minute,
+			"Run go test with a -timeout longer than E2E_SOAK_DURATION (%s)", deployed.Duration)
+	}
+	if deployed.Region == "" {
+		deployed.Region = "us-east-1"
+	}
+	if deployed.Stage == "" {
+		deployed.Stage = "prod"
+	}
+	return deployed
+}
+
+// RunSoak polls the API's health endpoint and the frontend every interval for the soak's duration, and collects
+// ECS tasks that stopped as it goes, since ECS forgets stopped tasks after an hour. At the end it scrapes the API's
+// request and 5xx counts for the whole soak from API Gateway metrics.
+func RunSoak(t *testing.T, deployed *DeployedSoak) *SoakReport {
+	report := &SoakReport{Start: time.Now().UTC()}
+	client := &http.Client{Timeout: 15 * time.Second}
+	urls := []string{"https://api." + deployed.Domain + MaintenanceHealthPath, "https://" + deployed.Domain + "/"}
+	seenTasks := map[string]bool{}
+
+	ticker := time.NewTicker(deployed.Interval)
+	defer ticker.Stop()
+	for now := time.Now(); now.Before(report.Start.Add(deployed.Duration)); now = <-ticker.C {
+		for _, url := range urls {
+			report.HealthChecks++
+			started := time.Now()
+			status, err := getStatusE(client, url)
+			if elapsed := time.Since(started).Milliseconds(); elapsed > report.SlowestHealthMS {
+				report.SlowestHealthMS = elapsed
+			}
+			if err != nil || status != http.StatusOK {
+				detail := fmt.Sprintf("answered %d", status)
+				if err != nil {
+					detail = err.Error()
+				}
+				report.HealthFailures = append(report.HealthFailures, SoakEvent{Time: now.UTC(), Source: url,
+					Detail: detail})
+			}
+		}
+
+		if deployed.Service != "" {
+			tasks, err := StoppedTasksE(t, deployed.Cluster, deployed.Service, deployed.Region)
+			require.NoError(t, err)
+			for _, task := range tasks {
+				arn := aws.ToString(task.TaskArn)
+				if seenTasks[arn] || task.StoppedAt == nil || task.StoppedAt.Before(report.Start) {
+					continue
+				}
+				seenTasks[arn] = true
+				if unexpected, outOfMemory := ClassifyStoppedTask(task); unexpected {
+					report.TaskStops = append(report.TaskStops, SoakEvent{Time: task.StoppedAt.UTC(), Source: arn,
+						Detail: fmt.Sprintf("%s: %s", task.StopCode, aws.ToString(task.StoppedReason))})
+					if outOfMemory {
+						report.OutOfMemoryKills++
+					}
+				}
+			}
+		}
+	}
+	report.End = time.Now().UTC()
+
+	if deployed.APIGatewayID != "" {
+		requests, serverErrors, err := APIGatewayRequestCountsE(t, deployed.APIGatewayID, deployed.Stage,
+			deployed.Region, report.Start, report.End)
+		require.NoError(t, err)
+		report.APIRequests, report.API5xx, report.ServerErrorsKnown = requests, serverErrors, true
+	}
+	return report
+}
+
+// getStatusE sends a GET request and returns the response status, or the error when none arrived
+func getStatusE(client *http.Client, url string) (int, error) {
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return 0, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	response.Body.Close()
+	return response.StatusCode, nil
+}
+
+// StoppedTasksE returns the tasks of an ECS service that stopped within the last hour, which is as long as ECS
+// keeps them
+func StoppedTasksE(t *testing.T, cluster, service, region string) ([]ecstypes.Task, error) {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	if err != nil {
+		return nil, err
+	}
+	client := ecs.NewFromConfig(cfg)
+
+	listed, err := client.ListTasks(context.Background(), &ecs.ListTasksInput{
+		Cluster:       aws.String(cluster),
+		ServiceName:   aws.String(service),
+		DesiredStatus: ecstypes.DesiredStatusStopped,
+	})
+	if err != nil || len(listed.TaskArns) == 0 {
+		return nil, err
+	}
+	described, err := client.DescribeTasks(context.Background(), &ecs.DescribeTasksInput{
+		Cluster: aws.String(cluster),
+		Tasks:   listed.TaskArns,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return described.Tasks, nil
+}
+
+// APIGatewayRequestCountsE sums a REST API stage's Count and 5XXError metrics between two times. API Gateway names
+// its metrics by API name rather than ID, so the name is looked up first. Like Application Auto Scaling,
+// API Gateway and CloudWatch are reached through the AWS CLI.
+func APIGatewayRequestCountsE(t *testing.T, apiID, stage, region string, start, end time.Time,
+) (requests, serverErrors float64, err error) {
+	output, err := shell.RunCommandAndGetStdOutE(t, shell.Command{Command: "aws", Args: []string{
+		"apigateway", "get-rest-api", "--rest-api-id", apiID, "--region", region, "--output", "json"}})
+	if err != nil {
+		return 0, 0, fmt.Errorf("aws apigateway get-rest-api failed: %w", err)
+	}
+	var api struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(output), &api); err != nil {
+		return 0, 0, fmt.Errorf("unexpected output from aws apigateway get-rest-api: %w", err)
+	}
+
+	sum := func(metric string) (float64, error) {
+		output, err := shell.RunCommandAndGetStdOutE(t, shell.Command{Command: "aws", Args: []string{
+			"cloudwatch", "get-metric-statistics", "--namespace", "AWS/ApiGateway", "--metric-name", metric,
+			"--dimensions", "Name=ApiName,Value=" + api.Name, "Name=Stage,Value=" + stage,
+			"--start-time", start.Format(time.RFC3339), "--end-time", end.Format(time.RFC3339),
+			"--period", "3600", "--statistics", "Sum", "--region", region, "--output", "json"}})
+		if err != nil {
+			return 0, fmt.Errorf("aws cloudwatch get-metric-statistics failed for %s: %w", metric, err)
+		}
+		return SumMetricDatapoints(output)
+	}
+	if requests, err = sum("Count"); err != nil {
+		return 0, 0, err
+	}
+	serverErrors, err = sum("5XXError")
+	return requests, serverErrors, err
+}
+
+// SumMetricDatapoints adds up the Sum of each datapoint aws cloudwatch get-metric-statistics returns
+func SumMetricDatapoints(output string) (float64, error) {
+	var statistics struct {
+		Datapoints []struct {
+			Sum float64 `json:"Sum"`
+		} `json:"Datapoints"`
+	}
+	if err := json.Unmarshal([]byte(output), &statistics); err != nil {
+		return 0, fmt.Errorf("unexpected output from aws cloudwatch get-metric-statistics: %w", err)
+	}
+	total := 0.0
+	for _, datapoint := range statistics.Datapoints {
+		total += datapoint.Sum
+	}
+	return total, nil
+}
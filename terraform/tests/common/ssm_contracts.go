@@ -0,0 +1,183 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// SSMParameterTier is the tier every parameter uses: Standard parameters are free and hold up to 4 KB, which is
+// plenty for runtime configuration
+const SSMParameterTier = "Standard"
+
+// SSMEnvironmentPlaceholder stands for the environment name in per-environment parameter paths
+const SSMEnvironmentPlaceholder = "{environment}"
+
+// SSMParameterContract is a parameter the application reads its configuration from
+type SSMParameterContract struct {
+	Path    string // Under "/<prefix>/", with SSMEnvironmentPlaceholder for per-environment parameters
+	Type    string // String, StringList or SecureString, which is encrypted with KMS
+	Setting string // The environment variable the Django settings module reads the value from
+}
+
+// SSMParameterContracts holds the contract for every parameter the ssm module creates. Feature flags and theme
+// settings moving to Parameter Store add their parameters here, with the setting that reads them.
+var SSMParameterContracts = []SSMParameterContract{
+	{Path: "secret-key", Type: "SecureString", Setting: "SECRET_KEY"},
+	{Path: "site-password", Type: "SecureString", Setting: "SITE_PASSWORD"},
+	{Path: SSMEnvironmentPlaceholder + "/database-url", Type: "SecureString", Setting: "DATABASE_URL"},
+}
+
+// Names returns the full names of the contract's parameters for a prefix, one per environment if its path has
+// SSMEnvironmentPlaceholder
+func (c SSMParameterContract) Names(prefix string, environments []string) []string {
+	if !strings.Contains(c.Path, SSMEnvironmentPlaceholder) {
+		return []string{fmt.Sprintf("/%s/%s", prefix, c.Path)}
+	}
+	names := make([]string, 0, len(environments))
+	for _, environment := range environments {
+		path := strings.ReplaceAll(c.Path, SSMEnvironmentPlaceholder, environment)
+		names = append(names, fmt.Sprintf("/%s/%s", prefix, path))
+	}
+	return names
+}
+
+// SSMParameterFindings checks the parameters in an audit context against SSMParameterContracts: each expected
+// parameter exists with its type and SSMParameterTier, and no parameter lacks a contract. Policies granting SSM
+// actions are checked with SSMReadPolicyProblems.
+func SSMParameterFindings(audit *AuditContext, prefix string, environments []string) []AuditFinding {
+	parameters := map[string]*tfjson.StateResource{}
+	var findings []AuditFinding
+	for _, resource := range audit.Resources {
+		switch resource.Type {
+		case "aws_ssm_parameter":
+			parameters[GetPlannedStringAttribute(resource, "name")] = resource
+		case "aws_iam_policy", "aws_iam_role_policy":
+			if audit.IsUnknown(resource, "policy") {
+				findings = append(findings, unknown("SSM-Read-Scoped", resource, "policy"))
+				continue
+			}
+			if problems := SSMReadPolicyProblems(GetPlannedStringAttribute(resource, "policy"), prefix); problems != nil {
+				findings = append(findings, passFail("SSM-Read-Scoped", resource, len(problems) == 0,
+					strings.Join(problems, "; ")))
+			}
+		}
+	}
+
+	expected := map[string]bool{}
+	for _, contract := range SSMParameterContracts {
+		for _, name := range contract.Names(prefix, environments) {
+			expected[name] = true
+			parameter, exists := parameters[name]
+			if !exists {
+				findings = append(findings, AuditFinding{Control: "SSM-Parameter-Exists", Resource: name,
+					Detail: fmt.Sprintf("parameter read into %s is not created", contract.Setting)})
+				continue
+			}
+
+			parameterType := GetPlannedStringAttribute(parameter, "type")
+			findings = append(findings, passFail("SSM-Parameter-Type", parameter, parameterType == contract.Type,
+				fmt.Sprintf("parameter is %s, expected %s", parameterType, contract.Type)))
+			tier := GetPlannedStringAttribute(parameter, "tier")
+			findings = append(findings, passFail("SSM-Parameter-Tier", parameter, tier == SSMParameterTier,
+				fmt.Sprintf("parameter is in the %s tier, expected %s", tier, SSMParameterTier)))
+		}
+	}
+
+	var unexpected []string
+	for name := range parameters {
+		if !expected[name] {
+			unexpected = append(unexpected, name)
+		}
+	}
+	sort.Strings(unexpected)
+	for _, name := range unexpected {
+		findings = append(findings, passFail("SSM-Parameter-Contract", parameters[name], false,
+			fmt.Sprintf("%s has no contract; add it to common.SSMParameterContracts", name)))
+	}
+	return findings
+}
+
+// SSMParameters checks the applied parameters and SSM read policies, as SSMParameterFindings does
+func SSMParameters(prefix string, environments ...string) Validator {
+	return func(_ *testing.T, applied *AppliedConfiguration) []AuditFinding {
+		return SSMParameterFindings(applied.State, prefix, environments)
+	}
+}
+
+// SSMReadPolicyProblems lists the ways an IAM policy grants SSM access beyond reading the prefix's parameters. It
+// returns nil if the policy allows no SSM actions, and an empty list if it only allows reading those parameters.
+func SSMReadPolicyProblems(policy, prefix string) []string {
+	var document struct {
+		Statement []struct {
+			Effect   string          `json:"Effect"`
+			Action   json.RawMessage `json:"Action"`
+			Resource json.RawMessage `json:"Resource"`
+		} `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(policy), &document); err != nil {
+		return []string{fmt.Sprintf("policy is not valid JSON: %v", err)}
+	}
+
+	parameterPath := fmt.Sprintf(":parameter/%s/", prefix)
+	var problems []string
+	for i, statement := range document.Statement {
+		if statement.Effect != "Allow" {
+			continue
+		}
+
+		var ssmActions []string
+		for _, action := range stringOrList(statement.Action) {
+			if action == "*" || strings.HasPrefix(strings.ToLower(action), "ssm:") {
+				ssmActions = append(ssmActions, action)
+			}
+		}
+		if len(ssmActions) == 0 {
+			continue
+		}
+		if problems == nil {
+			problems = []string{}
+		}
+
+		for _, action := range ssmActions {
+			name := strings.TrimPrefix(strings.ToLower(action), "ssm:")
+			if !strings.HasPrefix(name, "get") && !strings.HasPrefix(name, "describe") {
+				problems = append(problems, fmt.Sprintf("statement %d allows %s, which is not a read", i, action))
+			}
+		}
+		for _, resource := range stringOrList(statement.Resource) {
+			if !strings.HasPrefix(resource, "arn:aws:ssm:") || !strings.Contains(resource, parameterPath) {
+				problems = append(problems, fmt.Sprintf("statement %d grants %s on %s, outside %s", i,
+					strings.Join(ssmActions, ", "), resource, strings.TrimPrefix(parameterPath, ":parameter")))
+			}
+		}
+	}
+	return problems
+}
+
+// DjangoSettingsPath is the Django settings module, relative to the test packages
+const DjangoSettingsPath = "../../../backend/coalition/core/settings.py"
+
+// djangoEnvironmentRead matches the ways the settings module reads an environment variable
+var djangoEnvironmentRead = regexp.MustCompile(`os\.(?:getenv|environ\.get)\(\s*"([A-Z0-9_]+)"` +
+	`|os\.environ\["([A-Z0-9_]+)"\]`)
+
+// GetDjangoSettingsEnvironment returns the environment variables a Django settings file reads
+func GetDjangoSettingsEnvironment(path string) (map[string]bool, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := map[string]bool{}
+	for _, match := range djangoEnvironmentRead.FindAllStringSubmatch(string(source), -1) {
+		variables[match[1]+match[2]] = true
+	}
+	return variables, nil
+}
@@ -0,0 +1,93 @@
+package common
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// ProtectedResource identifies stateful resources that must never be destroyed or replaced by a routine change.
+// An empty Name matches every resource of the type.
+type ProtectedResource struct {
+	Type string
+	Name string
+}
+
+// StatefulResources are the resources whose replacement loses data or causes an outage
+var StatefulResources = []ProtectedResource{
+	{Type: "aws_db_instance"},
+	{Type: "aws_s3_bucket", Name: "static_assets"},
+	{Type: "aws_cloudfront_distribution"},
+	{Type: "aws_route53_zone"},
+	{Type: "aws_route53_record"},
+}
+
+// StatefulChange is a planned destroy or replace of a protected resource
+type StatefulChange struct {
+	Address string
+	Actions string
+}
+
+// FindStatefulReplacements returns every protected resource the plan would destroy or replace
+func FindStatefulReplacements(plan *terraform.PlanStruct) []StatefulChange {
+	var changes []StatefulChange
+	for _, address := range sortedKeys(plan.ResourceChangesMap) {
+		change := plan.ResourceChangesMap[address]
+		if change.Change == nil || !isProtected(change.Type, change.Name) {
+			continue
+		}
+
+		actions := change.Change.Actions
+		if actions.Delete() || actions.Replace() {
+			var names []string
+			for _, action := range actions {
+				names = append(names, string(action))
+			}
+			changes = append(changes, StatefulChange{Address: address, Actions: strings.Join(names, ", ")})
+		}
+	}
+	return changes
+}
+
+// isProtected reports whether a resource matches one of the StatefulResources
+func isProtected(resourceType, name string) bool {
+	for _, protected := range StatefulResources {
+		if protected.Type == resourceType && (protected.Name == "" || protected.Name == name) {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertNoStatefulReplacements fails the test if the plan destroys or replaces a protected resource.
+// Setting ALLOW_REPLACEMENT downgrades the failures to warnings for intentional replacements.
+func AssertNoStatefulReplacements(t *testing.T, plan *terraform.PlanStruct) {
+	changes := FindStatefulReplacements(plan)
+	if len(changes) == 0 {
+		t.Log("Plan does not destroy or replace any stateful resources")
+		return
+	}
+
+	allowed := os.Getenv("ALLOW_REPLACEMENT") != ""
+	for _, change := range changes {
+		if allowed {
+			t.Logf("WARNING: %s will be %s (allowed by ALLOW_REPLACEMENT)", change.Address, change.Actions)
+			continue
+		}
+		t.Errorf("Plan would %s stateful resource %s; set ALLOW_REPLACEMENT if this is intended",
+			change.Actions, change.Address)
+	}
+}
+
+// LoadPlanJSON parses the output of `terraform show -json <planfile>`
+func LoadPlanJSON(t *testing.T, path string) *terraform.PlanStruct {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err, "Failed to read plan JSON %s", path)
+
+	plan, err := terraform.ParsePlanJSON(string(content))
+	require.NoError(t, err, "Failed to parse plan JSON %s", path)
+	return plan
+}
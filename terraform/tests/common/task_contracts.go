@@ -0,0 +1,108 @@
+package common
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ContainerContract is the exact set of environment variables and secrets a container receives. Missing
+// variables break the task; unexpected ones are flagged too, since a stray variable can leak configuration
+// or credentials into a container that should not have them.
+type ContainerContract struct {
+	Environment map[string]string // Variable name to expected value
+	Secrets     []string          // Secret names; where they are read from is left to each test
+}
+
+// TaskContracts holds the contract for every ECS container, keyed by container name. The API runs on Lambda
+// with its environment managed by Zappa, and the frontend runs on Vercel, so geodata-import is the only
+// ECS task.
+var TaskContracts = map[string]ContainerContract{
+	"geodata-import": {
+		Environment: map[string]string{
+			"USE_GEODJANGO":          "true",
+			"DJANGO_SETTINGS_MODULE": "coalition.core.settings",
+			"IS_ECS_GEODATA_IMPORT":  "true",
+		},
+		Secrets: []string{"DATABASE_URL", "SECRET_KEY"},
+	},
+}
+
+// ContainerEnvironment is the environment and secrets of one container, however it was obtained
+type ContainerEnvironment struct {
+	Name        string
+	Environment map[string]string
+	Secrets     map[string]string // Secret name to valueFrom
+}
+
+func newContainerEnvironment(name string) ContainerEnvironment {
+	return ContainerEnvironment{Name: name, Environment: map[string]string{}, Secrets: map[string]string{}}
+}
+
+// GetPlannedContainerEnvironments parses the container definitions of a planned ECS task definition
+func GetPlannedContainerEnvironments(t *testing.T, taskDefinition *tfjson.StateResource) []ContainerEnvironment {
+	definitions := GetPlannedStringAttribute(taskDefinition, "container_definitions")
+	require.NotEmpty(t, definitions, "%s should have known container definitions", taskDefinition.Address)
+
+	var containers []struct {
+		Name        string `json:"name"`
+		Environment []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"environment"`
+		Secrets []struct {
+			Name      string `json:"name"`
+			ValueFrom string `json:"valueFrom"`
+		} `json:"secrets"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(definitions), &containers))
+
+	var environments []ContainerEnvironment
+	for _, container := range containers {
+		environment := newContainerEnvironment(container.Name)
+		for _, variable := range container.Environment {
+			environment.Environment[variable.Name] = variable.Value
+		}
+		for _, secret := range container.Secrets {
+			environment.Secrets[secret.Name] = secret.ValueFrom
+		}
+		environments = append(environments, environment)
+	}
+	return environments
+}
+
+// GetECSContainerEnvironment reads the environment and secrets of a container in a deployed task definition
+func GetECSContainerEnvironment(container ecstypes.ContainerDefinition) ContainerEnvironment {
+	environment := newContainerEnvironment(aws.ToString(container.Name))
+	for _, variable := range container.Environment {
+		environment.Environment[aws.ToString(variable.Name)] = aws.ToString(variable.Value)
+	}
+	for _, secret := range container.Secrets {
+		environment.Secrets[aws.ToString(secret.Name)] = aws.ToString(secret.ValueFrom)
+	}
+	return environment
+}
+
+// AssertContainerContract fails the test unless the container's environment and secrets exactly match its contract
+func AssertContainerContract(t *testing.T, container ContainerEnvironment) {
+	contract, exists := TaskContracts[container.Name]
+	require.True(t, exists, "Container %s has no contract in common.TaskContracts", container.Name)
+
+	assert.Equal(t, contract.Environment, container.Environment,
+		"Environment of %s should match its contract exactly", container.Name)
+
+	secretNames := make([]string, 0, len(container.Secrets))
+	for name := range container.Secrets {
+		secretNames = append(secretNames, name)
+	}
+	sort.Strings(secretNames)
+	expectedSecrets := append([]string(nil), contract.Secrets...)
+	sort.Strings(expectedSecrets)
+	assert.Equal(t, expectedSecrets, secretNames, "Secrets of %s should match its contract exactly", container.Name)
+}
@@ -0,0 +1,126 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Fargate settings shared by every task. The platform version is chosen by whoever runs the task rather than
+// by the task definition, so it is pinned in the run-task calls instead.
+const (
+	FargatePlatformVersion            = "1.4.0"
+	FargateDefaultEphemeralStorageGiB = 20
+)
+
+// TaskRuntime is the platform and Linux configuration a container is expected to run with
+type TaskRuntime struct {
+	CPUArchitecture        string
+	EphemeralStorageGiB    int
+	InitProcessEnabled     bool
+	ReadonlyRootFilesystem bool
+}
+
+// TaskRuntimes holds the expected runtime for every ECS container, keyed by container name like TaskContracts.
+// geodata-import stays on X86_64 because CI builds its image for linux/amd64; moving it to ARM64 for the
+// Graviton discount means building an arm64 image first.
+var TaskRuntimes = map[string]TaskRuntime{
+	"geodata-import": {
+		CPUArchitecture:        "X86_64",
+		EphemeralStorageGiB:    FargateDefaultEphemeralStorageGiB,
+		InitProcessEnabled:     true,
+		ReadonlyRootFilesystem: false, // GDAL writes scratch files next to the downloaded shapefiles
+	},
+}
+
+// ContainerRuntime is the runtime configuration of one container, however it was obtained
+type ContainerRuntime struct {
+	Name                   string
+	OperatingSystemFamily  string
+	CPUArchitecture        string
+	EphemeralStorageGiB    int
+	InitProcessEnabled     bool
+	ReadonlyRootFilesystem bool
+}
+
+// GetPlannedContainerRuntimes reads the runtime configuration of each container in a planned ECS task definition
+func GetPlannedContainerRuntimes(t *testing.T, taskDefinition *tfjson.StateResource) []ContainerRuntime {
+	task := ContainerRuntime{EphemeralStorageGiB: FargateDefaultEphemeralStorageGiB}
+	if platforms, ok := taskDefinition.AttributeValues["runtime_platform"].([]interface{}); ok && len(platforms) > 0 {
+		platform, _ := platforms[0].(map[string]interface{})
+		task.OperatingSystemFamily, _ = platform["operating_system_family"].(string)
+		task.CPUArchitecture, _ = platform["cpu_architecture"].(string)
+	}
+	if storage, ok := taskDefinition.AttributeValues["ephemeral_storage"].([]interface{}); ok && len(storage) > 0 {
+		size, _ := storage[0].(map[string]interface{})["size_in_gib"].(float64)
+		task.EphemeralStorageGiB = int(size)
+	}
+
+	definitions := GetPlannedStringAttribute(taskDefinition, "container_definitions")
+	require.NotEmpty(t, definitions, "%s should have known container definitions", taskDefinition.Address)
+
+	var containers []struct {
+		Name            string `json:"name"`
+		LinuxParameters *struct {
+			InitProcessEnabled bool `json:"initProcessEnabled"`
+		} `json:"linuxParameters"`
+		ReadonlyRootFilesystem bool `json:"readonlyRootFilesystem"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(definitions), &containers))
+
+	var runtimes []ContainerRuntime
+	for _, container := range containers {
+		runtime := task
+		runtime.Name = container.Name
+		runtime.InitProcessEnabled = container.LinuxParameters != nil && container.LinuxParameters.InitProcessEnabled
+		runtime.ReadonlyRootFilesystem = container.ReadonlyRootFilesystem
+		runtimes = append(runtimes, runtime)
+	}
+	return runtimes
+}
+
+// GetECSContainerRuntimes reads the runtime configuration of each container in a deployed task definition
+func GetECSContainerRuntimes(taskDefinition *ecstypes.TaskDefinition) []ContainerRuntime {
+	task := ContainerRuntime{EphemeralStorageGiB: FargateDefaultEphemeralStorageGiB}
+	if taskDefinition.RuntimePlatform != nil {
+		task.OperatingSystemFamily = string(taskDefinition.RuntimePlatform.OperatingSystemFamily)
+		task.CPUArchitecture = string(taskDefinition.RuntimePlatform.CpuArchitecture)
+	}
+	if taskDefinition.EphemeralStorage != nil {
+		task.EphemeralStorageGiB = int(taskDefinition.EphemeralStorage.SizeInGiB)
+	}
+
+	var runtimes []ContainerRuntime
+	for _, container := range taskDefinition.ContainerDefinitions {
+		runtime := task
+		runtime.Name = aws.ToString(container.Name)
+		if container.LinuxParameters != nil {
+			runtime.InitProcessEnabled = aws.ToBool(container.LinuxParameters.InitProcessEnabled)
+		}
+		runtime.ReadonlyRootFilesystem = aws.ToBool(container.ReadonlyRootFilesystem)
+		runtimes = append(runtimes, runtime)
+	}
+	return runtimes
+}
+
+// AssertTaskRuntime fails the test unless a container runs on Linux with the runtime listed in TaskRuntimes
+func AssertTaskRuntime(t *testing.T, container ContainerRuntime) {
+	expected, exists := TaskRuntimes[container.Name]
+	require.True(t, exists, "Container %s has no runtime in common.TaskRuntimes", container.Name)
+
+	assert.Equal(t, "LINUX", container.OperatingSystemFamily,
+		"%s should declare its operating system family", container.Name)
+	assert.Equal(t, expected.CPUArchitecture, container.CPUArchitecture,
+		"%s should run on the architecture its image is built for", container.Name)
+	assert.Equal(t, expected.EphemeralStorageGiB, container.EphemeralStorageGiB,
+		"%s ephemeral storage changed; storage beyond the Fargate default is billed", container.Name)
+	assert.Equal(t, expected.InitProcessEnabled, container.InitProcessEnabled,
+		"%s init process setting", container.Name)
+	assert.Equal(t, expected.ReadonlyRootFilesystem, container.ReadonlyRootFilesystem,
+		"%s read-only root filesystem setting", container.Name)
+}
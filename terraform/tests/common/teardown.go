@@ -0,0 +1,402 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/cleanup"
+	"terraform-tests/s3util"
+	"terraform-tests/tfopts"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	terratest_testing "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// DestroyRetryPolicy controls how often a failed destroy is retried and how long to wait between attempts
+type DestroyRetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration // Doubled after every failed attempt
+	MaxBackoff     time.Duration
+}
+
+// DefaultDestroyRetryPolicy allows four attempts over roughly seven minutes, enough for AWS to release ENIs left
+// by Lambda functions and VPC endpoints
+func DefaultDestroyRetryPolicy() DestroyRetryPolicy {
+	return DestroyRetryPolicy{MaxAttempts: 4, InitialBackoff: 30 * time.Second, MaxBackoff: 4 * time.Minute}
+}
+
+// destroyBlocker is a destroy failure that can be cleared through the AWS API before the next attempt
+type destroyBlocker struct {
+	name      string
+	pattern   *regexp.Regexp // Matches one diagnostic; the first group is the ID of the blocked resource
+	remediate func(t terratest_testing.TestingT, clients *teardownClients, id string) error
+}
+
+// dependencyViolationPattern matches a diagnostic for a subnet, security group or VPC that could not be deleted
+// while something still used it; the first group is its ID
+var dependencyViolationPattern = regexp.MustCompile(
+	`deleting (?:EC2 Subnet|Security Group|EC2 VPC) \(((?:subnet|sg|vpc)-[0-9a-f]+)\).*DependencyViolation`)
+
+// destroyBlockers are the failures seen when destroying test stacks that terraform cannot resolve by itself
+var destroyBlockers = []destroyBlocker{
+	{
+		name:      "non-empty S3 bucket",
+		pattern:   regexp.MustCompile(`deleting S3 Bucket \(([^)]+)\).*BucketNotEmpty`),
+		remediate: emptyBucket,
+	},
+	{
+		name: "enabled CloudFront distribution",
+		pattern: regexp.MustCompile(
+			`CloudFront Distribution \(([A-Z0-9]+)\).*(DistributionNotDisabled|timeout while waiting)`),
+		remediate: disableDistribution,
+	},
+	{
+		name:      "network interface still in use",
+		pattern:   dependencyViolationPattern,
+		remediate: releaseNetworkInterfaces,
+	},
+}
+
+// DestroyWithRetryE runs terraform destroy until it succeeds or the policy's attempts run out, backing off between
+// attempts. Every failed destroy is retried, since throttling and eventually consistent dependencies clear by
+// themselves; a known blocker (a bucket that still holds object versions, a CloudFront distribution that is still
+// enabled, or ENIs that keep a subnet or security group in use) is also cleared through the AWS API first. Only a
+// terraform binary that cannot be run, which would only fail again, is returned at once. It returns the last
+// destroy error.
+func DestroyWithRetryE(
+	t terratest_testing.TestingT,
+	terraformOptions *terraform.Options,
+	policy DestroyRetryPolicy,
+) error {
+	_, err := DestroyWithReportE(t, terraformOptions, policy)
+	return err
+}
+
+// DestroyWithReportE is DestroyWithRetryE that also reports how each attempt and each resource went, so a test can
+// hold the destroy to a time budget and say what stalled it. The report is complete even when the destroy fails.
+func DestroyWithReportE(
+	t terratest_testing.TestingT,
+	terraformOptions *terraform.Options,
+	policy DestroyRetryPolicy,
+) (*DestroyReport, error) {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	report := &DestroyReport{}
+	start := time.Now()
+	defer func() { report.Duration = time.Since(start) }()
+
+	var clients *teardownClients
+	backoff := policy.InitialBackoff
+	var err error
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		attemptStart := time.Now()
+		var output string
+		output, err = terraform.DestroyE(t, terraformOptions)
+		report.mergeDestroyProgress(ParseDestroyProgress(output))
+		result := DestroyAttempt{Duration: time.Since(attemptStart), Err: err}
+		if err == nil {
+			report.Attempts = append(report.Attempts, result)
+			return report, nil
+		}
+		for _, diagnostic := range splitDiagnostics(output + "\n" + err.Error()) {
+			if match := dependencyViolationPattern.FindStringSubmatch(diagnostic); match != nil {
+				result.DependencyViolations = append(result.DependencyViolations, match[1])
+			}
+		}
+		report.Attempts = append(report.Attempts, result)
+		if !terraformRan(err) || attempt == policy.MaxAttempts {
+			break
+		}
+
+		if blocked := findDestroyBlockers(output + "\n" + err.Error()); len(blocked) > 0 {
+			if clients == nil {
+				var clientErr error
+				if clients, clientErr = newTeardownClients(t, terraformOptions); clientErr != nil {
+					return report, fmt.Errorf("%w (AWS clients for remediation could not be created: %v)", err,
+						clientErr)
+				}
+			}
+			remediateDestroyBlockers(t, clients, blocked)
+		}
+
+		logger.Default.Logf(t, "Destroy attempt %d/%d of %s failed, retrying in %s",
+			attempt, policy.MaxAttempts, terraformOptions.TerraformDir, backoff)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, policy.MaxBackoff)
+	}
+	return report, err
+}
+
+// terraformRan reports whether a terraform command's error is terraform itself exiting with an error, as opposed to
+// the binary not running at all. Terratest wraps both in a retry.FatalError around a shell.ErrWithCmdOutput, neither
+// of which unwraps, so they are opened by hand.
+func terraformRan(err error) bool {
+	if fatal, ok := err.(retry.FatalError); ok {
+		err = fatal.Underlying
+	}
+	if withOutput, ok := err.(*shell.ErrWithCmdOutput); ok {
+		err = withOutput.Underlying
+	}
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr)
+}
+
+// registeredDestroys holds the options RegisterDestroy has seen, so each configuration is destroyed once even when
+// both SetupModuleTest and ApplyAndValidate register it
+var registeredDestroys sync.Map
+
+// RegisterDestroy destroys the configuration with DestroyWithRetryE and DefaultDestroyRetryPolicy once the test
+// finishes, in the cleanup.Destroy phase. Until then the configuration is tracked, so RunWithInterruptCleanup
+// destroys it if the run is interrupted. Configurations registered by hand, without ApplyAndValidate, are checked
+// for placeholder IDs here too.
+func RegisterDestroy(t *testing.T, terraformOptions *terraform.Options) {
+	RejectFakeIDs(t, terraformOptions)
+	if _, registered := registeredDestroys.LoadOrStore(terraformOptions, true); registered {
+		return
+	}
+
+	tfopts.Track(terraformOptions)
+	cleanup.Func(t, cleanup.Destroy, "destroy "+terraformOptions.TerraformDir, func(t *testing.T) error {
+		if err := DestroyWithRetryE(t, terraformOptions, DefaultDestroyRetryPolicy()); err != nil {
+			return err
+		}
+		tfopts.Untrack(terraformOptions)
+		return nil
+	})
+}
+
+// RegisterEmptyBuckets empties the buckets named by the given outputs before the configuration is destroyed.
+// Outputs that cannot be read, because the apply never got that far, are skipped.
+func RegisterEmptyBuckets(t *testing.T, terraformOptions *terraform.Options, bucketOutputs ...string) {
+	for _, output := range bucketOutputs {
+		cleanup.Func(t, cleanup.BeforeDestroy, "empty bucket "+output, func(t *testing.T) error {
+			bucket, err := terraform.OutputE(t, terraformOptions, output)
+			if err != nil || bucket == "" {
+				return nil
+			}
+			_, err = s3util.EmptyBucketE(t, bucket)
+			return err
+		})
+	}
+}
+
+// blockedResource is a resource a destroy failed on, and the known blocker that failed it
+type blockedResource struct {
+	blocker destroyBlocker
+	id      string
+}
+
+// findDestroyBlockers returns each resource a failed destroy's output names as held up by a known blocker
+func findDestroyBlockers(output string) []blockedResource {
+	var blocked []blockedResource
+	seen := map[string]bool{}
+	for _, diagnostic := range splitDiagnostics(output) {
+		for _, blocker := range destroyBlockers {
+			match := blocker.pattern.FindStringSubmatch(diagnostic)
+			if match == nil || seen[match[1]] {
+				continue
+			}
+			seen[match[1]] = true
+			blocked = append(blocked, blockedResource{blocker: blocker, id: match[1]})
+		}
+	}
+	return blocked
+}
+
+// remediateDestroyBlockers clears the blockers a failed destroy named. Remediation failures are only logged, since
+// the next attempt reports whatever is still blocked.
+func remediateDestroyBlockers(t terratest_testing.TestingT, clients *teardownClients, blocked []blockedResource) {
+	for _, resource := range blocked {
+		logger.Default.Logf(t, "Destroy blocked by %s %s, remediating", resource.blocker.name, resource.id)
+		if err := resource.blocker.remediate(t, clients, resource.id); err != nil {
+			logger.Default.Logf(t, "Failed to remediate %s %s: %v", resource.blocker.name, resource.id, err)
+		}
+	}
+}
+
+// diagnosticBorder matches the box drawing terraform puts around diagnostics, which also wraps long messages
+var diagnosticBorder = regexp.MustCompile(`[│╷╵]`)
+
+// splitDiagnostics returns each "Error:" diagnostic in terraform's output on a single line
+func splitDiagnostics(output string) []string {
+	flattened := strings.Join(strings.Fields(diagnosticBorder.ReplaceAllString(output, " ")), " ")
+	diagnostics := strings.Split(flattened, "Error: ")
+	return diagnostics[1:]
+}
+
+// teardownClients are the AWS clients used to remediate destroy blockers
+type teardownClients struct {
+	ctx context.Context
+	ec2 *ec2.Client
+}
+
+// newTeardownClients creates clients for the region the terraform options deploy to
+func newTeardownClients(
+	t terratest_testing.TestingT,
+	terraformOptions *terraform.Options,
+) (*teardownClients, error) {
+	region := terraformOptions.EnvVars["AWS_DEFAULT_REGION"]
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	ctx := context.Background()
+	cfg, err := awscalls.LoadConfig(ctx, t, region)
+	if err != nil {
+		return nil, err
+	}
+	return &teardownClients{ctx: ctx, ec2: ec2.NewFromConfig(cfg)}, nil
+}
+
+// emptyBucket deletes every object version and delete marker in a bucket. Versioned buckets keep both after their
+// objects are deleted, and terraform only empties buckets that set force_destroy.
+func emptyBucket(t terratest_testing.TestingT, _ *teardownClients, bucket string) error {
+	_, err := s3util.EmptyBucketE(t, bucket)
+	return err
+}
+
+// disableDistribution disables a CloudFront distribution, which must happen before it can be deleted, and waits for
+// the change to deploy. It shells out to the AWS CLI, which CI images already provide.
+func disableDistribution(t terratest_testing.TestingT, _ *teardownClients, id string) error {
+	output, err := shell.RunCommandAndGetStdOutE(t, shell.Command{
+		Command: "aws",
+		Args:    []string{"cloudfront", "get-distribution-config", "--id", id, "--output", "json"},
+	})
+	if err != nil {
+		return err
+	}
+
+	var current struct {
+		ETag               string                 `json:"ETag"`
+		DistributionConfig map[string]interface{} `json:"DistributionConfig"`
+	}
+	if err = json.Unmarshal([]byte(output), &current); err != nil {
+		return fmt.Errorf("failed to parse distribution config: %w", err)
+	}
+
+	if enabled, _ := current.DistributionConfig["Enabled"].(bool); enabled {
+		current.DistributionConfig["Enabled"] = false
+		updated, marshalErr := json.Marshal(current.DistributionConfig)
+		if marshalErr != nil {
+			return marshalErr
+		}
+
+		configFile := filepath.Join(os.TempDir(), fmt.Sprintf("distribution-%s-%s.json", id, tfopts.NewUniqueID()))
+		if err = os.WriteFile(configFile, updated, 0o600); err != nil {
+			return err
+		}
+		defer os.Remove(configFile)
+
+		if _, err = shell.RunCommandAndGetStdOutE(t, shell.Command{
+			Command: "aws",
+			Args: []string{
+				"cloudfront", "update-distribution",
+				"--id", id,
+				"--if-match", current.ETag,
+				"--distribution-config", "file://" + configFile,
+			},
+		}); err != nil {
+			return err
+		}
+		logger.Default.Logf(t, "Disabled CloudFront distribution %s", id)
+	}
+
+	_, err = shell.RunCommandAndGetStdOutE(t, shell.Command{
+		Command: "aws",
+		Args:    []string{"cloudfront", "wait", "distribution-deployed", "--id", id},
+	})
+	return err
+}
+
+// interfaceFilterNames maps the ID prefix of a resource blocked by ENIs to the filter that finds those ENIs
+var interfaceFilterNames = map[string]string{
+	"subnet": "subnet-id",
+	"sg":     "group-id",
+	"vpc":    "vpc-id",
+}
+
+// releaseNetworkInterfaces deletes the detached ENIs that keep a subnet, security group or VPC in use, and the VPC
+// endpoints that own any endpoint ENIs among them. ENIs still attached to something else, such as a Lambda
+// function AWS has not finished cleaning up, are left for a later attempt.
+func releaseNetworkInterfaces(t terratest_testing.TestingT, clients *teardownClients, id string) error {
+	filterName := interfaceFilterNames[strings.SplitN(id, "-", 2)[0]]
+	interfaces, err := clients.ec2.DescribeNetworkInterfaces(clients.ctx, &ec2.DescribeNetworkInterfacesInput{
+		Filters: []ec2types.Filter{{Name: aws.String(filterName), Values: []string{id}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, eni := range interfaces.NetworkInterfaces {
+		eniID := aws.ToString(eni.NetworkInterfaceId)
+		switch {
+		case eni.InterfaceType == ec2types.NetworkInterfaceTypeVpcEndpoint:
+			// Endpoint ENIs cannot be deleted directly; they go once their endpoint is deleted
+			if err = deleteOwningEndpoint(t, clients, aws.ToString(eni.VpcId), eniID); err != nil {
+				return err
+			}
+		case eni.Status == ec2types.NetworkInterfaceStatusAvailable:
+			if _, err = clients.ec2.DeleteNetworkInterface(clients.ctx, &ec2.DeleteNetworkInterfaceInput{
+				NetworkInterfaceId: eni.NetworkInterfaceId,
+			}); err != nil {
+				return err
+			}
+			logger.Default.Logf(t, "Deleted detached ENI %s (%s)", eniID, aws.ToString(eni.Description))
+		default:
+			logger.Default.Logf(t, "ENI %s (%s) is %s, waiting for AWS to release it",
+				eniID, aws.ToString(eni.Description), eni.Status)
+		}
+	}
+	return nil
+}
+
+// deleteOwningEndpoint deletes the VPC endpoint that owns an ENI, unless it is already being deleted
+func deleteOwningEndpoint(t terratest_testing.TestingT, clients *teardownClients, vpcID, eniID string) error {
+	endpoints, err := clients.ec2.DescribeVpcEndpoints(clients.ctx, &ec2.DescribeVpcEndpointsInput{
+		Filters: []ec2types.Filter{{Name: aws.String("vpc-id"), Values: []string{vpcID}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints.VpcEndpoints {
+		owns := false
+		for _, id := range endpoint.NetworkInterfaceIds {
+			owns = owns || id == eniID
+		}
+		state := strings.ToLower(string(endpoint.State))
+		if !owns || state == "deleting" || state == "deleted" {
+			continue
+		}
+
+		if _, err = clients.ec2.DeleteVpcEndpoints(clients.ctx, &ec2.DeleteVpcEndpointsInput{
+			VpcEndpointIds: []string{aws.ToString(endpoint.VpcEndpointId)},
+		}); err != nil {
+			return err
+		}
+		logger.Default.Logf(t, "Deleted VPC endpoint %s, which owned ENI %s", aws.ToString(endpoint.VpcEndpointId), eniID)
+	}
+	return nil
+}
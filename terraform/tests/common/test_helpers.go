@@ -1,278 +1,21 @@
 package common
 
 import (
-	"context"
 	"fmt"
-	"os"
 	"strings"
 	"testing"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"terraform-tests/awsval"
+	"terraform-tests/tfopts"
+
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	terratest_aws "github.com/gruntwork-io/terratest/modules/aws"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-// TestConfig holds common configuration for tests
-type TestConfig struct {
-	TerraformDir string
-	AWSRegion    string
-	Prefix       string
-	UniqueID     string
-	AccountID    string // Public field for test access
-}
-
-// NewTestConfig creates a new test configuration with a unique ID
-func NewTestConfig(terraformDir string) *TestConfig {
-	// Use time-based unique ID instead of deprecated rand.Seed
-	uniqueID := fmt.Sprintf("test-%d", time.Now().UnixNano()%100000)
-
-	return &TestConfig{
-		TerraformDir: terraformDir,
-		AWSRegion:    "us-east-1",
-		Prefix:       fmt.Sprintf("coalition-%s", uniqueID),
-		UniqueID:     uniqueID,
-	}
-}
-
-// GetTerraformOptions returns default terraform options for testing with remote backend
-func (tc *TestConfig) GetTerraformOptions(vars map[string]interface{}) *terraform.Options {
-	defaultVars := map[string]interface{}{
-		"prefix":     tc.Prefix,
-		"aws_region": tc.AWSRegion,
-		// Test-specific overrides
-		"create_vpc":             true,
-		"create_public_subnets":  true,
-		"create_private_subnets": true,
-		"create_db_subnets":      true,
-		// Use minimal resources for testing
-		"db_allocated_storage": 20,
-		"db_instance_class":    "db.t4g.micro",
-		// Required for some modules but not needed for most tests
-		"route53_zone_id": "Z123456789",
-		"domain_name":     fmt.Sprintf("%s.example.com", tc.UniqueID),
-		"alert_email":     "test@example.com",
-		"db_password":     "testpassword123!",
-		"app_db_password": "apppassword123!",
-	}
-
-	// Merge with provided vars (provided vars override defaults)
-	for k, v := range vars {
-		defaultVars[k] = v
-	}
-
-	return &terraform.Options{
-		TerraformDir:    tc.TerraformDir,
-		TerraformBinary: "terraform", // Explicitly use terraform instead of auto-detecting OpenTofu
-		Vars:            defaultVars,
-		BackendConfig: map[string]interface{}{
-			"bucket":         fmt.Sprintf("coalition-terraform-state-%s", tc.mustGetAccountID()),
-			"key":            fmt.Sprintf("tests/terraform-test-%s.tfstate", tc.UniqueID),
-			"region":         tc.AWSRegion,
-			"encrypt":        true,
-			"dynamodb_table": "coalition-terraform-locks",
-		},
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION":  tc.AWSRegion,
-			"TERRATEST_TERRAFORM": "terraform", // Force Terratest to use terraform
-		},
-	}
-}
-
-// GetTerraformOptionsForPlanOnly returns terraform options for plan-only tests (no backend)
-// Only includes minimal defaults — callers should pass module-specific vars explicitly.
-func (tc *TestConfig) GetTerraformOptionsForPlanOnly(vars map[string]interface{}) *terraform.Options {
-	defaultVars := map[string]interface{}{}
-
-	// Merge with provided vars (provided vars override defaults)
-	for k, v := range vars {
-		defaultVars[k] = v
-	}
-
-	return &terraform.Options{
-		TerraformDir:    tc.TerraformDir,
-		TerraformBinary: "terraform", // Explicitly use terraform instead of auto-detecting OpenTofu
-		Vars:            defaultVars,
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION":  tc.AWSRegion,
-			"TERRATEST_TERRAFORM": "terraform",
-		},
-	}
-}
-
-// mustGetAccountID returns the AWS account ID for backend configuration
-// Used only by regular terraform tests that need S3 backend
-func (tc *TestConfig) mustGetAccountID() string {
-	// Try from environment variable first (set in CI)
-	if accountID := os.Getenv("AWS_ACCOUNT_ID"); accountID != "" {
-		return accountID
-	}
-
-	// For local development, use a fallback value
-	// Real apply tests should set AWS_ACCOUNT_ID environment variable
-	return "123456789012" // placeholder account ID
-}
-
-// GetAccountID lazily populates and returns the AccountID field
-func (tc *TestConfig) GetAccountID() string {
-	if tc.AccountID == "" {
-		tc.AccountID = tc.mustGetAccountID()
-	}
-	return tc.AccountID
-}
-
-// GetModuleTerraformOptions returns terraform options for testing individual modules
-func (tc *TestConfig) GetModuleTerraformOptions(modulePath string, vars map[string]interface{}) *terraform.Options {
-	// Get minimal variables suitable for individual module testing
-	moduleVars := tc.getModuleSpecificVars(modulePath, vars)
-
-	return &terraform.Options{
-		TerraformDir:    modulePath,
-		TerraformBinary: "terraform", // Explicitly use terraform instead of auto-detecting OpenTofu
-		Vars:            moduleVars,
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION":  tc.AWSRegion,
-			"TERRATEST_TERRAFORM": "terraform", // Force Terratest to use terraform
-		},
-	}
-}
-
-// getModuleSpecificVars returns only the variables needed for a specific module
-func (tc *TestConfig) getModuleSpecificVars(
-	modulePath string,
-	additionalVars map[string]interface{},
-) map[string]interface{} {
-	var baseVars map[string]interface{}
-
-	// Add module-specific variables based on the module path
-	switch {
-	case strings.Contains(modulePath, "/security"):
-		// Security module variables (does not include aws_region)
-		baseVars = map[string]interface{}{
-			"prefix":                   tc.Prefix,
-			"vpc_id":                   "vpc-12345678",
-			"allowed_bastion_cidrs":    []string{"10.0.0.0/8"},
-			"lambda_security_group_id": "sg-lambda123",
-		}
-	case strings.Contains(modulePath, "/networking"):
-		// Networking module variables (includes aws_region)
-		baseVars = map[string]interface{}{
-			"prefix":                 tc.Prefix,
-			"aws_region":             tc.AWSRegion,
-			"create_vpc":             true,
-			"create_public_subnets":  true,
-			"create_private_subnets": true,
-			"create_db_subnets":      true,
-		}
-	case strings.Contains(modulePath, "/database"):
-		// Database module variables (includes aws_region)
-		baseVars = map[string]interface{}{
-			"prefix":               tc.Prefix,
-			"aws_region":           tc.AWSRegion,
-			"db_subnet_ids":        []string{"subnet-12345", "subnet-67890"},
-			"db_security_group_id": "sg-db123",
-			"db_allocated_storage": 20,
-			"db_instance_class":    "db.t4g.micro",
-			"db_password":          "testpassword123!",
-			"db_name":              "testdb",
-			"db_username":          "testuser",
-			"app_db_username":      "appuser",
-		}
-	case strings.Contains(modulePath, "/bastion"):
-		// Bastion module variables
-		baseVars = map[string]interface{}{
-			"prefix":                    tc.Prefix,
-			"public_subnet_id":          "subnet-public",
-			"bastion_security_group_id": "sg-bastion123",
-			"bastion_key_name":          "test-key",
-			"bastion_public_key":        "",
-			"create_new_key_pair":       false,
-		}
-	default:
-		// Default fallback for unrecognized modules
-		baseVars = map[string]interface{}{
-			"prefix":     tc.Prefix,
-			"aws_region": tc.AWSRegion,
-		}
-	}
-
-	// Merge with additional vars (additional vars override base vars)
-	for k, v := range additionalVars {
-		baseVars[k] = v
-	}
-
-	return baseVars
-}
-
-// GetSubnetById gets a subnet by ID using AWS SDK v2 directly
-func GetSubnetById(t *testing.T, subnetID, region string) *types.Subnet {
-	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
-	assert.NoError(t, err)
-
-	svc := ec2.NewFromConfig(cfg)
-	result, err := svc.DescribeSubnets(context.Background(), &ec2.DescribeSubnetsInput{
-		SubnetIds: []string{subnetID},
-	})
-	assert.NoError(t, err)
-	assert.Len(t, result.Subnets, 1)
-
-	return &result.Subnets[0]
-}
-
-// GetSecurityGroupById gets a security group by ID using AWS SDK v2 directly
-func GetSecurityGroupById(t *testing.T, sgID, region string) *types.SecurityGroup {
-	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
-	assert.NoError(t, err)
-
-	svc := ec2.NewFromConfig(cfg)
-	result, err := svc.DescribeSecurityGroups(context.Background(), &ec2.DescribeSecurityGroupsInput{
-		GroupIds: []string{sgID},
-	})
-	assert.NoError(t, err)
-	assert.Len(t, result.SecurityGroups, 1)
-
-	return &result.SecurityGroups[0]
-}
-
-// GetInternetGatewaysForVpc gets internet gateways for a VPC using AWS SDK v2 directly
-func GetInternetGatewaysForVpc(t *testing.T, vpcID, region string) []types.InternetGateway {
-	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
-	assert.NoError(t, err)
-
-	svc := ec2.NewFromConfig(cfg)
-	result, err := svc.DescribeInternetGateways(context.Background(), &ec2.DescribeInternetGatewaysInput{
-		Filters: []types.Filter{
-			{
-				Name:   aws.String("attachment.vpc-id"),
-				Values: []string{vpcID},
-			},
-		},
-	})
-	assert.NoError(t, err)
-
-	return result.InternetGateways
-}
-
-// GetEc2InstanceById gets an EC2 instance by ID using AWS SDK v2 directly
-func GetEc2InstanceById(t *testing.T, instanceID, region string) *types.Instance {
-	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
-	assert.NoError(t, err)
-
-	svc := ec2.NewFromConfig(cfg)
-	result, err := svc.DescribeInstances(context.Background(), &ec2.DescribeInstancesInput{
-		InstanceIds: []string{instanceID},
-	})
-	assert.NoError(t, err)
-	assert.Len(t, result.Reservations, 1)
-	assert.Len(t, result.Reservations[0].Instances, 1)
-
-	return &result.Reservations[0].Instances[0]
-}
-
 // ValidateAWSResource checks if an AWS resource exists
 func ValidateAWSResource(t *testing.T, awsRegion, resourceType, resourceID string) {
 	switch resourceType {
@@ -281,11 +24,11 @@ func ValidateAWSResource(t *testing.T, awsRegion, resourceType, resourceID strin
 		assert.NotNil(t, vpc)
 		// Note: VPC state validation removed as Terratest VPC struct doesn't expose State field
 	case "subnet":
-		subnet := GetSubnetById(t, resourceID, awsRegion)
+		subnet := awsval.GetSubnet(t, resourceID, awsRegion)
 		assert.NotNil(t, subnet)
 		assert.Equal(t, types.SubnetStateAvailable, subnet.State)
 	case "security_group":
-		sg := GetSecurityGroupById(t, resourceID, awsRegion)
+		sg := awsval.GetSecurityGroup(t, resourceID, awsRegion)
 		assert.NotNil(t, sg)
 	case "load_balancer":
 		// Load balancer validation would go here
@@ -356,24 +99,17 @@ func GetIntegrationTestVars() map[string]interface{} {
 	return testVars
 }
 
-// CleanupResources performs cleanup for failed tests
+// CleanupResources destroys a test's resources now, retrying and clearing known blockers with
+// DefaultDestroyRetryPolicy, and fails the test if they cannot all be destroyed. Tests register the destroy with
+// RegisterDestroy instead, so it runs in order with their other cleanup.
 func CleanupResources(t *testing.T, terraformOptions *terraform.Options) {
-	// This will run terraform destroy
-	terraform.Destroy(t, terraformOptions)
-}
-
-// SkipIfShortTest skips tests that require AWS resources when running with -short flag
-func SkipIfShortTest(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping test that requires AWS resources in short mode")
-	}
+	require.NoError(t, DestroyWithRetryE(t, terraformOptions, DefaultDestroyRetryPolicy()),
+		"Failed to destroy %s", terraformOptions.TerraformDir)
 }
 
 // ValidateModuleStructure validates that a Terraform module has the expected file structure
 func ValidateModuleStructure(t *testing.T, moduleName string) {
-	if !testing.Short() {
-		t.Skip("Skipping validation-only test in full mode")
-	}
+	RequireTier(t, TierUnit)
 
 	moduleDir := fmt.Sprintf("../../modules/%s", moduleName)
 	requiredFiles := []string{"main.tf", "variables.tf", "outputs.tf", "versions.tf"}
@@ -385,135 +121,42 @@ func ValidateModuleStructure(t *testing.T, moduleName string) {
 	t.Logf("%s module structure validation passed", moduleName)
 }
 
-// SetupModuleTest sets up a module test with common configuration and cleanup
+// SetupModuleTest sets up a module test in its own namespace, with common configuration and cleanup. The returned
+// configuration carries the namespace's prefix. Tests call RequireTier first: a plan-tier test gets options in
+// ModePlan for PlanOnly, and any other tier options in ModeApply for ApplyAndValidate, which destroys them.
 func SetupModuleTest(
 	t *testing.T,
 	moduleName string,
 	testVars map[string]interface{},
-) (*TestConfig, *terraform.Options) {
-	SkipIfShortTest(t)
-
-	testConfig := NewTestConfig(fmt.Sprintf("../../modules/%s", moduleName))
-	terraformOptions := testConfig.GetModuleTerraformOptions(fmt.Sprintf("../../modules/%s", moduleName), testVars)
-
-	// Setup cleanup using t.Cleanup for better test isolation
-	t.Cleanup(func() {
-		CleanupResources(t, terraformOptions)
-	})
+) (*tfopts.TestConfig, *ModeOptions) {
+	modulePath := fmt.Sprintf("../../modules/%s", moduleName)
+	namespace := NewNamespace(tfopts.NewTestConfig(modulePath), moduleName)
+	options := namespace.GetModuleTerraformOptions(t, modulePath, testVars)
+	if tier, _ := TestTier(t); tier == TierPlan {
+		options = tfopts.PlanMode(options.Options)
+	}
 
-	return testConfig, terraformOptions
+	return namespace.Config, options
 }
 
-// GetDefaultDatabaseTestVars returns default test variables for database module
+// GetDefaultDatabaseTestVars returns the database module's test overrides, such as the smallest instance class
 func GetDefaultDatabaseTestVars() map[string]interface{} {
-	return map[string]interface{}{
-		"db_subnet_ids":              []string{"subnet-db1", "subnet-db2"},
-		"db_security_group_id":       "sg-database123",
-		"db_allocated_storage":       20,
-		"db_engine_version":          "16.9",
-		"db_instance_class":          "db.t4g.micro",
-		"db_name":                    "testdb",
-		"db_username":                "testuser",
-		"db_password":                "testpassword123!",
-		"app_db_username":            "appuser",
-		"use_secrets_manager":        false,
-		"db_backup_retention_period": 7,
-		"auto_setup_database":        false,
-	}
+	return tfopts.ConfigurationTestValues("database")
 }
 
-// GetDefaultSecurityTestVars returns default test variables for security module
+// GetDefaultSecurityTestVars returns the security module's test overrides
 func GetDefaultSecurityTestVars() map[string]interface{} {
-	return map[string]interface{}{
-		"vpc_id":                   "vpc-12345678",
-		"allowed_bastion_cidrs":    []string{"10.0.0.0/8"},
-		"lambda_security_group_id": "sg-lambda123",
-	}
+	return tfopts.ConfigurationTestValues("security")
 }
 
-// GetMonitoringTestVars returns default test variables for monitoring module
+// GetMonitoringTestVars returns the monitoring module's test overrides
 func GetMonitoringTestVars() map[string]interface{} {
-	return map[string]interface{}{
-		"vpc_id":              "vpc-12345678",
-		"alert_email":         "test@example.com",
-		"budget_limit_amount": "100",
-	}
+	return tfopts.ConfigurationTestValues("monitoring")
 }
 
-// GetDefaultStorageTestVars returns default test variables for storage module
+// GetDefaultStorageTestVars returns the storage module's test overrides, which leave CloudFront disabled
 func GetDefaultStorageTestVars() map[string]interface{} {
-	return map[string]interface{}{
-		"domain_name":            "test.example.com",
-		"alb_dns_name":           "test-alb-123456789.us-east-1.elb.amazonaws.com",
-		"force_destroy":          true,
-		"cors_allowed_origins":   []string{"https://example.com"},
-		"enable_versioning":      true,
-		"enable_lifecycle_rules": true,
-	}
-}
-
-// ValidateTerraformOutput validates that a terraform output exists and is not empty
-func ValidateTerraformOutput(t *testing.T, terraformOptions *terraform.Options, outputName string) string {
-	output := terraform.Output(t, terraformOptions, outputName)
-	assert.NotEmpty(t, output, fmt.Sprintf("Terraform output '%s' should not be empty", outputName))
-	return output
-}
-
-// ValidateTerraformOutputList validates that a terraform output list exists and has expected length
-func ValidateTerraformOutputList(
-	t *testing.T,
-	terraformOptions *terraform.Options,
-	outputName string,
-	expectedLength int,
-) []string {
-	outputs := terraform.OutputList(t, terraformOptions, outputName)
-	if expectedLength > 0 {
-		assert.Len(
-			t,
-			outputs,
-			expectedLength,
-			fmt.Sprintf("Terraform output list '%s' should have %d items", outputName, expectedLength),
-		)
-	} else {
-		assert.NotEmpty(t, outputs, fmt.Sprintf("Terraform output list '%s' should not be empty", outputName))
-	}
-	return outputs
-}
-
-// RunTerraformWithProgress runs terraform init and apply with configurable progress logging interval
-func RunTerraformWithProgress(
-	t *testing.T,
-	terraformOptions *terraform.Options,
-	operationName string,
-	tickerInterval time.Duration,
-) {
-	t.Logf("Starting %s at %s", operationName, time.Now().Format("15:04:05"))
-
-	// Use default interval if zero value provided
-	if tickerInterval == 0 {
-		tickerInterval = 2 * time.Minute
-	}
-
-	// Log the progress periodically
-	done := make(chan bool)
-	go func() {
-		ticker := time.NewTicker(tickerInterval)
-		defer ticker.Stop()
-		startTime := time.Now()
-		for {
-			select {
-			case <-done:
-				return
-			case <-ticker.C:
-				elapsed := time.Since(startTime)
-				t.Logf("Still running %s - elapsed time: %v", operationName, elapsed)
-			}
-		}
-	}()
-	defer close(done)
-
-	terraform.InitAndApply(t, terraformOptions)
-	t.Logf("%s completed at %s", operationName, time.Now().Format("15:04:05"))
+	return tfopts.ConfigurationTestValues("storage")
 }
 
 // LogPhaseStart logs the start of a test phase with timestamp
@@ -526,28 +169,13 @@ func LogPhaseComplete(t *testing.T, phaseName, result string) {
 	t.Logf("%s complete: %s", phaseName, result)
 }
 
-// InitTerraformForPlanOnly initializes terraform without backend for plan-only tests
-func InitTerraformForPlanOnly(t *testing.T, terraformOptions *terraform.Options) {
-	t.Logf("Starting terraform init for directory: %s", terraformOptions.TerraformDir)
-	terraform.RunTerraformCommand(t, terraformOptions, "init", "-backend=false")
-	t.Logf("Terraform init completed successfully")
-}
-
-// CleanupTerraformState removes local terraform state to prevent conflicts between tests
-func CleanupTerraformState(t *testing.T, terraformDir string) {
-	terraformStateDir := fmt.Sprintf("%s/.terraform", terraformDir)
-	if err := os.RemoveAll(terraformStateDir); err != nil {
-		t.Logf("Warning: Failed to cleanup terraform state directory: %v", err)
-	} else {
-		t.Logf("Cleaned up terraform state directory: %s", terraformStateDir)
-	}
-}
-
 // SetupIntegrationTest creates a TestConfig with automatic cleanup for integration tests
-func SetupIntegrationTest(t *testing.T) *TestConfig {
-	testConfig := NewTestConfig("../../")
+func SetupIntegrationTest(t *testing.T) *tfopts.TestConfig {
+	RequireTier(t, TierIntegration)
+
+	testConfig := tfopts.NewTestConfig("../../")
 	t.Cleanup(func() {
-		CleanupTerraformState(t, testConfig.TerraformDir)
+		tfopts.CleanupState(t, testConfig.TerraformDir)
 	})
 	return testConfig
 }
@@ -0,0 +1,122 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Tier is how much a test needs to run, from the repository alone to a deployed stack. Every test declares its
+// tier with RequireTier, and TEST_TIERS selects the tiers that run.
+type Tier string
+
+// Tiers, from cheapest to most expensive
+const (
+	TierUnit        Tier = "unit"        // Reads the repository only; needs no AWS credentials
+	TierPlan        Tier = "plan"        // Runs terraform init and plan for a module; creates nothing
+	TierApply       Tier = "apply"       // Applies and destroys a single module
+	TierIntegration Tier = "integration" // Plans the root configuration, which wires the modules together
+	TierE2E         Tier = "e2e"         // Runs against a deployed stack
+)
+
+// AllTiers lists every tier in order
+var AllTiers = []Tier{TierUnit, TierPlan, TierApply, TierIntegration, TierE2E}
+
+// shortTiers are the tiers -short runs when TEST_TIERS is unset: those that create nothing
+var shortTiers = []Tier{TierUnit, TierPlan}
+
+var (
+	testTiersMutex sync.Mutex
+	testTiers      = map[string]Tier{} // By test name, as declared with RequireTier
+)
+
+// RequireTier skips the test unless its tier is enabled. The enabled tiers are TEST_TIERS, a comma-separated list
+// such as "unit,plan" or "all"; when it is unset, -short enables unit and plan, and a run without it enables all.
+// Tests in the integration and e2e tiers still skip when the account or stack they need is not configured.
+func RequireTier(t *testing.T, tier Tier) {
+	t.Helper()
+
+	enabled, source, err := EnabledTiers()
+	if err != nil {
+		t.Fatalf("Invalid TEST_TIERS: %v", err)
+	}
+	for _, candidate := range enabled {
+		if candidate == tier {
+			testTiersMutex.Lock()
+			testTiers[t.Name()] = tier
+			testTiersMutex.Unlock()
+			return
+		}
+	}
+	t.Skipf("Skipping %s test - %s enables only %s", tier, source, joinTiers(enabled))
+}
+
+// TestTier returns the tier a test declared with RequireTier. Subtests have the tier of the closest test above
+// them that declared one.
+func TestTier(t *testing.T) (Tier, bool) {
+	testTiersMutex.Lock()
+	defer testTiersMutex.Unlock()
+
+	for name := t.Name(); ; {
+		if tier, ok := testTiers[name]; ok {
+			return tier, true
+		}
+		i := strings.LastIndex(name, "/")
+		if i < 0 {
+			return "", false
+		}
+		name = name[:i]
+	}
+}
+
+// EnabledTiers returns the tiers enabled for this run and what enabled them, for messages
+func EnabledTiers() ([]Tier, string, error) {
+	if value, ok := os.LookupEnv("TEST_TIERS"); ok && strings.TrimSpace(value) != "" {
+		tiers, err := ParseTiers(value)
+		return tiers, "TEST_TIERS", err
+	}
+	if testing.Short() {
+		return shortTiers, "-short", nil
+	}
+	return AllTiers, "the default", nil
+}
+
+// ParseTiers parses a comma-separated list of tier names, where "all" stands for every tier
+func ParseTiers(value string) ([]Tier, error) {
+	var tiers []Tier
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "":
+			continue
+		case name == "all":
+			return AllTiers, nil
+		case !isTier(Tier(name)):
+			return nil, fmt.Errorf("unknown tier %q; expected all or one of %s", name, joinTiers(AllTiers))
+		}
+		tiers = append(tiers, Tier(name))
+	}
+	if len(tiers) == 0 {
+		return nil, fmt.Errorf("no tiers in %q", value)
+	}
+	return tiers, nil
+}
+
+func isTier(tier Tier) bool {
+	for _, known := range AllTiers {
+		if known == tier {
+			return true
+		}
+	}
+	return false
+}
+
+func joinTiers(tiers []Tier) string {
+	names := make([]string, 0, len(tiers))
+	for _, tier := range tiers {
+		names = append(names, string(tier))
+	}
+	return strings.Join(names, ",")
+}
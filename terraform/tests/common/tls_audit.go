@@ -0,0 +1,148 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// TLSAuditChecks returns the checks that verify every data path of the stack uses TLS
+func TLSAuditChecks() []AuditCheck {
+	return []AuditCheck{
+		CheckCloudFrontTLS,
+		CheckLoadBalancerListenerTLS,
+		CheckRDSForceSSL,
+		CheckS3SecureTransport,
+	}
+}
+
+// CheckCloudFrontTLS verifies viewer and origin protocol policies and the viewer certificate TLS floor
+func CheckCloudFrontTLS(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_cloudfront_distribution" {
+		return nil
+	}
+
+	var findings []AuditFinding
+	behaviors := append(
+		nestedBlocks(resource.AttributeValues, "default_cache_behavior"),
+		nestedBlocks(resource.AttributeValues, "ordered_cache_behavior")...,
+	)
+	for _, behavior := range behaviors {
+		policy, _ := behavior["viewer_protocol_policy"].(string)
+		findings = append(findings, passFail("TLS-CloudFront-Viewer", resource,
+			policy == "redirect-to-https" || policy == "https-only",
+			fmt.Sprintf("cache behavior for %v allows viewer protocol %q", behavior["path_pattern"], policy)))
+	}
+
+	for _, origin := range nestedBlocks(resource.AttributeValues, "origin") {
+		for _, custom := range nestedBlocks(origin, "custom_origin_config") {
+			policy, _ := custom["origin_protocol_policy"].(string)
+			findings = append(findings, passFail("TLS-CloudFront-Origin", resource, policy == "https-only",
+				fmt.Sprintf("origin %v uses origin protocol %q", origin["origin_id"], policy)))
+		}
+	}
+
+	for _, certificate := range nestedBlocks(resource.AttributeValues, "viewer_certificate") {
+		if isDefault, _ := certificate["cloudfront_default_certificate"].(bool); isDefault {
+			continue
+		}
+		version, _ := certificate["minimum_protocol_version"].(string)
+		findings = append(findings, passFail("TLS-CloudFront-MinimumVersion", resource,
+			strings.HasPrefix(version, "TLSv1.2"),
+			fmt.Sprintf("viewer certificate allows minimum protocol %q", version)))
+	}
+
+	return findings
+}
+
+// CheckLoadBalancerListenerTLS verifies load balancer listeners are HTTPS or redirect plain HTTP to HTTPS
+func CheckLoadBalancerListenerTLS(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+	if resource.Type != "aws_lb_listener" && resource.Type != "aws_alb_listener" {
+		return nil
+	}
+
+	protocol := GetPlannedStringAttribute(resource, "protocol")
+	if protocol == "HTTPS" || protocol == "TLS" {
+		return []AuditFinding{passFail("TLS-ALB-Listener", resource, true, "")}
+	}
+
+	for _, action := range nestedBlocks(resource.AttributeValues, "default_action") {
+		for _, redirect := range nestedBlocks(action, "redirect") {
+			if redirectProtocol, _ := redirect["protocol"].(string); redirectProtocol == "HTTPS" {
+				return []AuditFinding{passFail("TLS-ALB-Listener", resource, true, "")}
+			}
+		}
+	}
+
+	return []AuditFinding{passFail("TLS-ALB-Listener", resource, false,
+		fmt.Sprintf("listener serves %s without redirecting to HTTPS", protocol))}
+}
+
+// CheckRDSForceSSL verifies the parameter group attached to each DB instance sets rds.force_ssl
+func CheckRDSForceSSL(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if resource.Type != "aws_db_instance" {
+		return nil
+	}
+
+	groupName := GetPlannedStringAttribute(resource, "parameter_group_name")
+	if audit.IsUnknown(resource, "parameter_group_name") {
+		return []AuditFinding{unknown("TLS-RDS-ForceSSL", resource, "parameter_group_name")}
+	}
+
+	for _, candidate := range audit.Resources {
+		if candidate.Type != "aws_db_parameter_group" || GetPlannedStringAttribute(candidate, "name") != groupName {
+			continue
+		}
+		for _, parameter := range nestedBlocks(candidate.AttributeValues, "parameter") {
+			if parameter["name"] == "rds.force_ssl" {
+				return []AuditFinding{passFail("TLS-RDS-ForceSSL", resource, parameter["value"] == "1",
+					fmt.Sprintf("parameter group %s sets rds.force_ssl=%v", groupName, parameter["value"]))}
+			}
+		}
+		return []AuditFinding{passFail("TLS-RDS-ForceSSL", resource, false,
+			fmt.Sprintf("parameter group %s does not set rds.force_ssl", groupName))}
+	}
+
+	return []AuditFinding{passFail("TLS-RDS-ForceSSL", resource, false,
+		fmt.Sprintf("parameter group %s is not managed by this configuration", groupName))}
+}
+
+// CheckS3SecureTransport verifies bucket policies deny requests made without TLS
+func CheckS3SecureTransport(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if resource.Type != "aws_s3_bucket_policy" {
+		return nil
+	}
+
+	if audit.IsUnknown(resource, "policy") {
+		return []AuditFinding{unknown("TLS-S3-SecureTransport", resource, "policy")}
+	}
+
+	policy := GetPlannedStringAttribute(resource, "policy")
+	return []AuditFinding{passFail("TLS-S3-SecureTransport", resource, PolicyDeniesInsecureTransport(policy),
+		"bucket policy does not deny requests where aws:SecureTransport is false")}
+}
+
+// PolicyDeniesInsecureTransport reports whether an IAM policy document has a Deny on aws:SecureTransport=false
+func PolicyDeniesInsecureTransport(policy string) bool {
+	var document struct {
+		Statement []struct {
+			Effect    string                            `json:"Effect"`
+			Condition map[string]map[string]interface{} `json:"Condition"`
+		} `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(policy), &document); err != nil {
+		return false
+	}
+
+	for _, statement := range document.Statement {
+		if statement.Effect != "Deny" {
+			continue
+		}
+		if value, ok := statement.Condition["Bool"]["aws:SecureTransport"]; ok && fmt.Sprint(value) == "false" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"terraform-tests/tfopts"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+// ToggleCase is one combination of conditional variables and the number of instances each resource should plan
+type ToggleCase struct {
+	Name   string
+	Vars   map[string]interface{} // Merged over the module's base test variables
+	Counts map[string]int         // Resource address without its count or for_each index
+}
+
+// CountPlannedInstances counts the planned instances of a resource across all of its count or for_each indexes
+func CountPlannedInstances(plan *terraform.PlanStruct, address string) int {
+	instances := 0
+	for _, resource := range plan.ResourcePlannedValuesMap {
+		if resourceAddressWithoutIndex(resource.Address, resource.Type, resource.Name) == address {
+			instances++
+		}
+	}
+	return instances
+}
+
+// resourceAddressWithoutIndex drops the trailing instance index, which may itself contain dots or brackets
+func resourceAddressWithoutIndex(address, resourceType, name string) string {
+	resource := resourceType + "." + name
+	if i := strings.LastIndex(address, resource); i >= 0 {
+		return address[:i+len(resource)]
+	}
+	return address
+}
+
+// RunToggleMatrix plans a module once per case and asserts the planned instance count of every listed resource,
+// so a regression in any count or for_each condition fails with the combination that exposed it
+func RunToggleMatrix(t *testing.T, modulePath string, baseVars map[string]interface{}, cases []ToggleCase) {
+	RequireTier(t, TierPlan)
+
+	RunToggleCases(t, baseVars, cases, func(t *testing.T, vars map[string]interface{}) *terraform.PlanStruct {
+		testConfig := tfopts.NewTestConfig(modulePath)
+		return PlanOnly(t, testConfig.GetModuleTerraformOptionsForPlanOnly(modulePath, vars))
+	})
+}
+
+// RunToggleCases is RunToggleMatrix for a configuration planned some other way, such as the root configuration,
+// which plans against its S3 backend. Each case runs as a subtest that plans the base variables with the case's
+// merged over them.
+func RunToggleCases(t *testing.T, baseVars map[string]interface{}, cases []ToggleCase,
+	plan func(t *testing.T, vars map[string]interface{}) *terraform.PlanStruct) {
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			vars := make(map[string]interface{}, len(baseVars)+len(tc.Vars))
+			for name, value := range baseVars {
+				vars[name] = value
+			}
+			for name, value := range tc.Vars {
+				vars[name] = value
+			}
+
+			planned := plan(t, vars)
+
+			addresses := make([]string, 0, len(tc.Counts))
+			for address := range tc.Counts {
+				addresses = append(addresses, address)
+			}
+			sort.Strings(addresses)
+
+			for _, address := range addresses {
+				assert.Equal(t, tc.Counts[address], CountPlannedInstances(planned, address),
+					"Planned instances of %s with %s", address, describeToggles(tc.Vars))
+			}
+		})
+	}
+}
+
+// describeToggles renders a case's variables in a stable order for failure messages
+func describeToggles(vars map[string]interface{}) string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	toggles := make([]string, 0, len(names))
+	for _, name := range names {
+		toggles = append(toggles, fmt.Sprintf("%s=%v", name, vars[name]))
+	}
+	return strings.Join(toggles, " ")
+}
@@ -0,0 +1,214 @@
+package common
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/e2e"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// XRayActions are what a traced workload's role needs: sending segments and telemetry, and reading the sampling
+// rules and targets that decide which requests are traced
+var XRayActions = []string{
+	"xray:PutTraceSegments",
+	"xray:PutTelemetryRecords",
+	"xray:GetSamplingRules",
+	"xray:GetSamplingTargets",
+}
+
+// MissingXRayActions returns the XRayActions none of a role's policies allow
+func MissingXRayActions(policies []string) []string {
+	return missingPolicyActions(policies, XRayActions)
+}
+
+// TracingSidecarPorts are the images of the tracing sidecars an ECS task may run, with the port each must expose
+// to the application: the X-Ray daemon protocol for the X-Ray daemon, and OTLP over gRPC for the ADOT collector
+var TracingSidecarPorts = map[string]string{
+	"aws-xray-daemon":    "2000/udp",
+	"aws-otel-collector": "4317/tcp",
+}
+
+// tracingEnvironment lists the variables that send an application's traces to a sidecar
+var tracingEnvironment = []string{"AWS_XRAY_DAEMON_ADDRESS", "OTEL_EXPORTER_OTLP_ENDPOINT"}
+
+// CheckTracingSidecar verifies an ECS task definition whose containers send traces runs a tracing sidecar that
+// exposes the port of its protocol. Task definitions without tracing are not checked, which today is all of them.
+func CheckTracingSidecar(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if resource.Type != "aws_ecs_task_definition" {
+		return nil
+	}
+	if audit.IsUnknown(resource, "container_definitions") {
+		return []AuditFinding{unknown("Tracing-Sidecar", resource, "container_definitions")}
+	}
+
+	var containers []struct {
+		Name         string `json:"name"`
+		Image        string `json:"image"`
+		PortMappings []struct {
+			ContainerPort int    `json:"containerPort"`
+			Protocol      string `json:"protocol"`
+		} `json:"portMappings"`
+		Environment []struct {
+			Name string `json:"name"`
+		} `json:"environment"`
+	}
+	definitions := GetPlannedStringAttribute(resource, "container_definitions")
+	if err := json.Unmarshal([]byte(definitions), &containers); err != nil {
+		return []AuditFinding{passFail("Tracing-Sidecar", resource, false, "container definitions are not valid JSON")}
+	}
+
+	traced, sidecar, problem := "", "", ""
+	for _, container := range containers {
+		for _, variable := range container.Environment {
+			if containsValue(tracingEnvironment, variable.Name) {
+				traced = container.Name
+			}
+		}
+		repository, _, _ := strings.Cut(path.Base(container.Image), ":")
+		port, isSidecar := TracingSidecarPorts[repository]
+		if !isSidecar {
+			continue
+		}
+		sidecar = container.Name
+		exposed := false
+		for _, mapping := range container.PortMappings {
+			protocol := mapping.Protocol
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			exposed = exposed || fmt.Sprintf("%d/%s", mapping.ContainerPort, protocol) == port
+		}
+		if !exposed {
+			problem = fmt.Sprintf("sidecar %s does not expose %s", container.Name, port)
+		}
+	}
+
+	switch {
+	case traced == "" && sidecar == "":
+		return nil
+	case sidecar == "":
+		problem = fmt.Sprintf("container %s sends traces but the task runs no X-Ray or ADOT sidecar", traced)
+	}
+	return []AuditFinding{passFail("Tracing-Sidecar", resource, problem == "", problem)}
+}
+
+// SamplingRule is the part of an X-Ray sampling rule that decides whether a service's requests are traced
+type SamplingRule struct {
+	RuleName      string  `json:"RuleName"`
+	ServiceName   string  `json:"ServiceName"`
+	ReservoirSize int     `json:"ReservoirSize"`
+	FixedRate     float64 `json:"FixedRate"`
+}
+
+// SamplingRuleProblem describes why no sampling rule traces any of a service's requests, or returns "" if one
+// does. Rule service names may use X-Ray's * and ? wildcards; the Default rule matches every service.
+func SamplingRuleProblem(rules []SamplingRule, service string) string {
+	for _, rule := range rules {
+		if matched, _ := path.Match(rule.ServiceName, service); matched &&
+			(rule.ReservoirSize > 0 || rule.FixedRate > 0) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("no sampling rule traces requests to %s", service)
+}
+
+// runXRayCommand runs an aws xray command and decodes its JSON output
+func runXRayCommand(t *testing.T, region string, out interface{}, args ...string) error {
+	return awscalls.RunCLI(context.Background(), t, region, out, "xray", args...)
+}
+
+// GetSamplingRules returns the X-Ray sampling rules of the account in a region
+func GetSamplingRules(t *testing.T, region string) []SamplingRule {
+	var result struct {
+		SamplingRuleRecords []struct {
+			SamplingRule SamplingRule `json:"SamplingRule"`
+		} `json:"SamplingRuleRecords"`
+	}
+	require.NoError(t, runXRayCommand(t, region, &result, "get-sampling-rules"))
+
+	rules := make([]SamplingRule, 0, len(result.SamplingRuleRecords))
+	for _, record := range result.SamplingRuleRecords {
+		rules = append(rules, record.SamplingRule)
+	}
+	return rules
+}
+
+// NewTraceID returns a new X-Ray trace ID: the version, the current time in hex seconds and 96 random bits
+func NewTraceID() string {
+	random := make([]byte, 12)
+	_, _ = rand.Read(random)
+	return fmt.Sprintf("1-%08x-%s", time.Now().Unix(), hex.EncodeToString(random))
+}
+
+// XRayTraceTimeout bounds how long a traced request's segments take to become retrievable
+const XRayTraceTimeout = 2 * time.Minute
+
+// TraceRequest sends a GET request marked as sampled under a new trace ID and waits up to XRayTraceTimeout for
+// X-Ray to return the trace, returning the origins of its segments, such as AWS::Lambda::Function
+func TraceRequest(t *testing.T, url, region string) []string {
+	traceID := NewTraceID()
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, http.NoBody)
+	require.NoError(t, err)
+	request.Header.Set("X-Amzn-Trace-Id", fmt.Sprintf("Root=%s;Sampled=1", traceID))
+
+	response, err := e2e.NewNonRedirectingClient().Do(request)
+	require.NoError(t, err, "Request to %s failed", url)
+	response.Body.Close()
+	t.Logf("%s answered %d under trace %s", url, response.StatusCode, traceID)
+
+	var origins []string
+	_, err = retry.DoWithRetryE(t, "Wait for X-Ray trace "+traceID, int(XRayTraceTimeout/(10*time.Second)),
+		10*time.Second, func() (string, error) {
+			var result struct {
+				Traces []struct {
+					Segments []struct {
+						Document string `json:"Document"`
+					} `json:"Segments"`
+				} `json:"Traces"`
+			}
+			if err := runXRayCommand(t, region, &result, "batch-get-traces", "--trace-ids", traceID); err != nil {
+				return "", err
+			}
+			origins = nil
+			for _, trace := range result.Traces {
+				for _, segment := range trace.Segments {
+					var document struct {
+						Origin string `json:"origin"`
+					}
+					_ = json.Unmarshal([]byte(segment.Document), &document)
+					origins = append(origins, document.Origin)
+				}
+			}
+			if len(origins) == 0 {
+				return "", fmt.Errorf("trace %s has no segments yet", traceID)
+			}
+			return "", nil
+		})
+	require.NoError(t, err, "X-Ray should record trace %s of %s", traceID, url)
+	return origins
+}
+
+// XRayTracingExpected reports whether E2E_XRAY_TRACING says the deployed stage is traced, skipping the test when
+// it is not. Zappa only enables tracing on the prod stage.
+func XRayTracingExpected(t *testing.T) {
+	RequireTier(t, TierE2E)
+
+	if enabled, _ := strconv.ParseBool(os.Getenv("E2E_XRAY_TRACING")); !enabled {
+		t.Skip("Skipping end-to-end test - set E2E_XRAY_TRACING=true when the deployed stage has xray_tracing on")
+	}
+}
@@ -0,0 +1,310 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"terraform-tests/awsval"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// AppliedConfiguration is what validators check: an applied configuration's options, with its state and outputs
+// read once after the apply, so validators do not each run terraform
+type AppliedConfiguration struct {
+	Options *terraform.Options
+	State   *AuditContext
+	Outputs map[string]interface{}
+	Region  string
+}
+
+// NewAppliedConfiguration reads the state and outputs of an applied configuration
+func NewAppliedConfiguration(t *testing.T, terraformOptions *terraform.Options) *AppliedConfiguration {
+	return &AppliedConfiguration{
+		Options: terraformOptions,
+		State:   NewStateAuditContext(t, terraformOptions),
+		Outputs: terraform.OutputAll(t, terraformOptions),
+		Region:  terraformOptions.EnvVars["AWS_DEFAULT_REGION"],
+	}
+}
+
+// Output returns one of the configuration's outputs as a string, or "" if it is not set
+func (a *AppliedConfiguration) Output(name string) string {
+	value, ok := a.Outputs[name]
+	if !ok || value == nil {
+		return ""
+	}
+	if s, isString := value.(string); isString {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+// Validator checks an applied configuration and returns its findings. ApplyAndValidate runs validators in order
+// and reports their findings together, so a run shows every violation instead of stopping at the first.
+type Validator func(t *testing.T, applied *AppliedConfiguration) []AuditFinding
+
+// Audit runs audit checks, such as PublicExposureChecks, against every resource in the applied state
+func Audit(checks ...AuditCheck) Validator {
+	return func(_ *testing.T, applied *AppliedConfiguration) []AuditFinding {
+		return RunAudit(applied.State, checks...)
+	}
+}
+
+// Encryption checks that resources are encrypted at rest with at least the key type EncryptionExpectations sets
+func Encryption() Validator {
+	return Audit(CheckEncryptionAtRest)
+}
+
+// TagPolicy checks that every resource that takes tags carries the required tags, including those the provider's
+// default_tags add. An empty required value accepts any value.
+func TagPolicy(required map[string]string) Validator {
+	keys := make([]string, 0, len(required))
+	for key := range required {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return Audit(func(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+		tags, taggable := resource.AttributeValues["tags_all"].(map[string]interface{})
+		if !taggable {
+			return nil
+		}
+
+		var problems []string
+		for _, key := range keys {
+			value, present := tags[key]
+			switch {
+			case !present:
+				problems = append(problems, "missing tag "+key)
+			case required[key] != "" && fmt.Sprint(value) != required[key]:
+				problems = append(problems, fmt.Sprintf("tag %s is %q, expected %q", key, value, required[key]))
+			}
+		}
+		return []AuditFinding{passFail("Tag-Policy", resource, len(problems) == 0, strings.Join(problems, "; "))}
+	})
+}
+
+// Naming checks that resources of each type in suffixes are named with the prefix and end with the type's suffix,
+// as ValidateResourceNaming does for a single name. An empty suffix accepts any ending. NamingConventions holds
+// every resource to the full convention for its type.
+func Naming(prefix string, suffixes map[string]string) Validator {
+	return Audit(func(resource *tfjson.StateResource, _ *AuditContext) []AuditFinding {
+		suffix, checked := suffixes[resource.Type]
+		if !checked {
+			return nil
+		}
+
+		name := resourceName(resource)
+		return []AuditFinding{passFail("Naming", resource,
+			strings.HasPrefix(name, prefix) && strings.HasSuffix(name, suffix),
+			fmt.Sprintf("named %q, expected %s...%s", name, prefix, suffix))}
+	})
+}
+
+// resourceName returns the name AWS knows a resource by: the attribute NameConstraints limits, its name or
+// identifier attribute or, for resources without one such as VPCs and subnets, its Name tag
+func resourceName(resource *tfjson.StateResource) string {
+	for _, attribute := range []string{nameAttribute(resource.Type), "name", "identifier"} {
+		if name := GetPlannedStringAttribute(resource, attribute); name != "" {
+			return name
+		}
+	}
+	return nameTag(resource)
+}
+
+// nameAttribute returns the attribute holding a resource type's name
+func nameAttribute(resourceType string) string {
+	if constraint, constrained := NameConstraints[resourceType]; constrained {
+		return constraint.Attribute
+	}
+	return "name"
+}
+
+// nameTag returns a resource's Name tag, or "" if it has none
+func nameTag(resource *tfjson.StateResource) string {
+	tags, _ := resource.AttributeValues["tags"].(map[string]interface{})
+	name, _ := tags["Name"].(string)
+	return name
+}
+
+// SGRule is an ingress rule a security group must have, with the IPv4 and IPv6 CIDR blocks it must and must not
+// admit
+type SGRule struct {
+	Port       int32
+	Protocol   string // "tcp" when empty
+	Allowed    []string
+	Disallowed []string // Such as 0.0.0.0/0 and ::/0
+}
+
+// SGRules checks the ingress rules of the security group whose ID is the named output
+func SGRules(outputName string, rules ...SGRule) Validator {
+	return func(t *testing.T, applied *AppliedConfiguration) []AuditFinding {
+		resource := "output." + outputName
+		groupID := applied.Output(outputName)
+		if groupID == "" {
+			return []AuditFinding{{Control: "SG-Rules", Resource: resource, Detail: "output is not set"}}
+		}
+
+		group := awsval.GetSecurityGroup(t, groupID, applied.Region)
+		findings := make([]AuditFinding, 0, len(rules))
+		for _, rule := range rules {
+			finding := checkSGRule(group, rule)
+			finding.Resource = resource
+			findings = append(findings, finding)
+		}
+		return findings
+	}
+}
+
+// checkSGRule finds the group's ingress permission for the rule's protocol and port and checks its CIDR blocks
+func checkSGRule(group *types.SecurityGroup, rule SGRule) AuditFinding {
+	protocol := rule.Protocol
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	finding := AuditFinding{Control: "SG-Rules", ARN: aws.ToString(group.SecurityGroupArn)}
+
+	for _, permission := range group.IpPermissions {
+		if aws.ToString(permission.IpProtocol) != protocol || aws.ToInt32(permission.FromPort) != rule.Port ||
+			aws.ToInt32(permission.ToPort) != rule.Port {
+			continue
+		}
+
+		cidrs := map[string]bool{}
+		for _, ipRange := range permission.IpRanges {
+			cidrs[aws.ToString(ipRange.CidrIp)] = true
+		}
+		for _, ipRange := range permission.Ipv6Ranges {
+			cidrs[aws.ToString(ipRange.CidrIpv6)] = true
+		}
+		var problems []string
+		for _, cidr := range rule.Allowed {
+			if !cidrs[cidr] {
+				problems = append(problems, "does not admit "+cidr)
+			}
+		}
+		for _, cidr := range rule.Disallowed {
+			if cidrs[cidr] {
+				problems = append(problems, "admits "+cidr)
+			}
+		}
+		finding.Passed = len(problems) == 0
+		finding.Detail = fmt.Sprintf("%s/%d ingress: %s", protocol, rule.Port, strings.Join(problems, "; "))
+		return finding
+	}
+
+	finding.Detail = fmt.Sprintf("%s has no %s/%d ingress rule", aws.ToString(group.GroupName), protocol, rule.Port)
+	return finding
+}
+
+// WebACLRules checks the configuration of the WAF web ACL whose ARN is the named output
+func WebACLRules(outputName string, expected awsval.WebACLExpectation) Validator {
+	return func(t *testing.T, applied *AppliedConfiguration) []AuditFinding {
+		resource := "output." + outputName
+		arn := applied.Output(outputName)
+		if arn == "" {
+			return []AuditFinding{{Control: "WAF", Resource: resource, Detail: "output is not set"}}
+		}
+
+		acl, err := awsval.GetWebACLE(t, arn, applied.Region)
+		if err != nil {
+			return []AuditFinding{{Control: "WAF", Resource: resource, ARN: arn, Detail: err.Error()}}
+		}
+		return awsval.CheckWebACL(acl, expected)
+	}
+}
+
+// WebACLAssociated checks the web ACL whose ARN is the named output is the one associated with the resource whose
+// ARN is resourceOutput, such as an application load balancer
+func WebACLAssociated(webACLOutput, resourceOutput string) Validator {
+	return func(t *testing.T, applied *AppliedConfiguration) []AuditFinding {
+		resource := "output." + resourceOutput
+		webACLARN, resourceARN := applied.Output(webACLOutput), applied.Output(resourceOutput)
+		if webACLARN == "" || resourceARN == "" {
+			return []AuditFinding{{Control: "WAF-Association", Resource: resource,
+				Detail: fmt.Sprintf("outputs %s and %s must both be set", webACLOutput, resourceOutput)}}
+		}
+
+		associated, err := awsval.GetWebACLForResourceE(t, resourceARN, applied.Region)
+		if err != nil {
+			return []AuditFinding{{Control: "WAF-Association", Resource: resource, ARN: webACLARN, Detail: err.Error()}}
+		}
+		return []AuditFinding{awsval.CheckWebACLAssociation(resource, associated, webACLARN)}
+	}
+}
+
+// EmailAuthRecords checks the hosted zone publishes SPF, DKIM and DMARC records for the domain SES sends from, the
+// named output, with the DKIM tokens of dkimTokensOutput. A configuration whose domain output is empty sends no
+// email, so there is nothing to check.
+func EmailAuthRecords(zoneID, domainOutput, dkimTokensOutput string) Validator {
+	return func(t *testing.T, applied *AppliedConfiguration) []AuditFinding {
+		domain := applied.Output(domainOutput)
+		if domain == "" {
+			return nil
+		}
+
+		var dkimTokens []string
+		tokens, _ := applied.Outputs[dkimTokensOutput].([]interface{})
+		for _, token := range tokens {
+			dkimTokens = append(dkimTokens, fmt.Sprint(token))
+		}
+
+		records, err := awsval.GetZoneRecordsE(t, zoneID, applied.Region)
+		if err != nil {
+			return []AuditFinding{{Control: "Email-Records", Resource: zoneID, Detail: err.Error()}}
+		}
+		return awsval.CheckEmailAuthRecords(domain, records, dkimTokens)
+	}
+}
+
+// Outputs checks that each named output is set and not empty
+func Outputs(names ...string) Validator {
+	return func(_ *testing.T, applied *AppliedConfiguration) []AuditFinding {
+		findings := make([]AuditFinding, 0, len(names))
+		for _, name := range names {
+			findings = append(findings, AuditFinding{
+				Control:  "Outputs",
+				Resource: "output." + name,
+				Passed:   outputSet(applied.Outputs[name]),
+				Detail:   "output is not set or is empty",
+			})
+		}
+		return findings
+	}
+}
+
+// OutputMatches checks that the named output matches a regular expression, such as the ARN format of its service
+func OutputMatches(name, pattern string) Validator {
+	expression := regexp.MustCompile(pattern)
+	return func(_ *testing.T, applied *AppliedConfiguration) []AuditFinding {
+		value := applied.Output(name)
+		return []AuditFinding{{
+			Control:  "Outputs",
+			Resource: "output." + name,
+			Passed:   expression.MatchString(value),
+			Detail:   fmt.Sprintf("%q does not match %s", value, pattern),
+		}}
+	}
+}
+
+// outputSet reports whether an output value is present and not empty
+func outputSet(value interface{}) bool {
+	switch typed := value.(type) {
+	case nil:
+		return false
+	case string:
+		return typed != ""
+	case []interface{}:
+		return len(typed) > 0
+	case map[string]interface{}:
+		return len(typed) > 0
+	}
+	return true
+}
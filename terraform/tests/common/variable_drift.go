@@ -0,0 +1,137 @@
+package common
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"terraform-tests/tfopts"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ModuleVariable is an input variable as declared in a module's variables.tf
+type ModuleVariable struct {
+	Name           string
+	HasDescription bool
+	HasType        bool
+	HasDefault     bool
+}
+
+// GetModuleVariables parses the variable blocks of a module's variables.tf, in declaration order
+func GetModuleVariables(t *testing.T, modulePath string) []ModuleVariable {
+	body := parseTerraformFile(t, filepath.Join(modulePath, "variables.tf"))
+
+	var variables []ModuleVariable
+	for _, block := range body.Blocks {
+		if block.Type != "variable" || len(block.Labels) == 0 {
+			continue
+		}
+		_, hasDescription := block.Body.Attributes["description"]
+		_, hasType := block.Body.Attributes["type"]
+		_, hasDefault := block.Body.Attributes["default"]
+		variables = append(variables, ModuleVariable{
+			Name:           block.Labels[0],
+			HasDescription: hasDescription,
+			HasType:        hasType,
+			HasDefault:     hasDefault,
+		})
+	}
+	return variables
+}
+
+// GetReferencedVariables returns the names of all variables referenced as var.<name> in a module's .tf files.
+// A variable's own validation blocks do not count as a reference to it.
+func GetReferencedVariables(t *testing.T, modulePath string) map[string]bool {
+	files, err := filepath.Glob(filepath.Join(modulePath, "*.tf"))
+	require.NoError(t, err)
+
+	referenced := make(map[string]bool)
+	for _, file := range files {
+		body := parseTerraformFile(t, file)
+		for _, block := range body.Blocks {
+			self := ""
+			if block.Type == "variable" && len(block.Labels) > 0 {
+				self = block.Labels[0]
+			}
+			for _, name := range variableReferences(block) {
+				if name != self {
+					referenced[name] = true
+				}
+			}
+		}
+	}
+	return referenced
+}
+
+// variableReferences collects the names of the variables referenced anywhere under a node
+func variableReferences(node hclsyntax.Node) []string {
+	var names []string
+	hclsyntax.VisitAll(node, func(node hclsyntax.Node) hcl.Diagnostics {
+		expr, ok := node.(*hclsyntax.ScopeTraversalExpr)
+		if !ok || expr.Traversal.RootName() != "var" || len(expr.Traversal) < 2 {
+			return nil
+		}
+		if attribute, ok := expr.Traversal[1].(hcl.TraverseAttr); ok {
+			names = append(names, attribute.Name)
+		}
+		return nil
+	})
+	return names
+}
+
+// parseTerraformFile parses a .tf file and fails the test if it is not valid HCL
+func parseTerraformFile(t *testing.T, path string) *hclsyntax.Body {
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	require.False(t, diags.HasErrors(), "Failed to parse %s: %s", path, diags.Error())
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	require.True(t, ok, "Unexpected body type in %s", path)
+	return body
+}
+
+// AssertVariablesDocumented fails the test for every variable in a module that has no description or type
+func AssertVariablesDocumented(t *testing.T, modulePath string) {
+	for _, variable := range GetModuleVariables(t, modulePath) {
+		assert.True(t, variable.HasDescription, "Variable %s in %s has no description", variable.Name, modulePath)
+		assert.True(t, variable.HasType, "Variable %s in %s has no type", variable.Name, modulePath)
+	}
+}
+
+// AssertNoUnusedVariables fails the test for every variable a module declares but never references
+func AssertNoUnusedVariables(t *testing.T, modulePath string) {
+	referenced := GetReferencedVariables(t, modulePath)
+	for _, variable := range GetModuleVariables(t, modulePath) {
+		assert.True(t, referenced[variable.Name],
+			"Variable %s in %s is declared but never used; remove it or wire it up", variable.Name, modulePath)
+	}
+}
+
+// AssertFixtureVariablesDeclared fails the test for every variable a Go fixture passes that the module does not
+// declare, which Terraform would reject as an undeclared variable once the test actually runs
+func AssertFixtureVariablesDeclared(t *testing.T, modulePath, fixture string, vars map[string]interface{}) {
+	declared := make(map[string]bool)
+	for _, variable := range GetModuleVariables(t, modulePath) {
+		declared[variable.Name] = true
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		assert.True(t, declared[name], "%s sets %s, which %s does not declare", fixture, name, modulePath)
+	}
+}
+
+// moduleDeclaresVariable reports whether a module's variables.tf declares the named variable
+func moduleDeclaresVariable(modulePath, name string) bool {
+	_, declared := tfopts.DeclaredVariables(modulePath)[name]
+	return declared
+}
@@ -0,0 +1,142 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// VariableSurface is the part of an input variable deployers depend on: its name, its type and its default. A
+// variable without a default is required.
+type VariableSurface struct {
+	Name     string          `json:"name"`
+	Type     string          `json:"type"`
+	Required bool            `json:"required,omitempty"`
+	Default  json.RawMessage `json:"default,omitempty"`
+}
+
+// GetVariableSurface returns the variables of a module's variables.tf by name. Types are written the way Terraform
+// prints them and defaults as JSON, so reformatting the file does not change the surface.
+func GetVariableSurface(t *testing.T, modulePath string) []VariableSurface {
+	body := parseTerraformFile(t, filepath.Join(modulePath, "variables.tf"))
+
+	var surface []VariableSurface
+	for _, block := range body.Blocks {
+		if block.Type != "variable" || len(block.Labels) == 0 {
+			continue
+		}
+		variable := VariableSurface{Name: block.Labels[0], Type: "any", Required: true}
+
+		if attribute, ok := block.Body.Attributes["type"]; ok {
+			constraint, _, diags := typeexpr.TypeConstraintWithDefaults(attribute.Expr)
+			require.False(t, diags.HasErrors(), "Variable %s has an invalid type: %s", variable.Name, diags.Error())
+			variable.Type = typeexpr.TypeString(constraint)
+		}
+		if attribute, ok := block.Body.Attributes["default"]; ok {
+			value, diags := attribute.Expr.Value(nil)
+			require.False(t, diags.HasErrors(), "Variable %s has a non-literal default: %s", variable.Name,
+				diags.Error())
+			encoded, err := json.Marshal(ctyjson.SimpleJSONValue{Value: value})
+			require.NoError(t, err)
+			variable.Required, variable.Default = false, encoded
+		}
+		surface = append(surface, variable)
+	}
+	sort.Slice(surface, func(i, j int) bool { return surface[i].Name < surface[j].Name })
+	return surface
+}
+
+// VariableSurfaceChanges describes how a variable surface differs from a snapshot, one line per variable. Removed
+// variables, new required variables and changed types break existing deployments and are marked as such.
+func VariableSurfaceChanges(snapshot, current []VariableSurface) []string {
+	before := map[string]VariableSurface{}
+	for _, variable := range snapshot {
+		before[variable.Name] = variable
+	}
+	after := map[string]VariableSurface{}
+	for _, variable := range current {
+		after[variable.Name] = variable
+	}
+
+	var changes []string
+	for _, old := range snapshot {
+		if _, kept := after[old.Name]; !kept {
+			changes = append(changes, fmt.Sprintf("BREAKING: %s was removed", old.Name))
+		}
+	}
+	for _, variable := range current {
+		old, existed := before[variable.Name]
+		switch {
+		case !existed && variable.Required:
+			changes = append(changes, fmt.Sprintf("BREAKING: %s was added without a default", variable.Name))
+		case !existed:
+			changes = append(changes, fmt.Sprintf("%s was added with default %s", variable.Name,
+				defaultOrNone(variable)))
+		case old.Type != variable.Type:
+			changes = append(changes, fmt.Sprintf("BREAKING: %s changed type from %s to %s", variable.Name, old.Type,
+				variable.Type))
+		case variable.Required && !old.Required:
+			changes = append(changes, fmt.Sprintf("BREAKING: %s lost its default %s", variable.Name,
+				defaultOrNone(old)))
+		case defaultOrNone(old) != defaultOrNone(variable):
+			changes = append(changes, fmt.Sprintf("%s changed default from %s to %s", variable.Name,
+				defaultOrNone(old), defaultOrNone(variable)))
+		}
+	}
+	return changes
+}
+
+// defaultOrNone returns a variable's default as compact JSON, which is how defaults are compared whatever the
+// snapshot's indentation, or "none" for a required variable
+func defaultOrNone(variable VariableSurface) string {
+	if variable.Required {
+		return "none"
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, variable.Default); err != nil {
+		return string(variable.Default)
+	}
+	return compact.String()
+}
+
+// SnapshotsUpdateEnabled reports whether UPDATE_SNAPSHOTS asks for snapshots to be rewritten instead of compared
+func SnapshotsUpdateEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("UPDATE_SNAPSHOTS"))
+	return enabled
+}
+
+// AssertVariableSurfaceSnapshot fails the test when a module's variable surface differs from the snapshot file,
+// listing each change. With UPDATE_SNAPSHOTS=true, it rewrites the snapshot instead, for committing alongside the
+// change that made it.
+func AssertVariableSurfaceSnapshot(t *testing.T, modulePath, snapshotPath string) {
+	current := GetVariableSurface(t, modulePath)
+
+	if SnapshotsUpdateEnabled() {
+		encoded, err := json.MarshalIndent(current, "", "  ")
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Dir(snapshotPath), 0o755))
+		require.NoError(t, os.WriteFile(snapshotPath, append(encoded, '\n'), 0o644))
+		t.Logf("Updated %s with %d variables", snapshotPath, len(current))
+		return
+	}
+
+	content, err := os.ReadFile(snapshotPath)
+	require.NoError(t, err, "Create the snapshot by running the test with UPDATE_SNAPSHOTS=true")
+	var snapshot []VariableSurface
+	require.NoError(t, json.Unmarshal(content, &snapshot), "%s is not a variable snapshot", snapshotPath)
+
+	for _, change := range VariableSurfaceChanges(snapshot, current) {
+		assert.Fail(t, "Variable surface changed", "%s\nReview the change for deployers, then rerun with "+
+			"UPDATE_SNAPSHOTS=true to update %s", change, snapshotPath)
+	}
+}
@@ -0,0 +1,40 @@
+package common
+
+import (
+	"fmt"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// WAFAuditChecks returns the check that each web ACL is associated with a resource it protects
+func WAFAuditChecks() []AuditCheck {
+	return []AuditCheck{CheckWebACLAssociated}
+}
+
+// CheckWebACLAssociated verifies a regional web ACL is named by an aws_wafv2_web_acl_association, and a CloudFront
+// one by a distribution's web_acl_id. A web ACL filters nothing until it is associated, and the association is a
+// resource of its own that is easily left out. In a plan, where a new web ACL's ARN is unknown, an association
+// whose target is also unknown is taken to be its.
+func CheckWebACLAssociated(resource *tfjson.StateResource, audit *AuditContext) []AuditFinding {
+	if resource.Type != "aws_wafv2_web_acl" {
+		return nil
+	}
+	associationType, attribute := "aws_wafv2_web_acl_association", "web_acl_arn"
+	if scope, _ := resource.AttributeValues["scope"].(string); scope == "CLOUDFRONT" {
+		associationType, attribute = "aws_cloudfront_distribution", "web_acl_id"
+	}
+
+	arn, _ := resource.AttributeValues["arn"].(string)
+	arnUnknown := audit.IsUnknown(resource, "arn")
+	for _, association := range audit.Resources {
+		if association.Type != associationType {
+			continue
+		}
+		target, _ := association.AttributeValues[attribute].(string)
+		if (arn != "" && target == arn) || (arnUnknown && audit.IsUnknown(association, attribute)) {
+			return []AuditFinding{passFail("WAF-Association", resource, true, "associated by "+association.Address)}
+		}
+	}
+	return []AuditFinding{passFail("WAF-Association", resource, false,
+		fmt.Sprintf("no %s names the web ACL, so it filters no traffic", associationType))}
+}
@@ -0,0 +1,82 @@
+// Package e2e reaches a deployed stack the way its users do, over HTTP, SSH and raw TCP, for end-to-end tests that
+// create no infrastructure of their own.
+package e2e
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/smoketest"
+
+	"github.com/gruntwork-io/terratest/modules/ssh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NewNonRedirectingClient returns an HTTP client that reports redirects instead of following them,
+// so a response can be attributed to the backend that produced it
+func NewNonRedirectingClient() *http.Client {
+	return smoketest.NewClient(15 * time.Second)
+}
+
+// GetStatus sends a GET request and returns the response status without following redirects
+func GetStatus(t *testing.T, client *http.Client, url string) int {
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, http.NoBody)
+	require.NoError(t, err)
+
+	response, err := client.Do(request)
+	require.NoError(t, err, "Request to %s failed", url)
+	response.Body.Close()
+	return response.StatusCode
+}
+
+// RunOverSSH runs a shell command on a host over SSH with a private key and returns its combined output
+func RunOverSSH(t *testing.T, hostname, user, privateKey, command string) (string, error) {
+	host := ssh.Host{
+		Hostname:    hostname,
+		SshUserName: user,
+		SshKeyPair:  &ssh.KeyPair{PrivateKey: privateKey},
+	}
+	return ssh.CheckSshCommandE(t, host, command)
+}
+
+// GetRunnerPublicIP returns the public IPv4 address the test runner connects from, overridable with E2E_RUNNER_IP
+func GetRunnerPublicIP(t *testing.T) net.IP {
+	address := os.Getenv("E2E_RUNNER_IP")
+	if address == "" {
+		client := &http.Client{Timeout: 10 * time.Second}
+		response, err := client.Get("https://checkip.amazonaws.com")
+		require.NoError(t, err, "Failed to look up the runner's public IP")
+		defer response.Body.Close()
+
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		address = strings.TrimSpace(string(body))
+	}
+
+	ip := net.ParseIP(address).To4()
+	require.NotNil(t, ip, "Runner IP %q is not an IPv4 address", address)
+	return ip
+}
+
+// AssertTCPConnectTimesOut fails the test unless connecting to address times out, which is how a security
+// group drops traffic it does not allow. A refused connection means the packet reached the host.
+func AssertTCPConnectTimesOut(t *testing.T, address string, timeout time.Duration) {
+	connection, err := net.DialTimeout("tcp", address, timeout)
+	if err == nil {
+		connection.Close()
+		assert.Fail(t, "Connection should have been dropped", "Connected to %s", address)
+		return
+	}
+
+	var netErr net.Error
+	assert.True(t, errors.As(err, &netErr) && netErr.Timeout(),
+		"Connection to %s should time out, got: %v", address, err)
+}
@@ -0,0 +1,100 @@
+// Package fixtures stands up the minimal prerequisite infrastructure that module apply tests deploy into: a tiny
+// VPC with two subnets, a set of security groups, a dummy secret and a target group. Module tests pass the IDs of
+// these real resources instead of fake ones such as vpc-12345678, which plans accept but applies cannot use.
+//
+// Each fixture is a small terraform configuration under fixtures/modules, applied through common.ApplyFixture, so a
+// fixture is applied once per package run and shared by every test asking for the same one. Fixtures compose by
+// taking the fixtures they are deployed into:
+//
+//	vpc := fixtures.NewVPC(t)
+//	securityGroups := fixtures.NewSecurityGroups(t, vpc)
+package fixtures
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"terraform-tests/common"
+)
+
+// modulePath returns the terraform configuration of a fixture, wherever the test runs from
+func modulePath(name string) string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "modules", name)
+}
+
+// VPC is a VPC with two private subnets in different availability zones and no gateways
+type VPC struct {
+	ID        string
+	CIDRBlock string
+	SubnetIDs []string
+}
+
+// NewVPC returns the shared fixture VPC, applying it if no test has yet
+func NewVPC(t *testing.T) *VPC {
+	fixture := common.ApplyFixture(t, modulePath("vpc"), map[string]interface{}{})
+	return &VPC{
+		ID:        fixture.Output("vpc_id"),
+		CIDRBlock: fixture.Output("vpc_cidr_block"),
+		SubnetIDs: fixture.OutputList("subnet_ids"),
+	}
+}
+
+// SecurityGroups are empty security groups standing in for those other modules create, such as the Lambda
+// security group the security module admits to the database
+type SecurityGroups struct {
+	VPCID     string
+	LambdaID  string
+	DBID      string
+	BastionID string
+	AppID     string
+}
+
+// NewSecurityGroups returns the shared security groups in a fixture VPC
+func NewSecurityGroups(t *testing.T, vpc *VPC) *SecurityGroups {
+	fixture := common.ApplyFixture(t, modulePath("sg-set"), map[string]interface{}{"vpc_id": vpc.ID})
+	return &SecurityGroups{
+		VPCID:     vpc.ID,
+		LambdaID:  fixture.Output("lambda_security_group_id"),
+		DBID:      fixture.Output("db_security_group_id"),
+		BastionID: fixture.Output("bastion_security_group_id"),
+		AppID:     fixture.Output("app_security_group_id"),
+	}
+}
+
+// Secret is a Secrets Manager secret holding a dummy value
+type Secret struct {
+	ARN  string
+	Name string
+}
+
+// NewSecret returns the shared secret with the given name, so a test that needs several secrets asks for each by
+// name
+func NewSecret(t *testing.T, name string) *Secret {
+	fixture := common.ApplyFixture(t, modulePath("secret"), map[string]interface{}{"name": name})
+	return &Secret{
+		ARN:  fixture.Output("secret_arn"),
+		Name: fixture.Output("secret_name"),
+	}
+}
+
+// TargetGroup is an HTTP target group for IP targets, with no targets registered
+type TargetGroup struct {
+	ARN  string
+	Name string
+	Port int
+}
+
+// NewTargetGroup returns the shared target group for targets listening on the port in a fixture VPC
+func NewTargetGroup(t *testing.T, vpc *VPC, port int) *TargetGroup {
+	fixture := common.ApplyFixture(t, modulePath("target-group"), map[string]interface{}{
+		"vpc_id": vpc.ID,
+		"port":   port,
+	})
+	return &TargetGroup{
+		ARN:  fixture.Output("target_group_arn"),
+		Name: fixture.Output("target_group_name"),
+		Port: port,
+	}
+}
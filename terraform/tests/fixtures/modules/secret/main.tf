@@ -0,0 +1,32 @@
+# A Secrets Manager secret with a dummy value, for modules that grant access to or read a secret by ARN
+
+variable "prefix" {
+  description = "Prefix to use for resource names"
+  type        = string
+}
+
+variable "name" {
+  description = "Name of the secret within the prefix, so one test can use several"
+  type        = string
+  default     = "secret"
+}
+
+resource "aws_secretsmanager_secret" "fixture" {
+  name = "${var.prefix}/${var.name}"
+
+  # Deleted at once on destroy, so the name can be reused and nothing lingers in the account
+  recovery_window_in_days = 0
+}
+
+resource "aws_secretsmanager_secret_version" "fixture" {
+  secret_id     = aws_secretsmanager_secret.fixture.id
+  secret_string = jsonencode({ value = "fixture-${var.name}" })
+}
+
+output "secret_arn" {
+  value = aws_secretsmanager_secret.fixture.arn
+}
+
+output "secret_name" {
+  value = aws_secretsmanager_secret.fixture.name
+}
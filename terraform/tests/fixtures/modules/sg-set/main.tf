@@ -0,0 +1,44 @@
+# Empty security groups standing in for the ones other modules create, for modules that reference them in their
+# own rules
+
+variable "prefix" {
+  description = "Prefix to use for resource names"
+  type        = string
+}
+
+variable "vpc_id" {
+  description = "ID of the VPC the security groups belong to"
+  type        = string
+}
+
+locals {
+  roles = ["lambda", "db", "bastion", "app"]
+}
+
+resource "aws_security_group" "fixture" {
+  for_each = toset(local.roles)
+
+  name        = "${var.prefix}-${each.key}-sg"
+  description = "Fixture ${each.key} security group"
+  vpc_id      = var.vpc_id
+
+  tags = {
+    Name = "${var.prefix}-${each.key}-sg"
+  }
+}
+
+output "lambda_security_group_id" {
+  value = aws_security_group.fixture["lambda"].id
+}
+
+output "db_security_group_id" {
+  value = aws_security_group.fixture["db"].id
+}
+
+output "bastion_security_group_id" {
+  value = aws_security_group.fixture["bastion"].id
+}
+
+output "app_security_group_id" {
+  value = aws_security_group.fixture["app"].id
+}
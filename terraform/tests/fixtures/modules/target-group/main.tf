@@ -0,0 +1,41 @@
+# An IP target group with no targets, for modules that attach services or listener rules to one
+
+variable "prefix" {
+  description = "Prefix to use for resource names"
+  type        = string
+}
+
+variable "vpc_id" {
+  description = "ID of the VPC the targets run in"
+  type        = string
+}
+
+variable "port" {
+  description = "Port the targets listen on"
+  type        = number
+  default     = 8000
+}
+
+resource "aws_lb_target_group" "fixture" {
+  # Names are limited to 32 characters, which the prefix alone can use up, so terraform generates one
+  port        = var.port
+  protocol    = "HTTP"
+  target_type = "ip"
+  vpc_id      = var.vpc_id
+
+  health_check {
+    path = "/"
+  }
+
+  tags = {
+    Name = "${var.prefix}-tg"
+  }
+}
+
+output "target_group_arn" {
+  value = aws_lb_target_group.fixture.arn
+}
+
+output "target_group_name" {
+  value = aws_lb_target_group.fixture.name
+}
@@ -0,0 +1,51 @@
+# Smallest VPC the module apply tests can deploy into: two private subnets in different availability zones, as
+# RDS subnet groups and load balancers require, and no gateways to pay for or wait on
+
+variable "prefix" {
+  description = "Prefix to use for resource names"
+  type        = string
+}
+
+variable "cidr_block" {
+  description = "CIDR block of the VPC, split in half between the subnets"
+  type        = string
+  default     = "10.200.0.0/24"
+}
+
+data "aws_availability_zones" "available" {
+  state = "available"
+}
+
+resource "aws_vpc" "fixture" {
+  cidr_block           = var.cidr_block
+  enable_dns_support   = true
+  enable_dns_hostnames = true
+
+  tags = {
+    Name = "${var.prefix}-vpc"
+  }
+}
+
+resource "aws_subnet" "private" {
+  count = 2
+
+  vpc_id            = aws_vpc.fixture.id
+  cidr_block        = cidrsubnet(var.cidr_block, 1, count.index)
+  availability_zone = data.aws_availability_zones.available.names[count.index]
+
+  tags = {
+    Name = "${var.prefix}-private-${count.index + 1}"
+  }
+}
+
+output "vpc_id" {
+  value = aws_vpc.fixture.id
+}
+
+output "vpc_cidr_block" {
+  value = aws_vpc.fixture.cidr_block
+}
+
+output "subnet_ids" {
+  value = aws_subnet.private[*].id
+}
@@ -0,0 +1,144 @@
+// Package flaky decides which failed tests are worth re-running: those whose output matches a known transient
+// infrastructure failure, such as throttling, and that are not quarantined. It reads the events go test -json
+// writes, keeps the quarantine list and the history of retries, and reports what was retried.
+package flaky
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Signature is a failure pattern that is known to pass when the test is run again
+type Signature struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// TransientSignatures are the failures worth a retry, in the order they are checked
+var TransientSignatures = []Signature{
+	{
+		Name: "throttling",
+		Pattern: regexp.MustCompile(`Throttling|RequestLimitExceeded|TooManyRequestsException|Rate exceeded|` +
+			`SlowDown|PriorRequestNotComplete`),
+	},
+	{
+		// IDs and IAM roles can take a few seconds to be visible to every API after they are created
+		Name: "eventual consistency",
+		Pattern: regexp.MustCompile(`Invalid(InstanceID|Group|SubnetID|RouteTableID|VpcID|NetworkInterfaceID)\.NotFound|` +
+			`role defined for the function cannot be assumed|execution role does not have permissions|` +
+			`InvalidParameterValue.*(IAM role|instance profile)`),
+	},
+	{
+		Name: "cloudfront propagation",
+		Pattern: regexp.MustCompile(`(?i)waiting for cloudfront distribution \(\S+\) (to be )?deploy|` +
+			`DistributionNotDisabled|cloudfront.*(PreconditionFailed|timeout while waiting)`),
+	},
+}
+
+// MatchTransient returns the first transient signature the output matches
+func MatchTransient(output string) (Signature, bool) {
+	for _, signature := range TransientSignatures {
+		if signature.Pattern.MatchString(output) {
+			return signature, true
+		}
+	}
+	return Signature{}, false
+}
+
+// Event is one line of go test -json output
+type Event struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Output  string
+	Elapsed float64
+}
+
+// Run is the result of one top-level test in one go test run. Subtests are folded into their top-level test,
+// since a subtest cannot be re-run without the setup its parent does.
+type Run struct {
+	Package string
+	Test    string // Empty for a failure outside any test, such as a build error or a panic in TestMain
+	Action  string // pass, fail or skip
+	Elapsed float64
+	Output  string
+}
+
+// Key identifies the test across runs
+func (r Run) Key() string {
+	return r.Package + "." + r.Test
+}
+
+// ReadRuns reads go test -json output, copying the test output to w as go test -v would print it, and returns the
+// result of every top-level test and every package that failed outside its tests. Lines that are not JSON, such
+// as build errors on older toolchains, are copied as they are.
+func ReadRuns(r io.Reader, w io.Writer) ([]Run, error) {
+	var (
+		runs    []Run
+		index   = map[string]int{}
+		outputs = map[string]*strings.Builder{}
+	)
+	output := func(key string) *strings.Builder {
+		if outputs[key] == nil {
+			outputs[key] = &strings.Builder{}
+		}
+		return outputs[key]
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || event.Action == "" {
+			_, _ = fmt.Fprintln(w, scanner.Text())
+			continue
+		}
+
+		test, _, _ := strings.Cut(event.Test, "/")
+		key := event.Package + "." + test
+		switch event.Action {
+		case "output", "build-output":
+			_, _ = io.WriteString(w, event.Output)
+			output(key).WriteString(event.Output)
+		case "pass", "fail", "skip":
+			// A subtest's result is part of its parent's, and a package passes or skips through its tests
+			if test != event.Test || (test == "" && event.Action != "fail") {
+				continue
+			}
+			if i, ok := index[key]; ok {
+				runs[i].Action, runs[i].Elapsed = event.Action, event.Elapsed
+				continue
+			}
+			index[key] = len(runs)
+			runs = append(runs, Run{Package: event.Package, Test: test, Action: event.Action, Elapsed: event.Elapsed})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return runs, err
+	}
+
+	// A package fails whenever one of its tests does; it only needs its own entry when none of them failed
+	failedTests := map[string]bool{}
+	for _, run := range runs {
+		if run.Test != "" && run.Action == "fail" {
+			failedTests[run.Package] = true
+		}
+	}
+	kept := runs[:0]
+	for _, run := range runs {
+		if run.Test == "" && failedTests[run.Package] {
+			continue
+		}
+		if builder, ok := outputs[run.Key()]; ok {
+			run.Output = builder.String()
+		}
+		kept = append(kept, run)
+	}
+	return kept, nil
+}
@@ -0,0 +1,102 @@
+package flaky
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const goTestJSON = `{"Action":"run","Package":"terraform-tests/modules","Test":"TestStorageModule"}
+{"Action":"output","Package":"terraform-tests/modules","Test":"TestStorageModule/A","Output":"Rate exceeded\n"}
+{"Action":"fail","Package":"terraform-tests/modules","Test":"TestStorageModule/A","Elapsed":3}
+{"Action":"fail","Package":"terraform-tests/modules","Test":"TestStorageModule","Elapsed":4}
+{"Action":"pass","Package":"terraform-tests/modules","Test":"TestParseTiers","Elapsed":0}
+{"Action":"output","Package":"terraform-tests/modules","Test":"TestSecurityModule","Output":"Error: invalid CIDR\n"}
+{"Action":"fail","Package":"terraform-tests/modules","Test":"TestSecurityModule","Elapsed":9}
+{"Action":"fail","Package":"terraform-tests/modules","Elapsed":13}
+not json
+{"Action":"output","Package":"terraform-tests/integration","Output":"panic: boom\n"}
+{"Action":"fail","Package":"terraform-tests/integration","Elapsed":1}
+`
+
+func TestReadRunsFoldsSubtestsIntoTopLevelTests(t *testing.T) {
+	var output strings.Builder
+	runs, err := ReadRuns(strings.NewReader(goTestJSON), &output)
+	require.NoError(t, err)
+
+	require.Len(t, runs, 4, "Subtests and the failure of a package with failed tests should not get entries")
+	assert.Equal(t, "terraform-tests/modules.TestStorageModule", runs[0].Key())
+	assert.Equal(t, "fail", runs[0].Action)
+	assert.Contains(t, runs[0].Output, "Rate exceeded", "A subtest's output should belong to its top-level test")
+	assert.Equal(t, "pass", runs[1].Action)
+	assert.Equal(t, Run{Package: "terraform-tests/integration", Action: "fail", Elapsed: 1, Output: "panic: boom\n"},
+		runs[3], "A package that failed outside its tests should get an entry of its own")
+
+	assert.Contains(t, output.String(), "not json\n")
+	assert.Contains(t, output.String(), "Error: invalid CIDR\n")
+}
+
+func TestMatchTransient(t *testing.T) {
+	for output, expected := range map[string]string{
+		"api error Throttling: Rate exceeded":                                       "throttling",
+		"InvalidGroup.NotFound: The security group 'sg-123' does not exist":         "eventual consistency",
+		"The role defined for the function cannot be assumed by Lambda.":            "eventual consistency",
+		"waiting for CloudFront Distribution (E2ABC) deploy: timeout while waiting": "cloudfront propagation",
+		"Error: Invalid value for variable \"prefix\"":                              "",
+	} {
+		signature, _ := MatchTransient(output)
+		assert.Equal(t, expected, signature.Name, output)
+	}
+}
+
+func TestTriageRetriesOnlyTransientFailuresOutsideQuarantine(t *testing.T) {
+	runs, err := ReadRuns(strings.NewReader(goTestJSON), io.Discard)
+	require.NoError(t, err)
+
+	results, retry := Triage(runs, &Quarantine{})
+	require.Len(t, retry, 1)
+	assert.Equal(t, "TestStorageModule", retry[0].Test)
+	assert.Equal(t, "throttling", results[0].Signature)
+	assert.Equal(t, OutcomeFailed, results[2].Outcome, "A failure without a transient signature should not be retried")
+	assert.False(t, results[3].Retried, "A failure outside any test cannot be retried")
+
+	ApplyRetries(results, []Run{{Package: "terraform-tests/modules", Test: "TestStorageModule", Action: "pass"}})
+	assert.Equal(t, OutcomeFlaky, results[0].Outcome)
+
+	quarantine := &Quarantine{}
+	quarantine.Add("terraform-tests/modules", "TestStorageModule", "flaky", time.Now())
+	results, retry = Triage(runs, quarantine)
+	assert.Empty(t, retry, "Quarantined tests should not be retried")
+	assert.Equal(t, OutcomeQuarantined, results[0].Outcome)
+	assert.True(t, NewReport(results, nil, time.Now()).Failed(), "Other failures should still fail the run")
+}
+
+func TestQuarantineRepeatOffenders(t *testing.T) {
+	results := []Result{{Package: "terraform-tests/modules", Test: "TestStorageModule", Outcome: OutcomeFlaky,
+		Signature: "throttling", Retried: true}}
+	history := &History{}
+	quarantine := &Quarantine{}
+	now := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	for run := 1; run < 3; run++ {
+		assert.Empty(t, QuarantineRepeatOffenders(results, history, quarantine, 3, 5, now))
+	}
+	quarantined := QuarantineRepeatOffenders(results, history, quarantine, 3, 5, now)
+	assert.Equal(t, []string{"terraform-tests/modules.TestStorageModule"}, quarantined)
+	assert.Equal(t, []QuarantinedTest{{
+		Package: "terraform-tests/modules",
+		Test:    "TestStorageModule",
+		Reason:  "Retried for throttling in 3 of the last 3 runs",
+		Since:   "2026-10-16",
+	}}, quarantine.Tests)
+
+	for run := 0; run < 10; run++ {
+		history.Record(HistoryRun{Time: now}, 5)
+	}
+	assert.Len(t, history.Runs, 5, "The history should keep only the most recent runs")
+	assert.Zero(t, history.RetriedRuns("terraform-tests/modules.TestStorageModule"))
+}
@@ -0,0 +1,133 @@
+package flaky
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// QuarantinedTest is a test whose failures are reported but do not fail the run, and which is never retried
+type QuarantinedTest struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+	Reason  string `json:"reason"`
+	Since   string `json:"since"` // Date it was quarantined, as YYYY-MM-DD
+}
+
+// Quarantine is the list of quarantined tests, kept in the repository so that adding or removing one is reviewed
+type Quarantine struct {
+	Tests []QuarantinedTest `json:"tests"`
+}
+
+// LoadQuarantine reads the quarantine list, treating a missing file as an empty list
+func LoadQuarantine(path string) (*Quarantine, error) {
+	quarantine := &Quarantine{}
+	if err := readJSON(path, quarantine); err != nil {
+		return nil, fmt.Errorf("failed to read quarantine list %s: %w", path, err)
+	}
+	return quarantine, nil
+}
+
+// Contains reports whether the test is quarantined
+func (q *Quarantine) Contains(pkg, test string) bool {
+	for _, quarantined := range q.Tests {
+		if quarantined.Package == pkg && quarantined.Test == test {
+			return true
+		}
+	}
+	return false
+}
+
+// Add quarantines a test, keeping the list sorted so changes to it diff cleanly
+func (q *Quarantine) Add(pkg, test, reason string, now time.Time) {
+	if q.Contains(pkg, test) {
+		return
+	}
+	q.Tests = append(q.Tests, QuarantinedTest{
+		Package: pkg,
+		Test:    test,
+		Reason:  reason,
+		Since:   now.UTC().Format("2006-01-02"),
+	})
+	sort.Slice(q.Tests, func(i, j int) bool {
+		if q.Tests[i].Package != q.Tests[j].Package {
+			return q.Tests[i].Package < q.Tests[j].Package
+		}
+		return q.Tests[i].Test < q.Tests[j].Test
+	})
+}
+
+// Save writes the quarantine list
+func (q *Quarantine) Save(path string) error {
+	return writeJSON(path, q)
+}
+
+// HistoryRun records which tests needed a retry in one run
+type HistoryRun struct {
+	Time    time.Time `json:"time"`
+	Retried []string  `json:"retried,omitempty"` // Key of each retried test
+}
+
+// History is the record of recent runs that decides when a test is retried often enough to be quarantined. CI
+// carries it from run to run in its cache.
+type History struct {
+	Runs []HistoryRun `json:"runs"`
+}
+
+// LoadHistory reads the retry history, treating a missing file as no history
+func LoadHistory(path string) (*History, error) {
+	history := &History{}
+	if err := readJSON(path, history); err != nil {
+		return nil, fmt.Errorf("failed to read retry history %s: %w", path, err)
+	}
+	return history, nil
+}
+
+// Record adds a run, keeping only the most recent keep runs
+func (h *History) Record(run HistoryRun, keep int) {
+	h.Runs = append(h.Runs, run)
+	if len(h.Runs) > keep {
+		h.Runs = h.Runs[len(h.Runs)-keep:]
+	}
+}
+
+// RetriedRuns returns how many of the recorded runs retried the test
+func (h *History) RetriedRuns(key string) int {
+	count := 0
+	for _, run := range h.Runs {
+		for _, retried := range run.Retried {
+			if retried == key {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// Save writes the retry history
+func (h *History) Save(path string) error {
+	return writeJSON(path, h)
+}
+
+func readJSON(path string, value interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+func writeJSON(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
@@ -0,0 +1,214 @@
+package flaky
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Outcome is what a test's result means for the run
+type Outcome string
+
+// Outcomes, as written to the report
+const (
+	OutcomePassed      Outcome = "passed"
+	OutcomeSkipped     Outcome = "skipped"
+	OutcomeFailed      Outcome = "failed"      // Fails the run
+	OutcomeFlaky       Outcome = "flaky"       // Failed with a transient signature, then passed on its retry
+	OutcomeQuarantined Outcome = "quarantined" // Failed while quarantined; reported, but does not fail the run
+)
+
+// Result is the final outcome of one top-level test
+type Result struct {
+	Package     string  `json:"package"`
+	Test        string  `json:"test"`
+	Outcome     Outcome `json:"outcome"`
+	Signature   string  `json:"signature,omitempty"` // The transient signature that got the test retried
+	Retried     bool    `json:"retried,omitempty"`
+	Quarantined bool    `json:"quarantined,omitempty"` // Quarantined before this run, or because of it
+	Elapsed     float64 `json:"elapsed_seconds"`
+}
+
+// Key identifies the test across runs
+func (r Result) Key() string {
+	return r.Package + "." + r.Test
+}
+
+// Triage turns the runs of the first attempt into results and returns the failed tests to retry: those that
+// matched a transient signature and are not quarantined. Their results stay failed until ApplyRetries.
+func Triage(runs []Run, quarantine *Quarantine) ([]Result, []Run) {
+	results := make([]Result, 0, len(runs))
+	var retry []Run
+	for _, run := range runs {
+		result := Result{
+			Package:     run.Package,
+			Test:        run.Test,
+			Outcome:     OutcomePassed,
+			Quarantined: run.Test != "" && quarantine.Contains(run.Package, run.Test),
+			Elapsed:     run.Elapsed,
+		}
+		switch {
+		case run.Action == "skip":
+			result.Outcome = OutcomeSkipped
+		case run.Action == "pass":
+			// Passed, whether or not it is quarantined
+		case result.Quarantined:
+			result.Outcome = OutcomeQuarantined
+		default:
+			result.Outcome = OutcomeFailed
+			if signature, ok := MatchTransient(run.Output); ok && run.Test != "" {
+				result.Signature = signature.Name
+				result.Retried = true
+				retry = append(retry, run)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, retry
+}
+
+// ApplyRetries marks each retried test that passed its retry as flaky. A retried test missing from the reruns,
+// such as when the retry failed to build, stays failed.
+func ApplyRetries(results []Result, reruns []Run) {
+	passed := map[string]bool{}
+	for _, rerun := range reruns {
+		passed[rerun.Key()] = rerun.Action == "pass"
+	}
+	for i := range results {
+		if results[i].Retried && passed[results[i].Key()] {
+			results[i].Outcome = OutcomeFlaky
+		}
+	}
+}
+
+// QuarantineRepeatOffenders records which tests this run retried and quarantines those retried in at least after
+// of the recent runs, returning their keys. Retries are meant for rare transient failures; a test that keeps
+// needing one is flagged for a fix instead of being retried forever.
+func QuarantineRepeatOffenders(
+	results []Result,
+	history *History,
+	quarantine *Quarantine,
+	after int,
+	keep int,
+	now time.Time,
+) []string {
+	run := HistoryRun{Time: now.UTC()}
+	for _, result := range results {
+		if result.Retried {
+			run.Retried = append(run.Retried, result.Key())
+		}
+	}
+	history.Record(run, keep)
+
+	var quarantined []string
+	for i, result := range results {
+		if !result.Retried {
+			continue
+		}
+		count := history.RetriedRuns(result.Key())
+		if count < after {
+			continue
+		}
+		reason := fmt.Sprintf("Retried for %s in %d of the last %d runs", result.Signature, count, len(history.Runs))
+		quarantine.Add(result.Package, result.Test, reason, now)
+		results[i].Quarantined = true
+		quarantined = append(quarantined, result.Key())
+	}
+	return quarantined
+}
+
+// Report is the summary of a run, written as an artifact
+type Report struct {
+	Time             time.Time       `json:"time"`
+	Counts           map[Outcome]int `json:"counts"`
+	Results          []Result        `json:"results"` // Every test that did not simply pass or skip
+	NewlyQuarantined []string        `json:"newly_quarantined,omitempty"`
+}
+
+// NewReport summarizes the results
+func NewReport(results []Result, newlyQuarantined []string, now time.Time) *Report {
+	report := &Report{Time: now.UTC(), Counts: map[Outcome]int{}, NewlyQuarantined: newlyQuarantined}
+	for _, result := range results {
+		report.Counts[result.Outcome]++
+		if result.Outcome == OutcomeFailed || result.Outcome == OutcomeFlaky || result.Quarantined {
+			report.Results = append(report.Results, result)
+		}
+	}
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].Key() < report.Results[j].Key()
+	})
+	return report
+}
+
+// Save writes the report as JSON
+func (r *Report) Save(path string) error {
+	return writeJSON(path, r)
+}
+
+// Failed reports whether any test failed the run
+func (r *Report) Failed() bool {
+	return r.Counts[OutcomeFailed] > 0
+}
+
+// Markdown renders the report for a GitHub Actions job summary
+func (r *Report) Markdown() string {
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "### Test retries\n\n%d passed, %d flaky, %d quarantined, %d failed, %d skipped\n",
+		r.Counts[OutcomePassed], r.Counts[OutcomeFlaky], r.Counts[OutcomeQuarantined], r.Counts[OutcomeFailed],
+		r.Counts[OutcomeSkipped])
+	if len(r.Results) == 0 {
+		return summary.String()
+	}
+
+	summary.WriteString("\n| Test | Outcome | Retried for | Quarantined |\n| --- | --- | --- | --- |\n")
+	for _, result := range r.Results {
+		quarantined := ""
+		if result.Quarantined {
+			quarantined = "yes"
+		}
+		fmt.Fprintf(&summary, "| `%s` | %s | %s | %s |\n", result.Key(), result.Outcome, result.Signature, quarantined)
+	}
+	for _, key := range r.NewlyQuarantined {
+		fmt.Fprintf(&summary, "\n`%s` needed a retry too often and was added to the quarantine list.", key)
+	}
+	return summary.String()
+}
+
+// Annotate writes a GitHub Actions warning for every flaky or quarantined test, so they show on the run without
+// failing it
+func (r *Report) Annotate(w io.Writer) {
+	newlyQuarantined := map[string]bool{}
+	for _, key := range r.NewlyQuarantined {
+		newlyQuarantined[key] = true
+	}
+
+	for _, result := range r.Results {
+		var message string
+		switch {
+		case newlyQuarantined[result.Key()]:
+			message = "Quarantined after repeated retries for " + result.Signature + "; commit the quarantine list or fix it"
+		case result.Outcome == OutcomeFlaky:
+			message = "Passed on retry after a " + result.Signature + " failure"
+		case result.Outcome == OutcomeQuarantined:
+			message = "Failed while quarantined"
+		case result.Quarantined && result.Outcome == OutcomePassed:
+			message = "Passed while quarantined; consider removing it from the quarantine list"
+		default:
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "::warning title=%s::%s\n",
+			escapeWorkflowProperty(result.Key()), escapeWorkflowData(message))
+	}
+}
+
+// escapeWorkflowData escapes a workflow command's message the way the GitHub Actions toolkit does
+func escapeWorkflowData(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(value)
+}
+
+// escapeWorkflowProperty escapes a workflow command property, such as title, which also may not contain : or ,
+func escapeWorkflowProperty(value string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(escapeWorkflowData(value))
+}
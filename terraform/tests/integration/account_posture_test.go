@@ -0,0 +1,33 @@
+package integration
+
+import (
+	"os"
+	"testing"
+
+	"terraform-tests/common"
+)
+
+// TestAccountSecurityPosture checks the deployment region's account-level security services, read-only: GuardDuty
+// is enabled, Security Hub subscribes to its standards, and no open finding concerns a test resource. Run it after
+// the suite to catch test runs that left insecure resources behind.
+func TestAccountSecurityPosture(t *testing.T) {
+	if !common.AccountPostureEnabled() {
+		t.Skip("Skipping account posture checks - set ACCOUNT_POSTURE=true in an account with GuardDuty and " +
+			"Security Hub enabled")
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	t.Run("GuardDutyEnabled", func(t *testing.T) {
+		common.AssertGuardDutyEnabled(t, region)
+	})
+	t.Run("SecurityHubStandards", func(t *testing.T) {
+		common.AssertSecurityHubStandardsReady(t, region)
+	})
+	t.Run("NoOpenFindings", func(t *testing.T) {
+		common.AssertNoOpenSecurityFindings(t, region, common.AccountPosturePrefix())
+	})
+}
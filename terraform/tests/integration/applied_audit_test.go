@@ -0,0 +1,55 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"terraform-tests/cleanup"
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// TestAppliedStackAudits applies the root configuration and runs the compliance audits against its state. The
+// plan-tier audits skip every value computed at apply time, such as bucket policies referencing ARNs, so this is
+// where those are checked. It is opt-in, since the stack needs a Route53 zone it can create records in.
+func TestAppliedStackAudits(t *testing.T) {
+	// Skip this test if not in CI (requires S3 backend)
+	if os.Getenv("CI") == "" && os.Getenv("AWS_ACCOUNT_ID") == "" {
+		t.Skip("Skipping integration test - requires CI environment or AWS_ACCOUNT_ID with S3 backend")
+	}
+
+	testConfig := common.SetupIntegrationTest(t)
+
+	zoneID := os.Getenv("AUDIT_ZONE_ID")
+	if zoneID == "" {
+		t.Skip("Skipping applied audits - AUDIT_ZONE_ID is not set to a Route53 zone the stack can use")
+	}
+
+	testVars := getAuditTestVars(t, testConfig)
+	// A prod stack's database is deletion protected, so the destroy below could not remove it
+	testVars["environment"] = "test"
+	testVars["route53_zone_id"] = zoneID
+	testVars["domain_name"] = fmt.Sprintf("%s.%s", testConfig.UniqueID,
+		common.GetHostedZoneName(t, zoneID, testConfig.AWSRegion))
+
+	options := tfopts.ApplyMode(testConfig.GetTerraformOptions(testVars))
+	cleanup.Func(t, cleanup.AfterDestroy, "check "+options.TerraformDir+" was destroyed", func(t *testing.T) error {
+		remaining, err := terraform.RunTerraformCommandAndGetStdoutE(t, options.Options, "state", "list")
+		if err != nil {
+			return fmt.Errorf("failed to list the state left after the destroy: %w", err)
+		}
+		if remaining = strings.TrimSpace(remaining); remaining != "" {
+			return fmt.Errorf("destroy left resources in state: %s", strings.ReplaceAll(remaining, "\n", ", "))
+		}
+		return nil
+	})
+
+	common.ApplyAndValidate(t, options,
+		common.Audit(common.TLSAuditChecks()...),
+		common.Audit(common.PublicExposureChecks()...),
+	)
+}
@@ -0,0 +1,23 @@
+package integration
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDeployedDatabaseRestoresFromBackup restores the deployed database from its most recent AWS Backup recovery
+// point into a new instance, which is deleted afterwards, and checks the restored instance is usable
+func TestDeployedDatabaseRestoresFromBackup(t *testing.T) {
+	deployed := common.GetDeployedBackup(t)
+
+	restored := common.RestoreLatestRDSRecoveryPoint(t, deployed.DBInstanceARN, deployed.Vault,
+		deployed.RestoreRoleARN, deployed.Region)
+
+	assert.Equal(t, "available", aws.ToString(restored.DBInstanceStatus))
+	assert.True(t, aws.ToBool(restored.StorageEncrypted), "Restored database should be encrypted")
+	assert.False(t, aws.ToBool(restored.PubliclyAccessible), "Restored database should not be public")
+}
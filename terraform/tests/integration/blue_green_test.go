@@ -0,0 +1,28 @@
+package integration
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDeployedServiceRollsBackFailingCanary deploys a revision of the service's task definition whose health checks
+// always fail, and checks CodeDeploy rolls the canary back and leaves production on the original revision
+func TestDeployedServiceRollsBackFailingCanary(t *testing.T) {
+	deployed := common.GetDeployedBlueGreen(t)
+
+	original := aws.ToString(common.GetECSService(t, deployed.Cluster, deployed.Service,
+		deployed.Region).TaskDefinition)
+	unhealthy := common.RegisterUnhealthyRevision(t, original, deployed.Region)
+
+	deployment := common.DeployTaskDefinition(t, deployed, unhealthy)
+
+	assert.NotEqual(t, "Succeeded", deployment.Status, "A revision failing its health checks should not deploy")
+	assert.True(t, deployment.RolledBack(), "CodeDeploy should roll back deployment %s: %s",
+		deployment.DeploymentID, deployment.ErrorInfo.Message)
+	assert.Equal(t, original, aws.ToString(common.GetECSService(t, deployed.Cluster, deployed.Service,
+		deployed.Region).TaskDefinition), "Production should stay on the original task definition")
+}
@@ -0,0 +1,23 @@
+package integration
+
+import (
+	"os"
+	"testing"
+
+	"terraform-tests/common"
+)
+
+// TestCloudTrailAuditsRegion checks a CloudTrail trail records infrastructure changes in the deployment region:
+// it covers the region, is logging management writes with log file validation, and delivers to an encrypted
+// bucket. Apply tests check individual changes reach the trail's event history.
+func TestCloudTrailAuditsRegion(t *testing.T) {
+	if !common.CloudTrailChecksEnabled() {
+		t.Skip("Skipping CloudTrail checks - set CLOUDTRAIL_CHECKS=true in an account with a trail")
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+	common.AssertRegionAudited(t, region)
+}
@@ -0,0 +1,36 @@
+package integration
+
+import (
+	"os"
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCostAllocationTagsActive checks the Project and Environment default tags are activated for cost
+// allocation, without which the budget's Project filter matches no spend
+func TestCostAllocationTagsActive(t *testing.T) {
+	common.RequireTier(t, common.TierE2E)
+
+	statuses := common.GetCostAllocationTagStatuses(t, common.CostAllocationTagKeys)
+	for _, key := range common.CostAllocationTagKeys {
+		assert.Equal(t, "Active", statuses[key], "Activate %s under Billing > Cost allocation tags, or with "+
+			"aws ce update-cost-allocation-tags-status; it only appears there once a resource carries it", key)
+	}
+}
+
+// TestDeployedAnomalySubscriptionThreshold checks the deployed anomaly subscription's threshold, as AWS stores it,
+// still alerts on $5 anomalies as the monitoring module intends
+func TestDeployedAnomalySubscriptionThreshold(t *testing.T) {
+	common.RequireTier(t, common.TierE2E)
+
+	subscriptionARN := os.Getenv("E2E_ANOMALY_SUBSCRIPTION_ARN")
+	if subscriptionARN == "" {
+		t.Skip("Skipping end-to-end test - set E2E_ANOMALY_SUBSCRIPTION_ARN to the cost_anomaly_subscription_arn output")
+	}
+
+	threshold := common.GetAnomalySubscriptionThreshold(t, subscriptionARN)
+	assert.Equal(t, common.AnomalyAlertThreshold, threshold)
+}
@@ -0,0 +1,48 @@
+package integration
+
+import (
+	"os"
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/policy"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// planWithCostGuards plans the configuration and holds the plan to the cost guards every integration plan must pass
+func planWithCostGuards(t *testing.T, terraformOptions *terraform.Options) *terraform.PlanStruct {
+	plan := common.PlanAndShow(t, terraformOptions)
+	t.Run("CostGuards", func(t *testing.T) {
+		policy.AssertCostGuards(t, plan)
+	})
+	return plan
+}
+
+func TestMainConfigurationCostGuards(t *testing.T) {
+	// Skip this test if not in CI (requires S3 backend)
+	if os.Getenv("CI") == "" && os.Getenv("AWS_ACCOUNT_ID") == "" {
+		t.Skip("Skipping integration test - requires CI environment or AWS_ACCOUNT_ID with S3 backend")
+	}
+
+	testConfig := common.SetupIntegrationTest(t)
+	terraformOptions := testConfig.GetTerraformOptions(getAuditTestVars(t, testConfig))
+
+	planWithCostGuards(t, terraformOptions)
+}
+
+func TestNonProductionPlanUsesCheapestTiers(t *testing.T) {
+	// Skip this test if not in CI (requires S3 backend)
+	if os.Getenv("CI") == "" && os.Getenv("AWS_ACCOUNT_ID") == "" {
+		t.Skip("Skipping integration test - requires CI environment or AWS_ACCOUNT_ID with S3 backend")
+	}
+
+	testConfig := common.SetupIntegrationTest(t)
+	testVars := getAuditTestVars(t, testConfig)
+	// Production deliberately trades cost for reach (e.g. all CloudFront edge locations)
+	testVars["environment"] = "dev"
+	terraformOptions := testConfig.GetTerraformOptions(testVars)
+
+	plan := planWithCostGuards(t, terraformOptions)
+	policy.AssertCheapestTiers(t, plan)
+}
@@ -0,0 +1,76 @@
+package integration
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestReportTestRunCosts reports the actual spend of recent test runs tagged with TestRunId.
+// Cost Explorer data lags by about a day, so run this the day after the suite ran.
+func TestReportTestRunCosts(t *testing.T) {
+	daysValue := os.Getenv("COST_REPORT_DAYS")
+	if daysValue == "" {
+		t.Skip("Skipping cost report - set COST_REPORT_DAYS to the number of days of test runs to report")
+	}
+
+	days, err := strconv.Atoi(daysValue)
+	require.NoError(t, err, "COST_REPORT_DAYS must be a number of days")
+	require.Positive(t, days)
+
+	end := time.Now().UTC()
+	start := end.AddDate(0, 0, -days)
+	report := common.GetTestRunCosts(t, start.Format("2006-01-02"), end.Format("2006-01-02"))
+
+	body, err := json.MarshalIndent(report, "", "  ")
+	require.NoError(t, err)
+
+	outputPath := os.Getenv("COST_REPORT_OUTPUT")
+	if outputPath == "" {
+		outputPath = filepath.Join(t.TempDir(), "test-run-costs.json")
+	}
+	require.NoError(t, os.WriteFile(outputPath, body, 0o644))
+
+	for _, run := range report.Runs {
+		t.Logf("%s: $%.2f", run.RunID, run.CostUSD)
+	}
+	t.Logf("Total for %d test runs: $%.2f; report written to %s", len(report.Runs), report.TotalUSD, outputPath)
+}
+
+// TestReportBranchCosts reports the actual spend of recent test runs per branch, from the GitBranch tag every test
+// apply adds. Like TestReportTestRunCosts, it reads Cost Explorer data about a day behind.
+func TestReportBranchCosts(t *testing.T) {
+	daysValue := os.Getenv("COST_REPORT_DAYS")
+	if daysValue == "" {
+		t.Skip("Skipping branch cost report - set COST_REPORT_DAYS to the number of days of test runs to report")
+	}
+
+	days, err := strconv.Atoi(daysValue)
+	require.NoError(t, err, "COST_REPORT_DAYS must be a number of days")
+	require.Positive(t, days)
+
+	end := time.Now().UTC()
+	start := end.AddDate(0, 0, -days)
+	report := common.GetBranchCosts(t, start.Format("2006-01-02"), end.Format("2006-01-02"))
+
+	body, err := json.MarshalIndent(report, "", "  ")
+	require.NoError(t, err)
+
+	outputPath := os.Getenv("BRANCH_COST_REPORT_OUTPUT")
+	if outputPath == "" {
+		outputPath = filepath.Join(t.TempDir(), "branch-costs.json")
+	}
+	require.NoError(t, os.WriteFile(outputPath, body, 0o644))
+
+	for _, branch := range report.Branches {
+		t.Logf("%s: $%.2f", branch.Branch, branch.CostUSD)
+	}
+	t.Logf("Total for %d branches: $%.2f; report written to %s", len(report.Branches), report.TotalUSD, outputPath)
+}
@@ -0,0 +1,44 @@
+package integration
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"terraform-tests/awsval"
+	"terraform-tests/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeployedDatabaseSaturationDegradesGracefully opens enough concurrent API requests to push the database near the
+// max_connections its parameter group pins. The API must answer with successes, throttling or 503s rather than
+// crashing, and the DatabaseConnections alarm must fire. The answers show whether the instance class is big enough
+// for the traffic the API can let through.
+func TestDeployedDatabaseSaturationDegradesGracefully(t *testing.T) {
+	deployed := common.GetDeployedDatabaseSaturation(t)
+
+	instance := common.GetRDSInstanceById(t, deployed.InstanceID, deployed.Region)
+	require.NotEmpty(t, instance.DBParameterGroups, "%s has no parameter group", deployed.InstanceID)
+	parameterGroup := aws.ToString(instance.DBParameterGroups[0].DBParameterGroupName)
+	maxConnections, err := strconv.Atoi(
+		common.GetDBParameterValue(t, parameterGroup, "max_connections", deployed.Region))
+	if deployed.Concurrency == 0 {
+		require.NoError(t, err, "Set E2E_DB_SATURATION_CONCURRENCY, since %s does not pin max_connections",
+			parameterGroup)
+		deployed.Concurrency = maxConnections * common.DBSaturationPercent / 100
+	}
+
+	url := "https://api." + deployed.Domain + "/api/campaigns/"
+	start := time.Now()
+	t.Logf("Sending requests to %s from %d clients at once for %s (%s pins max_connections %d)", url,
+		deployed.Concurrency, deployed.Duration, parameterGroup, maxConnections)
+	result := common.SaturateAPI(url, deployed.Concurrency, deployed.Duration)
+	t.Logf("Status codes while saturated: %v, unanswered: %d", result.Codes, result.TransportErrors)
+	common.ReportAuditFindings(t, []common.AuditFinding{common.CheckSaturationBehavior(url, result)})
+
+	summary, err := awsval.WaitForAlarmStateE(t, deployed.AlarmName, "ALARM", deployed.Region, start)
+	require.NoError(t, err, "%s should fire while connections are saturated", deployed.AlarmName)
+	t.Log(summary)
+}
@@ -0,0 +1,30 @@
+package integration
+
+import (
+	"os"
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/policy"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMainConfigurationDefaultTags plans the root configuration and checks the AWS provider tags everything through
+// default_tags, so resources are tagged whether or not their block remembers a tags argument
+func TestMainConfigurationDefaultTags(t *testing.T) {
+	// Skip this test if not in CI (requires S3 backend)
+	if os.Getenv("CI") == "" && os.Getenv("AWS_ACCOUNT_ID") == "" {
+		t.Skip("Skipping integration test - requires CI environment or AWS_ACCOUNT_ID with S3 backend")
+	}
+
+	testConfig := common.SetupIntegrationTest(t)
+	terraformOptions := testConfig.GetTerraformOptions(getAuditTestVars(t, testConfig))
+	defaults, ok := terraformOptions.Vars["tags"].(map[string]string)
+	require.True(t, ok, "The root configuration should be planned with tags")
+
+	plan := planWithCostGuards(t, terraformOptions)
+
+	policy.AssertProviderDefaultTags(t, plan, "aws", "var.tags")
+	policy.AssertInheritDefaultTags(t, plan, defaults, policy.RepresentativeTaggedResources...)
+}
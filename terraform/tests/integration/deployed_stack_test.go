@@ -0,0 +1,131 @@
+package integration
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"terraform-tests/awsval"
+	"terraform-tests/common"
+	"terraform-tests/e2e"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeployedDatabaseRejectsNonSSLConnections(t *testing.T) {
+	stack := common.GetDeployedStack(t)
+
+	// Unencrypted connections must be refused by rds.force_ssl
+	output, err := stack.RunOnBastion(t, stack.PsqlCommand("disable", "SELECT 1"))
+	assert.Error(t, err, "Non-SSL connection should be rejected")
+	assert.Contains(t, output, "no encryption", "Rejection should come from the SSL requirement")
+
+	// The same connection over TLS must succeed
+	output, err = stack.RunOnBastion(t, stack.PsqlCommand("require", "SHOW ssl"))
+	require.NoError(t, err, "SSL connection should succeed: %s", output)
+	assert.Contains(t, output, "on")
+}
+
+func TestDeployedBastionIsHardened(t *testing.T) {
+	stack := common.GetDeployedStack(t)
+	instance := stack.GetBastionInstance(t)
+	instanceID := aws.ToString(instance.InstanceId)
+
+	t.Run("IMDSv2", func(t *testing.T) {
+		awsval.ValidateInstanceRequiresIMDSv2(t, instance)
+	})
+
+	t.Run("EncryptedRootVolume", func(t *testing.T) {
+		awsval.ValidateInstanceRootVolumeEncrypted(t, instance, stack.Region)
+	})
+
+	t.Run("LeastPrivilegeRole", func(t *testing.T) {
+		awsval.ValidateInstanceRolePolicies(t, instance, stack.Region, common.BastionAllowedPolicies)
+	})
+
+	t.Run("RecentAMI", func(t *testing.T) {
+		awsval.ValidateInstanceAMIAge(t, instance, stack.Region, common.BastionMaxAMIAgeDays)
+	})
+
+	t.Run("SessionManager", func(t *testing.T) {
+		// The bastion shuts itself down when idle, and a stopped agent cannot report in
+		if instance.State == nil || instance.State.Name != types.InstanceStateNameRunning {
+			t.Skipf("Bastion %s is not running", instanceID)
+		}
+		awsval.ValidateInstanceManagedBySSM(t, instanceID, stack.Region)
+	})
+}
+
+func TestDeployedBastionReachesDatabaseViaSSM(t *testing.T) {
+	stack := common.GetDeployedStack(t)
+	require.NotEmpty(t, stack.DBEndpoint, "E2E_DB_ENDPOINT must be set to check database reachability")
+
+	instance := stack.GetBastionInstance(t)
+	if instance.State == nil || instance.State.Name != types.InstanceStateNameRunning {
+		t.Skipf("Bastion %s is not running", aws.ToString(instance.InstanceId))
+	}
+
+	host, port, err := net.SplitHostPort(stack.DBEndpoint)
+	if err != nil {
+		host, port = stack.DBEndpoint, "5432"
+	}
+
+	// Opens a TCP connection with bash's /dev/tcp so no client needs to be installed on the host
+	command := fmt.Sprintf("timeout 5 bash -c '</dev/tcp/%s/%s' && echo reachable", host, port)
+	output, err := stack.RunOnBastionViaSSM(t, command)
+	require.NoError(t, err, "Bastion should reach %s:%s without SSH", host, port)
+	assert.Contains(t, output, "reachable")
+}
+
+func TestDeployedBastionRejectsDisallowedSSHSource(t *testing.T) {
+	stack := common.GetDeployedStack(t)
+	instance := stack.GetBastionInstance(t)
+	runnerIP := e2e.GetRunnerPublicIP(t)
+
+	// Rule check: the runner must fall outside allowed_bastion_cidrs for it to stand in for a disallowed source
+	for _, group := range awsval.GetInstanceSecurityGroups(t, instance, stack.Region) {
+		if awsval.SecurityGroupAllowsIngressFrom(group, 22, runnerIP) {
+			t.Skipf("Runner %s is allowed SSH by %s; run from a network outside allowed_bastion_cidrs",
+				runnerIP, aws.ToString(group.GroupId))
+		}
+	}
+
+	if instance.State == nil || instance.State.Name != types.InstanceStateNameRunning {
+		t.Skipf("Bastion %s is not running", aws.ToString(instance.InstanceId))
+	}
+
+	e2e.AssertTCPConnectTimesOut(t, net.JoinHostPort(stack.BastionHost, "22"), 10*time.Second)
+}
+
+func TestDeployedBastionEgressIsRestricted(t *testing.T) {
+	stack := common.GetDeployedStack(t)
+	instance := stack.GetBastionInstance(t)
+
+	for _, group := range awsval.GetInstanceSecurityGroups(t, instance, stack.Region) {
+		if awsval.SecurityGroupAllowsAllEgress(group) {
+			t.Skipf("Bastion egress is unrestricted (%s allows all outbound traffic)", aws.ToString(group.GroupId))
+		}
+	}
+
+	if instance.State == nil || instance.State.Name != types.InstanceStateNameRunning {
+		t.Skipf("Bastion %s is not running", aws.ToString(instance.InstanceId))
+	}
+
+	// The probe host accepts connections on every TCP port, so only the security group can stop them
+	probeHost := os.Getenv("E2E_EGRESS_PROBE_HOST")
+	if probeHost == "" {
+		probeHost = "portquiz.net"
+	}
+
+	for _, port := range common.BastionUnexpectedEgressPorts {
+		command := fmt.Sprintf("timeout 5 bash -c '</dev/tcp/%s/%d' && echo reachable || echo blocked", probeHost, port)
+		output, err := stack.RunOnBastionViaSSM(t, command)
+		require.NoError(t, err)
+		assert.Contains(t, output, "blocked", "Bastion should not reach %s:%d", probeHost, port)
+	}
+}
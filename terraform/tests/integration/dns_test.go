@@ -0,0 +1,133 @@
+package integration
+
+import (
+	"os"
+	"testing"
+
+	"terraform-tests/awsval"
+	"terraform-tests/common"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dnsOutputs are the outputs an operator relies on to wire up DNS, whichever mode is used
+var dnsOutputs = []string{"api_domain_name", "api_domain_target", "acm_validation_records", "dns_records"}
+
+func TestMainConfigurationDNSModes(t *testing.T) {
+	// Skip this test if not in CI (requires S3 backend)
+	if os.Getenv("CI") == "" && os.Getenv("AWS_ACCOUNT_ID") == "" {
+		t.Skip("Skipping integration test - requires CI environment or AWS_ACCOUNT_ID with S3 backend")
+	}
+
+	testCases := []struct {
+		name            string
+		vars            map[string]interface{}
+		apiRecord       bool
+		frontendRecords bool
+	}{
+		{
+			name:            "ExistingZone",
+			vars:            map[string]interface{}{},
+			apiRecord:       true,
+			frontendRecords: true,
+		},
+		{
+			name:            "ExistingZoneWithoutFrontendRecords",
+			vars:            map[string]interface{}{"create_frontend_dns_records": false},
+			apiRecord:       true,
+			frontendRecords: false,
+		},
+		{
+			name: "ExternalDNS",
+			vars: map[string]interface{}{
+				"manage_dns":      false,
+				"route53_zone_id": "",
+			},
+			apiRecord:       false,
+			frontendRecords: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testConfig := common.SetupIntegrationTest(t)
+			testVars := getAuditTestVars(t, testConfig)
+			for name, value := range tc.vars {
+				testVars[name] = value
+			}
+
+			plan := planWithCostGuards(t, testConfig.GetTerraformOptions(testVars))
+
+			expectedRecords := func(enabled bool) int {
+				if enabled {
+					return 1
+				}
+				return 0
+			}
+			assert.Equal(t, expectedRecords(tc.apiRecord), common.CountPlannedInstances(plan, "aws_route53_record.api"))
+			assert.Equal(t, expectedRecords(tc.frontendRecords),
+				common.CountPlannedInstances(plan, "aws_route53_record.apex"))
+			assert.Equal(t, expectedRecords(tc.frontendRecords),
+				common.CountPlannedInstances(plan, "aws_route53_record.www"))
+
+			// The API custom domain and certificate are needed whoever hosts DNS
+			assert.Contains(t, plan.ResourcePlannedValuesMap, "aws_api_gateway_domain_name.api")
+			assert.Contains(t, plan.ResourcePlannedValuesMap, "aws_acm_certificate_validation.main")
+
+			if !tc.apiRecord {
+				// Nothing may be written to Route 53, including certificate validation and SES verification
+				assert.Empty(t, common.GetPlannedResourcesByType(plan, "aws_route53_record"),
+					"External DNS should not create any Route 53 records")
+				assert.Equal(t, []interface{}{}, common.GetPlannedOutput(plan, "dns_records"))
+			} else {
+				assert.NotZero(t, common.CountPlannedInstances(plan, "aws_route53_record.cert_validation"),
+					"Certificate validation records should be created in the zone")
+			}
+
+			for _, output := range dnsOutputs {
+				assert.Contains(t, plan.RawPlan.OutputChanges, output, "Output %s should be planned", output)
+			}
+		})
+	}
+}
+
+func TestMainConfigurationRequiresZoneWhenManagingDNS(t *testing.T) {
+	// Skip this test if not in CI (requires S3 backend)
+	if os.Getenv("CI") == "" && os.Getenv("AWS_ACCOUNT_ID") == "" {
+		t.Skip("Skipping integration test - requires CI environment or AWS_ACCOUNT_ID with S3 backend")
+	}
+
+	testConfig := common.SetupIntegrationTest(t)
+	testVars := getAuditTestVars(t, testConfig)
+	testVars["route53_zone_id"] = ""
+
+	_, err := terraform.InitAndPlanE(t, testConfig.GetTerraformOptions(testVars))
+	require.Error(t, err, "Plan should fail without a zone while manage_dns is true")
+	assert.Contains(t, err.Error(), "route53_zone_id must be set when manage_dns is true")
+}
+
+// TestDeployedHealthCheckIsHealthy checks every Route53 checker reports the app record's health check healthy,
+// allowing a freshly deployed check the time Route53 takes to detect a change
+func TestDeployedHealthCheckIsHealthy(t *testing.T) {
+	healthCheckID := common.GetDeployedHealthCheckID(t)
+
+	awsval.WaitForHealthCheckHealthy(t, healthCheckID, common.FailoverDetection)
+}
+
+// TestDeployedZoneIsSigned checks Route53 signs the deployed zone with an active key signing key whose DS record
+// is the one given to the registrar, and that a validating resolver gets the apex's SOA signed and validated
+func TestDeployedZoneIsSigned(t *testing.T) {
+	deployed := common.GetDeployedDNSSEC(t)
+
+	status, err := awsval.GetDNSSECE(t, deployed.ZoneID)
+	require.NoError(t, err)
+	common.ReportAuditFindings(t, awsval.CheckDNSSECStatus(deployed.ZoneID, status, deployed.DSRecord))
+
+	signatures, validated, err := common.ApexSignaturesE(deployed.Domain, deployed.Resolver)
+	require.NoError(t, err)
+	assert.Positive(t, signatures, "The SOA of %s should be answered with an RRSIG", deployed.Domain)
+	assert.True(t, validated, "%s should validate %s, which fails when the registrar's DS record is wrong",
+		deployed.Resolver, deployed.Domain)
+}
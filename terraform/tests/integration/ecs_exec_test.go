@@ -0,0 +1,21 @@
+package integration
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDeployedTaskOpensECSExecSession opens an ECS Exec session in a running task and runs python -V, as an
+// operator debugging the task would
+func TestDeployedTaskOpensECSExecSession(t *testing.T) {
+	deployed := common.GetDeployedECSTask(t)
+
+	common.AssertECSExecReady(t, deployed.Cluster, deployed.TaskARN, deployed.Container, deployed.Region)
+
+	output := common.ExecuteCommand(t, deployed.Cluster, deployed.TaskARN, deployed.Container, deployed.Region,
+		"python -V")
+	assert.Contains(t, output, "Python 3", "python -V should run in %s", deployed.Container)
+}
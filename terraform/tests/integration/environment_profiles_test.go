@@ -0,0 +1,138 @@
+package integration
+
+import (
+	"os"
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// environmentProfile is what the root configuration should derive from var.environment
+type environmentProfile struct {
+	environment            string
+	storagePriceClass      string
+	serverlessEnvironment  string // Name the serverless storage module uses for the environment
+	serverlessForceDestroy bool
+	serverlessCloudFront   bool
+	deletionProtection     bool
+}
+
+var environmentProfiles = []environmentProfile{
+	{
+		environment:            "dev",
+		storagePriceClass:      common.TestCloudFrontPriceClass,
+		serverlessEnvironment:  "dev",
+		serverlessForceDestroy: true,
+		serverlessCloudFront:   false,
+		deletionProtection:     false,
+	},
+	{
+		environment:            "staging",
+		storagePriceClass:      common.TestCloudFrontPriceClass,
+		serverlessEnvironment:  "staging",
+		serverlessForceDestroy: true,
+		serverlessCloudFront:   true,
+		deletionProtection:     true,
+	},
+	{
+		environment:            "prod",
+		storagePriceClass:      "PriceClass_All",
+		serverlessEnvironment:  "production",
+		serverlessForceDestroy: false,
+		serverlessCloudFront:   true,
+		deletionProtection:     true,
+	},
+}
+
+// TestMainConfigurationEnvironmentProfiles plans the root configuration once per environment and checks that what
+// should vary by environment does, and that database sizing and backups stay as configured in every environment
+func TestMainConfigurationEnvironmentProfiles(t *testing.T) {
+	// Skip this test if not in CI (requires S3 backend)
+	if os.Getenv("CI") == "" && os.Getenv("AWS_ACCOUNT_ID") == "" {
+		t.Skip("Skipping integration test - requires CI environment or AWS_ACCOUNT_ID with S3 backend")
+	}
+
+	repositoryNames := make(map[string]string)
+
+	for _, profile := range environmentProfiles {
+		t.Run(profile.environment, func(t *testing.T) {
+			testConfig := common.SetupIntegrationTest(t)
+			testVars := getAuditTestVars(t, testConfig)
+			testVars["environment"] = profile.environment
+
+			plan := planWithCostGuards(t, testConfig.GetTerraformOptions(testVars))
+			resources := plan.ResourcePlannedValuesMap
+
+			t.Run("Naming", func(t *testing.T) {
+				repository, exists := resources["module.lambda_ecr.aws_ecr_repository.lambda"]
+				require.True(t, exists, "Lambda ECR repository should be planned")
+				name := common.GetPlannedStringAttribute(repository, "name")
+				assert.Equal(t, "coalition-"+profile.environment, name)
+
+				// The repository name does not include the prefix, so only the environment keeps stacks apart
+				for otherEnvironment, otherName := range repositoryNames {
+					assert.NotEqual(t, otherName, name, "%s and %s would share a repository",
+						profile.environment, otherEnvironment)
+				}
+				repositoryNames[profile.environment] = name
+			})
+
+			t.Run("Tagging", func(t *testing.T) {
+				repository := resources["module.lambda_ecr.aws_ecr_repository.lambda"]
+				require.NotNil(t, repository)
+				assert.Equal(t, profile.environment, plannedTag(repository.AttributeValues, "Environment"))
+
+				bucket, exists := resources["module.serverless_storage.aws_s3_bucket.assets"]
+				require.True(t, exists, "Serverless assets bucket should be planned")
+				assert.Equal(t, profile.serverlessEnvironment, plannedTag(bucket.AttributeValues, "Environment"))
+			})
+
+			t.Run("DeletionProtection", func(t *testing.T) {
+				database, exists := resources["module.database.aws_db_instance.postgres"]
+				require.True(t, exists, "Database instance should be planned")
+				assert.Equal(t, profile.deletionProtection, database.AttributeValues["deletion_protection"])
+				assert.Equal(t, !profile.deletionProtection, database.AttributeValues["skip_final_snapshot"],
+					"A protected database should keep a final snapshot")
+
+				bucket := resources["module.serverless_storage.aws_s3_bucket.assets"]
+				require.NotNil(t, bucket)
+				assert.Equal(t, profile.serverlessForceDestroy, bucket.AttributeValues["force_destroy"])
+			})
+
+			t.Run("BackupRetention", func(t *testing.T) {
+				database := resources["module.database.aws_db_instance.postgres"]
+				require.NotNil(t, database)
+				assert.EqualValues(t, 14, database.AttributeValues["backup_retention_period"],
+					"Backups are kept for 14 days in every environment")
+			})
+
+			t.Run("Sizing", func(t *testing.T) {
+				database := resources["module.database.aws_db_instance.postgres"]
+				require.NotNil(t, database)
+				assert.Equal(t, common.TestDBInstanceClass, common.GetPlannedStringAttribute(database, "instance_class"),
+					"Instance class comes from db_instance_class, not from the environment")
+
+				distribution, exists := resources["module.storage.aws_cloudfront_distribution.static_assets"]
+				require.True(t, exists, "Static assets distribution should be planned")
+				assert.Equal(t, profile.storagePriceClass, common.GetPlannedStringAttribute(distribution, "price_class"))
+
+				cdnInstances := common.CountPlannedInstances(plan, "module.serverless_storage.aws_cloudfront_distribution.cdn")
+				if profile.serverlessCloudFront {
+					assert.Equal(t, 1, cdnInstances, "Serverless assets should be served through CloudFront")
+				} else {
+					assert.Zero(t, cdnInstances, "Serverless CloudFront should be skipped to save costs")
+				}
+			})
+		})
+	}
+}
+
+// plannedTag returns a tag from a planned resource's tags, or "" if it is unset or unknown
+func plannedTag(attributes map[string]interface{}, key string) string {
+	tags, _ := attributes["tags"].(map[string]interface{})
+	value, _ := tags[key].(string)
+	return value
+}
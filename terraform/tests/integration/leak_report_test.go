@@ -0,0 +1,50 @@
+package integration
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestReportLeakedResources lists what test applies left behind in each region, grouped by the branch, commit and
+// test that created it. Anything a running suite has applied is listed too, so run it when none is, or narrow it
+// to one branch with LEAK_REPORT_BRANCH.
+func TestReportLeakedResources(t *testing.T) {
+	regions := os.Getenv("LEAK_REPORT_REGIONS")
+	if regions == "" {
+		t.Skip("Skipping leak report - set LEAK_REPORT_REGIONS to the comma-separated regions to search")
+	}
+
+	filter := map[string]string{tfopts.TestRunIDTag: ""}
+	if branch := os.Getenv("LEAK_REPORT_BRANCH"); branch != "" {
+		filter[tfopts.GitBranchTag] = tfopts.TagValue(branch)
+	}
+
+	var leaked []common.NamespacedResource
+	for _, region := range strings.Split(regions, ",") {
+		leaked = append(leaked, common.FindResourcesByTags(t, strings.TrimSpace(region), filter)...)
+	}
+	groups := common.GroupByOrigin(leaked)
+
+	body, err := json.MarshalIndent(groups, "", "  ")
+	require.NoError(t, err)
+
+	outputPath := os.Getenv("LEAK_REPORT_OUTPUT")
+	if outputPath == "" {
+		outputPath = filepath.Join(t.TempDir(), "leaked-resources.json")
+	}
+	require.NoError(t, os.WriteFile(outputPath, body, 0o644))
+
+	for _, group := range groups {
+		t.Logf("%s on %s (%s), run %s: %d resources", group.TestName, group.GitBranch, group.GitCommit,
+			group.TestRunID, len(group.ARNs))
+	}
+	t.Logf("%d resources left by %d tests; report written to %s", len(leaked), len(groups), outputPath)
+}
@@ -0,0 +1,55 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCampaignLaunchMeetsSLOs rehearses a campaign launch against a deployed stack: it reads the campaign list and
+// the campaign and submits endorsements at E2E_LOAD_RATE requests per second, and checks each endpoint against its
+// p95 latency and error-rate SLO. When E2E_ECS_CLUSTER and E2E_ECS_SERVICE are set, it also checks auto scaling
+// added tasks in response.
+func TestCampaignLaunchMeetsSLOs(t *testing.T) {
+	deployed := common.GetDeployedLoadTest(t)
+	apiURL, origin := "https://api."+deployed.Domain, "https://"+deployed.Domain
+
+	csrf, err := common.GetCSRFCredentialsE(&http.Client{Timeout: 30 * time.Second}, apiURL)
+	require.NoError(t, err)
+
+	desiredBefore := 0
+	if deployed.Service != "" {
+		desiredBefore = common.GetServiceDesiredCount(t, deployed.Cluster, deployed.Service, deployed.Region)
+	}
+
+	requests := int(float64(deployed.Rate) * deployed.Duration.Seconds())
+	targets := common.CampaignLaunchTargets(t, apiURL, origin, deployed.CampaignID, requests, csrf)
+	t.Logf("Sending %d requests per second to %s for %s", deployed.Rate, apiURL, deployed.Duration)
+	results, err := common.RunLoadE(t, targets, deployed.Rate, deployed.Duration)
+	require.NoError(t, err)
+
+	slos := map[string]common.LoadSLO{
+		"GET /api/campaigns/":      common.CampaignReadSLO,
+		"GET /api/campaigns/{id}/": common.CampaignReadSLO,
+		"POST /api/endorsements/":  common.EndorsementSubmitSLO,
+	}
+	summaries := common.SummarizeLoad(results)
+	var findings []common.AuditFinding
+	for endpoint, slo := range slos {
+		summary := summaries[endpoint]
+		if summary.Throttled > 0 {
+			t.Logf("%s: %d of %d requests were rate limited", endpoint, summary.Throttled, summary.Requests)
+		}
+		findings = append(findings, common.CheckLoadSLO(endpoint, summary, slo)...)
+	}
+	common.ReportAuditFindings(t, findings)
+
+	if deployed.Service != "" {
+		common.WaitForScaleOut(t, deployed.Cluster, deployed.Service, deployed.Region, desiredBefore,
+			common.LoadScaleOutTimeout)
+	}
+}
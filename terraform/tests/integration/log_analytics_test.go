@@ -0,0 +1,19 @@
+package integration
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDeployedLogsQueryableInAthena runs a count over the last two days of a deployed log table, which only
+// returns records when Firehose or the load balancer delivered them under the prefixes the table projects
+func TestDeployedLogsQueryableInAthena(t *testing.T) {
+	deployed := common.GetDeployedLogAnalytics(t)
+
+	count := common.CountRecentLogs(t, deployed)
+	t.Logf("%s.%s holds %d log records from the last two days", deployed.Database, deployed.Table, count)
+	assert.Positive(t, count, "Athena should find recently delivered logs in %s", deployed.Table)
+}
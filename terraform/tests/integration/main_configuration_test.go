@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"terraform-tests/common"
+	"terraform-tests/keys"
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
@@ -26,13 +27,11 @@ func TestMainConfiguration(t *testing.T) {
 	testVars["alert_email"] = "test@example.com"
 	testVars["db_password"] = "SuperSecurePassword123!"
 	testVars["app_db_password"] = "AppPassword123!"
-	testVars["bastion_key_name"] = "test-key"
-	testVars["create_new_key_pair"] = false
+	keys.GenerateEphemeralKeyPair(t).SetBastionVars(testVars)
 
 	terraformOptions := testConfig.GetTerraformOptions(testVars)
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := planWithCostGuards(t, terraformOptions)
 
 	// Validate all expected outputs are defined
 	expectedOutputs := []string{
@@ -44,7 +43,7 @@ func TestMainConfiguration(t *testing.T) {
 	}
 
 	for _, output := range expectedOutputs {
-		assert.Contains(t, planOutput, output, "Plan should define %s output", output)
+		assert.Contains(t, plan.RawPlan.OutputChanges, output, "Plan should define %s output", output)
 	}
 
 	// Validate key resources are created
@@ -61,12 +60,8 @@ func TestMainConfiguration(t *testing.T) {
 	}
 
 	for _, resource := range expectedResources {
-		assert.Contains(t, planOutput, resource, "Plan should create %s resource", resource)
+		assert.NotZero(t, common.CountPlannedInstances(plan, resource), "Plan should create %s resource", resource)
 	}
-
-	// Verify the plan completes successfully
-	assert.Contains(t, planOutput, "Plan:", "Plan should complete successfully")
-	assert.NotContains(t, planOutput, "Error:", "Plan should not contain errors")
 }
 
 func TestMainConfigurationValidation(t *testing.T) {
@@ -83,19 +78,20 @@ func TestMainConfigurationValidation(t *testing.T) {
 	testVars["alert_email"] = "test@example.com"
 	testVars["db_password"] = "SuperSecurePassword123!"
 	testVars["app_db_password"] = "AppPassword123!"
-	testVars["bastion_key_name"] = "test-key"
-	testVars["create_new_key_pair"] = false
+	keys.GenerateEphemeralKeyPair(t).SetBastionVars(testVars)
 
 	terraformOptions := testConfig.GetTerraformOptions(testVars)
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := planWithCostGuards(t, terraformOptions)
 
 	// Should succeed and contain main components
-	assert.Contains(t, planOutput, "module.networking.aws_vpc.main", "Plan should create VPC")
-	assert.Contains(t, planOutput, "module.database.aws_db_instance.postgres", "Plan should create database")
-	assert.Contains(t, planOutput, "module.bastion.aws_instance.bastion", "Plan should create bastion host")
-	assert.Contains(t, planOutput, "module.zappa.aws_s3_bucket.zappa_deployments", "Plan should create Zappa S3 bucket")
+	assert.NotZero(t, common.CountPlannedInstances(plan, "module.networking.aws_vpc.main"), "Plan should create VPC")
+	assert.NotZero(t, common.CountPlannedInstances(plan, "module.database.aws_db_instance.postgres"),
+		"Plan should create database")
+	assert.NotZero(t, common.CountPlannedInstances(plan, "module.bastion.aws_instance.bastion"),
+		"Plan should create bastion host")
+	assert.NotZero(t, common.CountPlannedInstances(plan, "module.zappa.aws_s3_bucket.zappa_deployments"),
+		"Plan should create Zappa S3 bucket")
 }
 
 func TestMainConfigurationCORS(t *testing.T) {
@@ -115,8 +111,7 @@ func TestMainConfigurationCORS(t *testing.T) {
 		testVars["alert_email"] = "test@example.com"
 		testVars["db_password"] = "SuperSecurePassword123!"
 		testVars["app_db_password"] = "AppPassword123!"
-		testVars["bastion_key_name"] = "test-key"
-		testVars["create_new_key_pair"] = false
+		keys.GenerateEphemeralKeyPair(t).SetBastionVars(testVars)
 
 		terraformOptions := testConfig.GetTerraformOptions(testVars)
 
@@ -138,8 +133,7 @@ func TestMainConfigurationCORS(t *testing.T) {
 		testVars["alert_email"] = "test@example.com"
 		testVars["db_password"] = "SuperSecurePassword123!"
 		testVars["app_db_password"] = "AppPassword123!"
-		testVars["bastion_key_name"] = "test-key"
-		testVars["create_new_key_pair"] = false
+		keys.GenerateEphemeralKeyPair(t).SetBastionVars(testVars)
 
 		terraformOptions := testConfig.GetTerraformOptions(testVars)
 
@@ -0,0 +1,13 @@
+package integration
+
+import (
+	"os"
+	"testing"
+
+	"terraform-tests/common"
+)
+
+// TestMain destroys any root stack still deployed if the run is interrupted, such as when CI cancels the job
+func TestMain(m *testing.M) {
+	os.Exit(common.RunWithInterruptCleanup(m))
+}
@@ -0,0 +1,18 @@
+package integration
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+)
+
+// TestDeployedCloudFrontFailsOverFromLoadBalancer blocks CloudFront from the app's load balancer and checks the
+// distribution serves the failover origin or custom error page instead. The load balancer's ingress is restored
+// afterwards.
+func TestDeployedCloudFrontFailsOverFromLoadBalancer(t *testing.T) {
+	deployed := common.GetDeployedOriginFailover(t)
+
+	common.BlockCloudFrontIngress(t, deployed.SecurityGroupID, deployed.Region)
+
+	common.AssertServedByFallback(t, deployed.URL, deployed.Marker)
+}
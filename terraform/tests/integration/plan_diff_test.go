@@ -0,0 +1,49 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestPlanDiffAgainstBaseRef plans the root module on the current checkout and on PLAN_DIFF_BASE_REF,
+// then writes a markdown impact report for reviewers to PLAN_DIFF_OUTPUT (or the test's temp directory)
+func TestPlanDiffAgainstBaseRef(t *testing.T) {
+	// Skip this test if not in CI (requires S3 backend)
+	if os.Getenv("CI") == "" && os.Getenv("AWS_ACCOUNT_ID") == "" {
+		t.Skip("Skipping integration test - requires CI environment or AWS_ACCOUNT_ID with S3 backend")
+	}
+
+	baseRef := os.Getenv("PLAN_DIFF_BASE_REF")
+	if baseRef == "" {
+		t.Skip("Skipping plan diff - set PLAN_DIFF_BASE_REF to the ref to compare against (e.g. origin/main)")
+	}
+
+	testConfig := common.SetupIntegrationTest(t)
+	testVars := getAuditTestVars(t, testConfig)
+
+	headOptions := testConfig.GetTerraformOptions(testVars)
+	headPlan := planWithCostGuards(t, headOptions)
+
+	// Plan the base ref with identical variables so only configuration changes show up in the diff
+	baseOptions := testConfig.GetTerraformOptions(testVars)
+	baseOptions.TerraformDir = common.CheckoutGitRef(t, testConfig.TerraformDir, baseRef)
+	basePlan := common.PlanAndShow(t, baseOptions)
+
+	diff := common.DiffPlans(basePlan, headPlan)
+	report := diff.Markdown(baseRef, "HEAD")
+
+	outputPath := os.Getenv("PLAN_DIFF_OUTPUT")
+	if outputPath == "" {
+		outputPath = filepath.Join(t.TempDir(), "plan-diff.md")
+	}
+	require.NoError(t, os.WriteFile(outputPath, []byte(report), 0o644))
+
+	t.Logf("Plan diff: %d added, %d removed, %d changed; report written to %s",
+		len(diff.Added), len(diff.Removed), len(diff.Changed), outputPath)
+	t.Log("\n" + report)
+}
@@ -0,0 +1,41 @@
+package integration
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPullRequestPreviewLifecycle creates a pull request's preview environment, deploys the application into it
+// with PREVIEW_DEPLOY_COMMAND, runs the smoke checks against it and destroys it. CI runs it when a pull request is
+// opened or updated, so the preview is built from the same scenario, namespaces and cleanup the tests use, and
+// fails if the preview cannot be destroyed again.
+func TestPullRequestPreviewLifecycle(t *testing.T) {
+	request := common.GetPreviewRequest(t)
+
+	preview := common.NewPreviewEnvironment(t, request.PullRequest, request.SharedDatabase)
+	t.Logf("Creating the preview of pull request #%d in namespace %s", request.PullRequest, preview.Namespace.Prefix)
+	preview.Create(t)
+
+	if request.DeployCommand == "" {
+		t.Log("PREVIEW_DEPLOY_COMMAND is not set, so only the preview's infrastructure was created")
+	} else {
+		apiURL := preview.Deploy(t, request.DeployCommand)
+		var frontendURLs []string
+		if request.FrontendURL != "" {
+			frontendURLs = append(frontendURLs, request.FrontendURL)
+		}
+
+		report := preview.Smoke(t, apiURL, frontendURLs...)
+		for _, result := range report.Results {
+			t.Run(result.Check+"/"+result.Target, func(t *testing.T) {
+				assert.True(t, result.Passed, result.Detail)
+			})
+		}
+	}
+
+	require.NoError(t, preview.DestroyE(t), "The preview should destroy cleanly, as it must when the pull request closes")
+}
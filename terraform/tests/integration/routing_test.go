@@ -0,0 +1,214 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/common"
+	"terraform-tests/e2e"
+	"terraform-tests/smoketest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMainConfigurationRoutesAPIAndFrontend(t *testing.T) {
+	// Skip this test if not in CI (requires S3 backend)
+	if os.Getenv("CI") == "" && os.Getenv("AWS_ACCOUNT_ID") == "" {
+		t.Skip("Skipping integration test - requires CI environment or AWS_ACCOUNT_ID with S3 backend")
+	}
+
+	testConfig := common.SetupIntegrationTest(t)
+	testVars := getAuditTestVars(t, testConfig)
+	domain := testVars["domain_name"].(string)
+	terraformOptions := testConfig.GetTerraformOptions(testVars)
+
+	plan := planWithCostGuards(t, terraformOptions)
+
+	t.Run("APIDomain", func(t *testing.T) {
+		domainName, exists := plan.ResourcePlannedValuesMap["aws_api_gateway_domain_name.api"]
+		require.True(t, exists, "API custom domain should be planned")
+		assert.Equal(t, "api."+domain, common.GetPlannedStringAttribute(domainName, "domain_name"))
+
+		// Without a base path the whole API, /api/* and /admin/* alike, is served from the domain root
+		mapping, exists := plan.ResourcePlannedValuesMap["aws_api_gateway_base_path_mapping.api"]
+		require.True(t, exists, "Base path mapping should be planned")
+		assert.Empty(t, common.GetPlannedStringAttribute(mapping, "base_path"),
+			"API should be mapped at the root so Django sees /api/ and /admin/ paths unchanged")
+		assert.Equal(t, testVars["api_gateway_id"], common.GetPlannedStringAttribute(mapping, "api_id"))
+
+		record, exists := plan.ResourcePlannedValuesMap["aws_route53_record.api[0]"]
+		require.True(t, exists, "API DNS record should be planned")
+		assert.Equal(t, "api."+domain, common.GetPlannedStringAttribute(record, "name"))
+		aliases, _ := record.AttributeValues["alias"].([]interface{})
+		require.Len(t, aliases, 1, "API record should alias the API Gateway domain")
+		assert.Equal(t, true, aliases[0].(map[string]interface{})["evaluate_target_health"],
+			"Route 53 should take the API Gateway endpoint's health into account")
+	})
+
+	t.Run("FrontendDomains", func(t *testing.T) {
+		apex, exists := plan.ResourcePlannedValuesMap["aws_route53_record.apex[0]"]
+		require.True(t, exists, "Apex DNS record should be planned")
+		assert.Equal(t, "A", common.GetPlannedStringAttribute(apex, "type"))
+		assert.ElementsMatch(t, []interface{}{common.VercelApexIP}, apex.AttributeValues["records"])
+
+		www, exists := plan.ResourcePlannedValuesMap["aws_route53_record.www[0]"]
+		require.True(t, exists, "www DNS record should be planned")
+		assert.Equal(t, "CNAME", common.GetPlannedStringAttribute(www, "type"))
+		assert.ElementsMatch(t, []interface{}{common.VercelCNAME}, www.AttributeValues["records"])
+	})
+
+	t.Run("NoServerSideRendering", func(t *testing.T) {
+		for _, resourceType := range common.ServerRenderingResourceTypes {
+			for _, resource := range common.GetPlannedResourcesByType(plan, resourceType) {
+				assert.Fail(t, "Unexpected SSR resource", "%s should not be planned; the frontend runs on Vercel",
+					resource.Address)
+			}
+		}
+
+		// The only container images are the Lambda ones for the API
+		for _, repository := range common.GetPlannedResourcesByType(plan, "aws_ecr_repository") {
+			assert.True(t, strings.HasPrefix(repository.Address, "module.lambda_ecr."),
+				"%s should not be planned; there is no SSR image to store", repository.Address)
+		}
+	})
+}
+
+// TestMainConfigurationMaintenanceMode plans the root configuration with maintenance mode on and off, checking the
+// maintenance page answers 503 while the health check stays routed, and that turning it off removes the page
+func TestMainConfigurationMaintenanceMode(t *testing.T) {
+	// Skip this test if not in CI (requires S3 backend)
+	if os.Getenv("CI") == "" && os.Getenv("AWS_ACCOUNT_ID") == "" {
+		t.Skip("Skipping integration test - requires CI environment or AWS_ACCOUNT_ID with S3 backend")
+	}
+	if !common.ModuleSupportsMaintenanceMode("../..") {
+		t.Skipf("The root configuration does not declare %s yet", common.MaintenanceModeVariable)
+	}
+
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("%s=%t", common.MaintenanceModeVariable, enabled), func(t *testing.T) {
+			testConfig := common.SetupIntegrationTest(t)
+			testVars := getAuditTestVars(t, testConfig)
+			testVars[common.MaintenanceModeVariable] = enabled
+			terraformOptions := testConfig.GetTerraformOptions(testVars)
+
+			plan := planWithCostGuards(t, terraformOptions)
+
+			findings := common.RunAudit(common.NewPlanAuditContext(plan), common.CheckMaintenanceMode)
+			if enabled {
+				assert.NotEmpty(t, findings, "Maintenance mode should plan a maintenance page")
+				common.ReportAuditFindings(t, findings)
+			} else {
+				assert.Empty(t, findings, "No maintenance page should be planned with maintenance mode off")
+			}
+		})
+	}
+}
+
+func TestDeployedRoutingReachesExpectedBackends(t *testing.T) {
+	domain := common.GetDeployedDomain(t)
+	client := e2e.NewNonRedirectingClient()
+
+	for _, route := range common.ExpectedRoutes(domain) {
+		t.Run(fmt.Sprintf("%s%s", route.Host, route.Path), func(t *testing.T) {
+			backend, response := common.RequestRoute(t, client, route)
+			assert.Equal(t, route.Backend, backend,
+				"https://%s%s answered with status %d from the wrong backend", route.Host, route.Path, response.StatusCode)
+			assert.Less(t, response.StatusCode, 500, "Backend should not fail the request")
+		})
+	}
+}
+
+// TestDeployedEnvironmentPassesSmokeTest runs the checks cmd/smoketest runs after each deploy: every hostname
+// resolves and serves a certificate with at least two weeks left, each route reaches its backend, and the API is
+// healthy
+func TestDeployedEnvironmentPassesSmokeTest(t *testing.T) {
+	domain := common.GetDeployedDomain(t)
+
+	report := smoketest.NewChecker(smoketest.Options{}).Run(context.Background(), smoketest.NewEnvironment(domain))
+	for _, result := range report.Results {
+		t.Run(result.Check+"/"+result.Target, func(t *testing.T) {
+			assert.True(t, result.Passed, result.Detail)
+		})
+	}
+}
+
+func TestDeployedAPIHealthCheck(t *testing.T) {
+	domain := common.GetDeployedDomain(t)
+	client := &http.Client{Timeout: common.APIGatewayIntegrationTimeout + 5*time.Second}
+
+	// The first request may land on a cold Lambda; it still has to finish before API Gateway gives up
+	health, status, elapsed := common.GetAPIHealth(t, client, domain)
+	t.Logf("First health check answered %d in %s", status, elapsed)
+	assert.Less(t, elapsed, common.APIGatewayIntegrationTimeout, "Cold start should finish within the API Gateway timeout")
+	require.Equal(t, http.StatusOK, status, "API should report healthy")
+	assert.Equal(t, "healthy", health.Status)
+	assert.Equal(t, "healthy", health.Database.Status, "API should reach the database")
+
+	// A warm function answers quickly
+	_, status, elapsed = common.GetAPIHealth(t, client, domain)
+	t.Logf("Warm health check answered %d in %s", status, elapsed)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Less(t, elapsed, common.APIWarmHealthCheckBudget, "Warm health check should be fast")
+}
+
+func TestDeployedAPIReceivesForwardedProto(t *testing.T) {
+	domain := common.GetDeployedDomain(t)
+	client := e2e.NewNonRedirectingClient()
+
+	// Django trusts X-Forwarded-Proto (SECURE_PROXY_SSL_HEADER). If API Gateway did not pass it on, Django would
+	// treat the request as plain HTTP, skip the Referer check and complain about the missing cookie instead.
+	status, reason := common.PostWithoutCSRFCredentials(t, client, fmt.Sprintf("https://api.%s/admin/login/", domain), "")
+	assert.Equal(t, http.StatusForbidden, status, "Admin login without CSRF credentials should be rejected")
+	assert.Equal(t, common.CSRFRejectedNoReferer, reason,
+		"Django should see the request as HTTPS and insist on a Referer")
+}
+
+// TestDeployedDjangoHostAndCSRFContract checks that the domain settings given to the Lambda function
+// (ALLOWED_HOSTS and CSRF_TRUSTED_ORIGINS) match how the stack is reached
+func TestDeployedDjangoHostAndCSRFContract(t *testing.T) {
+	domain := common.GetDeployedDomain(t)
+	executeAPIURL := common.GetExecuteAPIURL(t)
+	client := e2e.NewNonRedirectingClient()
+
+	t.Run("CustomDomainAllowed", func(t *testing.T) {
+		status := e2e.GetStatus(t, client, fmt.Sprintf("https://api.%s/api/health", domain))
+		assert.Equal(t, http.StatusOK, status, "api.%s should be in ALLOWED_HOSTS", domain)
+	})
+
+	t.Run("RawExecuteAPIHostRejected", func(t *testing.T) {
+		// Only the custom domain is a supported entry point; Django answers DisallowedHost with 400
+		status := e2e.GetStatus(t, client, executeAPIURL+"/api/health")
+		assert.Equal(t, http.StatusBadRequest, status, "The execute-api hostname should not be in ALLOWED_HOSTS")
+	})
+
+	loginURL := fmt.Sprintf("https://api.%s/admin/login/", domain)
+
+	t.Run("FrontendOriginTrusted", func(t *testing.T) {
+		status, reason := common.PostWithoutCSRFCredentials(t, client, loginURL, "https://"+domain)
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, common.CSRFRejectedNoCookie, reason,
+			"https://%s should be in CSRF_TRUSTED_ORIGINS, leaving only the missing cookie", domain)
+	})
+
+	t.Run("UnknownOriginRejected", func(t *testing.T) {
+		status, reason := common.PostWithoutCSRFCredentials(t, client, loginURL, "https://untrusted.example.com")
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.NotEqual(t, common.CSRFRejectedNoCookie, reason, "An unknown origin should fail the origin check first")
+	})
+}
+
+// TestDeployedMaintenanceMode checks the deployed site is or is not in maintenance, as E2E_MAINTENANCE_MODE says,
+// and that monitors reach the API health check either way. Run it once after turning maintenance mode on and again
+// after turning it off.
+func TestDeployedMaintenanceMode(t *testing.T) {
+	domain := common.GetDeployedDomain(t)
+	enabled := common.MaintenanceModeExpected(t)
+
+	common.AssertMaintenanceMode(t, domain, enabled)
+}
@@ -0,0 +1,17 @@
+package integration
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+)
+
+// TestDeployedServiceScalesToZeroOnSchedule fast-forwards a non-production service's overnight scale-to-zero and
+// checks its tasks drain and its load balancer serves a maintenance response. Capacity is restored afterwards.
+func TestDeployedServiceScalesToZeroOnSchedule(t *testing.T) {
+	deployed := common.GetDeployedScheduledService(t)
+
+	common.ScaleToZeroNow(t, deployed.Cluster, deployed.Service, deployed.Region)
+
+	common.AssertMaintenanceResponse(t, deployed.URL)
+}
@@ -0,0 +1,26 @@
+package integration
+
+import (
+	"strings"
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDeployedSESDeliversThroughConfigurationSet sends a message from the stack's sending address to the SES
+// mailbox simulator and checks the configuration set publishes its delivery to the notification topic. The
+// simulator accepts mail from a sandboxed account, so this runs before the account has production access.
+func TestDeployedSESDeliversThroughConfigurationSet(t *testing.T) {
+	deployed := common.GetDeployedSES(t)
+
+	domain := deployed.FromEmail[strings.LastIndex(deployed.FromEmail, "@")+1:]
+	identity := common.GetSESIdentity(t, domain, deployed.Region)
+	assert.True(t, identity.VerifiedForSendingStatus, "The sending domain %s should be verified", domain)
+
+	queueURL := common.SubscribeTestQueue(t, deployed.NotificationTopicARN, deployed.Region)
+	messageID := common.SendSESTestEmail(t, deployed.Region, deployed.FromEmail, common.SESMailboxSimulatorSuccess,
+		deployed.ConfigurationSet)
+	common.WaitForSESEvent(t, queueURL, deployed.Region, messageID, "Delivery")
+}
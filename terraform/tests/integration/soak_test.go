@@ -0,0 +1,34 @@
+package integration
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeployedEnvironmentSoak keeps polling a deployed environment for hours before a production cutover, and writes
+// a stability report of failed health checks, the API's 5xx rate and tasks that restarted or ran out of memory
+func TestDeployedEnvironmentSoak(t *testing.T) {
+	deployed := common.GetDeployedSoak(t)
+
+	t.Logf("Soaking %s for %s, polling every %s", deployed.Domain, deployed.Duration, deployed.Interval)
+	report := common.RunSoak(t, deployed)
+
+	body, err := json.MarshalIndent(report, "", "  ")
+	require.NoError(t, err)
+	outputPath := os.Getenv("SOAK_REPORT_OUTPUT")
+	if outputPath == "" {
+		outputPath = filepath.Join(t.TempDir(), "soak-report.json")
+	}
+	require.NoError(t, os.WriteFile(outputPath, body, 0o644))
+
+	t.Logf("%d of %d health checks failed (slowest %dms), %.0f of %.0f API requests ended in a 5xx, "+
+		"%d tasks stopped unexpectedly; report written to %s", len(report.HealthFailures), report.HealthChecks,
+		report.SlowestHealthMS, report.API5xx, report.APIRequests, len(report.TaskStops), outputPath)
+	common.ReportAuditFindings(t, report.Findings())
+}
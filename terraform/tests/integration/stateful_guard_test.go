@@ -0,0 +1,21 @@
+package integration
+
+import (
+	"os"
+	"testing"
+
+	"terraform-tests/common"
+)
+
+// TestPlanHasNoStatefulReplacements guards pull request plans against destroying or replacing
+// databases, the static assets bucket, CloudFront distributions and DNS records.
+// STATEFUL_GUARD_PLAN_JSON must point at the output of `terraform show -json tfplan` for the deployed environment.
+func TestPlanHasNoStatefulReplacements(t *testing.T) {
+	planPath := os.Getenv("STATEFUL_GUARD_PLAN_JSON")
+	if planPath == "" {
+		t.Skip("Skipping stateful resource guard - set STATEFUL_GUARD_PLAN_JSON to a plan rendered with terraform show -json")
+	}
+
+	plan := common.LoadPlanJSON(t, planPath)
+	common.AssertNoStatefulReplacements(t, plan)
+}
@@ -0,0 +1,138 @@
+package integration
+
+import (
+	"os"
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	terratestaws "github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// rootToggleCounts returns the instances the root configuration should plan for a combination of its networking
+// and DNS toggles. The networking module always creates its S3 gateway endpoint.
+func rootToggleCounts(createVPC, public, private, db, manageDNS bool) map[string]int {
+	instances := func(enabled bool) int {
+		if enabled {
+			return 1
+		}
+		return 0
+	}
+
+	return map[string]int{
+		"module.networking.aws_vpc.main":                               instances(createVPC),
+		"module.networking.aws_internet_gateway.igw":                   instances(createVPC),
+		"module.networking.aws_vpc_endpoint.s3":                        1,
+		"module.networking.aws_subnet.public_a":                        instances(public),
+		"module.networking.aws_subnet.public_b":                        instances(public),
+		"module.networking.aws_route_table.public":                     instances(public),
+		"module.networking.aws_route.public_internet_gateway_new":      instances(public && createVPC),
+		"module.networking.aws_route.public_internet_gateway_existing": instances(public && !createVPC),
+		"module.networking.aws_subnet.private_a":                       instances(private),
+		"module.networking.aws_subnet.private_b":                       instances(private),
+		"module.networking.aws_route_table.private_app":                instances(private),
+		"module.networking.aws_subnet.private_db_a":                    instances(db),
+		"module.networking.aws_subnet.private_db_b":                    instances(db),
+		"module.networking.aws_route_table.private_db":                 instances(db),
+		"aws_route53_record.api":                                       instances(manageDNS),
+		"aws_route53_record.apex":                                      instances(manageDNS),
+		"aws_route53_record.www":                                       instances(manageDNS),
+	}
+}
+
+// TestMainConfigurationToggleMatrix plans the root configuration for combinations of create_vpc, the
+// create_*_subnets toggles and manage_dns, and checks every conditional resource they drive. A new VPC is planned
+// with all of its subnets, since the root's modules need each tier; the existing-VPC cases deploy into the region's
+// default VPC, the only VPC with an internet gateway every account starts with, and are skipped without one.
+func TestMainConfigurationToggleMatrix(t *testing.T) {
+	// Skip this test if not in CI (requires S3 backend)
+	if os.Getenv("CI") == "" && os.Getenv("AWS_ACCOUNT_ID") == "" {
+		t.Skip("Skipping integration test - requires CI environment or AWS_ACCOUNT_ID with S3 backend")
+	}
+	common.RequireTier(t, common.TierIntegration)
+
+	externalDNS := map[string]interface{}{"manage_dns": false, "route53_zone_id": ""}
+	cases := []common.ToggleCase{
+		{
+			Name:   "EverythingCreated",
+			Vars:   map[string]interface{}{},
+			Counts: rootToggleCounts(true, true, true, true, true),
+		},
+		{
+			Name:   "ExternalDNS",
+			Vars:   externalDNS,
+			Counts: rootToggleCounts(true, true, true, true, false),
+		},
+	}
+
+	plan := func(t *testing.T, vars map[string]interface{}) *terraform.PlanStruct {
+		testConfig := common.SetupIntegrationTest(t)
+		testVars := getAuditTestVars(t, testConfig)
+		for name, value := range vars {
+			testVars[name] = value
+		}
+		return planWithCostGuards(t, testConfig.GetTerraformOptions(testVars))
+	}
+
+	region := tfopts.NewTestConfig("../../").AWSRegion
+	defaultVPC, err := terratestaws.GetDefaultVpcE(t, region)
+	if err != nil {
+		common.RunToggleCases(t, nil, cases, plan)
+		t.Run("ExistingVPC", func(t *testing.T) {
+			t.Skipf("No default VPC in %s to stand in for an existing VPC: %v", region, err)
+		})
+		return
+	}
+	subnetIDs := terratestaws.GetDefaultSubnetIDsForVpc(t, *defaultVPC)
+	require.GreaterOrEqual(t, len(subnetIDs), 2, "Default VPC should have a subnet in at least two AZs")
+	subnetIDs = subnetIDs[:2]
+
+	existingNetworking := map[string]interface{}{
+		"create_vpc":             false,
+		"vpc_id":                 defaultVPC.Id,
+		"create_public_subnets":  false,
+		"public_subnet_ids":      subnetIDs,
+		"create_private_subnets": false,
+		"private_subnet_ids":     subnetIDs,
+		"create_db_subnets":      false,
+		"db_subnet_ids":          subnetIDs,
+	}
+	externalDNSInExistingNetworking := map[string]interface{}{}
+	for _, toggles := range []map[string]interface{}{existingNetworking, externalDNS} {
+		for name, value := range toggles {
+			externalDNSInExistingNetworking[name] = value
+		}
+	}
+
+	cases = append(cases,
+		common.ToggleCase{
+			Name:   "ExistingNetworking",
+			Vars:   existingNetworking,
+			Counts: rootToggleCounts(false, false, false, false, true),
+		},
+		common.ToggleCase{
+			Name:   "ExistingNetworkingExternalDNS",
+			Vars:   externalDNSInExistingNetworking,
+			Counts: rootToggleCounts(false, false, false, false, false),
+		},
+		common.ToggleCase{
+			// Plan only, so these are never allocated; they sit at the top of the default 172.31.0.0/16 range
+			Name: "ExistingVPCWithNewSubnets",
+			Vars: map[string]interface{}{
+				"create_vpc":               false,
+				"vpc_id":                   defaultVPC.Id,
+				"public_subnet_a_cidr":     "172.31.250.0/24",
+				"public_subnet_b_cidr":     "172.31.251.0/24",
+				"private_subnet_a_cidr":    "172.31.252.0/24",
+				"private_subnet_b_cidr":    "172.31.253.0/24",
+				"private_db_subnet_a_cidr": "172.31.254.0/24",
+				"private_db_subnet_b_cidr": "172.31.255.0/24",
+			},
+			Counts: rootToggleCounts(false, true, true, true, true),
+		},
+	)
+	common.RunToggleCases(t, nil, cases, plan)
+}
@@ -0,0 +1,28 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDeployedAPIRequestIsTraced sends a sampled request to the API health check and checks X-Ray records a
+// segment from the Lambda function, and that the account's sampling rules trace the API's requests
+func TestDeployedAPIRequestIsTraced(t *testing.T) {
+	domain := common.GetDeployedDomain(t)
+	common.XRayTracingExpected(t)
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	origins := common.TraceRequest(t, fmt.Sprintf("https://api.%s/api/health", domain), region)
+	assert.Contains(t, origins, "AWS::Lambda::Function", "The trace should include the function's segment")
+
+	rules := common.GetSamplingRules(t, region)
+	assert.Empty(t, common.SamplingRuleProblem(rules, "coalition-prod"))
+}
@@ -0,0 +1,24 @@
+package integration
+
+import (
+	"testing"
+
+	"terraform-tests/awsval"
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeployedWebACLIsAssociated checks the deployed web ACL is the one associated with each resource it must
+// protect. A web ACL that is created but never associated filters nothing, and no plan or output shows it.
+func TestDeployedWebACLIsAssociated(t *testing.T) {
+	deployed := common.GetDeployedWebACL(t)
+
+	findings := make([]common.AuditFinding, 0, len(deployed.ResourceARNs))
+	for _, resourceARN := range deployed.ResourceARNs {
+		associated, err := awsval.GetWebACLForResourceE(t, resourceARN, deployed.Region)
+		require.NoError(t, err)
+		findings = append(findings, awsval.CheckWebACLAssociation(resourceARN, associated, deployed.WebACLARN))
+	}
+	common.ReportAuditFindings(t, findings)
+}
@@ -0,0 +1,18 @@
+package integration
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+)
+
+// TestDeployedWorkerQueueIsConfigured checks a deployed worker queue is encrypted, redrives to a dead-letter queue
+// and hides messages for the worker's runtime, then round-trips a message through the dead-letter queue. The
+// worker queue itself is not used for the round trip, since its worker would receive the message first.
+func TestDeployedWorkerQueueIsConfigured(t *testing.T) {
+	deployed := common.GetDeployedWorkerQueue(t)
+
+	common.ValidateWorkerQueue(t, deployed.QueueURL, deployed.Region, deployed.MaxRuntime)
+	common.AssertQueueRoundTrip(t, common.GetDeadLetterQueueURL(t, deployed.QueueURL, deployed.Region),
+		deployed.Region)
+}
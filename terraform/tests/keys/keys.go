@@ -0,0 +1,129 @@
+// Package keys manages SSH key pairs that only live for the duration of a test, so tests never depend on
+// a key pair having been created in the account beforehand.
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/cleanup"
+	"terraform-tests/tfopts"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	terratest_ssh "github.com/gruntwork-io/terratest/modules/ssh"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// Algorithm is the type of SSH key to generate
+type Algorithm string
+
+// Supported key algorithms. EC2 accepts both; ED25519 is faster to generate and preferred.
+const (
+	ED25519 Algorithm = "ed25519"
+	RSA     Algorithm = "rsa"
+)
+
+// RSAKeyBits is the size of generated RSA keys
+const RSAKeyBits = 3072
+
+// KeyPair is an SSH key pair generated in memory for a single test
+type KeyPair struct {
+	Name       string // EC2 key pair name
+	Algorithm  Algorithm
+	PublicKey  string // authorized_keys format, as EC2 and the bastion module expect
+	PrivateKey string // PEM-encoded OpenSSH private key
+	Signer     ssh.Signer
+}
+
+// GenerateEphemeralKeyPair creates an ED25519 key pair in memory with a unique EC2 key pair name
+func GenerateEphemeralKeyPair(t *testing.T) *KeyPair {
+	return GenerateEphemeralKeyPairWithAlgorithm(t, ED25519)
+}
+
+// GenerateEphemeralKeyPairWithAlgorithm creates a key pair of the given algorithm in memory
+func GenerateEphemeralKeyPairWithAlgorithm(t *testing.T, algorithm Algorithm) *KeyPair {
+	var privateKey crypto.Signer
+	var err error
+	switch algorithm {
+	case ED25519:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	case RSA:
+		privateKey, err = rsa.GenerateKey(rand.Reader, RSAKeyBits)
+	default:
+		require.Failf(t, "Unsupported key algorithm", "%q is not a supported key algorithm", algorithm)
+	}
+	require.NoError(t, err, "Failed to generate %s key", algorithm)
+
+	name := fmt.Sprintf("terratest-%s", tfopts.NewUniqueID())
+
+	signer, err := ssh.NewSignerFromSigner(privateKey)
+	require.NoError(t, err)
+
+	block, err := ssh.MarshalPrivateKey(privateKey, name)
+	require.NoError(t, err)
+
+	return &KeyPair{
+		Name:       name,
+		Algorithm:  algorithm,
+		PublicKey:  strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))) + " " + name,
+		PrivateKey: string(pem.EncodeToMemory(block)),
+		Signer:     signer,
+	}
+}
+
+// SetBastionVars points the bastion variables at this key pair, letting Terraform create it in EC2
+func (k *KeyPair) SetBastionVars(vars map[string]interface{}) {
+	vars["bastion_key_name"] = k.Name
+	vars["bastion_public_key"] = k.PublicKey
+	vars["create_new_key_pair"] = true
+}
+
+// TerratestKeyPair returns the key pair in the form terratest's ssh helpers use
+func (k *KeyPair) TerratestKeyPair() *terratest_ssh.KeyPair {
+	return &terratest_ssh.KeyPair{PublicKey: k.PublicKey, PrivateKey: k.PrivateKey}
+}
+
+// ImportToEC2 imports the public key as an EC2 key pair and deletes it when the test finishes
+func (k *KeyPair) ImportToEC2(t *testing.T, region string) {
+	svc := newEC2Client(t, region)
+	_, err := svc.ImportKeyPair(context.Background(), &ec2.ImportKeyPairInput{
+		KeyName:           aws.String(k.Name),
+		PublicKeyMaterial: []byte(k.PublicKey),
+	})
+	require.NoError(t, err, "Failed to import key pair %s", k.Name)
+	t.Logf("Imported EC2 key pair %s", k.Name)
+
+	k.DeleteFromEC2OnCleanup(t, region)
+}
+
+// DeleteFromEC2OnCleanup deletes the EC2 key pair when the test finishes, after its configurations are destroyed,
+// which also catches a key pair a configuration created and left behind
+func (k *KeyPair) DeleteFromEC2OnCleanup(t *testing.T, region string) {
+	cleanup.Func(t, cleanup.AfterDestroy, "delete EC2 key pair "+k.Name, func(t *testing.T) error {
+		cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+		if err != nil {
+			return err
+		}
+		// DeleteKeyPair succeeds when the key pair no longer exists
+		_, err = ec2.NewFromConfig(cfg).DeleteKeyPair(context.Background(), &ec2.DeleteKeyPairInput{
+			KeyName: aws.String(k.Name),
+		})
+		return err
+	})
+}
+
+func newEC2Client(t *testing.T, region string) *ec2.Client {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, region)
+	require.NoError(t, err)
+	return ec2.NewFromConfig(cfg)
+}
@@ -0,0 +1,53 @@
+package keys
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerateEphemeralKeyPair(t *testing.T) {
+	for _, algorithm := range []Algorithm{ED25519, RSA} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			keyPair := GenerateEphemeralKeyPairWithAlgorithm(t, algorithm)
+
+			publicKey, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(keyPair.PublicKey))
+			require.NoError(t, err, "Public key should be in authorized_keys format")
+			assert.Equal(t, keyPair.Name, comment)
+
+			// The PEM private key, as handed to terratest, must match the public key
+			parsed, err := ssh.ParsePrivateKey([]byte(keyPair.PrivateKey))
+			require.NoError(t, err)
+			assert.Equal(t, publicKey.Marshal(), parsed.PublicKey().Marshal())
+
+			data := make([]byte, 32)
+			_, err = rand.Read(data)
+			require.NoError(t, err)
+			signature, err := keyPair.Signer.Sign(rand.Reader, data)
+			require.NoError(t, err)
+			assert.NoError(t, publicKey.Verify(data, signature), "Signer should sign for the public key")
+		})
+	}
+}
+
+func TestGenerateEphemeralKeyPairNamesAreUnique(t *testing.T) {
+	first := GenerateEphemeralKeyPair(t)
+	second := GenerateEphemeralKeyPair(t)
+
+	assert.NotEqual(t, first.Name, second.Name)
+	assert.NotEqual(t, first.PublicKey, second.PublicKey)
+}
+
+func TestSetBastionVars(t *testing.T) {
+	keyPair := GenerateEphemeralKeyPair(t)
+	vars := map[string]interface{}{"bastion_key_name": "test-key", "create_new_key_pair": false}
+
+	keyPair.SetBastionVars(vars)
+
+	assert.Equal(t, keyPair.Name, vars["bastion_key_name"])
+	assert.Equal(t, keyPair.PublicKey, vars["bastion_public_key"])
+	assert.Equal(t, true, vars["create_new_key_pair"])
+}
@@ -0,0 +1,45 @@
+package modules
+
+import (
+	"encoding/json"
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSecurityHubFindingFilters checks the findings query is narrowed to active, unresolved findings on resources
+// matching the prefix
+func TestSecurityHubFindingFilters(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	var filters map[string][]map[string]string
+	require.NoError(t, json.Unmarshal([]byte(common.SecurityHubFindingFilters("coalition-test-")), &filters))
+
+	assert.Equal(t, []map[string]string{{"Value": "coalition-test-", "Comparison": "CONTAINS"}},
+		filters["ResourceId"])
+	assert.Equal(t, []map[string]string{{"Value": "ACTIVE", "Comparison": "EQUALS"}}, filters["RecordState"])
+	assert.Len(t, filters["WorkflowStatus"], 2)
+}
+
+// TestOpenSecurityFindings checks passing controls are dropped and failures and GuardDuty threats kept
+func TestOpenSecurityFindings(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	var findings []common.SecurityFinding
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"Id":"passed","Compliance":{"Status":"PASSED"}},
+		{"Id":"unavailable","Compliance":{"Status":"NOT_AVAILABLE"}},
+		{"Id":"failed","Compliance":{"Status":"FAILED"}},
+		{"Id":"warning","Compliance":{"Status":"WARNING"}},
+		{"Id":"guardduty","ProductName":"GuardDuty"}
+	]`), &findings))
+
+	var open []string
+	for _, finding := range common.OpenSecurityFindings(findings) {
+		open = append(open, finding.ID)
+	}
+	assert.Equal(t, []string{"failed", "warning", "guardduty"}, open)
+}
@@ -0,0 +1,119 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBackupScheduleIntervalHours checks the gap between runs is read from AWS Backup cron schedules
+func TestBackupScheduleIntervalHours(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	for schedule, expected := range map[string]int{
+		"cron(0 5 * * ? *)":       24,
+		"cron(0 5,17 * * ? *)":    12,
+		"cron(0 */6 * * ? *)":     6,
+		"cron(0 0/12 * * ? *)":    12,
+		"cron(0 * * * ? *)":       1,
+		"cron(0 5 ? * SUN *)":     24 * 7,
+		"cron(0 5 1 * ? *)":       24 * 31,
+		"cron(0 5 1 JAN ? *)":     24 * 366,
+		"cron(30 2 ? * * *)":      24,
+		"cron(0 5 ? * MON-FRI *)": 24 * 7,
+	} {
+		interval, err := common.BackupScheduleIntervalHours(schedule)
+		require.NoError(t, err, schedule)
+		assert.Equal(t, expected, interval, schedule)
+	}
+
+	for _, schedule := range []string{"rate(1 day)", "cron(0 5 * * ?)", "cron(0 */0 * * ? *)"} {
+		_, err := common.BackupScheduleIntervalHours(schedule)
+		assert.Error(t, err, schedule)
+	}
+}
+
+// TestBackupAuditChecks runs the backup audit over a plan, selection, vault and database done right and the same
+// done wrong
+func TestBackupAuditChecks(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	resource := func(resourceType, name string, attributes map[string]interface{}) *tfjson.StateResource {
+		return &tfjson.StateResource{Address: resourceType + "." + name, Type: resourceType, Name: name,
+			AttributeValues: attributes}
+	}
+	rule := func(name, schedule string, lifecycle ...interface{}) map[string]interface{} {
+		return map[string]interface{}{"rule_name": name, "schedule": schedule, "lifecycle": lifecycle}
+	}
+	lifecycle := func(coldStorageAfter, deleteAfter float64) map[string]interface{} {
+		return map[string]interface{}{"cold_storage_after": coldStorageAfter, "delete_after": deleteAfter}
+	}
+	backupTag := map[string]interface{}{common.BackupSelectionTag.Key: common.BackupSelectionTag.Value}
+
+	audit := &common.AuditContext{Resources: []*tfjson.StateResource{
+		resource("aws_backup_plan", "daily", map[string]interface{}{"rule": []interface{}{
+			rule("daily", "cron(0 5 * * ? *)", lifecycle(0, 35)),
+			rule("archive", "cron(0 5 ? * SUN *)", lifecycle(30, 365)),
+		}}),
+		resource("aws_backup_plan", "weekly", map[string]interface{}{"rule": []interface{}{
+			rule("weekly", "cron(0 5 ? * SUN *)", lifecycle(0, 35)),
+		}}),
+		resource("aws_backup_plan", "sloppy", map[string]interface{}{"rule": []interface{}{
+			rule("short", "cron(0 5 * * ? *)", lifecycle(0, 3)),
+			rule("cold", "cron(0 5 * * ? *)", lifecycle(30, 60)),
+			rule("forever", "cron(0 5 * * ? *)"),
+		}}),
+		resource("aws_backup_selection", "tagged", map[string]interface{}{"selection_tag": []interface{}{
+			map[string]interface{}{"type": "STRINGEQUALS", "key": "Backup", "value": "true"},
+		}}),
+		resource("aws_backup_selection", "conditions", map[string]interface{}{"condition": []interface{}{
+			map[string]interface{}{"string_equals": []interface{}{
+				map[string]interface{}{"key": "aws:ResourceTag/Backup", "value": "true"},
+			}},
+		}}),
+		resource("aws_backup_selection", "listed", map[string]interface{}{
+			"resources": []interface{}{"arn:aws:rds:us-east-1:123456789012:db:coalition-db"},
+		}),
+		resource("aws_backup_vault", "encrypted", map[string]interface{}{
+			"kms_key_arn": "arn:aws:kms:us-east-1:123456789012:key/abc",
+		}),
+		resource("aws_backup_vault", "default_key", map[string]interface{}{"kms_key_arn": ""}),
+		resource("aws_db_instance", "tagged", map[string]interface{}{"tags": backupTag}),
+		resource("aws_db_instance", "default_tags", map[string]interface{}{"tags_all": backupTag}),
+		resource("aws_db_instance", "untagged", map[string]interface{}{}),
+	}}
+
+	failed := map[string]bool{}
+	for _, finding := range common.RunAudit(audit, common.BackupAuditChecks()...) {
+		if !finding.Passed && !finding.Skipped {
+			failed[finding.Control+" "+finding.Resource] = true
+		}
+	}
+
+	assert.Equal(t, map[string]bool{
+		"Backup-Plan-Frequency aws_backup_plan.weekly":         true,
+		"Backup-Rule-Retention aws_backup_plan.sloppy/short":   true,
+		"Backup-Rule-Retention aws_backup_plan.sloppy/cold":    true,
+		"Backup-Rule-Retention aws_backup_plan.sloppy/forever": true,
+		"Backup-Selection-Tag aws_backup_selection.listed":     true,
+		"Encryption-At-Rest aws_backup_vault.default_key":      true,
+		"Backup-RDS-Coverage aws_db_instance.untagged":         true,
+	}, failed)
+}
+
+// TestRDSBackupCoverageWithoutBackupPlan checks databases are not flagged before any backup plan selects by tag
+func TestRDSBackupCoverageWithoutBackupPlan(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	database := &tfjson.StateResource{Address: "aws_db_instance.main", Type: "aws_db_instance",
+		AttributeValues: map[string]interface{}{}}
+	findings := common.CheckRDSBackupCoverage(database,
+		&common.AuditContext{Resources: []*tfjson.StateResource{database}})
+
+	require.Len(t, findings, 1)
+	assert.True(t, findings[0].Skipped)
+}
@@ -4,9 +4,64 @@ import (
 	"testing"
 
 	"terraform-tests/common"
+	"terraform-tests/keys"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestBastionModuleValidation runs validation-only tests that don't require AWS credentials
 func TestBastionModuleValidation(t *testing.T) {
 	common.ValidateModuleStructure(t, "bastion")
 }
+
+func TestBastionModulePlansHardenedInstance(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	// Tests never rely on a key pair already existing in the account
+	testVars := map[string]interface{}{}
+	keys.GenerateEphemeralKeyPair(t).SetBastionVars(testVars)
+
+	_, terraformOptions := common.SetupModuleTest(t, "bastion", testVars)
+
+	plan := common.PlanOnly(t, terraformOptions)
+
+	instance, exists := plan.ResourcePlannedValuesMap["aws_instance.bastion"]
+	require.True(t, exists, "Bastion instance should be planned")
+
+	metadataOptions := instance.AttributeValues["metadata_options"].([]interface{})
+	require.Len(t, metadataOptions, 1, "Bastion should configure instance metadata options")
+	assert.Equal(t, "required", metadataOptions[0].(map[string]interface{})["http_tokens"],
+		"Bastion should require IMDSv2")
+
+	rootVolumes := instance.AttributeValues["root_block_device"].([]interface{})
+	require.Len(t, rootVolumes, 1)
+	assert.Equal(t, true, rootVolumes[0].(map[string]interface{})["encrypted"], "Root volume should be encrypted")
+
+	// Session Manager is the only permission the instance role grants
+	attachments := common.GetPlannedResourcesByType(plan, "aws_iam_role_policy_attachment")
+	require.Len(t, attachments, 1)
+	assert.Contains(t, common.BastionAllowedPolicies, common.GetPlannedStringAttribute(attachments[0], "policy_arn"))
+	assert.Empty(t, common.GetPlannedResourcesByType(plan, "aws_iam_role_policy"), "Bastion role should not have inline policies")
+}
+
+func TestBastionModulePlansEphemeralKeyPair(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	keyPair := keys.GenerateEphemeralKeyPair(t)
+	testVars := map[string]interface{}{}
+	keyPair.SetBastionVars(testVars)
+
+	_, terraformOptions := common.SetupModuleTest(t, "bastion", testVars)
+
+	plan := common.PlanOnly(t, terraformOptions)
+
+	planned, exists := plan.ResourcePlannedValuesMap["aws_key_pair.bastion[0]"]
+	require.True(t, exists, "Key pair should be planned when create_new_key_pair is true")
+	assert.Equal(t, keyPair.Name, planned.AttributeValues["key_name"])
+	assert.Equal(t, keyPair.PublicKey, planned.AttributeValues["public_key"])
+
+	instance := plan.ResourcePlannedValuesMap["aws_instance.bastion"]
+	require.NotNil(t, instance)
+	assert.Equal(t, keyPair.Name, instance.AttributeValues["key_name"], "Bastion should launch with the generated key")
+}
@@ -0,0 +1,74 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBlueGreenAuditChecks runs the blue/green audit over a service and deployment group done right and a
+// service whose deployment group shifts traffic at once, without rollback, behind a single listener
+func TestBlueGreenAuditChecks(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	resource := func(resourceType, name string, attributes map[string]interface{}) *tfjson.StateResource {
+		return &tfjson.StateResource{Address: resourceType + "." + name, Type: resourceType, Name: name,
+			AttributeValues: attributes}
+	}
+	block := func(attributes map[string]interface{}) []interface{} {
+		return []interface{}{attributes}
+	}
+	listenerPair := func(production, test string) []interface{} {
+		return block(map[string]interface{}{"target_group_pair_info": block(map[string]interface{}{
+			"prod_traffic_route": block(map[string]interface{}{"listener_arns": []interface{}{production}}),
+			"test_traffic_route": block(map[string]interface{}{"listener_arns": []interface{}{test}}),
+			"target_group": []interface{}{
+				map[string]interface{}{"name": "coalition-blue"}, map[string]interface{}{"name": "coalition-green"},
+			},
+		})})
+	}
+
+	audit := &common.AuditContext{Resources: []*tfjson.StateResource{
+		resource("aws_codedeploy_app", "api", map[string]interface{}{"name": "coalition-api", "compute_platform": "ECS"}),
+		resource("aws_ecs_service", "api", map[string]interface{}{
+			"name":                  "coalition-api",
+			"deployment_controller": block(map[string]interface{}{"type": "CODE_DEPLOY"}),
+		}),
+		resource("aws_codedeploy_deployment_group", "api", map[string]interface{}{
+			"app_name":               "coalition-api",
+			"deployment_config_name": "CodeDeployDefault.ECSCanary10Percent5Minutes",
+			"ecs_service":            block(map[string]interface{}{"service_name": "coalition-api"}),
+			"deployment_style": block(map[string]interface{}{
+				"deployment_option": "WITH_TRAFFIC_CONTROL", "deployment_type": "BLUE_GREEN"}),
+			"auto_rollback_configuration": block(map[string]interface{}{
+				"enabled": true, "events": []interface{}{"DEPLOYMENT_FAILURE"}}),
+			"load_balancer_info": listenerPair("arn:listener/https", "arn:listener/test"),
+		}),
+		resource("aws_ecs_service", "worker", map[string]interface{}{"name": "coalition-worker"}),
+		resource("aws_codedeploy_deployment_group", "worker", map[string]interface{}{
+			"app_name":               "coalition-api",
+			"deployment_config_name": "CodeDeployDefault.ECSAllAtOnce",
+			"ecs_service":            block(map[string]interface{}{"service_name": "coalition-worker"}),
+			"deployment_style": block(map[string]interface{}{
+				"deployment_option": "WITH_TRAFFIC_CONTROL", "deployment_type": "BLUE_GREEN"}),
+			"load_balancer_info": listenerPair("arn:listener/https", "arn:listener/https"),
+		}),
+	}}
+
+	failed := map[string]bool{}
+	for _, finding := range common.RunAudit(audit, common.BlueGreenAuditChecks()...) {
+		if !finding.Passed && !finding.Skipped {
+			failed[finding.Control+" "+finding.Resource] = true
+		}
+	}
+
+	assert.Equal(t, map[string]bool{
+		"BlueGreen-ECS-Controller aws_ecs_service.worker":                true,
+		"BlueGreen-Traffic-Shift aws_codedeploy_deployment_group.worker": true,
+		"BlueGreen-Auto-Rollback aws_codedeploy_deployment_group.worker": true,
+		"BlueGreen-Listeners aws_codedeploy_deployment_group.worker":     true,
+	}, failed)
+}
@@ -0,0 +1,63 @@
+package modules
+
+import (
+	"net"
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestVPCCIDRBlocksFitTheVPC checks every test subnet lies inside the test VPC and no two subnets overlap, which
+// the networking module's apply would otherwise reject partway through
+func TestVPCCIDRBlocksFitTheVPC(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	cidrBlocks := common.GetVPCCIDRBlocks()
+	_, vpc, err := net.ParseCIDR(cidrBlocks["vpc_cidr"])
+	require.NoError(t, err)
+
+	subnets := map[string]*net.IPNet{}
+	for name, cidr := range cidrBlocks {
+		if name == "vpc_cidr" {
+			continue
+		}
+		_, subnet, err := net.ParseCIDR(cidr)
+		require.NoError(t, err, "%s is not a CIDR block", name)
+		assert.Equal(t, cidr, subnet.String(), "%s should be written as its network address", name)
+		assert.True(t, vpc.Contains(subnet.IP), "%s %s is outside the VPC %s", name, cidr, vpc)
+		subnets[name] = subnet
+	}
+	assert.Len(t, subnets, 6)
+
+	for name, subnet := range subnets {
+		for otherName, other := range subnets {
+			if name < otherName {
+				assert.False(t, subnet.Contains(other.IP) || other.Contains(subnet.IP), "%s %s overlaps %s %s",
+					name, subnet, otherName, other)
+			}
+		}
+	}
+}
+
+// TestTestVarsCopyCIDRBlocks checks the test variable helpers include every CIDR block, and each call returns its
+// own map, so a test changing its variables does not change another's
+func TestTestVarsCopyCIDRBlocks(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	cidrBlocks := common.GetVPCCIDRBlocks()
+
+	networkingVars := common.GetNetworkingTestVars()
+	integrationVars := common.GetIntegrationTestVars()
+	for name, cidr := range cidrBlocks {
+		assert.Equal(t, cidr, networkingVars[name])
+		assert.Equal(t, cidr, integrationVars[name])
+	}
+	assert.Len(t, networkingVars, len(cidrBlocks), "Networking vars should only hold the CIDR blocks")
+	assert.Equal(t, false, integrationVars["prevent_destroy"], "Integration tests must be able to destroy")
+
+	networkingVars["vpc_cidr"] = "172.16.0.0/16"
+	assert.Equal(t, cidrBlocks["vpc_cidr"], common.GetNetworkingTestVars()["vpc_cidr"])
+}
@@ -0,0 +1,72 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTrailProblems checks which trails count as auditing a region
+func TestTrailProblems(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	audited := common.Trail{Name: "org", HomeRegion: "us-west-2", IsMultiRegionTrail: true,
+		LogFileValidationEnabled: true, IsLogging: true, LogsManagementWrites: true}
+	assert.Empty(t, common.TrailProblems(audited, "us-east-1"))
+
+	regional := audited
+	regional.IsMultiRegionTrail = false
+	assert.Equal(t, []string{"trail only covers us-west-2"}, common.TrailProblems(regional, "us-east-1"))
+	assert.Empty(t, common.TrailProblems(regional, "us-west-2"))
+
+	stopped := common.Trail{HomeRegion: "us-east-1"}
+	assert.Len(t, common.TrailProblems(stopped, "us-east-1"), 3)
+}
+
+// TestLogsManagementWrites checks management writes are detected with basic and advanced event selectors
+func TestLogsManagementWrites(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	for name, tc := range map[string]struct {
+		selectors string
+		expected  bool
+	}{
+		"basic all": {
+			`{"EventSelectors":[{"ReadWriteType":"All","IncludeManagementEvents":true}]}`, true},
+		"basic write only": {
+			`{"EventSelectors":[{"ReadWriteType":"WriteOnly","IncludeManagementEvents":true}]}`, true},
+		"basic read only": {
+			`{"EventSelectors":[{"ReadWriteType":"ReadOnly","IncludeManagementEvents":true}]}`, false},
+		"basic data only": {
+			`{"EventSelectors":[{"ReadWriteType":"All","IncludeManagementEvents":false}]}`, false},
+		"advanced management": {
+			`{"AdvancedEventSelectors":[{"FieldSelectors":[{"Field":"eventCategory","Equals":["Management"]}]}]}`,
+			true},
+		"advanced management reads": {
+			`{"AdvancedEventSelectors":[{"FieldSelectors":[{"Field":"eventCategory","Equals":["Management"]},` +
+				`{"Field":"readOnly","Equals":["true"]}]}]}`, false},
+		"advanced data": {
+			`{"AdvancedEventSelectors":[{"FieldSelectors":[{"Field":"eventCategory","Equals":["Data"]}]}]}`, false},
+	} {
+		assert.Equal(t, tc.expected, common.LogsManagementWrites(tc.selectors), name)
+	}
+}
+
+// TestFindCloudTrailEvent checks events are matched by name and by the contents of their records
+func TestFindCloudTrailEvent(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	events := []common.CloudTrailEvent{
+		{EventID: "1", EventName: "CreateTags", CloudTrailEvent: `{"requestParameters":"coalition-test-ab12"}`},
+		{EventID: "2", EventName: "CreateVpc", CloudTrailEvent: `{"requestParameters":"coalition-test-cd34"}`},
+		{EventID: "3", EventName: "CreateVpc", CloudTrailEvent: `{"requestParameters":"coalition-test-ab12"}`},
+	}
+
+	found := common.FindCloudTrailEvent(events, "CreateVpc", "coalition-test-ab12")
+	require.NotNil(t, found)
+	assert.Equal(t, "3", found.EventID)
+	assert.Nil(t, common.FindCloudTrailEvent(events, "DeleteVpc"))
+}
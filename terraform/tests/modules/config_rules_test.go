@@ -0,0 +1,61 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestConfigComplianceFindings checks AWS Config evaluations are matched to the applied resources by ID, ignoring
+// other resources in the account and reporting unevaluated ones as skipped
+func TestConfigComplianceFindings(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	state := &common.AuditContext{Resources: []*tfjson.StateResource{
+		{Address: "aws_security_group.bastion", Type: "aws_security_group",
+			AttributeValues: map[string]interface{}{"id": "sg-bastion"}},
+		{Address: "aws_security_group.db", Type: "aws_security_group",
+			AttributeValues: map[string]interface{}{"id": "sg-db"}},
+		{Address: "aws_security_group.lambda", Type: "aws_security_group",
+			AttributeValues: map[string]interface{}{"id": "sg-lambda"}},
+		{Address: "aws_s3_bucket.static", Type: "aws_s3_bucket",
+			AttributeValues: map[string]interface{}{"id": "coalition-test-static-assets"}},
+		{Address: "aws_instance.bastion", Type: "aws_instance", AttributeValues: map[string]interface{}{
+			"id":                "i-bastion",
+			"root_block_device": []interface{}{map[string]interface{}{"volume_id": "vol-root"}},
+		}},
+	}}
+	resources := common.ConfigResources(state)
+	assert.Equal(t, common.ConfigResource{Type: "AWS::EC2::Volume", Address: "aws_instance.bastion/root_block_device"},
+		resources["vol-root"])
+	assert.NotContains(t, resources, "i-bastion")
+
+	evaluations := []common.ConfigEvaluation{
+		{ResourceType: "AWS::EC2::SecurityGroup", ResourceID: "sg-bastion", ComplianceType: "NON_COMPLIANT"},
+		{ResourceType: "AWS::EC2::SecurityGroup", ResourceID: "sg-db", ComplianceType: "COMPLIANT"},
+		{ResourceType: "AWS::EC2::SecurityGroup", ResourceID: "sg-someone-else", ComplianceType: "NON_COMPLIANT"},
+	}
+	restrictedSSH := common.ConfigRules[1]
+	assert.Equal(t, "restricted-ssh", restrictedSSH.Name)
+	findings := common.ConfigComplianceFindings(restrictedSSH, evaluations, resources)
+
+	outcomes := map[string]string{}
+	for _, finding := range findings {
+		switch {
+		case finding.Skipped:
+			outcomes[finding.Resource] = "skipped"
+		case finding.Passed:
+			outcomes[finding.Resource] = "passed"
+		default:
+			outcomes[finding.Resource] = "failed"
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"aws_security_group.bastion": "failed",
+		"aws_security_group.db":      "passed",
+		"aws_security_group.lambda":  "skipped",
+	}, outcomes)
+}
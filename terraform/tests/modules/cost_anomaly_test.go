@@ -0,0 +1,71 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseThresholdExpression reads single, And and Or threshold expressions and rejects ones that do not
+// compare an impact at or above a value
+func TestParseThresholdExpression(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	absolute := `{"Dimensions":{"Key":"ANOMALY_TOTAL_IMPACT_ABSOLUTE","Values":["5"],` +
+		`"MatchOptions":["GREATER_THAN_OR_EQUAL"]}}`
+	percentage := `{"Dimensions":{"Key":"ANOMALY_TOTAL_IMPACT_PERCENTAGE","Values":["20"],` +
+		`"MatchOptions":["GREATER_THAN_OR_EQUAL"]}}`
+
+	threshold, err := common.ParseThresholdExpression(absolute)
+	require.NoError(t, err)
+	assert.Equal(t, common.AnomalyAlertThreshold, threshold)
+
+	threshold, err = common.ParseThresholdExpression(`{"And":[` + absolute + `,` + percentage + `]}`)
+	require.NoError(t, err)
+	assert.Equal(t, common.AnomalyThreshold{AbsoluteUSD: 5, Percentage: 20, Operator: "And"}, threshold)
+
+	threshold, err = common.ParseThresholdExpression(`{"Or":[` + absolute + `,` + percentage + `]}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Or", threshold.Operator)
+
+	for _, invalid := range []string{
+		`{"Dimensions":{"Key":"ANOMALY_TOTAL_IMPACT_ABSOLUTE","Values":["5"],"MatchOptions":["LESS_THAN"]}}`,
+		`{"Dimensions":{"Key":"ANOMALY_TOTAL_IMPACT_ABSOLUTE","Values":["five"],` +
+			`"MatchOptions":["GREATER_THAN_OR_EQUAL"]}}`,
+		`{"Dimensions":{"Key":"SERVICE","Values":["5"],"MatchOptions":["GREATER_THAN_OR_EQUAL"]}}`,
+		`{"Tags":{"Key":"Project"}}`,
+		`5`,
+	} {
+		_, err := common.ParseThresholdExpression(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+// TestAnomalyThresholdAlerts simulates anomalies against each kind of threshold. A percentage is of the expected
+// spend, so the same impact alerts on a small bill and not on a large one.
+func TestAnomalyThresholdAlerts(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	intent := common.AnomalyAlertThreshold
+	assert.True(t, intent.Alerts(5, 1000))
+	assert.False(t, intent.Alerts(4.99, 1))
+
+	percentage := common.AnomalyThreshold{Percentage: 20}
+	assert.True(t, percentage.Alerts(5, 20))
+	assert.False(t, percentage.Alerts(5, 100))
+	assert.False(t, percentage.Alerts(5, 0), "Without expected spend there is no percentage to compare")
+
+	both := common.AnomalyThreshold{AbsoluteUSD: 5, Percentage: 20, Operator: "And"}
+	assert.True(t, both.Alerts(10, 40))
+	assert.False(t, both.Alerts(10, 100))
+	assert.False(t, both.Alerts(4, 10))
+
+	either := both
+	either.Operator = "Or"
+	assert.True(t, either.Alerts(10, 100))
+	assert.True(t, either.Alerts(4, 10))
+	assert.False(t, either.Alerts(4, 100))
+}
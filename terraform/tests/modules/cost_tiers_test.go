@@ -0,0 +1,15 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+)
+
+// TestModuleDefaultsStayInCheapestTiers reads variable defaults straight from the Terraform sources,
+// so it needs no AWS credentials and runs in short mode
+func TestModuleDefaultsStayInCheapestTiers(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	common.AssertVariableDefaultTiers(t, "../..", common.CostTierDefaults)
+}
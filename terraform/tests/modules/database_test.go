@@ -5,9 +5,15 @@ import (
 	"testing"
 
 	"terraform-tests/common"
+	"terraform-tests/fixtures"
+	"terraform-tests/tfopts"
+	"terraform-tests/tfout"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestDatabaseModuleValidation runs validation-only tests that don't require AWS credentials
@@ -15,34 +21,45 @@ func TestDatabaseModuleValidation(t *testing.T) {
 	common.ValidateModuleStructure(t, "database")
 }
 
+// withDatabaseFixtures places the database in the shared fixture VPC's subnets and database security group, which
+// its subnet group and instance must reference by real IDs
+func withDatabaseFixtures(t *testing.T, vars map[string]interface{}) map[string]interface{} {
+	vpc := fixtures.NewVPC(t)
+	vars["db_subnet_ids"] = vpc.SubnetIDs
+	vars["db_security_group_id"] = fixtures.NewSecurityGroups(t, vpc).DBID
+	return vars
+}
+
 func TestDatabaseModuleCreatesRDSInstance(t *testing.T) {
-	testConfig, terraformOptions := common.SetupModuleTest(t, "database", common.GetDefaultDatabaseTestVars())
+	common.RequireTier(t, common.TierApply)
 
-	terraform.InitAndApply(t, terraformOptions)
+	testVars := withDatabaseFixtures(t, common.GetDefaultDatabaseTestVars())
+	testConfig, options := common.SetupModuleTest(t, "database", testVars)
+	terraformOptions := options.Options
 
-	// Validate RDS instance outputs
-	dbInstanceID := common.ValidateTerraformOutput(t, terraformOptions, "db_instance_id")
-	common.ValidateTerraformOutput(t, terraformOptions, "db_instance_endpoint")
+	common.ApplyAndValidate(t, options,
+		common.Outputs("db_instance_id", "db_instance_endpoint"),
+	)
 
-	dbInstanceName := terraform.Output(t, terraformOptions, "db_instance_name")
-	dbInstancePort := terraform.Output(t, terraformOptions, "db_instance_port")
+	// Validate RDS instance outputs
+	dbInstanceID := tfout.Output[string](t, terraformOptions, "db_instance_id")
+	dbInstanceName := tfout.Output[string](t, terraformOptions, "db_instance_name")
+	dbInstancePort := tfout.Output[int](t, terraformOptions, "db_instance_port")
 
 	assert.Equal(t, "testdb", dbInstanceName)
-	assert.Equal(t, "5432", dbInstancePort)
+	assert.Equal(t, 5432, dbInstancePort)
 
 	// Validate instance naming
-	expectedInstanceID := fmt.Sprintf("%s-postgres", testConfig.Prefix)
+	expectedInstanceID := fmt.Sprintf("%s-db", testConfig.Prefix)
 	assert.Equal(t, expectedInstanceID, dbInstanceID)
 }
 
 func TestDatabaseModuleCreatesSubnetGroup(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/database")
+	testConfig := tfopts.NewTestConfig("../../modules/database")
 
 	testVars := map[string]interface{}{
-		"db_subnet_ids":              []string{"subnet-db1", "subnet-db2"},
-		"db_security_group_id":       "sg-database123",
 		"db_allocated_storage":       20,
 		"db_engine_version":          "16.9",
 		"db_instance_class":          "db.t4g.micro",
@@ -55,16 +72,16 @@ func TestDatabaseModuleCreatesSubnetGroup(t *testing.T) {
 		"auto_setup_database":        false,
 	}
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/database", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/database", withDatabaseFixtures(t, testVars))
+
+	terraformOptions := options.Options
 
-	terraform.InitAndApply(t, terraformOptions)
+	common.ApplyAndValidate(t, options)
 
 	// Validate subnet group
-	subnetGroupName := terraform.Output(t, terraformOptions, "db_subnet_group_name")
-	assert.NotEmpty(t, subnetGroupName)
+	subnetGroupName := tfout.OutputString(t, terraformOptions, "db_subnet_group_name").NotEmpty().Value()
 
-	expectedSubnetGroupName := fmt.Sprintf("%s-db-subnet-group", testConfig.Prefix)
+	expectedSubnetGroupName := fmt.Sprintf("%s-db-subnet", testConfig.Prefix)
 	assert.Equal(t, expectedSubnetGroupName, subnetGroupName)
 
 	// In a real test, you'd validate the subnet group contains the correct subnets
@@ -72,13 +89,11 @@ func TestDatabaseModuleCreatesSubnetGroup(t *testing.T) {
 }
 
 func TestDatabaseModuleCreatesParameterGroup(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/database")
+	testConfig := tfopts.NewTestConfig("../../modules/database")
 
 	testVars := map[string]interface{}{
-		"db_subnet_ids":              []string{"subnet-db1", "subnet-db2"},
-		"db_security_group_id":       "sg-database123",
 		"db_allocated_storage":       20,
 		"db_engine_version":          "16.9",
 		"db_instance_class":          "db.t4g.micro",
@@ -91,27 +106,25 @@ func TestDatabaseModuleCreatesParameterGroup(t *testing.T) {
 		"auto_setup_database":        false,
 	}
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/database", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/database", withDatabaseFixtures(t, testVars))
 
-	terraform.InitAndApply(t, terraformOptions)
+	terraformOptions := options.Options
+
+	common.ApplyAndValidate(t, options)
 
 	// Validate parameter group
-	parameterGroupName := terraform.Output(t, terraformOptions, "db_parameter_group_name")
-	assert.NotEmpty(t, parameterGroupName)
+	parameterGroupName := tfout.OutputString(t, terraformOptions, "db_parameter_group_name").NotEmpty().Value()
 
-	expectedParameterGroupName := fmt.Sprintf("%s-postgres16", testConfig.Prefix)
+	expectedParameterGroupName := fmt.Sprintf("%s-pg-16-test", testConfig.Prefix)
 	assert.Equal(t, expectedParameterGroupName, parameterGroupName)
 }
 
 func TestDatabaseModuleWithSecretsManager(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/database")
+	testConfig := tfopts.NewTestConfig("../../modules/database")
 
 	testVars := map[string]interface{}{
-		"db_subnet_ids":              []string{"subnet-db1", "subnet-db2"},
-		"db_security_group_id":       "sg-database123",
 		"db_allocated_storage":       20,
 		"db_engine_version":          "16.9",
 		"db_instance_class":          "db.t4g.micro",
@@ -124,27 +137,37 @@ func TestDatabaseModuleWithSecretsManager(t *testing.T) {
 		"auto_setup_database":        false,
 	}
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/database", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/database", withDatabaseFixtures(t, testVars))
+
+	terraformOptions := options.Options
 
-	terraform.InitAndApply(t, terraformOptions)
+	common.ApplyAndValidate(t, options)
 
 	// When secrets manager is enabled, password should be managed differently
-	dbInstanceID := terraform.Output(t, terraformOptions, "db_instance_id")
-	assert.NotEmpty(t, dbInstanceID)
+	tfout.OutputString(t, terraformOptions, "db_instance_id").NotEmpty()
+
+	// Validate the master secret was created with the expected name
+	secretArn := tfout.OutputString(t, terraformOptions, "db_master_secret_arn").NotEmpty().Value()
+	assert.Contains(t, secretArn, fmt.Sprintf("secret:%s/database-master", testConfig.Prefix))
+
+	// Validate the secret describes the RDS instance that was created
+	instance := common.GetRDSInstanceById(t, fmt.Sprintf("%s-db", testConfig.Prefix), testConfig.AWSRegion)
+	secret := common.GetDatabaseMasterSecret(t, secretArn, testConfig.AWSRegion)
+
+	assert.Equal(t, *instance.MasterUsername, secret.Username)
+	assert.Equal(t, *instance.DBName, secret.DBName)
+	assert.Equal(t, testVars["db_password"], secret.Password)
 
-	// Validate that the instance was created successfully with secrets manager integration
-	// In a real test, you'd verify the secrets are properly created and linked
+	// Validate the raw master password never surfaces in terraform outputs
+	common.ValidateOutputsDoNotContain(t, terraformOptions, testVars["db_password"].(string))
 }
 
 func TestDatabaseModuleValidatesBackupConfiguration(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/database")
+	testConfig := tfopts.NewTestConfig("../../modules/database")
 
 	testVars := map[string]interface{}{
-		"db_subnet_ids":              []string{"subnet-db1", "subnet-db2"},
-		"db_security_group_id":       "sg-database123",
 		"db_allocated_storage":       20,
 		"db_engine_version":          "16.9",
 		"db_instance_class":          "db.t4g.micro",
@@ -157,14 +180,14 @@ func TestDatabaseModuleValidatesBackupConfiguration(t *testing.T) {
 		"auto_setup_database":        false,
 	}
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/database", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/database", withDatabaseFixtures(t, testVars))
 
-	terraform.InitAndApply(t, terraformOptions)
+	terraformOptions := options.Options
+
+	common.ApplyAndValidate(t, options)
 
 	// Validate the database was created
-	dbInstanceID := terraform.Output(t, terraformOptions, "db_instance_id")
-	assert.NotEmpty(t, dbInstanceID)
+	tfout.OutputString(t, terraformOptions, "db_instance_id").NotEmpty()
 
 	// In a real test, you'd validate backup configuration using AWS SDK:
 	// - backup_retention_period is set correctly
@@ -174,13 +197,11 @@ func TestDatabaseModuleValidatesBackupConfiguration(t *testing.T) {
 }
 
 func TestDatabaseModuleValidatesEncryption(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/database")
+	testConfig := tfopts.NewTestConfig("../../modules/database")
 
 	testVars := map[string]interface{}{
-		"db_subnet_ids":              []string{"subnet-db1", "subnet-db2"},
-		"db_security_group_id":       "sg-database123",
 		"db_allocated_storage":       20,
 		"db_engine_version":          "16.9",
 		"db_instance_class":          "db.t4g.micro",
@@ -193,28 +214,21 @@ func TestDatabaseModuleValidatesEncryption(t *testing.T) {
 		"auto_setup_database":        false,
 	}
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/database", testVars)
-	defer common.CleanupResources(t, terraformOptions)
-
-	terraform.InitAndApply(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/database", withDatabaseFixtures(t, testVars))
 
-	// Validate the database was created
-	dbInstanceID := terraform.Output(t, terraformOptions, "db_instance_id")
-	assert.NotEmpty(t, dbInstanceID)
-
-	// In a real test, you'd validate encryption settings using AWS SDK:
-	// - storage_encrypted is true
-	// - kms_key_id is set appropriately
+	// The instance must be encrypted with the module's customer-managed KMS key
+	common.ApplyAndValidate(t, options,
+		common.Outputs("db_instance_id", "db_kms_key_arn"),
+		common.Encryption(),
+	)
 }
 
 func TestDatabaseModuleValidatesPostGISExtension(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/database")
+	testConfig := tfopts.NewTestConfig("../../modules/database")
 
 	testVars := map[string]interface{}{
-		"db_subnet_ids":              []string{"subnet-db1", "subnet-db2"},
-		"db_security_group_id":       "sg-database123",
 		"db_allocated_storage":       20,
 		"db_engine_version":          "16.9",
 		"db_instance_class":          "db.t4g.micro",
@@ -227,14 +241,14 @@ func TestDatabaseModuleValidatesPostGISExtension(t *testing.T) {
 		"auto_setup_database":        true, // Enable database setup to test PostGIS
 	}
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/database", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/database", withDatabaseFixtures(t, testVars))
+
+	terraformOptions := options.Options
 
-	terraform.InitAndApply(t, terraformOptions)
+	common.ApplyAndValidate(t, options)
 
 	// Validate the database was created
-	dbInstanceID := terraform.Output(t, terraformOptions, "db_instance_id")
-	assert.NotEmpty(t, dbInstanceID)
+	tfout.OutputString(t, terraformOptions, "db_instance_id").NotEmpty()
 
 	// In a real test with actual database connectivity, you'd:
 	// 1. Connect to the database
@@ -244,13 +258,11 @@ func TestDatabaseModuleValidatesPostGISExtension(t *testing.T) {
 }
 
 func TestDatabaseModuleValidatesResourceNaming(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/database")
+	testConfig := tfopts.NewTestConfig("../../modules/database")
 
 	testVars := map[string]interface{}{
-		"db_subnet_ids":              []string{"subnet-db1", "subnet-db2"},
-		"db_security_group_id":       "sg-database123",
 		"db_allocated_storage":       20,
 		"db_engine_version":          "16.9",
 		"db_instance_class":          "db.t4g.micro",
@@ -263,24 +275,20 @@ func TestDatabaseModuleValidatesResourceNaming(t *testing.T) {
 		"auto_setup_database":        false,
 	}
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/database", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/database", withDatabaseFixtures(t, testVars))
 
-	terraform.InitAndApply(t, terraformOptions)
+	terraformOptions := options.Options
 
 	// Validate resource naming conventions
-	dbInstanceID := terraform.Output(t, terraformOptions, "db_instance_id")
-	dbSubnetGroupName := terraform.Output(t, terraformOptions, "db_subnet_group_name")
-	dbParameterGroupName := terraform.Output(t, terraformOptions, "db_parameter_group_name")
-
-	// Validate naming follows conventions
-	common.ValidateResourceNaming(t, dbInstanceID, testConfig.Prefix, "postgres")
-	common.ValidateResourceNaming(t, dbSubnetGroupName, testConfig.Prefix, "db-subnet-group")
-	common.ValidateResourceNaming(t, dbParameterGroupName, testConfig.Prefix, "postgres16")
+	common.ApplyAndValidate(t, options, common.NamingConventions(testConfig.Prefix))
+
+	// With prevent_destroy off, the instance should use the testing parameter group
+	dbParameterGroupName := tfout.Output[string](t, terraformOptions, "db_parameter_group_name")
+	common.ValidateResourceNaming(t, dbParameterGroupName, testConfig.Prefix, "-pg-16-test")
 }
 
 func TestDatabaseModuleValidatesStorageConfiguration(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
 	// Test different storage configurations
 	testCases := []struct {
@@ -301,13 +309,9 @@ func TestDatabaseModuleValidatesStorageConfiguration(t *testing.T) {
 				return
 			}
 
-			common.SkipIfShortTest(t)
-
-			testConfig := common.NewTestConfig("../../modules/database")
+			testConfig := tfopts.NewTestConfig("../../modules/database")
 
 			testVars := map[string]interface{}{
-				"db_subnet_ids":              []string{"subnet-db1", "subnet-db2"},
-				"db_security_group_id":       "sg-database123",
 				"db_allocated_storage":       tc.allocatedStorage,
 				"db_engine_version":          "16.9",
 				"db_instance_class":          "db.t4g.micro",
@@ -320,16 +324,205 @@ func TestDatabaseModuleValidatesStorageConfiguration(t *testing.T) {
 				"auto_setup_database":        false,
 			}
 
-			terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/database", testVars)
-			defer common.CleanupResources(t, terraformOptions)
+			options := testConfig.GetModuleTerraformOptions("../../modules/database", withDatabaseFixtures(t, testVars))
+
+			terraformOptions := options.Options
 
-			terraform.InitAndApply(t, terraformOptions)
+			common.ApplyAndValidate(t, options)
 
 			// Validate the database was created with correct storage
-			dbInstanceID := terraform.Output(t, terraformOptions, "db_instance_id")
-			assert.NotEmpty(t, dbInstanceID)
+			tfout.OutputString(t, terraformOptions, "db_instance_id").NotEmpty()
 
 			// In a real test, you'd validate the allocated storage using AWS SDK
 		})
 	}
 }
+
+func TestDatabaseModuleValidatesMonitoringConfiguration(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	testVars := common.GetDefaultDatabaseTestVars()
+	testVars["db_max_allocated_storage"] = 50
+	testVars["db_performance_insights_enabled"] = true
+	testVars["db_performance_insights_retention_period"] = 7
+	testVars["db_monitoring_interval"] = 60
+
+	testConfig, options := common.SetupModuleTest(t, "database", withDatabaseFixtures(t, testVars))
+
+	common.ApplyAndValidate(t, options)
+
+	instance := common.GetRDSInstanceById(t, fmt.Sprintf("%s-db", testConfig.Prefix), testConfig.AWSRegion)
+
+	common.ValidateRDSStorageAutoscaling(t, instance, 50)
+	common.ValidateRDSPerformanceInsights(t, instance, 7)
+	common.ValidateRDSEnhancedMonitoring(t, instance, 60)
+}
+
+// TestDatabaseModuleDefaultsToFreeMonitoring plans the module with its defaults and checks Performance Insights and
+// enhanced monitoring are off, so only the root stack, which opts in, pays for them
+func TestDatabaseModuleDefaultsToFreeMonitoring(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	testConfig := tfopts.NewTestConfig("../../modules/database")
+	terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly("../../modules/database",
+		common.GetDefaultDatabaseTestVars())
+	plan := common.PlanOnly(t, terraformOptions)
+
+	instance, exists := plan.ResourcePlannedValuesMap["aws_db_instance.postgres"]
+	require.True(t, exists, "Plan should contain aws_db_instance.postgres")
+	assert.Equal(t, false, instance.AttributeValues["performance_insights_enabled"])
+	assert.EqualValues(t, 0, instance.AttributeValues["monitoring_interval"])
+	assert.Zero(t, common.CountPlannedInstances(plan, "aws_iam_role.rds_monitoring"),
+		"No enhanced monitoring role should be planned")
+}
+
+func TestDatabaseModuleEnforcesSSL(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	testVars := common.GetDefaultDatabaseTestVars()
+
+	testConfig, options := common.SetupModuleTest(t, "database", withDatabaseFixtures(t, testVars))
+
+	common.ApplyAndValidate(t, options)
+
+	// The testing parameter group must be attached and force SSL for every client
+	parameterGroupName := fmt.Sprintf("%s-pg-16-test", testConfig.Prefix)
+	instance := common.GetRDSInstanceById(t, fmt.Sprintf("%s-db", testConfig.Prefix), testConfig.AWSRegion)
+
+	common.ValidateRDSParameterGroupAttached(t, instance, parameterGroupName)
+	assert.Equal(t, "1", common.GetDBParameterValue(t, parameterGroupName, "rds.force_ssl", testConfig.AWSRegion))
+}
+
+// TestDatabaseModulePlansConnectionsAlarm plans the module and checks the instance has an alarm on its
+// DatabaseConnections that fires before the instance class's max_connections are used up
+func TestDatabaseModulePlansConnectionsAlarm(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	testConfig := tfopts.NewTestConfig("../../modules/database")
+	terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly("../../modules/database",
+		common.GetDefaultDatabaseTestVars())
+	plan := common.PlanOnly(t, terraformOptions)
+
+	common.ReportAuditFindings(t, common.RunAudit(common.NewPlanAuditContext(plan),
+		common.DatabaseConnectionsAuditChecks()...))
+}
+
+// isPlannedReplica reports whether a planned instance replicates another, whose identifier may not be known yet
+func isPlannedReplica(audit *common.AuditContext, instance *tfjson.StateResource) bool {
+	return common.GetPlannedStringAttribute(instance, "replicate_source_db") != "" ||
+		audit.IsUnknown(instance, "replicate_source_db")
+}
+
+// TestDatabaseModulePlansReadReplica plans the module with a read replica and checks there is exactly one, named by
+// the convention and sized like the primary
+func TestDatabaseModulePlansReadReplica(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	if !common.ModuleSupportsReadReplica("../../modules/database") {
+		t.Skipf("Database module does not declare %s yet", common.ReadReplicaVariable)
+	}
+
+	testConfig := tfopts.NewTestConfig("../../modules/database")
+	testVars := common.GetDefaultDatabaseTestVars()
+	testVars[common.ReadReplicaVariable] = true
+
+	terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly("../../modules/database", testVars)
+	plan := common.PlanOnly(t, terraformOptions)
+	audit := common.NewPlanAuditContext(plan)
+
+	var replicas []*tfjson.StateResource
+	for _, instance := range common.GetPlannedResourcesByType(plan, "aws_db_instance") {
+		if isPlannedReplica(audit, instance) {
+			replicas = append(replicas, instance)
+		}
+	}
+	require.Len(t, replicas, 1, "Expected one read replica")
+	assert.Equal(t, fmt.Sprintf("%s-db-replica", testConfig.Prefix),
+		common.GetPlannedStringAttribute(replicas[0], "identifier"))
+	assert.Equal(t, common.TestDBInstanceClass, common.GetPlannedStringAttribute(replicas[0], "instance_class"))
+}
+
+// TestDatabaseModuleReadReplicaLifecycle adds a replica to an applied primary, checking the change never replaces
+// the primary, then waits for the replica to replicate and promotes it to a standalone instance
+func TestDatabaseModuleReadReplicaLifecycle(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	if !common.ModuleSupportsReadReplica("../../modules/database") {
+		t.Skipf("Database module does not declare %s yet", common.ReadReplicaVariable)
+	}
+
+	testVars := common.GetDefaultDatabaseTestVars()
+
+	testConfig, options := common.SetupModuleTest(t, "database", withDatabaseFixtures(t, testVars))
+	terraformOptions := options.Options
+
+	common.ApplyAndValidate(t, options)
+
+	// Adding the replica must leave the primary in place
+	terraformOptions.Vars[common.ReadReplicaVariable] = true
+	plan := common.PlanAndShow(t, terraformOptions)
+	common.AssertNoStatefulReplacements(t, plan)
+
+	// Apply the plan that was checked, then go back to applying from variables for the destroy
+	terraform.Apply(t, terraformOptions)
+	terraformOptions.PlanFilePath = ""
+
+	primaryID := fmt.Sprintf("%s-db", testConfig.Prefix)
+	replicaID := fmt.Sprintf("%s-db-replica", testConfig.Prefix)
+
+	replica := common.GetRDSInstanceById(t, replicaID, testConfig.AWSRegion)
+	common.ValidateRDSReadReplica(t, replica, primaryID)
+	common.WaitForRDSReplicationHealthy(t, replicaID, testConfig.AWSRegion, common.MaxReplicaLag)
+
+	promoted := common.PromoteRDSReadReplica(t, replicaID, testConfig.AWSRegion)
+	require.NotNil(t, promoted.Endpoint, "The promoted instance should have an endpoint")
+	assert.Equal(t, int32(5432), aws.ToInt32(promoted.Endpoint.Port))
+	assert.Empty(t, common.GetRDSInstanceById(t, primaryID, testConfig.AWSRegion).ReadReplicaDBInstanceIdentifiers,
+		"The primary should have no replicas once the replica is promoted")
+}
+
+// TestDatabaseModulePlansEngineFamilies plans the module as each engine family it supports and runs the shared
+// plan assertions, which for Aurora include the Serverless v2 capacity range
+func TestDatabaseModulePlansEngineFamilies(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	for _, engine := range common.DatabaseEngines {
+		t.Run(engine.Name, func(t *testing.T) {
+			if !engine.SupportedBy("../../modules/database") {
+				t.Skipf("Database module does not declare %s yet", common.DatabaseEngineVariable)
+			}
+
+			testConfig := tfopts.NewTestConfig("../../modules/database")
+			terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly("../../modules/database", engine.TestVars())
+			common.AssertPlannedDatabase(t, common.PlanOnly(t, terraformOptions), engine)
+		})
+	}
+}
+
+// TestDatabaseModuleEngineFamilies deploys the module as each engine family it supports and runs the shared
+// assertions against what AWS reports, plus the Serverless v2 scaling and cluster endpoints for Aurora
+func TestDatabaseModuleEngineFamilies(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	for _, engine := range common.DatabaseEngines {
+		t.Run(engine.Name, func(t *testing.T) {
+			if !engine.SupportedBy("../../modules/database") {
+				t.Skipf("Database module does not declare %s yet", common.DatabaseEngineVariable)
+			}
+
+			testVars := withDatabaseFixtures(t, engine.TestVars())
+			testConfig, options := common.SetupModuleTest(t, "database", testVars)
+
+			common.ApplyAndValidate(t, options)
+
+			deployment := common.GetDatabaseDeployment(t, engine, fmt.Sprintf("%s-db", testConfig.Prefix),
+				testConfig.AWSRegion)
+			common.ValidateDatabaseDeployment(t, deployment, engine, testConfig.Prefix,
+				testVars["db_security_group_id"].(string), 7)
+
+			if engine.IsAurora() {
+				common.ValidateAuroraServerlessV2(t, deployment, common.TestAuroraMinACU, common.TestAuroraMaxACU)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCheckDatabaseConnectionsAlarm checks an instance needs max_connections pinned in its parameter group, and an
+// alarm on its own DatabaseConnections firing at no more than DBConnectionsAlarmPercent of it
+func TestCheckDatabaseConnectionsAlarm(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	instance := &tfjson.StateResource{Address: "aws_db_instance.postgres", Type: "aws_db_instance",
+		AttributeValues: map[string]interface{}{"identifier": "coalition-db", "parameter_group_name": "coalition-pg-16"}}
+	parameterGroup := func(parameters ...map[string]interface{}) *tfjson.StateResource {
+		values := make([]interface{}, 0, len(parameters))
+		for _, parameter := range parameters {
+			values = append(values, parameter)
+		}
+		return &tfjson.StateResource{Address: "aws_db_parameter_group.postgres", Type: "aws_db_parameter_group",
+			AttributeValues: map[string]interface{}{"name": "coalition-pg-16", "parameter": values}}
+	}
+	forceSSL := map[string]interface{}{"name": "rds.force_ssl", "value": "1"}
+	pinned := parameterGroup(forceSSL, map[string]interface{}{"name": "max_connections", "value": "100"})
+	alarm := func(identifier string, threshold float64) *tfjson.StateResource {
+		return &tfjson.StateResource{Address: "aws_cloudwatch_metric_alarm.db_connections",
+			Type: "aws_cloudwatch_metric_alarm", AttributeValues: map[string]interface{}{
+				"namespace": "AWS/RDS", "metric_name": "DatabaseConnections", "threshold": threshold,
+				"dimensions": map[string]interface{}{"DBInstanceIdentifier": identifier},
+			}}
+	}
+	passed := func(resources ...*tfjson.StateResource) []bool {
+		var outcomes []bool
+		for _, finding := range common.RunAudit(&common.AuditContext{Resources: resources},
+			common.DatabaseConnectionsAuditChecks()...) {
+			outcomes = append(outcomes, finding.Passed)
+		}
+		return outcomes
+	}
+
+	maxConnections, known := common.PlannedMaxConnections(instance, &common.AuditContext{
+		Resources: []*tfjson.StateResource{instance, pinned}})
+	assert.True(t, known)
+	assert.Equal(t, 100, maxConnections)
+
+	assert.Equal(t, []bool{true}, passed(instance, pinned, alarm("coalition-db", 80)))
+	assert.Equal(t, []bool{false}, passed(instance, pinned, alarm("coalition-db", 90)), "Fires too late")
+	assert.Equal(t, []bool{false}, passed(instance, pinned, alarm("other-db", 80)), "Watches another instance")
+	assert.Equal(t, []bool{false}, passed(instance, pinned))
+	assert.Equal(t, []bool{false}, passed(instance, parameterGroup(forceSSL), alarm("coalition-db", 80)),
+		"max_connections is left to the instance class's memory")
+}
+
+// TestCheckSaturationBehavior checks throttling and 503s count as graceful, while 5xx crashes and unanswered
+// requests do not
+func TestCheckSaturationBehavior(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	url := "https://api.example.org/api/campaigns/"
+	assert.True(t, common.CheckSaturationBehavior(url,
+		common.SaturationResult{Codes: map[int]int{200: 900, 429: 50, 503: 10}}).Passed)
+	assert.False(t, common.CheckSaturationBehavior(url,
+		common.SaturationResult{Codes: map[int]int{200: 900, 500: 3}}).Passed, "Django failed to connect")
+	assert.False(t, common.CheckSaturationBehavior(url,
+		common.SaturationResult{Codes: map[int]int{200: 900}, TransportErrors: 1}).Passed)
+	assert.False(t, common.CheckSaturationBehavior(url, common.SaturationResult{Codes: map[int]int{}}).Passed,
+		"No requests were made")
+}
@@ -0,0 +1,67 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/policy"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderDefaultTagsReferences(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	plan := &terraform.PlanStruct{RawPlan: tfjson.Plan{Config: &tfjson.Config{
+		ProviderConfigs: map[string]*tfjson.ProviderConfig{
+			"aws": {Name: "aws", Expressions: map[string]*tfjson.Expression{
+				"region": {ExpressionData: &tfjson.ExpressionData{References: []string{"var.aws_region"}}},
+				"default_tags": {ExpressionData: &tfjson.ExpressionData{NestedBlocks: []map[string]*tfjson.Expression{{
+					"tags": {ExpressionData: &tfjson.ExpressionData{References: []string{"var.tags"}}},
+				}}}},
+			}},
+			"awscc": {Name: "awscc"},
+		},
+	}}}
+
+	references, configured := policy.ProviderDefaultTagsReferences(plan, "aws")
+	assert.True(t, configured)
+	assert.Equal(t, []string{"var.tags"}, references)
+
+	_, configured = policy.ProviderDefaultTagsReferences(plan, "awscc")
+	assert.False(t, configured, "A provider without a default_tags block does not configure them")
+
+	_, configured = policy.ProviderDefaultTagsReferences(&terraform.PlanStruct{}, "aws")
+	assert.False(t, configured, "A plan without its configuration does not configure them")
+}
+
+// TestMissingDefaultTags checks a resource's tags_all must carry every default tag, with the default's value unless
+// the resource sets the tag itself
+func TestMissingDefaultTags(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	defaults := map[string]string{"Project": "coalition", "Environment": "Test"}
+	resource := func(tags, tagsAll map[string]interface{}) *tfjson.StateResource {
+		attributes := map[string]interface{}{"tags": tags}
+		if tagsAll != nil {
+			attributes["tags_all"] = tagsAll
+		}
+		return &tfjson.StateResource{AttributeValues: attributes}
+	}
+
+	assert.Empty(t, policy.MissingDefaultTags(resource(
+		map[string]interface{}{"Name": "vpc"},
+		map[string]interface{}{"Name": "vpc", "Project": "coalition", "Environment": "Test"},
+	), defaults))
+	assert.Empty(t, policy.MissingDefaultTags(resource(
+		map[string]interface{}{"Environment": "coalition-abc123"},
+		map[string]interface{}{"Project": "coalition", "Environment": "coalition-abc123"},
+	), defaults), "A tag the resource sets itself overrides the default")
+
+	assert.Equal(t, []string{"missing default tag Environment", `default tag Project is "other", expected "coalition"`},
+		policy.MissingDefaultTags(resource(nil, map[string]interface{}{"Project": "other"}), defaults))
+	assert.Equal(t, []string{"tags_all is not known at plan time"},
+		policy.MissingDefaultTags(resource(nil, nil), defaults))
+}
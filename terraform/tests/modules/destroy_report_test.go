@@ -0,0 +1,118 @@
+package modules
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"terraform-tests/common"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// destroyOutput is terraform destroy's output for a networking module wedged on its endpoint ENIs
+const destroyOutput = `
+module.networking.aws_vpc_endpoint.interface["logs"]: Destroying... [id=vpce-0a1b2c3d4e5f60718]
+aws_route_table_association.private_a[0]: Destroying... [id=rtbassoc-0123]
+aws_route_table_association.private_a[0]: Destruction complete after 1s
+module.networking.aws_vpc_endpoint.interface["logs"]: Still destroying... [id=vpce-0a1b2c3d4e5f60718, 00m10s elapsed]
+module.networking.aws_vpc_endpoint.interface["logs"]: Destruction complete after 2m35s
+aws_subnet.private_a[0]: Destroying... [id=subnet-0123456789abcdef0]
+aws_subnet.private_a[0]: Still destroying... [id=subnet-0123456789abcdef0, 19m50s elapsed]
+╷
+│ Error: deleting EC2 Subnet (subnet-0123456789abcdef0): operation error EC2: DeleteSubnet, api error
+│ DependencyViolation: The subnet 'subnet-0123456789abcdef0' has dependencies and cannot be deleted.
+╵
+`
+
+func TestParseDestroyProgress(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	assert.Equal(t, []common.ResourceDestroy{
+		{
+			Address:   `module.networking.aws_vpc_endpoint.interface["logs"]`,
+			ID:        "vpce-0a1b2c3d4e5f60718",
+			Duration:  2*time.Minute + 35*time.Second,
+			Completed: true,
+		},
+		{Address: "aws_route_table_association.private_a[0]", ID: "rtbassoc-0123", Duration: time.Second, Completed: true},
+		{Address: "aws_subnet.private_a[0]", ID: "subnet-0123456789abcdef0", Duration: 19*time.Minute + 50*time.Second},
+	}, common.ParseDestroyProgress(destroyOutput))
+}
+
+// TestDestroyReportDiagnostics checks a report counts the attempts that failed on resources still in use, and
+// names the resources that stalled, slowest first
+func TestDestroyReportDiagnostics(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	report := &common.DestroyReport{
+		Duration: 23 * time.Minute,
+		Attempts: []common.DestroyAttempt{
+			{Duration: 20 * time.Minute, Err: errors.New("exit status 1"),
+				DependencyViolations: []string{"subnet-0123456789abcdef0"}},
+			{Duration: 2 * time.Minute},
+		},
+		Resources: common.ParseDestroyProgress(destroyOutput),
+	}
+
+	assert.Equal(t, 1, report.DependencyViolationRetries())
+	stalled := report.Stalled(common.DestroyStallThreshold)
+	require.Len(t, stalled, 1, "The endpoint took less than the threshold and the association completed")
+	assert.Equal(t, "aws_subnet.private_a[0]", stalled[0].Address)
+
+	diagnostics := report.Diagnostics(common.DestroyStallThreshold)
+	assert.Contains(t, diagnostics, "Attempt 1 failed after 20m0s; still in use: subnet-0123456789abcdef0")
+	assert.Contains(t, diagnostics, "aws_subnet.private_a[0] (subnet-0123456789abcdef0) not destroyed after 19m50s")
+	assert.NotContains(t, diagnostics, "rtbassoc-0123")
+}
+
+// TestDestroyStopsWhenTerraformCannotRun checks a destroy that fails on something no retry clears, here a terraform
+// binary that cannot be run, is attempted once instead of backing off through the whole policy
+func TestDestroyStopsWhenTerraformCannotRun(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	terraformOptions := &terraform.Options{
+		TerraformDir:    t.TempDir(),
+		TerraformBinary: "terraform-not-installed",
+		NoColor:         true,
+	}
+	policy := common.DestroyRetryPolicy{MaxAttempts: 4, InitialBackoff: time.Hour, MaxBackoff: time.Hour}
+
+	report, err := common.DestroyWithReportE(t, terraformOptions, policy)
+	require.Error(t, err)
+	var fatal retry.FatalError
+	assert.ErrorAs(t, err, &fatal, "A binary that cannot be run is a fatal error")
+	assert.Len(t, report.Attempts, 1)
+}
+
+// TestDestroyRetriesFailuresWithoutKnownBlocker checks a destroy failing on something no remediation clears, here
+// API throttling, is still retried after backing off
+func TestDestroyRetriesFailuresWithoutKnownBlocker(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	// Fails with throttling on the first run only
+	dir := t.TempDir()
+	script := `#!/bin/sh
+echo run >> "$(dirname "$0")/runs"
+if [ "$(wc -l < "$(dirname "$0")/runs")" -lt 2 ]; then
+  echo "Error: deleting EC2 Internet Gateway (igw-0123): ThrottlingException: Rate exceeded" >&2
+  exit 1
+fi
+echo "Destroy complete! Resources: 0 destroyed."
+`
+	binary := filepath.Join(dir, "terraform")
+	require.NoError(t, os.WriteFile(binary, []byte(script), 0o755))
+
+	terraformOptions := &terraform.Options{TerraformDir: dir, TerraformBinary: binary, NoColor: true}
+	policy := common.DestroyRetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	report, err := common.DestroyWithReportE(t, terraformOptions, policy)
+	require.NoError(t, err)
+	require.Len(t, report.Attempts, 2)
+	assert.Error(t, report.Attempts[0].Err)
+}
@@ -0,0 +1,66 @@
+package modules
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// dnsFailoverScenario puts a primary and a standby in a second region behind Route53 failover records
+const dnsFailoverScenario = "../scenarios/dns-failover"
+
+// TestDNSFailoverScenario deploys the standby to DR_SECONDARY_REGION, fails the primary's health check and checks
+// Route53 answers with the standby within the failover budget. It is opt-in, since it needs a second region.
+func TestDNSFailoverScenario(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	secondaryRegion := os.Getenv("DR_SECONDARY_REGION")
+	if secondaryRegion == "" {
+		t.Skip("Skipping DR scenario - DR_SECONDARY_REGION is not set")
+	}
+
+	testConfig := tfopts.NewTestConfig(dnsFailoverScenario)
+	require.NotEqual(t, testConfig.AWSRegion, secondaryRegion, "DR_SECONDARY_REGION must differ from the primary region")
+
+	// Route53 refuses to host zones under reserved domains such as example.com, so the zone is named after the run
+	// under DR_ZONE_DOMAIN or, by default, is a domain of its own. It is never delegated either way.
+	zoneName := fmt.Sprintf("%s-dr.com", testConfig.UniqueID)
+	if zoneDomain := os.Getenv("DR_ZONE_DOMAIN"); zoneDomain != "" {
+		zoneName = fmt.Sprintf("%s.%s", testConfig.UniqueID, zoneDomain)
+	}
+
+	options := testConfig.GetModuleTerraformOptions(dnsFailoverScenario, map[string]interface{}{
+		"secondary_region": secondaryRegion,
+		"zone_name":        zoneName,
+		"primary_healthy":  true,
+	})
+
+	terraformOptions := options.Options
+
+	common.ApplyAndValidate(t, options,
+		common.Outputs("zone_id", "record_name", "record_ttl", "primary_target", "standby_target"),
+	)
+
+	applied := common.NewAppliedConfiguration(t, terraformOptions)
+	zoneID, recordName := applied.Output("zone_id"), applied.Output("record_name")
+	ttl, err := strconv.Atoi(applied.Output("record_ttl"))
+	require.NoError(t, err)
+	budget := common.FailoverBudget(ttl)
+
+	common.WaitForDNSAnswer(t, zoneID, recordName, "CNAME", applied.Output("primary_target"), budget)
+
+	// Simulate the primary failing
+	terraformOptions.Vars["primary_healthy"] = false
+	terraform.Apply(t, terraformOptions)
+
+	elapsed := common.WaitForDNSAnswer(t, zoneID, recordName, "CNAME", applied.Output("standby_target"), budget)
+	t.Logf("DNS failed over to the standby in %s of a %s budget", elapsed.Round(time.Second), budget)
+}
@@ -0,0 +1,114 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheckHealthCheckEndpoint checks an endpoint health check must poll the API health path over HTTPS and fail
+// within the threshold, and checks without an endpoint are left alone
+func TestCheckHealthCheckEndpoint(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	healthCheck := func(checkType, path string, threshold float64) *tfjson.StateResource {
+		return &tfjson.StateResource{Address: "aws_route53_health_check.api", Type: "aws_route53_health_check",
+			AttributeValues: map[string]interface{}{
+				"type": checkType, "resource_path": path, "failure_threshold": threshold,
+			}}
+	}
+	check := func(resource *tfjson.StateResource) []common.AuditFinding {
+		return common.RunAudit(&common.AuditContext{Resources: []*tfjson.StateResource{resource}},
+			common.CheckHealthCheckEndpoint)
+	}
+
+	findings := check(healthCheck("HTTPS", common.MaintenanceHealthPath, 3))
+	require.Len(t, findings, 1)
+	assert.True(t, findings[0].Passed, findings[0].Detail)
+
+	for _, unhealthy := range []*tfjson.StateResource{
+		healthCheck("HTTP", common.MaintenanceHealthPath, 3),
+		healthCheck("HTTPS", "/", 3),
+		healthCheck("HTTPS", common.MaintenanceHealthPath, 10),
+	} {
+		findings = check(unhealthy)
+		require.Len(t, findings, 1)
+		assert.False(t, findings[0].Passed, "%v should fail", unhealthy.AttributeValues)
+	}
+
+	assert.Empty(t, check(healthCheck("CALCULATED", "", 0)), "A calculated health check has no endpoint")
+}
+
+// TestCheckHealthCheckAlarm checks a health check passes only when an alarm watches its status, and is skipped
+// while its ID is unknown
+func TestCheckHealthCheckAlarm(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	healthCheck := &tfjson.StateResource{Address: "aws_route53_health_check.api", Type: "aws_route53_health_check",
+		AttributeValues: map[string]interface{}{"id": "abcdef01-2345-6789-abcd-ef0123456789"}}
+	alarm := func(healthCheckID string) *tfjson.StateResource {
+		return &tfjson.StateResource{Address: "aws_cloudwatch_metric_alarm.api_health",
+			Type: "aws_cloudwatch_metric_alarm",
+			AttributeValues: map[string]interface{}{
+				"namespace":   "AWS/Route53",
+				"metric_name": "HealthCheckStatus",
+				"dimensions":  map[string]interface{}{"HealthCheckId": healthCheckID},
+			}}
+	}
+	check := func(resources ...*tfjson.StateResource) []common.AuditFinding {
+		return common.RunAudit(&common.AuditContext{Resources: resources}, common.CheckHealthCheckAlarm)
+	}
+
+	findings := check(healthCheck, alarm("abcdef01-2345-6789-abcd-ef0123456789"))
+	require.Len(t, findings, 1)
+	assert.True(t, findings[0].Passed, findings[0].Detail)
+
+	findings = check(healthCheck, alarm("00000000-0000-0000-0000-000000000000"))
+	require.Len(t, findings, 1)
+	assert.False(t, findings[0].Passed, "An alarm on another health check should not count")
+
+	findings = check(healthCheck)
+	require.Len(t, findings, 1)
+	assert.False(t, findings[0].Passed)
+}
+
+// TestCheckFailoverRecordHealth checks a primary failover record must have a health check or evaluate its alias
+// target's health, and other records are left alone
+func TestCheckFailoverRecordHealth(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	record := func(failover string, attributes map[string]interface{}) *tfjson.StateResource {
+		if failover != "" {
+			attributes["failover_routing_policy"] = []interface{}{map[string]interface{}{"type": failover}}
+		}
+		return &tfjson.StateResource{Address: "aws_route53_record.api", Type: "aws_route53_record",
+			AttributeValues: attributes}
+	}
+	alias := func(evaluateTargetHealth bool) []interface{} {
+		return []interface{}{map[string]interface{}{"evaluate_target_health": evaluateTargetHealth}}
+	}
+	check := func(resource *tfjson.StateResource) []common.AuditFinding {
+		return common.RunAudit(&common.AuditContext{Resources: []*tfjson.StateResource{resource}},
+			common.CheckFailoverRecordHealth)
+	}
+
+	for name, guarded := range map[string]*tfjson.StateResource{
+		"health check":           record("PRIMARY", map[string]interface{}{"health_check_id": "abcdef01"}),
+		"evaluated alias target": record("PRIMARY", map[string]interface{}{"alias": alias(true)}),
+	} {
+		findings := check(guarded)
+		require.Len(t, findings, 1, name)
+		assert.True(t, findings[0].Passed, "%s: %s", name, findings[0].Detail)
+	}
+
+	findings := check(record("PRIMARY", map[string]interface{}{"alias": alias(false)}))
+	require.Len(t, findings, 1)
+	assert.False(t, findings[0].Passed)
+
+	assert.Empty(t, check(record("SECONDARY", map[string]interface{}{})), "A standby needs no health check")
+	assert.Empty(t, check(record("", map[string]interface{}{"alias": alias(false)})), "Only failover records apply")
+}
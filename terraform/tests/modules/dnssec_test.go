@@ -0,0 +1,102 @@
+package modules
+
+import (
+	"net"
+	"testing"
+
+	"terraform-tests/common"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// TestDNSSECAuditChecks checks the KMS key Route53 signs with must be an ECC_NIST_P256 signing key, and the key
+// signing key and zone signing must be active, while keys for other uses are left alone
+func TestDNSSECAuditChecks(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	dnssecPolicy := `{"Statement":[{"Principal":{"Service":"` + common.DNSSECSigningPrincipal + `"}}]}`
+	kmsKey := func(policy, keySpec, keyUsage string) *tfjson.StateResource {
+		return &tfjson.StateResource{Address: "aws_kms_key.dnssec", Type: "aws_kms_key",
+			AttributeValues: map[string]interface{}{
+				"policy": policy, "customer_master_key_spec": keySpec, "key_usage": keyUsage,
+			}}
+	}
+	passed := func(resource *tfjson.StateResource) []bool {
+		var outcomes []bool
+		for _, finding := range common.RunAudit(&common.AuditContext{Resources: []*tfjson.StateResource{resource}},
+			common.DNSSECAuditChecks()...) {
+			outcomes = append(outcomes, finding.Passed)
+		}
+		return outcomes
+	}
+
+	assert.Equal(t, []bool{true}, passed(kmsKey(dnssecPolicy, "ECC_NIST_P256", "SIGN_VERIFY")))
+	assert.Equal(t, []bool{false}, passed(kmsKey(dnssecPolicy, "SYMMETRIC_DEFAULT", "ENCRYPT_DECRYPT")))
+	assert.Empty(t, passed(kmsKey(`{"Statement":[]}`, "SYMMETRIC_DEFAULT", "ENCRYPT_DECRYPT")),
+		"A key Route53 may not sign with is not a DNSSEC key")
+
+	keySigningKey := func(status string) *tfjson.StateResource {
+		return &tfjson.StateResource{Address: "aws_route53_key_signing_key.main", Type: "aws_route53_key_signing_key",
+			AttributeValues: map[string]interface{}{"status": status}}
+	}
+	assert.Equal(t, []bool{true}, passed(keySigningKey("ACTIVE")))
+	assert.Equal(t, []bool{false}, passed(keySigningKey("INACTIVE")))
+
+	zoneSigning := func(status string) *tfjson.StateResource {
+		return &tfjson.StateResource{Address: "aws_route53_hosted_zone_dnssec.main",
+			Type: "aws_route53_hosted_zone_dnssec", AttributeValues: map[string]interface{}{"signing_status": status}}
+	}
+	assert.Equal(t, []bool{true}, passed(zoneSigning("SIGNING")))
+	assert.Equal(t, []bool{false}, passed(zoneSigning("NOT_SIGNING")))
+}
+
+// TestApexSignatures answers the SOA query from a local resolver, and checks the signatures in its answer are
+// counted and its authenticated data flag is read
+func TestApexSignatures(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	go func() {
+		query := make([]byte, 4096)
+		n, client, readErr := conn.ReadFrom(query)
+		if readErr != nil {
+			return
+		}
+		var parser dnsmessage.Parser
+		header, parseErr := parser.Start(query[:n])
+		if parseErr != nil {
+			return
+		}
+		question, parseErr := parser.Question()
+		if parseErr != nil {
+			return
+		}
+
+		builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, AuthenticData: true})
+		_ = builder.StartQuestions()
+		_ = builder.Question(question)
+		_ = builder.StartAnswers()
+		answer := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 300}
+		_ = builder.SOAResource(answer, dnsmessage.SOAResource{
+			NS: question.Name, MBox: question.Name, Serial: 1, Refresh: 7200, Retry: 900, Expire: 1209600, MinTTL: 86400,
+		})
+		answer.Type = dnsmessage.Type(46)
+		_ = builder.UnknownResource(answer, dnsmessage.UnknownResource{Type: answer.Type, Data: []byte{0, 6, 13, 2}})
+		response, buildErr := builder.Finish()
+		if buildErr != nil {
+			return
+		}
+		_, _ = conn.WriteTo(response, client)
+	}()
+
+	signatures, validated, err := common.ApexSignaturesE("example.org", conn.LocalAddr().String())
+	require.NoError(t, err)
+	assert.Equal(t, 1, signatures)
+	assert.True(t, validated)
+}
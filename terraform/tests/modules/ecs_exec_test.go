@@ -0,0 +1,56 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMissingECSExecActions checks the Session Manager channel actions are found across a role's policies
+func TestMissingECSExecActions(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	channels := `{"Statement":[{"Effect":"Allow","Action":["ssmmessages:CreateControlChannel",` +
+		`"ssmmessages:CreateDataChannel","ssmmessages:OpenControlChannel","ssmmessages:OpenDataChannel"],` +
+		`"Resource":"*"}]}`
+	s3 := `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"*"}]}`
+
+	assert.Empty(t, common.MissingECSExecActions([]string{s3, channels}))
+	assert.Empty(t, common.MissingECSExecActions(
+		[]string{`{"Statement":[{"Effect":"Allow","Action":"ssmmessages:*","Resource":"*"}]}`}))
+	assert.Equal(t, common.ECSExecActions, common.MissingECSExecActions([]string{s3}))
+	assert.Equal(t, common.ECSExecActions, common.MissingECSExecActions(
+		[]string{`{"Statement":[{"Effect":"Deny","Action":"ssmmessages:*","Resource":"*"}]}`}))
+}
+
+// TestCheckECSExecCluster checks clusters must encrypt and log ECS Exec sessions
+func TestCheckECSExecCluster(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	cluster := func(execute ...interface{}) *tfjson.StateResource {
+		attributes := map[string]interface{}{}
+		if len(execute) > 0 {
+			attributes["configuration"] = []interface{}{
+				map[string]interface{}{"execute_command_configuration": execute},
+			}
+		}
+		return &tfjson.StateResource{Address: "aws_ecs_cluster.geodata_import", Type: "aws_ecs_cluster",
+			AttributeValues: attributes}
+	}
+	passed := func(resource *tfjson.StateResource) bool {
+		findings := common.CheckECSExecCluster(resource, &common.AuditContext{})
+		require.Len(t, findings, 1)
+		return findings[0].Passed
+	}
+
+	assert.True(t, passed(cluster(map[string]interface{}{
+		"kms_key_id": "arn:aws:kms:us-east-1:123456789012:key/abc", "logging": "DEFAULT"})))
+	assert.False(t, passed(cluster(map[string]interface{}{"kms_key_id": "", "logging": "DEFAULT"})))
+	assert.False(t, passed(cluster(map[string]interface{}{
+		"kms_key_id": "arn:aws:kms:us-east-1:123456789012:key/abc", "logging": "NONE"})))
+	assert.False(t, passed(cluster()))
+}
@@ -0,0 +1,64 @@
+package modules
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExamplesPlan runs init, validate and plan for every example under terraform/examples, with fixture values
+// for the variables each requires, so documentation examples break the build as soon as a module change breaks them
+func TestExamplesPlan(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	for _, examplePath := range common.DiscoverExamples(t) {
+		t.Run(filepath.Base(examplePath), func(t *testing.T) {
+			common.ValidateExample(t, examplePath)
+
+			testConfig := tfopts.NewTestConfig(examplePath)
+			terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly(
+				examplePath, testConfig.ExampleVars(t, examplePath))
+			plan := common.PlanOnly(t, terraformOptions)
+			assert.NotEmpty(t, plan.ResourcePlannedValuesMap, "%s plans no resources", examplePath)
+		})
+	}
+}
+
+// TestMinimalExampleApplies applies and destroys the minimal example and checks every output it documents is set.
+// It runs nightly, with EXAMPLES_APPLY=true.
+func TestMinimalExampleApplies(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+	if !common.ExamplesApplyEnabled() {
+		t.Skip("Skipping example apply - set EXAMPLES_APPLY=true to apply the minimal example")
+	}
+
+	examplePath := filepath.Join(common.ExamplesDir, common.MinimalExample)
+	testConfig := tfopts.NewTestConfig(examplePath)
+	options := testConfig.GetModuleTerraformOptions(examplePath, testConfig.ExampleVars(t, examplePath))
+
+	var outputs []string
+	for name := range common.GetModuleOutputs(t, examplePath) {
+		outputs = append(outputs, name)
+	}
+	sort.Strings(outputs)
+	common.ApplyAndValidate(t, options, common.Outputs(outputs...))
+}
+
+// TestExampleFixturesCoverVariables checks every variable an example requires has a fixture, so a new example
+// fails in short mode rather than on its first plan
+func TestExampleFixturesCoverVariables(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	for _, examplePath := range common.DiscoverExamples(t) {
+		t.Run(filepath.Base(examplePath), func(t *testing.T) {
+			testConfig := tfopts.NewTestConfig(examplePath)
+			vars := testConfig.ExampleVars(t, examplePath)
+			assert.Equal(t, testConfig.Prefix, vars["prefix"], "Examples take the prefix to name resources by")
+		})
+	}
+}
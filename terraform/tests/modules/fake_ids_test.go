@@ -0,0 +1,59 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFindFakeIDs checks that the placeholder IDs module tests have used are caught, while real-looking IDs and
+// variables that hold names are left alone
+func TestFindFakeIDs(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	fakes := common.FindFakeIDs(map[string]interface{}{
+		"vpc_id":                    "vpc-12345678",
+		"lambda_security_group_id":  "sg-lambda123",
+		"db_subnet_ids":             []string{"subnet-0a1b2c3d4e5f67890", "subnet-db2"},
+		"database_secret_arn":       "arn:aws:secretsmanager:us-east-1:123456789:secret:test-db-secret",
+		"route53_zone_id":           "Z123456789",
+		"bastion_security_group_id": "sg-0a1b2c3d",
+		"s3_bucket_arn":             "arn:aws:s3:::test-bucket",
+		"flow_log_name":             "vpc-flow-logs",
+	})
+
+	variables := make([]string, 0, len(fakes))
+	for _, fake := range fakes {
+		variables = append(variables, fake.Variable)
+	}
+	assert.Equal(t, []string{
+		"database_secret_arn",
+		"db_subnet_ids[1]",
+		"lambda_security_group_id",
+		"route53_zone_id",
+		"vpc_id",
+	}, variables)
+	assert.Equal(t, "not a valid sg- ID", fakes[2].Reason)
+}
+
+// TestFakeIDsErrorNamesPlaceholders checks the error ApplyAndValidate fails on lists each placeholder, and that
+// options without any can be applied
+func TestFakeIDsErrorNamesPlaceholders(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	err := common.FakeIDsError(&terraform.Options{
+		TerraformDir: "../../modules/database",
+		Vars:         map[string]interface{}{"vpc_id": "vpc-12345678", "db_instance_class": "db.t4g.micro"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `vpc_id = "vpc-12345678"`)
+	assert.NotContains(t, err.Error(), "db_instance_class")
+
+	assert.NoError(t, common.FakeIDsError(&terraform.Options{
+		Vars: map[string]interface{}{"vpc_id": "vpc-0a1b2c3d4e5f6a7b8"},
+	}))
+}
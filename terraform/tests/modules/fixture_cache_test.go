@@ -0,0 +1,32 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFixtureKeyMatchesIdenticalConfigurations checks that fixtures are shared only between identical module
+// configurations, however their variables were built
+func TestFixtureKeyMatchesIdenticalConfigurations(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	key := func(modulePath string, vars map[string]interface{}) string {
+		fixtureKey, err := common.FixtureKey(modulePath, vars)
+		require.NoError(t, err)
+		return fixtureKey
+	}
+
+	vars := map[string]interface{}{"create_vpc": true, "vpc_cidr": "10.0.0.0/16", "azs": []string{"a", "b"}}
+	reordered := map[string]interface{}{"azs": []string{"a", "b"}, "vpc_cidr": "10.0.0.0/16", "create_vpc": true}
+
+	assert.Equal(t, key("../../modules/networking", vars), key("../../modules/networking", reordered))
+	assert.Equal(t, key("../../modules/networking", vars), key("../../modules/../modules/networking", vars),
+		"The same module reached by another path should share the fixture")
+	assert.NotEqual(t, key("../../modules/networking", vars),
+		key("../../modules/networking", map[string]interface{}{"create_vpc": true, "vpc_cidr": "10.1.0.0/16"}))
+	assert.NotEqual(t, key("../../modules/networking", vars), key("../../modules/security", vars))
+}
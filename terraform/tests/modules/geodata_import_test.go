@@ -3,11 +3,14 @@ package modules
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 	"time"
 
 	"terraform-tests/common"
+	"terraform-tests/fixtures"
+	"terraform-tests/tfopts"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -25,8 +28,133 @@ func TestGeodataImportModuleValidation(t *testing.T) {
 	common.ValidateModuleStructure(t, "geodata-import")
 }
 
+// geodataImportPlanVars returns the required variables for plan-only tests of the geodata-import module
+func geodataImportPlanVars() map[string]interface{} {
+	return map[string]interface{}{
+		"ecr_repository_url":    "123456789.dkr.ecr.us-east-1.amazonaws.com/test-repo",
+		"database_secret_arn":   "arn:aws:secretsmanager:us-east-1:123456789:secret:test-db-secret",
+		"django_secret_key_arn": "arn:aws:secretsmanager:us-east-1:123456789:secret:test-django-secret",
+		"s3_bucket_arn":         "arn:aws:s3:::test-bucket",
+	}
+}
+
+func TestGeodataImportContainerInsightsToggle(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	testCases := []struct {
+		name     string
+		enabled  interface{} // nil leaves the variable at its default
+		expected string
+	}{
+		{name: "DefaultDisabledForCost", enabled: nil, expected: "disabled"},
+		{name: "ExplicitlyDisabled", enabled: false, expected: "disabled"},
+		{name: "EnabledForObservability", enabled: true, expected: "enabled"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vars := geodataImportPlanVars()
+			if tc.enabled != nil {
+				vars["enable_container_insights"] = tc.enabled
+			}
+			_, terraformOptions := common.SetupModuleTest(t, "geodata-import", vars)
+
+			plan := common.PlanOnly(t, terraformOptions)
+
+			clusters := common.GetPlannedResourcesByType(plan, "aws_ecs_cluster")
+			require.Len(t, clusters, 1)
+
+			settings, ok := clusters[0].AttributeValues["setting"].([]interface{})
+			require.True(t, ok, "%s should plan its cluster settings", clusters[0].Address)
+
+			insights := ""
+			for _, setting := range settings {
+				if values, ok := setting.(map[string]interface{}); ok && values["name"] == "containerInsights" {
+					insights, _ = values["value"].(string)
+				}
+			}
+			assert.Equal(t, tc.expected, insights, "containerInsights should follow enable_container_insights")
+		})
+	}
+}
+
+func TestGeodataImportTaskMatchesEnvironmentContract(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	_, terraformOptions := common.SetupModuleTest(t, "geodata-import", geodataImportPlanVars())
+
+	plan := common.PlanOnly(t, terraformOptions)
+
+	taskDefinitions := common.GetPlannedResourcesByType(plan, "aws_ecs_task_definition")
+	require.Len(t, taskDefinitions, 1)
+
+	containers := common.GetPlannedContainerEnvironments(t, taskDefinitions[0])
+	require.Len(t, containers, 1)
+	common.AssertContainerContract(t, containers[0])
+	assert.Contains(t, containers[0].Secrets["DATABASE_URL"], "test-db-secret")
+	assert.Contains(t, containers[0].Secrets["SECRET_KEY"], "test-django-secret")
+}
+
+func TestGeodataImportTaskRuntimeSettings(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	_, terraformOptions := common.SetupModuleTest(t, "geodata-import", geodataImportPlanVars())
+
+	plan := common.PlanOnly(t, terraformOptions)
+
+	for _, taskDefinition := range common.GetPlannedResourcesByType(plan, "aws_ecs_task_definition") {
+		for _, container := range common.GetPlannedContainerRuntimes(t, taskDefinition) {
+			common.AssertTaskRuntime(t, container)
+		}
+	}
+
+	// The import is not traced, so this only reports if a container starts sending traces without a sidecar
+	common.ReportAuditFindings(t, common.RunAudit(common.NewPlanAuditContext(plan), common.CheckTracingSidecar))
+}
+
+// TestGeodataImportTaskSupportsECSExec checks that with ECS Exec enabled, the task role can open Session Manager
+// channels and the cluster encrypts and logs sessions, so operators can debug a long-running import
+func TestGeodataImportTaskSupportsECSExec(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+	if !common.ModuleSupportsECSExec("../../modules/geodata-import") {
+		t.Skipf("The geodata-import module does not declare %s yet", common.ECSExecVariable)
+	}
+
+	vars := geodataImportPlanVars()
+	vars[common.ECSExecVariable] = true
+	_, terraformOptions := common.SetupModuleTest(t, "geodata-import", vars)
+
+	plan := common.PlanOnly(t, terraformOptions)
+
+	// Role names are only known after apply, so task role policies are found by address
+	var taskPolicies []string
+	for _, policy := range common.GetPlannedResourcesByType(plan, "aws_iam_role_policy") {
+		if strings.Contains(policy.Address, "ecs_execution") || strings.Contains(policy.Address, "secrets") {
+			continue
+		}
+		taskPolicies = append(taskPolicies, common.GetPlannedStringAttribute(policy, "policy"))
+	}
+	assert.Empty(t, common.MissingECSExecActions(taskPolicies),
+		"The task role should allow the Session Manager channels ECS Exec opens")
+
+	common.ReportAuditFindings(t, common.RunAudit(common.NewPlanAuditContext(plan), common.CheckECSExecCluster))
+}
+
+// TestGeodataImportWorkflowPinsPlatformVersion checks the run-task call, since the platform version is not part
+// of the task definition
+func TestGeodataImportWorkflowPinsPlatformVersion(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	workflow, err := os.ReadFile("../../../.github/workflows/geodata_import.yml")
+	require.NoError(t, err)
+
+	assert.Contains(t, string(workflow), "--launch-type FARGATE")
+	assert.Contains(t, string(workflow), "--platform-version "+common.FargatePlatformVersion,
+		"The geodata import workflow should run tasks on a pinned Fargate platform version")
+}
+
 func TestGeodataImportModule(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 	t.Parallel()
 
 	// Generate unique names for this test
@@ -44,14 +172,18 @@ func TestGeodataImportModule(t *testing.T) {
 	iamClient := iam.NewFromConfig(cfg)
 	logsClient := cloudwatchlogs.NewFromConfig(cfg)
 
+	// The task definition references the secrets, so they should exist
+	databaseSecret := fixtures.NewSecret(t, "database")
+	djangoSecret := fixtures.NewSecret(t, "django-secret-key")
+
 	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
 		TerraformDir: "../../modules/geodata-import",
 		Vars: map[string]interface{}{
 			"prefix":                prefix,
 			"aws_region":            "us-east-1",
 			"ecr_repository_url":    "123456789.dkr.ecr.us-east-1.amazonaws.com/test-repo",
-			"database_secret_arn":   "arn:aws:secretsmanager:us-east-1:123456789:secret:test-db-secret",
-			"django_secret_key_arn": "arn:aws:secretsmanager:us-east-1:123456789:secret:test-django-secret",
+			"database_secret_arn":   databaseSecret.ARN,
+			"django_secret_key_arn": djangoSecret.ARN,
 			"s3_bucket_arn":         "arn:aws:s3:::test-bucket",
 			"tags": map[string]string{
 				"Environment": "test",
@@ -65,11 +197,8 @@ func TestGeodataImportModule(t *testing.T) {
 		TimeBetweenRetries: 10 * time.Second,
 	})
 
-	// Clean up resources with "terraform destroy" at the end of the test
-	defer terraform.Destroy(t, terraformOptions)
-
-	// Run "terraform init" and "terraform apply"
-	terraform.InitAndApply(t, terraformOptions)
+	// Run "terraform init" and "terraform apply", destroying at the end of the test
+	common.ApplyAndValidate(t, tfopts.ApplyMode(terraformOptions))
 
 	// Validate outputs
 	t.Run("ValidateOutputs", func(t *testing.T) {
@@ -166,26 +295,18 @@ func TestGeodataImportModule(t *testing.T) {
 		assert.Equal(t, "geodata-import", *container.Name)
 		assert.Contains(t, *container.Image, "test-repo")
 
-		// Check environment variables
-		envVars := make(map[string]string)
-		for _, env := range container.Environment {
-			envVars[*env.Name] = *env.Value
-		}
+		// Check environment variables and secrets against the contract, then where secrets come from
+		environment := common.GetECSContainerEnvironment(container)
+		common.AssertContainerContract(t, environment)
 
-		assert.Equal(t, "true", envVars["USE_GEODJANGO"])
-		assert.Equal(t, "coalition.core.settings", envVars["DJANGO_SETTINGS_MODULE"])
-		assert.Equal(t, "true", envVars["IS_ECS_GEODATA_IMPORT"])
+		assert.Contains(t, environment.Secrets["DATABASE_URL"], databaseSecret.ARN)
+		assert.Contains(t, environment.Secrets["SECRET_KEY"], djangoSecret.ARN)
 
-		// Check secrets
-		require.Len(t, container.Secrets, 2)
-		secretNames := make(map[string]string)
-		for _, secret := range container.Secrets {
-			secretNames[*secret.Name] = *secret.ValueFrom
+		// Check platform and Linux settings
+		for _, runtime := range common.GetECSContainerRuntimes(taskDef) {
+			common.AssertTaskRuntime(t, runtime)
 		}
 
-		assert.Contains(t, secretNames["DATABASE_URL"], "test-db-secret")
-		assert.Contains(t, secretNames["SECRET_KEY"], "test-django-secret")
-
 		// Check log configuration
 		require.NotNil(t, container.LogConfiguration)
 		assert.Equal(t, "awslogs", string(container.LogConfiguration.LogDriver))
@@ -298,7 +419,7 @@ func TestGeodataImportModule(t *testing.T) {
 }
 
 func TestGeodataImportModuleVariableValidation(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierPlan)
 	t.Parallel()
 
 	// Test validation errors
@@ -0,0 +1,136 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGitTagsFromEnvironment checks the commit and branch come from CI's variables, the pull request's head branch
+// before the ref GitHub Actions checked out, and are made safe as tag values
+func TestGitTagsFromEnvironment(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+	t.Setenv("GIT_COMMIT", "")
+	t.Setenv("GIT_BRANCH", "")
+	t.Setenv("GITHUB_SHA", "89abcdef0123456789abcdef0123456789abcdef")
+	t.Setenv("GITHUB_HEAD_REF", "fix/issue#42")
+	t.Setenv("GITHUB_REF_NAME", "42/merge")
+
+	assert.Equal(t, "89abcdef0123456789abcdef0123456789abcdef", tfopts.GitCommit())
+	assert.Equal(t, "fix/issue-42", tfopts.GitBranch(), "# is not allowed in a tag value")
+
+	t.Setenv("GITHUB_HEAD_REF", "")
+	assert.Equal(t, "42/merge", tfopts.GitBranch(), "Outside a pull request, the pushed ref is the branch")
+
+	t.Setenv("GIT_BRANCH", "main")
+	assert.Equal(t, "main", tfopts.GitBranch(), "GIT_BRANCH should override CI's variables")
+
+	tags := tfopts.RunTags()
+	assert.Equal(t, map[string]string{
+		tfopts.TestRunIDTag: tfopts.TestRunID(),
+		tfopts.GitCommitTag: "89abcdef0123456789abcdef0123456789abcdef",
+		tfopts.GitBranchTag: "main",
+	}, tags)
+}
+
+func TestTagValue(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	assert.Equal(t, "release 1.2", tfopts.TagValue(" release 1.2\n"))
+	assert.Equal(t, "dependabot/go_modules/aws-sdk-1.2", tfopts.TagValue("dependabot/go_modules/aws-sdk-1.2"))
+	assert.Equal(t, "a-b-c", tfopts.TagValue("a!b*c"))
+	assert.Equal(t, tfopts.UnknownGitValue, tfopts.TagValue(""))
+	assert.Len(t, tfopts.TagValue(strings.Repeat("x", 300)), 256)
+}
+
+// TestGroupByOrigin checks leaked resources are grouped by the branch, commit and test that created them
+func TestGroupByOrigin(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	origin := func(branch, commit, test string) map[string]string {
+		return map[string]string{
+			tfopts.GitBranchTag: branch,
+			tfopts.GitCommitTag: commit,
+			common.TestNameTag:  test,
+			tfopts.TestRunIDTag: "gh-1-1",
+		}
+	}
+	groups := common.GroupByOrigin([]common.NamespacedResource{
+		{ARN: "arn:aws:ec2:us-east-1:123456789012:vpc/vpc-2", Tags: origin("pr-7", "bbb", "TestNetworking")},
+		{ARN: "arn:aws:s3:::bucket", Tags: origin("main", "aaa", "TestStorage")},
+		{ARN: "arn:aws:ec2:us-east-1:123456789012:vpc/vpc-1", Tags: origin("pr-7", "bbb", "TestNetworking")},
+		{ARN: "arn:aws:sqs:us-east-1:123456789012:untagged"},
+	})
+
+	require.Len(t, groups, 3)
+	assert.Equal(t, common.ResourceOrigin{}, groups[0].ResourceOrigin, "Resources without tags sort first")
+	assert.Equal(t, "main", groups[1].GitBranch)
+	assert.Equal(t, common.ResourcesByOrigin{
+		ResourceOrigin: common.ResourceOrigin{
+			GitBranch: "pr-7", GitCommit: "bbb", TestName: "TestNetworking", TestRunID: "gh-1-1",
+		},
+		ARNs: []string{
+			"arn:aws:ec2:us-east-1:123456789012:vpc/vpc-1",
+			"arn:aws:ec2:us-east-1:123456789012:vpc/vpc-2",
+		},
+	}, groups[2])
+}
+
+func TestParseBranchCosts(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	report, err := common.ParseBranchCosts([]byte(`{"ResultsByTime": [
+		{"Groups": [
+			{"Keys": ["GitBranch$main"], "Metrics": {"UnblendedCost": {"Amount": "1.50"}}},
+			{"Keys": ["GitBranch$feature/x"], "Metrics": {"UnblendedCost": {"Amount": "4.00"}}},
+			{"Keys": ["GitBranch$"], "Metrics": {"UnblendedCost": {"Amount": "9.00"}}}
+		]},
+		{"Groups": [
+			{"Keys": ["GitBranch$main"], "Metrics": {"UnblendedCost": {"Amount": "1.00"}}}
+		]}
+	]}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []common.BranchCost{
+		{Branch: "feature/x", CostUSD: 4.00},
+		{Branch: "main", CostUSD: 2.50},
+	}, report.Branches, "Untagged spend is left out and the most expensive branch comes first")
+	assert.InDelta(t, 6.50, report.TotalUSD, 0.001)
+}
+
+// TestGetBranchCostsFollowsNextPageToken answers get-cost-and-usage from a fake AWS CLI that splits the groups
+// across two pages, and checks the spend on the second page is counted too
+func TestGetBranchCostsFollowsNextPageToken(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	dir := t.TempDir()
+	script := `#!/bin/sh
+case "$*" in
+*"--next-page-token page-2 "*)
+	echo '{"ResultsByTime": [{"Groups": [
+		{"Keys": ["GitBranch$main"], "Metrics": {"UnblendedCost": {"Amount": "2.00"}}}
+	]}]}' ;;
+*)
+	echo '{"NextPageToken": "page-2", "ResultsByTime": [{"Groups": [
+		{"Keys": ["GitBranch$main"], "Metrics": {"UnblendedCost": {"Amount": "1.00"}}},
+		{"Keys": ["GitBranch$feature/x"], "Metrics": {"UnblendedCost": {"Amount": "0.50"}}}
+	]}]}' ;;
+esac
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "aws"), []byte(script), 0o755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	report := common.GetBranchCosts(t, "2026-09-01", "2026-10-01")
+	assert.Equal(t, []common.BranchCost{
+		{Branch: "main", CostUSD: 3.00},
+		{Branch: "feature/x", CostUSD: 0.50},
+	}, report.Branches)
+	assert.InDelta(t, 3.50, report.TotalUSD, 0.001)
+}
@@ -0,0 +1,29 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTrackedStacksKeepEveryOptionsValue checks tests sharing a directory, as the root configuration's do, are each
+// tracked for the interrupt cleanup, and that a destroyed stack is no longer tracked
+func TestTrackedStacksKeepEveryOptionsValue(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	first := &terraform.Options{TerraformDir: "../../", BackendConfig: map[string]interface{}{"key": "first"}}
+	second := &terraform.Options{TerraformDir: "../../", BackendConfig: map[string]interface{}{"key": "second"}}
+	tfopts.Track(first)
+	tfopts.Track(second)
+	t.Cleanup(func() { tfopts.Untrack(second) })
+
+	assert.Subset(t, tfopts.TrackedStacks(), []*terraform.Options{first, second})
+
+	tfopts.Untrack(first)
+	assert.NotContains(t, tfopts.TrackedStacks(), first)
+	assert.Contains(t, tfopts.TrackedStacks(), second)
+}
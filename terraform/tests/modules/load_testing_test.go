@@ -0,0 +1,162 @@
+package modules
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadResult is the result of one request, with the fields the summary reads
+func loadResult(method, url string, code int, latency time.Duration) common.LoadResult {
+	return common.LoadResult{Method: method, URL: url, Code: code, Latency: latency}
+}
+
+// TestSummarizeLoad checks results are grouped by endpoint with IDs folded together, that timeouts and 5xx count
+// as errors while 429s are only throttled, and that p95 is the nearest-rank percentile
+func TestSummarizeLoad(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	var results []common.LoadResult
+	for i := 1; i <= 20; i++ {
+		results = append(results, loadResult("GET", "https://api.example.org/api/campaigns/", 200,
+			time.Duration(i)*10*time.Millisecond))
+	}
+	results = append(results,
+		loadResult("GET", "https://api.example.org/api/campaigns/7/", 200, 50*time.Millisecond),
+		loadResult("GET", "https://api.example.org/api/campaigns/12/", 502, 80*time.Millisecond),
+		loadResult("POST", "https://api.example.org/api/endorsements/", 200, time.Second),
+		loadResult("POST", "https://api.example.org/api/endorsements/", 429, 100*time.Millisecond),
+		loadResult("POST", "https://api.example.org/api/endorsements/", 0, 30*time.Second),
+	)
+
+	summaries := common.SummarizeLoad(results)
+	require.Len(t, summaries, 3)
+
+	list := summaries["GET /api/campaigns/"]
+	assert.Equal(t, 20, list.Requests)
+	assert.Equal(t, 190*time.Millisecond, list.P95)
+	assert.Zero(t, list.ErrorRate())
+
+	detail := summaries["GET /api/campaigns/{id}/"]
+	assert.Equal(t, 2, detail.Requests)
+	assert.Equal(t, 0.5, detail.ErrorRate())
+
+	submit := summaries["POST /api/endorsements/"]
+	assert.Equal(t, 1, submit.Errors)
+	assert.Equal(t, 1, submit.Throttled)
+	assert.Equal(t, map[int]int{0: 1, 200: 1, 429: 1}, submit.Codes)
+}
+
+// TestRunLoadCyclesThroughTargets checks an attack sends the targets in turn, with their methods, headers and
+// bodies, and records each response
+func TestRunLoadCyclesThroughTargets(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			bodies = append(bodies, r.Header.Get("X-CSRFToken")+" "+string(body))
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	header := http.Header{}
+	header.Set("X-CSRFToken", "token")
+	targets := []common.LoadTarget{
+		{Method: http.MethodGet, URL: server.URL + "/api/campaigns/"},
+		{Method: http.MethodPost, URL: server.URL + "/api/endorsements/", Body: []byte(`{}`), Header: header},
+	}
+
+	results, err := common.RunLoadE(t, targets, 40, 250*time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, results, 10)
+	summaries := common.SummarizeLoad(results)
+	assert.Equal(t, map[int]int{http.StatusOK: 5}, summaries["GET /api/campaigns/"].Codes)
+	assert.Equal(t, map[int]int{http.StatusCreated: 5}, summaries["POST /api/endorsements/"].Codes)
+	assert.Equal(t, []string{"token {}", "token {}", "token {}", "token {}", "token {}"}, bodies)
+
+	_, err = common.RunLoadE(t, nil, 40, time.Second)
+	assert.Error(t, err)
+}
+
+// TestCheckLoadSLO checks latency and error rate are separate findings, and an endpoint that got no requests fails
+func TestCheckLoadSLO(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	slo := common.LoadSLO{P95: time.Second, MaxErrorRate: 0.01}
+	passed := func(summary common.LoadSummary) map[string]bool {
+		outcomes := map[string]bool{}
+		for _, finding := range common.CheckLoadSLO("GET /api/campaigns/", summary, slo) {
+			outcomes[finding.Control] = finding.Passed
+		}
+		return outcomes
+	}
+
+	assert.Equal(t, map[string]bool{"Load-Latency": true, "Load-ErrorRate": true},
+		passed(common.LoadSummary{Requests: 100, Errors: 1, P95: time.Second}))
+	assert.Equal(t, map[string]bool{"Load-Latency": false, "Load-ErrorRate": false},
+		passed(common.LoadSummary{Requests: 100, Errors: 2, P95: 1500 * time.Millisecond}))
+	assert.Equal(t, map[string]bool{"Load-Requests": false}, passed(common.LoadSummary{}))
+}
+
+// TestCampaignLaunchTargets checks every fifth request submits an endorsement from its own simulator address with
+// the CSRF credentials, and that targets are written in vegeta's JSON format with the body base64-encoded
+func TestCampaignLaunchTargets(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	csrf := &common.CSRFCredentials{Cookie: "csrftoken=secret", Token: "masked"}
+	targets := common.CampaignLaunchTargets(t, "https://api.example.org", "https://example.org", 7, 20, csrf)
+	require.Len(t, targets, 20)
+
+	emails := map[string]bool{}
+	for i, target := range targets {
+		if i%5 != 4 {
+			assert.Equal(t, "GET", target.Method)
+			assert.True(t, strings.HasPrefix(target.URL, "https://api.example.org/api/campaigns/"), target.URL)
+			continue
+		}
+		assert.Equal(t, "POST", target.Method)
+		assert.Equal(t, "https://api.example.org/api/endorsements/", target.URL)
+		assert.Equal(t, "masked", target.Header.Get("X-CSRFToken"))
+		assert.Equal(t, "https://example.org", target.Header.Get("Origin"))
+
+		var body struct {
+			CampaignID  int `json:"campaign_id"`
+			Stakeholder struct {
+				Email string `json:"email"`
+			} `json:"stakeholder"`
+		}
+		require.NoError(t, json.Unmarshal(target.Body, &body))
+		assert.Equal(t, 7, body.CampaignID)
+		assert.True(t, strings.HasSuffix(body.Stakeholder.Email, "@simulator.amazonses.com"), body.Stakeholder.Email)
+		emails[body.Stakeholder.Email] = true
+	}
+	assert.Len(t, emails, 4, "Each submission should come from its own address")
+
+	encoded, err := json.Marshal(targets[4])
+	require.NoError(t, err)
+	var format struct {
+		Method string              `json:"method"`
+		URL    string              `json:"url"`
+		Body   string              `json:"body"`
+		Header map[string][]string `json:"header"`
+	}
+	require.NoError(t, json.Unmarshal(encoded, &format))
+	assert.NotContains(t, format.Body, "campaign_id", "vegeta expects the body base64-encoded")
+	assert.Equal(t, []string{"application/json"}, format.Header["Content-Type"])
+}
@@ -0,0 +1,148 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProviderLockFilesAreValid checks the root and every environment commit a lock file, and every lock file in
+// the root, environments and modules pins a version and hashes for each provider
+func TestProviderLockFilesAreValid(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	deployed, err := common.DeployedConfigurationDirectories(terraformRoot)
+	require.NoError(t, err)
+	directories, err := common.ConfigurationDirectories(terraformRoot)
+	require.NoError(t, err)
+
+	locked := 0
+	for _, directory := range directories {
+		path := filepath.Join(directory, common.LockFileName)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if slices.Contains(deployed, directory) {
+				t.Errorf("%s has no %s; run `terraform init -backend=false` and `%s` in it and commit the file",
+					directory, common.LockFileName, common.LockFileCommand())
+			}
+			continue
+		}
+		locked++
+
+		providers, err := common.ParseLockFile(path)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		for _, problem := range common.LockFileProblems(providers) {
+			t.Errorf("%s: %s; run `%s` in %s", path, problem, common.LockFileCommand(), directory)
+		}
+	}
+	t.Logf("Checked %d lock files in %d configurations", locked, len(directories))
+}
+
+// TestProviderLockFilesCoverPlatforms relocks each committed lock file for common.LockFilePlatforms in a copy of
+// the configuration and fails on any hash relocking adds, since that is a platform the lock file does not cover.
+// It needs terraform and the registry, so it runs in the plan tier.
+func TestProviderLockFilesCoverPlatforms(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	directories, err := common.ConfigurationDirectories(terraformRoot)
+	require.NoError(t, err)
+
+	for _, directory := range directories {
+		path := filepath.Join(directory, common.LockFileName)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		configuration, err := filepath.Rel(terraformRoot, directory)
+		require.NoError(t, err)
+
+		t.Run(configuration, func(t *testing.T) {
+			locked, err := common.ParseLockFile(path)
+			require.NoError(t, err)
+
+			relocked := common.RelockProviders(t, terraformRoot, configuration)
+			for _, problem := range common.LockFileCoverageProblems(locked, relocked, common.LockFilePlatforms) {
+				t.Errorf("%s: %s; run `%s` in %s", path, problem, common.LockFileCommand(), directory)
+			}
+		})
+	}
+}
+
+func TestLockFileProblems(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	path := filepath.Join(t.TempDir(), common.LockFileName)
+	require.NoError(t, os.WriteFile(path, []byte(`# This file is maintained automatically by "terraform init".
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.100.0"
+  constraints = "~> 5.0"
+  hashes = [
+    "h1:linux",
+    "h1:darwin",
+    "zh:0123",
+    "zh:4567",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.7.2"
+  hashes = [
+    "h1:linux",
+    "zh:89ab",
+  ]
+}
+`), 0o600))
+
+	providers, err := common.ParseLockFile(path)
+	require.NoError(t, err)
+	require.Len(t, providers, 2)
+	assert.Equal(t, common.LockedProvider{
+		Source:   "registry.terraform.io/hashicorp/aws",
+		Version:  "5.100.0",
+		H1Hashes: []string{"h1:linux", "h1:darwin"},
+		ZHHashes: []string{"zh:0123", "zh:4567"},
+	}, providers[0])
+
+	assert.Empty(t, common.LockFileProblems(providers))
+	assert.Equal(t, []string{"registry.terraform.io/hashicorp/random 3.7.2 has no zh hashes of its registry packages"},
+		common.LockFileProblems([]common.LockedProvider{{
+			Source: "registry.terraform.io/hashicorp/random", Version: "3.7.2", H1Hashes: []string{"h1:linux"},
+		}}))
+	assert.Equal(t, "terraform providers lock -platform=linux_amd64 -platform=darwin_arm64", common.LockFileCommand())
+}
+
+// TestLockFileCoverageProblems checks coverage comes from what relocking adds, not from counting hashes: two h1
+// hashes from two linux machines are as many as the platforms, but relocking still adds darwin_arm64's
+func TestLockFileCoverageProblems(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	aws := func(version string, h1 ...string) common.LockedProvider {
+		return common.LockedProvider{Source: "registry.terraform.io/hashicorp/aws", Version: version, H1Hashes: h1,
+			ZHHashes: []string{"zh:0123"}}
+	}
+	locked := []common.LockedProvider{aws("5.99.1", "h1:linux_amd64", "h1:linux_arm64")}
+
+	assert.Empty(t, common.LockFileCoverageProblems(locked,
+		[]common.LockedProvider{aws("5.99.1", "h1:linux_amd64", "h1:linux_arm64")}, common.LockFilePlatforms),
+		"Relocking that adds nothing means every platform is covered")
+
+	assert.Equal(t, []string{
+		"registry.terraform.io/hashicorp/aws 5.99.1 lacks 1 of the h1 hashes for linux_amd64, darwin_arm64",
+	}, common.LockFileCoverageProblems(locked, []common.LockedProvider{
+		aws("5.99.1", "h1:linux_amd64", "h1:linux_arm64", "h1:darwin_arm64"),
+	}, common.LockFilePlatforms))
+
+	assert.Equal(t, []string{
+		"registry.terraform.io/hashicorp/aws is locked at 5.99.1, but the constraints now select 5.99.2",
+		"registry.terraform.io/hashicorp/random is not locked",
+	}, common.LockFileCoverageProblems(locked, []common.LockedProvider{
+		aws("5.99.2", "h1:other"),
+		{Source: "registry.terraform.io/hashicorp/random", Version: "3.7.2"},
+	}, common.LockFilePlatforms))
+}
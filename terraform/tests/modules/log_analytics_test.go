@@ -0,0 +1,125 @@
+package modules
+
+import (
+	"testing"
+	"time"
+
+	"terraform-tests/common"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheckWAFLogging checks WAF logs must go to a destination named for WAF, and unknown ARNs are reported as such
+func TestCheckWAFLogging(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	logging := func(destinations ...interface{}) *tfjson.StateResource {
+		return &tfjson.StateResource{Address: "aws_wafv2_web_acl_logging_configuration.main",
+			Type:            "aws_wafv2_web_acl_logging_configuration",
+			AttributeValues: map[string]interface{}{"log_destination_configs": destinations}}
+	}
+	check := func(resource *tfjson.StateResource) []common.AuditFinding {
+		return common.RunAudit(&common.AuditContext{Resources: []*tfjson.StateResource{resource}},
+			common.CheckWAFLogging)
+	}
+
+	findings := check(logging("arn:aws:firehose:us-east-1:123456789012:deliverystream/aws-waf-logs-coalition"))
+	require.Len(t, findings, 1)
+	assert.True(t, findings[0].Passed, findings[0].Detail)
+
+	findings = check(logging("arn:aws:firehose:us-east-1:123456789012:deliverystream/coalition-waf"))
+	require.Len(t, findings, 1)
+	assert.False(t, findings[0].Passed)
+
+	findings = check(logging())
+	require.Len(t, findings, 1)
+	assert.False(t, findings[0].Passed)
+}
+
+// TestCheckFirehoseLogDelivery checks a log stream must write compressed, encrypted objects under day prefixes
+func TestCheckFirehoseLogDelivery(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	stream := func(destination string, config map[string]interface{}, encrypted bool) *tfjson.StateResource {
+		return &tfjson.StateResource{Address: "aws_kinesis_firehose_delivery_stream.waf_logs",
+			Type: "aws_kinesis_firehose_delivery_stream",
+			AttributeValues: map[string]interface{}{
+				"destination":               destination,
+				"extended_s3_configuration": []interface{}{config},
+				"server_side_encryption":    []interface{}{map[string]interface{}{"enabled": encrypted}},
+			}}
+	}
+	failed := func(resource *tfjson.StateResource) []string {
+		var controls []string
+		for _, finding := range common.RunAudit(&common.AuditContext{Resources: []*tfjson.StateResource{resource}},
+			common.CheckFirehoseLogDelivery) {
+			if !finding.Passed {
+				controls = append(controls, finding.Control)
+			}
+		}
+		return controls
+	}
+
+	assert.Empty(t, failed(stream("extended_s3", map[string]interface{}{
+		"prefix":              "waf/!{timestamp:yyyy/MM/dd}/",
+		"error_output_prefix": "errors/!{firehose:error-output-type}/",
+		"compression_format":  "GZIP",
+	}, true)))
+
+	assert.ElementsMatch(t, []string{"Firehose-Partitioned-Prefix", "Firehose-Compression", "Firehose-Encryption"},
+		failed(stream("extended_s3", map[string]interface{}{
+			"prefix":             "waf/!{timestamp:yyyy-MM-dd}/",
+			"compression_format": "UNCOMPRESSED",
+		}, false)))
+
+	assert.Equal(t, []string{"Firehose-Destination"}, failed(stream("http_endpoint", nil, true)))
+}
+
+// TestGlueLogTableProblem checks a log table needs a day partition projected in the layout Firehose writes
+func TestGlueLogTableProblem(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	table := func(partition string, parameters map[string]interface{}) *tfjson.StateResource {
+		return &tfjson.StateResource{Address: "aws_glue_catalog_table.waf_logs", Type: "aws_glue_catalog_table",
+			AttributeValues: map[string]interface{}{
+				"partition_keys":     []interface{}{map[string]interface{}{"name": partition, "type": "string"}},
+				"parameters":         parameters,
+				"storage_descriptor": []interface{}{map[string]interface{}{"location": "s3://coalition-logs/waf/"}},
+			}}
+	}
+	projected := func() map[string]interface{} {
+		return map[string]interface{}{
+			"projection.enabled":        "true",
+			"projection.day.type":       "date",
+			"projection.day.format":     "yyyy/MM/dd",
+			"projection.day.range":      "2024/01/01,NOW",
+			"storage.location.template": "s3://coalition-logs/waf/${day}/",
+		}
+	}
+
+	assert.Empty(t, common.GlueLogTableProblem(table("day", projected())))
+	assert.Contains(t, common.GlueLogTableProblem(table("dt", projected())), "not partitioned by day")
+
+	parameters := projected()
+	parameters["projection.day.format"] = "yyyy-MM-dd"
+	assert.Contains(t, common.GlueLogTableProblem(table("day", parameters)), "expected yyyy/MM/dd")
+
+	parameters = projected()
+	delete(parameters, "projection.enabled")
+	assert.Contains(t, common.GlueLogTableProblem(table("day", parameters)), "MSCK REPAIR TABLE")
+
+	parameters = projected()
+	parameters["storage.location.template"] = "s3://coalition-logs/waf/"
+	assert.NotEmpty(t, common.GlueLogTableProblem(table("day", parameters)))
+}
+
+// TestRecentLogCountQuery checks the smoke query only reads yesterday's and today's partitions
+func TestRecentLogCountQuery(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	now := time.Date(2025, time.March, 1, 2, 0, 0, 0, time.UTC)
+	assert.Equal(t, `SELECT count(*) FROM "waf_logs" WHERE day >= '2025/02/28'`,
+		common.RecentLogCountQuery("waf_logs", now))
+}
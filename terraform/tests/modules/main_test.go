@@ -0,0 +1,27 @@
+package modules
+
+import (
+	"os"
+	"testing"
+
+	"terraform-tests/common"
+)
+
+// TestMain destroys any module stack still deployed if the run is interrupted, such as when CI cancels the job
+func TestMain(m *testing.M) {
+	os.Exit(common.RunWithInterruptCleanup(m))
+}
+
+// TestDestroyRetainedStacks destroys the stacks an interrupted run recorded in RETAINED_STATE_DIR. It is meant to be
+// run on its own after a cancelled job:
+//
+//	RETAINED_STATE_DIR=retained-state go test -run TestDestroyRetainedStacks ./modules/
+func TestDestroyRetainedStacks(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	dir := os.Getenv("RETAINED_STATE_DIR")
+	if dir == "" {
+		t.Skip("Skipping retained stack cleanup - RETAINED_STATE_DIR is not set")
+	}
+	common.DestroyRetainedStacks(t, dir)
+}
@@ -0,0 +1,61 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCheckMaintenanceMode checks maintenance pages must answer 503 and leave the health check reachable, whether
+// a load balancer rule or CloudFront serves them
+func TestCheckMaintenanceMode(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	rule := func(name string, priority float64, action map[string]interface{},
+		paths ...interface{}) *tfjson.StateResource {
+		attributes := map[string]interface{}{"priority": priority, "action": []interface{}{action}}
+		if len(paths) > 0 {
+			attributes["condition"] = []interface{}{map[string]interface{}{
+				"path_pattern": []interface{}{map[string]interface{}{"values": paths}},
+			}}
+		}
+		return &tfjson.StateResource{Address: "aws_lb_listener_rule." + name, Type: "aws_lb_listener_rule",
+			AttributeValues: attributes}
+	}
+	maintenance := func(status string) map[string]interface{} {
+		return map[string]interface{}{"type": "fixed-response", "fixed_response": []interface{}{
+			map[string]interface{}{"status_code": status, "content_type": "text/html"},
+		}}
+	}
+	forward := map[string]interface{}{"type": "forward"}
+	failed := func(resources ...*tfjson.StateResource) map[string]bool {
+		controls := map[string]bool{}
+		for _, finding := range common.RunAudit(&common.AuditContext{Resources: resources}, common.CheckMaintenanceMode) {
+			controls[finding.Control] = controls[finding.Control] || !finding.Passed
+		}
+		return controls
+	}
+
+	assert.Equal(t, map[string]bool{"Maintenance-Status": false, "Maintenance-Health-Path": false},
+		failed(rule("health", 1, forward, common.MaintenanceHealthPath), rule("maintenance", 2, maintenance("503"))))
+	assert.Equal(t, map[string]bool{"Maintenance-Status": true, "Maintenance-Health-Path": true},
+		failed(rule("health", 3, forward, common.MaintenanceHealthPath), rule("maintenance", 2, maintenance("200"))))
+	assert.Empty(t, failed(rule("api", 1, forward, "/api/*")), "Without a maintenance rule there is nothing to check")
+
+	distribution := func(behaviors ...interface{}) *tfjson.StateResource {
+		return &tfjson.StateResource{Address: "aws_cloudfront_distribution.site", Type: "aws_cloudfront_distribution",
+			AttributeValues: map[string]interface{}{
+				"custom_error_response": []interface{}{map[string]interface{}{
+					"error_code": 503.0, "response_code": 503.0, "response_page_path": "/maintenance.html",
+				}},
+				"ordered_cache_behavior": behaviors,
+			}}
+	}
+	assert.Equal(t, map[string]bool{"Maintenance-Health-Path": false},
+		failed(distribution(map[string]interface{}{"path_pattern": "/api/*"})))
+	assert.Equal(t, map[string]bool{"Maintenance-Health-Path": true},
+		failed(distribution(map[string]interface{}{"path_pattern": "/static/*"})))
+}
@@ -2,9 +2,12 @@ package modules
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"terraform-tests/common"
+	"terraform-tests/fixtures"
+	"terraform-tests/tfopts"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -12,6 +15,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestMonitoringModuleValidation runs validation-only tests that don't require AWS credentials
@@ -19,16 +23,24 @@ func TestMonitoringModuleValidation(t *testing.T) {
 	common.ValidateModuleStructure(t, "monitoring")
 }
 
+// withMonitoringFixtures points the VPC flow logs at the shared fixture VPC, which must exist for the apply
+func withMonitoringFixtures(t *testing.T, vars map[string]interface{}) map[string]interface{} {
+	vars["vpc_id"] = fixtures.NewVPC(t).ID
+	return vars
+}
+
 func TestMonitoringModuleCreatesSNSTopics(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
+
+	testConfig := tfopts.NewTestConfig("../../modules/monitoring")
+	testVars := withMonitoringFixtures(t, common.GetMonitoringTestVars())
 
-	testConfig := common.NewTestConfig("../../modules/monitoring")
-	testVars := common.GetMonitoringTestVars()
+	options := testConfig.GetModuleTerraformOptions("../../modules/monitoring", testVars)
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/monitoring", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	terraformOptions := options.Options
+	common.RegisterEmptyBuckets(t, terraformOptions, "alb_logs_bucket")
 
-	terraform.InitAndApply(t, terraformOptions)
+	common.ApplyAndValidate(t, options)
 
 	// Validate SNS topics exist
 	budgetTopicArn := terraform.Output(t, terraformOptions, "budget_alerts_sns_topic_arn")
@@ -61,15 +73,17 @@ func TestMonitoringModuleCreatesSNSTopics(t *testing.T) {
 }
 
 func TestMonitoringModuleCreatesBudget(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/monitoring")
-	testVars := common.GetMonitoringTestVars()
+	testConfig := tfopts.NewTestConfig("../../modules/monitoring")
+	testVars := withMonitoringFixtures(t, common.GetMonitoringTestVars())
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/monitoring", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/monitoring", testVars)
 
-	terraform.InitAndApply(t, terraformOptions)
+	terraformOptions := options.Options
+	common.RegisterEmptyBuckets(t, terraformOptions, "alb_logs_bucket")
+
+	common.ApplyAndValidate(t, options)
 
 	// Create AWS client
 	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(testConfig.AWSRegion))
@@ -104,15 +118,17 @@ func TestMonitoringModuleCreatesBudget(t *testing.T) {
 }
 
 func TestMonitoringModuleCreatesCostAnomalyDetection(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
+
+	testConfig := tfopts.NewTestConfig("../../modules/monitoring")
+	testVars := withMonitoringFixtures(t, common.GetMonitoringTestVars())
 
-	testConfig := common.NewTestConfig("../../modules/monitoring")
-	testVars := common.GetMonitoringTestVars()
+	options := testConfig.GetModuleTerraformOptions("../../modules/monitoring", testVars)
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/monitoring", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	terraformOptions := options.Options
+	common.RegisterEmptyBuckets(t, terraformOptions, "alb_logs_bucket")
 
-	terraform.InitAndApply(t, terraformOptions)
+	common.ApplyAndValidate(t, options)
 
 	// Validate cost anomaly detection outputs
 	monitorArn := terraform.Output(t, terraformOptions, "cost_anomaly_monitor_arn")
@@ -127,15 +143,17 @@ func TestMonitoringModuleCreatesCostAnomalyDetection(t *testing.T) {
 }
 
 func TestMonitoringModuleCreatesS3Bucket(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/monitoring")
-	testVars := common.GetMonitoringTestVars()
+	testConfig := tfopts.NewTestConfig("../../modules/monitoring")
+	testVars := withMonitoringFixtures(t, common.GetMonitoringTestVars())
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/monitoring", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/monitoring", testVars)
 
-	terraform.InitAndApply(t, terraformOptions)
+	terraformOptions := options.Options
+	common.RegisterEmptyBuckets(t, terraformOptions, "alb_logs_bucket")
+
+	common.ApplyAndValidate(t, options)
 
 	// Validate ALB logs bucket
 	bucketName := terraform.Output(t, terraformOptions, "alb_logs_bucket")
@@ -143,3 +161,111 @@ func TestMonitoringModuleCreatesS3Bucket(t *testing.T) {
 	assert.Contains(t, bucketName, testConfig.Prefix)
 	assert.Contains(t, bucketName, "alb-logs")
 }
+
+// withDashboardTargets gives the dashboard an ALB and ECS service to chart. Neither has to exist, as CloudWatch
+// does not check the dimensions a dashboard references.
+func withDashboardTargets(testConfig *tfopts.TestConfig, vars map[string]interface{}) map[string]interface{} {
+	vars["alb_arn_suffix"] = fmt.Sprintf("app/%s-alb/%s", testConfig.Prefix, testConfig.UniqueID)
+	vars["ecs_cluster_name"] = testConfig.Prefix + "-cluster"
+	vars["ecs_service_name"] = testConfig.Prefix + "-service"
+	return vars
+}
+
+// assertDashboardReferences checks a dashboard charts the deployment's database, and the ALB and ECS service
+// withDashboardTargets gave it, and no other resource's metrics
+func assertDashboardReferences(
+	t *testing.T,
+	name string,
+	body *common.DashboardBody,
+	prefix string,
+	vars map[string]interface{},
+) {
+	assert.NotEmpty(t, body.Widgets, "Dashboard %s should have widgets", name)
+
+	// Every resource dimension on the dashboard must point at this deployment
+	for _, dimension := range []string{"DBInstanceIdentifier", "FunctionName", "LoadBalancer", "ServiceName"} {
+		for _, value := range body.DimensionValues(dimension) {
+			assert.Contains(t, value, prefix,
+				"Dashboard %s dimension %s should reference the deployed prefix", name, dimension)
+		}
+	}
+
+	for dimension, expected := range map[string]interface{}{
+		"DBInstanceIdentifier": fmt.Sprintf("%s-db", prefix),
+		"LoadBalancer":         vars["alb_arn_suffix"],
+		"ClusterName":          vars["ecs_cluster_name"],
+		"ServiceName":          vars["ecs_service_name"],
+	} {
+		values := body.DimensionValues(dimension)
+		assert.NotEmpty(t, values, "Dashboard %s should chart a %s", name, dimension)
+		for _, value := range values {
+			assert.Equal(t, expected, value, "Dashboard %s dimension %s", name, dimension)
+		}
+	}
+}
+
+// TestMonitoringModulePlansDashboard checks the module's CloudWatch dashboard is named for the deployment and its
+// widgets only chart this deployment's resources
+func TestMonitoringModulePlansDashboard(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	testConfig := tfopts.NewTestConfig("../../modules/monitoring")
+	testVars := withDashboardTargets(testConfig, common.GetMonitoringTestVars())
+
+	terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly("../../modules/monitoring", testVars)
+	plan := common.PlanOnly(t, terraformOptions)
+
+	dashboards := common.GetPlannedResourcesByType(plan, "aws_cloudwatch_dashboard")
+	require.NotEmpty(t, dashboards, "Monitoring module should define a CloudWatch dashboard")
+
+	for _, dashboard := range dashboards {
+		name := common.GetPlannedStringAttribute(dashboard, "dashboard_name")
+		common.ValidateResourceNaming(t, name, testConfig.Prefix, "")
+
+		body := common.GetDashboardBody(t, plan, dashboard.Address)
+		assertDashboardReferences(t, name, body, testConfig.Prefix, testVars)
+	}
+}
+
+// TestMonitoringModuleDeploysDashboard applies the module and checks the dashboard CloudWatch stores charts the
+// deployment's ALB, ECS service and database
+func TestMonitoringModuleDeploysDashboard(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	testConfig := tfopts.NewTestConfig("../../modules/monitoring")
+	testVars := withDashboardTargets(testConfig, withMonitoringFixtures(t, common.GetMonitoringTestVars()))
+
+	options := testConfig.GetModuleTerraformOptions("../../modules/monitoring", testVars)
+
+	terraformOptions := options.Options
+	common.RegisterEmptyBuckets(t, terraformOptions, "alb_logs_bucket")
+
+	common.ApplyAndValidate(t, options)
+
+	name := terraform.Output(t, terraformOptions, "dashboard_name")
+	common.ValidateResourceNaming(t, name, testConfig.Prefix, "")
+
+	body := common.GetDeployedDashboardBody(t, testConfig.AWSRegion, name)
+	assertDashboardReferences(t, name, body, testConfig.Prefix, testVars)
+}
+
+// TestMonitoringModulePlansAnomalyThreshold checks the planned anomaly subscription alerts as the module intends:
+// on an anomaly of $5 or more, whatever the expected spend, and never below it
+func TestMonitoringModulePlansAnomalyThreshold(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	testConfig := tfopts.NewTestConfig("../../modules/monitoring")
+	terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly("../../modules/monitoring",
+		common.GetMonitoringTestVars())
+	plan := common.PlanOnly(t, terraformOptions)
+
+	subscriptions := common.GetPlannedResourcesByType(plan, "awscc_ce_anomaly_subscription")
+	require.Len(t, subscriptions, 1)
+	threshold := common.PlannedAnomalyThreshold(t, subscriptions[0])
+	assert.Equal(t, common.AnomalyAlertThreshold, threshold)
+
+	for _, expectedUSD := range []float64{1, 30, 10000} {
+		assert.True(t, threshold.Alerts(5, expectedUSD), "A $5 anomaly over $%.0f should alert", expectedUSD)
+		assert.False(t, threshold.Alerts(4.99, expectedUSD), "A $4.99 anomaly over $%.0f should not", expectedUSD)
+	}
+}
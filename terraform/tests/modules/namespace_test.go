@@ -0,0 +1,76 @@
+package modules
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespacePrefixes(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	testConfig := tfopts.NewTestConfig("../../modules/geodata-import")
+
+	networking := common.NewNamespace(testConfig, "networking")
+	assert.Regexp(t, `^networking-test-[0-9a-f]{14}$`, networking.Prefix)
+	assert.Equal(t, networking.Prefix, networking.Config.Prefix)
+	assert.Equal(t, testConfig.AWSRegion, networking.Config.AWSRegion)
+	assert.NotEqual(t, testConfig.Prefix, networking.Prefix, "The test config itself should keep its prefix")
+
+	// Long names are shortened to keep the prefix within the limit the modules are checked against
+	long := common.NewNamespace(testConfig, "geodata-import")
+	assert.Regexp(t, `^geodata-impo-test-[0-9a-f]{14}$`, long.Prefix)
+	assert.LessOrEqual(t, len(long.Prefix), common.MaxPrefixLength)
+
+	assert.NotEqual(t, networking.Prefix, common.NewNamespace(testConfig, "networking").Prefix,
+		"Every namespace should get its own prefix, even for the same component")
+}
+
+// TestNamespaceModuleOptions checks that a namespace applies from its own working copy, which tags every resource
+// through the provider, and also passes its tags to modules that take them
+func TestNamespaceModuleOptions(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	namespace := common.NewNamespace(tfopts.NewTestConfig("../../modules/zappa"), "zappa")
+	options := namespace.GetModuleTerraformOptions(t, "../../modules/zappa", map[string]interface{}{
+		"tags": map[string]string{"Purpose": "terratest"},
+	})
+
+	assert.NotEqual(t, "../../modules/zappa", options.TerraformDir)
+	assert.Equal(t, "zappa", filepath.Base(options.TerraformDir))
+	assert.FileExists(t, filepath.Join(options.TerraformDir, "main.tf"))
+	assert.Equal(t, namespace.Prefix, options.Vars["prefix"])
+
+	namespaceTags := map[string]string{
+		tfopts.TestRunIDTag:     tfopts.TestRunID(),
+		tfopts.GitCommitTag:     tfopts.GitCommit(),
+		tfopts.GitBranchTag:     tfopts.GitBranch(),
+		common.TestNamespaceTag: namespace.Prefix,
+		common.TestNameTag:      t.Name(),
+	}
+	assert.Equal(t, namespaceTags, namespace.Tags(t))
+
+	var provider struct {
+		Provider struct {
+			AWS struct {
+				DefaultTags struct {
+					Tags map[string]string `json:"tags"`
+				} `json:"default_tags"`
+			} `json:"aws"`
+		} `json:"provider"`
+	}
+	content, err := os.ReadFile(filepath.Join(options.TerraformDir, "namespace_provider.tf.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(content, &provider))
+	assert.Equal(t, namespaceTags, provider.Provider.AWS.DefaultTags.Tags)
+
+	namespaceTags["Purpose"] = "terratest"
+	assert.Equal(t, namespaceTags, options.Vars["tags"])
+}
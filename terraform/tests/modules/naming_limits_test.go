@@ -0,0 +1,26 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestResourceNamesFitAWSLimits composes every constrained resource name from the modules' naming
+// conventions with a worst-case prefix. It reads the Terraform sources only, so it runs in short mode
+// and catches names AWS would otherwise reject mid-apply.
+func TestResourceNamesFitAWSLimits(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	names := common.ComposeResourceNames(t, "../../modules", common.WorstCaseNameValues)
+	require.NotEmpty(t, names, "Expected to compose resource names from the modules")
+
+	for _, name := range names {
+		for _, violation := range common.ValidateComposedName(name) {
+			t.Errorf("%s in module %s: %s %q %s", name.Address, name.Module, name.Attribute, name.Name, violation)
+		}
+	}
+	t.Logf("Checked %d resource names against AWS limits with prefix %q", len(names), common.WorstCasePrefix)
+}
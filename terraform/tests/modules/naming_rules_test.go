@@ -0,0 +1,69 @@
+package modules
+
+import (
+	"strings"
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheckNamingRule checks names against the conventions for their resource types and the AWS limits
+func TestCheckNamingRule(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	const prefix = "coalition-abc123"
+	tests := []struct {
+		resourceType string
+		name         string
+		valid        bool
+	}{
+		{"aws_security_group", prefix + "-db-sg", true},
+		{"aws_security_group", prefix + "-bastion-sg", true},
+		{"aws_security_group", prefix + "-web", false},
+		{"aws_security_group", "other-db-sg", false},
+		{"aws_s3_bucket", prefix + "-static-assets-0123abcd", true},
+		{"aws_s3_bucket", prefix + "-Static-Assets", false},
+		{"aws_s3_bucket", prefix + "-static.assets", false},
+		{"aws_s3_bucket", prefix + "--assets", false},
+		{"aws_db_instance", prefix + "-db-replica", true},
+		{"aws_db_instance", prefix + "-db-standby", false},
+		{"aws_lb", prefix + "-alb", true},
+		{"aws_lb", prefix + "-public-application", false}, // 35 characters, over the ALB limit of 32
+		{"aws_db_parameter_group", prefix + "-pg-16-static-test", true},
+		{"aws_db_parameter_group", prefix + "-pg-16", false},
+		{"aws_secretsmanager_secret", prefix + "/database-master", true},
+		{"aws_subnet", prefix + "-private-db-a", true},
+		{"aws_kms_key", prefix + "-rds-kms-key", true}, // No rule of its own, so its Name tag is held to the default
+		{"aws_vpc_endpoint", prefix + "-geo_places-endpoint", true},
+		{"aws_kms_key", "rds-kms-key", false},
+	}
+	for _, tt := range tests {
+		violations := common.CheckNamingRule(tt.resourceType, tt.name, prefix)
+		assert.Equal(t, tt.valid, len(violations) == 0, "%s %q: %s", tt.resourceType, tt.name,
+			strings.Join(violations, "; "))
+	}
+}
+
+// TestComposedNamesFollowNamingRules composes every constrained resource name from the modules with a worst-case
+// prefix and holds it to its naming convention, so a module that strays from the convention fails in short mode
+func TestComposedNamesFollowNamingRules(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	names := common.ComposeResourceNames(t, "../../modules", common.WorstCaseNameValues)
+	require.NotEmpty(t, names, "Expected to compose resource names from the modules")
+
+	checked := 0
+	for _, name := range names {
+		if _, ruled := common.NamingRules[name.ResourceType]; !ruled || common.NamingException(name.Address) != "" {
+			continue
+		}
+		checked++
+		for _, violation := range common.CheckNamingRule(name.ResourceType, name.Name, common.WorstCasePrefix) {
+			t.Errorf("%s in module %s: %s %q %s", name.Address, name.Module, name.Attribute, name.Name, violation)
+		}
+	}
+	t.Logf("Checked %d resource names against their naming conventions", checked)
+}
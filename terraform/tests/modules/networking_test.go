@@ -5,40 +5,56 @@ import (
 	"fmt"
 	"testing"
 
+	"terraform-tests/awsval"
+	"terraform-tests/az"
 	"terraform-tests/common"
+	"terraform-tests/policy"
+	"terraform-tests/tfopts"
+	"terraform-tests/tfout"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	terratestaws "github.com/gruntwork-io/terratest/modules/aws"
-	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// expectedInterfaceEndpoints are the interface endpoint keys the module creates; each one is billed hourly per AZ
+var expectedInterfaceEndpoints = []string{"geo_places", "logs", "secretsmanager"}
+
+// withAvailabilityZones places the module's subnets in two zones picked for the region, instead of its a and b
+// zones, which may be impaired or not offer the bastion's instance type
+func withAvailabilityZones(t *testing.T, region string, vars map[string]interface{}) map[string]interface{} {
+	vars["availability_zones"] = az.PickTwo(t, region, az.BastionInstanceType)
+	return vars
+}
+
 // TestNetworkingModuleValidation runs validation-only tests that don't require AWS credentials
 func TestNetworkingModuleValidation(t *testing.T) {
 	common.ValidateModuleStructure(t, "networking")
 }
 
 func TestNetworkingModuleCreatesVPCAndSubnets(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
 	// Setup test configuration
-	testConfig := common.NewTestConfig("../../modules/networking")
-	testVars := common.GetNetworkingTestVars()
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
 
-	// Clean up resources with defer to ensure cleanup happens even if test fails
-	defer common.CleanupResources(t, terraformOptions)
+	terraformOptions := options.Options
+
+	// Destroy with defer so cleanup happens even if the test fails, then check nothing billable was left behind
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
 
 	// Run terraform init and apply
-	terraform.InitAndApply(t, terraformOptions)
+	common.ApplyAndValidate(t, options)
 
 	// Validate VPC creation
-	vpcID := terraform.Output(t, terraformOptions, "vpc_id")
-	assert.NotEmpty(t, vpcID)
+	vpcID := tfout.OutputString(t, terraformOptions, "vpc_id").NotEmpty().Value()
 
 	vpc := terratestaws.GetVpcById(t, vpcID, testConfig.AWSRegion)
 	// Note: VPC detailed validation simplified due to Terratest API limitations
@@ -46,33 +62,36 @@ func TestNetworkingModuleCreatesVPCAndSubnets(t *testing.T) {
 
 	// Note: VPC tag validation simplified due to Terratest API limitations
 	// Tags validation would require direct AWS SDK access
+	// The VPC is created with its Name tag, so its CreateVpc record carries the test prefix
+	if common.CloudTrailChecksEnabled() {
+		common.AssertCloudTrailEventRecorded(t, testConfig.AWSRegion, "CreateVpc", vpcID, testConfig.Prefix)
+	}
 }
 
 func TestNetworkingModuleCreatesPublicSubnets(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/networking")
-	testVars := common.GetNetworkingTestVars()
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
 	testVars["create_public_subnets"] = true
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
+
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
 
-	terraform.InitAndApply(t, terraformOptions)
+	common.ApplyAndValidate(t, options)
 
 	// Validate public subnets
-	publicSubnetIDs := terraform.OutputList(t, terraformOptions, "public_subnet_ids")
-	assert.Len(t, publicSubnetIDs, 2)
+	publicSubnetIDs := tfout.OutputList(t, terraformOptions, "public_subnet_ids").HasLen(2).AllMatch("^subnet-").Values()
+
+	az.AssertSubnetsInZones(t, testConfig.AWSRegion, publicSubnetIDs, testVars["availability_zones"].([]string))
 
 	for i, subnetID := range publicSubnetIDs {
-		subnet := common.GetSubnetById(t, subnetID, testConfig.AWSRegion)
+		subnet := awsval.GetSubnet(t, subnetID, testConfig.AWSRegion)
 		assert.Equal(t, "available", string(subnet.State))
 		assert.True(t, *subnet.MapPublicIpOnLaunch)
 
-		// Validate subnet is in correct AZ
-		expectedAZ := fmt.Sprintf("%s%s", testConfig.AWSRegion, []string{"a", "b"}[i])
-		assert.Equal(t, expectedAZ, *subnet.AvailabilityZone)
-
 		// Validate CIDR blocks
 		cidrBlocks := common.GetVPCCIDRBlocks()
 		expectedCIDR := []string{cidrBlocks["public_subnet_a_cidr"], cidrBlocks["public_subnet_b_cidr"]}[i]
@@ -81,23 +100,26 @@ func TestNetworkingModuleCreatesPublicSubnets(t *testing.T) {
 }
 
 func TestNetworkingModuleCreatesPrivateSubnets(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/networking")
-	testVars := common.GetNetworkingTestVars()
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
 	testVars["create_private_subnets"] = true
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
+
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
 
-	terraform.InitAndApply(t, terraformOptions)
+	common.ApplyAndValidate(t, options)
 
 	// Validate private app subnets
-	privateSubnetIDs := terraform.OutputList(t, terraformOptions, "private_subnet_ids")
-	assert.Len(t, privateSubnetIDs, 2)
+	privateSubnetIDs := tfout.OutputList(t, terraformOptions, "private_subnet_ids").HasLen(2).AllMatch("^subnet-").Values()
+
+	az.AssertSubnetsInZones(t, testConfig.AWSRegion, privateSubnetIDs, testVars["availability_zones"].([]string))
 
 	for i, subnetID := range privateSubnetIDs {
-		subnet := common.GetSubnetById(t, subnetID, testConfig.AWSRegion)
+		subnet := awsval.GetSubnet(t, subnetID, testConfig.AWSRegion)
 		assert.Equal(t, "available", string(subnet.State))
 		assert.False(t, *subnet.MapPublicIpOnLaunch)
 
@@ -109,23 +131,26 @@ func TestNetworkingModuleCreatesPrivateSubnets(t *testing.T) {
 }
 
 func TestNetworkingModuleCreatesDatabaseSubnets(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/networking")
-	testVars := common.GetNetworkingTestVars()
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
 	testVars["create_db_subnets"] = true
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
 
-	terraform.InitAndApply(t, terraformOptions)
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
+
+	common.ApplyAndValidate(t, options)
 
 	// Validate database subnets
-	dbSubnetIDs := terraform.OutputList(t, terraformOptions, "private_db_subnet_ids")
-	assert.Len(t, dbSubnetIDs, 2)
+	dbSubnetIDs := tfout.OutputList(t, terraformOptions, "private_db_subnet_ids").HasLen(2).AllMatch("^subnet-").Values()
+
+	az.AssertSubnetsInZones(t, testConfig.AWSRegion, dbSubnetIDs, testVars["availability_zones"].([]string))
 
 	for i, subnetID := range dbSubnetIDs {
-		subnet := common.GetSubnetById(t, subnetID, testConfig.AWSRegion)
+		subnet := awsval.GetSubnet(t, subnetID, testConfig.AWSRegion)
 		assert.Equal(t, "available", string(subnet.State))
 		assert.False(t, *subnet.MapPublicIpOnLaunch)
 
@@ -137,19 +162,21 @@ func TestNetworkingModuleCreatesDatabaseSubnets(t *testing.T) {
 }
 
 func TestNetworkingModuleCreatesInternetGateway(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/networking")
-	testVars := common.GetNetworkingTestVars()
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
+
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
 
-	terraform.InitAndApply(t, terraformOptions)
+	common.ApplyAndValidate(t, options)
 
 	// Validate Internet Gateway
-	vpcID := terraform.Output(t, terraformOptions, "vpc_id")
-	igws := common.GetInternetGatewaysForVpc(t, vpcID, testConfig.AWSRegion)
+	vpcID := tfout.Output[string](t, terraformOptions, "vpc_id")
+	igws := awsval.GetInternetGateways(t, vpcID, testConfig.AWSRegion)
 	assert.Len(t, igws, 1)
 
 	igw := igws[0]
@@ -162,20 +189,22 @@ func TestNetworkingModuleCreatesInternetGateway(t *testing.T) {
 }
 
 func TestNetworkingModuleCreatesVPCEndpoints(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/networking")
-	testVars := common.GetNetworkingTestVars()
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
 	testVars["create_vpc_endpoints"] = true
 	testVars["create_private_subnets"] = true
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
 
-	terraform.InitAndApply(t, terraformOptions)
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
+
+	common.ApplyAndValidate(t, options)
 
 	// Validate VPC endpoints exist
-	vpcID := terraform.Output(t, terraformOptions, "vpc_id")
+	vpcID := tfout.Output[string](t, terraformOptions, "vpc_id")
 
 	// We should have interface endpoints for CloudWatch Logs, Secrets Manager, and Geo Places
 	// Plus a gateway endpoint for S3
@@ -188,29 +217,30 @@ func TestNetworkingModuleCreatesVPCEndpoints(t *testing.T) {
 }
 
 func TestNetworkingModuleSkipsResourcesWhenDisabled(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/networking")
-	testVars := common.GetNetworkingTestVars()
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
 	// Disable optional components
 	testVars["create_public_subnets"] = false
 	testVars["create_private_subnets"] = false
 	testVars["create_db_subnets"] = false
 	testVars["create_vpc_endpoints"] = false
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
+
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
 
-	terraform.InitAndApply(t, terraformOptions)
+	common.ApplyAndValidate(t, options)
 
 	// Should only create VPC and IGW
-	vpcID := terraform.Output(t, terraformOptions, "vpc_id")
-	assert.NotEmpty(t, vpcID)
+	tfout.OutputString(t, terraformOptions, "vpc_id").NotEmpty()
 
 	// Subnet outputs should be empty when creation is disabled
-	publicSubnets := terraform.OutputList(t, terraformOptions, "public_subnet_ids")
-	privateSubnets := terraform.OutputList(t, terraformOptions, "private_subnet_ids")
-	dbSubnets := terraform.OutputList(t, terraformOptions, "private_db_subnet_ids")
+	publicSubnets := tfout.Output[[]string](t, terraformOptions, "public_subnet_ids")
+	privateSubnets := tfout.Output[[]string](t, terraformOptions, "private_subnet_ids")
+	dbSubnets := tfout.Output[[]string](t, terraformOptions, "private_db_subnet_ids")
 
 	assert.Empty(t, publicSubnets)
 	assert.Empty(t, privateSubnets)
@@ -218,51 +248,42 @@ func TestNetworkingModuleSkipsResourcesWhenDisabled(t *testing.T) {
 }
 
 func TestNetworkingModuleValidatesResourceNaming(t *testing.T) {
-	common.SkipIfShortTest(t)
-
-	testConfig := common.NewTestConfig("../../modules/networking")
-	testVars := common.GetNetworkingTestVars()
+	common.RequireTier(t, common.TierApply)
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
 
-	terraform.InitAndApply(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
 
-	// Validate VPC naming
-	vpcID := terraform.Output(t, terraformOptions, "vpc_id")
-	vpc := terratestaws.GetVpcById(t, vpcID, testConfig.AWSRegion)
-
-	if nameTag, exists := vpc.Tags["Name"]; exists {
-		common.ValidateResourceNaming(t, nameTag, testConfig.Prefix, "vpc")
-	}
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
 
-	// Validate subnet naming
-	publicSubnetIDs := terraform.OutputList(t, terraformOptions, "public_subnet_ids")
-	for _, subnetID := range publicSubnetIDs {
-		subnet := common.GetSubnetById(t, subnetID, testConfig.AWSRegion)
-		// Note: Tag validation simplified - EC2 tags use complex structure
-		assert.NotNil(t, subnet)
-	}
+	// VPCs and subnets have no name attribute, so their Name tags are checked
+	common.ApplyAndValidate(t, options,
+		common.Outputs("vpc_id", "public_subnet_ids"),
+		common.NamingConventions(testConfig.Prefix),
+	)
 }
 
 // TestPrivateSubnetRouting verifies that private app subnets have no default route (0.0.0.0/0)
 // and rely solely on VPC endpoints for AWS service access
 func TestPrivateSubnetRouting(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/networking")
-	testVars := common.GetNetworkingTestVars()
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
 	testVars["create_private_subnets"] = true
 	testVars["create_vpc_endpoints"] = true
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
+
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
 
-	terraform.InitAndApply(t, terraformOptions)
+	common.ApplyAndValidate(t, options)
 
 	// Get the private app route table ID
-	privateAppRouteTableID := terraform.Output(t, terraformOptions, "private_app_route_table_id")
-	assert.NotEmpty(t, privateAppRouteTableID)
+	privateAppRouteTableID := tfout.OutputString(t, terraformOptions, "private_app_route_table_id").NotEmpty().Value()
 
 	// Create AWS client to examine route table directly
 	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(testConfig.AWSRegion))
@@ -293,28 +314,28 @@ func TestPrivateSubnetRouting(t *testing.T) {
 	assert.False(t, hasDefaultRoute, "Private app route table should not have a default route (0.0.0.0/0)")
 
 	// Verify VPC endpoints are created
-	s3EndpointID := terraform.Output(t, terraformOptions, "s3_endpoint_id")
-	assert.NotEmpty(t, s3EndpointID, "S3 VPC endpoint should be created")
+	tfout.OutputString(t, terraformOptions, "s3_endpoint_id").NotEmpty()
 
-	endpointsSecurityGroupID := terraform.Output(t, terraformOptions, "endpoints_security_group_id")
-	assert.NotEmpty(t, endpointsSecurityGroupID, "VPC endpoints security group should be created")
+	tfout.OutputString(t, terraformOptions, "endpoints_security_group_id").NotEmpty()
 }
 
 // TestVPCEndpointsConfiguration verifies VPC endpoints are properly configured for private subnet access
 func TestVPCEndpointsConfiguration(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/networking")
-	testVars := common.GetNetworkingTestVars()
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
 	testVars["create_vpc_endpoints"] = true
 	testVars["create_private_subnets"] = true
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
 
-	terraform.InitAndApply(t, terraformOptions)
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
 
-	vpcID := terraform.Output(t, terraformOptions, "vpc_id")
+	common.ApplyAndValidate(t, options)
+
+	vpcID := tfout.Output[string](t, terraformOptions, "vpc_id")
 
 	// Create AWS client
 	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(testConfig.AWSRegion))
@@ -323,8 +344,7 @@ func TestVPCEndpointsConfiguration(t *testing.T) {
 	ec2Client := ec2.NewFromConfig(cfg)
 
 	// Check S3 Gateway endpoint
-	s3EndpointID := terraform.Output(t, terraformOptions, "s3_endpoint_id")
-	assert.NotEmpty(t, s3EndpointID, "S3 Gateway endpoint ID should not be empty")
+	s3EndpointID := tfout.OutputString(t, terraformOptions, "s3_endpoint_id").NotEmpty().Value()
 
 	s3Input := &ec2.DescribeVpcEndpointsInput{
 		VpcEndpointIds: []string{s3EndpointID},
@@ -339,12 +359,22 @@ func TestVPCEndpointsConfiguration(t *testing.T) {
 	assert.Contains(t, *s3Endpoint.ServiceName, "s3")
 
 	// Check interface endpoints
-	interfaceEndpoints := terraform.OutputMap(t, terraformOptions, "interface_endpoints")
-	assert.NotEmpty(t, interfaceEndpoints, "Interface endpoints should be created")
+	// Every interface endpoint has an hourly cost, so the set must match exactly
+	interfaceEndpoints := tfout.OutputMap(t, terraformOptions, "interface_endpoints").
+		HasKeys(expectedInterfaceEndpoints...).Values()
+
+	vpcInterfaceEndpoints, err := ec2Client.DescribeVpcEndpoints(context.TODO(), &ec2.DescribeVpcEndpointsInput{
+		Filters: []types.Filter{
+			{Name: aws.String("vpc-id"), Values: []string{vpcID}},
+			{Name: aws.String("vpc-endpoint-type"), Values: []string{string(types.VpcEndpointTypeInterface)}},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, vpcInterfaceEndpoints.VpcEndpoints, len(expectedInterfaceEndpoints),
+		"VPC should not contain interface endpoints beyond the expected services")
 
 	// Verify expected endpoints exist
-	expectedServices := []string{"logs", "secretsmanager", "geo_places"}
-	for _, service := range expectedServices {
+	for _, service := range expectedInterfaceEndpoints {
 		endpointID, exists := interfaceEndpoints[service]
 		assert.True(t, exists, fmt.Sprintf("Interface endpoint for %s should exist", service))
 
@@ -364,21 +394,55 @@ func TestVPCEndpointsConfiguration(t *testing.T) {
 	}
 }
 
+// TestNetworkingModuleDestroysWithEndpoints applies the module with interface endpoints in both zones and destroys
+// it straight away. Endpoint ENIs keep the subnets and endpoint security group in use until AWS releases them, which
+// is what usually wedges a destroy, so the destroy must finish within NetworkingDestroyBudget and fail on a resource
+// still in use at most MaxDependencyViolationRetries times.
+func TestNetworkingModuleDestroysWithEndpoints(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	namespace := common.NewNamespace(tfopts.NewTestConfig("../../modules/networking"), "networking-destroy")
+	testVars := withAvailabilityZones(t, namespace.Config.AWSRegion, common.GetNetworkingTestVars())
+	testVars["create_vpc_endpoints"] = true
+	testVars["create_private_subnets"] = true
+	testVars["enable_single_az_endpoints"] = false
+
+	options := namespace.GetModuleTerraformOptions(t, "../../modules/networking", testVars)
+
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, namespace.Config.AWSRegion, namespace.Prefix)
+
+	common.ApplyAndValidate(t, options)
+	tfout.OutputMap(t, terraformOptions, "interface_endpoints").HasKeys(expectedInterfaceEndpoints...)
+
+	report, err := common.DestroyWithReportE(t, terraformOptions, common.DefaultDestroyRetryPolicy())
+	diagnostics := report.Diagnostics(common.DestroyStallThreshold)
+	t.Log(diagnostics)
+
+	require.NoError(t, err, "Destroy did not finish\n%s", diagnostics)
+	assert.LessOrEqual(t, report.Duration, common.NetworkingDestroyBudget,
+		"Destroy took longer than its budget\n%s", diagnostics)
+	assert.LessOrEqual(t, report.DependencyViolationRetries(), common.MaxDependencyViolationRetries,
+		"Destroy failed on resources still in use too often\n%s", diagnostics)
+}
+
 // TestCostOptimization verifies the design avoids NAT Gateway costs
 func TestCostOptimization(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/networking")
-	testVars := common.GetNetworkingTestVars()
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
 	testVars["create_private_subnets"] = true
 	testVars["create_vpc_endpoints"] = true
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
-	defer common.CleanupResources(t, terraformOptions)
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
+
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
 
-	terraform.InitAndApply(t, terraformOptions)
+	common.ApplyAndValidate(t, options)
 
-	vpcID := terraform.Output(t, terraformOptions, "vpc_id")
+	vpcID := tfout.Output[string](t, terraformOptions, "vpc_id")
 
 	// Create AWS client
 	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(testConfig.AWSRegion))
@@ -401,31 +465,31 @@ func TestCostOptimization(t *testing.T) {
 	assert.Empty(t, natResult.NatGateways, "No NAT Gateways should exist - cost optimization through VPC endpoints")
 
 	// Verify VPC endpoints are created as cost-effective alternative
-	s3EndpointID := terraform.Output(t, terraformOptions, "s3_endpoint_id")
-	assert.NotEmpty(t, s3EndpointID, "S3 VPC endpoint should replace NAT Gateway for AWS service access")
+	tfout.OutputString(t, terraformOptions, "s3_endpoint_id").NotEmpty()
 }
 
 // TestEndpointSubnetLogic verifies both single-AZ and multi-AZ VPC endpoint configurations
 func TestEndpointSubnetLogic(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
 	// Test single-AZ endpoint configuration (cost optimization)
 	t.Run("SingleAZEndpoints", func(t *testing.T) {
-		testConfig := common.NewTestConfig("../../modules/networking")
-		testVars := common.GetNetworkingTestVars()
+		testConfig := tfopts.NewTestConfig("../../modules/networking")
+		testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
 		testVars["create_vpc_endpoints"] = true
 		testVars["create_private_subnets"] = true
 		testVars["enable_single_az_endpoints"] = true
 
-		terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
-		defer common.CleanupResources(t, terraformOptions)
+		options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
+		terraformOptions := options.Options
+		common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
 
-		terraform.InitAndApply(t, terraformOptions)
+		common.ApplyAndValidate(t, options)
 
 		// Validate that interface endpoints are created in only one subnet
-		vpcID := terraform.Output(t, terraformOptions, "vpc_id")
-		privateSubnetIDs := terraform.OutputList(t, terraformOptions, "private_subnet_ids")
-		assert.Len(t, privateSubnetIDs, 2, "Should have 2 private subnets available")
+		vpcID := tfout.Output[string](t, terraformOptions, "vpc_id")
+		privateSubnetIDs := tfout.OutputList(t, terraformOptions, "private_subnet_ids").
+			HasLen(2).AllMatch("^subnet-").Values()
 
 		// Create AWS client to check endpoint subnet configuration
 		cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(testConfig.AWSRegion))
@@ -470,21 +534,22 @@ func TestEndpointSubnetLogic(t *testing.T) {
 
 	// Test multi-AZ endpoint configuration (high availability)
 	t.Run("MultiAZEndpoints", func(t *testing.T) {
-		testConfig := common.NewTestConfig("../../modules/networking")
-		testVars := common.GetNetworkingTestVars()
+		testConfig := tfopts.NewTestConfig("../../modules/networking")
+		testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
 		testVars["create_vpc_endpoints"] = true
 		testVars["create_private_subnets"] = true
 		testVars["enable_single_az_endpoints"] = false
 
-		terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
-		defer common.CleanupResources(t, terraformOptions)
+		options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
+		terraformOptions := options.Options
+		common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
 
-		terraform.InitAndApply(t, terraformOptions)
+		common.ApplyAndValidate(t, options)
 
 		// Validate that interface endpoints are created in multiple subnets
-		vpcID := terraform.Output(t, terraformOptions, "vpc_id")
-		privateSubnetIDs := terraform.OutputList(t, terraformOptions, "private_subnet_ids")
-		assert.Len(t, privateSubnetIDs, 2, "Should have 2 private subnets available")
+		vpcID := tfout.Output[string](t, terraformOptions, "vpc_id")
+		privateSubnetIDs := tfout.OutputList(t, terraformOptions, "private_subnet_ids").
+			HasLen(2).AllMatch("^subnet-").Values()
 
 		// Create AWS client to check endpoint subnet configuration
 		cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(testConfig.AWSRegion))
@@ -531,20 +596,548 @@ func TestEndpointSubnetLogic(t *testing.T) {
 
 	// Test validation: single-AZ with no private subnets should fail
 	t.Run("ValidationFailureNoSubnets", func(t *testing.T) {
-		testConfig := common.NewTestConfig("../../modules/networking")
+		testConfig := tfopts.NewTestConfig("../../modules/networking")
 		testVars := common.GetNetworkingTestVars()
 		testVars["create_vpc_endpoints"] = true
 		testVars["create_private_subnets"] = false // No private subnets
 		testVars["enable_single_az_endpoints"] = true
 		testVars["private_subnet_ids"] = []string{} // Empty existing subnets
 
-		terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
-		defer common.CleanupResources(t, terraformOptions)
+		terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly("../../modules/networking", testVars)
 
-		// This should fail validation during plan/apply
-		_, err := terraform.InitAndPlanE(t, terraformOptions)
+		// This should fail validation during plan
+		_, err := common.PlanOnlyE(t, terraformOptions)
 		assert.Error(t, err, "Should fail when enable_single_az_endpoints=true but no private subnets configured")
 		assert.Contains(t, err.Error(), "at least one private subnet must be configured",
 			"Error should mention subnet requirement")
 	})
 }
+
+// TestNetworkingPlanWithoutInterfaceEndpoints verifies that private subnets without interface endpoints are
+// planned offline: no interface endpoints, no NAT gateway and no default route, only the free S3 gateway
+func TestNetworkingPlanWithoutInterfaceEndpoints(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := common.GetNetworkingTestVars()
+	testVars["create_private_subnets"] = true
+	testVars["create_vpc_endpoints"] = false
+
+	terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly("../../modules/networking", testVars)
+	plan := common.PlanOnly(t, terraformOptions)
+
+	policy.AssertNoResourceType(t, plan, "aws_nat_gateway")
+	assert.NotContains(t, plan.ResourcePlannedValuesMap, "aws_security_group.vpc_endpoints[0]")
+
+	for _, endpoint := range common.GetPlannedResourcesByType(plan, "aws_vpc_endpoint") {
+		assert.Equal(t, "Gateway", common.GetPlannedStringAttribute(endpoint, "vpc_endpoint_type"),
+			"%s should not be an interface endpoint when create_vpc_endpoints is false", endpoint.Address)
+	}
+
+	privateRouteTable, exists := plan.ResourcePlannedValuesMap["aws_route_table.private_app[0]"]
+	assert.True(t, exists, "Private app route table should be planned")
+	if exists {
+		routes, _ := privateRouteTable.AttributeValues["route"].([]interface{})
+		for _, route := range routes {
+			routeAttributes, _ := route.(map[string]interface{})
+			assert.NotEqual(t, "0.0.0.0/0", routeAttributes["cidr_block"], "Private app subnets should stay offline")
+		}
+	}
+}
+
+// TestNetworkingPlanInterfaceEndpointsMatchExpectedServices verifies no interface endpoint is silently added
+func TestNetworkingPlanInterfaceEndpointsMatchExpectedServices(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := common.GetNetworkingTestVars()
+	testVars["create_private_subnets"] = true
+	testVars["create_vpc_endpoints"] = true
+
+	terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly("../../modules/networking", testVars)
+	plan := common.PlanOnly(t, terraformOptions)
+
+	var plannedServices []string
+	for _, endpoint := range common.GetPlannedResourcesByType(plan, "aws_vpc_endpoint") {
+		if common.GetPlannedStringAttribute(endpoint, "vpc_endpoint_type") == "Interface" {
+			plannedServices = append(plannedServices, fmt.Sprint(endpoint.Index))
+		}
+	}
+	assert.ElementsMatch(t, expectedInterfaceEndpoints, plannedServices,
+		"Interface endpoints should match the expected services exactly")
+}
+
+// TestPrivateSubnetsWithoutInterfaceEndpoints applies private subnets with endpoints disabled and verifies
+// only the S3 gateway endpoint exists and there is no NAT gateway
+func TestPrivateSubnetsWithoutInterfaceEndpoints(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
+	testVars["create_private_subnets"] = true
+	testVars["create_vpc_endpoints"] = false
+
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
+
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
+
+	common.ApplyAndValidate(t, options)
+
+	vpcID := tfout.Output[string](t, terraformOptions, "vpc_id")
+	assert.Empty(t, tfout.Output[map[string]string](t, terraformOptions, "interface_endpoints"))
+
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(testConfig.AWSRegion))
+	assert.NoError(t, err)
+	ec2Client := ec2.NewFromConfig(cfg)
+
+	vpcFilter := []types.Filter{{Name: aws.String("vpc-id"), Values: []string{vpcID}}}
+
+	endpoints, err := ec2Client.DescribeVpcEndpoints(context.TODO(), &ec2.DescribeVpcEndpointsInput{Filters: vpcFilter})
+	assert.NoError(t, err)
+	assert.Len(t, endpoints.VpcEndpoints, 1, "Only the S3 gateway endpoint should exist")
+	for _, endpoint := range endpoints.VpcEndpoints {
+		assert.Equal(t, types.VpcEndpointTypeGateway, endpoint.VpcEndpointType)
+	}
+
+	natGateways, err := ec2Client.DescribeNatGateways(context.TODO(), &ec2.DescribeNatGatewaysInput{Filter: vpcFilter})
+	assert.NoError(t, err)
+	assert.Empty(t, natGateways.NatGateways, "Private subnets without endpoints should not fall back to a NAT gateway")
+}
+
+// networkingToggleCounts returns the instances the networking module should plan for a combination of toggles
+// with create_vpc=true
+func networkingToggleCounts(public, private, db, endpoints bool) map[string]int {
+	instances := func(enabled bool, n int) int {
+		if enabled {
+			return n
+		}
+		return 0
+	}
+
+	return map[string]int{
+		"aws_vpc.main":                               1,
+		"aws_internet_gateway.igw":                   1,
+		"aws_vpc_endpoint.s3":                        1,
+		"aws_subnet.public_a":                        instances(public, 1),
+		"aws_subnet.public_b":                        instances(public, 1),
+		"aws_route_table.public":                     instances(public, 1),
+		"aws_route.public_internet_gateway_new":      instances(public, 1),
+		"aws_route.public_internet_gateway_existing": 0,
+		"aws_route_table_association.public_a":       instances(public, 1),
+		"aws_route_table_association.public_b":       instances(public, 1),
+		"aws_subnet.private_a":                       instances(private, 1),
+		"aws_subnet.private_b":                       instances(private, 1),
+		"aws_route_table.private_app":                instances(private, 1),
+		"aws_route_table_association.private_app_a":  instances(private, 1),
+		"aws_route_table_association.private_app_b":  instances(private, 1),
+		"aws_subnet.private_db_a":                    instances(db, 1),
+		"aws_subnet.private_db_b":                    instances(db, 1),
+		"aws_route_table.private_db":                 instances(db, 1),
+		"aws_route_table_association.private_db_a":   instances(db, 1),
+		"aws_route_table_association.private_db_b":   instances(db, 1),
+		"aws_security_group.vpc_endpoints":           instances(endpoints, 1),
+		"aws_vpc_endpoint.interface":                 instances(endpoints, len(expectedInterfaceEndpoints)),
+	}
+}
+
+// TestNetworkingConditionalResourceMatrix plans a curated set of toggle combinations and checks every conditional
+// resource, so a broken count or for_each condition is caught with the combination that exposed it
+func TestNetworkingConditionalResourceMatrix(t *testing.T) {
+	baseVars := common.GetNetworkingTestVars()
+
+	common.RunToggleMatrix(t, "../../modules/networking", baseVars, []common.ToggleCase{
+		{
+			Name:   "EverythingCreated",
+			Vars:   map[string]interface{}{"create_vpc_endpoints": true},
+			Counts: networkingToggleCounts(true, true, true, true),
+		},
+		{
+			Name:   "WithoutInterfaceEndpoints",
+			Vars:   map[string]interface{}{"create_vpc_endpoints": false},
+			Counts: networkingToggleCounts(true, true, true, false),
+		},
+		{
+			Name: "WithoutPublicSubnets",
+			Vars: map[string]interface{}{
+				"create_public_subnets": false,
+				"create_vpc_endpoints":  true,
+			},
+			Counts: networkingToggleCounts(false, true, true, true),
+		},
+		{
+			Name: "WithoutPrivateSubnets",
+			Vars: map[string]interface{}{
+				"create_private_subnets": false,
+				"create_vpc_endpoints":   false,
+			},
+			Counts: networkingToggleCounts(true, false, true, false),
+		},
+		{
+			Name: "WithoutDatabaseSubnets",
+			Vars: map[string]interface{}{
+				"create_db_subnets":    false,
+				"create_vpc_endpoints": false,
+			},
+			Counts: networkingToggleCounts(true, true, false, false),
+		},
+		{
+			Name: "OnlyVPCCreated",
+			Vars: map[string]interface{}{
+				"create_public_subnets":  false,
+				"create_private_subnets": false,
+				"create_db_subnets":      false,
+				"create_vpc_endpoints":   false,
+			},
+			Counts: networkingToggleCounts(false, false, false, false),
+		},
+	})
+}
+
+// TestNetworkingRejectsInconsistentNetworkInputs verifies that combinations mixing a new VPC with existing
+// networking, or existing networking with missing inputs, fail validation before anything is planned
+func TestNetworkingRejectsInconsistentNetworkInputs(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	existingVPC := "vpc-0123456789abcdef0"
+	existingSubnets := []string{"subnet-0123456789abcdef0", "subnet-0fedcba9876543210"}
+
+	testCases := []struct {
+		name          string
+		vars          map[string]interface{}
+		expectedError string
+	}{
+		{
+			name:          "ExistingVPCWithoutID",
+			vars:          map[string]interface{}{"create_vpc": false},
+			expectedError: "vpc_id must be the ID of an existing VPC",
+		},
+		{
+			name:          "VPCIDWithNewVPC",
+			vars:          map[string]interface{}{"create_vpc": true, "vpc_id": existingVPC},
+			expectedError: "vpc_id must be the ID of an existing VPC",
+		},
+		{
+			name: "ExistingSubnetsInNewVPC",
+			vars: map[string]interface{}{
+				"create_public_subnets": false,
+				"public_subnet_ids":     existingSubnets,
+			},
+			expectedError: "public_subnet_ids can only be set when using an existing VPC",
+		},
+		{
+			name: "SubnetIDsIgnoredWhenCreatingSubnets",
+			vars: map[string]interface{}{
+				"create_vpc":    false,
+				"vpc_id":        existingVPC,
+				"db_subnet_ids": existingSubnets,
+			},
+			expectedError: "db_subnet_ids can only be set when using an existing VPC",
+		},
+		{
+			name: "NewDatabaseSubnetsInExistingVPCWithoutCIDRs",
+			vars: map[string]interface{}{
+				"create_vpc":               false,
+				"vpc_id":                   existingVPC,
+				"private_db_subnet_a_cidr": "",
+				"private_db_subnet_b_cidr": "",
+			},
+			expectedError: "private_db_subnet_a_cidr must be a valid CIDR block when create_db_subnets is true",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testConfig := tfopts.NewTestConfig("../../modules/networking")
+			testVars := common.GetNetworkingTestVars()
+			for name, value := range tc.vars {
+				testVars[name] = value
+			}
+
+			terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly("../../modules/networking", testVars)
+			_, err := common.PlanOnlyE(t, terraformOptions)
+			require.Error(t, err, "Plan should fail validation")
+			assert.Contains(t, err.Error(), tc.expectedError)
+		})
+	}
+}
+
+// TestNetworkingPlanWithExistingVPC plans the create_vpc=false path against the account's default VPC, which
+// always has an internet gateway attached, and checks existing networking is referenced rather than recreated
+func TestNetworkingPlanWithExistingVPC(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	region := tfopts.NewTestConfig("../../modules/networking").AWSRegion
+	defaultVPC, err := terratestaws.GetDefaultVpcE(t, region)
+	if err != nil {
+		t.Skipf("No default VPC in %s to stand in for an existing VPC: %v", region, err)
+	}
+	subnetIDs := terratestaws.GetDefaultSubnetIDsForVpc(t, *defaultVPC)
+	require.GreaterOrEqual(t, len(subnetIDs), 2, "Default VPC should have a subnet in at least two AZs")
+	existingSubnets := subnetIDs[:2]
+
+	t.Run("ExistingSubnets", func(t *testing.T) {
+		testConfig := tfopts.NewTestConfig("../../modules/networking")
+		testVars := common.GetNetworkingTestVars()
+		testVars["create_vpc"] = false
+		testVars["vpc_id"] = defaultVPC.Id
+		testVars["create_public_subnets"] = false
+		testVars["public_subnet_ids"] = existingSubnets
+		testVars["create_private_subnets"] = false
+		testVars["private_subnet_ids"] = existingSubnets
+		testVars["create_db_subnets"] = false
+		testVars["db_subnet_ids"] = existingSubnets
+		testVars["create_vpc_endpoints"] = false
+
+		terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly("../../modules/networking", testVars)
+		plan := common.PlanOnly(t, terraformOptions)
+
+		for _, resourceType := range []string{
+			"aws_vpc", "aws_subnet", "aws_internet_gateway", "aws_route_table", "aws_route",
+			"aws_route_table_association", "aws_security_group",
+		} {
+			assert.Empty(t, common.GetPlannedResourcesByType(plan, resourceType),
+				"No %s should be created when all networking already exists", resourceType)
+		}
+
+		// The S3 gateway endpoint is always created; with no managed route tables it is attached to none
+		endpoint, exists := plan.ResourcePlannedValuesMap["aws_vpc_endpoint.s3"]
+		require.True(t, exists, "S3 gateway endpoint should be planned")
+		assert.Equal(t, defaultVPC.Id, common.GetPlannedStringAttribute(endpoint, "vpc_id"))
+		assert.Empty(t, endpoint.AttributeValues["route_table_ids"])
+
+		assert.Equal(t, defaultVPC.Id, common.GetPlannedOutput(plan, "vpc_id"))
+		assert.Equal(t, aws.ToString(defaultVPC.CidrBlock), common.GetPlannedOutput(plan, "vpc_cidr"))
+		for _, output := range []string{"public_subnet_ids", "private_subnet_ids", "private_db_subnet_ids"} {
+			assert.ElementsMatch(t, existingSubnets, common.GetPlannedOutput(plan, output),
+				"%s should pass the existing subnets through", output)
+		}
+	})
+
+	t.Run("NewSubnetsInExistingVPC", func(t *testing.T) {
+		testConfig := tfopts.NewTestConfig("../../modules/networking")
+		testVars := common.GetNetworkingTestVars()
+		testVars["create_vpc"] = false
+		testVars["vpc_id"] = defaultVPC.Id
+		testVars["create_vpc_endpoints"] = false
+		// Plan only, so these are never allocated; they sit at the top of the default 172.31.0.0/16 range
+		testVars["public_subnet_a_cidr"] = "172.31.250.0/24"
+		testVars["public_subnet_b_cidr"] = "172.31.251.0/24"
+		testVars["private_subnet_a_cidr"] = "172.31.252.0/24"
+		testVars["private_subnet_b_cidr"] = "172.31.253.0/24"
+		testVars["private_db_subnet_a_cidr"] = "172.31.254.0/24"
+		testVars["private_db_subnet_b_cidr"] = "172.31.255.0/24"
+
+		terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly("../../modules/networking", testVars)
+		plan := common.PlanOnly(t, terraformOptions)
+
+		assert.Empty(t, common.GetPlannedResourcesByType(plan, "aws_vpc"), "No VPC should be created")
+		assert.Empty(t, common.GetPlannedResourcesByType(plan, "aws_internet_gateway"),
+			"The existing VPC's internet gateway should be reused")
+
+		subnets := common.GetPlannedResourcesByType(plan, "aws_subnet")
+		assert.Len(t, subnets, 6)
+		for _, subnet := range subnets {
+			assert.Equal(t, defaultVPC.Id, common.GetPlannedStringAttribute(subnet, "vpc_id"),
+				"%s should be created in the existing VPC", subnet.Address)
+		}
+
+		// The public route must use the internet gateway already attached to the VPC
+		assert.NotContains(t, plan.ResourcePlannedValuesMap, "aws_route.public_internet_gateway_new[0]")
+		route, exists := plan.ResourcePlannedValuesMap["aws_route.public_internet_gateway_existing[0]"]
+		require.True(t, exists, "Public route to the existing internet gateway should be planned")
+		assert.Equal(t, getAttachedInternetGatewayID(t, region, defaultVPC.Id),
+			common.GetPlannedStringAttribute(route, "gateway_id"))
+	})
+}
+
+// TestNetworkingModuleUsesExistingVPC applies the module into a VPC created by a separate instance of it, the way
+// a deployment would use a VPC managed elsewhere, and verifies nothing is duplicated
+func TestNetworkingModuleUsesExistingVPC(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	// The existing VPC, with public and private subnets but no database subnets
+	hostConfig := tfopts.NewTestConfig("../../modules/networking")
+	hostVars := withAvailabilityZones(t, hostConfig.AWSRegion, common.GetNetworkingTestVars())
+	hostVars["create_db_subnets"] = false
+	hostVars["create_vpc_endpoints"] = false
+
+	host := hostConfig.GetModuleTerraformOptions("../../modules/networking", hostVars)
+	hostOptions := host.Options
+	common.RegisterNetworkCleanup(t, hostOptions, hostConfig.AWSRegion, hostConfig.Prefix)
+
+	common.ApplyAndValidate(t, host)
+
+	vpcID := tfout.Output[string](t, hostOptions, "vpc_id")
+	publicSubnetIDs := tfout.Output[[]string](t, hostOptions, "public_subnet_ids")
+	privateSubnetIDs := tfout.Output[[]string](t, hostOptions, "private_subnet_ids")
+
+	// Reuse the VPC and its subnets, adding only the database subnets
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
+	testVars["create_vpc"] = false
+	testVars["vpc_id"] = vpcID
+	testVars["create_public_subnets"] = false
+	testVars["public_subnet_ids"] = publicSubnetIDs
+	testVars["create_private_subnets"] = false
+	testVars["private_subnet_ids"] = privateSubnetIDs
+	testVars["create_vpc_endpoints"] = false
+
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
+	terraformOptions := options.Options
+
+	// Registered after the host, so it is destroyed before the host VPC; the leak check runs on the host, which
+	// owns the VPC
+	common.ApplyAndValidate(t, options)
+
+	assert.Equal(t, vpcID, tfout.Output[string](t, terraformOptions, "vpc_id"))
+	assert.Equal(t, hostVars["vpc_cidr"], tfout.Output[string](t, terraformOptions, "vpc_cidr"))
+	assert.ElementsMatch(t, publicSubnetIDs, tfout.Output[[]string](t, terraformOptions, "public_subnet_ids"))
+	assert.ElementsMatch(t, privateSubnetIDs, tfout.Output[[]string](t, terraformOptions, "private_subnet_ids"))
+
+	dbSubnetIDs := tfout.OutputList(t, terraformOptions, "private_db_subnet_ids").HasLen(2).AllMatch("^subnet-").Values()
+	az.AssertSubnetsInZones(t, testConfig.AWSRegion, dbSubnetIDs, testVars["availability_zones"].([]string))
+
+	// The VPC should now hold exactly the host's subnets plus the new database subnets, behind one gateway
+	expectedSubnetIDs := append(append(append([]string{}, publicSubnetIDs...), privateSubnetIDs...), dbSubnetIDs...)
+	var vpcSubnetIDs []string
+	for _, subnet := range terratestaws.GetSubnetsForVpc(t, vpcID, testConfig.AWSRegion) {
+		vpcSubnetIDs = append(vpcSubnetIDs, subnet.Id)
+	}
+	assert.ElementsMatch(t, expectedSubnetIDs, vpcSubnetIDs)
+	assert.NotEmpty(t, getAttachedInternetGatewayID(t, testConfig.AWSRegion, vpcID))
+
+	vpcs, err := terratestaws.GetVpcsE(t, []types.Filter{{
+		Name:   aws.String("tag:Name"),
+		Values: []string{testConfig.Prefix + "-vpc"},
+	}}, testConfig.AWSRegion)
+	require.NoError(t, err)
+	assert.Empty(t, vpcs, "No VPC should be created when create_vpc is false")
+}
+
+// TestNetworkingPlanDualStack plans the module with IPv6 enabled and checks the VPC and subnets are dual-stack and
+// private subnets egress through an egress-only internet gateway rather than a NAT gateway
+func TestNetworkingPlanDualStack(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	if !common.ModuleSupportsIPv6("../../modules/networking") {
+		t.Skipf("Networking module does not declare %s yet", common.IPv6Variable)
+	}
+
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := common.GetNetworkingTestVars()
+	testVars[common.IPv6Variable] = true
+
+	terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly("../../modules/networking", testVars)
+	plan := common.PlanOnly(t, terraformOptions)
+
+	common.AssertPlannedDualStack(t, plan)
+}
+
+// TestNetworkingModuleCreatesDualStackSubnets applies the module with IPv6 enabled and checks every subnet has an
+// IPv6 block from the VPC's, public subnets route ::/0 to the internet gateway and private subnets to the
+// egress-only internet gateway
+func TestNetworkingModuleCreatesDualStackSubnets(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	if !common.ModuleSupportsIPv6("../../modules/networking") {
+		t.Skipf("Networking module does not declare %s yet", common.IPv6Variable)
+	}
+
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
+	testVars[common.IPv6Variable] = true
+	testVars["create_vpc_endpoints"] = false
+
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
+
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
+
+	common.ApplyAndValidate(t, options)
+
+	vpcID := tfout.Output[string](t, terraformOptions, "vpc_id")
+	vpcIPv6CIDR := common.AssertVPCHasIPv6CIDR(t, vpcID, testConfig.AWSRegion)
+	internetGatewayID := getAttachedInternetGatewayID(t, testConfig.AWSRegion, vpcID)
+	egressOnlyGatewayID := common.AssertEgressOnlyInternetGateway(t, vpcID, testConfig.AWSRegion)
+
+	for _, subnetID := range tfout.Output[[]string](t, terraformOptions, "public_subnet_ids") {
+		common.AssertSubnetHasIPv6CIDR(t, subnetID, testConfig.AWSRegion, vpcIPv6CIDR)
+		common.AssertIPv6DefaultRoute(t, subnetID, testConfig.AWSRegion, internetGatewayID)
+	}
+	for _, subnetID := range tfout.Output[[]string](t, terraformOptions, "private_subnet_ids") {
+		common.AssertSubnetHasIPv6CIDR(t, subnetID, testConfig.AWSRegion, vpcIPv6CIDR)
+		common.AssertIPv6DefaultRoute(t, subnetID, testConfig.AWSRegion, egressOnlyGatewayID)
+	}
+	// Database subnets get IPv6 addresses too, but no route out to check
+	for _, subnetID := range tfout.Output[[]string](t, terraformOptions, "private_db_subnet_ids") {
+		common.AssertSubnetHasIPv6CIDR(t, subnetID, testConfig.AWSRegion, vpcIPv6CIDR)
+	}
+}
+
+// TestNetworkingPlanStaticEgress plans the module with static egress enabled and checks private app subnets get a
+// NAT gateway with an Elastic IP while database subnets stay without a route out
+func TestNetworkingPlanStaticEgress(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	if !common.ModuleProvidesStaticEgress("../../modules/networking") {
+		t.Skipf("Networking module does not declare %s yet", common.StaticEgressVariable)
+	}
+
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := common.GetNetworkingTestVars()
+	testVars[common.StaticEgressVariable] = true
+
+	terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly("../../modules/networking", testVars)
+	plan := common.PlanOnly(t, terraformOptions)
+
+	common.AssertPlannedStaticEgress(t, plan)
+}
+
+// TestNetworkingModuleStaticEgress applies the module with static egress enabled and checks private app subnets
+// route out through a NAT gateway whose address is in the egress IP output, and database subnets have no route to
+// the internet
+func TestNetworkingModuleStaticEgress(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	if !common.ModuleProvidesStaticEgress("../../modules/networking") {
+		t.Skipf("Networking module does not declare %s yet", common.StaticEgressVariable)
+	}
+
+	testConfig := tfopts.NewTestConfig("../../modules/networking")
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, common.GetNetworkingTestVars())
+	testVars[common.StaticEgressVariable] = true
+	testVars["create_vpc_endpoints"] = false
+
+	options := testConfig.GetModuleTerraformOptions("../../modules/networking", testVars)
+
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
+
+	common.ApplyAndValidate(t, options)
+
+	egressIPs := tfout.OutputList(t, terraformOptions, common.StaticEgressIPsOutput).NotEmpty().Values()
+	for _, subnetID := range tfout.Output[[]string](t, terraformOptions, "private_subnet_ids") {
+		natGatewayID := awsval.AssertDefaultRouteViaNAT(t, subnetID, testConfig.AWSRegion)
+		if natGatewayID != "" {
+			awsval.AssertNATGatewayEgressIPs(t, natGatewayID, testConfig.AWSRegion, egressIPs)
+		}
+	}
+	for _, subnetID := range tfout.Output[[]string](t, terraformOptions, "private_db_subnet_ids") {
+		awsval.AssertNoInternetRoute(t, subnetID, testConfig.AWSRegion)
+	}
+}
+
+// getAttachedInternetGatewayID returns the ID of the internet gateway attached to a VPC, failing if there is
+// not exactly one
+func getAttachedInternetGatewayID(t *testing.T, region, vpcID string) string {
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	require.NoError(t, err)
+
+	result, err := ec2.NewFromConfig(cfg).DescribeInternetGateways(context.TODO(), &ec2.DescribeInternetGatewaysInput{
+		Filters: []types.Filter{{Name: aws.String("attachment.vpc-id"), Values: []string{vpcID}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.InternetGateways, 1, "VPC %s should have exactly one internet gateway", vpcID)
+	return aws.ToString(result.InternetGateways[0].InternetGatewayId)
+}
@@ -0,0 +1,57 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCheckCloudFrontOriginFailover checks app behaviors on a load balancer origin need an origin group failing
+// over on the load balancer's errors, while S3 and static file behaviors do not
+func TestCheckCloudFrontOriginFailover(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	origins := []interface{}{
+		map[string]interface{}{"origin_id": "S3-assets", "s3_origin_config": []interface{}{map[string]interface{}{}}},
+		map[string]interface{}{"origin_id": "ALB", "custom_origin_config": []interface{}{map[string]interface{}{}}},
+	}
+	group := func(codes ...interface{}) []interface{} {
+		return []interface{}{map[string]interface{}{
+			"origin_id":         "App",
+			"failover_criteria": []interface{}{map[string]interface{}{"status_codes": codes}},
+			"member": []interface{}{
+				map[string]interface{}{"origin_id": "ALB"}, map[string]interface{}{"origin_id": "S3-assets"},
+			},
+		}}
+	}
+	check := func(defaultTarget string, groups []interface{}) []common.AuditFinding {
+		distribution := &tfjson.StateResource{Address: "aws_cloudfront_distribution.app",
+			Type: "aws_cloudfront_distribution", AttributeValues: map[string]interface{}{
+				"origin":                 origins,
+				"origin_group":           groups,
+				"default_cache_behavior": []interface{}{map[string]interface{}{"target_origin_id": defaultTarget}},
+				"ordered_cache_behavior": []interface{}{
+					map[string]interface{}{"path_pattern": "/static/*", "target_origin_id": "ALB"},
+				},
+			}}
+		return common.CheckCloudFrontOriginFailover(distribution, &common.AuditContext{})
+	}
+
+	assert.Empty(t, check("S3-assets", nil), "S3 and static file behaviors need no failover")
+
+	findings := check("App", group(500.0, 502.0, 503.0, 504.0))
+	assert.Len(t, findings, 1)
+	assert.True(t, findings[0].Passed, findings[0].Detail)
+
+	findings = check("App", group(503.0))
+	assert.Len(t, findings, 1)
+	assert.False(t, findings[0].Passed)
+	assert.Contains(t, findings[0].Detail, "500, 502, 504")
+
+	findings = check("ALB", nil)
+	assert.Len(t, findings, 1)
+	assert.False(t, findings[0].Passed, "App requests straight to the load balancer have no failover")
+}
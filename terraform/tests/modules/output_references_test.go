@@ -0,0 +1,16 @@
+package modules
+
+import (
+	"path/filepath"
+	"testing"
+
+	"terraform-tests/common"
+)
+
+// TestOutputReferencesDeclared parses the tests in this package and the module sources, so a renamed or removed
+// output fails in short mode instead of at the end of an apply
+func TestOutputReferencesDeclared(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	common.AssertOutputReferencesDeclared(t, ".", filepath.Join(terraformRoot, "modules"))
+}
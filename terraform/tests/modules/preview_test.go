@@ -0,0 +1,69 @@
+package modules
+
+import (
+	"path/filepath"
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPreviewVars checks each pull request gets a database of its own, and that a shared database replaces the
+// preview's own instance
+func TestPreviewVars(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	vars := common.PreviewVars(42, nil)
+	assert.Equal(t, "coalition_pr_42", vars["db_name"])
+	assert.NotContains(t, vars, "shared_database")
+	assert.NotEqual(t, vars["app_db_password"], common.PreviewVars(42, nil)["app_db_password"],
+		"Each preview should get its own passwords")
+
+	shared := &common.SharedPreviewDatabase{Endpoint: "shared-db.example.internal:5432", VPCID: "vpc-0a1b2c3d4e5f60718",
+		PrivateSubnetIDs: []string{"subnet-0a1b2c3d4e5f60718"}, DBSubnetCIDRs: []string{"10.0.21.0/24"}}
+	vars = common.PreviewVars(7, shared)
+	assert.Equal(t, "coalition_pr_7", vars["db_name"])
+	assert.Equal(t, "shared-db.example.internal:5432",
+		vars["shared_database"].(map[string]interface{})["endpoint"])
+}
+
+// TestDeployedAPIURL checks the deployed stage's URL is taken from a Zappa deploy's output
+func TestDeployedAPIURL(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	url, found := common.DeployedAPIURL("Calling deploy for stage pr42..\nUploading pr42-1700000000.zip (42.0MiB)..\n" +
+		"Deployment complete!: https://abc123def4.execute-api.us-east-1.amazonaws.com/pr42\n")
+	assert.True(t, found)
+	assert.Equal(t, "https://abc123def4.execute-api.us-east-1.amazonaws.com/pr42", url)
+
+	_, found = common.DeployedAPIURL("Error: Warning! Status check on the deployed lambda failed.")
+	assert.False(t, found)
+}
+
+// TestPreviewScenarioWorkingCopy checks a namespace can apply a scenario from a working copy, with the modules its
+// sources point at copied alongside it
+func TestPreviewScenarioWorkingCopy(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	namespace := common.NewNamespace(tfopts.NewTestConfig(common.PreviewScenario), "pr-42")
+	options := namespace.GetModuleTerraformOptions(t, "../scenarios/preview", common.PreviewVars(42, nil))
+
+	assert.Equal(t, "preview", filepath.Base(options.TerraformDir))
+	assert.FileExists(t, filepath.Join(options.TerraformDir, "main.tf"))
+	assert.FileExists(t, filepath.Join(options.TerraformDir, "../../../modules/database/main.tf"))
+	assert.Equal(t, namespace.Prefix, options.Vars["prefix"])
+}
+
+// TestPreviewDatabaseCanBeDestroyed checks the preview's own database turns off the database module's
+// prevent_destroy, which defaults to true and would fail every preview's destroy
+func TestPreviewDatabaseCanBeDestroyed(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	preventDestroy, set := common.GetModuleArgument(t, common.PreviewScenario, "database", "prevent_destroy")
+	assert.True(t, set, "The preview should set prevent_destroy on its database")
+	assert.Equal(t, "false", preventDestroy)
+	assert.Equal(t, "true", common.GetVariableDefault(t, "../../modules/database", "prevent_destroy"),
+		"The module's default is what the preview overrides")
+}
@@ -0,0 +1,80 @@
+package modules
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"terraform-tests/common"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestQueueAuditChecks runs the queue audit over a worker queue and its dead-letter queue, one of each done right
+// and one of each done wrong
+func TestQueueAuditChecks(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	common.QueueWorkerRuntimes["geocoding"] = 5 * time.Minute
+	common.QueueWorkerRuntimes["email"] = 5 * time.Minute
+	t.Cleanup(func() {
+		delete(common.QueueWorkerRuntimes, "geocoding")
+		delete(common.QueueWorkerRuntimes, "email")
+	})
+
+	queue := func(name, resourceName string, attributes map[string]interface{}) *tfjson.StateResource {
+		attributes["name"] = name
+		return &tfjson.StateResource{Address: "aws_sqs_queue." + resourceName, Type: "aws_sqs_queue",
+			Name: resourceName, AttributeValues: attributes}
+	}
+	redrive := func(deadLetterQueue string, maxReceiveCount int) string {
+		return fmt.Sprintf(`{"deadLetterTargetArn":"arn:aws:sqs:us-east-1:123456789012:%s","maxReceiveCount":%d}`,
+			deadLetterQueue, maxReceiveCount)
+	}
+	policy := func(name, document string) *tfjson.StateResource {
+		return &tfjson.StateResource{Address: "aws_iam_role_policy." + name, Type: "aws_iam_role_policy",
+			Name: name, AttributeValues: map[string]interface{}{"policy": document}}
+	}
+
+	audit := &common.AuditContext{Resources: []*tfjson.StateResource{
+		queue("coalition-geocoding", "geocoding", map[string]interface{}{
+			"redrive_policy":             redrive("coalition-geocoding-dlq", 5),
+			"visibility_timeout_seconds": 900.0,
+			"sqs_managed_sse_enabled":    true,
+		}),
+		queue("coalition-geocoding-dlq", "geocoding_dlq", map[string]interface{}{
+			"message_retention_seconds": 1209600.0,
+			"sqs_managed_sse_enabled":   true,
+		}),
+		queue("coalition-email", "email", map[string]interface{}{
+			"redrive_policy":             redrive("coalition-email-dlq", 50),
+			"visibility_timeout_seconds": 30.0,
+			"sqs_managed_sse_enabled":    false,
+		}),
+		queue("coalition-email-dlq", "email_dlq", map[string]interface{}{
+			"message_retention_seconds": 345600.0,
+			"sqs_managed_sse_enabled":   true,
+		}),
+		policy("geocoding_worker", `{"Statement":[{"Effect":"Allow",`+
+			`"Action":["sqs:ReceiveMessage","sqs:DeleteMessage"],`+
+			`"Resource":"arn:aws:sqs:us-east-1:123456789012:coalition-geocoding"}]}`),
+		policy("email_worker", `{"Statement":[{"Effect":"Allow","Action":"sqs:*","Resource":"*"}]}`),
+		policy("logs", `{"Statement":[{"Effect":"Allow","Action":"logs:PutLogEvents","Resource":"*"}]}`),
+	}}
+
+	failed := map[string]bool{}
+	for _, finding := range common.RunAudit(audit, common.QueueAuditChecks()...) {
+		if !finding.Passed && !finding.Skipped {
+			failed[finding.Control+" "+finding.Resource] = true
+		}
+	}
+
+	assert.Equal(t, map[string]bool{
+		"SQS-Redrive aws_sqs_queue.email":                    true,
+		"SQS-Visibility-Timeout aws_sqs_queue.email":         true,
+		"Encryption-At-Rest aws_sqs_queue.email":             true,
+		"SQS-DLQ-Retention aws_sqs_queue.email_dlq":          true,
+		"SQS-Access-Scoped aws_iam_role_policy.email_worker": true,
+	}, failed)
+}
@@ -0,0 +1,117 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestModulesArePublishable checks every module could be published to a registry on its own. It parses the
+// modules' .tf files, so it runs in short mode.
+func TestModulesArePublishable(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	for _, modulePath := range terraformModulePaths(t) {
+		if modulePath == terraformRoot {
+			continue
+		}
+		t.Run(terraformModuleName(modulePath), func(t *testing.T) {
+			common.AssertModulePublishable(t, modulePath)
+		})
+	}
+}
+
+// TestModuleProviderConstraintsCompatible checks the root and the modules admit a common version of each provider
+// they share, so a deployer can use any combination of them together
+func TestModuleProviderConstraintsCompatible(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	constraints := map[string][]string{}
+	for _, modulePath := range terraformModulePaths(t) {
+		for _, requirement := range common.GetProviderRequirements(t, modulePath) {
+			constraints[requirement.Source] = append(constraints[requirement.Source], requirement.Version)
+		}
+	}
+
+	for source, versions := range constraints {
+		compatible := common.CompatibleProviderVersion(versions)
+		assert.NotEmpty(t, compatible, "No version of %s satisfies every module's constraint: %v", source, versions)
+		t.Logf("%s: %s admits every module's constraint", source, compatible)
+	}
+}
+
+// TestModuleExamplesValidate runs terraform validate in every module's examples, which call the module the way a
+// registry consumer would. It needs the terraform binary and downloads providers, but no AWS credentials.
+func TestModuleExamplesValidate(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	for _, modulePath := range terraformModulePaths(t) {
+		for _, examplePath := range common.ModuleExamplePaths(t, modulePath) {
+			t.Run(terraformModuleName(modulePath)+"/"+filepath.Base(examplePath), func(t *testing.T) {
+				common.ValidateExample(t, examplePath)
+			})
+		}
+	}
+}
+
+// TestRegistryPackagingChecks runs the packaging checks over a module that breaks each rule
+func TestRegistryPackagingChecks(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	modulePath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(modulePath, "main.tf"), []byte(`
+terraform {
+  required_providers {
+    aws = {
+      source                = "hashicorp/aws"
+      version               = "< 6.0"
+      configuration_aliases = [aws.replica]
+    }
+    random = {
+      source = "hashicorp/random"
+    }
+  }
+}
+
+provider "aws" {
+  region = "us-east-1"
+}
+
+module "shared" {
+  source = "../shared"
+}
+
+locals {
+  script = "${path.module}/scripts/setup.sh"
+  config = file("${path.root}/config.json")
+}
+`), 0o644))
+
+	assert.Equal(t, []string{`main.tf:20: "../shared"`, "main.tf:25: path.root"},
+		common.ModulePathEscapes(t, modulePath))
+	assert.Equal(t, []string{`main.tf:15: provider "aws"`}, common.ModuleProviderBlocks(t, modulePath))
+
+	requirements := common.GetProviderRequirements(t, modulePath)
+	assert.Equal(t, []common.ProviderRequirement{
+		{Name: "aws", Source: "hashicorp/aws", Version: "< 6.0"},
+		{Name: "random", Source: "hashicorp/random"},
+	}, requirements)
+	assert.Contains(t, common.ProviderConstraintProblem(requirements[0].Version), "no lower bound")
+	assert.Equal(t, "no version constraint", common.ProviderConstraintProblem(requirements[1].Version))
+	assert.Empty(t, common.ProviderConstraintProblem(">= 5.0, < 6.0"))
+}
+
+// TestCompatibleProviderVersion checks the lowest version admitted by every constraint is found, if there is one
+func TestCompatibleProviderVersion(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	assert.Equal(t, "5.99.0", common.CompatibleProviderVersion([]string{">= 5.0", "~> 5.99.0", ">= 5.0"}))
+	assert.Equal(t, "3.0.0", common.CompatibleProviderVersion([]string{"~> 3.0", ">= 3.0"}))
+	assert.Empty(t, common.CompatibleProviderVersion([]string{"~> 5.99.0", ">= 6.0"}))
+	assert.Empty(t, common.CompatibleProviderVersion([]string{"not a constraint"}))
+}
@@ -0,0 +1,49 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCheckScheduledScaleToZero checks non-production services need both scheduled actions on the expected
+// schedule, and production services may not be scheduled to zero
+func TestCheckScheduledScaleToZero(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	resourceID := common.ECSServiceResourceID("coalition-dev", "coalition-api")
+	target := &tfjson.StateResource{Address: "aws_appautoscaling_target.api", Type: "aws_appautoscaling_target",
+		AttributeValues: map[string]interface{}{"service_namespace": "ecs", "resource_id": resourceID}}
+	action := func(name, schedule, minimum, maximum string) *tfjson.StateResource {
+		return &tfjson.StateResource{
+			Address: "aws_appautoscaling_scheduled_action." + name,
+			Type:    "aws_appautoscaling_scheduled_action",
+			AttributeValues: map[string]interface{}{
+				"resource_id": resourceID,
+				"schedule":    schedule,
+				"timezone":    common.NonProductionScaleToZero.Timezone,
+				"scalable_target_action": []interface{}{
+					map[string]interface{}{"min_capacity": minimum, "max_capacity": maximum},
+				},
+			},
+		}
+	}
+	scaleDown := action("scale_down", common.NonProductionScaleToZero.ScaleDown, "0", "0")
+	scaleUp := action("scale_up", common.NonProductionScaleToZero.ScaleUp, "1", "2")
+	passed := func(environment string, actions ...*tfjson.StateResource) bool {
+		audit := &common.AuditContext{Resources: append([]*tfjson.StateResource{target}, actions...)}
+		findings := common.RunAudit(audit, common.CheckScheduledScaleToZero(environment))
+		assert.Len(t, findings, 1)
+		return len(findings) == 1 && findings[0].Passed
+	}
+
+	assert.True(t, passed("dev", scaleDown, scaleUp))
+	assert.False(t, passed("dev", scaleDown), "Services must be scaled back up")
+	assert.False(t, passed("dev", scaleUp), "Services must be scaled to zero")
+	assert.False(t, passed("staging", action("scale_down", "cron(0 23 * * ? *)", "0", "0"), scaleUp))
+	assert.True(t, passed("prod", scaleUp))
+	assert.False(t, passed("prod", scaleDown, scaleUp))
+}
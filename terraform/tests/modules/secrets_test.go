@@ -7,12 +7,15 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"terraform-tests/common"
+	"terraform-tests/tfopts"
 )
 
 func TestSecretsModuleBasicValidation(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
 	t.Parallel()
 
-	testConfig := common.NewTestConfig("../../modules/secrets")
+	testConfig := tfopts.NewTestConfig("../../modules/secrets")
 
 	// Test case 1: Default configuration (site password secret always created)
 	t.Run("DefaultConfigurationWorks", func(t *testing.T) {
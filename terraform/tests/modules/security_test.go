@@ -2,10 +2,16 @@ package modules
 
 import (
 	"fmt"
+	"net"
 	"testing"
 
+	"terraform-tests/awsval"
 	"terraform-tests/common"
+	"terraform-tests/fixtures"
+	"terraform-tests/moduletest"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 )
@@ -15,176 +21,257 @@ func TestSecurityModuleValidation(t *testing.T) {
 	common.ValidateModuleStructure(t, "security")
 }
 
-func getSecurityTestVars() map[string]interface{} {
-	return map[string]interface{}{
-		"vpc_id":                   "vpc-12345678",
-		"allowed_bastion_cidrs":    []string{"10.0.0.0/8"},
-		"lambda_security_group_id": "sg-lambda123",
-	}
+// withSecurityFixtures points the module at the shared fixture VPC and Lambda security group, since its security
+// groups cannot be created in a VPC that does not exist
+func withSecurityFixtures(t *testing.T, vars map[string]interface{}) {
+	vpc := fixtures.NewVPC(t)
+	securityGroups := fixtures.NewSecurityGroups(t, vpc)
+	vars["vpc_id"] = vpc.ID
+	vars["lambda_security_group_id"] = securityGroups.LambdaID
 }
 
-func TestSecurityModuleCreatesDatabaseSecurityGroup(t *testing.T) {
-	common.SkipIfShortTest(t)
-
-	testConfig := common.NewTestConfig("../../modules/security")
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/security", getSecurityTestVars())
-	defer common.CleanupResources(t, terraformOptions)
-
-	terraform.InitAndApply(t, terraformOptions)
-
-	// Validate Database security group
-	dbSGID := terraform.Output(t, terraformOptions, "db_security_group_id")
-	assert.NotEmpty(t, dbSGID)
-
-	sg := common.GetSecurityGroupById(t, dbSGID, testConfig.AWSRegion)
-	assert.Equal(t, "vpc-12345678", *sg.VpcId)
-
-	expectedName := fmt.Sprintf("%s-db-sg", testConfig.Prefix)
-	assert.Equal(t, expectedName, *sg.GroupName)
+// securityTestVars returns the module's test variables with the bastion CIDR blocks replaced
+func securityTestVars(bastionCIDRs ...string) map[string]interface{} {
+	vars := common.GetDefaultSecurityTestVars()
+	vars["allowed_bastion_cidrs"] = bastionCIDRs
+	return vars
+}
 
-	// Check for PostgreSQL port rule
-	hasPostgreSQLRule := false
+// restrictedEgressTestVars returns the variables with the bastion's egress restricted
+func restrictedEgressTestVars(vars map[string]interface{}) map[string]interface{} {
+	vars["restrict_bastion_egress"] = true
+	return vars
+}
 
-	for _, permission := range sg.IpPermissions {
-		if permission.FromPort != nil && permission.ToPort != nil && permission.IpProtocol != nil {
-			if *permission.FromPort == 5432 && *permission.ToPort == 5432 && *permission.IpProtocol == "tcp" {
-				hasPostgreSQLRule = true
-				break
-			}
+// plannedBastionCIDRs asserts the bastion group's SSH ingress admits exactly the CIDR blocks
+func plannedBastionCIDRs(cidrs ...string) moduletest.PlanAssertion {
+	return func(t *testing.T, plan *terraform.PlanStruct, _ string) {
+		bastion, ok := plan.ResourcePlannedValuesMap["aws_security_group.bastion_sg[0]"]
+		if !assert.True(t, ok, "The bastion security group should be planned") {
+			return
 		}
+		ingress, _ := bastion.AttributeValues["ingress"].([]interface{})
+		if !assert.Len(t, ingress, 1, "The bastion group should have one ingress rule") {
+			return
+		}
+		rule, _ := ingress[0].(map[string]interface{})
+		assert.Equal(t, float64(22), rule["from_port"])
+		assert.ElementsMatch(t, cidrs, rule["cidr_blocks"])
 	}
-
-	assert.True(t, hasPostgreSQLRule, "Database security group should allow PostgreSQL traffic")
 }
 
-func TestSecurityModuleCreatesBastionSecurityGroup(t *testing.T) {
-	common.SkipIfShortTest(t)
-
-	testConfig := common.NewTestConfig("../../modules/security")
-
-	testVars := map[string]interface{}{
-		"vpc_id":                   "vpc-12345678",
-		"allowed_bastion_cidrs":    []string{"192.168.1.0/24", "10.0.0.0/8"},
-		"lambda_security_group_id": "sg-lambda123",
+// plannedBastionEgress asserts a bastion group with restricted egress reaches the internet only over HTTP and HTTPS,
+// and the database on its port, rather than allowing all outbound traffic
+func plannedBastionEgress(t *testing.T, plan *terraform.PlanStruct, _ string) {
+	bastion, ok := plan.ResourcePlannedValuesMap["aws_security_group.bastion_sg[0]"]
+	if !assert.True(t, ok, "The bastion security group should be planned") {
+		return
 	}
+	egress, _ := bastion.AttributeValues["egress"].([]interface{})
+	ports := make([]float64, 0, len(egress))
+	for _, value := range egress {
+		rule, _ := value.(map[string]interface{})
+		assert.Equal(t, "tcp", rule["protocol"], "Bastion egress should be limited to TCP ports: %v", rule)
+		assert.Equal(t, rule["from_port"], rule["to_port"], "Bastion egress should open single ports: %v", rule)
+		ports = append(ports, rule["from_port"].(float64))
+	}
+	assert.ElementsMatch(t, []float64{80, 443, 5432}, ports)
+}
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/security", testVars)
-	defer common.CleanupResources(t, terraformOptions)
-
-	terraform.InitAndApply(t, terraformOptions)
-
-	// Validate Bastion security group
-	bastionSGID := terraform.Output(t, terraformOptions, "bastion_security_group_id")
-	assert.NotEmpty(t, bastionSGID)
-
-	sg := common.GetSecurityGroupById(t, bastionSGID, testConfig.AWSRegion)
-	assert.Equal(t, "vpc-12345678", *sg.VpcId)
-
-	expectedName := fmt.Sprintf("%s-bastion-sg", testConfig.Prefix)
-	assert.Equal(t, expectedName, *sg.GroupName)
-
-	// Check for SSH rule with restricted CIDR blocks
-	hasSSHRule := false
-
-	for _, permission := range sg.IpPermissions {
-		if permission.FromPort != nil && permission.ToPort != nil && permission.IpProtocol != nil {
-			if *permission.FromPort == 22 && *permission.ToPort == 22 && *permission.IpProtocol == "tcp" {
-				hasSSHRule = true
-				// Check CIDR blocks
-				var cidrBlocks []string
-				for _, ipRange := range permission.IpRanges {
-					if ipRange.CidrIp != nil {
-						cidrBlocks = append(cidrBlocks, *ipRange.CidrIp)
-					}
-				}
-				// Should only allow traffic from specified CIDR blocks
-				assert.Contains(t, cidrBlocks, "192.168.1.0/24")
-				assert.Contains(t, cidrBlocks, "10.0.0.0/8")
-				assert.NotContains(t, cidrBlocks, "0.0.0.0/0", "Bastion should not allow SSH from anywhere")
-			}
+// securityGroupInVPC checks the group whose ID is the named output is in the VPC the module was given, and is
+// named with the prefix and suffix
+func securityGroupInVPC(outputName, suffix string) common.Validator {
+	return func(t *testing.T, applied *common.AppliedConfiguration) []common.AuditFinding {
+		group := awsval.GetSecurityGroup(t, applied.Output(outputName), applied.Region)
+		vpcID, _ := applied.Options.Vars["vpc_id"].(string)
+		name := fmt.Sprintf("%s%s", applied.Options.Vars["prefix"], suffix)
+		return []common.AuditFinding{
+			{Control: "SG-VPC", Resource: "output." + outputName, Passed: aws.ToString(group.VpcId) == vpcID,
+				Detail: fmt.Sprintf("group is in %s, expected %s", aws.ToString(group.VpcId), vpcID)},
+			{Control: "SG-Name", Resource: "output." + outputName, Passed: aws.ToString(group.GroupName) == name,
+				Detail: fmt.Sprintf("group is named %s, expected %s", aws.ToString(group.GroupName), name)},
 		}
 	}
-
-	assert.True(t, hasSSHRule, "Bastion security group should allow SSH traffic")
 }
 
-func TestSecurityModuleCreatesWAF(t *testing.T) {
-	common.SkipIfShortTest(t)
-
-	testConfig := common.NewTestConfig("../../modules/security")
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/security", getSecurityTestVars())
-	defer common.CleanupResources(t, terraformOptions)
-
-	terraform.InitAndApply(t, terraformOptions)
-
-	// Validate WAF Web ACL
-	wafWebACLArn := terraform.Output(t, terraformOptions, "waf_web_acl_arn")
-	assert.NotEmpty(t, wafWebACLArn)
-	assert.Contains(t, wafWebACLArn, "arn:aws:wafv2:", "WAF Web ACL ARN should be valid WAFv2 ARN")
+// bastionAdmits checks whether the bastion group admits SSH from a source address
+func bastionAdmits(source string, admitted bool) common.Validator {
+	return func(t *testing.T, applied *common.AppliedConfiguration) []common.AuditFinding {
+		group := awsval.GetSecurityGroup(t, applied.Output("bastion_security_group_id"), applied.Region)
+		return []common.AuditFinding{{
+			Control:  "SG-Source",
+			Resource: "output.bastion_security_group_id",
+			Passed:   awsval.SecurityGroupAllowsIngressFrom(group, 22, net.ParseIP(source)) == admitted,
+			Detail:   fmt.Sprintf("SSH from %s admitted should be %t", source, admitted),
+		}}
+	}
 }
 
-func TestSecurityModuleValidatesResourceTags(t *testing.T) {
-	common.SkipIfShortTest(t)
-
-	testConfig := common.NewTestConfig("../../modules/security")
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/security", getSecurityTestVars())
-	defer common.CleanupResources(t, terraformOptions)
-
-	terraform.InitAndApply(t, terraformOptions)
-
-	// Validate database security group naming
-	dbSGID := terraform.Output(t, terraformOptions, "db_security_group_id")
-	dbSG := common.GetSecurityGroupById(t, dbSGID, testConfig.AWSRegion)
-	common.ValidateResourceNaming(t, *dbSG.GroupName, testConfig.Prefix, "db-sg")
-
-	// Validate bastion security group naming
-	bastionSGID := terraform.Output(t, terraformOptions, "bastion_security_group_id")
-	bastionSG := common.GetSecurityGroupById(t, bastionSGID, testConfig.AWSRegion)
-	common.ValidateResourceNaming(t, *bastionSG.GroupName, testConfig.Prefix, "bastion-sg")
+// bastionEgressRestricted checks the bastion group does not allow all outbound traffic
+func bastionEgressRestricted(t *testing.T, applied *common.AppliedConfiguration) []common.AuditFinding {
+	group := awsval.GetSecurityGroup(t, applied.Output("bastion_security_group_id"), applied.Region)
+	return []common.AuditFinding{{
+		Control:  "SG-Egress",
+		Resource: "output.bastion_security_group_id",
+		Passed:   !awsval.SecurityGroupAllowsAllEgress(group),
+		Detail:   "the bastion group should not allow all outbound traffic",
+	}}
 }
 
-func TestSecurityModuleWithRestrictiveBastionCIDRs(t *testing.T) {
-	common.SkipIfShortTest(t)
-
-	testConfig := common.NewTestConfig("../../modules/security")
-
-	// Test with very restrictive CIDR blocks
-	testVars := map[string]interface{}{
-		"vpc_id":                   "vpc-12345678",
-		"allowed_bastion_cidrs":    []string{"203.0.113.0/24"}, // Single specific network
-		"lambda_security_group_id": "sg-lambda123",
-	}
+// TestSecurityModule plans and applies the module's permutations. Applies use the shared fixture VPC.
+func TestSecurityModule(t *testing.T) {
+	moduletest.Run(t,
+		moduletest.Case{
+			Name:   "DatabaseSecurityGroup",
+			Module: "security",
+			Vars:   common.GetDefaultSecurityTestVars(),
+			Setup:  withSecurityFixtures,
+			PlanAssertions: []moduletest.PlanAssertion{
+				moduletest.PlannedAttribute("aws_security_group.db_sg[0]", "name", "{prefix}-db-sg"),
+				moduletest.PlansResource("aws_security_group_rule.db_ingress_bastion[0]"),
+			},
+			ApplyValidators: []common.Validator{
+				common.Outputs("db_security_group_id"),
+				common.SGRules("db_security_group_id", common.SGRule{Port: 5432}),
+				securityGroupInVPC("db_security_group_id", "-db-sg"),
+			},
+		},
+		moduletest.Case{
+			Name:   "BastionSecurityGroup",
+			Module: "security",
+			Vars:   securityTestVars("192.168.1.0/24", "10.0.0.0/8"),
+			Setup:  withSecurityFixtures,
+			PlanAssertions: []moduletest.PlanAssertion{
+				moduletest.PlannedAttribute("aws_security_group.bastion_sg[0]", "name", "{prefix}-bastion-sg"),
+				plannedBastionCIDRs("192.168.1.0/24", "10.0.0.0/8"),
+			},
+			// SSH should only be allowed from the specified CIDR blocks
+			ApplyValidators: []common.Validator{
+				common.Outputs("bastion_security_group_id"),
+				common.SGRules("bastion_security_group_id", common.SGRule{
+					Port:       22,
+					Allowed:    []string{"192.168.1.0/24", "10.0.0.0/8"},
+					Disallowed: []string{"0.0.0.0/0", "::/0"},
+				}),
+				securityGroupInVPC("bastion_security_group_id", "-bastion-sg"),
+			},
+		},
+		moduletest.Case{
+			Name:   "RestrictedBastionEgress",
+			Module: "security",
+			Vars:   restrictedEgressTestVars(common.GetDefaultSecurityTestVars()),
+			Setup:  withSecurityFixtures,
+			PlanAssertions: []moduletest.PlanAssertion{
+				plannedBastionEgress,
+			},
+			ApplyValidators: []common.Validator{
+				bastionEgressRestricted,
+			},
+		},
+		moduletest.Case{
+			// A database security group supplied from elsewhere is reached through its subnets' CIDR blocks
+			Name:   "RestrictedBastionEgressToExternalDatabase",
+			Module: "security",
+			Vars: restrictedEgressTestVars(map[string]interface{}{
+				"create_db_sg":            false,
+				"bastion_db_egress_cidrs": []string{"10.0.20.0/24"},
+			}),
+			PlanAssertions: []moduletest.PlanAssertion{
+				moduletest.OmitsResource("aws_security_group.db_sg[0]"),
+				plannedBastionEgress,
+			},
+		},
+		moduletest.Case{
+			Name:   "RestrictiveBastionCIDRs",
+			Module: "security",
+			Vars:   securityTestVars("203.0.113.0/24"), // Single specific network
+			Setup:  withSecurityFixtures,
+			PlanAssertions: []moduletest.PlanAssertion{
+				plannedBastionCIDRs("203.0.113.0/24"),
+			},
+			// SSH should only be allowed from the specific test network, not anywhere or the broader private network
+			ApplyValidators: []common.Validator{
+				common.SGRules("bastion_security_group_id", common.SGRule{
+					Port:       22,
+					Allowed:    []string{"203.0.113.0/24"},
+					Disallowed: []string{"0.0.0.0/0", "10.0.0.0/8"},
+				}),
+				bastionAdmits("203.0.113.10", true),
+				bastionAdmits("198.51.100.10", false),
+			},
+		},
+		moduletest.Case{
+			Name:   "WAF",
+			Module: "security",
+			Vars:   common.GetDefaultSecurityTestVars(),
+			Setup:  withSecurityFixtures,
+			PlanAssertions: []moduletest.PlanAssertion{
+				moduletest.PlannedAttribute("aws_wafv2_web_acl.main[0]", "scope", "REGIONAL"),
+			},
+			// The web ACL should run the managed rule groups and the per-IP rate limit, and report metrics for each
+			ApplyValidators: []common.Validator{
+				common.OutputMatches("waf_web_acl_arn", "^arn:aws:wafv2:"),
+				common.WebACLRules("waf_web_acl_arn", awsval.WebACLExpectation{
+					Scope:         "REGIONAL",
+					DefaultAction: "allow",
+					ManagedRuleGroups: []string{
+						"AWSManagedRulesCommonRuleSet",
+						"AWSManagedRulesKnownBadInputsRuleSet",
+						"AWSManagedRulesSQLiRuleSet",
+					},
+					RateLimit: 2000,
+				}),
+			},
+		},
+		moduletest.Case{
+			Name:   "WithoutWAF",
+			Module: "security",
+			Vars:   map[string]interface{}{"create_waf": false},
+			PlanAssertions: []moduletest.PlanAssertion{
+				moduletest.OmitsResource("aws_wafv2_web_acl.main[0]"),
+				moduletest.PlansResource("aws_security_group.db_sg[0]", "aws_security_group.bastion_sg[0]"),
+			},
+		},
+		moduletest.Case{
+			Name:   "ResourceTags",
+			Module: "security",
+			Vars:   common.GetDefaultSecurityTestVars(),
+			Setup:  withSecurityFixtures,
+			// Every security group and the WAF should be Name-tagged and named by the convention for its type
+			ApplyValidators: []common.Validator{
+				common.TagPolicy(map[string]string{"Name": ""}),
+				moduletest.WithPrefix(common.NamingConventions),
+			},
+		},
+		moduletest.Case{
+			Name:   "NoPublicExposure",
+			Module: "security",
+			Vars:   common.GetDefaultSecurityTestVars(),
+			Setup:  withSecurityFixtures,
+			// Scan the applied state so computed values are audited too, and with CONFIG_RULES set, check AWS
+			// Config's restricted-ssh rule agrees
+			ApplyValidators: []common.Validator{
+				common.Audit(common.PublicExposureChecks()...),
+				common.ConfigCompliance(),
+			},
+		},
+	)
+}
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/security", testVars)
-	defer common.CleanupResources(t, terraformOptions)
-
-	terraform.InitAndApply(t, terraformOptions)
-
-	// Validate bastion security group only allows the specific CIDR
-	bastionSGID := terraform.Output(t, terraformOptions, "bastion_security_group_id")
-	bastionSG := common.GetSecurityGroupById(t, bastionSGID, testConfig.AWSRegion)
-
-	assert.NotNil(t, bastionSG, "Bastion security group should exist and be configured")
-
-	// Check for SSH rule with restricted CIDR blocks
-	hasSSHRule := false
-	for _, permission := range bastionSG.IpPermissions {
-		if permission.FromPort != nil && permission.ToPort != nil && permission.IpProtocol != nil {
-			if *permission.FromPort == 22 && *permission.ToPort == 22 && *permission.IpProtocol == "tcp" {
-				hasSSHRule = true
-				// Collect CIDR blocks for SSH rule
-				var cidrBlocks []string
-				for _, ipRange := range permission.IpRanges {
-					if ipRange.CidrIp != nil {
-						cidrBlocks = append(cidrBlocks, *ipRange.CidrIp)
-					}
-				}
-				// Should only allow traffic from the specific restrictive CIDR block
-				assert.Contains(t, cidrBlocks, "203.0.113.0/24", "Should allow SSH from the specific test network")
-				assert.NotContains(t, cidrBlocks, "0.0.0.0/0", "Should not allow SSH from anywhere")
-				assert.NotContains(t, cidrBlocks, "10.0.0.0/8", "Should not allow SSH from the broader private network")
-			}
-		}
-	}
-	assert.True(t, hasSSHRule, "Bastion security group should have SSH rule")
+// TestSecurityGroupIngressChecksIPv6Ranges checks that ingress helpers read a group's IPv6 ranges, so a rule that
+// opens a port to ::/0 is not missed by checks written for IPv4
+func TestSecurityGroupIngressChecksIPv6Ranges(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	group := &types.SecurityGroup{IpPermissions: []types.IpPermission{{
+		IpProtocol: aws.String("tcp"),
+		FromPort:   aws.Int32(22),
+		ToPort:     aws.Int32(22),
+		IpRanges:   []types.IpRange{{CidrIp: aws.String("10.0.0.0/8")}},
+		Ipv6Ranges: []types.Ipv6Range{{CidrIpv6: aws.String("2001:db8::/32")}},
+	}}}
+
+	assert.True(t, awsval.SecurityGroupAllowsIngressFrom(group, 22, net.ParseIP("2001:db8::10")))
+	assert.False(t, awsval.SecurityGroupAllowsIngressFrom(group, 22, net.ParseIP("2001:db9::10")))
+	assert.True(t, awsval.SecurityGroupAllowsIngressFrom(group, 22, net.ParseIP("10.1.2.3")))
 }
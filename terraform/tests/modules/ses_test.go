@@ -1,14 +1,18 @@
 package modules
 
 import (
-	"path/filepath"
+	"fmt"
+	"os"
 	"strings"
 	"testing"
 
+	"terraform-tests/awsval"
 	"terraform-tests/common"
+	"terraform-tests/tfopts"
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSESModuleStructure(t *testing.T) {
@@ -16,11 +20,9 @@ func TestSESModuleStructure(t *testing.T) {
 }
 
 func TestSESModulePlanCreatesExpectedResources(t *testing.T) {
-	if !testing.Short() {
-		t.Skip("Skipping validation-only test in full mode")
-	}
+	common.RequireTier(t, common.TierPlan)
 
-	testConfig := common.NewTestConfig("../../modules/ses")
+	testConfig := tfopts.NewTestConfig("../../modules/ses")
 
 	terraformOptions := testConfig.GetTerraformOptionsForPlanOnly(map[string]interface{}{
 		"prefix":                 testConfig.Prefix,
@@ -33,10 +35,8 @@ func TestSESModulePlanCreatesExpectedResources(t *testing.T) {
 		"notification_email":     "alerts@test.example.com",
 		"dmarc_email":            "dmarc@test.example.com",
 	})
-	terraformOptions.TerraformDir = testConfig.TerraformDir
-	terraformOptions.PlanFilePath = filepath.Join(testConfig.TerraformDir, "tfplan")
 
-	planStruct := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+	planStruct := common.PlanOnly(t, terraformOptions)
 
 	// Verify resource counts - the plan should create resources, not be empty
 	assert.Greater(t, len(planStruct.ResourcePlannedValuesMap), 0, "Plan should create resources")
@@ -66,11 +66,9 @@ func TestSESModulePlanCreatesExpectedResources(t *testing.T) {
 }
 
 func TestSESModulePlanWithDomainVerification(t *testing.T) {
-	if !testing.Short() {
-		t.Skip("Skipping validation-only test in full mode")
-	}
+	common.RequireTier(t, common.TierPlan)
 
-	testConfig := common.NewTestConfig("../../modules/ses")
+	testConfig := tfopts.NewTestConfig("../../modules/ses")
 
 	terraformOptions := testConfig.GetTerraformOptionsForPlanOnly(map[string]interface{}{
 		"prefix":                 testConfig.Prefix,
@@ -82,10 +80,8 @@ func TestSESModulePlanWithDomainVerification(t *testing.T) {
 		"enable_notifications":   false,
 		"dmarc_email":            "",
 	})
-	terraformOptions.TerraformDir = testConfig.TerraformDir
-	terraformOptions.PlanFilePath = filepath.Join(testConfig.TerraformDir, "tfplan")
 
-	planStruct := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+	planStruct := common.PlanOnly(t, terraformOptions)
 
 	// With verify_domain=true, should include domain identity and DKIM resources
 	hasDomainIdentity := false
@@ -105,11 +101,9 @@ func TestSESModulePlanWithDomainVerification(t *testing.T) {
 }
 
 func TestSESModulePlanWithNotificationsDisabled(t *testing.T) {
-	if !testing.Short() {
-		t.Skip("Skipping validation-only test in full mode")
-	}
+	common.RequireTier(t, common.TierPlan)
 
-	testConfig := common.NewTestConfig("../../modules/ses")
+	testConfig := tfopts.NewTestConfig("../../modules/ses")
 
 	terraformOptions := testConfig.GetTerraformOptionsForPlanOnly(map[string]interface{}{
 		"prefix":                 testConfig.Prefix,
@@ -122,10 +116,8 @@ func TestSESModulePlanWithNotificationsDisabled(t *testing.T) {
 		"enable_notifications":   false,
 		"dmarc_email":            "",
 	})
-	terraformOptions.TerraformDir = testConfig.TerraformDir
-	terraformOptions.PlanFilePath = filepath.Join(testConfig.TerraformDir, "tfplan")
 
-	planStruct := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+	planStruct := common.PlanOnly(t, terraformOptions)
 
 	// With enable_notifications=false, should NOT include SNS topic
 	hasSNSTopic := false
@@ -138,3 +130,162 @@ func TestSESModulePlanWithNotificationsDisabled(t *testing.T) {
 
 	assert.False(t, hasSNSTopic, "Plan with enable_notifications=false should not include SNS topic")
 }
+
+// TestSESSendingPolicyProblems checks what counts as a sending policy doing more than send as approved addresses
+func TestSESSendingPolicyProblems(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	approved := []string{"*@example.org", "noreply@example.org"}
+	policy := func(action, operator, senders string) string {
+		return fmt.Sprintf(`{"Statement":[{"Effect":"Allow","Action":%s,"Resource":"*",`+
+			`"Condition":{%q:{"ses:FromAddress":%s}}}]}`, action, operator, senders)
+	}
+
+	testCases := []struct {
+		name     string
+		policy   string
+		problems []string
+	}{
+		{"scoped", policy(`["ses:SendEmail","ses:SendRawEmail"]`, "StringLike", `["*@example.org"]`), nil},
+		{"extra action", policy(`["ses:SendEmail","ses:*"]`, "StringLike", `"noreply@example.org"`),
+			[]string{"statement 0 allows ses:*"}},
+		{"unapproved sender", policy(`"ses:SendEmail"`, "StringLike", `["*"]`),
+			[]string{"statement 0 allows sending as *"}},
+		{"literal wildcard", policy(`"ses:SendEmail"`, "StringEquals", `["*@example.org","noreply@example.org"]`),
+			[]string{"statement 0 matches *@example.org with StringEquals"}},
+		{"no condition", `{"Statement":[{"Effect":"Allow","Action":"ses:SendEmail","Resource":"*"}]}`,
+			[]string{"statement 0 does not limit ses:FromAddress"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.problems, common.SESSendingPolicyProblems(tc.policy, approved))
+		})
+	}
+}
+
+// TestSESModulePlansDNSRecordsAndScopedSending plans the module with Route53 records and checks the records SES
+// verifies and signs with, the SPF and DMARC values, that the sending policy only lets the SMTP user send as the
+// domain, and that the configuration set publishes delivery events to the notification topic
+func TestSESModulePlansDNSRecordsAndScopedSending(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	testConfig := tfopts.NewTestConfig("../../modules/ses")
+	terraformOptions := testConfig.GetTerraformOptionsForPlanOnly(map[string]interface{}{
+		"prefix":                 testConfig.Prefix,
+		"aws_region":             testConfig.AWSRegion,
+		"domain_name":            "test.example.com",
+		"from_email":             "noreply@test.example.com",
+		"verify_domain":          true,
+		"route53_zone_id":        "Z0123456789ABCDEFGHIJ",
+		"create_route53_records": true,
+		"enable_notifications":   true,
+		"dmarc_email":            "dmarc@test.example.com",
+	})
+	plan := common.PlanOnly(t, terraformOptions)
+
+	// SPF and DMARC are literal values, so they can be checked before the zone holds them
+	spf, exists := plan.ResourcePlannedValuesMap["aws_route53_record.spf[0]"]
+	if assert.True(t, exists, "The SPF record should be planned") {
+		records, _ := spf.AttributeValues["records"].([]interface{})
+		if assert.Len(t, records, 1) {
+			assert.Empty(t, awsval.SPFRecordProblems(fmt.Sprint(records[0])))
+		}
+	}
+	dmarc, exists := plan.ResourcePlannedValuesMap["aws_route53_record.dmarc[0]"]
+	if assert.True(t, exists, "The DMARC record should be planned") {
+		assert.Equal(t, "_dmarc.test.example.com", common.GetPlannedStringAttribute(dmarc, "name"))
+		records, _ := dmarc.AttributeValues["records"].([]interface{})
+		if assert.Len(t, records, 1) {
+			assert.Empty(t, awsval.DMARCRecordProblems(fmt.Sprint(records[0])))
+		}
+	}
+
+	verification, exists := plan.ResourcePlannedValuesMap["aws_route53_record.ses_verification[0]"]
+	if assert.True(t, exists, "The verification record should be planned") {
+		assert.Equal(t, "_amazonses.test.example.com", common.GetPlannedStringAttribute(verification, "name"))
+		assert.Equal(t, "TXT", common.GetPlannedStringAttribute(verification, "type"))
+	}
+	assert.Len(t, common.GetPlannedResourcesByType(plan, "aws_ses_domain_identity_verification"), 1,
+		"Apply should wait for the domain to verify")
+
+	var dkimRecords int
+	for _, record := range common.GetPlannedResourcesByType(plan, "aws_route53_record") {
+		if strings.HasPrefix(record.Address, "aws_route53_record.dkim[") {
+			dkimRecords++
+			assert.Equal(t, "CNAME", common.GetPlannedStringAttribute(record, "type"))
+		}
+	}
+	assert.Equal(t, 3, dkimRecords, "Expected a CNAME for each of the three DKIM tokens")
+
+	// Only the SMTP user may send, and only as the domain
+	policies := common.GetPlannedResourcesByType(plan, "aws_iam_user_policy")
+	require.Len(t, policies, 1)
+	assert.Equal(t, fmt.Sprintf("%s-ses-smtp-user", testConfig.Prefix),
+		common.GetPlannedStringAttribute(policies[0], "user"))
+	assert.Empty(t, common.SESSendingPolicyProblems(common.GetPlannedStringAttribute(policies[0], "policy"),
+		[]string{"*@test.example.com", "noreply@test.example.com"}))
+	for _, resourceType := range []string{"aws_iam_role_policy", "aws_iam_policy", "aws_iam_role_policy_attachment"} {
+		assert.Empty(t, common.GetPlannedResourcesByType(plan, resourceType),
+			"No role should be granted SES sending besides the SMTP user")
+	}
+
+	destinations := common.GetPlannedResourcesByType(plan, "aws_ses_event_destination")
+	require.Len(t, destinations, 1, "The configuration set should publish events")
+	assert.Equal(t, true, destinations[0].AttributeValues["enabled"])
+	assert.ElementsMatch(t, common.SESEventTypes, destinations[0].AttributeValues["matching_types"])
+	assert.Len(t, destinations[0].AttributeValues["sns_destination"], 1, "Events should go to the notification topic")
+}
+
+// TestSESModuleVerifiesDomainInRoute53 applies the module for a subdomain of a delegated Route53 zone and checks
+// the identity verifies, the zone holds its verification, SPF, DKIM and DMARC records, and mail sent through the
+// configuration set reaches the notification topic as a delivery event. It is opt-in, since SES can only verify a
+// domain whose records resolve publicly.
+func TestSESModuleVerifiesDomainInRoute53(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	zoneID := os.Getenv("SES_TEST_ZONE_ID")
+	if zoneID == "" {
+		t.Skip("Skipping SES domain verification - SES_TEST_ZONE_ID is not set to a delegated Route53 zone")
+	}
+
+	testConfig := tfopts.NewTestConfig("../../modules/ses")
+	domain := fmt.Sprintf("%s.%s", testConfig.UniqueID, common.GetHostedZoneName(t, zoneID, testConfig.AWSRegion))
+	fromEmail := "noreply@" + domain
+
+	options := testConfig.GetModuleTerraformOptions("../../modules/ses", map[string]interface{}{
+		"domain_name":            domain,
+		"from_email":             fromEmail,
+		"verify_domain":          true,
+		"route53_zone_id":        zoneID,
+		"create_route53_records": true,
+		"dmarc_email":            "dmarc@" + domain,
+		"enable_notifications":   true,
+		"secret_recovery_days":   0,
+	})
+
+	terraformOptions := options.Options
+
+	common.ApplyAndValidate(t, options,
+		common.Outputs("ses_domain_identity", "ses_verification_token", "ses_configuration_set",
+			"ses_notification_topic_arn"),
+		common.EmailAuthRecords(zoneID, "ses_domain_identity", "ses_dkim_tokens"),
+	)
+
+	common.AssertSESDNSRecords(t, zoneID, domain, testConfig.AWSRegion,
+		terraform.Output(t, terraformOptions, "ses_verification_token"),
+		terraform.OutputList(t, terraformOptions, "ses_dkim_tokens"))
+
+	identity := common.GetSESIdentity(t, domain, testConfig.AWSRegion)
+	assert.True(t, identity.VerifiedForSendingStatus, "The domain should be verified for sending")
+	assert.True(t, identity.DkimAttributes.SigningEnabled, "SES should sign mail from the domain")
+	assert.ElementsMatch(t, terraform.OutputList(t, terraformOptions, "ses_dkim_tokens"), identity.DkimAttributes.Tokens)
+	t.Logf("DKIM status for %s: %s", domain, identity.DkimAttributes.Status)
+
+	// The mailbox simulator accepts mail from a sandboxed account, so this works before production access
+	queueURL := common.SubscribeTestQueue(t, terraform.Output(t, terraformOptions, "ses_notification_topic_arn"),
+		testConfig.AWSRegion)
+	messageID := common.SendSESTestEmail(t, testConfig.AWSRegion, fromEmail, common.SESMailboxSimulatorSuccess,
+		terraform.Output(t, terraformOptions, "ses_configuration_set"))
+	common.WaitForSESEvent(t, queueURL, testConfig.AWSRegion, messageID, "Delivery")
+}
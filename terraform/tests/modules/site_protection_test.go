@@ -1,66 +1,65 @@
 package modules
 
 import (
+	"encoding/json"
 	"testing"
 
+	"terraform-tests/moduletest"
+
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestSecretsModuleValidation(t *testing.T) {
-	t.Parallel()
+// getSiteProtectionTestVars returns the secrets module's variables, with a site password when one is given
+func getSiteProtectionTestVars(sitePassword string) map[string]interface{} {
+	vars := map[string]interface{}{
+		"app_db_username": "testuser",
+		"app_db_password": "testpass123",
+		"db_endpoint":     "test.cluster-xyz.us-east-1.rds.amazonaws.com:5432",
+		"db_name":         "testdb",
+	}
+	if sitePassword != "" {
+		vars["site_password"] = sitePassword
+	}
+	return vars
+}
 
-	// Test case 1: Secrets module with default values (always creates site password secret)
-	t.Run("DefaultValuesWork", func(t *testing.T) {
-		terraformOptions := &terraform.Options{
-			TerraformDir: "../../modules/secrets",
-			Vars: map[string]interface{}{
-				"prefix":          "test-coalition",
-				"app_db_username": "testuser",
-				"app_db_password": "testpass123",
-				"db_endpoint":     "test.cluster-xyz.us-east-1.rds.amazonaws.com:5432",
-				"db_name":         "testdb",
-				// site_password defaults to "" which becomes "changeme" in secret
-			},
-			NoColor: true,
-			EnvVars: map[string]string{
-				"TF_SKIP_PROVIDER_VERIFY":      "true",
-				"AWS_PROVIDER_SKIP_VALIDATION": "true",
-				"AWS_ACCESS_KEY_ID":            "fake-access-key",
-				"AWS_SECRET_ACCESS_KEY":        "fake-secret-key",
-				"AWS_DEFAULT_REGION":           "us-east-1",
-			},
-		}
+// plannedSitePassword asserts the password the site password secret is created with
+func plannedSitePassword(expected string) moduletest.PlanAssertion {
+	return func(t *testing.T, plan *terraform.PlanStruct, _ string) {
+		version, ok := plan.ResourcePlannedValuesMap["aws_secretsmanager_secret_version.site_password_secret"]
+		require.True(t, ok, "The site password secret should always be created")
 
-		// This should succeed - secrets are always created
-		_, err := terraform.InitE(t, terraformOptions)
-		assert.NoError(t, err, "Secrets module with default configuration should initialize successfully")
-	})
+		secretString, _ := version.AttributeValues["secret_string"].(string)
+		var secret struct {
+			Password string `json:"password"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(secretString), &secret))
+		assert.Equal(t, expected, secret.Password)
+	}
+}
 
-	// Test case 2: Secrets module with custom password
-	t.Run("CustomPasswordWorks", func(t *testing.T) {
-		terraformOptions := &terraform.Options{
-			TerraformDir: "../../modules/secrets",
-			Vars: map[string]interface{}{
-				"prefix":          "test-coalition",
-				"app_db_username": "testuser",
-				"app_db_password": "testpass123",
-				"db_endpoint":     "test.cluster-xyz.us-east-1.rds.amazonaws.com:5432",
-				"db_name":         "testdb",
-				"site_password":   "custom-secure-password",
+func TestSecretsModuleValidation(t *testing.T) {
+	moduletest.Run(t,
+		// site_password defaults to "", which becomes "changeme" in the secret
+		moduletest.Case{
+			Name:   "DefaultValuesWork",
+			Module: "secrets",
+			Vars:   getSiteProtectionTestVars(""),
+			PlanAssertions: []moduletest.PlanAssertion{
+				moduletest.PlannedAttribute("aws_secretsmanager_secret.site_password_secret", "name",
+					"{prefix}/site-password"),
+				plannedSitePassword("changeme"),
 			},
-			NoColor: true,
-			EnvVars: map[string]string{
-				"TF_SKIP_PROVIDER_VERIFY":      "true",
-				"AWS_PROVIDER_SKIP_VALIDATION": "true",
-				"AWS_ACCESS_KEY_ID":            "fake-access-key",
-				"AWS_SECRET_ACCESS_KEY":        "fake-secret-key",
-				"AWS_DEFAULT_REGION":           "us-east-1",
+		},
+		moduletest.Case{
+			Name:   "CustomPasswordWorks",
+			Module: "secrets",
+			Vars:   getSiteProtectionTestVars("custom-secure-password"),
+			PlanAssertions: []moduletest.PlanAssertion{
+				plannedSitePassword("custom-secure-password"),
 			},
-		}
-
-		// This should succeed
-		_, err := terraform.InitE(t, terraformOptions)
-		assert.NoError(t, err, "Secrets module with custom password should initialize successfully")
-	})
+		},
+	)
 }
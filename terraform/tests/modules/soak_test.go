@@ -0,0 +1,83 @@
+package modules
+
+import (
+	"testing"
+	"time"
+
+	"terraform-tests/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClassifyStoppedTask checks tasks stopped for a deployment, scale-in or by hand are expected, while failed
+// health checks and out-of-memory kills are not
+func TestClassifyStoppedTask(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	classify := func(task ecstypes.Task) [2]bool {
+		unexpected, outOfMemory := common.ClassifyStoppedTask(task)
+		return [2]bool{unexpected, outOfMemory}
+	}
+
+	assert.Equal(t, [2]bool{false, false}, classify(ecstypes.Task{
+		StopCode:      ecstypes.TaskStopCodeServiceSchedulerInitiated,
+		StoppedReason: aws.String("Scaling activity initiated by (deployment ecs-svc/123)"),
+	}))
+	assert.Equal(t, [2]bool{false, false}, classify(ecstypes.Task{StopCode: ecstypes.TaskStopCodeUserInitiated}))
+	assert.Equal(t, [2]bool{true, false}, classify(ecstypes.Task{
+		StopCode:      ecstypes.TaskStopCodeServiceSchedulerInitiated,
+		StoppedReason: aws.String("Task failed ELB health checks in (target-group arn:aws:elasticloadbalancing:...)"),
+	}))
+	assert.Equal(t, [2]bool{true, true}, classify(ecstypes.Task{
+		StopCode:      ecstypes.TaskStopCodeEssentialContainerExited,
+		StoppedReason: aws.String("Essential container in task exited"),
+		Containers:    []ecstypes.Container{{Reason: aws.String("OutOfMemoryError: Container killed due to memory usage")}},
+	}))
+}
+
+// TestSoakReportFindings checks the soak's budgets, and that the 5xx finding is skipped without API Gateway metrics
+func TestSoakReportFindings(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	passed := func(report *common.SoakReport) map[string]bool {
+		outcomes := map[string]bool{}
+		for _, finding := range report.Findings() {
+			if !finding.Skipped {
+				outcomes[finding.Control] = finding.Passed
+			}
+		}
+		return outcomes
+	}
+
+	assert.Equal(t, map[string]bool{"Soak-Health": true, "Soak-Restarts": true, "Soak-OOM": true, "Soak-5xx": true},
+		passed(&common.SoakReport{HealthChecks: 1000, HealthFailures: make([]common.SoakEvent, 1),
+			ServerErrorsKnown: true, APIRequests: 10000, API5xx: 10}))
+	assert.Equal(t, map[string]bool{"Soak-Health": false, "Soak-Restarts": false, "Soak-OOM": false,
+		"Soak-5xx": false},
+		passed(&common.SoakReport{HealthChecks: 100, HealthFailures: make([]common.SoakEvent, 1),
+			TaskStops: make([]common.SoakEvent, 1), OutOfMemoryKills: 1, ServerErrorsKnown: true,
+			APIRequests: 1000, API5xx: 2}))
+	assert.Equal(t, map[string]bool{"Soak-Health": false, "Soak-Restarts": true, "Soak-OOM": true},
+		passed(&common.SoakReport{Start: time.Now()}), "No health checks ran")
+}
+
+// TestSumMetricDatapoints checks the datapoints of aws cloudwatch get-metric-statistics are added up
+func TestSumMetricDatapoints(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	total, err := common.SumMetricDatapoints(`{"Label": "5XXError", "Datapoints": [` +
+		`{"Timestamp": "2026-01-01T00:00:00Z", "Sum": 3.0, "Unit": "Count"},` +
+		`{"Timestamp": "2026-01-01T01:00:00Z", "Sum": 4.0, "Unit": "Count"}]}`)
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, total)
+
+	total, err = common.SumMetricDatapoints(`{"Label": "Count", "Datapoints": []}`)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+
+	_, err = common.SumMetricDatapoints("not json")
+	assert.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getSSMTestVars(prefix string) map[string]interface{} {
+	return map[string]interface{}{
+		"prefix":          prefix,
+		"db_endpoint":     "test.endpoint.amazonaws.com:5432",
+		"environments":    []string{"dev", "prod"},
+		"app_db_username": "test_user",
+		"app_db_password": "test_password",
+	}
+}
+
+// TestSSMParameterContractsMatchDjangoSettings checks every parameter feeds a variable the Django settings module
+// actually reads, so a renamed setting cannot silently leave its parameter unused
+func TestSSMParameterContractsMatchDjangoSettings(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	variables, err := common.GetDjangoSettingsEnvironment(common.DjangoSettingsPath)
+	require.NoError(t, err)
+
+	for _, contract := range common.SSMParameterContracts {
+		assert.True(t, variables[contract.Setting], "Parameter %s feeds %s, which %s does not read",
+			contract.Path, contract.Setting, common.DjangoSettingsPath)
+	}
+}
+
+// TestSSMReadPolicyProblems checks read policies are held to the prefix's parameters and to read actions
+func TestSSMReadPolicyProblems(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	policy := func(actions, resource string) string {
+		return `{"Statement":[{"Effect":"Allow","Action":` + actions + `,"Resource":"` + resource + `"},` +
+			`{"Effect":"Allow","Action":"kms:Decrypt","Resource":"*"}]}`
+	}
+	scoped := "arn:aws:ssm:us-east-1:123456789012:parameter/coalition/secret-key"
+
+	assert.Empty(t, common.SSMReadPolicyProblems(policy(`["ssm:GetParameter","ssm:DescribeParameters"]`, scoped),
+		"coalition"))
+	assert.Len(t, common.SSMReadPolicyProblems(policy(`"ssm:GetParameter"`, "*"), "coalition"), 1)
+	assert.Len(t, common.SSMReadPolicyProblems(policy(`"ssm:GetParameter"`,
+		"arn:aws:ssm:us-east-1:123456789012:parameter/coalition-staging/secret-key"), "coalition"), 1)
+	assert.Len(t, common.SSMReadPolicyProblems(policy(`["ssm:GetParameter","ssm:PutParameter"]`, scoped),
+		"coalition"), 1)
+	assert.Nil(t, common.SSMReadPolicyProblems(
+		`{"Statement":[{"Effect":"Allow","Action":"logs:PutLogEvents","Resource":"*"}]}`, "coalition"))
+}
+
+// TestSSMModulePlansContractParameters checks the module plans exactly the contracted parameters, with their types
+// and tier, for each environment
+func TestSSMModulePlansContractParameters(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	testConfig := tfopts.NewTestConfig("../../modules/ssm")
+	terraformOptions := testConfig.GetTerraformOptionsForPlanOnly(getSSMTestVars(testConfig.Prefix))
+	plan := common.PlanOnly(t, terraformOptions)
+
+	// The read policy lists parameter ARNs, which are only known after apply, so its scope is checked when applied
+	common.ReportAuditFindings(t, common.SSMParameterFindings(common.NewPlanAuditContext(plan), testConfig.Prefix,
+		[]string{"dev", "prod"}))
+}
+
+// TestSSMModuleParametersAndReadScope applies the module and checks its parameters against their contracts and
+// that its read policy only reaches the prefix's parameters
+func TestSSMModuleParametersAndReadScope(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	testConfig := tfopts.NewTestConfig("../../modules/ssm")
+	options := testConfig.GetModuleTerraformOptions("../../modules/ssm", getSSMTestVars(testConfig.Prefix))
+
+	common.ApplyAndValidate(t, options,
+		common.Outputs("ssm_read_policy_arn", "secret_key_parameter_arn"),
+		common.SSMParameters(testConfig.Prefix, "dev", "prod"),
+	)
+}
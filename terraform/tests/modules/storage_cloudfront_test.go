@@ -0,0 +1,53 @@
+//go:build slow
+
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStorageModuleWithCloudFront covers the CloudFront distribution the other storage tests skip. Creating and
+// destroying it takes 20-40 minutes, so it only builds with -tags slow.
+func TestStorageModuleWithCloudFront(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	testConfig := tfopts.NewTestConfig("../../modules/storage")
+
+	testVars := common.GetDefaultStorageTestVars()
+	testVars["prefix"] = testConfig.Prefix
+	testVars["enable_cloudfront"] = true
+
+	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/storage", testVars).Options
+
+	common.RegisterEmptyBuckets(t, terraformOptions, "static_assets_bucket_name")
+	common.RegisterDestroy(t, terraformOptions)
+
+	common.RunTerraformWithProgress(t, terraformOptions, "storage apply with CloudFront", 0)
+
+	outputs := []string{
+		"cloudfront_origin_access_identity_path",
+		"cloudfront_distribution_domain_name",
+		"cloudfront_distribution_id",
+		"cloudfront_distribution_arn",
+		"cloudfront_distribution_hosted_zone_id",
+	}
+	for _, output := range outputs {
+		value := terraform.Output(t, terraformOptions, output)
+		assert.NotEmpty(t, value, "Output %s should not be empty", output)
+	}
+
+	// Validate CloudFront domain and ARN formats
+	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_distribution_domain_name")
+	assert.Contains(t, cloudfrontDomain, "cloudfront.net")
+
+	cloudfrontArn := terraform.Output(t, terraformOptions, "cloudfront_distribution_arn")
+	cloudfrontID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+	assert.Contains(t, cloudfrontArn, "arn:aws:cloudfront::")
+	assert.Contains(t, cloudfrontArn, "distribution/"+cloudfrontID)
+}
@@ -4,108 +4,91 @@ import (
 	"testing"
 
 	"terraform-tests/common"
+	"terraform-tests/tfopts"
+	"terraform-tests/tfout"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestStorageModule(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/storage")
+	testConfig := tfopts.NewTestConfig("../../modules/storage")
 
 	testVars := common.GetDefaultStorageTestVars()
 	testVars["prefix"] = testConfig.Prefix
 	testVars["domain_name"] = "test.example.com"
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/storage", testVars)
+	options := testConfig.GetModuleTerraformOptions("../../modules/storage", testVars)
 
-	defer common.CleanupResources(t, terraformOptions)
+	terraformOptions := options.Options
 
-	terraform.InitAndApply(t, terraformOptions)
+	common.RegisterEmptyBuckets(t, terraformOptions, "static_assets_bucket_name")
+
+	common.ApplyAndValidate(t, options)
 
 	// Validate bucket outputs
-	bucketName := terraform.Output(t, terraformOptions, "static_assets_bucket_name")
-	bucketArn := terraform.Output(t, terraformOptions, "static_assets_bucket_arn")
-	bucketDomain := terraform.Output(t, terraformOptions, "static_assets_bucket_domain_name")
-	uploadPolicyArn := terraform.Output(t, terraformOptions, "static_assets_upload_policy_arn")
-
-	// Validate CloudFront outputs
-	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_distribution_domain_name")
-	cloudfrontId := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
-
-	// Validate outputs exist and have expected format
-	assert.NotEmpty(t, bucketName)
-	assert.NotEmpty(t, bucketArn)
-	assert.NotEmpty(t, bucketDomain)
-	assert.NotEmpty(t, uploadPolicyArn)
-	assert.NotEmpty(t, cloudfrontDomain)
-	assert.NotEmpty(t, cloudfrontId)
+	bucketName := tfout.OutputString(t, terraformOptions, "static_assets_bucket_name").NotEmpty().Value()
+	bucketArn := tfout.OutputString(t, terraformOptions, "static_assets_bucket_arn").HasPrefix("arn:aws:s3:::").Value()
+	tfout.OutputString(t, terraformOptions, "static_assets_bucket_domain_name").NotEmpty()
+	uploadPolicyArn := tfout.OutputString(t, terraformOptions, "static_assets_upload_policy_arn").
+		Matches(`^arn:aws:iam::\d+:policy/`).Value()
 
 	// Validate bucket name format
 	assert.Contains(t, bucketName, testConfig.Prefix)
 	assert.Contains(t, bucketName, "static-assets")
-
-	// Validate ARN format
-	assert.Contains(t, bucketArn, "arn:aws:s3:::")
 	assert.Contains(t, bucketArn, bucketName)
-
-	// Validate IAM policy ARN format
-	assert.Contains(t, uploadPolicyArn, "arn:aws:iam::")
-	assert.Contains(t, uploadPolicyArn, "policy/")
 	assert.Contains(t, uploadPolicyArn, testConfig.Prefix)
-
-	// Validate CloudFront domain format
-	assert.Contains(t, cloudfrontDomain, "cloudfront.net")
 }
 
 func TestStorageModuleWithDefaultCORS(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/storage")
+	testConfig := tfopts.NewTestConfig("../../modules/storage")
 
 	testVars := map[string]interface{}{
 		"prefix":                 testConfig.Prefix,
 		"domain_name":            "test-cors.example.com",
-		"alb_dns_name":           "test-alb-cors-123456789.us-east-1.elb.amazonaws.com",
 		"force_destroy":          true,
 		"cors_allowed_origins":   []string{"*"}, // Test with wildcard
 		"enable_versioning":      false,
 		"enable_lifecycle_rules": false,
+		"enable_cloudfront":      false,
 	}
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/storage", testVars)
+	options := testConfig.GetModuleTerraformOptions("../../modules/storage", testVars)
 
-	defer common.CleanupResources(t, terraformOptions)
+	terraformOptions := options.Options
 
-	terraform.InitAndApply(t, terraformOptions)
+	common.RegisterEmptyBuckets(t, terraformOptions, "static_assets_bucket_name")
 
-	// Validate basic outputs exist
-	bucketName := terraform.Output(t, terraformOptions, "static_assets_bucket_name")
-	bucketArn := terraform.Output(t, terraformOptions, "static_assets_bucket_arn")
+	common.ApplyAndValidate(t, options)
 
-	assert.NotEmpty(t, bucketName)
-	assert.NotEmpty(t, bucketArn)
+	// Validate basic outputs exist
+	tfout.OutputString(t, terraformOptions, "static_assets_bucket_name").NotEmpty()
+	tfout.OutputString(t, terraformOptions, "static_assets_bucket_arn").NotEmpty()
 }
 
 func TestStorageModuleMinimalConfig(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 
-	testConfig := common.NewTestConfig("../../modules/storage")
+	testConfig := tfopts.NewTestConfig("../../modules/storage")
 
-	// Test with minimal configuration
+	// Test with minimal configuration, apart from skipping the slow CloudFront distribution
 	testVars := map[string]interface{}{
-		"prefix":        testConfig.Prefix,
-		"domain_name":   "test-minimal.example.com",
-		"alb_dns_name":  "test-alb-minimal-123456789.us-east-1.elb.amazonaws.com",
-		"force_destroy": true, // Required for test cleanup
+		"prefix":            testConfig.Prefix,
+		"domain_name":       "test-minimal.example.com",
+		"force_destroy":     true, // Required for test cleanup
+		"enable_cloudfront": false,
 	}
 
-	terraformOptions := testConfig.GetModuleTerraformOptions("../../modules/storage", testVars)
+	options := testConfig.GetModuleTerraformOptions("../../modules/storage", testVars)
+
+	terraformOptions := options.Options
 
-	defer common.CleanupResources(t, terraformOptions)
+	common.RegisterEmptyBuckets(t, terraformOptions, "static_assets_bucket_name")
 
-	terraform.InitAndApply(t, terraformOptions)
+	common.ApplyAndValidate(t, options)
 
 	// Validate all required outputs exist even with minimal config
 	outputs := []string{
@@ -115,15 +98,31 @@ func TestStorageModuleMinimalConfig(t *testing.T) {
 		"static_assets_bucket_regional_domain_name",
 		"static_assets_bucket_hosted_zone_id",
 		"static_assets_upload_policy_arn",
-		"cloudfront_origin_access_identity_path",
-		"cloudfront_distribution_domain_name",
-		"cloudfront_distribution_id",
-		"cloudfront_distribution_arn",
-		"cloudfront_distribution_hosted_zone_id",
 	}
 
 	for _, output := range outputs {
-		value := terraform.Output(t, terraformOptions, output)
-		assert.NotEmpty(t, value, "Output %s should not be empty", output)
+		tfout.OutputString(t, terraformOptions, output).NotEmpty()
 	}
+
+	// With CONFIG_RULES set, check AWS Config finds the bucket does not allow public reads
+	common.AssertConfigCompliance(t, terraformOptions)
+}
+
+// TestStorageModulePlansCloudFrontOriginFailover checks the distribution's failover coverage. It only sends
+// static files to the app's domain, which need no failover origin, so no app behavior is reported until it
+// fronts the app itself.
+func TestStorageModulePlansCloudFrontOriginFailover(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	testConfig := tfopts.NewTestConfig("../../modules/storage")
+
+	testVars := common.GetDefaultStorageTestVars()
+	testVars["prefix"] = testConfig.Prefix
+	testVars["domain_name"] = "test.example.com"
+	testVars["enable_cloudfront"] = true
+
+	plan := common.PlanOnly(t, testConfig.GetTerraformOptionsForPlanOnly(testVars))
+
+	findings := common.RunAudit(common.NewPlanAuditContext(plan), common.CheckCloudFrontOriginFailover)
+	assert.Empty(t, findings, "The distribution should only send static files to the app's domain")
 }
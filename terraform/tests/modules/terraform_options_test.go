@@ -0,0 +1,106 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetTerraformOptionsMergesVars checks variables the caller passes override the defaults, defaults the caller
+// leaves out are kept, and the run tags are added to the caller's tags rather than replacing them
+func TestGetTerraformOptionsMergesVars(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+	t.Setenv("GIT_COMMIT", "0123456789abcdef0123456789abcdef01234567")
+	t.Setenv("GIT_BRANCH", "feature/tags")
+
+	testConfig := tfopts.NewTestConfig(terraformRoot)
+	vars := testConfig.GetTerraformOptions(map[string]interface{}{
+		"create_vpc": false,
+		"vpc_id":     "vpc-0a1b2c3d",
+		"tags":       map[string]string{"Owner": "tests"},
+	}).Vars
+
+	assert.Equal(t, false, vars["create_vpc"], "A caller's variable should override the default")
+	assert.Equal(t, "vpc-0a1b2c3d", vars["vpc_id"], "A variable without a default should be passed through")
+	assert.Equal(t, testConfig.Prefix, vars["prefix"])
+	assert.Equal(t, true, vars["create_public_subnets"], "Defaults the caller leaves out should be kept")
+	assert.Equal(t, map[string]string{
+		"Owner":             "tests",
+		tfopts.TestRunIDTag: tfopts.TestRunID(),
+		tfopts.GitCommitTag: "0123456789abcdef0123456789abcdef01234567",
+		tfopts.GitBranchTag: "feature/tags",
+	}, vars["tags"], "The run tags should be added to the caller's tags, not the default ones")
+
+	vars = testConfig.GetTerraformOptions(nil).Vars
+	assert.Equal(t, map[string]string{
+		"Project":           "coalition",
+		"Environment":       "Test",
+		tfopts.TestRunIDTag: tfopts.TestRunID(),
+		tfopts.GitCommitTag: "0123456789abcdef0123456789abcdef01234567",
+		tfopts.GitBranchTag: "feature/tags",
+	}, vars["tags"], "Without tags from the caller, the run tags should be added to the default tags")
+}
+
+// TestGetTerraformOptionsBackendKey checks each test configuration keeps its state under its own key, in the
+// account's state bucket
+func TestGetTerraformOptionsBackendKey(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+	t.Setenv("AWS_ACCOUNT_ID", "210987654321")
+
+	testConfig := tfopts.NewTestConfig(terraformRoot)
+	backend := testConfig.GetTerraformOptions(nil).BackendConfig
+
+	assert.Equal(t, "coalition-terraform-state-210987654321", backend["bucket"])
+	assert.Equal(t, "tests/terraform-test-"+testConfig.UniqueID+".tfstate", backend["key"])
+	assert.Equal(t, testConfig.AWSRegion, backend["region"])
+	assert.Equal(t, true, backend["encrypt"])
+
+	other := tfopts.NewTestConfig(terraformRoot).GetTerraformOptions(nil).BackendConfig
+	assert.NotEqual(t, backend["key"], other["key"], "Two test configurations should not share a state file")
+}
+
+// TestGetModuleTerraformOptionsMergesVars checks each module gets its own test values, which the caller's
+// override, and only variables it declares, such as a region only when it takes one, and the run tags when it
+// takes tags
+func TestGetModuleTerraformOptionsMergesVars(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	testConfig := tfopts.NewTestConfig(terraformRoot)
+
+	security := testConfig.GetModuleTerraformOptions("../../modules/security", map[string]interface{}{
+		"allowed_bastion_cidrs": []string{"192.0.2.0/24"},
+	})
+	assert.Equal(t, []string{"192.0.2.0/24"}, security.Vars["allowed_bastion_cidrs"],
+		"A caller's variable should override the module's base variable")
+	assert.Equal(t, "vpc-12345678", security.Vars["vpc_id"])
+	assert.NotContains(t, security.Vars, "aws_region", "The security module takes no region")
+	assert.Equal(t, testConfig.AWSRegion, security.EnvVars["AWS_DEFAULT_REGION"])
+
+	networking := testConfig.GetModuleTerraformOptions("../../modules/networking", nil)
+	assert.Equal(t, testConfig.AWSRegion, networking.Vars["aws_region"])
+	assert.Equal(t, testConfig.Prefix, networking.Vars["prefix"])
+
+	database := testConfig.GetModuleTerraformOptions("../../modules/database", nil)
+	assert.Equal(t, false, database.Vars["prevent_destroy"],
+		"Apply tests must be able to destroy the database's parameter groups")
+
+	for _, modulePath := range terraformModulePaths(t) {
+		vars := testConfig.GetModuleTerraformOptions(modulePath, nil).Vars
+		declared := map[string]bool{}
+		for _, variable := range common.GetModuleVariables(t, modulePath) {
+			declared[variable.Name] = true
+		}
+		for name := range vars {
+			assert.True(t, declared[name], "%s is given %s, which it does not declare", modulePath, name)
+		}
+		if declared["prefix"] {
+			assert.Equal(t, testConfig.Prefix, vars["prefix"], "%s should get the test prefix", modulePath)
+		}
+		if declared["tags"] {
+			assert.Equal(t, tfopts.RunTags(), vars["tags"], "%s should be tagged with the run", modulePath)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTiers(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	tiers, err := common.ParseTiers(" Unit, plan ,")
+	require.NoError(t, err)
+	assert.Equal(t, []common.Tier{common.TierUnit, common.TierPlan}, tiers)
+
+	tiers, err = common.ParseTiers("e2e,all")
+	require.NoError(t, err)
+	assert.Equal(t, common.AllTiers, tiers)
+
+	_, err = common.ParseTiers("unit,smoke")
+	assert.ErrorContains(t, err, `unknown tier "smoke"`)
+
+	_, err = common.ParseTiers(",")
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package modules
+
+import (
+	"regexp"
+	"testing"
+
+	"terraform-tests/common"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMissingXRayActions checks X-Ray actions are found in a role's policies, directly or through wildcards
+func TestMissingXRayActions(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	writes := `{"Statement":[{"Effect":"Allow","Action":["xray:PutTraceSegments","xray:PutTelemetryRecords"],` +
+		`"Resource":"*"}]}`
+	sampling := `{"Statement":[{"Effect":"Allow","Action":["xray:GetSamplingRules","xray:GetSamplingTargets"],` +
+		`"Resource":"*"}]}`
+
+	assert.Empty(t, common.MissingXRayActions([]string{writes, sampling}))
+	assert.Empty(t, common.MissingXRayActions([]string{`{"Statement":[{"Effect":"Allow","Action":"xray:*"}]}`}))
+	assert.Equal(t, []string{"xray:GetSamplingRules", "xray:GetSamplingTargets"},
+		common.MissingXRayActions([]string{writes}))
+}
+
+// TestCheckTracingSidecar checks traced containers need a sidecar exposing its protocol's port, and untraced
+// task definitions are left alone
+func TestCheckTracingSidecar(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	check := func(definitions string) []common.AuditFinding {
+		task := &tfjson.StateResource{Address: "aws_ecs_task_definition.api", Type: "aws_ecs_task_definition",
+			AttributeValues: map[string]interface{}{"container_definitions": definitions}}
+		return common.RunAudit(&common.AuditContext{Resources: []*tfjson.StateResource{task}}, common.CheckTracingSidecar)
+	}
+	app := `{"name":"api","image":"coalition-api:latest","environment":[{"name":"OTEL_EXPORTER_OTLP_ENDPOINT"}]}`
+
+	assert.Empty(t, check(`[{"name":"geodata-import","image":"geodata:latest"}]`))
+
+	findings := check(`[` + app + `,{"name":"adot",` +
+		`"image":"public.ecr.aws/aws-observability/aws-otel-collector:v0.40.0",` +
+		`"portMappings":[{"containerPort":4317,"protocol":"tcp"}]}]`)
+	require.Len(t, findings, 1)
+	assert.True(t, findings[0].Passed, findings[0].Detail)
+
+	findings = check(`[{"name":"api","image":"coalition-api","environment":[{"name":"AWS_XRAY_DAEMON_ADDRESS"}]},` +
+		`{"name":"xray","image":"amazon/aws-xray-daemon","portMappings":[{"containerPort":2000}]}]`)
+	require.Len(t, findings, 1)
+	assert.False(t, findings[0].Passed, "The X-Ray daemon listens on UDP")
+
+	findings = check(`[` + app + `]`)
+	require.Len(t, findings, 1)
+	assert.False(t, findings[0].Passed)
+	assert.Contains(t, findings[0].Detail, "no X-Ray or ADOT sidecar")
+}
+
+// TestSamplingRuleProblem checks a service needs a matching sampling rule that traces some requests
+func TestSamplingRuleProblem(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	defaultRule := common.SamplingRule{RuleName: "Default", ServiceName: "*", ReservoirSize: 1, FixedRate: 0.05}
+	off := common.SamplingRule{RuleName: "Off", ServiceName: "coalition-*"}
+
+	assert.Empty(t, common.SamplingRuleProblem([]common.SamplingRule{defaultRule}, "coalition-prod"))
+	assert.Empty(t, common.SamplingRuleProblem([]common.SamplingRule{off, defaultRule}, "coalition-prod"))
+	assert.NotEmpty(t, common.SamplingRuleProblem([]common.SamplingRule{off}, "coalition-prod"))
+	assert.NotEmpty(t, common.SamplingRuleProblem(nil, "coalition-prod"))
+}
+
+// TestNewTraceID checks trace IDs have X-Ray's format and differ between calls
+func TestNewTraceID(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	id := common.NewTraceID()
+	assert.Regexp(t, regexp.MustCompile(`^1-[0-9a-f]{8}-[0-9a-f]{24}$`), id)
+	assert.NotEqual(t, id, common.NewTraceID())
+}
@@ -0,0 +1,52 @@
+package modules
+
+import (
+	"sync"
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNewTestConfigUniqueIDsDoNotCollide creates many test configurations concurrently, as parallel tests do,
+// and checks that every one gets its own ID and resource prefix
+func TestNewTestConfigUniqueIDsDoNotCollide(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	const workers = 16
+	const configsPerWorker = 500
+
+	var mutex sync.Mutex
+	seen := make(map[string]bool, workers*configsPerWorker)
+	var wg sync.WaitGroup
+
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < configsPerWorker; i++ {
+				testConfig := tfopts.NewTestConfig("../../")
+
+				mutex.Lock()
+				assert.False(t, seen[testConfig.UniqueID], "UniqueID %s was generated twice", testConfig.UniqueID)
+				seen[testConfig.UniqueID] = true
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, workers*configsPerWorker)
+}
+
+func TestNewUniqueIDFitsPrefixLimit(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	testConfig := tfopts.NewTestConfig("../../")
+
+	assert.Regexp(t, `^test-[0-9a-f]{14}$`, testConfig.UniqueID)
+	assert.LessOrEqual(t, len(testConfig.Prefix), common.MaxPrefixLength,
+		"Test prefix %s is longer than the names checked by TestResourceNamesFitAWSLimits", testConfig.Prefix)
+}
@@ -0,0 +1,127 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/awscalls"
+	"terraform-tests/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidatorsReportEveryViolation checks the tag, naming and output validators against an applied state held in
+// memory, so each finding can be checked without an apply
+func TestValidatorsReportEveryViolation(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	applied := &common.AppliedConfiguration{
+		State: &common.AuditContext{Resources: []*tfjson.StateResource{
+			{
+				Address: "aws_security_group.db_sg[0]",
+				Type:    "aws_security_group",
+				AttributeValues: map[string]interface{}{
+					"name":     "coalition-abc123-db-sg",
+					"tags_all": map[string]interface{}{"Name": "coalition-abc123-db-sg", "Environment": "Test"},
+				},
+			},
+			{
+				Address: "aws_vpc.main[0]",
+				Type:    "aws_vpc",
+				AttributeValues: map[string]interface{}{
+					"tags":     map[string]interface{}{"Name": "other-vpc"},
+					"tags_all": map[string]interface{}{"Name": "other-vpc", "Environment": "Prod"},
+				},
+			},
+			{
+				Address:         "aws_security_group_rule.db_ingress_bastion[0]",
+				Type:            "aws_security_group_rule",
+				AttributeValues: map[string]interface{}{"from_port": float64(5432)},
+			},
+		}},
+		Outputs: map[string]interface{}{
+			"vpc_id":     "vpc-0a1b2c3d",
+			"subnet_ids": []interface{}{},
+		},
+	}
+
+	failed := func(validator common.Validator) map[string]string {
+		failures := map[string]string{}
+		for _, finding := range validator(t, applied) {
+			if !finding.Passed && !finding.Skipped {
+				failures[finding.Resource] = finding.Detail
+			}
+		}
+		return failures
+	}
+
+	tagFailures := failed(common.TagPolicy(map[string]string{"Name": "", "Environment": "Test", "Owner": ""}))
+	assert.Equal(t, map[string]string{
+		"aws_security_group.db_sg[0]": "missing tag Owner",
+		"aws_vpc.main[0]":             `tag Environment is "Prod", expected "Test"; missing tag Owner`,
+	}, tagFailures, "Resources that take no tags, such as rules, should not be checked")
+
+	namingFailures := failed(common.Naming("coalition-abc123", map[string]string{
+		"aws_security_group": "-sg",
+		"aws_vpc":            "-vpc",
+	}))
+	assert.Len(t, namingFailures, 1)
+	assert.Contains(t, namingFailures, "aws_vpc.main[0]",
+		"A VPC without a name attribute should be checked by its Name tag")
+
+	outputFailures := failed(common.Outputs("vpc_id", "subnet_ids", "missing"))
+	assert.Len(t, outputFailures, 2)
+	assert.Contains(t, outputFailures, "output.missing")
+	assert.Contains(t, outputFailures, "output.subnet_ids", "An empty list output should count as not set")
+}
+
+// TestSGRulesValidator checks the security group validator against a group served by a mocked EC2, so the lookup
+// it makes and each rule's finding can be checked without an apply
+func TestSGRulesValidator(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	var requested []string
+	awscalls.Mock(t, func(operation string, input interface{}) (interface{}, error) {
+		require.Equal(t, "DescribeSecurityGroups", operation)
+		requested = append(requested, input.(*ec2.DescribeSecurityGroupsInput).GroupIds...)
+		return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: []types.SecurityGroup{{
+			GroupId:   aws.String("sg-0a1b2c3d"),
+			GroupName: aws.String("coalition-abc123-db-sg"),
+			IpPermissions: []types.IpPermission{{
+				IpProtocol: aws.String("tcp"),
+				FromPort:   aws.Int32(5432),
+				ToPort:     aws.Int32(5432),
+				IpRanges:   []types.IpRange{{CidrIp: aws.String("10.0.0.0/16")}, {CidrIp: aws.String("0.0.0.0/0")}},
+			}},
+		}}}, nil
+	})
+
+	applied := &common.AppliedConfiguration{
+		Outputs: map[string]interface{}{"db_security_group_id": "sg-0a1b2c3d"},
+		Region:  "us-east-1",
+	}
+	findings := common.SGRules("db_security_group_id",
+		common.SGRule{Port: 5432, Allowed: []string{"10.0.0.0/16"}, Disallowed: []string{"0.0.0.0/0"}},
+		common.SGRule{Port: 22},
+	)(t, applied)
+
+	assert.Equal(t, []string{"sg-0a1b2c3d"}, requested)
+	require.Len(t, findings, 2)
+	assert.False(t, findings[0].Passed)
+	assert.Equal(t, "tcp/5432 ingress: admits 0.0.0.0/0", findings[0].Detail)
+	assert.False(t, findings[1].Passed)
+	assert.Equal(t, "coalition-abc123-db-sg has no tcp/22 ingress rule", findings[1].Detail)
+
+	calls := awscalls.Calls(t.Name())
+	require.Len(t, calls, 1, "Mocked calls should still be recorded")
+	assert.Equal(t, "EC2", calls[0].Service)
+
+	findings = common.SGRules("missing")(t, applied)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "output is not set", findings[0].Detail)
+	assert.Len(t, requested, 1, "An unset output should not be looked up")
+}
@@ -0,0 +1,91 @@
+package modules
+
+import (
+	"path/filepath"
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/stretchr/testify/require"
+)
+
+// terraformRoot is the terraform directory relative to this package
+const terraformRoot = "../.."
+
+// terraformModulePaths returns the root configuration and every module under modules/
+func terraformModulePaths(t *testing.T) []string {
+	modules, err := filepath.Glob(filepath.Join(terraformRoot, "modules", "*", "variables.tf"))
+	require.NoError(t, err)
+	require.NotEmpty(t, modules, "No modules found under %s", terraformRoot)
+
+	paths := []string{terraformRoot}
+	for _, variablesFile := range modules {
+		paths = append(paths, filepath.Dir(variablesFile))
+	}
+	return paths
+}
+
+// terraformModuleName names a module path for subtests
+func terraformModuleName(modulePath string) string {
+	if modulePath == terraformRoot {
+		return "root"
+	}
+	return filepath.Base(modulePath)
+}
+
+// TestModuleVariablesDocumented parses every variables.tf, so it needs no AWS credentials and runs in short mode
+func TestModuleVariablesDocumented(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	for _, modulePath := range terraformModulePaths(t) {
+		t.Run(terraformModuleName(modulePath), func(t *testing.T) {
+			common.AssertVariablesDocumented(t, modulePath)
+		})
+	}
+}
+
+func TestModuleVariablesAreUsed(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	for _, modulePath := range terraformModulePaths(t) {
+		t.Run(terraformModuleName(modulePath), func(t *testing.T) {
+			common.AssertNoUnusedVariables(t, modulePath)
+		})
+	}
+}
+
+// TestFixtureVariablesDeclared checks the variables the shared Go fixtures pass, merged with the base variables
+// every module test receives, against what each module declares
+func TestFixtureVariablesDeclared(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	fixtures := []struct {
+		name   string
+		module string
+		vars   map[string]interface{}
+	}{
+		{name: "GetNetworkingTestVars", module: "networking", vars: common.GetNetworkingTestVars()},
+		{name: "GetDefaultDatabaseTestVars", module: "database", vars: common.GetDefaultDatabaseTestVars()},
+		{name: "GetDefaultSecurityTestVars", module: "security", vars: common.GetDefaultSecurityTestVars()},
+		{name: "GetMonitoringTestVars", module: "monitoring", vars: common.GetMonitoringTestVars()},
+		{name: "GetDefaultStorageTestVars", module: "storage", vars: common.GetDefaultStorageTestVars()},
+		{name: "geodataImportPlanVars", module: "geodata-import", vars: geodataImportPlanVars()},
+		{name: "BaseVars", module: "bastion", vars: nil},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.name+"/"+fixture.module, func(t *testing.T) {
+			modulePath := filepath.Join(terraformRoot, "modules", fixture.module)
+			testConfig := tfopts.NewTestConfig(modulePath)
+			options := testConfig.GetModuleTerraformOptions(modulePath, fixture.vars)
+			common.AssertFixtureVariablesDeclared(t, modulePath, fixture.name, options.Vars)
+		})
+	}
+
+	t.Run("GetIntegrationTestVars/root", func(t *testing.T) {
+		testConfig := tfopts.NewTestConfig(terraformRoot)
+		options := testConfig.GetTerraformOptions(common.GetIntegrationTestVars())
+		common.AssertFixtureVariablesDeclared(t, terraformRoot, "GetIntegrationTestVars", options.Vars)
+	})
+}
@@ -0,0 +1,52 @@
+package modules
+
+import (
+	"encoding/json"
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rootVariablesSnapshot records the root configuration's variables as deployers see them
+const rootVariablesSnapshot = "testdata/root_variables.json"
+
+// TestRootVariableSurfaceSnapshot compares the root configuration's variables with the snapshot, so renaming or
+// retyping a variable, or changing a default, is a deliberate change reviewed with the snapshot
+func TestRootVariableSurfaceSnapshot(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	common.AssertVariableSurfaceSnapshot(t, terraformRoot, rootVariablesSnapshot)
+}
+
+// TestVariableSurfaceChanges checks which changes to a variable surface are marked as breaking
+func TestVariableSurfaceChanges(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	snapshot := []common.VariableSurface{
+		{Name: "aws_region", Type: "string", Default: json.RawMessage(`"us-east-1"`)},
+		{Name: "budget", Type: "number", Default: json.RawMessage(`30`)},
+		{Name: "domain_name", Type: "string", Required: true},
+		{Name: "legacy", Type: "bool", Default: json.RawMessage(`false`)},
+		{Name: "tags", Type: "map(string)", Default: json.RawMessage(`{}`)},
+	}
+	current := []common.VariableSurface{
+		{Name: "aws_region", Type: "string", Required: true},
+		{Name: "budget", Type: "number", Default: json.RawMessage(`50`)},
+		{Name: "domain_name", Type: "string", Required: true},
+		{Name: "alert_email", Type: "string", Required: true},
+		{Name: "dark_mode", Type: "bool", Default: json.RawMessage(`true`)},
+		{Name: "tags", Type: "map(any)", Default: json.RawMessage(`{}`)},
+	}
+
+	assert.Equal(t, []string{
+		"BREAKING: legacy was removed",
+		`BREAKING: aws_region lost its default "us-east-1"`,
+		"budget changed default from 30 to 50",
+		"BREAKING: alert_email was added without a default",
+		"dark_mode was added with default true",
+		"BREAKING: tags changed type from map(string) to map(any)",
+	}, common.VariableSurfaceChanges(snapshot, current))
+	assert.Empty(t, common.VariableSurfaceChanges(snapshot, snapshot))
+}
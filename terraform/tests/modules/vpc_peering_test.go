@@ -0,0 +1,132 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vpcPeeringScenario peers a coalition VPC with a shared-services VPC holding the database subnets, built from the
+// networking, security and vpc-peering modules
+const vpcPeeringScenario = "../scenarios/vpc-peering"
+
+// Address space of the scenario's two VPCs and the coalition app subnets the database admits
+const (
+	coalitionVPCCIDR = "10.210.0.0/16"
+	sharedVPCCIDR    = "10.220.0.0/16"
+)
+
+var coalitionAppSubnetCIDRs = []string{"10.210.3.0/24", "10.210.4.0/24"}
+
+func getVPCPeeringScenarioVars() map[string]interface{} {
+	return map[string]interface{}{
+		"coalition_vpc_cidr": coalitionVPCCIDR,
+		"shared_vpc_cidr":    sharedVPCCIDR,
+	}
+}
+
+// TestVPCPeeringUnapprovedPeerIngress checks which ingress rules count as exposing a group to the peer: any rule
+// whose CIDR block overlaps the peer's or that references a group across the peering, unless it opens only an
+// approved port
+func TestVPCPeeringUnapprovedPeerIngress(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	tcp := func(from, to int32, cidrs ...string) types.IpPermission {
+		permission := types.IpPermission{IpProtocol: aws.String("tcp"), FromPort: aws.Int32(from), ToPort: aws.Int32(to)}
+		for _, cidr := range cidrs {
+			permission.IpRanges = append(permission.IpRanges, types.IpRange{CidrIp: aws.String(cidr)})
+		}
+		return permission
+	}
+
+	group := &types.SecurityGroup{GroupId: aws.String("sg-0a1b2c3d4e5f60718"), IpPermissions: []types.IpPermission{
+		tcp(5432, 5432, "10.210.3.0/24"), // Approved port from an app subnet
+		tcp(22, 22, "10.220.0.0/16"),     // The group's own VPC, not the peer
+		tcp(0, 65535, "10.0.0.0/8"),      // Covers the peer on every port
+		{IpProtocol: aws.String("-1"), UserIdGroupPairs: []types.UserIdGroupPair{{
+			GroupId:                aws.String("sg-0fedcba9876543210"),
+			VpcPeeringConnectionId: aws.String("pcx-0a1b2c3d4e5f60718"),
+		}}},
+	}}
+
+	problems := common.UnapprovedPeerIngress(group, coalitionVPCCIDR, common.DatabasePeerPorts)
+	require.Len(t, problems, 2, "%v", problems)
+	assert.Contains(t, problems[0], "10.0.0.0/8 on tcp/0-65535")
+	assert.Contains(t, problems[1], "sg-0fedcba9876543210 via pcx-0a1b2c3d4e5f60718 on all traffic")
+}
+
+// TestVPCPeeringScenarioPlansRoutesBothWays plans the scenario and checks each side routes the other's CIDR block
+// through the peering and the database admits only the coalition app subnets
+func TestVPCPeeringScenarioPlansRoutesBothWays(t *testing.T) {
+	common.RequireTier(t, common.TierPlan)
+
+	testConfig := tfopts.NewTestConfig(vpcPeeringScenario)
+	terraformOptions := testConfig.GetModuleTerraformOptionsForPlanOnly(vpcPeeringScenario, getVPCPeeringScenarioVars())
+	plan := common.PlanOnly(t, terraformOptions)
+
+	routes := map[string]string{
+		"module.vpc_peering.aws_route.requester_to_accepter[0]": sharedVPCCIDR,
+		"module.vpc_peering.aws_route.accepter_to_requester[0]": coalitionVPCCIDR,
+	}
+	for address, destination := range routes {
+		route, exists := plan.ResourcePlannedValuesMap[address]
+		if assert.True(t, exists, "%s should be planned", address) {
+			assert.Equal(t, destination, common.GetPlannedStringAttribute(route, "destination_cidr_block"))
+		}
+	}
+
+	const ingressAddress = "module.shared_security.aws_security_group_rule.db_ingress_lambda_cidrs[0]"
+	ingress, exists := plan.ResourcePlannedValuesMap[ingressAddress]
+	require.True(t, exists, "The database should admit the coalition app subnets")
+	assert.EqualValues(t, common.DatabasePeerPorts[0], ingress.AttributeValues["from_port"])
+	assert.EqualValues(t, common.DatabasePeerPorts[0], ingress.AttributeValues["to_port"])
+	assert.ElementsMatch(t, coalitionAppSubnetCIDRs, ingress.AttributeValues["cidr_blocks"])
+}
+
+// TestVPCPeeringScenario applies the scenario and checks the peering is active, both sides route through it, DNS
+// settings are as expected, and the peer reaches the database subnets only on the PostgreSQL port
+func TestVPCPeeringScenario(t *testing.T) {
+	common.RequireTier(t, common.TierApply)
+
+	testConfig := tfopts.NewTestConfig(vpcPeeringScenario)
+	testVars := withAvailabilityZones(t, testConfig.AWSRegion, getVPCPeeringScenarioVars())
+	options := testConfig.GetModuleTerraformOptions(vpcPeeringScenario, testVars)
+	terraformOptions := options.Options
+	common.RegisterNetworkCleanup(t, terraformOptions, testConfig.AWSRegion, testConfig.Prefix)
+
+	common.ApplyAndValidate(t, options,
+		common.Outputs("vpc_id", "shared_vpc_id", "app_route_table_id", "db_route_table_id", "peering_connection_id"),
+		common.SGRules("db_security_group_id", common.SGRule{
+			Port:       common.DatabasePeerPorts[0],
+			Allowed:    coalitionAppSubnetCIDRs,
+			Disallowed: []string{"0.0.0.0/0", coalitionVPCCIDR},
+		}),
+	)
+
+	applied := common.NewAppliedConfiguration(t, terraformOptions)
+	coalitionVPCID, sharedVPCID := applied.Output("vpc_id"), applied.Output("shared_vpc_id")
+	peeringID := applied.Output("peering_connection_id")
+
+	peering := common.GetVpcPeeringConnection(t, peeringID, testConfig.AWSRegion)
+	common.AssertPeeringActive(t, peering, coalitionVPCID, sharedVPCID)
+
+	common.AssertRouteToPeer(t, applied.Output("app_route_table_id"), testConfig.AWSRegion, sharedVPCCIDR, peeringID)
+	common.AssertRouteToPeer(t, applied.Output("db_route_table_id"), testConfig.AWSRegion, coalitionVPCCIDR, peeringID)
+
+	// Both VPCs resolve DNS, but neither resolves the other's private hostnames: the module leaves remote DNS
+	// resolution off, and the endpoints of private RDS instances resolve to private addresses without it
+	common.AssertVPCDNSEnabled(t, coalitionVPCID, testConfig.AWSRegion)
+	common.AssertVPCDNSEnabled(t, sharedVPCID, testConfig.AWSRegion)
+	requesterDNS, accepterDNS := common.PeeringDNSResolution(peering)
+	assert.False(t, requesterDNS, "The coalition VPC should not resolve shared-services private hostnames")
+	assert.False(t, accepterDNS, "The shared-services VPC should not resolve coalition private hostnames")
+
+	common.AssertPeerReachesOnlyApprovedPorts(t, sharedVPCID, testConfig.AWSRegion, coalitionVPCCIDR,
+		common.DatabasePeerPorts)
+}
@@ -0,0 +1,68 @@
+package modules
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWAFAuditChecks plans a web ACL without an association, one associated through an ARN known only after
+// apply and one associated in state, and checks only the first fails
+func TestWAFAuditChecks(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	plan := &terraform.PlanStruct{
+		ResourcePlannedValuesMap: map[string]*tfjson.StateResource{},
+		ResourceChangesMap:       map[string]*tfjson.ResourceChange{},
+	}
+	add := func(address, resourceType string, attributes map[string]interface{}, unknown ...string) {
+		plan.ResourcePlannedValuesMap[address] = &tfjson.StateResource{Address: address, Type: resourceType,
+			AttributeValues: attributes}
+		afterUnknown := map[string]interface{}{}
+		for _, attribute := range unknown {
+			afterUnknown[attribute] = true
+		}
+		plan.ResourceChangesMap[address] = &tfjson.ResourceChange{Address: address,
+			Change: &tfjson.Change{AfterUnknown: afterUnknown}}
+	}
+	add("module.security.aws_wafv2_web_acl.main[0]", "aws_wafv2_web_acl",
+		map[string]interface{}{"scope": "REGIONAL"}, "arn")
+
+	failed := func() []string {
+		var resources []string
+		for _, finding := range common.RunAudit(common.NewPlanAuditContext(plan), common.WAFAuditChecks()...) {
+			if !finding.Passed {
+				resources = append(resources, finding.Resource)
+			}
+		}
+		return resources
+	}
+	assert.Equal(t, []string{"module.security.aws_wafv2_web_acl.main[0]"}, failed(),
+		"A web ACL nothing is associated with should fail")
+
+	add("aws_wafv2_web_acl_association.api", "aws_wafv2_web_acl_association",
+		map[string]interface{}{}, "web_acl_arn", "resource_arn")
+	assert.Empty(t, failed(), "An association planned with the new web ACL's ARN should count")
+
+	const aclARN = "arn:aws:wafv2:us-east-1:123456789012:regional/webacl/coalition-waf/a1b2c3d4"
+	state := &common.AuditContext{Resources: []*tfjson.StateResource{
+		{Address: "aws_wafv2_web_acl.main", Type: "aws_wafv2_web_acl",
+			AttributeValues: map[string]interface{}{"scope": "REGIONAL", "arn": aclARN}},
+		{Address: "aws_wafv2_web_acl_association.other", Type: "aws_wafv2_web_acl_association",
+			AttributeValues: map[string]interface{}{"web_acl_arn": aclARN + "-other"}},
+	}}
+	findings := common.RunAudit(state, common.CheckWebACLAssociated)
+	if assert.Len(t, findings, 1) {
+		assert.False(t, findings[0].Passed, "An association naming another web ACL should not count")
+	}
+
+	state.Resources[1].AttributeValues["web_acl_arn"] = aclARN
+	findings = common.RunAudit(state, common.CheckWebACLAssociated)
+	if assert.Len(t, findings, 1) {
+		assert.True(t, findings[0].Passed)
+	}
+}
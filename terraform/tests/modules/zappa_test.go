@@ -3,11 +3,15 @@ package modules
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
 
+	"terraform-tests/cleanup"
 	"terraform-tests/common"
+	"terraform-tests/s3util"
+	"terraform-tests/tfopts"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -27,7 +31,7 @@ func TestZappaModuleValidation(t *testing.T) {
 }
 
 func TestZappaModule(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierApply)
 	t.Parallel()
 
 	// Generate unique names for this test
@@ -44,27 +48,17 @@ func TestZappaModule(t *testing.T) {
 	s3Client := s3.NewFromConfig(cfg)
 	iamClient := iam.NewFromConfig(cfg)
 
-	defer func() {
-		// Cleanup: Delete S3 bucket if it exists
-		bucketName := fmt.Sprintf("%s-zappa-deployments", prefix)
-
-		// List and delete all objects in bucket
-		listInput := &s3.ListObjectsV2Input{Bucket: aws.String(bucketName)}
-		result, err := s3Client.ListObjectsV2(ctx, listInput)
-		if err == nil {
-			for _, obj := range result.Contents {
-				_, _ = s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
-					Bucket: aws.String(bucketName),
-					Key:    obj.Key,
-				})
-			}
+	// Delete the bucket if it is left behind after destroy
+	deploymentBucket := fmt.Sprintf("%s-zappa-deployments", prefix)
+	cleanup.Func(t, cleanup.AfterDestroy, "delete bucket "+deploymentBucket, func(t *testing.T) error {
+		if _, emptyErr := s3util.EmptyBucketE(t, deploymentBucket); emptyErr != nil {
+			return emptyErr
 		}
-
-		// Delete bucket
 		_, _ = s3Client.DeleteBucket(ctx, &s3.DeleteBucketInput{
-			Bucket: aws.String(bucketName),
+			Bucket: aws.String(deploymentBucket),
 		})
-	}()
+		return nil
+	})
 
 	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
 		TerraformDir: "../../modules/zappa",
@@ -85,11 +79,11 @@ func TestZappaModule(t *testing.T) {
 		TimeBetweenRetries: 10 * time.Second,
 	})
 
-	// Clean up resources with "terraform destroy" at the end of the test
-	defer terraform.Destroy(t, terraformOptions)
+	// Empty the versioned deployment bucket, then run "terraform destroy" at the end of the test
+	common.RegisterEmptyBuckets(t, terraformOptions, "s3_bucket_name")
 
 	// Run "terraform init" and "terraform apply"
-	terraform.InitAndApply(t, terraformOptions)
+	common.ApplyAndValidate(t, tfopts.ApplyMode(terraformOptions))
 
 	// Validate outputs
 	t.Run("ValidateOutputs", func(t *testing.T) {
@@ -219,6 +213,11 @@ func TestZappaModule(t *testing.T) {
 		assert.Contains(t, policyDocument, "lambda:UpdateFunctionCode")
 		assert.Contains(t, policyDocument, "s3:PutObject")
 		assert.Contains(t, policyDocument, "apigateway:")
+
+		// Zappa's prod stage enables X-Ray tracing, which needs these on the function's role
+		decoded, err := url.QueryUnescape(policyDocument)
+		require.NoError(t, err)
+		assert.Empty(t, common.MissingXRayActions([]string{decoded}), "The role should allow sending X-Ray traces")
 	})
 
 	// Test resource tagging
@@ -244,7 +243,7 @@ func TestZappaModule(t *testing.T) {
 }
 
 func TestZappaModuleVariableValidation(t *testing.T) {
-	common.SkipIfShortTest(t)
+	common.RequireTier(t, common.TierPlan)
 	t.Parallel()
 
 	// Test validation errors
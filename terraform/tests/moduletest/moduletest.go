@@ -0,0 +1,167 @@
+// Package moduletest runs a module's variable permutations from a table. Each Case names a module, the variables
+// of one permutation, and what to check of its plan and of its applied resources:
+//
+//	moduletest.Run(t, moduletest.Case{
+//		Name:   "RestrictiveBastionCIDRs",
+//		Module: "security",
+//		Vars:   map[string]interface{}{"allowed_bastion_cidrs": []string{"203.0.113.0/24"}},
+//		PlanAssertions: []moduletest.PlanAssertion{
+//			moduletest.PlansResource("aws_security_group.bastion_sg"),
+//		},
+//		ApplyValidators: []common.Validator{
+//			common.SGRules("bastion_security_group_id", common.SGRule{Port: 22, Allowed: []string{"203.0.113.0/24"}}),
+//		},
+//	})
+//
+// Every module in the table is first initialized and validated in the unit tier. A case's plan assertions run in
+// the plan tier and its validators in the apply tier, each against its own test configuration, so one table covers
+// what a module's near-duplicate test functions did.
+package moduletest
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"terraform-tests/common"
+	"terraform-tests/tfopts"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+// Case is one permutation of a module's variables and the checks it must pass
+type Case struct {
+	Name   string // The subtest name
+	Module string // A directory under modules/, such as "security"
+	Vars   map[string]interface{}
+
+	// Setup changes a copy of Vars before the apply, such as pointing IDs at shared fixtures. Plans keep the
+	// placeholder IDs, which they accept.
+	Setup func(t *testing.T, vars map[string]interface{})
+
+	PlanAssertions  []PlanAssertion
+	ApplyValidators []common.Validator
+}
+
+// PlanAssertion checks a case's plan. The prefix is the one the case's resources are planned under.
+type PlanAssertion func(t *testing.T, plan *terraform.PlanStruct, prefix string)
+
+// ModulePath returns the directory of a module, wherever the test runs from
+func ModulePath(module string) string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "modules", module)
+}
+
+// Run runs each case as a subtest: its plan assertions against a plan of the module, then its validators against
+// an apply, which is destroyed when the subtest ends. Before the cases, each module is initialized without a
+// backend and validated once.
+func Run(t *testing.T, cases ...Case) {
+	t.Helper()
+
+	validated := map[string]bool{}
+	for _, c := range cases {
+		if validated[c.Module] {
+			continue
+		}
+		validated[c.Module] = true
+		t.Run("Validate/"+c.Module, func(t *testing.T) {
+			common.RequireTier(t, common.TierUnit)
+			validate(t, ModulePath(c.Module))
+		})
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			if len(c.PlanAssertions) > 0 {
+				t.Run("Plan", func(t *testing.T) { runPlan(t, c) })
+			}
+			if len(c.ApplyValidators) > 0 {
+				t.Run("Apply", func(t *testing.T) { runApply(t, c) })
+			}
+		})
+	}
+}
+
+// validate runs terraform init without a backend and terraform validate in a module
+func validate(t *testing.T, modulePath string) {
+	terraformOptions := &terraform.Options{TerraformDir: modulePath, TerraformBinary: "terraform", NoColor: true}
+	tfopts.InitForPlanOnly(t, terraformOptions)
+	terraform.Validate(t, terraformOptions)
+}
+
+func runPlan(t *testing.T, c Case) {
+	common.RequireTier(t, common.TierPlan)
+
+	modulePath := ModulePath(c.Module)
+	testConfig := tfopts.NewTestConfig(modulePath)
+	plan := common.PlanOnly(t, testConfig.GetModuleTerraformOptionsForPlanOnly(modulePath, copyVars(c.Vars)))
+	for _, assertion := range c.PlanAssertions {
+		assertion(t, plan, testConfig.Prefix)
+	}
+}
+
+func runApply(t *testing.T, c Case) {
+	common.RequireTier(t, common.TierApply)
+
+	modulePath := ModulePath(c.Module)
+	testConfig := tfopts.NewTestConfig(modulePath)
+	vars := copyVars(c.Vars)
+	if c.Setup != nil {
+		c.Setup(t, vars)
+	}
+	common.ApplyAndValidate(t, testConfig.GetModuleTerraformOptions(modulePath, vars), c.ApplyValidators...)
+}
+
+// copyVars copies a case's variables, so a case's Setup and the option helpers cannot change the table
+func copyVars(vars map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(vars))
+	for key, value := range vars {
+		copied[key] = value
+	}
+	return copied
+}
+
+// PlansResource asserts the plan creates each resource address
+func PlansResource(addresses ...string) PlanAssertion {
+	return func(t *testing.T, plan *terraform.PlanStruct, _ string) {
+		for _, address := range addresses {
+			assert.Contains(t, plan.ResourcePlannedValuesMap, address, "%s should be planned", address)
+		}
+	}
+}
+
+// OmitsResource asserts the plan does not create any of the resource addresses
+func OmitsResource(addresses ...string) PlanAssertion {
+	return func(t *testing.T, plan *terraform.PlanStruct, _ string) {
+		for _, address := range addresses {
+			assert.NotContains(t, plan.ResourcePlannedValuesMap, address, "%s should not be planned", address)
+		}
+	}
+}
+
+// PlannedAttribute asserts a planned resource's attribute, with {prefix} in the expected value standing for the
+// case's prefix
+func PlannedAttribute(address, attribute string, expected interface{}) PlanAssertion {
+	return func(t *testing.T, plan *terraform.PlanStruct, prefix string) {
+		resource, ok := plan.ResourcePlannedValuesMap[address]
+		if !assert.True(t, ok, "%s should be planned", address) {
+			return
+		}
+		want := expected
+		if text, isString := expected.(string); isString {
+			want = strings.ReplaceAll(text, "{prefix}", prefix)
+		}
+		assert.Equal(t, want, resource.AttributeValues[attribute], "%s.%s", address, attribute)
+	}
+}
+
+// WithPrefix builds a validator from the prefix of the case's resources, which is only known once the case runs,
+// such as for common.NamingConventions
+func WithPrefix(build func(prefix string) common.Validator) common.Validator {
+	return func(t *testing.T, applied *common.AppliedConfiguration) []common.AuditFinding {
+		prefix, _ := applied.Options.Vars["prefix"].(string)
+		return build(prefix)(t, applied)
+	}
+}
@@ -0,0 +1,106 @@
+// Package policy holds plan-level guards that every integration plan is held to, catching costly or
+// dangerous changes before anything is applied.
+package policy
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// ForbiddenResourceTypes are resource types this stack deliberately avoids because of their fixed hourly cost
+var ForbiddenResourceTypes = map[string]string{
+	"aws_nat_gateway": "VPC endpoints replace NAT gateways for private subnet access to AWS services",
+}
+
+// AllowedElasticIPs are the only Elastic IPs the stack allocates; any other is likely a leak or a NAT in disguise
+var AllowedElasticIPs = []string{
+	"module.bastion.aws_eip.bastion",
+}
+
+// ProvisionedIOPSVolumeTypes are storage types billed per provisioned IOPS
+var ProvisionedIOPSVolumeTypes = map[string]bool{"io1": true, "io2": true}
+
+// AssertCostGuards runs every cost guard against a plan
+func AssertCostGuards(t *testing.T, plan *terraform.PlanStruct) {
+	for resourceType := range ForbiddenResourceTypes {
+		AssertNoResourceType(t, plan, resourceType)
+	}
+	AssertOnlyAllowedAddresses(t, plan, "aws_eip", AllowedElasticIPs...)
+	AssertNoProvisionedIOPS(t, plan)
+	AssertNoMultiAZ(t, plan)
+}
+
+// AssertNoResourceType fails the test if the plan contains any resource of the given type
+func AssertNoResourceType(t *testing.T, plan *terraform.PlanStruct, resourceType string) {
+	reason := ForbiddenResourceTypes[resourceType]
+	if reason == "" {
+		reason = "resource type is not allowed in this stack"
+	}
+
+	for _, resource := range plannedResources(plan, resourceType) {
+		t.Errorf("Plan contains %s: %s", resource.Address, reason)
+	}
+}
+
+// AssertOnlyAllowedAddresses fails the test if the plan contains a resource of the given type at any other address
+func AssertOnlyAllowedAddresses(t *testing.T, plan *terraform.PlanStruct, resourceType string, allowed ...string) {
+	allowedSet := map[string]bool{}
+	for _, address := range allowed {
+		allowedSet[address] = true
+	}
+
+	for _, resource := range plannedResources(plan, resourceType) {
+		if !allowedSet[resource.Address] {
+			t.Errorf("Plan contains unexpected %s; allowed addresses are %v", resource.Address, allowed)
+		}
+	}
+}
+
+// AssertNoProvisionedIOPS fails the test if a database or volume uses provisioned-IOPS storage
+func AssertNoProvisionedIOPS(t *testing.T, plan *terraform.PlanStruct) {
+	for _, resource := range plannedResources(plan, "aws_db_instance") {
+		if storageType, _ := resource.AttributeValues["storage_type"].(string); ProvisionedIOPSVolumeTypes[storageType] {
+			t.Errorf("%s uses provisioned-IOPS storage %q; use gp3", resource.Address, storageType)
+		}
+	}
+
+	for _, resource := range plannedResources(plan, "aws_ebs_volume") {
+		if volumeType, _ := resource.AttributeValues["type"].(string); ProvisionedIOPSVolumeTypes[volumeType] {
+			t.Errorf("%s uses provisioned-IOPS volume type %q; use gp3", resource.Address, volumeType)
+		}
+	}
+
+	for _, resource := range plannedResources(plan, "aws_instance") {
+		devices, _ := resource.AttributeValues["root_block_device"].([]interface{})
+		for _, device := range devices {
+			attributes, _ := device.(map[string]interface{})
+			if volumeType, _ := attributes["volume_type"].(string); ProvisionedIOPSVolumeTypes[volumeType] {
+				t.Errorf("%s root volume uses provisioned-IOPS type %q; use gp3", resource.Address, volumeType)
+			}
+		}
+	}
+}
+
+// AssertNoMultiAZ fails the test if a database is planned as multi-AZ, which doubles its cost
+func AssertNoMultiAZ(t *testing.T, plan *terraform.PlanStruct) {
+	for _, resource := range plannedResources(plan, "aws_db_instance") {
+		if multiAZ, _ := resource.AttributeValues["multi_az"].(bool); multiAZ {
+			t.Errorf("%s is multi-AZ; test plans should use a single-AZ database", resource.Address)
+		}
+	}
+}
+
+// plannedResources returns the planned resources of a type in address order, so failures are reported stably
+func plannedResources(plan *terraform.PlanStruct, resourceType string) []*tfjson.StateResource {
+	var resources []*tfjson.StateResource
+	for _, resource := range plan.ResourcePlannedValuesMap {
+		if resource.Type == resourceType {
+			resources = append(resources, resource)
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Address < resources[j].Address })
+	return resources
+}
@@ -0,0 +1,108 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// RepresentativeTaggedResources are root configuration resources, from modules without a tags variable, that take
+// the provider's default tags only through default_tags
+var RepresentativeTaggedResources = []string{
+	"module.networking.aws_vpc.main[0]",
+	"module.networking.aws_subnet.private_a[0]",
+	"module.database.aws_db_instance.postgres",
+	"module.bastion.aws_instance.bastion",
+	"module.storage.aws_s3_bucket.static_assets",
+	"module.storage.aws_cloudfront_distribution.static_assets[0]",
+}
+
+// ProviderDefaultTagsReferences returns what a provider's default_tags block takes its tags from, such as
+// ["var.tags"], and whether the provider configures default_tags at all. The provider is keyed as in the plan's
+// configuration: "aws" for the root module's default provider.
+func ProviderDefaultTagsReferences(plan *terraform.PlanStruct, provider string) ([]string, bool) {
+	if plan.RawPlan.Config == nil {
+		return nil, false
+	}
+	config, exists := plan.RawPlan.Config.ProviderConfigs[provider]
+	if !exists {
+		return nil, false
+	}
+	defaultTags, exists := config.Expressions["default_tags"]
+	if !exists || defaultTags == nil || len(defaultTags.NestedBlocks) == 0 {
+		return nil, false
+	}
+	tags, exists := defaultTags.NestedBlocks[0]["tags"]
+	if !exists || tags == nil {
+		return nil, true
+	}
+	return tags.References, true
+}
+
+// AssertProviderDefaultTags fails the test unless the provider configures default_tags from the given reference
+func AssertProviderDefaultTags(t *testing.T, plan *terraform.PlanStruct, provider, reference string) {
+	references, configured := ProviderDefaultTagsReferences(plan, provider)
+	if !configured {
+		t.Errorf("Provider %s does not configure default_tags, so only resources with their own tags are tagged",
+			provider)
+		return
+	}
+	for _, configuredReference := range references {
+		if configuredReference == reference {
+			return
+		}
+	}
+	t.Errorf("Provider %s takes default_tags from %v, expected %s", provider, references, reference)
+}
+
+// MissingDefaultTags returns how a planned resource's tags_all falls short of the provider's default tags. A tag
+// the resource sets itself overrides the default, so only its presence is checked.
+func MissingDefaultTags(resource *tfjson.StateResource, defaults map[string]string) []string {
+	tagsAll, known := resource.AttributeValues["tags_all"].(map[string]interface{})
+	if !known {
+		return []string{"tags_all is not known at plan time"}
+	}
+	own, _ := resource.AttributeValues["tags"].(map[string]interface{})
+
+	keys := make([]string, 0, len(defaults))
+	for key := range defaults {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range keys {
+		value, present := tagsAll[key]
+		_, overridden := own[key]
+		switch {
+		case !present:
+			problems = append(problems, "missing default tag "+key)
+		case !overridden && fmt.Sprint(value) != defaults[key]:
+			problems = append(problems, fmt.Sprintf("default tag %s is %q, expected %q", key, value, defaults[key]))
+		}
+	}
+	return problems
+}
+
+// AssertInheritDefaultTags fails the test if any of the resources is not planned, or does not carry the provider's
+// default tags in tags_all
+func AssertInheritDefaultTags(
+	t *testing.T,
+	plan *terraform.PlanStruct,
+	defaults map[string]string,
+	addresses ...string,
+) {
+	for _, address := range addresses {
+		resource, planned := plan.ResourcePlannedValuesMap[address]
+		if !planned {
+			t.Errorf("%s is not planned, so its default tags cannot be checked", address)
+			continue
+		}
+		for _, problem := range MissingDefaultTags(resource, defaults) {
+			t.Errorf("%s: %s", address, problem)
+		}
+	}
+}
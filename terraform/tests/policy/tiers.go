@@ -0,0 +1,61 @@
+package policy
+
+import (
+	"strconv"
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// TierExceptions are resources (by type and name, in any module) allowed above the cheapest tier, with the reason
+var TierExceptions = map[string]string{
+	"aws_ecs_task_definition.geodata_import": "shapefile processing needs 2 vCPU and 4 GB",
+}
+
+// AssertCheapestTiers fails the test if a non-production plan puts a resource above the cheapest viable tier
+func AssertCheapestTiers(t *testing.T, plan *terraform.PlanStruct) {
+	for _, resource := range plannedResources(plan, "aws_db_instance") {
+		if class, _ := resource.AttributeValues["instance_class"].(string); class != common.TestDBInstanceClass {
+			t.Errorf("%s uses instance class %q; test plans should use %s", resource.Address, class, common.TestDBInstanceClass)
+		}
+		if storageType, _ := resource.AttributeValues["storage_type"].(string); storageType != common.TestStorageType {
+			t.Errorf("%s uses storage type %q; test plans should use %s", resource.Address, storageType, common.TestStorageType)
+		}
+	}
+
+	for _, resource := range plannedResources(plan, "aws_instance") {
+		devices, _ := resource.AttributeValues["root_block_device"].([]interface{})
+		for _, device := range devices {
+			attributes, _ := device.(map[string]interface{})
+			if volumeType, _ := attributes["volume_type"].(string); volumeType != common.TestStorageType {
+				t.Errorf("%s root volume uses type %q; test plans should use %s",
+					resource.Address, volumeType, common.TestStorageType)
+			}
+		}
+	}
+
+	for _, resource := range plannedResources(plan, "aws_cloudfront_distribution") {
+		if priceClass, _ := resource.AttributeValues["price_class"].(string); priceClass != common.TestCloudFrontPriceClass {
+			t.Errorf("%s uses %q; non-production distributions should use %s",
+				resource.Address, priceClass, common.TestCloudFrontPriceClass)
+		}
+	}
+
+	for _, resource := range plannedResources(plan, "aws_ecs_task_definition") {
+		if reason, excepted := TierExceptions[resource.Type+"."+resource.Name]; excepted {
+			t.Logf("%s is exempt from the Fargate tier check: %s", resource.Address, reason)
+			continue
+		}
+
+		cpu, _ := resource.AttributeValues["cpu"].(string)
+		memory, _ := resource.AttributeValues["memory"].(string)
+		if value, err := strconv.Atoi(cpu); err != nil || value > common.TestFargateCPU {
+			t.Errorf("%s requests %q CPU units; test fixtures should use %d", resource.Address, cpu, common.TestFargateCPU)
+		}
+		if value, err := strconv.Atoi(memory); err != nil || value > common.TestFargateMemory {
+			t.Errorf("%s requests %q MiB; test fixtures should use %d", resource.Address, memory, common.TestFargateMemory)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Package report holds the outcome of checking a control against a resource and reports a set of them as a
+// compliance report, one subtest per finding, so a failure names the control and resource that failed.
+package report
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Finding is the outcome of a single compliance control evaluated against a single resource
+type Finding struct {
+	Control  string
+	Resource string
+	ARN      string // set when the resource has an ARN, i.e. when auditing applied state
+	Passed   bool
+	Skipped  bool // the value needed by the control is not known yet (computed at apply time)
+	Detail   string
+}
+
+// Violation reports whether the finding failed its control, rather than passing or being skipped
+func (f Finding) Violation() bool {
+	return !f.Passed && !f.Skipped
+}
+
+// Report reports each finding as its own subtest, named control/resource, and logs a summary of the violations
+func Report(t *testing.T, findings []Finding) {
+	if len(findings) == 0 {
+		t.Log("Audit produced no findings")
+		return
+	}
+
+	failed := 0
+	for _, finding := range findings {
+		if finding.Violation() {
+			failed++
+		}
+		t.Run(fmt.Sprintf("%s/%s", finding.Control, finding.Resource), func(t *testing.T) {
+			switch {
+			case finding.Skipped:
+				t.Skip(finding.Detail)
+			case !finding.Passed:
+				t.Error(finding.Detail)
+			}
+		})
+	}
+
+	t.Logf("Audit complete: %d findings, %d violations", len(findings), failed)
+	for _, finding := range findings {
+		if finding.Violation() {
+			t.Logf("  VIOLATION %s %s: %s", finding.Control, finding.Resource, finding.Detail)
+		}
+	}
+}
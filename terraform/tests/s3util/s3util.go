@@ -0,0 +1,102 @@
+// Package s3util empties S3 buckets that tests create, including the object versions and delete markers that
+// versioned buckets keep after their objects are deleted and that otherwise block DeleteBucket.
+package s3util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"terraform-tests/awscalls"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	terratest_testing "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// maxDeleteBatch is the most keys DeleteObjects accepts, and the most a ListObjectVersions page returns
+const maxDeleteBatch = 1000
+
+// EmptyBucket deletes every object version and delete marker in a bucket, failing the test on error. A bucket that
+// does not exist is already empty.
+func EmptyBucket(t *testing.T, bucket string) {
+	_, err := EmptyBucketE(t, bucket)
+	require.NoError(t, err, "Failed to empty bucket %s", bucket)
+}
+
+// EmptyBucketE deletes every object version and delete marker in a bucket, in whichever region it lives, and
+// returns how many were deleted. A bucket that does not exist is already empty.
+func EmptyBucketE(t terratest_testing.TestingT, bucket string) (int, error) {
+	ctx := context.Background()
+	cfg, err := awscalls.LoadConfig(ctx, t, "us-east-1")
+	if err != nil {
+		return 0, err
+	}
+
+	region, err := manager.GetBucketRegion(ctx, s3.NewFromConfig(cfg), bucket)
+	var notFound manager.BucketNotFound
+	if errors.As(err, &notFound) {
+		logger.Default.Logf(t, "Bucket %s does not exist, nothing to empty", bucket)
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) { o.Region = region })
+	input := &s3.ListObjectVersionsInput{Bucket: aws.String(bucket), MaxKeys: aws.Int32(maxDeleteBatch)}
+	deleted := 0
+	for {
+		page, listErr := client.ListObjectVersions(ctx, input)
+		if listErr != nil {
+			return deleted, listErr
+		}
+
+		objects := make([]types.ObjectIdentifier, 0, len(page.Versions)+len(page.DeleteMarkers))
+		for _, version := range page.Versions {
+			objects = append(objects, types.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+		}
+		for _, marker := range page.DeleteMarkers {
+			objects = append(objects, types.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+		}
+
+		// Versions and delete markers share the page limit, so a page never needs more than one batch
+		if len(objects) > 0 {
+			if err = deleteBatch(ctx, client, bucket, objects); err != nil {
+				return deleted, err
+			}
+			deleted += len(objects)
+		}
+
+		if !aws.ToBool(page.IsTruncated) {
+			break
+		}
+		input.KeyMarker = page.NextKeyMarker
+		input.VersionIdMarker = page.NextVersionIdMarker
+	}
+
+	logger.Default.Logf(t, "Deleted %d object versions and delete markers from %s", deleted, bucket)
+	return deleted, nil
+}
+
+// deleteBatch deletes up to maxDeleteBatch object versions, failing if any of them could not be deleted
+func deleteBatch(ctx context.Context, client *s3.Client, bucket string, objects []types.ObjectIdentifier) error {
+	output, err := client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(bucket),
+		Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+	})
+	if err != nil {
+		return err
+	}
+	if len(output.Errors) > 0 {
+		first := output.Errors[0]
+		return fmt.Errorf("failed to delete %d of %d objects from %s, first %s: %s", len(output.Errors),
+			len(objects), bucket, aws.ToString(first.Key), aws.ToString(first.Message))
+	}
+	return nil
+}
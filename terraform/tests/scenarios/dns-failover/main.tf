@@ -0,0 +1,78 @@
+# Disaster recovery scenario: a primary and a minimal standby in a second region behind Route53 failover records.
+#
+# The primary's health check is calculated from no child checks with a threshold of 0, so Route53 always considers
+# it healthy, and setting primary_healthy to false inverts it to simulate a primary failure. Disabling the check
+# would not do: Route53 treats a disabled health check as healthy.
+
+provider "aws" {
+  region = var.aws_region
+}
+
+provider "aws" {
+  alias  = "secondary"
+  region = var.secondary_region
+}
+
+resource "aws_s3_bucket" "primary" {
+  bucket        = "${var.prefix}-dr-primary"
+  force_destroy = true
+
+  tags = {
+    Name = "${var.prefix}-dr-primary"
+  }
+}
+
+resource "aws_s3_bucket" "standby" {
+  provider = aws.secondary
+
+  bucket        = "${var.prefix}-dr-standby"
+  force_destroy = true
+
+  tags = {
+    Name = "${var.prefix}-dr-standby"
+  }
+}
+
+resource "aws_route53_zone" "dr" {
+  name          = var.zone_name
+  comment       = "DNS failover scenario for ${var.prefix}"
+  force_destroy = true
+}
+
+resource "aws_route53_health_check" "primary" {
+  type                   = "CALCULATED"
+  child_healthchecks     = []
+  child_health_threshold = 0
+  invert_healthcheck     = !var.primary_healthy
+
+  tags = {
+    Name = "${var.prefix}-dr-primary"
+  }
+}
+
+resource "aws_route53_record" "primary" {
+  zone_id         = aws_route53_zone.dr.zone_id
+  name            = "app.${var.zone_name}"
+  type            = "CNAME"
+  ttl             = var.record_ttl
+  records         = [aws_s3_bucket.primary.bucket_regional_domain_name]
+  set_identifier  = "primary"
+  health_check_id = aws_route53_health_check.primary.id
+
+  failover_routing_policy {
+    type = "PRIMARY"
+  }
+}
+
+resource "aws_route53_record" "standby" {
+  zone_id        = aws_route53_zone.dr.zone_id
+  name           = "app.${var.zone_name}"
+  type           = "CNAME"
+  ttl            = var.record_ttl
+  records        = [aws_s3_bucket.standby.bucket_regional_domain_name]
+  set_identifier = "standby"
+
+  failover_routing_policy {
+    type = "SECONDARY"
+  }
+}
@@ -0,0 +1,29 @@
+output "zone_id" {
+  description = "ID of the hosted zone holding the failover records"
+  value       = aws_route53_zone.dr.zone_id
+}
+
+output "record_name" {
+  description = "Name of the failover records"
+  value       = aws_route53_record.primary.name
+}
+
+output "record_ttl" {
+  description = "TTL of the failover records, in seconds"
+  value       = var.record_ttl
+}
+
+output "primary_target" {
+  description = "What the records answer while the primary is healthy"
+  value       = aws_s3_bucket.primary.bucket_regional_domain_name
+}
+
+output "standby_target" {
+  description = "What the records answer once the primary fails"
+  value       = aws_s3_bucket.standby.bucket_regional_domain_name
+}
+
+output "primary_health_check_id" {
+  description = "ID of the primary's health check"
+  value       = aws_route53_health_check.primary.id
+}
@@ -0,0 +1,36 @@
+variable "prefix" {
+  description = "Prefix to use for resource names"
+  type        = string
+}
+
+variable "aws_region" {
+  description = "The primary region"
+  type        = string
+}
+
+variable "secondary_region" {
+  description = "The region the standby is deployed to"
+  type        = string
+
+  validation {
+    condition     = var.secondary_region != var.aws_region
+    error_message = "secondary_region must differ from aws_region."
+  }
+}
+
+variable "zone_name" {
+  description = "Name of the hosted zone the failover records are created in. It need not be delegated."
+  type        = string
+}
+
+variable "record_ttl" {
+  description = "TTL of the failover records, in seconds"
+  type        = number
+  default     = 60
+}
+
+variable "primary_healthy" {
+  description = "Whether the primary's health check reports healthy. Set to false to simulate a primary failure."
+  type        = bool
+  default     = true
+}
@@ -0,0 +1,101 @@
+# A pull request's preview environment: the smallest stack the API can be deployed into with Zappa. It has a VPC
+# with private subnets, the Zappa bucket, role and Lambda security group, and the application secrets. It has its
+# own small database unless shared_database points it at an existing instance, where it gets a database of its
+# own. There is no CloudFront, WAF, bastion, SES or custom domain: the API is reached on its execute-api URL and the
+# frontend on its Vercel preview URL.
+
+provider "aws" {
+  region = var.aws_region
+}
+
+locals {
+  shared = var.shared_database != null
+
+  vpc_id             = local.shared ? var.shared_database.vpc_id : module.networking[0].vpc_id
+  private_subnet_ids = local.shared ? var.shared_database.private_subnet_ids : module.networking[0].private_subnet_ids
+  db_subnet_cidrs    = local.shared ? var.shared_database.db_subnet_cidrs : module.networking[0].db_subnet_cidrs
+  db_endpoint        = local.shared ? var.shared_database.endpoint : module.database[0].db_instance_endpoint
+}
+
+# Private app and database subnets, with the endpoints the Lambda function needs in a single zone
+module "networking" {
+  source = "../../../modules/networking"
+  count  = local.shared ? 0 : 1
+
+  prefix             = var.prefix
+  aws_region         = var.aws_region
+  availability_zones = var.availability_zones
+
+  vpc_cidr                   = var.vpc_cidr
+  create_public_subnets      = false
+  create_private_subnets     = true
+  private_subnet_a_cidr      = cidrsubnet(var.vpc_cidr, 8, 3)
+  private_subnet_b_cidr      = cidrsubnet(var.vpc_cidr, 8, 4)
+  create_db_subnets          = true
+  private_db_subnet_a_cidr   = cidrsubnet(var.vpc_cidr, 8, 5)
+  private_db_subnet_b_cidr   = cidrsubnet(var.vpc_cidr, 8, 6)
+  create_vpc_endpoints       = true
+  enable_single_az_endpoints = true
+}
+
+module "zappa" {
+  source = "../../../modules/zappa"
+
+  prefix                = var.prefix
+  aws_region            = var.aws_region
+  vpc_id                = local.vpc_id
+  create_lambda_sg      = true
+  database_subnet_cidrs = local.db_subnet_cidrs
+
+  secret_arns = [
+    module.secrets.db_url_secret_arn,
+    module.secrets.secret_key_secret_arn,
+  ]
+  secrets_kms_key_arn = module.secrets.secrets_kms_key_arn
+}
+
+# Database security group admitting the Lambda function, for the preview's own database
+module "security" {
+  source = "../../../modules/security"
+  count  = local.shared ? 0 : 1
+
+  prefix                   = var.prefix
+  vpc_id                   = local.vpc_id
+  lambda_security_group_id = module.zappa.lambda_security_group_id
+  enable_lambda_sg_rules   = true
+  create_bastion_sg        = false
+  create_waf               = false
+}
+
+# The smallest database, without backups, deletion protection, a final snapshot or prevent_destroy, since it lives
+# as long as the pull request
+module "database" {
+  source = "../../../modules/database"
+  count  = local.shared ? 0 : 1
+
+  prefix                     = var.prefix
+  aws_region                 = var.aws_region
+  db_subnet_ids              = module.networking[0].private_db_subnet_ids
+  db_security_group_id       = module.security[0].db_security_group_id
+  db_allocated_storage       = var.db_allocated_storage
+  db_instance_class          = var.db_instance_class
+  db_name                    = var.db_name
+  db_username                = var.db_username
+  db_password                = var.db_password
+  app_db_username            = var.app_db_username
+  use_secrets_manager        = false
+  db_backup_retention_period = 0
+  deletion_protection        = false
+  prevent_destroy            = false
+  auto_setup_database        = false
+}
+
+module "secrets" {
+  source = "../../../modules/secrets"
+
+  prefix          = var.prefix
+  app_db_username = var.app_db_username
+  app_db_password = var.app_db_password
+  db_endpoint     = local.db_endpoint
+  db_name         = var.db_name
+}
@@ -0,0 +1,39 @@
+output "zappa_bucket_name" {
+  description = "Name of the S3 bucket Zappa uploads the preview's packages to"
+  value       = module.zappa.s3_bucket_name
+}
+
+output "zappa_role_arn" {
+  description = "ARN of the IAM role the preview's Lambda function runs as"
+  value       = module.zappa.zappa_deployment_role_arn
+}
+
+output "lambda_security_group_id" {
+  description = "ID of the security group for the preview's Lambda function"
+  value       = module.zappa.lambda_security_group_id
+}
+
+output "private_subnet_ids" {
+  description = "IDs of the private subnets the preview's Lambda function runs in"
+  value       = local.private_subnet_ids
+}
+
+output "db_url_secret_arn" {
+  description = "ARN of the secret holding the preview's database URL"
+  value       = module.secrets.db_url_secret_arn
+}
+
+output "secret_key_secret_arn" {
+  description = "ARN of the secret holding the preview's Django secret key"
+  value       = module.secrets.secret_key_secret_arn
+}
+
+output "db_name" {
+  description = "Name of the preview's database"
+  value       = var.db_name
+}
+
+output "db_instance_id" {
+  description = "ID of the preview's own database instance, empty on a shared database"
+  value       = local.shared ? "" : module.database[0].db_instance_id
+}
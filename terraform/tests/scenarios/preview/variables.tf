@@ -0,0 +1,71 @@
+variable "prefix" {
+  description = "Prefix to use for resource names"
+  type        = string
+}
+
+variable "aws_region" {
+  description = "The AWS region to deploy to"
+  type        = string
+}
+
+variable "availability_zones" {
+  description = "The two availability zones for the subnets"
+  type        = list(string)
+  default     = []
+}
+
+variable "vpc_cidr" {
+  description = "CIDR block of the preview's VPC, when it has its own database"
+  type        = string
+  default     = "10.230.0.0/16"
+}
+
+variable "shared_database" {
+  description = "An existing database instance to give the preview a database on, with the VPC it is reached from. Null creates a database for the preview."
+  type = object({
+    endpoint           = string
+    vpc_id             = string
+    private_subnet_ids = list(string)
+    db_subnet_cidrs    = list(string)
+  })
+  default = null
+}
+
+variable "db_name" {
+  description = "Name of the preview's database, on its own instance or the shared one"
+  type        = string
+}
+
+variable "db_instance_class" {
+  description = "Instance class of the preview's own database"
+  type        = string
+  default     = "db.t4g.micro"
+}
+
+variable "db_allocated_storage" {
+  description = "Storage of the preview's own database, in GB"
+  type        = number
+  default     = 20
+}
+
+variable "db_username" {
+  description = "Master username of the preview's own database"
+  type        = string
+}
+
+variable "db_password" {
+  description = "Master password of the preview's own database"
+  type        = string
+  sensitive   = true
+}
+
+variable "app_db_username" {
+  description = "Username the application connects as"
+  type        = string
+}
+
+variable "app_db_password" {
+  description = "Password the application connects with"
+  type        = string
+  sensitive   = true
+}
@@ -0,0 +1,91 @@
+# A coalition VPC peered with a shared-services VPC that holds the database subnets, as the dev account's VPC is
+# peered with the shared account's, but in one account and region. Built from the real networking, security and
+# vpc-peering modules, so tests check the routes, DNS settings and database exposure those modules produce.
+
+provider "aws" {
+  region = var.aws_region
+}
+
+# The accepter side, which is the shared account in a deployment
+provider "aws" {
+  alias  = "shared"
+  region = var.aws_region
+}
+
+data "aws_caller_identity" "current" {}
+
+# Coalition VPC with private app subnets only, like dev's
+module "coalition_networking" {
+  source = "../../../modules/networking"
+
+  prefix             = "${var.prefix}-app"
+  aws_region         = var.aws_region
+  availability_zones = var.availability_zones
+
+  vpc_cidr               = var.coalition_vpc_cidr
+  create_public_subnets  = false
+  create_private_subnets = true
+  private_subnet_a_cidr  = cidrsubnet(var.coalition_vpc_cidr, 8, 3)
+  private_subnet_b_cidr  = cidrsubnet(var.coalition_vpc_cidr, 8, 4)
+  create_db_subnets      = false
+  create_vpc_endpoints   = false
+}
+
+# Shared-services VPC with the database subnets only
+module "shared_networking" {
+  source = "../../../modules/networking"
+
+  providers = {
+    aws = aws.shared
+  }
+
+  prefix             = "${var.prefix}-shared"
+  aws_region         = var.aws_region
+  availability_zones = var.availability_zones
+
+  vpc_cidr                 = var.shared_vpc_cidr
+  create_public_subnets    = false
+  create_private_subnets   = false
+  create_db_subnets        = true
+  private_db_subnet_a_cidr = cidrsubnet(var.shared_vpc_cidr, 8, 5)
+  private_db_subnet_b_cidr = cidrsubnet(var.shared_vpc_cidr, 8, 6)
+  create_vpc_endpoints     = false
+}
+
+# Database security group admitting the coalition app subnets across the peering, as shared's admits dev's Lambda
+module "shared_security" {
+  source = "../../../modules/security"
+
+  providers = {
+    aws = aws.shared
+  }
+
+  prefix               = "${var.prefix}-shared"
+  vpc_id               = module.shared_networking.vpc_id
+  allowed_lambda_cidrs = module.coalition_networking.app_subnet_cidrs
+  create_waf           = false
+  create_bastion_sg    = false
+}
+
+module "vpc_peering" {
+  source = "../../../modules/vpc-peering"
+
+  providers = {
+    aws          = aws
+    aws.accepter = aws.shared
+  }
+
+  name = "${var.prefix}-app-to-shared"
+
+  requester_vpc_id          = module.coalition_networking.vpc_id
+  requester_vpc_cidr        = var.coalition_vpc_cidr
+  requester_route_table_ids = compact([module.coalition_networking.private_app_route_table_id])
+  requester_route_count     = 1
+
+  accepter_vpc_id          = module.shared_networking.vpc_id
+  accepter_vpc_cidr        = var.shared_vpc_cidr
+  accepter_account_id      = data.aws_caller_identity.current.account_id
+  accepter_region          = var.aws_region
+  accepter_route_table_ids = compact([module.shared_networking.private_db_route_table_id])
+  accepter_route_count     = 1
+}
@@ -0,0 +1,39 @@
+output "vpc_id" {
+  description = "ID of the coalition VPC"
+  value       = module.coalition_networking.vpc_id
+}
+
+output "shared_vpc_id" {
+  description = "ID of the shared-services VPC"
+  value       = module.shared_networking.vpc_id
+}
+
+output "coalition_vpc_cidr" {
+  description = "CIDR block of the coalition VPC"
+  value       = var.coalition_vpc_cidr
+}
+
+output "shared_vpc_cidr" {
+  description = "CIDR block of the shared-services VPC"
+  value       = var.shared_vpc_cidr
+}
+
+output "app_route_table_id" {
+  description = "ID of the coalition VPC's private app route table"
+  value       = module.coalition_networking.private_app_route_table_id
+}
+
+output "db_route_table_id" {
+  description = "ID of the shared-services VPC's database route table"
+  value       = module.shared_networking.private_db_route_table_id
+}
+
+output "db_security_group_id" {
+  description = "ID of the shared-services database security group"
+  value       = module.shared_security.db_security_group_id
+}
+
+output "peering_connection_id" {
+  description = "ID of the VPC peering connection"
+  value       = module.vpc_peering.peering_connection_id
+}
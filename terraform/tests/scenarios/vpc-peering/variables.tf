@@ -0,0 +1,27 @@
+variable "prefix" {
+  description = "Prefix to use for resource names"
+  type        = string
+}
+
+variable "aws_region" {
+  description = "The AWS region to deploy to"
+  type        = string
+}
+
+variable "availability_zones" {
+  description = "The two availability zones for the subnets of both VPCs"
+  type        = list(string)
+  default     = []
+}
+
+variable "coalition_vpc_cidr" {
+  description = "CIDR block of the coalition VPC, the peering requester"
+  type        = string
+  default     = "10.210.0.0/16"
+}
+
+variable "shared_vpc_cidr" {
+  description = "CIDR block of the shared-services VPC, the peering accepter"
+  type        = string
+  default     = "10.220.0.0/16"
+}
@@ -0,0 +1,433 @@
+// Package sfn validates Step Functions state machines, such as a geodata pipeline that downloads, imports and
+// validates a dataset in turn: that the definition is well formed, every task retries and catches its failures, and
+// the role lets each task state call what it invokes. StartAndWait runs an execution to completion.
+//
+// Step Functions is called with awscalls.RunCLI until go.mod requires its SDK module, so its calls are recorded
+// with the test's other AWS calls.
+package sfn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-tests/awscalls"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errorsAll is the error name that matches every error in a Retry or Catch
+const errorsAll = "States.ALL"
+
+// executionPollInterval is how often StartAndWait checks on an execution
+const executionPollInterval = 10 * time.Second
+
+// Definition is a state machine definition in the Amazon States Language. Parallel branches and Map iterators
+// are definitions of their own.
+type Definition struct {
+	StartAt string           `json:"StartAt"`
+	States  map[string]State `json:"States"`
+}
+
+// State is one state of a definition, with the fields the validators read
+type State struct {
+	Type       string                 `json:"Type"`
+	Resource   string                 `json:"Resource"`
+	Parameters map[string]interface{} `json:"Parameters"`
+	Next       string                 `json:"Next"`
+	End        bool                   `json:"End"`
+	Default    string                 `json:"Default"`
+	Choices    []struct {
+		Next string `json:"Next"`
+	} `json:"Choices"`
+	Retry []struct {
+		ErrorEquals []string `json:"ErrorEquals"`
+		MaxAttempts *int     `json:"MaxAttempts"`
+	} `json:"Retry"`
+	Catch []struct {
+		ErrorEquals []string `json:"ErrorEquals"`
+		Next        string   `json:"Next"`
+	} `json:"Catch"`
+	Branches      []Definition `json:"Branches"`
+	Iterator      *Definition  `json:"Iterator"`
+	ItemProcessor *Definition  `json:"ItemProcessor"`
+}
+
+// Permission is an IAM action a task state needs on a resource
+type Permission struct {
+	State    string
+	Action   string
+	Resource string
+}
+
+// ParseDefinition decodes a state machine definition
+func ParseDefinition(definition string) (*Definition, error) {
+	var parsed Definition
+	if err := json.Unmarshal([]byte(definition), &parsed); err != nil {
+		return nil, fmt.Errorf("definition is not valid JSON: %w", err)
+	}
+	return &parsed, nil
+}
+
+// Problems lists what is wrong with a definition: a missing start state, transitions to states that do not
+// exist, states nothing transitions to, and task states that do not retry or do not catch every error
+func (d *Definition) Problems() []string {
+	var problems []string
+	if _, exists := d.States[d.StartAt]; !exists {
+		problems = append(problems, fmt.Sprintf("StartAt names unknown state %q", d.StartAt))
+	}
+
+	reachable := map[string]bool{d.StartAt: true}
+	for _, name := range d.stateNames() {
+		state := d.States[name]
+		for _, next := range state.transitions() {
+			reachable[next] = true
+			if _, exists := d.States[next]; !exists {
+				problems = append(problems, fmt.Sprintf("%s transitions to unknown state %q", name, next))
+			}
+		}
+
+		if state.Type == "Task" {
+			problems = append(problems, state.taskProblems(name)...)
+		}
+		for _, nested := range state.nested() {
+			for _, problem := range nested.Problems() {
+				problems = append(problems, fmt.Sprintf("%s: %s", name, problem))
+			}
+		}
+	}
+
+	for _, name := range d.stateNames() {
+		if !reachable[name] {
+			problems = append(problems, fmt.Sprintf("%s is unreachable", name))
+		}
+	}
+	return problems
+}
+
+// stateNames returns the names of the states in a stable order
+func (d *Definition) stateNames() []string {
+	names := make([]string, 0, len(d.States))
+	for name := range d.States {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// transitions returns the states a state may move to
+func (s State) transitions() []string {
+	var next []string
+	for _, candidate := range []string{s.Next, s.Default} {
+		if candidate != "" {
+			next = append(next, candidate)
+		}
+	}
+	for _, choice := range s.Choices {
+		next = append(next, choice.Next)
+	}
+	for _, catcher := range s.Catch {
+		next = append(next, catcher.Next)
+	}
+	return next
+}
+
+// nested returns the definitions of a Parallel state's branches or a Map state's iterator
+func (s State) nested() []Definition {
+	nested := append([]Definition{}, s.Branches...)
+	for _, iterator := range []*Definition{s.Iterator, s.ItemProcessor} {
+		if iterator != nil {
+			nested = append(nested, *iterator)
+		}
+	}
+	return nested
+}
+
+// taskProblems lists what is missing from a task state's error handling. Tasks call other services, so each
+// must retry at least once and catch every error to a state that can report the failure.
+func (s State) taskProblems(name string) []string {
+	var problems []string
+
+	retries := false
+	for _, retrier := range s.Retry {
+		if retrier.MaxAttempts == nil || *retrier.MaxAttempts > 0 {
+			retries = true
+		}
+	}
+	if !retries {
+		problems = append(problems, fmt.Sprintf("%s does not retry", name))
+	}
+
+	catchesAll := false
+	for _, catcher := range s.Catch {
+		for _, errorName := range catcher.ErrorEquals {
+			catchesAll = catchesAll || errorName == errorsAll
+		}
+	}
+	if !catchesAll {
+		problems = append(problems, fmt.Sprintf("%s does not catch %s", name, errorsAll))
+	}
+	return problems
+}
+
+// RequiredPermissions returns the IAM permissions the state machine's role needs for its task states to call
+// what they invoke: Lambda functions, ECS tasks, and other state machines
+func (d *Definition) RequiredPermissions() []Permission {
+	var permissions []Permission
+	for _, name := range d.stateNames() {
+		state := d.States[name]
+		if state.Type == "Task" {
+			permissions = append(permissions, state.permissions(name)...)
+		}
+		for _, nested := range state.nested() {
+			permissions = append(permissions, nested.RequiredPermissions()...)
+		}
+	}
+	return permissions
+}
+
+// permissions returns what a task state needs, read from its resource and parameters. Integrations this does not
+// know return nothing, and are left to the execution test.
+func (s State) permissions(name string) []Permission {
+	parameter := func(key string) string {
+		value, _ := s.Parameters[key].(string)
+		return value
+	}
+	integration := strings.TrimPrefix(s.Resource, "arn:aws:states:::")
+
+	switch {
+	case strings.HasPrefix(s.Resource, "arn:aws:lambda:"):
+		return []Permission{{State: name, Action: "lambda:InvokeFunction", Resource: s.Resource}}
+	case strings.HasPrefix(integration, "lambda:invoke"):
+		return []Permission{{State: name, Action: "lambda:InvokeFunction", Resource: parameter("FunctionName")}}
+	case strings.HasPrefix(integration, "ecs:runTask"):
+		return []Permission{{State: name, Action: "ecs:RunTask", Resource: parameter("TaskDefinition")}}
+	case strings.HasPrefix(integration, "states:startExecution"):
+		return []Permission{{State: name, Action: "states:StartExecution", Resource: parameter("StateMachineArn")}}
+	}
+	return nil
+}
+
+// PermissionProblems lists the required permissions that none of the role's policy documents allow, and those a
+// policy allows on every resource instead of the one the state invokes
+func PermissionProblems(required []Permission, policies []string) []string {
+	var problems []string
+	for _, permission := range required {
+		allowed, wildcard := false, false
+		for _, policy := range policies {
+			for _, statement := range allowStatements(policy) {
+				if !matchesAny(statement.actions, permission.Action) || !matchesAny(statement.resources, permission.Resource) {
+					continue
+				}
+				allowed = true
+				wildcard = wildcard || containsString(statement.resources, "*")
+			}
+		}
+
+		switch {
+		case !allowed:
+			problems = append(problems, fmt.Sprintf("%s needs %s on %s", permission.State, permission.Action,
+				permission.Resource))
+		case wildcard:
+			problems = append(problems, fmt.Sprintf("%s is allowed %s on every resource, not just %s",
+				permission.State, permission.Action, permission.Resource))
+		}
+	}
+	return problems
+}
+
+// statement is an Allow statement's actions and resources
+type statement struct {
+	actions   []string
+	resources []string
+}
+
+// allowStatements returns the Allow statements of a policy document
+func allowStatements(policy string) []statement {
+	var document struct {
+		Statement []struct {
+			Effect   string          `json:"Effect"`
+			Action   json.RawMessage `json:"Action"`
+			Resource json.RawMessage `json:"Resource"`
+		} `json:"Statement"`
+	}
+	if json.Unmarshal([]byte(policy), &document) != nil {
+		return nil
+	}
+
+	var statements []statement
+	for _, raw := range document.Statement {
+		if raw.Effect == "Allow" {
+			statements = append(statements, statement{stringOrList(raw.Action), stringOrList(raw.Resource)})
+		}
+	}
+	return statements
+}
+
+// stringOrList decodes a policy element that may be a single string or a list of them
+func stringOrList(raw json.RawMessage) []string {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}
+	}
+	var list []string
+	_ = json.Unmarshal(raw, &list)
+	return list
+}
+
+// matchesAny reports whether any IAM pattern, in which * and ? are wildcards, matches a value. Actions match
+// regardless of case, as IAM compares them.
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		expression := "^" + strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(pattern)) + "$"
+		if regexp.MustCompile("(?i)" + expression).MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether a list holds a value
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// StateMachine is the part of a deployed state machine the validators read
+type StateMachine struct {
+	Definition string `json:"definition"`
+	RoleArn    string `json:"roleArn"`
+}
+
+// Execution is the outcome of a state machine execution
+type Execution struct {
+	ExecutionArn string `json:"executionArn"`
+	Status       string `json:"status"`
+	Output       string `json:"output"`
+	Error        string `json:"error"`
+	Cause        string `json:"cause"`
+}
+
+// regionOf returns the region of an ARN
+func regionOf(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 4 {
+		return ""
+	}
+	return parts[3]
+}
+
+// runE runs an aws stepfunctions command in the region of an ARN and decodes its JSON output
+func runE(t *testing.T, arn string, out interface{}, args ...string) error {
+	return awscalls.RunCLI(context.Background(), t, regionOf(arn), out, "stepfunctions", args...)
+}
+
+// DescribeStateMachine returns a deployed state machine's definition and role
+func DescribeStateMachine(t *testing.T, arn string) *StateMachine {
+	var machine StateMachine
+	require.NoError(t, runE(t, arn, &machine, "describe-state-machine", "--state-machine-arn", arn))
+	return &machine
+}
+
+// GetRolePolicyDocuments returns the documents of every inline and attached managed policy of a role
+func GetRolePolicyDocuments(t *testing.T, roleArn string) []string {
+	cfg, err := awscalls.LoadConfig(context.Background(), t, "us-east-1")
+	require.NoError(t, err)
+	svc := iam.NewFromConfig(cfg)
+	roleName := aws.String(roleArn[strings.LastIndex(roleArn, "/")+1:])
+
+	// IAM returns policy documents URL-encoded
+	decode := func(document *string) string {
+		decoded, decodeErr := url.QueryUnescape(aws.ToString(document))
+		require.NoError(t, decodeErr)
+		return decoded
+	}
+
+	var documents []string
+	inline, err := svc.ListRolePolicies(context.Background(), &iam.ListRolePoliciesInput{RoleName: roleName})
+	require.NoError(t, err)
+	for _, name := range inline.PolicyNames {
+		policy, policyErr := svc.GetRolePolicy(context.Background(), &iam.GetRolePolicyInput{
+			RoleName:   roleName,
+			PolicyName: aws.String(name),
+		})
+		require.NoError(t, policyErr)
+		documents = append(documents, decode(policy.PolicyDocument))
+	}
+
+	attached, err := svc.ListAttachedRolePolicies(context.Background(), &iam.ListAttachedRolePoliciesInput{
+		RoleName: roleName,
+	})
+	require.NoError(t, err)
+	for _, attachment := range attached.AttachedPolicies {
+		policy, policyErr := svc.GetPolicy(context.Background(), &iam.GetPolicyInput{PolicyArn: attachment.PolicyArn})
+		require.NoError(t, policyErr)
+		version, versionErr := svc.GetPolicyVersion(context.Background(), &iam.GetPolicyVersionInput{
+			PolicyArn: attachment.PolicyArn,
+			VersionId: policy.Policy.DefaultVersionId,
+		})
+		require.NoError(t, versionErr)
+		documents = append(documents, decode(version.PolicyVersion.Document))
+	}
+	return documents
+}
+
+// ValidateStateMachine asserts a deployed state machine's definition has no Problems and its role allows every
+// task state exactly what it invokes
+func ValidateStateMachine(t *testing.T, arn string) {
+	machine := DescribeStateMachine(t, arn)
+	definition, err := ParseDefinition(machine.Definition)
+	require.NoError(t, err)
+
+	assert.Empty(t, definition.Problems(), "State machine %s has definition problems", arn)
+	assert.Empty(t, PermissionProblems(definition.RequiredPermissions(), GetRolePolicyDocuments(t, machine.RoleArn)),
+		"State machine %s role does not fit its task states", arn)
+}
+
+// StartAndWait starts an execution with the given JSON input, waits up to timeout for it to finish, and fails the
+// test unless it SUCCEEDED. It returns the finished execution, whose Output holds the result.
+func StartAndWait(t *testing.T, arn, input string, timeout time.Duration) *Execution {
+	execution, err := StartAndWaitE(t, arn, input, timeout)
+	require.NoError(t, err)
+	require.Equal(t, "SUCCEEDED", execution.Status, "Execution %s ended with %s: %s",
+		execution.ExecutionArn, execution.Error, execution.Cause)
+	return execution
+}
+
+// StartAndWaitE starts an execution and waits up to timeout for it to reach a terminal status, which it returns
+// without judging
+func StartAndWaitE(t *testing.T, arn, input string, timeout time.Duration) (*Execution, error) {
+	var started Execution
+	if err := runE(t, arn, &started, "start-execution", "--state-machine-arn", arn, "--input", input); err != nil {
+		return nil, err
+	}
+
+	var execution Execution
+	attempts := int(timeout/executionPollInterval) + 1
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("Wait for execution %s", started.ExecutionArn), attempts,
+		executionPollInterval, func() (string, error) {
+			if describeErr := runE(t, arn, &execution, "describe-execution", "--execution-arn",
+				started.ExecutionArn); describeErr != nil {
+				return "", describeErr
+			}
+			if execution.Status == "RUNNING" {
+				return "", fmt.Errorf("execution is %s", execution.Status)
+			}
+			return execution.Status, nil
+		})
+	return &execution, err
+}
@@ -0,0 +1,85 @@
+package sfn
+
+import (
+	"testing"
+
+	"terraform-tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// geodataPipeline downloads a dataset with Lambda, imports it with an ECS task and validates it with Lambda. The
+// validate step neither retries nor catches its errors, and Cleanup is never reached.
+const geodataPipeline = `{
+  "StartAt": "Download",
+  "States": {
+    "Download": {
+      "Type": "Task",
+      "Resource": "arn:aws:states:::lambda:invoke",
+      "Parameters": {"FunctionName": "arn:aws:lambda:us-east-1:123456789012:function:coalition-download"},
+      "Retry": [{"ErrorEquals": ["States.TaskFailed"], "MaxAttempts": 2}],
+      "Catch": [{"ErrorEquals": ["States.ALL"], "Next": "Failed"}],
+      "Next": "Import"
+    },
+    "Import": {
+      "Type": "Task",
+      "Resource": "arn:aws:states:::ecs:runTask.sync",
+      "Parameters": {"TaskDefinition": "arn:aws:ecs:us-east-1:123456789012:task-definition/coalition-geodata-import"},
+      "Retry": [{"ErrorEquals": ["States.ALL"]}],
+      "Catch": [{"ErrorEquals": ["States.ALL"], "Next": "Failed"}],
+      "Next": "Validate"
+    },
+    "Validate": {
+      "Type": "Task",
+      "Resource": "arn:aws:lambda:us-east-1:123456789012:function:coalition-validate",
+      "Retry": [{"ErrorEquals": ["States.ALL"], "MaxAttempts": 0}],
+      "Next": "Done"
+    },
+    "Cleanup": {"Type": "Pass", "Next": "Done"},
+    "Done": {"Type": "Succeed"},
+    "Failed": {"Type": "Fail"}
+  }
+}`
+
+func TestDefinitionProblems(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	definition, err := ParseDefinition(geodataPipeline)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"Validate does not retry",
+		"Validate does not catch States.ALL",
+		"Cleanup is unreachable",
+	}, definition.Problems())
+
+	_, err = ParseDefinition(`{"StartAt":`)
+	assert.Error(t, err)
+}
+
+func TestPermissionProblems(t *testing.T) {
+	common.RequireTier(t, common.TierUnit)
+
+	definition, err := ParseDefinition(geodataPipeline)
+	require.NoError(t, err)
+	required := definition.RequiredPermissions()
+	require.Len(t, required, 3)
+
+	policy := `{"Statement":[
+	  {"Effect":"Allow","Action":"lambda:InvokeFunction",
+	   "Resource":"arn:aws:lambda:us-east-1:123456789012:function:coalition-*"},
+	  {"Effect":"Allow","Action":["ecs:RunTask","ecs:StopTask"],"Resource":"*"}
+	]}`
+
+	assert.Equal(t, []string{
+		"Import is allowed ecs:RunTask on every resource, not just " +
+			"arn:aws:ecs:us-east-1:123456789012:task-definition/coalition-geodata-import",
+	}, PermissionProblems(required, []string{policy}))
+
+	assert.Equal(t, []string{
+		"Download needs lambda:InvokeFunction on arn:aws:lambda:us-east-1:123456789012:function:coalition-download",
+		"Import needs ecs:RunTask on arn:aws:ecs:us-east-1:123456789012:task-definition/coalition-geodata-import",
+		"Validate needs lambda:InvokeFunction on arn:aws:lambda:us-east-1:123456789012:function:coalition-validate",
+	}, PermissionProblems(required, nil))
+}
@@ -0,0 +1,334 @@
+// Package smoketest checks a deployed environment the way its users reach it: each hostname resolves, serves a
+// valid certificate that is not about to expire, and routes requests to the backend that should answer them, and
+// the API reports itself and its database healthy. It needs no AWS credentials, Terraform or testing.T, so the same
+// checks run from the e2e tests and, through cmd/smoketest, after each deploy.
+package smoketest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backends that requests to the stack's domain are routed to
+const (
+	BackendAPI      = "api"      // Django on Lambda, behind the API Gateway custom domain
+	BackendFrontend = "frontend" // Next.js on Vercel
+)
+
+// Health check budgets for the API. API Gateway cuts off any integration after 29 seconds, so that is the
+// worst case for a cold start; once warm, the health check should answer well within the warm budget.
+const (
+	APIGatewayIntegrationTimeout = 29 * time.Second
+	APIWarmHealthCheckBudget     = 3 * time.Second
+)
+
+// DefaultMinCertValidity is how long a certificate must stay valid. ACM renews 60 days before expiry, so a
+// certificate within two weeks of it has failed to renew.
+const DefaultMinCertValidity = 14 * 24 * time.Hour
+
+// Kinds of check, as written to the report
+const (
+	CheckDNS    = "dns"
+	CheckTLS    = "tls"
+	CheckRoute  = "route"
+	CheckHealth = "health"
+)
+
+// Environment is the hostnames of a deployed stack
+type Environment struct {
+	APIHost       string   `json:"api_host"`
+	APIBasePath   string   `json:"api_base_path,omitempty"` // Stage path of an API reached on its execute-api URL
+	FrontendHosts []string `json:"frontend_hosts"`
+}
+
+// NewEnvironment returns the hostnames a stack serves on its domain: the API on api.<domain> and the frontend on
+// the apex and www
+func NewEnvironment(domain string) Environment {
+	return Environment{APIHost: "api." + domain, FrontendHosts: []string{domain, "www." + domain}}
+}
+
+// EnvironmentFromURLs returns the environment of an API at a URL, such as an execute-api stage URL, and a frontend
+// on the hosts of the other URLs
+func EnvironmentFromURLs(apiURL string, frontendURLs ...string) (Environment, error) {
+	parsed, err := url.Parse(apiURL)
+	if err != nil || parsed.Host == "" {
+		return Environment{}, fmt.Errorf("%q is not an API URL", apiURL)
+	}
+	environment := Environment{APIHost: parsed.Host, APIBasePath: strings.TrimSuffix(parsed.Path, "/")}
+	for _, frontendURL := range frontendURLs {
+		parsed, err = url.Parse(frontendURL)
+		if err != nil || parsed.Host == "" {
+			return Environment{}, fmt.Errorf("%q is not a frontend URL", frontendURL)
+		}
+		environment.FrontendHosts = append(environment.FrontendHosts, parsed.Host)
+	}
+	return environment, nil
+}
+
+// APIURL returns the URL the API's paths are relative to
+func (e Environment) APIURL() string {
+	return "https://" + e.APIHost + e.APIBasePath
+}
+
+// Hosts returns every hostname of the environment, the API's first
+func (e Environment) Hosts() []string {
+	return append([]string{e.APIHost}, e.FrontendHosts...)
+}
+
+// Route is a request the stack must send to a particular backend
+type Route struct {
+	Host    string
+	Path    string
+	Backend string
+}
+
+// Routes lists the requests that pin down how traffic is split: the API domain serves both the Django API and
+// admin, and the frontend domains serve the frontend
+func (e Environment) Routes() []Route {
+	routes := []Route{
+		{Host: e.APIHost, Path: e.APIBasePath + "/api/health", Backend: BackendAPI},
+		{Host: e.APIHost, Path: e.APIBasePath + "/admin/", Backend: BackendAPI},
+	}
+	for _, host := range e.FrontendHosts {
+		routes = append(routes, Route{Host: host, Path: "/", Backend: BackendFrontend})
+	}
+	return routes
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Check      string `json:"check"`
+	Target     string `json:"target"`
+	Passed     bool   `json:"passed"`
+	Detail     string `json:"detail"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of every check against an environment
+type Report struct {
+	Environment Environment `json:"environment"`
+	Start       time.Time   `json:"start"`
+	Passed      bool        `json:"passed"`
+	Results     []Result    `json:"results"`
+}
+
+// Failed returns the checks that failed
+func (r *Report) Failed() []Result {
+	var failed []Result
+	for _, result := range r.Results {
+		if !result.Passed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Options tunes the checks. The zero value checks against the system's roots with DefaultMinCertValidity.
+type Options struct {
+	MinCertValidity time.Duration
+	RootCAs         *x509.CertPool // Roots to verify certificates with; nil uses the system's
+	Resolver        *net.Resolver  // nil uses the default resolver
+}
+
+// Checker runs the checks against an environment
+type Checker struct {
+	options Options
+	client  *http.Client
+}
+
+// NewChecker returns a checker whose requests may take as long as a cold start
+func NewChecker(options Options) *Checker {
+	if options.MinCertValidity == 0 {
+		options.MinCertValidity = DefaultMinCertValidity
+	}
+	if options.Resolver == nil {
+		options.Resolver = net.DefaultResolver
+	}
+	client := NewClient(APIGatewayIntegrationTimeout + 5*time.Second)
+	client.Transport = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		DialContext:     (&net.Dialer{Timeout: 10 * time.Second, Resolver: options.Resolver}).DialContext,
+		TLSClientConfig: &tls.Config{RootCAs: options.RootCAs, MinVersion: tls.VersionTLS12},
+	}
+	return &Checker{options: options, client: client}
+}
+
+// NewClient returns an HTTP client that reports redirects instead of following them, so a response can be
+// attributed to the backend that produced it
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Run checks that every host resolves and serves a valid certificate, that each route reaches its backend, and
+// that the API is healthy. DNS and TLS come first, so their failures explain the request failures that follow.
+func (c *Checker) Run(ctx context.Context, environment Environment) *Report {
+	report := &Report{Environment: environment, Start: time.Now().UTC()}
+	for _, host := range environment.Hosts() {
+		report.Results = append(report.Results, c.CheckDNS(ctx, host))
+	}
+	for _, host := range environment.Hosts() {
+		report.Results = append(report.Results, c.CheckTLS(ctx, host))
+	}
+	for _, route := range environment.Routes() {
+		report.Results = append(report.Results, c.CheckRoute(ctx, route))
+	}
+	report.Results = append(report.Results, c.CheckHealth(ctx, environment.APIURL()))
+	report.Passed = len(report.Failed()) == 0
+	return report
+}
+
+// CheckDNS checks a host resolves to at least one address
+func (c *Checker) CheckDNS(ctx context.Context, host string) Result {
+	start := time.Now()
+	name := hostname(host)
+	addresses, err := c.options.Resolver.LookupHost(ctx, name)
+	result := Result{Check: CheckDNS, Target: name, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	sort.Strings(addresses)
+	result.Passed = len(addresses) > 0
+	result.Detail = "resolves to " + strings.Join(addresses, ", ")
+	return result
+}
+
+// CheckTLS checks a host serves a certificate that is valid for it, chains to a trusted root and stays valid for
+// at least MinCertValidity, over TLS 1.2 or later. A host without a port is reached on 443.
+func (c *Checker) CheckTLS(ctx context.Context, host string) Result {
+	start := time.Now()
+	address := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		address = net.JoinHostPort(host, "443")
+	}
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 10 * time.Second, Resolver: c.options.Resolver},
+		Config:    &tls.Config{ServerName: hostname(host), RootCAs: c.options.RootCAs, MinVersion: tls.VersionTLS12},
+	}
+	connection, err := dialer.DialContext(ctx, "tcp", address)
+	result := Result{Check: CheckTLS, Target: host, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	defer connection.Close()
+
+	state := connection.(*tls.Conn).ConnectionState()
+	certificate := state.PeerCertificates[0]
+	remaining := time.Until(certificate.NotAfter)
+	result.Passed = remaining >= c.options.MinCertValidity
+	result.Detail = fmt.Sprintf("%s certificate from %s expires %s, in %d days (at least %d required)",
+		tls.VersionName(state.Version), certificate.Issuer.CommonName, certificate.NotAfter.Format(time.DateOnly),
+		int(remaining.Hours()/24), int(c.options.MinCertValidity.Hours()/24))
+	return result
+}
+
+// IdentifyBackend names the backend that served a response from the headers each platform adds
+func IdentifyBackend(response *http.Response) string {
+	switch {
+	case response.Header.Get("X-Vercel-Id") != "":
+		return BackendFrontend
+	case response.Header.Get("X-Amzn-Requestid") != "" || response.Header.Get("X-Amz-Apigw-Id") != "":
+		return BackendAPI
+	default:
+		return ""
+	}
+}
+
+// CheckRoute sends a GET request for a route over HTTPS and checks the expected backend answered without failing
+func (c *Checker) CheckRoute(ctx context.Context, route Route) Result {
+	target := fmt.Sprintf("https://%s%s", route.Host, route.Path)
+	result := Result{Check: CheckRoute, Target: target}
+	start := time.Now()
+	response, err := c.get(ctx, target)
+	result.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	response.Body.Close()
+
+	backend := IdentifyBackend(response)
+	result.Passed = backend == route.Backend && response.StatusCode < 500
+	result.Detail = fmt.Sprintf("answered %d from %q, expected %q", response.StatusCode, backend, route.Backend)
+	return result
+}
+
+// APIHealth is the part of the Django health check response the checks rely on
+type APIHealth struct {
+	Status   string `json:"status"`
+	Database struct {
+		Status string `json:"status"`
+	} `json:"database"`
+}
+
+// GetAPIHealth requests the health check of the API at a URL and returns the parsed body, the HTTP status and how
+// long the request took
+func GetAPIHealth(ctx context.Context, client *http.Client, apiURL string) (*APIHealth, int, time.Duration, error) {
+	healthURL := apiURL + "/api/health"
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, http.NoBody)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	start := time.Now()
+	response, err := client.Do(request)
+	elapsed := time.Since(start)
+	if err != nil {
+		return nil, 0, elapsed, fmt.Errorf("request to %s failed after %s: %w", healthURL, elapsed, err)
+	}
+	defer response.Body.Close()
+
+	health := &APIHealth{}
+	if err := json.NewDecoder(response.Body).Decode(health); err != nil {
+		return nil, response.StatusCode, elapsed, fmt.Errorf("health check should return JSON: %w", err)
+	}
+	return health, response.StatusCode, elapsed, nil
+}
+
+// CheckHealth checks the API reports itself and its database healthy within the API Gateway timeout
+func (c *Checker) CheckHealth(ctx context.Context, apiURL string) Result {
+	result := Result{Check: CheckHealth, Target: apiURL + "/api/health"}
+	health, status, elapsed, err := GetAPIHealth(ctx, c.client, apiURL)
+	result.DurationMS = elapsed.Milliseconds()
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	result.Passed = status == http.StatusOK && health.Status == "healthy" && health.Database.Status == "healthy" &&
+		elapsed < APIGatewayIntegrationTimeout
+	result.Detail = fmt.Sprintf("answered %d in %s: status %q, database %q", status, elapsed.Round(time.Millisecond),
+		health.Status, health.Database.Status)
+	return result
+}
+
+// get sends a GET request without following redirects
+func (c *Checker) get(ctx context.Context, target string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	return c.client.Do(request)
+}
+
+// hostname drops the port from a host, if it has one
+func hostname(host string) string {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		return name
+	}
+	return host
+}
@@ -0,0 +1,113 @@
+package smoketest
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStack serves the API's paths with the headers API Gateway adds and everything else with Vercel's, over TLS
+// with a certificate for 127.0.0.1
+func newStack(t *testing.T, databaseStatus string) (Environment, Options) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/health":
+			w.Header().Set("X-Amzn-Requestid", "request")
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"status": "healthy", "database": {"status": "` + databaseStatus + `"}}`))
+		case strings.HasPrefix(r.URL.Path, "/admin/"):
+			w.Header().Set("X-Amzn-Requestid", "request")
+			http.Redirect(w, r, "/admin/login/", http.StatusFound)
+		default:
+			w.Header().Set("X-Vercel-Id", "iad1::abc")
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	host := strings.TrimPrefix(server.URL, "https://")
+	return Environment{APIHost: host, FrontendHosts: []string{host}}, Options{RootCAs: roots}
+}
+
+func TestNewEnvironmentServesAPIAndFrontendOnTheDomain(t *testing.T) {
+	environment := NewEnvironment("example.org")
+
+	assert.Equal(t, []string{"api.example.org", "example.org", "www.example.org"}, environment.Hosts())
+	assert.Equal(t, []Route{
+		{Host: "api.example.org", Path: "/api/health", Backend: BackendAPI},
+		{Host: "api.example.org", Path: "/admin/", Backend: BackendAPI},
+		{Host: "example.org", Path: "/", Backend: BackendFrontend},
+		{Host: "www.example.org", Path: "/", Backend: BackendFrontend},
+	}, environment.Routes())
+}
+
+func TestEnvironmentFromURLsServesTheAPIUnderItsStage(t *testing.T) {
+	environment, err := EnvironmentFromURLs("https://abc123.execute-api.us-east-1.amazonaws.com/pr-42/",
+		"https://coalition-git-pr-42.vercel.app")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://abc123.execute-api.us-east-1.amazonaws.com/pr-42", environment.APIURL())
+	assert.Equal(t, []string{"coalition-git-pr-42.vercel.app"}, environment.FrontendHosts)
+	assert.Equal(t, "/pr-42/api/health", environment.Routes()[0].Path)
+
+	_, err = EnvironmentFromURLs("not a url")
+	assert.Error(t, err)
+}
+
+func TestRunPassesAHealthyStack(t *testing.T) {
+	environment, options := newStack(t, "healthy")
+
+	report := NewChecker(options).Run(context.Background(), environment)
+	assert.Empty(t, report.Failed())
+	assert.True(t, report.Passed)
+
+	checks := map[string]int{}
+	for _, result := range report.Results {
+		checks[result.Check]++
+	}
+	assert.Equal(t, map[string]int{CheckDNS: 2, CheckTLS: 2, CheckRoute: 3, CheckHealth: 1}, checks)
+}
+
+func TestRunFailsAnUnhealthyDatabase(t *testing.T) {
+	environment, options := newStack(t, "unhealthy")
+
+	report := NewChecker(options).Run(context.Background(), environment)
+	require.Len(t, report.Failed(), 1)
+	assert.Equal(t, CheckHealth, report.Failed()[0].Check)
+	assert.Contains(t, report.Failed()[0].Detail, `database "unhealthy"`)
+	assert.False(t, report.Passed)
+}
+
+func TestCheckTLSFailsACertificateNearExpiry(t *testing.T) {
+	environment, options := newStack(t, "healthy")
+	options.MinCertValidity = 100 * 365 * 24 * time.Hour
+
+	result := NewChecker(options).CheckTLS(context.Background(), environment.APIHost)
+	assert.False(t, result.Passed)
+	assert.Contains(t, result.Detail, "expires")
+}
+
+func TestCheckTLSFailsAnUntrustedCertificate(t *testing.T) {
+	environment, _ := newStack(t, "healthy")
+
+	result := NewChecker(Options{RootCAs: x509.NewCertPool()}).CheckTLS(context.Background(), environment.APIHost)
+	assert.False(t, result.Passed)
+	assert.Contains(t, result.Detail, "certificate")
+}
+
+func TestCheckRouteFailsTheWrongBackend(t *testing.T) {
+	environment, options := newStack(t, "healthy")
+
+	result := NewChecker(options).CheckRoute(context.Background(),
+		Route{Host: environment.APIHost, Path: "/", Backend: BackendAPI})
+	assert.False(t, result.Passed)
+	assert.Equal(t, `answered 200 from "frontend", expected "api"`, result.Detail)
+}
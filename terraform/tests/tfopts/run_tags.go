@@ -0,0 +1,158 @@
+package tfopts
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TestRunIDTag is the cost allocation tag applied to resources created by a test run
+const TestRunIDTag = "TestRunId"
+
+var (
+	testRunID     string
+	testRunIDOnce sync.Once
+)
+
+// TestRunID identifies the current test suite run. It comes from TEST_RUN_ID (set it once for all packages),
+// then the GitHub Actions run, and otherwise is derived from the time it was first requested.
+func TestRunID() string {
+	testRunIDOnce.Do(func() {
+		testRunID = os.Getenv("TEST_RUN_ID")
+		if testRunID == "" && os.Getenv("GITHUB_RUN_ID") != "" {
+			testRunID = fmt.Sprintf("gh-%s-%s", os.Getenv("GITHUB_RUN_ID"), os.Getenv("GITHUB_RUN_ATTEMPT"))
+		}
+		if testRunID == "" {
+			testRunID = "local-" + time.Now().UTC().Format("20060102T150405Z")
+		}
+	})
+	return testRunID
+}
+
+// addRunTags adds the RunTags to the "tags" variable, keeping any tags the caller already set
+func addRunTags(vars map[string]interface{}, defaults map[string]string) {
+	MergeTags(vars, defaults, RunTags())
+}
+
+// MergeTags sets tags in the "tags" variable, keeping any other tags the caller already set. When the variable is
+// unset, the defaults are used as its starting point.
+func MergeTags(vars map[string]interface{}, defaults, tags map[string]string) {
+	merged := map[string]string{}
+	switch existing := vars["tags"].(type) {
+	case map[string]string:
+		for key, value := range existing {
+			merged[key] = value
+		}
+	case map[string]interface{}:
+		for key, value := range existing {
+			merged[key] = fmt.Sprint(value)
+		}
+	default:
+		for key, value := range defaults {
+			merged[key] = value
+		}
+	}
+
+	for key, value := range tags {
+		merged[key] = value
+	}
+	vars["tags"] = merged
+}
+
+// ModuleAcceptsTags reports whether a module declares a "tags" input variable
+func ModuleAcceptsTags(modulePath string) bool {
+	_, declared := DeclaredVariables(modulePath)["tags"]
+	return declared
+}
+
+// Tags that trace a resource to the commit and branch whose tests created it
+const (
+	GitCommitTag = "GitCommit"
+	GitBranchTag = "GitBranch"
+)
+
+// UnknownGitValue is the GitCommit or GitBranch of a run outside a git checkout, or on a detached HEAD
+const UnknownGitValue = "unknown"
+
+var (
+	gitCommit, gitBranch string
+	gitHeadOnce          sync.Once
+)
+
+// readGitHead reads the checkout's commit and branch once, for runs without them in the environment
+func readGitHead() {
+	gitHeadOnce.Do(func() {
+		git := func(args ...string) string {
+			output, err := exec.Command("git", args...).Output()
+			if err != nil {
+				return ""
+			}
+			return strings.TrimSpace(string(output))
+		}
+		gitCommit = git("rev-parse", "HEAD")
+		if branch := git("rev-parse", "--abbrev-ref", "HEAD"); branch != "HEAD" {
+			gitBranch = branch
+		}
+	})
+}
+
+// GitCommit is the commit under test. It comes from GIT_COMMIT, then GITHUB_SHA, and otherwise the checkout's HEAD.
+func GitCommit() string {
+	commit := firstNonEmpty(os.Getenv("GIT_COMMIT"), os.Getenv("GITHUB_SHA"))
+	if commit == "" {
+		readGitHead()
+		commit = gitCommit
+	}
+	return TagValue(commit)
+}
+
+// GitBranch is the branch under test. It comes from GIT_BRANCH, then the pull request's head branch or the pushed
+// branch on GitHub Actions, and otherwise the checkout's current branch.
+func GitBranch() string {
+	branch := firstNonEmpty(os.Getenv("GIT_BRANCH"), os.Getenv("GITHUB_HEAD_REF"), os.Getenv("GITHUB_REF_NAME"))
+	if branch == "" {
+		readGitHead()
+		branch = gitBranch
+	}
+	return TagValue(branch)
+}
+
+// firstNonEmpty returns the first of values that is set
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// tagValueDisallowed matches the characters AWS does not accept in a tag value
+var tagValueDisallowed = regexp.MustCompile(`[^\p{L}\p{Z}\p{N}_.:/=+\-@]+`)
+
+// TagValue makes a value safe to use as an AWS tag value: disallowed characters become "-", it is cut to 256
+// characters, and an empty value becomes UnknownGitValue
+func TagValue(value string) string {
+	value = tagValueDisallowed.ReplaceAllString(strings.TrimSpace(value), "-")
+	if runes := []rune(value); len(runes) > 256 {
+		value = string(runes[:256])
+	}
+	if value == "" {
+		return UnknownGitValue
+	}
+	return value
+}
+
+// RunTags returns the tags every test apply adds to what it creates: the test run, and the commit and branch
+// under test
+func RunTags() map[string]string {
+	return map[string]string{
+		TestRunIDTag: TestRunID(),
+		GitCommitTag: GitCommit(),
+		GitBranchTag: GitBranch(),
+	}
+}
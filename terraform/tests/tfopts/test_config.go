@@ -0,0 +1,192 @@
+package tfopts
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConfig holds common configuration for tests
+type TestConfig struct {
+	TerraformDir string
+	AWSRegion    string
+	Prefix       string
+	UniqueID     string
+	AccountID    string // Public field for test access
+}
+
+// NewTestConfig creates a new test configuration with a unique ID
+func NewTestConfig(terraformDir string) *TestConfig {
+	uniqueID := NewUniqueID()
+
+	return &TestConfig{
+		TerraformDir: terraformDir,
+		AWSRegion:    "us-east-1",
+		Prefix:       fmt.Sprintf("coalition-%s", uniqueID),
+		UniqueID:     uniqueID,
+	}
+}
+
+// GetTerraformOptions returns default terraform options for testing with remote backend
+func (tc *TestConfig) GetTerraformOptions(vars map[string]interface{}) *terraform.Options {
+	// Layered over the root configuration's test values (see test_values.go); provided vars override them
+	defaultVars := tc.testVars(RootConfiguration, tc.TerraformDir, vars)
+	addRunTags(defaultVars, map[string]string{"Project": "coalition", "Environment": "Test"})
+
+	return &terraform.Options{
+		TerraformDir:    tc.TerraformDir,
+		TerraformBinary: "terraform", // Explicitly use terraform instead of auto-detecting OpenTofu
+		Vars:            defaultVars,
+		BackendConfig: map[string]interface{}{
+			"bucket":         fmt.Sprintf("coalition-terraform-state-%s", tc.mustGetAccountID()),
+			"key":            fmt.Sprintf("tests/terraform-test-%s.tfstate", tc.UniqueID),
+			"region":         tc.AWSRegion,
+			"encrypt":        true,
+			"dynamodb_table": "coalition-terraform-locks",
+		},
+		EnvVars: map[string]string{
+			"AWS_DEFAULT_REGION":  tc.AWSRegion,
+			"TERRATEST_TERRAFORM": "terraform", // Force Terratest to use terraform
+		},
+	}
+}
+
+// GetTerraformOptionsForPlanOnly returns terraform options for plan-only tests (no backend), marked with ModePlan so
+// only common.PlanOnly accepts them. Only includes minimal defaults — callers should pass module-specific vars explicitly.
+func (tc *TestConfig) GetTerraformOptionsForPlanOnly(vars map[string]interface{}) *ModeOptions {
+	defaultVars := map[string]interface{}{}
+
+	// Merge with provided vars (provided vars override defaults)
+	for k, v := range vars {
+		defaultVars[k] = v
+	}
+
+	return PlanMode(&terraform.Options{
+		TerraformDir:    tc.TerraformDir,
+		TerraformBinary: "terraform", // Explicitly use terraform instead of auto-detecting OpenTofu
+		Vars:            defaultVars,
+		EnvVars: map[string]string{
+			"AWS_DEFAULT_REGION":  tc.AWSRegion,
+			"TERRATEST_TERRAFORM": "terraform",
+		},
+	})
+}
+
+// mustGetAccountID returns the AWS account ID for backend configuration
+// Used only by regular terraform tests that need S3 backend
+func (tc *TestConfig) mustGetAccountID() string {
+	// Try from environment variable first (set in CI)
+	if accountID := os.Getenv("AWS_ACCOUNT_ID"); accountID != "" {
+		return accountID
+	}
+
+	// For local development, use a fallback value
+	// Real apply tests should set AWS_ACCOUNT_ID environment variable
+	return "123456789012" // placeholder account ID
+}
+
+// GetAccountID lazily populates and returns the AccountID field
+func (tc *TestConfig) GetAccountID() string {
+	if tc.AccountID == "" {
+		tc.AccountID = tc.mustGetAccountID()
+	}
+	return tc.AccountID
+}
+
+// GetModuleTerraformOptions returns terraform options for applying an individual module, marked with ModeApply so
+// only common.ApplyAndValidate accepts them
+func (tc *TestConfig) GetModuleTerraformOptions(modulePath string, vars map[string]interface{}) *ModeOptions {
+	terraformOptions := tc.moduleTerraformOptions(modulePath, vars)
+	Track(terraformOptions)
+	return ApplyMode(terraformOptions)
+}
+
+// GetModuleTerraformOptionsForPlanOnly returns terraform options for planning an individual module, marked with
+// ModePlan so only common.PlanOnly accepts them. They may keep the placeholder IDs of the module's test values.
+func (tc *TestConfig) GetModuleTerraformOptionsForPlanOnly(
+	modulePath string,
+	vars map[string]interface{},
+) *ModeOptions {
+	return PlanMode(tc.moduleTerraformOptions(modulePath, vars))
+}
+
+// moduleTerraformOptions builds the options of a module for either mode
+func (tc *TestConfig) moduleTerraformOptions(modulePath string, vars map[string]interface{}) *terraform.Options {
+	// Only the variables the module declares, layered over its test values (see test_values.go)
+	moduleVars := tc.testVars(moduleConfiguration(modulePath), modulePath, vars)
+	if ModuleAcceptsTags(modulePath) {
+		addRunTags(moduleVars, nil)
+	}
+
+	return &terraform.Options{
+		TerraformDir:    modulePath,
+		TerraformBinary: "terraform", // Explicitly use terraform instead of auto-detecting OpenTofu
+		Vars:            moduleVars,
+		EnvVars: map[string]string{
+			"AWS_DEFAULT_REGION":  tc.AWSRegion,
+			"TERRATEST_TERRAFORM": "terraform", // Force Terratest to use terraform
+		},
+	}
+}
+
+// ExampleVars returns a value for each variable an example declares without a default, failing the test for any
+// that has no test value. Variables with defaults keep them, since those are what the example shows.
+func (tc *TestConfig) ExampleVars(t *testing.T, examplePath string) map[string]interface{} {
+	fixtures := tc.sharedTestValues()
+	fixtures["prefix"], fixtures["aws_region"] = tc.Prefix, tc.AWSRegion
+
+	declared := DeclaredVariables(examplePath)
+	names := make([]string, 0, len(declared))
+	for name := range declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	vars := map[string]interface{}{}
+	for _, name := range names {
+		if declared[name] {
+			continue
+		}
+		value, ok := fixtures[name]
+		require.True(t, ok, "%s requires %s, which has no test value; add one to sharedTestValues", examplePath, name)
+		vars[name] = value
+	}
+	return vars
+}
+
+var (
+	trackedMutex sync.Mutex
+	tracked      = map[*terraform.Options]bool{} // Each options value, so tests sharing a directory are all kept
+)
+
+// Track remembers options that may deploy a stack, so it can be destroyed if the run is interrupted.
+// GetModuleTerraformOptions tracks the options it builds, and common.RegisterDestroy those it is given.
+func Track(terraformOptions *terraform.Options) {
+	trackedMutex.Lock()
+	defer trackedMutex.Unlock()
+	tracked[terraformOptions] = true
+}
+
+// Untrack forgets options whose stack has been destroyed
+func Untrack(terraformOptions *terraform.Options) {
+	trackedMutex.Lock()
+	defer trackedMutex.Unlock()
+	delete(tracked, terraformOptions)
+}
+
+// TrackedStacks returns every tracked options value, for common.RunWithInterruptCleanup to destroy what they left in
+// state
+func TrackedStacks() []*terraform.Options {
+	trackedMutex.Lock()
+	defer trackedMutex.Unlock()
+	stacks := make([]*terraform.Options, 0, len(tracked))
+	for terraformOptions := range tracked {
+		stacks = append(stacks, terraformOptions)
+	}
+	return stacks
+}
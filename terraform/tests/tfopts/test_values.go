@@ -0,0 +1,151 @@
+package tfopts
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Test variables are layered, each layer overriding the one before:
+//
+//  1. The test configuration's prefix and region, for every configuration that declares them
+//  2. sharedTestValues, for each variable a configuration declares without a default
+//  3. configurationTestValues, a configuration's overrides of variables that have defaults, such as creating every
+//     subnet or using the smallest database
+//  4. The caller's variables
+//
+// A value is written once however many configurations take it, so a new required variable is added to
+// sharedTestValues alone and every configuration declaring it gets the value.
+
+// The smallest database tests create. common.CostTierDefaults holds the modules' defaults to the same tier.
+const (
+	TestDBInstanceClass    = "db.t4g.micro"
+	TestDBAllocatedStorage = 20
+)
+
+// RootConfiguration is the configurationTestValues key of the root configuration
+const RootConfiguration = ""
+
+// sharedTestValues returns the test value of each variable, by name, for the configurations that require it. Fake
+// IDs are placeholders that plans accept; apply tests replace them with fixtures.
+func (tc *TestConfig) sharedTestValues() map[string]interface{} {
+	return map[string]interface{}{
+		"domain_name":               fmt.Sprintf("%s.example.com", tc.UniqueID),
+		"alert_email":               "test@example.com",
+		"api_gateway_id":            "test123",
+		"vpc_id":                    "vpc-12345678",
+		"public_subnet_id":          "subnet-public",
+		"bastion_security_group_id": "sg-bastion123",
+		"allowed_bastion_cidrs":     []string{"203.0.113.0/24"},
+		"db_subnet_ids":             []string{"subnet-db1", "subnet-db2"},
+		"db_security_group_id":      "sg-database123",
+		"db_endpoint":               "test.cluster-xyz.us-east-1.rds.amazonaws.com:5432",
+		"db_name":                   "testdb",
+		"db_username":               "testuser",
+		"db_password":               "testpassword123!",
+		"app_db_username":           "appuser",
+		"app_db_password":           testAppDBPassword,
+	}
+}
+
+// testAppDBPassword is shared by the modules that require the application password and the root, which
+// generates one unless it is given
+const testAppDBPassword = "apppassword123!"
+
+// Overrides shared by more than one configuration
+var (
+	// networkCreationTestValues create every part of the network, so each is tested
+	networkCreationTestValues = map[string]interface{}{
+		"create_vpc":             true,
+		"create_public_subnets":  true,
+		"create_private_subnets": true,
+		"create_db_subnets":      true,
+	}
+
+	// minimalDatabaseTestValues use the smallest database that can be created
+	minimalDatabaseTestValues = map[string]interface{}{
+		"db_allocated_storage": TestDBAllocatedStorage,
+		"db_instance_class":    TestDBInstanceClass,
+	}
+)
+
+// configurationTestValues are each configuration's overrides of variables that have defaults, by module directory
+// name or RootConfiguration
+var configurationTestValues = map[string][]map[string]interface{}{
+	// environment defaults to prod, whose database is deletion protected and whose ECR repository is unprefixed
+	RootConfiguration: {networkCreationTestValues, minimalDatabaseTestValues, {
+		"environment":     "test",
+		"route53_zone_id": "Z123456789",
+		"app_db_password": testAppDBPassword,
+	}},
+	"networking": {networkCreationTestValues},
+	// prevent_destroy defaults to true, which would keep every apply test's parameter groups from being destroyed
+	"database": {minimalDatabaseTestValues, {
+		"prevent_destroy":            false,
+		"db_engine_version":          "16.9",
+		"use_secrets_manager":        false,
+		"db_backup_retention_period": 7,
+		"auto_setup_database":        false,
+	}},
+	"security": {{
+		"allowed_bastion_cidrs":    []string{"10.0.0.0/8"},
+		"lambda_security_group_id": "sg-lambda123",
+	}},
+	"bastion": {{
+		"bastion_public_key":  "",
+		"create_new_key_pair": false,
+	}},
+	"monitoring": {{
+		"budget_limit_amount": "100",
+	}},
+	// CloudFront is disabled, since creating and destroying a distribution adds 20-40 minutes;
+	// TestStorageModuleWithCloudFront (-tags slow) covers it
+	"storage": {{
+		"domain_name":            "test.example.com",
+		"force_destroy":          true,
+		"cors_allowed_origins":   []string{"https://example.com"},
+		"enable_versioning":      true,
+		"enable_lifecycle_rules": true,
+		"enable_cloudfront":      false,
+	}},
+}
+
+// ConfigurationTestValues returns a copy of a configuration's overrides, which callers may change
+func ConfigurationTestValues(configuration string) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, layer := range configurationTestValues[configuration] {
+		for name, value := range layer {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// testVars layers the test variables of the configuration in configPath under the caller's vars
+func (tc *TestConfig) testVars(configuration, configPath string, vars map[string]interface{}) map[string]interface{} {
+	declared := DeclaredVariables(configPath)
+	shared := tc.sharedTestValues()
+
+	layered := map[string]interface{}{}
+	for name, hasDefault := range declared {
+		switch {
+		case name == "prefix":
+			layered[name] = tc.Prefix
+		case name == "aws_region":
+			layered[name] = tc.AWSRegion
+		case !hasDefault && shared[name] != nil:
+			layered[name] = shared[name]
+		}
+	}
+	for name, value := range ConfigurationTestValues(configuration) {
+		layered[name] = value
+	}
+	for name, value := range vars {
+		layered[name] = value
+	}
+	return layered
+}
+
+// moduleConfiguration returns the configurationTestValues key of a module path, which may be a working copy
+func moduleConfiguration(modulePath string) string {
+	return filepath.Base(filepath.Clean(modulePath))
+}
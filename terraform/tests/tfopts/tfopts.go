@@ -0,0 +1,79 @@
+// Package tfopts builds and prepares terraform options for tests. A TestConfig layers test values over the
+// variables a configuration declares and tags what it creates with the run, commit and branch; the options it
+// builds are marked for a plan or an apply, and can be initialized without a backend.
+package tfopts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// Mode is what a test does with a configuration: only plan it, or apply it and destroy it afterwards
+type Mode string
+
+// Modes
+const (
+	ModePlan  Mode = "plan"  // Init and plan only; the options may hold placeholder IDs and have no backend
+	ModeApply Mode = "apply" // Apply, validate and destroy
+)
+
+// ModeOptions are terraform options together with the mode they were built for. common.PlanOnly and
+// common.ApplyAndValidate check the mode, so options built for a plan are never applied by mistake.
+type ModeOptions struct {
+	*terraform.Options
+	Mode Mode
+}
+
+// PlanMode marks options as built for a plan
+func PlanMode(terraformOptions *terraform.Options) *ModeOptions {
+	return &ModeOptions{Options: terraformOptions, Mode: ModePlan}
+}
+
+// ApplyMode marks options as built for an apply
+func ApplyMode(terraformOptions *terraform.Options) *ModeOptions {
+	return &ModeOptions{Options: terraformOptions, Mode: ModeApply}
+}
+
+// DeclaredVariables returns whether each variable a configuration declares has a default, by name. A missing or
+// unparsable variables.tf declares none.
+func DeclaredVariables(configPath string) map[string]bool {
+	declared := map[string]bool{}
+	file, diags := hclparse.NewParser().ParseHCLFile(filepath.Join(configPath, "variables.tf"))
+	if diags.HasErrors() {
+		return declared
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return declared
+	}
+	for _, block := range body.Blocks {
+		if block.Type == "variable" && len(block.Labels) > 0 {
+			_, hasDefault := block.Body.Attributes["default"]
+			declared[block.Labels[0]] = hasDefault
+		}
+	}
+	return declared
+}
+
+// InitForPlanOnly initializes terraform without backend for plan-only tests
+func InitForPlanOnly(t *testing.T, terraformOptions *terraform.Options) {
+	t.Logf("Starting terraform init for directory: %s", terraformOptions.TerraformDir)
+	terraform.RunTerraformCommand(t, terraformOptions, "init", "-backend=false")
+	t.Logf("Terraform init completed successfully")
+}
+
+// CleanupState removes local terraform state to prevent conflicts between tests
+func CleanupState(t *testing.T, terraformDir string) {
+	terraformStateDir := fmt.Sprintf("%s/.terraform", terraformDir)
+	if err := os.RemoveAll(terraformStateDir); err != nil {
+		t.Logf("Warning: Failed to cleanup terraform state directory: %v", err)
+	} else {
+		t.Logf("Cleaned up terraform state directory: %s", terraformStateDir)
+	}
+}
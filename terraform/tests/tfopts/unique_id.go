@@ -0,0 +1,32 @@
+package tfopts
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+)
+
+// workerSalt distinguishes test processes running side by side, such as parallel CI jobs or
+// `go test` running several packages at once. TEST_WORKER_ID overrides the process ID.
+var workerSalt = func() byte {
+	worker := os.Getenv("TEST_WORKER_ID")
+	if worker == "" {
+		worker = strconv.Itoa(os.Getpid())
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(worker))
+	return byte(hash.Sum32())
+}()
+
+// NewUniqueID returns an identifier for naming test resources, like "test-3f0a1b2c3d4e5f". It combines a
+// worker salt with 48 random bits, so IDs created concurrently across tests and processes do not collide.
+func NewUniqueID() string {
+	random := make([]byte, 6)
+	if _, err := rand.Read(random); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	return fmt.Sprintf("test-%02x%s", workerSalt, hex.EncodeToString(random))
+}
@@ -0,0 +1,171 @@
+// Package tfout reads terraform outputs as Go values and checks them. Output decodes an output's JSON value into
+// the type the caller asks for, and OutputString, OutputList and OutputMap return values to check fluently:
+//
+//	subnetIDs := tfout.OutputList(t, terraformOptions, "public_subnet_ids").HasLen(2).AllMatch("^subnet-").Values()
+//	vpcID := tfout.OutputString(t, terraformOptions, "vpc_id").HasPrefix("vpc-").Value()
+//	port := tfout.Output[int](t, terraformOptions, "db_instance_port")
+//
+// A failed check is reported with the output's name and the chain carries on, so one read reports every way the
+// output is wrong.
+package tfout
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// OutputE reads an output and decodes its value into T, such as a string, []string, map[string]string, bool, int
+// or float64. A number or bool read as a string is its JSON text, as terraform output -raw prints it.
+func OutputE[T any](t *testing.T, options *terraform.Options, name string) (T, error) {
+	raw, err := terraform.OutputJsonE(t, options, name)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return decode[T](name, raw)
+}
+
+// Output reads an output and decodes its value into T, failing the test if it cannot
+func Output[T any](t *testing.T, options *terraform.Options, name string) T {
+	t.Helper()
+
+	value, err := OutputE[T](t, options, name)
+	require.NoError(t, err)
+	return value
+}
+
+// decode decodes an output's JSON value into T
+func decode[T any](name, raw string) (T, error) {
+	var value T
+	err := json.Unmarshal([]byte(raw), &value)
+	if err == nil {
+		return value, nil
+	}
+
+	// Terraform prints numbers and bools as their JSON text, which is what a test comparing them as strings expects
+	if text, ok := any(&value).(*string); ok {
+		var scalar interface{}
+		if json.Unmarshal([]byte(raw), &scalar) == nil {
+			switch scalar.(type) {
+			case float64, bool:
+				*text = strings.TrimSpace(raw)
+				return value, nil
+			}
+		}
+	}
+	return value, fmt.Errorf("output %s is %s, which cannot be read as %T: %w", name, raw, value, err)
+}
+
+// StringOutput is a string output to check
+type StringOutput struct {
+	t     assert.TestingT
+	name  string
+	value string
+}
+
+// OutputString reads a string output to check
+func OutputString(t *testing.T, options *terraform.Options, name string) *StringOutput {
+	return &StringOutput{t: t, name: name, value: Output[string](t, options, name)}
+}
+
+// NotEmpty checks the output is set
+func (s *StringOutput) NotEmpty() *StringOutput {
+	assert.NotEmpty(s.t, s.value, "Output %s should not be empty", s.name)
+	return s
+}
+
+// HasPrefix checks the output starts with prefix, such as an ID's "vpc-"
+func (s *StringOutput) HasPrefix(prefix string) *StringOutput {
+	assert.True(s.t, strings.HasPrefix(s.value, prefix), "Output %s is %q, which should start with %q",
+		s.name, s.value, prefix)
+	return s
+}
+
+// Matches checks the output matches a regular expression
+func (s *StringOutput) Matches(pattern string) *StringOutput {
+	assert.Regexp(s.t, regexp.MustCompile(pattern), s.value, "Output %s", s.name)
+	return s
+}
+
+// Value returns the output
+func (s *StringOutput) Value() string {
+	return s.value
+}
+
+// ListOutput is a list of strings output to check
+type ListOutput struct {
+	t      assert.TestingT
+	name   string
+	values []string
+}
+
+// OutputList reads a list of strings output to check
+func OutputList(t *testing.T, options *terraform.Options, name string) *ListOutput {
+	return &ListOutput{t: t, name: name, values: Output[[]string](t, options, name)}
+}
+
+// NotEmpty checks the list has at least one value
+func (l *ListOutput) NotEmpty() *ListOutput {
+	assert.NotEmpty(l.t, l.values, "Output %s should not be empty", l.name)
+	return l
+}
+
+// HasLen checks the list has length values
+func (l *ListOutput) HasLen(length int) *ListOutput {
+	assert.Len(l.t, l.values, length, "Output %s should have %d values", l.name, length)
+	return l
+}
+
+// AllMatch checks every value matches a regular expression, such as "^subnet-" for a list of subnet IDs
+func (l *ListOutput) AllMatch(pattern string) *ListOutput {
+	expression := regexp.MustCompile(pattern)
+	for i, value := range l.values {
+		assert.Regexp(l.t, expression, value, "Output %s[%d]", l.name, i)
+	}
+	return l
+}
+
+// Values returns the list
+func (l *ListOutput) Values() []string {
+	return l.values
+}
+
+// MapOutput is a map of strings output to check
+type MapOutput struct {
+	t      assert.TestingT
+	name   string
+	values map[string]string
+}
+
+// OutputMap reads a map of strings output to check
+func OutputMap(t *testing.T, options *terraform.Options, name string) *MapOutput {
+	return &MapOutput{t: t, name: name, values: Output[map[string]string](t, options, name)}
+}
+
+// NotEmpty checks the map has at least one key
+func (m *MapOutput) NotEmpty() *MapOutput {
+	assert.NotEmpty(m.t, m.values, "Output %s should not be empty", m.name)
+	return m
+}
+
+// HasKeys checks the map has exactly the keys, in any order
+func (m *MapOutput) HasKeys(keys ...string) *MapOutput {
+	actual := make([]string, 0, len(m.values))
+	for key := range m.values {
+		actual = append(actual, key)
+	}
+	assert.ElementsMatch(m.t, keys, actual, "Output %s should have keys %v", m.name, keys)
+	return m
+}
+
+// Values returns the map
+func (m *MapOutput) Values() map[string]string {
+	return m.values
+}
@@ -0,0 +1,85 @@
+package tfout
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder collects the failures of a check instead of failing the test
+type recorder struct {
+	failures []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestDecodeOutputs(t *testing.T) {
+	text, err := decode[string]("vpc_id", `"vpc-0a1b"`)
+	require.NoError(t, err)
+	assert.Equal(t, "vpc-0a1b", text)
+
+	port, err := decode[int]("db_instance_port", `5432`)
+	require.NoError(t, err)
+	assert.Equal(t, 5432, port)
+
+	portText, err := decode[string]("db_instance_port", `5432`)
+	require.NoError(t, err)
+	assert.Equal(t, "5432", portText, "A number read as a string should be its JSON text")
+
+	enabled, err := decode[bool]("enabled", `true`)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	list, err := decode[[]string]("public_subnet_ids", `["subnet-1","subnet-2"]`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"subnet-1", "subnet-2"}, list)
+
+	endpoints, err := decode[map[string]string]("interface_endpoints", `{"ssm":"vpce-1"}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ssm": "vpce-1"}, endpoints)
+
+	_, err = decode[[]string]("vpc_id", `"vpc-0a1b"`)
+	assert.ErrorContains(t, err, "output vpc_id is \"vpc-0a1b\", which cannot be read as []string")
+
+	_, err = decode[string]("public_subnet_ids", `["subnet-1"]`)
+	assert.Error(t, err, "A list should not be read as a string")
+}
+
+func TestListOutputChecks(t *testing.T) {
+	failures := &recorder{}
+	values := (&ListOutput{t: failures, name: "public_subnet_ids", values: []string{"subnet-1", "sg-2"}}).
+		NotEmpty().HasLen(2).AllMatch("^subnet-").Values()
+
+	assert.Equal(t, []string{"subnet-1", "sg-2"}, values, "Checks should not change the values")
+	require.Len(t, failures.failures, 1, "Only the value that does not match should fail")
+	assert.Contains(t, failures.failures[0], "public_subnet_ids[1]")
+
+	failures = &recorder{}
+	(&ListOutput{t: failures, name: "private_subnet_ids"}).NotEmpty().HasLen(2)
+	assert.Len(t, failures.failures, 2, "Each failed check should be reported")
+}
+
+func TestStringAndMapOutputChecks(t *testing.T) {
+	failures := &recorder{}
+	value := (&StringOutput{t: failures, name: "vpc_id", value: "vpc-0a1b"}).
+		NotEmpty().HasPrefix("vpc-").Matches("^vpc-[0-9a-f]+$").Value()
+	assert.Equal(t, "vpc-0a1b", value)
+	assert.Empty(t, failures.failures)
+
+	(&StringOutput{t: failures, name: "vpc_id", value: "subnet-1"}).HasPrefix("vpc-")
+	require.Len(t, failures.failures, 1)
+	assert.Contains(t, failures.failures[0], `Output vpc_id is "subnet-1", which should start with "vpc-"`)
+
+	failures = &recorder{}
+	(&MapOutput{t: failures, name: "interface_endpoints", values: map[string]string{"ssm": "vpce-1"}}).
+		NotEmpty().HasKeys("ssm")
+	assert.Empty(t, failures.failures)
+
+	(&MapOutput{t: failures, name: "interface_endpoints", values: map[string]string{"ssm": "vpce-1"}}).
+		HasKeys("ssm", "logs")
+	assert.Len(t, failures.failures, 1)
+}
@@ -186,9 +186,27 @@ variable "prevent_destroy" {
 }
 
 # DNS and SSL Variables
+variable "manage_dns" {
+  description = "Whether to create DNS records in route53_zone_id; set to false when the domain's DNS is hosted outside Route 53"
+  type        = bool
+  default     = true
+}
+
 variable "route53_zone_id" {
-  description = "The Route 53 zone ID to create records in"
+  description = "The existing Route 53 zone ID to create records in (required when manage_dns is true)"
   type        = string
+  default     = ""
+
+  validation {
+    condition     = !var.manage_dns || var.route53_zone_id != ""
+    error_message = "route53_zone_id must be set when manage_dns is true."
+  }
+}
+
+variable "create_frontend_dns_records" {
+  description = "Whether to point the apex and www records at Vercel (only applies when manage_dns is true)"
+  type        = bool
+  default     = true
 }
 
 variable "domain_name" {